@@ -1,27 +1,318 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	// Embed the time zone database so preference time zones validate on
+	// hosts and images without one
+	_ "time/tzdata"
 
+	"github.com/boonyarit-iamsaard/user-management-api/internal/app"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/buildinfo"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/database"
 	"github.com/boonyarit-iamsaard/user-management-api/internal/handler"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/tracing"
 	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// shutdownTimeout bounds how long in-flight requests may take to drain
+const shutdownTimeout = 10 * time.Second
+
+// redisPingTimeout bounds the startup check that Redis is reachable
+const redisPingTimeout = 2 * time.Second
+
 func main() {
-	// Create a new Fiber instance
-	app := fiber.New(fiber.Config{
-		// Server configuration
+	migrateOnly := flag.Bool("migrate-only", false, "apply database migrations and exit")
+	seed := flag.Bool("seed", false, "create demo users from SEED_* settings before serving")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("failed to load configuration", err)
+	}
+
+	if err := logger.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		fatal("failed to set up logger", err)
+	}
+	// Tag every log line with the build, so logs show which deploy wrote them
+	build := buildinfo.Get()
+	slog.SetDefault(slog.Default().With(slog.String("version", build.Version), slog.String("commit", build.Commit)))
+
+	// Apply database migrations before serving any traffic, once the
+	// database accepts connections
+	if cfg.Database.URL != "" {
+		err := database.WaitReady(context.Background(), cfg.Database.URL, database.RetrySettings{
+			Attempts:   cfg.Database.ConnectAttempts,
+			Backoff:    cfg.Database.ConnectBackoff,
+			MaxBackoff: cfg.Database.ConnectMaxBackoff,
+		})
+		if err != nil {
+			fatal("failed to connect to database", err)
+		}
+		if cfg.Database.ReplicaURL != "" {
+			err := database.WaitReady(context.Background(), cfg.Database.ReplicaURL, database.RetrySettings{
+				Attempts:   cfg.Database.ConnectAttempts,
+				Backoff:    cfg.Database.ConnectBackoff,
+				MaxBackoff: cfg.Database.ConnectMaxBackoff,
+			})
+			if err != nil {
+				fatal("failed to connect to database replica", err)
+			}
+		}
+		if err := database.RunMigrations(cfg.Database.URL); err != nil {
+			fatal("failed to run migrations", err)
+		}
+	} else if *migrateOnly {
+		fatal("cannot run migrations", errors.New("DATABASE_URL is not set"))
+	}
+	if *migrateOnly {
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Endpoint, cfg.Tracing.ServiceName)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("failed to flush traces", slog.Any("error", err))
+		}
+	}()
+
+	if err := auth.SetBcryptCost(cfg.Auth.BcryptCost); err != nil {
+		fatal("invalid BCRYPT_COST", err)
+	}
+	auth.SetPasswordPolicy(auth.PasswordPolicy{
+		MinLength:     cfg.Password.MinLength,
+		RequireUpper:  cfg.Password.RequireUpper,
+		RequireLower:  cfg.Password.RequireLower,
+		RequireDigit:  cfg.Password.RequireDigit,
+		RequireSymbol: cfg.Password.RequireSymbol,
+		RejectCommon:  cfg.Password.RejectCommon,
 	})
+	if cfg.Password.CommonListFile != "" {
+		if err := auth.LoadCommonPasswords(cfg.Password.CommonListFile); err != nil {
+			fatal("invalid PASSWORD_COMMON_LIST_FILE", err)
+		}
+	}
+
+	// Check the key pair up front so a bad path fails with a clear message
+	// instead of surfacing as a generic listen error
+	if cfg.Server.TLSEnabled {
+		if _, err := tls.LoadX509KeyPair(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile); err != nil {
+			fatal("failed to load TLS certificate", err)
+		}
+	}
+
+	tokenKeys, err := loadTokenKeys(cfg.JWT)
+	if err != nil {
+		fatal("failed to load JWT signing keys", err)
+	}
+
+	// Rate limits and lockouts live in Redis when configured so replicas share
+	// them, falling back to process memory while Redis is unreachable
+	var store kvstore.Store = kvstore.NewMemory()
+	if cfg.Redis.URL != "" {
+		redisStore, err := kvstore.NewRedis(cfg.Redis.URL)
+		if err != nil {
+			fatal("invalid REDIS_URL", err)
+		}
+		defer redisStore.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), redisPingTimeout)
+		if err := redisStore.Ping(ctx); err != nil {
+			slog.Warn("redis unreachable, using in-memory state until it recovers", slog.Any("error", err))
+		}
+		cancel()
+		store = kvstore.NewFallback(redisStore, store)
+	}
+
+	// Setup dependencies
+	repos := app.NewInMemoryRepositories()
+	var db app.Pinger
+	if cfg.Database.URL != "" {
+		pool, err := database.Connect(context.Background(), cfg.Database.URL, cfg.Database.SlowQueryThreshold)
+		if err != nil {
+			fatal("failed to connect to database", err)
+		}
+		defer pool.Close()
+		db = pool
+
+		// User lookups and listings that tolerate replication lag read from
+		// the replica when one is configured
+		var replica *pgxpool.Pool
+		if cfg.Database.ReplicaURL != "" {
+			replica, err = database.Connect(context.Background(), cfg.Database.ReplicaURL, cfg.Database.SlowQueryThreshold)
+			if err != nil {
+				fatal("failed to connect to database replica", err)
+			}
+			defer replica.Close()
+		}
+
+		repos = app.NewPostgresRepositories(pool, replica)
+	} else {
+		slog.Warn("DATABASE_URL not set, using in-memory repositories")
+	}
+
+	application, err := app.New(cfg, repos, app.Options{Keys: tokenKeys, Store: store, DB: db})
+	if err != nil {
+		fatal("failed to set up the application", err)
+	}
+
+	if *seed {
+		if cfg.IsProduction() {
+			fatal("cannot seed users", errors.New("seeding is disabled in production"))
+		}
+		if err := seedUsers(context.Background(), application.Users, cfg.Seed); err != nil {
+			fatal("failed to seed users", err)
+		}
+	}
 
-	// Setup middleware
-	handler.SetupMiddleware(app)
+	server := handler.NewServer(cfg, application)
 
-	// Setup routes
-	handler.SetupRoutes(app)
+	// Stop on SIGINT or SIGTERM so in-flight requests can drain
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Start server
-	log.Println("Starting server on :3000")
-	if err := app.Listen(":3000"); err != nil {
-		log.Fatal("Server failed to start:", err)
+	addr := ":" + cfg.Server.Port
+	listenConfig := fiber.ListenConfig{}
+	if cfg.Server.TLSEnabled {
+		listenConfig.CertFile = cfg.Server.TLSCertFile
+		listenConfig.CertKeyFile = cfg.Server.TLSKeyFile
+	}
+	listenErr := make(chan error, 3)
+	go func() {
+		slog.Info("starting server", slog.String("addr", addr), slog.String("env", cfg.Env),
+			slog.Bool("tls", cfg.Server.TLSEnabled), slog.String("build_time", build.BuildTime),
+			slog.String("go_version", build.GoVersion))
+		listenErr <- server.Public.Listen(addr, listenConfig)
+	}()
+
+	// Send plain HTTP clients to the HTTPS listener
+	var redirectApp *fiber.App
+	if cfg.Server.TLSRedirectPort != "" {
+		redirectApp = newRedirectApp(cfg.Server.Port, cfg.Server.TrustedHosts)
+
+		redirectAddr := ":" + cfg.Server.TLSRedirectPort
+		go func() {
+			slog.Info("starting HTTPS redirect server", slog.String("addr", redirectAddr))
+			listenErr <- redirectApp.Listen(redirectAddr, fiber.ListenConfig{DisableStartupMessage: true})
+		}()
+	}
+
+	// Serve operational endpoints on the internal listener so they stay off
+	// the public one
+	if server.Admin != nil {
+		go func() {
+			slog.Info("starting admin server", slog.String("addr", cfg.Server.AdminAddr))
+			listenErr <- server.Admin.Listen(cfg.Server.AdminAddr, fiber.ListenConfig{DisableStartupMessage: true})
+		}()
+	}
+
+	select {
+	case err := <-listenErr:
+		if err != nil {
+			fatal("server failed to start", err)
+		}
+	case <-ctx.Done():
+		slog.Info("shutting down")
+		if err := server.Public.ShutdownWithTimeout(shutdownTimeout); err != nil {
+			slog.Error("shutdown did not complete cleanly", slog.Any("error", err))
+		}
+		if server.Admin != nil {
+			if err := server.Admin.ShutdownWithTimeout(shutdownTimeout); err != nil {
+				slog.Error("admin shutdown did not complete cleanly", slog.Any("error", err))
+			}
+		}
+		if redirectApp != nil {
+			if err := redirectApp.ShutdownWithTimeout(shutdownTimeout); err != nil {
+				slog.Error("redirect shutdown did not complete cleanly", slog.Any("error", err))
+			}
+		}
+
+		// Let queued webhook deliveries and email retries go out once no new
+		// requests can add more
+		closeCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := application.Close(closeCtx); err != nil {
+			slog.Error("background work did not finish", slog.Any("error", err))
+		}
+		cancel()
+		slog.Info("shutdown complete")
+	}
+}
+
+// newRedirectApp answers every request for one of hosts with a permanent
+// redirect to the same URL on the HTTPS port; 308 keeps the method and body
+// of non-GET requests
+func newRedirectApp(httpsPort string, hosts []string) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+	app.Use(handler.TrustedHosts(hosts))
+	app.Use(func(c fiber.Ctx) error {
+		host := c.Hostname()
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+		return c.Redirect().Status(fiber.StatusPermanentRedirect).
+			To("https://" + net.JoinHostPort(host, httpsPort) + c.OriginalURL())
+	})
+
+	return app
+}
+
+// loadTokenKeys builds the access token keys from the JWT settings, reading
+// RS256 keys from their PEM files
+func loadTokenKeys(cfg config.JWTConfig) (*auth.KeySet, error) {
+	if cfg.Algorithm == auth.AlgorithmRS256 {
+		pems := make(map[string][]byte, len(cfg.Keys))
+		for kid, path := range cfg.Keys {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read key %q: %w", kid, err)
+			}
+			pems[kid] = pem
+		}
+		return auth.NewRSAKeySet(cfg.KeyID, pems)
 	}
+
+	secrets := make(map[string][]byte, len(cfg.Keys)+1)
+	for kid, secret := range cfg.Keys {
+		secrets[kid] = []byte(secret)
+	}
+	if cfg.Secret != "" {
+		secrets[cfg.KeyID] = []byte(cfg.Secret)
+	}
+	if _, ok := secrets[cfg.KeyID]; !ok {
+		slog.Warn("JWT_SECRET not set, using a random secret for this process")
+		secrets[cfg.KeyID] = []byte(rand.Text())
+	}
+
+	return auth.NewHMACKeySet(cfg.KeyID, secrets)
+}
+
+// fatal logs the error and exits with a non-zero status
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.Any("error", err))
+	os.Exit(1)
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	app := fiber.New()
+	app.Get("/slow", func(c fiber.Ctx) error {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		return c.SendString("done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true})
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		done <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	<-started
+	if err := app.ShutdownWithTimeout(shutdownTimeout); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	got := <-done
+	if got.err != nil {
+		t.Fatalf("in-flight request failed: %v", got.err)
+	}
+	if got.status != http.StatusOK || got.body != "done" {
+		t.Errorf("in-flight request got %d %q, want 200 %q", got.status, got.body, "done")
+	}
+	if err := <-served; err != nil {
+		t.Errorf("listener returned %v after shutdown", err)
+	}
+
+	// New connections are refused once shut down
+	if _, err := net.DialTimeout("tcp", ln.Addr().String(), time.Second); err == nil {
+		t.Error("server accepted a connection after shutdown")
+	}
+}
+
+// The fixture is a self-signed certificate for localhost and 127.0.0.1
+const (
+	testCertFile = "testdata/tls.crt"
+	testKeyFile  = "testdata/tls.key"
+)
+
+func TestServeTLS(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendString("secure")
+	})
+
+	addr := make(chan net.Addr, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- app.Listen("127.0.0.1:0", fiber.ListenConfig{
+			DisableStartupMessage: true,
+			CertFile:              testCertFile,
+			CertKeyFile:           testKeyFile,
+			ListenerAddrFunc:      func(a net.Addr) { addr <- a },
+		})
+	}()
+	var url string
+	select {
+	case a := <-addr:
+		url = "https://" + a.String() + "/"
+	case err := <-served:
+		t.Fatalf("listen with TLS: %v", err)
+	}
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	cert, err := os.ReadFile(testCertFile)
+	if err != nil {
+		t.Fatalf("read certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(cert) {
+		t.Fatal("parse certificate fixture")
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET over TLS: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if resp.TLS == nil || !resp.TLS.HandshakeComplete {
+		t.Fatal("response did not arrive over TLS")
+	}
+	if resp.TLS.Version < tls.VersionTLS12 {
+		t.Errorf("negotiated TLS version %x, want 1.2 or later", resp.TLS.Version)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "secure" {
+		t.Errorf("got %d %q, want 200 %q", resp.StatusCode, body, "secure")
+	}
+
+	// Plain HTTP is not answered on the TLS port
+	plain := &http.Client{Timeout: time.Second}
+	if resp, err := plain.Get("http" + url[len("https"):]); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Error("plain HTTP request was served on the TLS port")
+		}
+	}
+}
+
+func TestRedirectApp(t *testing.T) {
+	app := newRedirectApp("8443", []string{"api.example.com"})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users?page=2", nil)
+	req.Host = "api.example.com:8080"
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPermanentRedirect {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusPermanentRedirect)
+	}
+	if got, want := resp.Header.Get("Location"), "https://api.example.com:8443/api/v1/users?page=2"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "evil.example.com"
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("GET with a spoofed host: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusPermanentRedirect {
+		t.Errorf("redirected a request for an untrusted host to %q", resp.Header.Get("Location"))
+	}
+}
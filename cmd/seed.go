@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+)
+
+// seedUsers creates the demo accounts described by cfg. Accounts whose email
+// is already taken are skipped, so seeding can run on every start.
+func seedUsers(ctx context.Context, users *service.UserService, cfg config.SeedConfig) error {
+	if cfg.AdminPassword == "" {
+		return errors.New("SEED_ADMIN_PASSWORD is required")
+	}
+	if cfg.UserCount > 0 && cfg.UserPassword == "" {
+		return errors.New("SEED_USER_PASSWORD is required when SEED_USER_COUNT is positive")
+	}
+
+	inputs := []service.CreateUserInput{{
+		Email:    cfg.AdminEmail,
+		Name:     "Admin",
+		Password: cfg.AdminPassword,
+		Role:     domain.RoleAdmin,
+	}}
+	for i := 1; i <= cfg.UserCount; i++ {
+		inputs = append(inputs, service.CreateUserInput{
+			Email:    fmt.Sprintf("user%d@example.com", i),
+			Name:     fmt.Sprintf("Demo User %d", i),
+			Password: cfg.UserPassword,
+			Role:     domain.RoleUser,
+		})
+	}
+
+	var created, skipped int
+	for _, input := range inputs {
+		_, err := users.Create(ctx, input)
+		switch {
+		case errors.Is(err, repository.ErrDuplicateEmail):
+			skipped++
+			slog.Info("seed user already exists", slog.String("email", input.Email))
+		case err != nil:
+			return fmt.Errorf("seed %s: %w", input.Email, err)
+		default:
+			created++
+			slog.Info("seed user created", slog.String("email", input.Email), slog.String("role", string(input.Role)))
+		}
+	}
+
+	slog.Info("seeding complete", slog.Int("created", created), slog.Int("skipped", skipped))
+	return nil
+}
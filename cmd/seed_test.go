@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newSeedService returns a UserService storing users in repo
+func newSeedService(t *testing.T, repo repository.UserRepository) *service.UserService {
+	t.Helper()
+
+	if err := auth.SetBcryptCost(bcrypt.MinCost); err != nil {
+		t.Fatalf("set bcrypt cost: %v", err)
+	}
+	keys, err := auth.NewHMACKeySet("test", map[string][]byte{"test": []byte("seed-test-secret")})
+	if err != nil {
+		t.Fatalf("create signing keys: %v", err)
+	}
+	dispatcher := webhook.NewDispatcher(repository.NewInMemoryWebhookRepository(), repository.NewInMemoryOutboxRepository(), repository.NewInMemoryTransactor(), webhook.Settings{
+		QueueSize:         10,
+		Workers:           1,
+		MaxAttempts:       1,
+		Timeout:           time.Second,
+		Backoff:           time.Second,
+		PollInterval:      time.Hour,
+		PollBatch:         10,
+		OutboxMaxAttempts: 1,
+	})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = dispatcher.Close(ctx)
+	})
+
+	sessions := service.NewSessionService(
+		repo, repository.NewInMemoryRefreshTokenRepository(), auth.NewTokenManager(keys), auth.NewDenylist(kvstore.NewMemory()), time.Minute, time.Hour,
+	)
+	return service.NewUserService(
+		repo, repository.NewInMemoryTransactor(), repository.NewInMemoryEmailVerificationRepository(),
+		repository.NewInMemoryPasswordResetRepository(), sessions, mailer.NewMockMailer(),
+		audit.NewLogger(repository.NewInMemoryAuditLogRepository()), dispatcher,
+		service.UserSettings{VerificationTTL: time.Hour, ResetTTL: time.Hour, LockoutThreshold: 5, LockoutDuration: time.Minute},
+	)
+}
+
+func TestSeedUsersIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemoryUserRepository()
+	users := newSeedService(t, repo)
+	cfg := config.SeedConfig{
+		AdminEmail:    "admin@example.com",
+		AdminPassword: "admin-seed-password",
+		UserCount:     3,
+		UserPassword:  "user-seed-password",
+	}
+
+	for run := 1; run <= 2; run++ {
+		if err := seedUsers(ctx, users, cfg); err != nil {
+			t.Fatalf("seed run %d: %v", run, err)
+		}
+
+		seeded, total, err := repo.List(ctx, repository.ListParams{Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if total != 4 || len(seeded) != 4 {
+			t.Fatalf("after run %d the repository holds %d users, want 4", run, total)
+		}
+	}
+
+	admin, err := repo.GetByEmail(ctx, "admin@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail admin: %v", err)
+	}
+	if !admin.Roles.Grants(domain.RoleAdmin) {
+		t.Errorf("admin roles = %v, want admin", admin.Roles)
+	}
+	if _, err := repo.GetByEmail(ctx, "user3@example.com"); err != nil {
+		t.Errorf("GetByEmail user3: %v", err)
+	}
+}
+
+func TestSeedUsersRequiresPasswords(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.SeedConfig
+	}{
+		{"no admin password", config.SeedConfig{AdminEmail: "admin@example.com"}},
+		{"no user password", config.SeedConfig{AdminEmail: "admin@example.com", AdminPassword: "admin-seed-password", UserCount: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewInMemoryUserRepository()
+			if err := seedUsers(context.Background(), newSeedService(t, repo), tt.cfg); err == nil {
+				t.Error("seedUsers succeeded, want an error")
+			}
+			if _, total, _ := repo.List(context.Background(), repository.ListParams{Page: 1, PageSize: 10}); total != 0 {
+				t.Errorf("seedUsers created %d users before failing", total)
+			}
+		})
+	}
+}
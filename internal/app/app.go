@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/featureflag"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/oauth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/ratelimit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/storage"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repositories holds the stores the services are built on
+type Repositories struct {
+	Users         repository.UserRepository
+	RefreshTokens repository.RefreshTokenRepository
+	Verifications repository.EmailVerificationRepository
+	Resets        repository.PasswordResetRepository
+	Idempotency   repository.IdempotencyRepository
+	AuditLogs     repository.AuditLogRepository
+	APIKeys       repository.APIKeyRepository
+	TwoFactor     repository.TwoFactorRepository
+	Webhooks      repository.WebhookRepository
+	Identities    repository.OAuthIdentityRepository
+	Outbox        repository.OutboxRepository
+	Preferences   repository.PreferencesRepository
+	Roles         repository.RoleRepository
+	Transactor    repository.Transactor
+}
+
+// NewPostgresRepositories returns the repositories backed by pool, reading
+// users from replica when it is not nil
+func NewPostgresRepositories(pool, replica *pgxpool.Pool) Repositories {
+	return Repositories{
+		Users:         repository.NewPostgresUserRepository(pool, replica),
+		RefreshTokens: repository.NewPostgresRefreshTokenRepository(pool),
+		Verifications: repository.NewPostgresEmailVerificationRepository(pool),
+		Resets:        repository.NewPostgresPasswordResetRepository(pool),
+		Idempotency:   repository.NewPostgresIdempotencyRepository(pool),
+		AuditLogs:     repository.NewPostgresAuditLogRepository(pool),
+		APIKeys:       repository.NewPostgresAPIKeyRepository(pool),
+		TwoFactor:     repository.NewPostgresTwoFactorRepository(pool),
+		Webhooks:      repository.NewPostgresWebhookRepository(pool),
+		Identities:    repository.NewPostgresOAuthIdentityRepository(pool),
+		Outbox:        repository.NewPostgresOutboxRepository(pool),
+		Preferences:   repository.NewPostgresPreferencesRepository(pool),
+		Roles:         repository.NewPostgresRoleRepository(pool),
+		Transactor:    repository.NewPostgresTransactor(pool),
+	}
+}
+
+// NewInMemoryRepositories returns repositories held in process memory
+func NewInMemoryRepositories() Repositories {
+	return Repositories{
+		Users:         repository.NewInMemoryUserRepository(),
+		RefreshTokens: repository.NewInMemoryRefreshTokenRepository(),
+		Verifications: repository.NewInMemoryEmailVerificationRepository(),
+		Resets:        repository.NewInMemoryPasswordResetRepository(),
+		Idempotency:   repository.NewInMemoryIdempotencyRepository(),
+		AuditLogs:     repository.NewInMemoryAuditLogRepository(),
+		APIKeys:       repository.NewInMemoryAPIKeyRepository(),
+		TwoFactor:     repository.NewInMemoryTwoFactorRepository(),
+		Webhooks:      repository.NewInMemoryWebhookRepository(),
+		Identities:    repository.NewInMemoryOAuthIdentityRepository(),
+		Outbox:        repository.NewInMemoryOutboxRepository(),
+		Preferences:   repository.NewInMemoryPreferencesRepository(),
+		Roles:         repository.NewInMemoryRoleRepository(),
+		Transactor:    repository.NewInMemoryTransactor(),
+	}
+}
+
+// Pinger is implemented by databases the health checks can reach
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Options holds the parts of the application the caller provides rather
+// than the configuration
+type Options struct {
+	// Keys sign and verify access tokens
+	Keys *auth.KeySet
+	// Store holds rate limits, lockouts, and revoked tokens; in memory when nil
+	Store kvstore.Store
+	// DB is pinged by the health checks; nil without a database
+	DB Pinger
+	// Mailer replaces the transport cfg.Mail selects when set
+	Mailer mailer.Mailer
+	// OAuthProviders replaces the providers cfg.OAuth enables when set
+	OAuthProviders map[string]oauth.Provider
+}
+
+// App is the application assembled from its configuration: the services
+// the handlers are wired to and the workers running behind them
+type App struct {
+	Repositories Repositories
+	Store        kvstore.Store
+	DB           Pinger
+	Features     *featureflag.Flags
+	Tokens       *auth.TokenManager
+	// Dispatcher and Mailer run in the background until Close
+	Dispatcher *webhook.Dispatcher
+	Mailer     *mailer.RetryingMailer
+
+	Users         *service.UserService
+	Sessions      *service.SessionService
+	TwoFactor     *service.TwoFactorService
+	OAuth         *service.OAuthService
+	Avatars       *service.AvatarService
+	Preferences   *service.PreferencesService
+	Impersonation *service.ImpersonationService
+	Permissions   *service.PermissionService
+	Erasure       *service.ErasureService
+	APIKeys       *service.APIKeyService
+	Webhooks      *service.WebhookService
+	// UploadsDir holds the avatars of local storage, empty when they are
+	// stored elsewhere
+	UploadsDir string
+
+	// pruner deletes expired idempotency keys until Close
+	pruner *pruner
+}
+
+// New builds the services on repos as cfg configures them and starts their
+// background workers
+func New(cfg *config.Config, repos Repositories, opts Options) (*App, error) {
+	features, err := featureflag.New(cfg.FeatureFlags)
+	if err != nil {
+		return nil, fmt.Errorf("load feature flags: %w", err)
+	}
+
+	a := &App{
+		Repositories: repos,
+		Store:        opts.Store,
+		DB:           opts.DB,
+		Features:     features,
+		Tokens:       auth.NewTokenManager(opts.Keys),
+	}
+	if a.Store == nil {
+		a.Store = kvstore.NewMemory()
+	}
+
+	mailTransport := opts.Mailer
+	if mailTransport == nil {
+		if mailTransport, err = newMailer(cfg.Mail); err != nil {
+			return nil, err
+		}
+	}
+
+	var avatarStorage storage.Storage
+	switch cfg.Storage.Driver {
+	case "s3":
+		slog.Warn("S3 storage is not implemented yet, avatar uploads will fail")
+		avatarStorage = storage.NewS3(cfg.Storage.S3Endpoint, cfg.Storage.S3Bucket, cfg.Storage.S3Region)
+	default:
+		local, err := storage.NewLocal(cfg.Storage.LocalDir, cfg.Server.BaseURL+"/uploads")
+		if err != nil {
+			return nil, fmt.Errorf("set up file storage: %w", err)
+		}
+		avatarStorage = local
+		a.UploadsDir = cfg.Storage.LocalDir
+	}
+
+	oauthProviders := opts.OAuthProviders
+	if oauthProviders == nil {
+		oauthProviders = make(map[string]oauth.Provider)
+		if cfg.OAuth.GoogleEnabled() {
+			oauthProviders["google"] = oauth.NewGoogle(cfg.OAuth.GoogleClientID, cfg.OAuth.GoogleClientSecret, cfg.OAuth.GoogleRedirectURL)
+		}
+	}
+
+	userRepo := repos.Users
+	if cfg.Cache.Enabled {
+		userRepo = repository.NewCachingUserRepository(userRepo, cfg.Cache.MaxEntries, cfg.Cache.TTL)
+	}
+
+	a.Sessions = service.NewSessionService(
+		userRepo, repos.RefreshTokens, a.Tokens, auth.NewDenylist(a.Store), cfg.JWT.TTL, cfg.JWT.RefreshTTL,
+	)
+	auditLogger := audit.NewLogger(repos.AuditLogs)
+	a.Dispatcher = webhook.NewDispatcher(repos.Webhooks, repos.Outbox, repos.Transactor, webhook.Settings{
+		QueueSize:         cfg.Webhook.QueueSize,
+		Workers:           cfg.Webhook.Workers,
+		MaxAttempts:       cfg.Webhook.MaxAttempts,
+		Timeout:           cfg.Webhook.Timeout,
+		Backoff:           cfg.Webhook.RetryBackoff,
+		PollInterval:      cfg.Webhook.OutboxPollInterval,
+		PollBatch:         cfg.Webhook.OutboxBatchSize,
+		OutboxMaxAttempts: cfg.Webhook.OutboxMaxAttempts,
+	})
+	a.Mailer = mailer.NewRetryingMailer(mailTransport, mailer.RetrySettings{
+		QueueSize: cfg.Mail.RetryQueueSize,
+		Attempts:  cfg.Mail.RetryAttempts,
+		Backoff:   cfg.Mail.RetryBackoff,
+	})
+	a.Webhooks = service.NewWebhookService(repos.Webhooks, auditLogger)
+	a.APIKeys = service.NewAPIKeyService(repos.APIKeys, auditLogger)
+	a.Users = service.NewUserService(
+		userRepo, repos.Transactor, repos.Verifications, repos.Resets, a.Sessions, a.Mailer, auditLogger, a.Dispatcher,
+		service.UserSettings{
+			VerificationTTL:      cfg.Auth.EmailVerificationTTL,
+			ResetTTL:             cfg.Auth.PasswordResetTTL,
+			RequireVerifiedEmail: cfg.Auth.RequireEmailVerification,
+			LockoutThreshold:     cfg.Auth.LockoutThreshold,
+			LockoutDuration:      cfg.Auth.LockoutDuration,
+			LoginThrottle:        ratelimit.NewSlidingWindow(a.Store, "ratelimit:email", cfg.RateLimit.LoginEmailMax, cfg.RateLimit.LoginEmailWindow),
+			LockoutStore:         a.Store,
+			BaseURL:              cfg.Server.BaseURL,
+		},
+	)
+	a.TwoFactor = service.NewTwoFactorService(userRepo, repos.TwoFactor, a.Tokens, auditLogger, service.TwoFactorSettings{
+		Issuer:           cfg.Auth.TOTPIssuer,
+		MFATokenTTL:      cfg.Auth.MFATokenTTL,
+		LockoutThreshold: cfg.Auth.LockoutThreshold,
+		LockoutDuration:  cfg.Auth.LockoutDuration,
+		UsedTokenStore:   a.Store,
+	})
+	a.OAuth = service.NewOAuthService(
+		oauthProviders, userRepo, repos.Transactor, repos.Identities, a.Sessions, a.Tokens, auditLogger, a.Dispatcher, cfg.OAuth.StateTTL,
+	)
+	a.Avatars = service.NewAvatarService(userRepo, repos.Transactor, avatarStorage, auditLogger, a.Dispatcher, service.AvatarSettings{
+		MaxSize:      cfg.Avatar.MaxSize,
+		MaxDimension: cfg.Avatar.MaxDimension,
+		Downscale:    cfg.Avatar.Downscale,
+	})
+	a.Preferences = service.NewPreferencesService(repos.Preferences, auditLogger)
+	a.Impersonation = service.NewImpersonationService(userRepo, a.Tokens, auditLogger, cfg.Auth.ImpersonationTTL)
+	a.Permissions = service.NewPermissionService(repos.Roles, cfg.Auth.PermissionCacheTTL)
+	a.Erasure = service.NewErasureService(userRepo, repos.Transactor, a.Sessions, a.Avatars, auditLogger, a.Dispatcher)
+	a.pruner = startPruner(repos.Idempotency, idempotencyPruneInterval)
+
+	return a, nil
+}
+
+// Close stops pruning and lets queued webhook deliveries and email retries
+// finish, or ctx end
+func (a *App) Close(ctx context.Context) error {
+	return errors.Join(a.pruner.Close(ctx), a.Dispatcher.Close(ctx), a.Mailer.Close(ctx))
+}
+
+// newMailer returns the mail transport cfg selects
+func newMailer(cfg config.MailConfig) (mailer.Mailer, error) {
+	if cfg.Driver != "smtp" {
+		return mailer.NewLogMailer(), nil
+	}
+
+	smtpMailer, err := mailer.NewSMTPMailer(mailer.SMTPSettings{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.From,
+		Timeout:  cfg.SMTPTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("set up smtp mailer: %w", err)
+	}
+
+	return smtpMailer, nil
+}
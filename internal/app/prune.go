@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// idempotencyPruneInterval is how often expired idempotency keys are deleted
+const idempotencyPruneInterval = time.Minute
+
+// idempotencyPruneBatch bounds each delete, so clearing a backlog never
+// holds many rows locked at once
+const idempotencyPruneBatch = 1000
+
+// pruneTimeout bounds each delete
+const pruneTimeout = 5 * time.Second
+
+// pruner deletes expired idempotency keys in the background until stopped
+type pruner struct {
+	keys     repository.IdempotencyRepository
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// startPruner starts deleting expired keys every interval
+func startPruner(keys repository.IdempotencyRepository, interval time.Duration) *pruner {
+	p := &pruner{keys: keys, interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+	go p.run()
+
+	return p
+}
+
+// run prunes on every tick until Close
+func (p *pruner) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.prune()
+		}
+	}
+}
+
+// prune deletes expired keys a batch at a time until a batch comes back
+// short or Close is called
+func (p *pruner) prune() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), pruneTimeout)
+		deleted, err := p.keys.DeleteExpired(ctx, idempotencyPruneBatch)
+		cancel()
+		if err != nil {
+			slog.Error("failed to delete expired idempotency keys", slog.Any("error", err))
+			return
+		}
+		if deleted < idempotencyPruneBatch {
+			return
+		}
+
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+	}
+}
+
+// Close stops the pruner and waits for a running delete to finish or ctx to end
+func (p *pruner) Close(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+func TestPrunerDeletesExpiredKeys(t *testing.T) {
+	ctx := context.Background()
+	keys := repository.NewInMemoryIdempotencyRepository()
+	for key, expiresAt := range map[string]time.Time{
+		"expired": time.Now().UTC().Add(-time.Second),
+		"live":    time.Now().UTC().Add(time.Hour),
+	} {
+		if _, _, err := keys.Reserve(ctx, &domain.IdempotencyKey{Owner: "user:ada", Key: key, ExpiresAt: expiresAt}); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+	}
+
+	p := startPruner(keys, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if err := p.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Only the live key was left to delete
+	if deleted, err := keys.DeleteExpired(ctx, 10); err != nil || deleted != 0 {
+		t.Errorf("expired keys left = %d, %v; want none", deleted, err)
+	}
+	if _, reserved, err := keys.Reserve(ctx, &domain.IdempotencyKey{Owner: "user:ada", Key: "live"}); err != nil || reserved {
+		t.Errorf("live key reserved again = %v, %v; want it kept", reserved, err)
+	}
+}
@@ -0,0 +1,138 @@
+package apperror
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Generic error codes used when no more specific code applies
+const (
+	CodeBadRequest       = "BAD_REQUEST"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	CodeConflict         = "CONFLICT"
+	CodeValidation       = "VALIDATION_ERROR"
+	CodeTooManyRequests  = "TOO_MANY_REQUESTS"
+	CodeInternal         = "INTERNAL_ERROR"
+	CodeTimeout          = "REQUEST_TIMEOUT"
+	CodeUnavailable      = "SERVICE_UNAVAILABLE"
+)
+
+// APIError is an error with a stable code and a message safe to show clients
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+	// Err is the underlying cause; it is logged but never sent to clients
+	Err error
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the underlying cause
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails returns a copy of the error carrying extra client-facing details
+func (e *APIError) WithDetails(details any) *APIError {
+	clone := *e
+	clone.Details = details
+	return &clone
+}
+
+// New creates an APIError with the given HTTP status, code, and message
+func New(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// BadRequest creates a 400 error
+func BadRequest(code, message string) *APIError {
+	return New(http.StatusBadRequest, code, message)
+}
+
+// Unauthorized creates a 401 error
+func Unauthorized(code, message string) *APIError {
+	return New(http.StatusUnauthorized, code, message)
+}
+
+// Forbidden creates a 403 error
+func Forbidden(code, message string) *APIError {
+	return New(http.StatusForbidden, code, message)
+}
+
+// NotFound creates a 404 error
+func NotFound(code, message string) *APIError {
+	return New(http.StatusNotFound, code, message)
+}
+
+// Conflict creates a 409 error
+func Conflict(code, message string) *APIError {
+	return New(http.StatusConflict, code, message)
+}
+
+// PreconditionFailed creates a 412 error
+func PreconditionFailed(code, message string) *APIError {
+	return New(http.StatusPreconditionFailed, code, message)
+}
+
+// UnprocessableEntity creates a 422 error
+func UnprocessableEntity(code, message string) *APIError {
+	return New(http.StatusUnprocessableEntity, code, message)
+}
+
+// Locked creates a 423 error
+func Locked(code, message string) *APIError {
+	return New(http.StatusLocked, code, message)
+}
+
+// PreconditionRequired creates a 428 error
+func PreconditionRequired(code, message string) *APIError {
+	return New(http.StatusPreconditionRequired, code, message)
+}
+
+// TooManyRequests creates a 429 error
+func TooManyRequests(code, message string) *APIError {
+	return New(http.StatusTooManyRequests, code, message)
+}
+
+// GatewayTimeout wraps an error caused by the request deadline in a 504
+func GatewayTimeout(err error) *APIError {
+	return &APIError{
+		Status:  http.StatusGatewayTimeout,
+		Code:    CodeTimeout,
+		Message: "request timed out",
+		Err:     err,
+	}
+}
+
+// ServiceUnavailable wraps an error caused by a dependency that is briefly
+// unreachable in a 503, telling the client to retry
+func ServiceUnavailable(err error) *APIError {
+	return &APIError{
+		Status:  http.StatusServiceUnavailable,
+		Code:    CodeUnavailable,
+		Message: "service temporarily unavailable, retry later",
+		Err:     err,
+	}
+}
+
+// Internal wraps an unexpected error in a generic 500 that hides the cause
+func Internal(err error) *APIError {
+	return &APIError{
+		Status:  http.StatusInternalServerError,
+		Code:    CodeInternal,
+		Message: "internal server error",
+		Err:     err,
+	}
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// requestIDKey is the context key holding the request id
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the id of the current request
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// requestIDFromContext returns the request id stored in ctx, or ""
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// clientIPKey is the context key holding the client IP
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the address of the client
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// clientIPFromContext returns the client IP stored in ctx, or ""
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// Logger records audit entries, taking the actor, request id, and client IP
+// from the context
+type Logger struct {
+	logs repository.AuditLogRepository
+}
+
+// NewLogger creates a Logger writing to the given repository
+func NewLogger(logs repository.AuditLogRepository) *Logger {
+	return &Logger{logs: logs}
+}
+
+// Record writes an entry for an action the authenticated caller performed on
+// the target user. Audit failures are logged rather than returned, so they
+// never undo or fail the action itself. While an admin impersonates a user,
+// the admin is recorded as the actor and the user as impersonated.
+func (l *Logger) Record(ctx context.Context, action domain.AuditAction, targetID string) {
+	identity, _ := auth.IdentityFromContext(ctx)
+	if identity.ActorID != "" {
+		l.record(ctx, identity.ActorID, identity.UserID, action, targetID)
+		return
+	}
+	l.RecordAs(ctx, identity.UserID, action, targetID)
+}
+
+// RecordAs writes an entry with an explicit actor, for actions taken before
+// the caller is authenticated, such as logging in
+func (l *Logger) RecordAs(ctx context.Context, actorID string, action domain.AuditAction, targetID string) {
+	l.record(ctx, actorID, "", action, targetID)
+}
+
+// record writes an entry for the actor impersonating no one or impersonatedID
+func (l *Logger) record(ctx context.Context, actorID, impersonatedID string, action domain.AuditAction, targetID string) {
+	// Ids taken from request parameters may share memory the HTTP server
+	// reuses once the request ends, so copy them before storing
+	entry := &domain.AuditLog{
+		ActorID:        strings.Clone(actorID),
+		ImpersonatedID: strings.Clone(impersonatedID),
+		Action:         action,
+		TargetID:       strings.Clone(targetID),
+		RequestID:      strings.Clone(requestIDFromContext(ctx)),
+		IP:             strings.Clone(clientIPFromContext(ctx)),
+	}
+
+	// Record even if the client has gone away, as the action already happened
+	if err := l.logs.Create(context.WithoutCancel(ctx), entry); err != nil {
+		logger.FromContext(ctx).Error("failed to write audit log",
+			slog.String("action", string(action)),
+			slog.String("target_id", targetID),
+			slog.Any("error", err),
+		)
+	}
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// failingAuditLogRepository refuses every entry
+type failingAuditLogRepository struct {
+	repository.AuditLogRepository
+}
+
+func (failingAuditLogRepository) Create(context.Context, *domain.AuditLog) error {
+	return errors.New("database is down")
+}
+
+// latestEntry returns the newest entry in logs
+func latestEntry(t *testing.T, logs repository.AuditLogRepository) *domain.AuditLog {
+	t.Helper()
+
+	entries, _, err := logs.List(context.Background(), repository.AuditLogFilter{}, 1, 1)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("no audit entry was written")
+	}
+
+	return entries[0]
+}
+
+func TestRecord(t *testing.T) {
+	logs := repository.NewInMemoryAuditLogRepository()
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{UserID: "admin-id"})
+	ctx = WithClientIP(WithRequestID(ctx, "req-1"), "203.0.113.7")
+
+	NewLogger(logs).Record(ctx, domain.AuditUserCreate, "user-id")
+
+	got := latestEntry(t, logs)
+	want := domain.AuditLog{ActorID: "admin-id", Action: domain.AuditUserCreate, TargetID: "user-id", RequestID: "req-1", IP: "203.0.113.7"}
+	got.ID, got.CreatedAt = "", want.CreatedAt
+	if *got != want {
+		t.Errorf("entry = %+v, want %+v", *got, want)
+	}
+}
+
+func TestRecordImpersonated(t *testing.T) {
+	logs := repository.NewInMemoryAuditLogRepository()
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{UserID: "user-id", ActorID: "admin-id"})
+
+	NewLogger(logs).Record(ctx, domain.AuditUserUpdate, "user-id")
+
+	if got := latestEntry(t, logs); got.ActorID != "admin-id" || got.ImpersonatedID != "user-id" {
+		t.Errorf("entry actor = %q impersonating %q, want the admin impersonating the user", got.ActorID, got.ImpersonatedID)
+	}
+}
+
+func TestRecordFailureIsNotFatal(t *testing.T) {
+	// Record has no error to return; it must only log the failure
+	NewLogger(failingAuditLogRepository{}).RecordAs(context.Background(), "user-id", domain.AuditUserLogin, "user-id")
+}
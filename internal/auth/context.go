@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	UserID string
+	Roles  domain.Roles
+	// SessionID is set for callers authenticated with an access token
+	SessionID string
+	// ActorID is the admin impersonating UserID, if any; authorization
+	// follows UserID while audit entries name the actor
+	ActorID string
+}
+
+// identityKey is the context key holding the request's Identity
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the authenticated caller
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the authenticated caller stored in ctx, if any
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+)
+
+// Denylist revokes access tokens before they expire. Entries live in a
+// kvstore.Store only as long as the tokens they refuse could, so it cleans
+// itself up.
+type Denylist struct {
+	store kvstore.Store
+}
+
+// NewDenylist creates a Denylist keeping its entries in store
+func NewDenylist(store kvstore.Store) *Denylist {
+	return &Denylist{store: store}
+}
+
+// Revoke refuses the token with these claims for the rest of its lifetime.
+// Tokens without a jti or already expired need no entry.
+func (d *Denylist) Revoke(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return d.store.SetWithTTL(ctx, denylistTokenKey(claims.ID), "1", ttl)
+}
+
+// RevokeSession refuses every token issued to the session so far. ttl is the
+// access token lifetime, after which none of them is valid anyway.
+func (d *Denylist) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	if sessionID == "" {
+		return nil
+	}
+
+	return d.store.SetWithTTL(ctx, denylistSessionKey(sessionID), "1", ttl)
+}
+
+// Revoked reports whether the token or its session has been revoked
+func (d *Denylist) Revoked(ctx context.Context, claims *Claims) (bool, error) {
+	if claims.ID != "" {
+		if revoked, err := d.has(ctx, denylistTokenKey(claims.ID)); revoked || err != nil {
+			return revoked, err
+		}
+	}
+	if claims.SessionID != "" {
+		return d.has(ctx, denylistSessionKey(claims.SessionID))
+	}
+
+	return false, nil
+}
+
+// has reports whether the store holds key
+func (d *Denylist) has(ctx context.Context, key string) (bool, error) {
+	_, err := d.store.Get(ctx, key)
+	if errors.Is(err, kvstore.ErrNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// denylistTokenKey names the entry revoking one token
+func denylistTokenKey(jti string) string {
+	return "denylist:jti:" + jti
+}
+
+// denylistSessionKey names the entry revoking the tokens of a session
+func denylistSessionKey(sessionID string) string {
+	return "denylist:sid:" + sessionID
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// claimsFor returns the claims of a token with the jti and session that
+// expires after ttl
+func claimsFor(jti, sessionID string, ttl time.Duration) *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ID: jti, ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl))},
+		SessionID:        sessionID,
+	}
+}
+
+// revoked reports whether denylist refuses claims, failing t on error
+func revoked(t *testing.T, denylist *Denylist, claims *Claims) bool {
+	t.Helper()
+
+	got, err := denylist.Revoked(context.Background(), claims)
+	if err != nil {
+		t.Fatalf("Revoked: %v", err)
+	}
+
+	return got
+}
+
+func TestGenerateTokenHasUniqueID(t *testing.T) {
+	m := newHMACManager(t, "a", map[string][]byte{"a": []byte("secret-a")})
+
+	seen := make(map[string]bool)
+	for range 10 {
+		claims, err := m.ParseToken(issue(t, m))
+		if err != nil {
+			t.Fatalf("ParseToken: %v", err)
+		}
+		if claims.ID == "" || seen[claims.ID] {
+			t.Fatalf("token jti = %q, want a new one for every token", claims.ID)
+		}
+		seen[claims.ID] = true
+	}
+}
+
+func TestDenylistRevoke(t *testing.T) {
+	ctx := context.Background()
+	denylist := NewDenylist(kvstore.NewMemory())
+	token := claimsFor("jti-1", "session-1", time.Minute)
+	sibling := claimsFor("jti-2", "session-1", time.Minute)
+
+	if revoked(t, denylist, token) {
+		t.Fatal("token revoked before Revoke")
+	}
+	if err := denylist.Revoke(ctx, token); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if !revoked(t, denylist, token) {
+		t.Error("revoked token is accepted")
+	}
+	// Revoking one token leaves the others of its session alone
+	if revoked(t, denylist, sibling) {
+		t.Error("another token of the session is refused")
+	}
+}
+
+func TestDenylistRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	denylist := NewDenylist(kvstore.NewMemory())
+
+	if err := denylist.RevokeSession(ctx, "session-1", time.Minute); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	for _, claims := range []*Claims{claimsFor("jti-1", "session-1", time.Minute), claimsFor("jti-2", "session-1", time.Minute)} {
+		if !revoked(t, denylist, claims) {
+			t.Errorf("token %s of the revoked session is accepted", claims.ID)
+		}
+	}
+	if revoked(t, denylist, claimsFor("jti-3", "session-2", time.Minute)) {
+		t.Error("token of another session is refused")
+	}
+}
+
+func TestDenylistForgetsExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	store := kvstore.NewMemory()
+	denylist := NewDenylist(store)
+	token := claimsFor("jti-1", "", 50*time.Millisecond)
+
+	if err := denylist.Revoke(ctx, token); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := denylist.RevokeSession(ctx, "session-1", 50*time.Millisecond); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// The entries went with the tokens they refused
+	for _, key := range []string{denylistTokenKey("jti-1"), denylistSessionKey("session-1")} {
+		if _, err := store.Get(ctx, key); !errors.Is(err, kvstore.ErrNotFound) {
+			t.Errorf("entry %s after expiry error = %v, want %v", key, err, kvstore.ErrNotFound)
+		}
+	}
+	if revoked(t, denylist, token) || revoked(t, denylist, claimsFor("jti-2", "session-1", time.Minute)) {
+		t.Error("denylist still refuses after its entries expired")
+	}
+}
+
+// countingStore is a Memory store counting its writes
+type countingStore struct {
+	*kvstore.Memory
+	writes int
+}
+
+func (s *countingStore) SetWithTTL(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.writes++
+	return s.Memory.SetWithTTL(ctx, key, value, ttl)
+}
+
+func TestDenylistSkipsTokensWithoutEntries(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{Memory: kvstore.NewMemory()}
+	denylist := NewDenylist(store)
+
+	for _, claims := range []*Claims{
+		{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}},
+		claimsFor("expired", "", -time.Minute),
+		{RegisteredClaims: jwt.RegisteredClaims{ID: "no-expiry"}},
+	} {
+		if err := denylist.Revoke(ctx, claims); err != nil {
+			t.Fatalf("Revoke: %v", err)
+		}
+	}
+	if err := denylist.RevokeSession(ctx, "", time.Minute); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if store.writes != 0 {
+		t.Errorf("denylist stored %d entries, want none for tokens that need no entry", store.writes)
+	}
+}
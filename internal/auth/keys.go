@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported access token signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still accepted with, each named by the kid put in the token header.
+// Keeping the previous key in the set after a rotation lets the tokens it
+// signed live out their lifetime.
+type KeySet struct {
+	method    jwt.SigningMethod
+	currentID string
+	signing   any
+	verifying map[string]any
+}
+
+// NewHMACKeySet creates an HS256 KeySet that signs with the secret of
+// currentID and verifies with any of secrets
+func NewHMACKeySet(currentID string, secrets map[string][]byte) (*KeySet, error) {
+	secret, ok := secrets[currentID]
+	if !ok {
+		return nil, fmt.Errorf("no secret for the current key %q", currentID)
+	}
+
+	verifying := make(map[string]any, len(secrets))
+	for kid, s := range secrets {
+		if len(s) == 0 {
+			return nil, fmt.Errorf("key %q: empty secret", kid)
+		}
+		verifying[kid] = s
+	}
+
+	return &KeySet{method: jwt.SigningMethodHS256, currentID: currentID, signing: secret, verifying: verifying}, nil
+}
+
+// NewRSAKeySet creates an RS256 KeySet from PEM-encoded keys. The key of
+// currentID must be a private key; the others only verify, so their public
+// keys are enough.
+func NewRSAKeySet(currentID string, pems map[string][]byte) (*KeySet, error) {
+	pem, ok := pems[currentID]
+	if !ok {
+		return nil, fmt.Errorf("no key for the current key %q", currentID)
+	}
+	private, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("key %q must be an RSA private key: %w", currentID, err)
+	}
+
+	verifying := make(map[string]any, len(pems))
+	for kid, pem := range pems {
+		if kid == currentID {
+			verifying[kid] = &private.PublicKey
+			continue
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			// A retired signing key may still be configured as its private key
+			key, privateErr := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if privateErr != nil {
+				return nil, fmt.Errorf("key %q: %w", kid, err)
+			}
+			public = &key.PublicKey
+		}
+		verifying[kid] = public
+	}
+
+	return &KeySet{method: jwt.SigningMethodRS256, currentID: currentID, signing: private, verifying: verifying}, nil
+}
+
+// sign signs the claims with the current key, naming it in the kid header
+func (k *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.currentID
+
+	signed, err := token.SignedString(k.signing)
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+
+	return signed, nil
+}
+
+// verificationKey returns the key named by the token's kid. Tokens without
+// one were signed before key ids were introduced and are checked against the
+// current key.
+func (k *KeySet) verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return k.verifying[k.currentID], nil
+	}
+
+	key, ok := k.verifying[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	return key, nil
+}
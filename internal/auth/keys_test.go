@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newHMACManager returns a TokenManager signing with currentID's secret
+func newHMACManager(t *testing.T, currentID string, secrets map[string][]byte) *TokenManager {
+	t.Helper()
+
+	keys, err := NewHMACKeySet(currentID, secrets)
+	if err != nil {
+		t.Fatalf("NewHMACKeySet: %v", err)
+	}
+
+	return NewTokenManager(keys)
+}
+
+// issue returns a fresh access token from m
+func issue(t *testing.T, m *TokenManager) string {
+	t.Helper()
+
+	token, err := m.GenerateToken("user-1", domain.Roles{domain.RoleUser}, "session-1", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	return token
+}
+
+// kidOf returns the kid header of token without verifying it
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+
+	return kid
+}
+
+func TestHMACKeyRotation(t *testing.T) {
+	oldSecret, newSecret := []byte("old-secret"), []byte("new-secret")
+	before := newHMACManager(t, "2025-01", map[string][]byte{"2025-01": oldSecret})
+	token := issue(t, before)
+	if kid := kidOf(t, token); kid != "2025-01" {
+		t.Errorf("kid = %q, want %q", kid, "2025-01")
+	}
+
+	during := newHMACManager(t, "2025-02", map[string][]byte{"2025-01": oldSecret, "2025-02": newSecret})
+	if _, err := during.ParseToken(token); err != nil {
+		t.Errorf("token of a still active key was rejected: %v", err)
+	}
+	if kid := kidOf(t, issue(t, during)); kid != "2025-02" {
+		t.Errorf("kid of a new token = %q, want the current %q", kid, "2025-02")
+	}
+
+	after := newHMACManager(t, "2025-02", map[string][]byte{"2025-02": newSecret})
+	if _, err := after.ParseToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token of a removed key error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestTokenWithoutKidUsesCurrentKey(t *testing.T) {
+	secret := []byte("current-secret")
+	m := newHMACManager(t, "2025-01", map[string][]byte{"2025-01": secret})
+
+	claims := Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject: "user-1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	if _, err := m.ParseToken(token); err != nil {
+		t.Errorf("token without a kid was rejected: %v", err)
+	}
+}
+
+func TestNewHMACKeySetErrors(t *testing.T) {
+	if _, err := NewHMACKeySet("missing", map[string][]byte{"other": []byte("secret")}); err == nil {
+		t.Error("NewHMACKeySet without the current key succeeded")
+	}
+	if _, err := NewHMACKeySet("current", map[string][]byte{"current": nil}); err == nil {
+		t.Error("NewHMACKeySet with an empty secret succeeded")
+	}
+}
+
+// rsaPEMs returns a new RSA key as a PKCS #1 private key PEM and a PKIX
+// public key PEM
+func rsaPEMs(t *testing.T) (private, public []byte) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+
+	private = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	public = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	return private, public
+}
+
+func TestRSAKeyRotation(t *testing.T) {
+	oldPrivate, oldPublic := rsaPEMs(t)
+	newPrivate, _ := rsaPEMs(t)
+
+	before, err := NewRSAKeySet("2025-01", map[string][]byte{"2025-01": oldPrivate})
+	if err != nil {
+		t.Fatalf("NewRSAKeySet: %v", err)
+	}
+	token := issue(t, NewTokenManager(before))
+
+	// The retired key only needs its public half to keep verifying
+	during, err := NewRSAKeySet("2025-02", map[string][]byte{"2025-01": oldPublic, "2025-02": newPrivate})
+	if err != nil {
+		t.Fatalf("NewRSAKeySet: %v", err)
+	}
+	if _, err := NewTokenManager(during).ParseToken(token); err != nil {
+		t.Errorf("token of a still active key was rejected: %v", err)
+	}
+
+	after, err := NewRSAKeySet("2025-02", map[string][]byte{"2025-02": newPrivate})
+	if err != nil {
+		t.Fatalf("NewRSAKeySet: %v", err)
+	}
+	if _, err := NewTokenManager(after).ParseToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token of a removed key error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	if _, err := NewRSAKeySet("2025-01", map[string][]byte{"2025-01": oldPublic}); err == nil {
+		t.Error("NewRSAKeySet with a public current key succeeded")
+	}
+}
+
+func TestRSAKeySetRejectsHMACTokens(t *testing.T) {
+	private, public := rsaPEMs(t)
+	keys, err := NewRSAKeySet("rsa", map[string][]byte{"rsa": private})
+	if err != nil {
+		t.Fatalf("NewRSAKeySet: %v", err)
+	}
+
+	// Signing with the public key as an HMAC secret is the classic algorithm confusion
+	forged := newHMACManager(t, "rsa", map[string][]byte{"rsa": public})
+	if _, err := NewTokenManager(keys).ParseToken(issue(t, forged)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("HS256 token error = %v, want %v", err, ErrInvalidToken)
+	}
+}
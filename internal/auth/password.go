@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultBcryptCost is the bcrypt work factor used unless configured otherwise
+const DefaultBcryptCost = 12
+
+var bcryptCost = DefaultBcryptCost
+
+// SetBcryptCost changes the bcrypt work factor used by HashPassword
+func SetBcryptCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	bcryptCost = cost
+	return nil
+}
+
+// HashPassword returns the bcrypt hash of a plaintext password
+func HashPassword(plain string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plain), bcryptCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// CheckPassword reports whether the plaintext password matches the hash
+func CheckPassword(hash, plain string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// CompareDummyPassword performs a hash comparison that always fails, so that
+// lookups for unknown accounts take as long as a real password check
+func CompareDummyPassword(plain string) {
+	dummyHashOnce.Do(func() {
+		hash, err := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcryptCost)
+		if err == nil {
+			dummyHash = string(hash)
+		}
+	})
+
+	CheckPassword(dummyHash, plain)
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Password policy rules reported in PasswordPolicyError
+const (
+	RuleMinLength = "min_length"
+	RuleUppercase = "uppercase"
+	RuleLowercase = "lowercase"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleCommon    = "common"
+)
+
+//go:embed common_passwords.txt
+var builtinCommonPasswords string
+
+// PasswordPolicy lists the rules new passwords must satisfy
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// RejectCommon refuses passwords on the common passwords list
+	RejectCommon bool
+}
+
+// DefaultPasswordPolicy only requires eight characters and rejects common passwords
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8, RejectCommon: true}
+
+// PasswordViolation names a policy rule a password failed
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError is returned by ValidatePassword with every rule the
+// password failed
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+// Error implements the error interface
+func (e *PasswordPolicyError) Error() string {
+	rules := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		rules[i] = v.Rule
+	}
+
+	return "password violates policy: " + strings.Join(rules, ", ")
+}
+
+var (
+	passwordPolicy  = DefaultPasswordPolicy
+	commonPasswords = parseCommonPasswords(builtinCommonPasswords)
+)
+
+// SetPasswordPolicy changes the policy enforced by ValidatePassword
+func SetPasswordPolicy(policy PasswordPolicy) {
+	passwordPolicy = policy
+}
+
+// LoadCommonPasswords replaces the built-in common passwords list with the
+// file at path, which holds one password per line
+func LoadCommonPasswords(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("load common passwords: %w", err)
+	}
+
+	commonPasswords = parseCommonPasswords(string(data))
+	return nil
+}
+
+// parseCommonPasswords builds the lookup set of a password list; entries are
+// compared case-insensitively
+func parseCommonPasswords(list string) map[string]bool {
+	passwords := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			passwords[strings.ToLower(password)] = true
+		}
+	}
+
+	return passwords
+}
+
+// ValidatePassword checks a new password against the policy, returning a
+// *PasswordPolicyError listing every rule it fails
+func ValidatePassword(password string) error {
+	policy := passwordPolicy
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	var violations []PasswordViolation
+	add := func(rule, message string) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: message})
+	}
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		add(RuleMinLength, fmt.Sprintf("must be at least %d characters", policy.MinLength))
+	}
+	if policy.RequireUpper && !upper {
+		add(RuleUppercase, "must contain an uppercase letter")
+	}
+	if policy.RequireLower && !lower {
+		add(RuleLowercase, "must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !digit {
+		add(RuleDigit, "must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		add(RuleSymbol, "must contain a symbol")
+	}
+	if policy.RejectCommon && commonPasswords[strings.ToLower(password)] {
+		add(RuleCommon, "is too common")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// usePasswordPolicy enforces policy until the test ends
+func usePasswordPolicy(t *testing.T, policy PasswordPolicy) {
+	t.Helper()
+
+	previous := passwordPolicy
+	SetPasswordPolicy(policy)
+	t.Cleanup(func() { SetPasswordPolicy(previous) })
+}
+
+// violatedRules returns the rules err reports, or nil when err is nil
+func violatedRules(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("error = %v, want a *PasswordPolicyError", err)
+	}
+	rules := make([]string, len(policyErr.Violations))
+	for i, v := range policyErr.Violations {
+		if v.Message == "" {
+			t.Errorf("violation of %s has no message", v.Rule)
+		}
+		rules[i] = v.Rule
+	}
+
+	return rules
+}
+
+func TestValidatePassword(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		rules    []string
+	}{
+		{"meets every rule", strict, "Tr0ub4dor&3x", nil},
+		{"too short", strict, "Ab1!", []string{RuleMinLength}},
+		{"length counts characters, not bytes", PasswordPolicy{MinLength: 4}, "ééé", []string{RuleMinLength}},
+		{"no uppercase", strict, "tr0ub4dor&3x", []string{RuleUppercase}},
+		{"no lowercase", strict, "TR0UB4DOR&3X", []string{RuleLowercase}},
+		{"no digit", strict, "Troubador&xx", []string{RuleDigit}},
+		{"no symbol", strict, "Tr0ub4dor33x", []string{RuleSymbol}},
+		{"a space is a symbol", strict, "Tr0ub4dor 3x", nil},
+		{"every rule failed", strict, "", []string{RuleMinLength, RuleUppercase, RuleLowercase, RuleDigit, RuleSymbol}},
+		{"rules off", PasswordPolicy{MinLength: 1}, "abc", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePasswordPolicy(t, tt.policy)
+
+			if got := violatedRules(t, ValidatePassword(tt.password)); fmt.Sprint(got) != fmt.Sprint(tt.rules) {
+				t.Errorf("ValidatePassword(%q) violations = %v, want %v", tt.password, got, tt.rules)
+			}
+		})
+	}
+}
+
+func TestValidatePasswordRejectsCommonPasswords(t *testing.T) {
+	usePasswordPolicy(t, PasswordPolicy{MinLength: 8, RejectCommon: true})
+
+	for _, password := range []string{"12345678", "Password1"} {
+		if got := violatedRules(t, ValidatePassword(password)); fmt.Sprint(got) != fmt.Sprint([]string{RuleCommon}) {
+			t.Errorf("ValidatePassword(%q) violations = %v, want [common]", password, got)
+		}
+	}
+	if err := ValidatePassword("correct-horse-battery"); err != nil {
+		t.Errorf("ValidatePassword of an uncommon password: %v", err)
+	}
+
+	usePasswordPolicy(t, PasswordPolicy{MinLength: 8})
+	if err := ValidatePassword("12345678"); err != nil {
+		t.Errorf("ValidatePassword with the common check off: %v", err)
+	}
+}
+
+func TestLoadCommonPasswords(t *testing.T) {
+	previous := commonPasswords
+	t.Cleanup(func() { commonPasswords = previous })
+	usePasswordPolicy(t, PasswordPolicy{MinLength: 1, RejectCommon: true})
+
+	path := filepath.Join(t.TempDir(), "common.txt")
+	if err := os.WriteFile(path, []byte("hunter2\n\n  Tr0ub4dor  \n"), 0o600); err != nil {
+		t.Fatalf("write list: %v", err)
+	}
+	if err := LoadCommonPasswords(path); err != nil {
+		t.Fatalf("LoadCommonPasswords: %v", err)
+	}
+
+	for password, common := range map[string]bool{"hunter2": true, "tr0ub4dor": true, "12345678": false} {
+		if got := ValidatePassword(password) != nil; got != common {
+			t.Errorf("ValidatePassword(%q) rejected = %v, want %v", password, got, common)
+		}
+	}
+
+	if err := LoadCommonPasswords(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadCommonPasswords of a missing file succeeded")
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	const plain = "correct horse battery staple"
+
+	hash, err := HashPassword(plain)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if hash == plain || strings.Contains(hash, plain) {
+		t.Fatalf("hash %q contains the plaintext", hash)
+	}
+	if cost, err := bcrypt.Cost([]byte(hash)); err != nil || cost != bcryptCost {
+		t.Errorf("hash cost = %d, %v; want %d", cost, err, bcryptCost)
+	}
+
+	if !CheckPassword(hash, plain) {
+		t.Error("CheckPassword rejected the hashed password")
+	}
+	if CheckPassword(hash, plain+"!") {
+		t.Error("CheckPassword accepted a different password")
+	}
+
+	// Every hash has its own salt
+	again, err := HashPassword(plain)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if again == hash {
+		t.Error("hashing the same password twice gave the same hash")
+	}
+}
+
+func TestSetBcryptCost(t *testing.T) {
+	t.Cleanup(func() { bcryptCost = DefaultBcryptCost })
+
+	for _, cost := range []int{bcrypt.MinCost - 1, bcrypt.MaxCost + 1} {
+		if err := SetBcryptCost(cost); err == nil {
+			t.Errorf("SetBcryptCost(%d) succeeded, want an error", cost)
+		}
+	}
+
+	if err := SetBcryptCost(bcrypt.MinCost); err != nil {
+		t.Fatalf("SetBcryptCost(%d): %v", bcrypt.MinCost, err)
+	}
+	hash, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(hash)); cost != bcrypt.MinCost {
+		t.Errorf("hash cost = %d, want %d", cost, bcrypt.MinCost)
+	}
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken is returned when a token cannot be verified
+var ErrInvalidToken = errors.New("invalid token")
+
+// mfaAudience marks tokens that only prove the password step of a two-factor
+// login; access tokens carry no audience
+const mfaAudience = "mfa"
+
+// oauthStateAudience marks the state parameter of an OAuth sign-in
+const oauthStateAudience = "oauth_state"
+
+// Claims are the JWT claims carried by access tokens
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles domain.Roles `json:"roles"`
+	// Role is the single role of tokens issued before users held several
+	// and is read until they have expired
+	Role domain.Role `json:"role,omitempty"`
+	// SessionID names the login session the token was issued to
+	SessionID string `json:"sid,omitempty"`
+	// Actor is set on impersonation tokens and names the admin acting as
+	// the subject, following the act claim of RFC 8693
+	Actor *Actor `json:"act,omitempty"`
+}
+
+// Actor is the party acting on behalf of a token's subject
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// RoleSet returns the roles the token grants
+func (c *Claims) RoleSet() domain.Roles {
+	if c.Roles == nil && c.Role != "" {
+		return domain.Roles{c.Role}
+	}
+
+	return c.Roles
+}
+
+// ActorID returns the id of the admin impersonating the subject, or ""
+func (c *Claims) ActorID() string {
+	if c.Actor == nil {
+		return ""
+	}
+
+	return c.Actor.Subject
+}
+
+// TokenManager signs and verifies access tokens with a KeySet
+type TokenManager struct {
+	keys *KeySet
+}
+
+// NewTokenManager creates a TokenManager using the given keys
+func NewTokenManager(keys *KeySet) *TokenManager {
+	return &TokenManager{keys: keys}
+}
+
+// GenerateToken issues a signed access token for the user's session that
+// expires after ttl
+func (m *TokenManager) GenerateToken(userID string, roles domain.Roles, sessionID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			// The jti lets the Denylist revoke this token alone
+			ID:        uuid.NewString(),
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Roles:     roles,
+		SessionID: sessionID,
+	}
+
+	return m.keys.sign(claims)
+}
+
+// GenerateImpersonationToken issues an access token letting the actor act as
+// the user. It belongs to the actor's session, so signing the actor out ends
+// the impersonation too.
+func (m *TokenManager) GenerateImpersonationToken(userID string, roles domain.Roles, actorID, sessionID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Roles:     roles,
+		SessionID: sessionID,
+		Actor:     &Actor{Subject: actorID},
+	}
+
+	return m.keys.sign(claims)
+}
+
+// ParseToken verifies the token signature and expiry and returns its claims
+func (m *TokenManager) ParseToken(tokenString string) (*Claims, error) {
+	claims, err := m.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if len(claims.Audience) > 0 {
+		return nil, fmt.Errorf("%w: token is not an access token", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// GenerateMFAToken issues a token proving the user passed the password step
+// of a two-factor login; it cannot be used as an access token. Its jti lets
+// the second step refuse it once it has been used.
+func (m *TokenManager) GenerateMFAToken(userID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Subject:   userID,
+		Audience:  jwt.ClaimStrings{mfaAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	return m.keys.sign(claims)
+}
+
+// ParseMFAToken verifies a token from GenerateMFAToken and returns its
+// claims, whose subject is the user it was issued for
+func (m *TokenManager) ParseMFAToken(tokenString string) (*Claims, error) {
+	return m.parse(tokenString, jwt.WithAudience(mfaAudience))
+}
+
+// GenerateOAuthState issues the state parameter of an OAuth sign-in. It
+// carries nonce, which the caller also stores in the browser, so the callback
+// can tell it was started there.
+func (m *TokenManager) GenerateOAuthState(nonce string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   nonce,
+		Audience:  jwt.ClaimStrings{oauthStateAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	return m.keys.sign(claims)
+}
+
+// ParseOAuthState verifies a state from GenerateOAuthState and returns its nonce
+func (m *TokenManager) ParseOAuthState(state string) (string, error) {
+	claims, err := m.parse(state, jwt.WithAudience(oauthStateAudience))
+	if err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
+
+// parse verifies the token signature and expiry along with any extra checks
+func (m *TokenManager) parse(tokenString string, opts ...jwt.ParserOption) (*Claims, error) {
+	opts = append(opts, jwt.WithValidMethods([]string{m.keys.method.Alg()}), jwt.WithExpirationRequired())
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, m.keys.verificationKey, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
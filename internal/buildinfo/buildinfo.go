@@ -0,0 +1,47 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set at link time with
+// -ldflags "-X github.com/boonyarit-iamsaard/user-management-api/internal/buildinfo.Version=v1.2.3"
+// and likewise for Commit and BuildTime
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build metadata. Without ldflags, the commit and build time
+// fall back to the version control details go build embeds, when present.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "unknown":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "unknown":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,17 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	version, commit, buildTime := Version, Commit, BuildTime
+	t.Cleanup(func() { Version, Commit, BuildTime = version, commit, buildTime })
+
+	Version, Commit, BuildTime = "v1.2.3", "abc123", "2026-01-02T03:04:05Z"
+	want := Info{Version: "v1.2.3", Commit: "abc123", BuildTime: "2026-01-02T03:04:05Z", GoVersion: runtime.Version()}
+	if got := Get(); got != want {
+		t.Errorf("Get = %+v, want the values set at link time %+v", got, want)
+	}
+}
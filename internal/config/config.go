@@ -0,0 +1,895 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/featureflag"
+	"github.com/joho/godotenv"
+)
+
+// Supported application environments
+const (
+	EnvDevelopment = "development"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+)
+
+// JSON libraries the server can encode and decode with
+const (
+	JSONStdlib = "stdlib"
+	JSONGoccy  = "goccy"
+)
+
+// Config represents the application configuration
+type Config struct {
+	Env       string
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Auth      AuthConfig
+	Password  PasswordConfig
+	Log       LogConfig
+	CORS      CORSConfig
+	RateLimit RateLimitConfig
+	Tracing   TracingConfig
+	Cache     CacheConfig
+	Webhook   WebhookConfig
+	Compress  CompressConfig
+	Seed      SeedConfig
+	OAuth     OAuthConfig
+	Storage   StorageConfig
+	Avatar    AvatarConfig
+	Mail      MailConfig
+	Security  SecurityConfig
+	// FeatureFlags turns the flags of featureflag on or off, leaving the
+	// flags not listed at their defaults
+	FeatureFlags map[string]bool
+}
+
+// ServerConfig holds HTTP server settings
+type ServerConfig struct {
+	Port string
+	// BaseURL is the public address used to build links sent to users
+	BaseURL string
+	// TrustedHosts lists the hostnames requests may be addressed to, or "*"
+	// for any
+	TrustedHosts []string
+	// TrustedProxies lists proxy IPs or CIDRs whose ProxyHeader is believed
+	TrustedProxies []string
+	ProxyHeader    string
+	// DocsEnabled serves the OpenAPI document and Swagger UI
+	DocsEnabled bool
+	// AdminAddr is the host:port of the internal listener serving metrics
+	// and detailed health, which then leave the public port
+	AdminAddr string
+	// PprofEnabled serves the net/http/pprof profiles under /debug/pprof on
+	// AdminAddr
+	PprofEnabled bool
+	// BodyLimit is the largest request body accepted, in bytes
+	BodyLimit int
+	// TLSEnabled serves HTTPS on Port using TLSCertFile and TLSKeyFile
+	TLSEnabled  bool
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSRedirectPort, when set with TLS, serves plain HTTP redirects to HTTPS
+	TLSRedirectPort string
+	// RequestTimeout cancels the request context, including database calls,
+	// once a request has run this long
+	RequestTimeout time.Duration
+	// CSVTimeout replaces RequestTimeout for the CSV import and bounds how
+	// long a streamed export may run
+	CSVTimeout time.Duration
+	// JSONLibrary is JSONStdlib or JSONGoccy, a faster drop-in replacement
+	JSONLibrary string
+}
+
+// DatabaseConfig holds database settings
+type DatabaseConfig struct {
+	URL         string
+	PingTimeout time.Duration
+	// ReplicaURL is a read replica serving user lookups and listings
+	ReplicaURL string
+	// SlowQueryThreshold logs a warning for queries running longer; zero disables it
+	SlowQueryThreshold time.Duration
+	// ConnectAttempts, ConnectBackoff, and ConnectMaxBackoff bound how long
+	// startup waits for the database to accept connections
+	ConnectAttempts   int
+	ConnectBackoff    time.Duration
+	ConnectMaxBackoff time.Duration
+}
+
+// JWTConfig holds token signing settings
+type JWTConfig struct {
+	// Algorithm is HS256 or RS256
+	Algorithm string
+	// KeyID names the key new tokens are signed with
+	KeyID string
+	// Secret is the HS256 secret of KeyID
+	Secret string
+	// Keys maps key ids to HS256 secrets or, with RS256, to PEM key files.
+	// Every key listed is accepted when verifying.
+	Keys       map[string]string
+	TTL        time.Duration
+	RefreshTTL time.Duration
+}
+
+// AuthConfig holds credential handling settings
+type AuthConfig struct {
+	BcryptCost int
+	// RequireEmailVerification blocks login until the user verifies their email
+	RequireEmailVerification bool
+	EmailVerificationTTL     time.Duration
+	PasswordResetTTL         time.Duration
+	// LockoutThreshold consecutive failed logins lock an account for LockoutDuration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// IdempotencyKeyTTL is how long responses are kept for Idempotency-Key replays
+	IdempotencyKeyTTL time.Duration
+	// TOTPIssuer names the service in authenticator apps
+	TOTPIssuer string
+	// MFATokenTTL is how long the second step of a two-factor login may take
+	MFATokenTTL time.Duration
+	// ImpersonationTTL is how long an admin may act as a user per token
+	ImpersonationTTL time.Duration
+	// PermissionCacheTTL is how long the permissions of each role are cached
+	PermissionCacheTTL time.Duration
+}
+
+// PasswordConfig holds the strength rules for new passwords
+type PasswordConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// RejectCommon refuses passwords on the common passwords list, which is
+	// read from CommonListFile when set and built in otherwise
+	RejectCommon   bool
+	CommonListFile string
+}
+
+// LogConfig holds logging settings
+type LogConfig struct {
+	Level  string
+	Format string
+	// Bodies logs request and response bodies at debug level
+	Bodies bool
+	// BodyMaxSize caps each logged body in bytes
+	BodyMaxSize int
+	// AccessSampleRate logs one in this many successful requests; errors are
+	// always logged
+	AccessSampleRate int
+	// AccessSlowThreshold logs every request running longer, sampled or not;
+	// zero disables it
+	AccessSlowThreshold time.Duration
+}
+
+// CORSConfig holds cross-origin resource sharing settings
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+}
+
+// RateLimitConfig holds per-IP request limits and the per-email login throttle
+type RateLimitConfig struct {
+	Max         int
+	Window      time.Duration
+	LoginMax    int
+	LoginWindow time.Duration
+	// LoginEmailMax failed logins for one email within any LoginEmailWindow,
+	// from any client IP, throttle further logins for it
+	LoginEmailMax    int
+	LoginEmailWindow time.Duration
+}
+
+// TracingConfig holds OpenTelemetry export settings
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector URL; tracing is disabled when empty
+	Endpoint    string
+	ServiceName string
+}
+
+// WebhookConfig holds settings of webhook delivery
+type WebhookConfig struct {
+	// QueueSize bounds the delivery attempts waiting for a worker; due
+	// attempts beyond it wait in the repository for a later poll
+	QueueSize int
+	Workers   int
+	// MaxAttempts is the number of tries before a delivery is given up
+	MaxAttempts int
+	// Timeout bounds each delivery attempt
+	Timeout time.Duration
+	// RetryBackoff is the wait before the first retry, doubling after each failure
+	RetryBackoff time.Duration
+	// OutboxPollInterval is how often unsent events and due delivery attempts
+	// are read from the repository
+	OutboxPollInterval time.Duration
+	// OutboxBatchSize is the most events relayed, or due attempts claimed, in
+	// one round of a poll
+	OutboxBatchSize int
+	// OutboxMaxAttempts is the number of failed fan-outs before an event is dead-lettered
+	OutboxMaxAttempts int
+}
+
+// CacheConfig holds settings of the in-process user cache
+type CacheConfig struct {
+	Enabled    bool
+	TTL        time.Duration
+	MaxEntries int
+}
+
+// RedisConfig holds the connection to the Redis shared by replicas
+type RedisConfig struct {
+	// URL is a redis:// or rediss:// address; rate limits and lockouts are
+	// kept in process memory when empty
+	URL string
+}
+
+// CompressConfig holds response compression settings
+type CompressConfig struct {
+	Enabled bool
+	// Level is speed, default, or best
+	Level string
+	// MinSize is the smallest body, in bytes, worth compressing
+	MinSize int
+}
+
+// OAuthConfig holds the OAuth clients of social login providers
+type OAuthConfig struct {
+	// Google sign-in is enabled when GoogleClientID is set
+	GoogleClientID     string
+	GoogleClientSecret string
+	// GoogleRedirectURL is the callback Google returns to, which must be an
+	// authorized redirect URI of the client
+	GoogleRedirectURL string
+	// StateTTL is how long a sign-in may take from redirect to callback
+	StateTTL time.Duration
+}
+
+// GoogleEnabled reports whether Google sign-in is configured
+func (c OAuthConfig) GoogleEnabled() bool {
+	return c.GoogleClientID != ""
+}
+
+// StorageConfig selects where uploaded files are kept
+type StorageConfig struct {
+	// Driver is local or s3
+	Driver string
+	// LocalDir holds the files of the local driver, which serves them at /uploads
+	LocalDir string
+	// S3Endpoint and S3Bucket locate the bucket of the s3 driver
+	S3Endpoint string
+	S3Bucket   string
+	S3Region   string
+}
+
+// AvatarConfig holds the limits on uploaded avatars
+type AvatarConfig struct {
+	// MaxSize is the largest upload accepted, in bytes
+	MaxSize int
+	// MaxDimension bounds the width and height of an avatar, in pixels
+	MaxDimension int
+	// Downscale shrinks larger images to MaxDimension instead of rejecting them
+	Downscale bool
+}
+
+// MailConfig selects how emails are delivered
+type MailConfig struct {
+	// Driver is log, which only logs emails, or smtp
+	Driver       string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// From is the sender address, optionally with a display name
+	From        string
+	SMTPTimeout time.Duration
+	// RetryQueueSize, RetryAttempts, and RetryBackoff control the retries of
+	// emails the user cannot ask for again, such as the welcome email
+	RetryQueueSize int
+	RetryAttempts  int
+	RetryBackoff   time.Duration
+}
+
+// SecurityConfig holds the security headers sent with every response; an
+// empty value leaves its header out
+type SecurityConfig struct {
+	// NoSniff sends X-Content-Type-Options: nosniff
+	NoSniff        bool
+	FrameOptions   string
+	ReferrerPolicy string
+	// HSTSMaxAge is the Strict-Transport-Security lifetime, sent only with
+	// TLS; zero leaves the header out
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	// CSP is the Content-Security-Policy of every response but the docs page
+	CSP string
+	// DocsCSP replaces the built-in policy of the Swagger UI page
+	DocsCSP string
+}
+
+// SeedConfig holds the demo accounts created by the -seed flag
+type SeedConfig struct {
+	AdminEmail    string
+	AdminPassword string
+	// UserCount regular users are created as user1@example.com and so on,
+	// all with UserPassword
+	UserCount    int
+	UserPassword string
+}
+
+// IsProduction reports whether the application runs in production mode
+func (c *Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// Load reads the configuration from environment variables. Outside of
+// production, variables are first loaded from a .env file when present.
+func Load() (*Config, error) {
+	if os.Getenv("APP_ENV") != EnvProduction {
+		if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("load .env file: %w", err)
+		}
+	}
+
+	l := &loader{}
+	env := l.string("APP_ENV", EnvDevelopment)
+
+	// Any origin may call the API during development, none by default elsewhere
+	var defaultOrigins []string
+	if env == EnvDevelopment {
+		defaultOrigins = []string{"*"}
+	}
+
+	// Any host may be used during development, only that of APP_BASE_URL by default elsewhere
+	baseURL := strings.TrimSuffix(l.string("APP_BASE_URL", "http://localhost:3000"), "/")
+	defaultHosts := []string{"*"}
+	if env != EnvDevelopment {
+		defaultHosts = nil
+		if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+			defaultHosts = []string{u.Hostname()}
+		}
+	}
+
+	cfg := &Config{
+		Env: env,
+		Server: ServerConfig{
+			Port:           l.string("PORT", "3000"),
+			BaseURL:        baseURL,
+			TrustedHosts:   l.list("TRUSTED_HOSTS", defaultHosts),
+			TrustedProxies: l.list("TRUSTED_PROXIES", nil),
+			ProxyHeader:    l.string("PROXY_HEADER", "X-Forwarded-For"),
+			DocsEnabled:    l.bool("DOCS_ENABLED", env != EnvProduction),
+			AdminAddr:      l.adminAddr(),
+			PprofEnabled:   l.bool("PPROF_ENABLED", false),
+			BodyLimit:      l.int("MAX_BODY_SIZE", 1<<20),
+			RequestTimeout: l.duration("REQUEST_TIMEOUT", 30*time.Second),
+			CSVTimeout:     l.duration("CSV_REQUEST_TIMEOUT", 5*time.Minute),
+			JSONLibrary:    l.string("JSON_LIBRARY", JSONStdlib),
+
+			TLSEnabled:      l.bool("TLS_ENABLED", false),
+			TLSCertFile:     l.string("TLS_CERT_FILE", ""),
+			TLSKeyFile:      l.string("TLS_KEY_FILE", ""),
+			TLSRedirectPort: l.string("TLS_REDIRECT_PORT", ""),
+		},
+		Database: DatabaseConfig{
+			URL:                l.string("DATABASE_URL", ""),
+			ReplicaURL:         l.string("DATABASE_REPLICA_URL", ""),
+			SlowQueryThreshold: l.duration("DATABASE_SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+			PingTimeout:        l.duration("DATABASE_PING_TIMEOUT", 2*time.Second),
+			ConnectAttempts:    l.int("DATABASE_CONNECT_ATTEMPTS", 10),
+			ConnectBackoff:     l.duration("DATABASE_CONNECT_BACKOFF", 500*time.Millisecond),
+			ConnectMaxBackoff:  l.duration("DATABASE_CONNECT_MAX_BACKOFF", 10*time.Second),
+		},
+		JWT: JWTConfig{
+			Algorithm:  l.string("JWT_ALGORITHM", "HS256"),
+			KeyID:      l.string("JWT_KEY_ID", "default"),
+			Secret:     l.string("JWT_SECRET", ""),
+			Keys:       l.pairs("JWT_KEYS"),
+			TTL:        l.duration("JWT_TTL", 15*time.Minute),
+			RefreshTTL: l.duration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		},
+		Auth: AuthConfig{
+			BcryptCost:               l.int("BCRYPT_COST", 12),
+			RequireEmailVerification: l.bool("REQUIRE_EMAIL_VERIFICATION", false),
+			EmailVerificationTTL:     l.duration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+			PasswordResetTTL:         l.duration("PASSWORD_RESET_TTL", time.Hour),
+			LockoutThreshold:         l.int("LOGIN_LOCKOUT_THRESHOLD", 5),
+			LockoutDuration:          l.duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			IdempotencyKeyTTL:        l.duration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+			TOTPIssuer:               l.string("TOTP_ISSUER", "User Management API"),
+			MFATokenTTL:              l.duration("MFA_TOKEN_TTL", 5*time.Minute),
+			ImpersonationTTL:         l.duration("IMPERSONATION_TTL", 10*time.Minute),
+			PermissionCacheTTL:       l.duration("PERMISSION_CACHE_TTL", time.Minute),
+		},
+		Password: PasswordConfig{
+			MinLength:      l.int("PASSWORD_MIN_LENGTH", 8),
+			RequireUpper:   l.bool("PASSWORD_REQUIRE_UPPERCASE", false),
+			RequireLower:   l.bool("PASSWORD_REQUIRE_LOWERCASE", false),
+			RequireDigit:   l.bool("PASSWORD_REQUIRE_DIGIT", false),
+			RequireSymbol:  l.bool("PASSWORD_REQUIRE_SYMBOL", false),
+			RejectCommon:   l.bool("PASSWORD_REJECT_COMMON", true),
+			CommonListFile: l.string("PASSWORD_COMMON_LIST_FILE", ""),
+		},
+		Log: LogConfig{
+			Level:               l.string("LOG_LEVEL", "info"),
+			Format:              l.string("LOG_FORMAT", "json"),
+			Bodies:              l.bool("LOG_BODIES", false),
+			BodyMaxSize:         l.int("LOG_BODY_MAX_SIZE", 4096),
+			AccessSampleRate:    l.int("LOG_ACCESS_SAMPLE_RATE", 1),
+			AccessSlowThreshold: l.duration("LOG_ACCESS_SLOW_THRESHOLD", time.Second),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   l.list("CORS_ALLOWED_ORIGINS", defaultOrigins),
+			AllowedMethods:   l.list("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE"}),
+			AllowedHeaders:   l.list("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "If-Match", "If-None-Match", "Idempotency-Key", "X-API-Key", "X-Feature-Flags"}),
+			AllowCredentials: l.bool("CORS_ALLOW_CREDENTIALS", false),
+		},
+		RateLimit: RateLimitConfig{
+			Max:         l.int("RATE_LIMIT_MAX", 100),
+			Window:      l.duration("RATE_LIMIT_WINDOW", time.Minute),
+			LoginMax:    l.int("LOGIN_RATE_LIMIT_MAX", 5),
+			LoginWindow: l.duration("LOGIN_RATE_LIMIT_WINDOW", time.Minute),
+
+			LoginEmailMax:    l.int("LOGIN_EMAIL_RATE_LIMIT_MAX", 10),
+			LoginEmailWindow: l.duration("LOGIN_EMAIL_RATE_LIMIT_WINDOW", 15*time.Minute),
+		},
+		Tracing: TracingConfig{
+			Endpoint:    l.string("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			ServiceName: l.string("OTEL_SERVICE_NAME", "user-management-api"),
+		},
+		Redis: RedisConfig{
+			URL: l.string("REDIS_URL", ""),
+		},
+		Cache: CacheConfig{
+			Enabled:    l.bool("USER_CACHE_ENABLED", false),
+			TTL:        l.duration("USER_CACHE_TTL", 30*time.Second),
+			MaxEntries: l.int("USER_CACHE_MAX_ENTRIES", 10000),
+		},
+		Compress: CompressConfig{
+			Enabled: l.bool("COMPRESSION_ENABLED", true),
+			Level:   l.string("COMPRESSION_LEVEL", "default"),
+			MinSize: l.int("COMPRESSION_MIN_SIZE", 1024),
+		},
+		Seed: SeedConfig{
+			AdminEmail:    l.string("SEED_ADMIN_EMAIL", "admin@example.com"),
+			AdminPassword: l.string("SEED_ADMIN_PASSWORD", ""),
+			UserCount:     l.int("SEED_USER_COUNT", 5),
+			UserPassword:  l.string("SEED_USER_PASSWORD", ""),
+		},
+		OAuth: OAuthConfig{
+			GoogleClientID:     l.string("GOOGLE_CLIENT_ID", ""),
+			GoogleClientSecret: l.string("GOOGLE_CLIENT_SECRET", ""),
+			GoogleRedirectURL:  l.string("GOOGLE_REDIRECT_URL", ""),
+			StateTTL:           l.duration("OAUTH_STATE_TTL", 10*time.Minute),
+		},
+		Storage: StorageConfig{
+			Driver:     l.string("STORAGE_DRIVER", "local"),
+			LocalDir:   l.string("STORAGE_LOCAL_DIR", "./uploads"),
+			S3Endpoint: l.string("S3_ENDPOINT", ""),
+			S3Bucket:   l.string("S3_BUCKET", ""),
+			S3Region:   l.string("S3_REGION", ""),
+		},
+		Mail: MailConfig{
+			Driver:         l.string("MAIL_DRIVER", "log"),
+			SMTPHost:       l.string("SMTP_HOST", ""),
+			SMTPPort:       l.int("SMTP_PORT", 587),
+			SMTPUsername:   l.string("SMTP_USERNAME", ""),
+			SMTPPassword:   l.string("SMTP_PASSWORD", ""),
+			From:           l.string("MAIL_FROM", ""),
+			SMTPTimeout:    l.duration("SMTP_TIMEOUT", 10*time.Second),
+			RetryQueueSize: l.int("MAIL_RETRY_QUEUE_SIZE", 100),
+			RetryAttempts:  l.int("MAIL_RETRY_ATTEMPTS", 5),
+			RetryBackoff:   l.duration("MAIL_RETRY_BACKOFF", 30*time.Second),
+		},
+		Security: SecurityConfig{
+			NoSniff:               l.bool("SECURITY_NOSNIFF", true),
+			FrameOptions:          l.string("SECURITY_FRAME_OPTIONS", "DENY"),
+			ReferrerPolicy:        l.string("SECURITY_REFERRER_POLICY", "no-referrer"),
+			HSTSMaxAge:            l.duration("SECURITY_HSTS_MAX_AGE", 365*24*time.Hour),
+			HSTSIncludeSubdomains: l.bool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", false),
+			CSP:                   l.string("SECURITY_CSP", "default-src 'none'; frame-ancestors 'none'"),
+			DocsCSP:               l.string("SECURITY_DOCS_CSP", ""),
+		},
+		FeatureFlags: l.flags("FEATURE_FLAGS"),
+		Avatar: AvatarConfig{
+			MaxSize:      l.int("AVATAR_MAX_SIZE", 512<<10),
+			MaxDimension: l.int("AVATAR_MAX_DIMENSION", 1024),
+			Downscale:    l.bool("AVATAR_DOWNSCALE", true),
+		},
+		Webhook: WebhookConfig{
+			QueueSize:          l.int("WEBHOOK_QUEUE_SIZE", 1000),
+			Workers:            l.int("WEBHOOK_WORKERS", 4),
+			MaxAttempts:        l.int("WEBHOOK_MAX_ATTEMPTS", 5),
+			Timeout:            l.duration("WEBHOOK_TIMEOUT", 10*time.Second),
+			RetryBackoff:       l.duration("WEBHOOK_RETRY_BACKOFF", time.Second),
+			OutboxPollInterval: l.duration("OUTBOX_POLL_INTERVAL", time.Second),
+			OutboxBatchSize:    l.int("OUTBOX_BATCH_SIZE", 100),
+			OutboxMaxAttempts:  l.int("OUTBOX_MAX_ATTEMPTS", 10),
+		},
+	}
+	if err := errors.Join(l.errs...); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// validate checks settings that depend on the environment
+func (c *Config) validate() error {
+	var errs []error
+
+	switch c.Env {
+	case EnvDevelopment, EnvStaging, EnvProduction:
+	default:
+		errs = append(errs, fmt.Errorf("APP_ENV: unknown environment %q", c.Env))
+	}
+
+	_, hasCurrentKey := c.JWT.Keys[c.JWT.KeyID]
+	switch c.JWT.Algorithm {
+	case "HS256":
+		if c.JWT.Secret != "" && hasCurrentKey {
+			errs = append(errs, errors.New("JWT_SECRET and JWT_KEYS both set the secret of JWT_KEY_ID"))
+		}
+		if c.IsProduction() && c.JWT.Secret == "" && !hasCurrentKey {
+			errs = append(errs, errors.New("JWT_SECRET or a JWT_KEYS entry for JWT_KEY_ID is required in production"))
+		}
+	case "RS256":
+		if c.JWT.Secret != "" {
+			errs = append(errs, errors.New("JWT_SECRET cannot be used with JWT_ALGORITHM=RS256"))
+		}
+		if !hasCurrentKey {
+			errs = append(errs, errors.New("JWT_KEYS must list the key file of JWT_KEY_ID when JWT_ALGORITHM=RS256"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("JWT_ALGORITHM: unknown algorithm %q", c.JWT.Algorithm))
+	}
+
+	if c.IsProduction() {
+		if c.Database.URL == "" {
+			errs = append(errs, errors.New("DATABASE_URL is required in production"))
+		}
+	}
+
+	if c.Database.SlowQueryThreshold < 0 {
+		errs = append(errs, errors.New("DATABASE_SLOW_QUERY_THRESHOLD must not be negative"))
+	}
+	if c.Database.ReplicaURL != "" && c.Database.URL == "" {
+		errs = append(errs, errors.New("DATABASE_REPLICA_URL requires DATABASE_URL"))
+	}
+	if c.Database.ConnectAttempts <= 0 || c.Database.ConnectBackoff <= 0 || c.Database.ConnectMaxBackoff < c.Database.ConnectBackoff {
+		errs = append(errs, errors.New("DATABASE_CONNECT_ATTEMPTS and DATABASE_CONNECT_BACKOFF must be positive, and DATABASE_CONNECT_MAX_BACKOFF at least DATABASE_CONNECT_BACKOFF"))
+	}
+
+	var adminPort string
+	if c.Server.AdminAddr != "" {
+		var err error
+		if _, adminPort, err = net.SplitHostPort(c.Server.AdminAddr); err != nil {
+			errs = append(errs, fmt.Errorf("ADMIN_ADDR: invalid address %q", c.Server.AdminAddr))
+		} else if adminPort == c.Server.Port {
+			errs = append(errs, errors.New("ADMIN_ADDR must use a different port from PORT"))
+		}
+	} else if c.Server.PprofEnabled {
+		errs = append(errs, errors.New("PPROF_ENABLED requires ADMIN_ADDR, so profiles stay off the public port"))
+	}
+
+	if c.JWT.TTL <= 0 {
+		errs = append(errs, errors.New("JWT_TTL must be positive"))
+	}
+	if c.JWT.RefreshTTL <= 0 {
+		errs = append(errs, errors.New("REFRESH_TOKEN_TTL must be positive"))
+	}
+	if c.Auth.EmailVerificationTTL <= 0 {
+		errs = append(errs, errors.New("EMAIL_VERIFICATION_TTL must be positive"))
+	}
+	if c.Auth.PasswordResetTTL <= 0 {
+		errs = append(errs, errors.New("PASSWORD_RESET_TTL must be positive"))
+	}
+	if c.Auth.LockoutThreshold <= 0 || c.Auth.LockoutDuration <= 0 {
+		errs = append(errs, errors.New("LOGIN_LOCKOUT_THRESHOLD and LOGIN_LOCKOUT_DURATION must be positive"))
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: unknown level %q", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_FORMAT: unknown format %q", c.Log.Format))
+	}
+	switch c.Compress.Level {
+	case "speed", "default", "best":
+	default:
+		errs = append(errs, fmt.Errorf("COMPRESSION_LEVEL: unknown level %q", c.Compress.Level))
+	}
+	if c.Compress.MinSize < 0 {
+		errs = append(errs, errors.New("COMPRESSION_MIN_SIZE must not be negative"))
+	}
+
+	if c.RateLimit.Max <= 0 || c.RateLimit.LoginMax <= 0 {
+		errs = append(errs, errors.New("RATE_LIMIT_MAX and LOGIN_RATE_LIMIT_MAX must be positive"))
+	}
+	if c.RateLimit.Window < time.Second || c.RateLimit.LoginWindow < time.Second {
+		errs = append(errs, errors.New("RATE_LIMIT_WINDOW and LOGIN_RATE_LIMIT_WINDOW must be at least 1s"))
+	}
+	if c.RateLimit.LoginEmailMax <= 0 || c.RateLimit.LoginEmailWindow < time.Second {
+		errs = append(errs, errors.New("LOGIN_EMAIL_RATE_LIMIT_MAX must be positive and LOGIN_EMAIL_RATE_LIMIT_WINDOW at least 1s"))
+	}
+
+	if c.Server.TLSEnabled && (c.Server.TLSCertFile == "" || c.Server.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE are required when TLS_ENABLED is true"))
+	}
+	if c.Server.TLSRedirectPort != "" {
+		if !c.Server.TLSEnabled {
+			errs = append(errs, errors.New("TLS_REDIRECT_PORT requires TLS_ENABLED"))
+		}
+		if c.Server.TLSRedirectPort == c.Server.Port || c.Server.TLSRedirectPort == adminPort {
+			errs = append(errs, errors.New("TLS_REDIRECT_PORT must differ from PORT and the ADMIN_ADDR port"))
+		}
+	}
+
+	if c.Server.BodyLimit <= 0 {
+		errs = append(errs, errors.New("MAX_BODY_SIZE must be positive"))
+	}
+
+	if c.Server.RequestTimeout <= 0 || c.Server.CSVTimeout <= 0 {
+		errs = append(errs, errors.New("REQUEST_TIMEOUT and CSV_REQUEST_TIMEOUT must be positive"))
+	}
+
+	switch c.Server.JSONLibrary {
+	case JSONStdlib, JSONGoccy:
+	default:
+		errs = append(errs, fmt.Errorf("JSON_LIBRARY: unknown library %q", c.Server.JSONLibrary))
+	}
+
+	if c.Auth.IdempotencyKeyTTL <= 0 {
+		errs = append(errs, errors.New("IDEMPOTENCY_KEY_TTL must be positive"))
+	}
+
+	// bcrypt ignores everything past 72 bytes
+	if c.Password.MinLength < 1 || c.Password.MinLength > 72 {
+		errs = append(errs, errors.New("PASSWORD_MIN_LENGTH must be between 1 and 72"))
+	}
+
+	if c.Auth.TOTPIssuer == "" {
+		errs = append(errs, errors.New("TOTP_ISSUER is required"))
+	}
+
+	if c.Auth.MFATokenTTL <= 0 {
+		errs = append(errs, errors.New("MFA_TOKEN_TTL must be positive"))
+	}
+
+	// Signing the admin out denylists their session for JWT_TTL, which must
+	// cover the impersonation tokens issued to it
+	if c.Auth.ImpersonationTTL <= 0 || c.Auth.ImpersonationTTL > c.JWT.TTL {
+		errs = append(errs, errors.New("IMPERSONATION_TTL must be positive and at most JWT_TTL"))
+	}
+	if c.Auth.PermissionCacheTTL <= 0 {
+		errs = append(errs, errors.New("PERMISSION_CACHE_TTL must be positive"))
+	}
+
+	if c.OAuth.GoogleEnabled() && (c.OAuth.GoogleClientSecret == "" || c.OAuth.GoogleRedirectURL == "") {
+		errs = append(errs, errors.New("GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL are required when GOOGLE_CLIENT_ID is set"))
+	}
+
+	if c.OAuth.StateTTL <= 0 {
+		errs = append(errs, errors.New("OAUTH_STATE_TTL must be positive"))
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+		if c.Storage.LocalDir == "" {
+			errs = append(errs, errors.New("STORAGE_LOCAL_DIR is required when STORAGE_DRIVER is local"))
+		}
+	case "s3":
+		if c.Storage.S3Endpoint == "" || c.Storage.S3Bucket == "" {
+			errs = append(errs, errors.New("S3_ENDPOINT and S3_BUCKET are required when STORAGE_DRIVER is s3"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_DRIVER: unknown driver %q", c.Storage.Driver))
+	}
+
+	switch c.Mail.Driver {
+	case "log":
+	case "smtp":
+		if c.Mail.SMTPHost == "" || c.Mail.From == "" {
+			errs = append(errs, errors.New("SMTP_HOST and MAIL_FROM are required when MAIL_DRIVER is smtp"))
+		}
+		if c.Mail.SMTPPort <= 0 || c.Mail.SMTPPort > 65535 {
+			errs = append(errs, errors.New("SMTP_PORT must be between 1 and 65535"))
+		}
+		if c.Mail.SMTPTimeout <= 0 {
+			errs = append(errs, errors.New("SMTP_TIMEOUT must be positive"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_DRIVER: unknown driver %q", c.Mail.Driver))
+	}
+	if c.Mail.RetryQueueSize <= 0 || c.Mail.RetryAttempts <= 0 || c.Mail.RetryBackoff <= 0 {
+		errs = append(errs, errors.New("MAIL_RETRY_QUEUE_SIZE, MAIL_RETRY_ATTEMPTS, and MAIL_RETRY_BACKOFF must be positive"))
+	}
+
+	if c.Security.HSTSMaxAge < 0 {
+		errs = append(errs, errors.New("SECURITY_HSTS_MAX_AGE must not be negative"))
+	}
+
+	if c.Avatar.MaxSize <= 0 || c.Avatar.MaxDimension <= 0 {
+		errs = append(errs, errors.New("AVATAR_MAX_SIZE and AVATAR_MAX_DIMENSION must be positive"))
+	}
+	if c.Avatar.MaxSize >= c.Server.BodyLimit {
+		errs = append(errs, errors.New("AVATAR_MAX_SIZE must be below MAX_BODY_SIZE"))
+	}
+
+	if c.Redis.URL != "" && !strings.HasPrefix(c.Redis.URL, "redis://") && !strings.HasPrefix(c.Redis.URL, "rediss://") {
+		errs = append(errs, errors.New("REDIS_URL must start with redis:// or rediss://"))
+	}
+	if c.Cache.Enabled && (c.Cache.TTL <= 0 || c.Cache.MaxEntries <= 0) {
+		errs = append(errs, errors.New("USER_CACHE_TTL and USER_CACHE_MAX_ENTRIES must be positive"))
+	}
+
+	if c.Webhook.QueueSize <= 0 || c.Webhook.Workers <= 0 || c.Webhook.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("WEBHOOK_QUEUE_SIZE, WEBHOOK_WORKERS, and WEBHOOK_MAX_ATTEMPTS must be positive"))
+	}
+
+	if c.Webhook.Timeout <= 0 || c.Webhook.RetryBackoff <= 0 {
+		errs = append(errs, errors.New("WEBHOOK_TIMEOUT and WEBHOOK_RETRY_BACKOFF must be positive"))
+	}
+	if c.Webhook.OutboxPollInterval <= 0 || c.Webhook.OutboxBatchSize <= 0 || c.Webhook.OutboxMaxAttempts <= 0 {
+		errs = append(errs, errors.New("OUTBOX_POLL_INTERVAL, OUTBOX_BATCH_SIZE, and OUTBOX_MAX_ATTEMPTS must be positive"))
+	}
+
+	if c.Log.BodyMaxSize <= 0 {
+		errs = append(errs, errors.New("LOG_BODY_MAX_SIZE must be positive"))
+	}
+	if c.Log.AccessSampleRate <= 0 {
+		errs = append(errs, errors.New("LOG_ACCESS_SAMPLE_RATE must be positive"))
+	}
+	if c.Log.AccessSlowThreshold < 0 {
+		errs = append(errs, errors.New("LOG_ACCESS_SLOW_THRESHOLD must not be negative"))
+	}
+
+	if len(c.Server.TrustedHosts) == 0 {
+		errs = append(errs, errors.New("TRUSTED_HOSTS must list at least one host"))
+	}
+	if c.IsProduction() && slices.Contains(c.Server.TrustedHosts, "*") {
+		errs = append(errs, errors.New("TRUSTED_HOSTS cannot be a wildcard in production"))
+	}
+
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		errs = append(errs, errors.New("CORS_ALLOW_CREDENTIALS cannot be used with a wildcard origin"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// loader reads typed environment variables, collecting parse errors
+type loader struct {
+	errs []error
+}
+
+func (l *loader) string(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func (l *loader) int(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid integer %q", key, value))
+		return fallback
+	}
+
+	return n
+}
+
+func (l *loader) duration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid duration %q", key, value))
+		return fallback
+	}
+
+	return d
+}
+
+func (l *loader) bool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid boolean %q", key, value))
+		return fallback
+	}
+
+	return b
+}
+
+// list reads a comma-separated value, ignoring blank entries
+func (l *loader) list(key string, fallback []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// adminAddr reads ADMIN_ADDR, falling back to METRICS_PORT, which it
+// replaces, on all interfaces
+func (l *loader) adminAddr() string {
+	if port := l.string("METRICS_PORT", ""); port != "" {
+		return l.string("ADMIN_ADDR", ":"+port)
+	}
+
+	return l.string("ADMIN_ADDR", "")
+}
+
+// flags reads a comma-separated list of feature flag name=bool entries
+func (l *loader) flags(key string) map[string]bool {
+	flags, err := featureflag.Parse(l.string(key, ""))
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: %w", key, err))
+	}
+
+	return flags
+}
+
+// pairs reads a comma-separated list of key=value entries
+func (l *loader) pairs(key string) map[string]string {
+	entries := l.list(key, nil)
+	if entries == nil {
+		return nil
+	}
+
+	pairs := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		k, v, ok := strings.Cut(entry, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" || v == "" {
+			l.errs = append(l.errs, fmt.Errorf("%s: entry %q is not key=value", key, entry))
+			continue
+		}
+		pairs[k] = v
+	}
+
+	return pairs
+}
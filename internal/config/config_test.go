@@ -0,0 +1,154 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setEnv sets the environment variables for the rest of the test
+func setEnv(t *testing.T, vars map[string]string) {
+	t.Helper()
+	for key, value := range vars {
+		t.Setenv(key, value)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.Env != EnvDevelopment {
+		t.Errorf("Env = %q, want %q", cfg.Env, EnvDevelopment)
+	}
+	if cfg.Server.Port != "3000" || cfg.Server.BaseURL != "http://localhost:3000" {
+		t.Errorf("Port, BaseURL = %q, %q; want 3000, http://localhost:3000", cfg.Server.Port, cfg.Server.BaseURL)
+	}
+	if cfg.JWT.TTL != 15*time.Minute || cfg.JWT.RefreshTTL != 7*24*time.Hour {
+		t.Errorf("JWT TTLs = %v, %v; want 15m, 168h", cfg.JWT.TTL, cfg.JWT.RefreshTTL)
+	}
+	if cfg.RateLimit.Max != 100 || cfg.RateLimit.Window != time.Minute {
+		t.Errorf("rate limit = %d per %v, want 100 per 1m", cfg.RateLimit.Max, cfg.RateLimit.Window)
+	}
+	if !slices.Equal(cfg.CORS.AllowedOrigins, []string{"*"}) || !slices.Equal(cfg.Server.TrustedHosts, []string{"*"}) {
+		t.Errorf("origins, hosts = %v, %v; want any during development", cfg.CORS.AllowedOrigins, cfg.Server.TrustedHosts)
+	}
+}
+
+func TestLoadReadsEnvironment(t *testing.T) {
+	setEnv(t, map[string]string{
+		"PORT":                 "8080",
+		"APP_BASE_URL":         "https://api.example.com/",
+		"JWT_TTL":              "30m",
+		"RATE_LIMIT_MAX":       "7",
+		"CORS_ALLOWED_ORIGINS": "https://a.example.com, https://b.example.com",
+		"FEATURE_FLAGS":        "webhooks=true,oauth=false",
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != "8080" || cfg.Server.BaseURL != "https://api.example.com" {
+		t.Errorf("Port, BaseURL = %q, %q", cfg.Server.Port, cfg.Server.BaseURL)
+	}
+	if cfg.JWT.TTL != 30*time.Minute || cfg.RateLimit.Max != 7 {
+		t.Errorf("JWT_TTL, RATE_LIMIT_MAX = %v, %d", cfg.JWT.TTL, cfg.RateLimit.Max)
+	}
+	if want := []string{"https://a.example.com", "https://b.example.com"}; !slices.Equal(cfg.CORS.AllowedOrigins, want) {
+		t.Errorf("origins = %v, want %v", cfg.CORS.AllowedOrigins, want)
+	}
+	if !cfg.FeatureFlags["webhooks"] || cfg.FeatureFlags["oauth"] {
+		t.Errorf("feature flags = %v", cfg.FeatureFlags)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "production without required settings",
+			env:  map[string]string{"APP_ENV": EnvProduction, "TRUSTED_HOSTS": "*"},
+			want: []string{
+				"JWT_SECRET or a JWT_KEYS entry for JWT_KEY_ID is required in production",
+				"DATABASE_URL is required in production",
+				"TRUSTED_HOSTS cannot be a wildcard in production",
+			},
+		},
+		{
+			name: "malformed values",
+			env:  map[string]string{"PORT": "3001", "JWT_TTL": "soon", "RATE_LIMIT_MAX": "many", "TLS_ENABLED": "maybe"},
+			want: []string{
+				`JWT_TTL: invalid duration "soon"`,
+				`RATE_LIMIT_MAX: invalid integer "many"`,
+				`TLS_ENABLED: invalid boolean "maybe"`,
+			},
+		},
+		{
+			name: "invalid values",
+			env:  map[string]string{"LOG_LEVEL": "loud", "JWT_TTL": "0s", "TLS_ENABLED": "true"},
+			want: []string{
+				`LOG_LEVEL: unknown level "loud"`,
+				"JWT_TTL must be positive",
+				"TLS_CERT_FILE and TLS_KEY_FILE are required when TLS_ENABLED is true",
+			},
+		},
+		{
+			name: "access log sampling",
+			env:  map[string]string{"LOG_ACCESS_SAMPLE_RATE": "0", "LOG_ACCESS_SLOW_THRESHOLD": "-1s"},
+			want: []string{
+				"LOG_ACCESS_SAMPLE_RATE must be positive",
+				"LOG_ACCESS_SLOW_THRESHOLD must not be negative",
+			},
+		},
+		{
+			name: "profiling without the admin listener",
+			env:  map[string]string{"PPROF_ENABLED": "true"},
+			want: []string{"PPROF_ENABLED requires ADMIN_ADDR"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatal("Load succeeded, want an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not report %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadProduction(t *testing.T) {
+	setEnv(t, map[string]string{
+		"APP_ENV":      EnvProduction,
+		"APP_BASE_URL": "https://api.example.com",
+		"JWT_SECRET":   "production-secret",
+		"DATABASE_URL": "postgres://localhost/users",
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.IsProduction() || cfg.Server.DocsEnabled {
+		t.Errorf("production = %t, docs = %t; want true, false", cfg.IsProduction(), cfg.Server.DocsEnabled)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 0 {
+		t.Errorf("origins = %v, want none by default", cfg.CORS.AllowedOrigins)
+	}
+	if want := []string{"api.example.com"}; !slices.Equal(cfg.Server.TrustedHosts, want) {
+		t.Errorf("trusted hosts = %v, want %v", cfg.Server.TrustedHosts, want)
+	}
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// unavailableCodes are the SQLSTATEs, besides the connection exception
+// class 08, of a server that is restarting or out of connections
+var unavailableCodes = map[string]bool{
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// IsUnavailable reports whether err means the database could not be reached
+// or is restarting, a condition the pool recovers from by reconnecting.
+// Requests failing with it may succeed when retried. Errors from a cancelled
+// or expired context are not included.
+func IsUnavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || unavailableCodes[pgErr.Code]
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) || pgconn.SafeToRetry(err)
+}
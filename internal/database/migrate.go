@@ -0,0 +1,71 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5" // registers the pgx5 driver
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// RunMigrations applies all pending migrations to the database
+func RunMigrations(dbURL string) error {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, migrateURL(dbURL))
+	if err != nil {
+		return fmt.Errorf("init migrations: %w", err)
+	}
+	defer m.Close()
+
+	before, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+
+	after, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("applied migrations", slog.Uint64("count", uint64(after-before)), slog.Uint64("version", uint64(after)))
+	return nil
+}
+
+// currentVersion returns the applied schema version, failing on a dirty schema
+func currentVersion(m *migrate.Migrate) (uint, error) {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("schema version %d is dirty", version)
+	}
+
+	return version, nil
+}
+
+// migrateURL rewrites a postgres connection string to the pgx5 driver scheme
+func migrateURL(dbURL string) string {
+	for _, scheme := range []string{"postgres://", "postgresql://"} {
+		if strings.HasPrefix(dbURL, scheme) {
+			return "pgx5://" + strings.TrimPrefix(dbURL, scheme)
+		}
+	}
+
+	return dbURL
+}
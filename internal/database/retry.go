@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// attemptTimeout bounds each connection attempt made by WaitReady
+const attemptTimeout = 5 * time.Second
+
+// RetrySettings bounds how long startup waits for the database
+type RetrySettings struct {
+	// Attempts is the number of connection attempts before giving up
+	Attempts int
+	// Backoff is the wait after the first failed attempt, doubling after
+	// each further failure up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// WaitReady connects to the database and pings it until it answers, waiting
+// with exponential backoff between failed attempts. It lets the service start
+// alongside a database that is still booting rather than exit at once.
+func WaitReady(ctx context.Context, dbURL string, retry RetrySettings) error {
+	config, err := pgx.ParseConfig(dbURL)
+	if err != nil {
+		return fmt.Errorf("parse database URL: %w", err)
+	}
+
+	backoff := retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx, config)
+		if err == nil {
+			if attempt > 1 {
+				slog.Info("database is ready", slog.Int("attempt", attempt))
+			}
+			return nil
+		}
+		if attempt >= retry.Attempts {
+			return fmt.Errorf("database not ready after %d attempts: %w", attempt, err)
+		}
+
+		slog.Warn("database not ready, retrying",
+			slog.Int("attempt", attempt),
+			slog.Int("max_attempts", retry.Attempts),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err),
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, retry.MaxBackoff)
+	}
+}
+
+// ping opens a single connection and pings the database through it
+func ping(ctx context.Context, config *pgx.ConnConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	return conn.Ping(ctx)
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// fakeServer is a PostgreSQL stand-in that drops its first connections, as
+// a server still booting does, and then accepts logins and answers pings
+type fakeServer struct {
+	ln       net.Listener
+	refuse   int32
+	attempts atomic.Int32
+}
+
+// newFakeServer listens on a local port, dropping the first refuse connections
+func newFakeServer(t *testing.T, refuse int) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &fakeServer{ln: ln, refuse: int32(refuse)}
+	go s.serve()
+
+	return s
+}
+
+// url is a connection string for the server
+func (s *fakeServer) url() string {
+	return "postgres://app:secret@" + s.ln.Addr().String() + "/users?sslmode=disable"
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		if s.attempts.Add(1) <= s.refuse {
+			conn.Close()
+			continue
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle trusts the login and answers every query with an empty result
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	backend := pgproto3.NewBackend(conn, conn)
+	if _, err := backend.ReceiveStartupMessage(); err != nil {
+		return
+	}
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: []byte{0, 0, 0, 1}})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		switch msg.(type) {
+		case *pgproto3.Query:
+			backend.Send(&pgproto3.EmptyQueryResponse{})
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+			if err := backend.Flush(); err != nil {
+				return
+			}
+		case *pgproto3.Terminate:
+			return
+		}
+	}
+}
+
+func TestWaitReadyAfterFailures(t *testing.T) {
+	server := newFakeServer(t, 3)
+
+	err := WaitReady(context.Background(), server.url(), RetrySettings{
+		Attempts: 5, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("WaitReady: %v", err)
+	}
+	if got := server.attempts.Load(); got <= 3 {
+		t.Errorf("WaitReady connected %d times, want it past the 3 dropped connections", got)
+	}
+}
+
+func TestWaitReadyGivesUp(t *testing.T) {
+	server := newFakeServer(t, 100)
+
+	err := WaitReady(context.Background(), server.url(), RetrySettings{
+		Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("WaitReady succeeded while the database refused connections")
+	}
+}
+
+func TestWaitReadyStopsOnCancel(t *testing.T) {
+	server := newFakeServer(t, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err := WaitReady(ctx, server.url(), RetrySettings{Attempts: 10, Backoff: time.Hour, MaxBackoff: time.Hour})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitReady error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer records a client span for every query run through the pool,
+// nested under the span of the request that issued it, and logs a warning
+// for queries slower than slowQuery
+type queryTracer struct {
+	slowQuery time.Duration
+}
+
+// queryStartKey is the context key of the running query
+type queryStartKey struct{}
+
+// queryStart is the running query and when it was sent
+type queryStart struct {
+	sql string
+	at  time.Time
+}
+
+// TraceQueryStart starts the span of a query
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if t.slowQuery > 0 {
+		ctx = context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, at: time.Now()})
+	}
+
+	ctx, _ = tracing.Tracer().Start(ctx, spanName(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.query.text", data.SQL),
+		),
+	)
+
+	return ctx
+}
+
+// TraceQueryEnd ends the span started by TraceQueryStart, warning when the
+// query ran past the slow query threshold
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+
+	if start, ok := ctx.Value(queryStartKey{}).(queryStart); ok {
+		if elapsed := time.Since(start.at); elapsed > t.slowQuery {
+			logger.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "slow query",
+				slog.String("query", queryName(start.sql)),
+				slog.Duration("duration", elapsed),
+				slog.String("sql", strings.Join(strings.Fields(start.sql), " ")),
+			)
+		}
+	}
+}
+
+// queryName names a query after its SQL command and the table it works on,
+// such as "SELECT users", for telling slow queries apart at a glance
+func queryName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+
+	command := strings.ToUpper(fields[0])
+	// The table follows FROM in reads and deletes, INTO in inserts, and
+	// the command itself in updates
+	after := map[string]string{"SELECT": "FROM", "DELETE": "FROM", "INSERT": "INTO", "UPDATE": "UPDATE"}[command]
+	for i, field := range fields[:len(fields)-1] {
+		if table := strings.Trim(fields[i+1], `"(`); strings.EqualFold(field, after) && table != "" {
+			return command + " " + table
+		}
+	}
+
+	return command
+}
+
+// spanName names a query span after its SQL command, such as "db SELECT"
+func spanName(sql string) string {
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		return "db " + strings.ToUpper(fields[0])
+	}
+
+	return "db query"
+}
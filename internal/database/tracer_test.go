@@ -0,0 +1,144 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans keeps the spans ended until t ends in memory; it cannot use
+// testutil.RecordSpans, which imports this package
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func TestQueryTracerNestsSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	tracer := queryTracer{}
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "GET /api/v1/users/:id")
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT id FROM users WHERE id = $1"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+	failedCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "insert into users (email) values ($1)"})
+	tracer.TraceQueryEnd(failedCtx, nil, pgx.TraceQueryEndData{Err: errors.New("connection reset")})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	for i, want := range []struct {
+		name   string
+		status codes.Code
+	}{
+		{"db SELECT", codes.Unset},
+		{"db INSERT", codes.Error},
+	} {
+		span := spans[i]
+		if span.Name() != want.name || span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("span %d = %s (%s), want a client span %s", i, span.Name(), span.SpanKind(), want.name)
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() || span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("span %s is not a child of the request span", span.Name())
+		}
+		if span.Status().Code != want.status {
+			t.Errorf("span %s status = %v, want %v", span.Name(), span.Status().Code, want.status)
+		}
+	}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT id FROM users WHERE id = $1", "SELECT users"},
+		{"insert into refresh_tokens (id) values ($1)", "INSERT refresh_tokens"},
+		{"UPDATE users SET name = $1", "UPDATE users"},
+		{`DELETE FROM "users"`, "DELETE users"},
+		{"SELECT 1", "SELECT"},
+		{"", "query"},
+	}
+	for _, tt := range tests {
+		if got := queryName(tt.sql); got != tt.want {
+			t.Errorf("queryName(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+// captureWarnings sends the default logger's records to the returned buffer
+// until t ends
+func captureWarnings(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return &buf
+}
+
+func TestQueryTracerWarnsOnSlowQueries(t *testing.T) {
+	logs := captureWarnings(t)
+	tracer := queryTracer{slowQuery: 20 * time.Millisecond}
+	ctx := context.Background()
+
+	fast := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(fast, nil, pgx.TraceQueryEndData{})
+	slow := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT id\n\tFROM users WHERE id = $1"})
+	time.Sleep(30 * time.Millisecond)
+	tracer.TraceQueryEnd(slow, nil, pgx.TraceQueryEndData{})
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %d records, want one for the slow query: %s", len(lines), logs)
+	}
+	var entry struct {
+		Level    string `json:"level"`
+		Msg      string `json:"msg"`
+		Query    string `json:"query"`
+		Duration int64  `json:"duration"`
+		SQL      string `json:"sql"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("decode log record: %v", err)
+	}
+	if entry.Level != "WARN" || entry.Msg != "slow query" || entry.Query != "SELECT users" {
+		t.Errorf("record = %+v, want a slow query warning for SELECT users", entry)
+	}
+	if time.Duration(entry.Duration) < 30*time.Millisecond {
+		t.Errorf("duration = %v, want at least the time the query took", time.Duration(entry.Duration))
+	}
+	if entry.SQL != "SELECT id FROM users WHERE id = $1" {
+		t.Errorf("sql = %q, want it on one line", entry.SQL)
+	}
+}
+
+func TestQueryTracerSlowQueryDisabled(t *testing.T) {
+	logs := captureWarnings(t)
+	tracer := queryTracer{}
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	time.Sleep(5 * time.Millisecond)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	if logs.Len() != 0 {
+		t.Errorf("logged %s with no threshold, want nothing", logs)
+	}
+}
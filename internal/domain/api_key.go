@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// APIKeyScope names a group of routes an API key may call
+type APIKeyScope string
+
+// Supported API key scopes
+const (
+	ScopeUsersRead  APIKeyScope = "users:read"
+	ScopeUsersWrite APIKeyScope = "users:write"
+	ScopeAuditRead  APIKeyScope = "audit:read"
+)
+
+// APIKeyScopes is the set of valid APIKeyScope values
+var APIKeyScopes = map[APIKeyScope]bool{
+	ScopeUsersRead:  true,
+	ScopeUsersWrite: true,
+	ScopeAuditRead:  true,
+}
+
+// APIKey is a long-lived credential for backend services. Only the hash of
+// the key is stored; the plaintext is shown once when the key is created.
+type APIKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// OwnerID is the admin who created the key and is recorded as the actor
+	// of the changes made with it
+	OwnerID string `json:"owner_id"`
+	// Prefix is the start of the plaintext key, kept so keys can be told apart
+	Prefix     string        `json:"prefix"`
+	KeyHash    string        `json:"-"`
+	Scopes     []APIKeyScope `json:"scopes"`
+	LastUsedAt *time.Time    `json:"last_used_at"`
+	RevokedAt  *time.Time    `json:"revoked_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// HasScope reports whether the key grants the scope
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
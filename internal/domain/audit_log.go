@@ -0,0 +1,73 @@
+package domain
+
+import "time"
+
+// AuditAction names a recorded change
+type AuditAction string
+
+// Audited actions
+const (
+	AuditUserCreate        AuditAction = "user.create"
+	AuditUserRegister      AuditAction = "user.register"
+	AuditUserUpdate        AuditAction = "user.update"
+	AuditUserDelete        AuditAction = "user.delete"
+	AuditUserRestore       AuditAction = "user.restore"
+	AuditUserErase         AuditAction = "user.erase"
+	AuditUserLogin         AuditAction = "user.login"
+	AuditUserSuspend       AuditAction = "user.suspend"
+	AuditUserReactivate    AuditAction = "user.reactivate"
+	AuditPasswordChange    AuditAction = "user.password_change"
+	AuditPasswordReset     AuditAction = "user.password_reset"
+	AuditEmailVerify       AuditAction = "user.email_verify"
+	AuditEmailChange       AuditAction = "user.email_change"
+	AuditTwoFactorEnable   AuditAction = "user.2fa_enable"
+	AuditPreferencesUpdate AuditAction = "user.preferences_update"
+	AuditOAuthLink         AuditAction = "user.oauth_link"
+	AuditImpersonate       AuditAction = "user.impersonate"
+	AuditRolesUpdate       AuditAction = "user.roles_update"
+	AuditAPIKeyCreate      AuditAction = "api_key.create"
+	AuditAPIKeyRevoke      AuditAction = "api_key.revoke"
+	AuditWebhookCreate     AuditAction = "webhook.create"
+)
+
+// AuditActions is the set of valid AuditAction values
+var AuditActions = map[AuditAction]bool{
+	AuditUserCreate:        true,
+	AuditUserRegister:      true,
+	AuditUserUpdate:        true,
+	AuditUserDelete:        true,
+	AuditUserRestore:       true,
+	AuditUserErase:         true,
+	AuditUserLogin:         true,
+	AuditUserSuspend:       true,
+	AuditUserReactivate:    true,
+	AuditPasswordChange:    true,
+	AuditPasswordReset:     true,
+	AuditEmailVerify:       true,
+	AuditEmailChange:       true,
+	AuditTwoFactorEnable:   true,
+	AuditPreferencesUpdate: true,
+	AuditOAuthLink:         true,
+	AuditImpersonate:       true,
+	AuditRolesUpdate:       true,
+	AuditAPIKeyCreate:      true,
+	AuditAPIKeyRevoke:      true,
+	AuditWebhookCreate:     true,
+}
+
+// AuditLog records who performed an action on which user, API key, or webhook
+type AuditLog struct {
+	ID string `json:"id"`
+	// ActorID is the user who acted; for sign-ups, logins, and token based
+	// flows it is the target user acting on their own account
+	ActorID string `json:"actor_id"`
+	// ImpersonatedID is the user the actor impersonated while acting, if any
+	ImpersonatedID string      `json:"impersonated_id,omitempty"`
+	Action         AuditAction `json:"action"`
+	TargetID       string      `json:"target_id"`
+	RequestID      string      `json:"request_id,omitempty"`
+	// IP is the client address, taken from the proxy header only when it
+	// came from a trusted proxy
+	IP        string    `json:"ip,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,10 @@
+package domain
+
+import "strings"
+
+// NormalizeEmail trims and lowercases an email address, so differently
+// cased copies of an address belong to one account. The local part is
+// lowercased too; no mail provider in practice treats it as case-sensitive.
+func NormalizeEmail(raw string) string {
+	return strings.ToLower(strings.TrimSpace(raw))
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"ada@example.com", "ada@example.com"},
+		{"Ada@Example.COM", "ada@example.com"},
+		{"  ada@example.com\t", "ada@example.com"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeEmail(tt.raw); got != tt.want {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
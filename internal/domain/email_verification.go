@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// EmailVerification is a single-use token proving ownership of a user's email
+type EmailVerification struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	// NewEmail is the address an email change moves the user to once the
+	// token is confirmed; it is empty for verifying the current address
+	NewEmail  string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
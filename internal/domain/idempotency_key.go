@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// IdempotencyKey records a client-supplied key for a request and, once the
+// request has finished, the response to replay when the key is sent again
+type IdempotencyKey struct {
+	// Owner is the caller the key belongs to, so callers who pick the same
+	// key never see each other's responses
+	Owner    string
+	Key      string
+	Endpoint string
+	// RequestHash fingerprints the request body so a reused key with a
+	// different body can be rejected
+	RequestHash  string
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	CreatedAt    time.Time
+	// CompletedAt is nil while the original request is still in flight
+	CompletedAt *time.Time
+	ExpiresAt   time.Time
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// OAuthIdentity links an account at an external identity provider to a user
+type OAuthIdentity struct {
+	// Provider names the identity provider, such as "google"
+	Provider string
+	// Subject is the provider's stable id for the account
+	Subject   string
+	UserID    string
+	CreatedAt time.Time
+}
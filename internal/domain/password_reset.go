@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// PasswordReset is a single-use token allowing a user to set a new password
+type PasswordReset struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,50 @@
+package domain
+
+// Permission names an action roles may allow, such as users:read
+type Permission string
+
+// Permissions the routes require. Roles are granted them in the
+// role_permissions table, so new roles are composed there.
+const (
+	PermissionUsersRead        Permission = "users:read"
+	PermissionUsersWrite       Permission = "users:write"
+	PermissionUsersDelete      Permission = "users:delete"
+	PermissionUsersImpersonate Permission = "users:impersonate"
+	PermissionRolesManage      Permission = "roles:manage"
+	PermissionAuditRead        Permission = "audit:read"
+	PermissionAPIKeysManage    Permission = "api_keys:manage"
+	PermissionWebhooksManage   Permission = "webhooks:manage"
+	PermissionHealthRead       Permission = "health:read"
+)
+
+// PermissionScopes maps the permissions API keys may exercise to the scope
+// they need for it; keys are refused the others
+var PermissionScopes = map[Permission]APIKeyScope{
+	PermissionUsersRead:   ScopeUsersRead,
+	PermissionUsersWrite:  ScopeUsersWrite,
+	PermissionUsersDelete: ScopeUsersWrite,
+	PermissionAuditRead:   ScopeAuditRead,
+}
+
+// DefaultRolePermissions are the permissions migrations grant the built-in
+// roles. Users hold none, so they only reach their own account.
+var DefaultRolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermissionUsersRead, PermissionUsersWrite, PermissionUsersDelete, PermissionUsersImpersonate,
+		PermissionRolesManage, PermissionAuditRead, PermissionAPIKeysManage, PermissionWebhooksManage,
+		PermissionHealthRead,
+	},
+	RoleSuperAdmin: {
+		PermissionUsersRead, PermissionUsersWrite, PermissionUsersDelete, PermissionUsersImpersonate,
+		PermissionRolesManage, PermissionAuditRead, PermissionAPIKeysManage, PermissionWebhooksManage,
+		PermissionHealthRead,
+	},
+}
+
+// PermissionSet is the set of permissions a caller holds
+type PermissionSet map[Permission]bool
+
+// Has reports whether the set includes the permission
+func (s PermissionSet) Has(permission Permission) bool {
+	return s[permission]
+}
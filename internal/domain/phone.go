@@ -0,0 +1,30 @@
+package domain
+
+import "strings"
+
+// phoneSeparators are the formatting characters dropped from phone numbers
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// NormalizePhone converts a phone number to canonical E.164, such as
+// +15551234567, dropping spaces, dashes, dots, and parentheses so differently
+// formatted copies of a number compare equal. It reports false when the
+// number is not a + followed by 8 to 15 digits without a leading zero. An
+// empty number stays empty.
+func NormalizePhone(raw string) (string, bool) {
+	if raw == "" {
+		return "", true
+	}
+
+	phone := phoneSeparators.Replace(raw)
+	digits, ok := strings.CutPrefix(phone, "+")
+	if !ok || len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+
+	return phone, true
+}
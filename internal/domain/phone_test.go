@@ -0,0 +1,30 @@
+package domain
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+		ok   bool
+	}{
+		{"+15551234567", "+15551234567", true},
+		{"+1 (555) 123-4567", "+15551234567", true},
+		{"+44 20.7946.0958", "+442079460958", true},
+		{"", "", true},
+		{"5551234567", "", false},
+		{"+0551234567", "", false},
+		{"+1555", "", false},
+		{"+1234567890123456", "", false},
+		{"+1555123456x", "", false},
+		{"++15551234567", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := NormalizePhone(tt.raw)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("NormalizePhone(%q) = %q, %v, want %q, %v", tt.raw, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// Preferences are the settings a user keeps for clients to honor, such as
+// how to localize times and which emails they want
+type Preferences struct {
+	UserID string `json:"-"`
+	// Locale is a canonical BCP 47 language tag such as en-US
+	Locale string `json:"locale"`
+	// Timezone is an IANA time zone name such as Europe/Berlin
+	Timezone      string                  `json:"timezone"`
+	Notifications NotificationPreferences `json:"notifications"`
+	UpdatedAt     time.Time               `json:"updated_at"`
+}
+
+// NotificationPreferences toggles the kinds of notifications a user receives
+type NotificationPreferences struct {
+	// Email covers account activity, such as new sign-ins
+	Email bool `json:"email"`
+	// Marketing covers product news and offers
+	Marketing bool `json:"marketing"`
+}
+
+// DefaultPreferences returns the preferences of a user who has not set any
+func DefaultPreferences(userID string) *Preferences {
+	return &Preferences{
+		UserID:        userID,
+		Locale:        "en",
+		Timezone:      "UTC",
+		Notifications: NotificationPreferences{Email: true},
+	}
+}
+
+// NormalizeLocale converts a BCP 47 language tag to its canonical form, such
+// as en-US for en_us, so equal locales compare equal. It reports false for
+// malformed tags and the undetermined und.
+func NormalizeLocale(raw string) (string, bool) {
+	tag, err := language.Parse(raw)
+	if err != nil || tag == language.Und {
+		return "", false
+	}
+
+	return tag.String(), true
+}
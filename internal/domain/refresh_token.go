@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// RefreshToken is a long-lived credential exchanged for new access tokens.
+// Tokens issued by rotating one another share the same FamilyID.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	Revoked   bool
+	// UserAgent and IP describe the client the token was issued to
+	UserAgent string
+	IP        string
+	CreatedAt time.Time
+}
+
+// Session is a login on one device: the family of refresh tokens issued
+// since the user signed in there
+type Session struct {
+	// ID is the FamilyID of the session's tokens
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastUsedAt is when the session last refreshed its tokens, or signed in
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// TwoFactor is a user's TOTP enrollment. It is pending until the user proves
+// their authenticator works by confirming a code, and only then enforced.
+type TwoFactor struct {
+	UserID string
+	// Secret is the base32 TOTP secret shared with the authenticator app
+	Secret      string
+	ConfirmedAt *time.Time
+	CreatedAt   time.Time
+	// LastTOTPStep is the time step of the last TOTP code accepted, which
+	// cannot be accepted again; 0 until a code has been used
+	LastTOTPStep int64
+}
+
+// Enabled reports whether the enrollment has been confirmed
+func (t *TwoFactor) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Role determines what a user is allowed to do
+type Role string
+
+// Built-in roles. Super admins are admins who may also impersonate other
+// admins; the role is granted in the database, never through the API.
+const (
+	RoleUser       Role = "user"
+	RoleAdmin      Role = "admin"
+	RoleSuperAdmin Role = "super_admin"
+)
+
+// roleRanks orders the roles so each grants the ones below it
+var roleRanks = map[Role]int{RoleUser: 1, RoleAdmin: 2, RoleSuperAdmin: 3}
+
+// Grants reports whether the role includes what required allows, as super
+// admins may do everything admins may. Roles outside the built-in ones only
+// grant themselves.
+func (r Role) Grants(required Role) bool {
+	if r == required {
+		return true
+	}
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
+
+// Roles is the set of roles a user holds
+type Roles []Role
+
+// Grants reports whether any of the roles includes what required allows
+func (rs Roles) Grants(required Role) bool {
+	return slices.ContainsFunc(rs, func(r Role) bool { return r.Grants(required) })
+}
+
+// Normalize returns the roles sorted and without duplicates
+func (rs Roles) Normalize() Roles {
+	normalized := slices.Clone(rs)
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// UserStatus tells whether a user may sign in
+type UserStatus string
+
+// Supported statuses; suspension is reversible, unlike deletion
+const (
+	StatusActive    UserStatus = "active"
+	StatusSuspended UserStatus = "suspended"
+)
+
+// User represents a user of the system
+type User struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	// Phone is in E.164 form, or empty when the user has none
+	Phone        string     `json:"phone,omitempty"`
+	Roles        Roles      `json:"roles"`
+	Status       UserStatus `json:"status"`
+	PasswordHash string     `json:"-"`
+	// EmailVerifiedAt is nil until the user confirms their email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// AvatarURL is where the uploaded avatar is served from, and AvatarKey
+	// names it in storage; both are empty when the user has none
+	AvatarURL string `json:"avatar_url,omitempty"`
+	AvatarKey string `json:"-"`
+	// FailedLoginAttempts counts consecutive failed logins since the last success or lock
+	FailedLoginAttempts int        `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	// Version starts at 1 and increases with every write, which succeeds only
+	// while it still matches the stored version
+	Version int `json:"version"`
+	// DeletedAt is set when the user has been soft-deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Suspended reports whether an admin has blocked the user from signing in
+func (u *User) Suspended() bool {
+	return u.Status == StatusSuspended
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRolesGrants(t *testing.T) {
+	tests := []struct {
+		name     string
+		roles    Roles
+		required Role
+		want     bool
+	}{
+		{"same role", Roles{RoleUser}, RoleUser, true},
+		{"higher role", Roles{RoleAdmin}, RoleUser, true},
+		{"super admin", Roles{RoleSuperAdmin}, RoleAdmin, true},
+		{"lower role", Roles{RoleUser}, RoleAdmin, false},
+		{"any of several", Roles{"support", RoleAdmin}, RoleAdmin, true},
+		{"custom role grants itself", Roles{"support"}, "support", true},
+		{"custom role grants nothing else", Roles{"support"}, RoleUser, false},
+		{"no roles", nil, RoleUser, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.roles.Grants(tt.required); got != tt.want {
+				t.Errorf("%v.Grants(%q) = %v, want %v", tt.roles, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolesNormalize(t *testing.T) {
+	roles := Roles{RoleUser, RoleAdmin, RoleUser}
+
+	if got, want := roles.Normalize(), (Roles{RoleAdmin, RoleUser}); !slices.Equal(got, want) {
+		t.Errorf("Normalize = %v, want %v", got, want)
+	}
+	if !slices.Equal(roles, Roles{RoleUser, RoleAdmin, RoleUser}) {
+		t.Errorf("Normalize changed its receiver to %v", roles)
+	}
+}
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// WebhookEvent names a user lifecycle event webhooks can subscribe to
+type WebhookEvent string
+
+// Supported webhook events
+const (
+	EventUserCreated WebhookEvent = "user.created"
+	EventUserUpdated WebhookEvent = "user.updated"
+	EventUserDeleted WebhookEvent = "user.deleted"
+)
+
+// WebhookEvents is the set of valid WebhookEvent values
+var WebhookEvents = map[WebhookEvent]bool{
+	EventUserCreated: true,
+	EventUserUpdated: true,
+	EventUserDeleted: true,
+}
+
+// Webhook is an endpoint notified of the events it subscribes to
+type Webhook struct {
+	ID     string         `json:"id"`
+	URL    string         `json:"url"`
+	Events []WebhookEvent `json:"events"`
+	// Secret signs every delivery; it is only shown when the webhook is created
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DeliveryStatus is the state of a webhook delivery
+type DeliveryStatus string
+
+// Webhook delivery states
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery records the attempts to send one event to one webhook
+type WebhookDelivery struct {
+	ID        string         `json:"id"`
+	WebhookID string         `json:"webhook_id"`
+	Event     WebhookEvent   `json:"event"`
+	Payload   []byte         `json:"-"`
+	Status    DeliveryStatus `json:"status"`
+	Attempts  int            `json:"attempts"`
+	// LastStatusCode is the HTTP status of the last attempt, 0 when no response arrived
+	LastStatusCode int    `json:"last_status_code,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	// NextAttemptAt is when a pending delivery is next due, or until when the
+	// attempt under way holds it; finished deliveries have none
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// OutboxEvent is an event recorded in the same transaction as the change it
+// announces, waiting to be fanned out to the subscribed webhooks
+type OutboxEvent struct {
+	ID       string       `json:"id"`
+	Type     WebhookEvent `json:"type"`
+	Payload  []byte       `json:"-"`
+	Attempts int          `json:"attempts"`
+	// LastError is why the last fan-out failed
+	LastError string `json:"last_error,omitempty"`
+	// Dead marks an event that ran out of attempts; it is kept for
+	// inspection but never retried
+	Dead      bool       `json:"dead"`
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
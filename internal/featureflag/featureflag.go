@@ -0,0 +1,95 @@
+package featureflag
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Names of the flags gating features that roll out gradually
+const (
+	TwoFactor = "two_factor"
+	Webhooks  = "webhooks"
+	OAuth     = "oauth"
+)
+
+// defaults holds every known flag and whether it is on when not configured.
+// Features that have shipped default to on; new ones start off so their code
+// can ship dark.
+var defaults = map[string]bool{
+	TwoFactor: true,
+	Webhooks:  true,
+	OAuth:     true,
+}
+
+// Known reports whether name is a flag this build knows about
+func Known(name string) bool {
+	_, ok := defaults[name]
+	return ok
+}
+
+// Names returns the known flags in alphabetical order
+func Names() []string {
+	return slices.Sorted(maps.Keys(defaults))
+}
+
+// Flags is a set of feature flags. It is immutable, so one set can be shared
+// by every request.
+type Flags struct {
+	enabled map[string]bool
+}
+
+// New creates the flags of the defaults with the given flags turned on or
+// off, failing on a name that is not known
+func New(overrides map[string]bool) (*Flags, error) {
+	return (&Flags{enabled: defaults}).With(overrides)
+}
+
+// Enabled reports whether the named flag is on; unknown flags are off
+func (f *Flags) Enabled(name string) bool {
+	return f.enabled[name]
+}
+
+// With returns a copy of the flags with the given flags turned on or off,
+// failing on a name that is not known
+func (f *Flags) With(overrides map[string]bool) (*Flags, error) {
+	enabled := maps.Clone(f.enabled)
+	for name, on := range overrides {
+		if !Known(name) {
+			return nil, fmt.Errorf("unknown feature flag %q", name)
+		}
+		enabled[name] = on
+	}
+
+	return &Flags{enabled: enabled}, nil
+}
+
+// Parse reads a comma-separated list of name=true or name=false entries,
+// such as "webhooks=false,oauth=true". A bare name turns the flag on.
+func Parse(s string) (map[string]bool, error) {
+	overrides := make(map[string]bool)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		on := true
+		if hasValue {
+			var err error
+			if on, err = strconv.ParseBool(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("feature flag %q: invalid boolean %q", name, value)
+			}
+		}
+		if !Known(name) {
+			return nil, fmt.Errorf("unknown feature flag %q", name)
+		}
+		overrides[name] = on
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,84 @@
+package featureflag
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	flags, err := New(nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, name := range Names() {
+		if flags.Enabled(name) != defaults[name] {
+			t.Errorf("Enabled(%s) = %v, want the default %v", name, flags.Enabled(name), defaults[name])
+		}
+	}
+	if flags.Enabled("teleport") {
+		t.Error("an unknown flag is enabled")
+	}
+
+	flags, err = New(map[string]bool{Webhooks: false})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if flags.Enabled(Webhooks) || !flags.Enabled(OAuth) {
+		t.Errorf("webhooks %v and oauth %v, want only webhooks turned off", flags.Enabled(Webhooks), flags.Enabled(OAuth))
+	}
+
+	if _, err := New(map[string]bool{"teleport": true}); err == nil {
+		t.Error("New with an unknown flag succeeded")
+	}
+}
+
+func TestWithLeavesOriginal(t *testing.T) {
+	flags, err := New(nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	changed, err := flags.With(map[string]bool{OAuth: false})
+	if err != nil {
+		t.Fatalf("With: %v", err)
+	}
+	if changed.Enabled(OAuth) || !flags.Enabled(OAuth) {
+		t.Errorf("oauth is %v in the copy and %v in the original, want only the copy changed", changed.Enabled(OAuth), flags.Enabled(OAuth))
+	}
+	if !defaults[OAuth] {
+		t.Error("With changed the defaults")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]bool
+	}{
+		{"", map[string]bool{}},
+		{"webhooks=false,oauth=true", map[string]bool{Webhooks: false, OAuth: true}},
+		{" two_factor , webhooks = 0 ,", map[string]bool{TwoFactor: true, Webhooks: false}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"teleport", "webhooks=maybe"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) succeeded", in)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	if got := Names(); !slices.Equal(got, []string{OAuth, TwoFactor, Webhooks}) {
+		t.Errorf("Names() = %v, want every flag in alphabetical order", got)
+	}
+}
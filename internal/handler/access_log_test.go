@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+// newAccessLogApp serves routes answering with a range of statuses behind
+// AccessLog configured with cfg
+func newAccessLogApp(t *testing.T, cfg config.LogConfig) *testEnv {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(AccessLog(cfg))
+	app.Get("/ok", func(c fiber.Ctx) error { return c.SendStatus(http.StatusOK) })
+	app.Get("/redirect", func(c fiber.Ctx) error { return c.Redirect().To("/ok") })
+	app.Get("/bad", func(_ fiber.Ctx) error { return apperror.BadRequest(apperror.CodeBadRequest, "bad") })
+	app.Get("/boom", func(_ fiber.Ctx) error { return apperror.Internal(nil) })
+	app.Get("/slow", func(c fiber.Ctx) error {
+		time.Sleep(20 * time.Millisecond)
+		return c.SendStatus(http.StatusOK)
+	})
+
+	return &testEnv{t: t, app: app}
+}
+
+// countByStatus counts access entries per status, failing t when an entry
+// does not carry the sample rate it should
+func countByStatus(t *testing.T, entries []map[string]any, rate int) map[int]int {
+	t.Helper()
+
+	counts := make(map[int]int)
+	for _, entry := range entries {
+		status := int(entry["status"].(float64))
+		counts[status]++
+
+		sampled, ok := entry["sample_rate"]
+		switch {
+		case status >= http.StatusBadRequest && ok:
+			t.Errorf("error entry %v carries a sample rate", entry)
+		case status < http.StatusBadRequest && rate > 1 && sampled != float64(rate):
+			t.Errorf("sampled entry %v has sample_rate %v, want %d", entry, sampled, rate)
+		}
+	}
+
+	return counts
+}
+
+func TestAccessLogSamplesSuccessfulRequests(t *testing.T) {
+	const rate = 4
+	env := newAccessLogApp(t, config.LogConfig{AccessSampleRate: rate})
+	logs := captureLogs(t)
+
+	for range 3 * rate {
+		env.do(http.MethodGet, "/ok", "", nil).expect(http.StatusOK)
+		env.do(http.MethodGet, "/bad", "", nil).expect(http.StatusBadRequest)
+	}
+	for range rate {
+		env.do(http.MethodGet, "/redirect", "", nil).expect(http.StatusSeeOther)
+		env.do(http.MethodGet, "/boom", "", nil).expect(http.StatusInternalServerError)
+	}
+
+	counts := countByStatus(t, logs.find("http request"), rate)
+	// Errors between successes do not change which successes are sampled:
+	// 3*rate + rate of them yield four entries
+	if successes := counts[http.StatusOK] + counts[http.StatusSeeOther]; successes != 4 {
+		t.Errorf("logged %d successful requests, want one in %d of %d", successes, rate, 4*rate)
+	}
+	if counts[http.StatusBadRequest] != 3*rate || counts[http.StatusInternalServerError] != rate {
+		t.Errorf("logged %d client and %d server errors, want all %d and %d", counts[http.StatusBadRequest], counts[http.StatusInternalServerError], 3*rate, rate)
+	}
+}
+
+func TestAccessLogWithoutSampling(t *testing.T) {
+	env := newAccessLogApp(t, config.LogConfig{AccessSampleRate: 1})
+	logs := captureLogs(t)
+
+	for range 5 {
+		env.do(http.MethodGet, "/ok", "", nil).expect(http.StatusOK)
+	}
+
+	entries := logs.find("http request")
+	if counts := countByStatus(t, entries, 1); counts[http.StatusOK] != 5 {
+		t.Errorf("logged %d of 5 requests, want all", counts[http.StatusOK])
+	}
+	if _, ok := entries[0]["sample_rate"]; ok {
+		t.Errorf("unsampled entry %v carries a sample rate", entries[0])
+	}
+}
+
+func TestAccessLogKeepsSlowRequests(t *testing.T) {
+	env := newAccessLogApp(t, config.LogConfig{AccessSampleRate: 1000, AccessSlowThreshold: 10 * time.Millisecond})
+	logs := captureLogs(t)
+
+	// The first sampled request is logged, the next ones are not
+	for range 3 {
+		env.do(http.MethodGet, "/ok", "", nil).expect(http.StatusOK)
+	}
+	for range 3 {
+		env.do(http.MethodGet, "/slow", "", nil).expect(http.StatusOK)
+	}
+
+	var fast, slow int
+	for _, entry := range logs.find("http request") {
+		switch entry["path"] {
+		case "/ok":
+			fast++
+		case "/slow":
+			slow++
+			if entry["slow"] != true {
+				t.Errorf("slow entry %v is not flagged slow", entry)
+			}
+		}
+	}
+	if fast != 1 || slow != 3 {
+		t.Errorf("logged %d fast and %d slow requests, want 1 and every slow one", fast, slow)
+	}
+}
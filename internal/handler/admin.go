@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http/pprof"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+)
+
+// AdminDependencies holds the handlers the internal admin listener serves
+type AdminDependencies struct {
+	Metrics *Metrics
+	Health  *HealthHandler
+	// Pprof serves the net/http/pprof profiles under /debug/pprof
+	Pprof bool
+}
+
+// NewAdminApp creates the app of the internal admin listener, serving the
+// operational endpoints kept off the public listener. It has no
+// authentication, so its address must only be reachable from inside.
+func NewAdminApp(deps AdminDependencies) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	app.Get("/metrics", deps.Metrics.Handler())
+	app.Get("/health/details", deps.Health.Details)
+
+	if deps.Pprof {
+		registerPprof(app)
+	}
+
+	return app
+}
+
+// registerPprof mounts the pprof handlers. Index also serves the named
+// profiles, such as heap, goroutine and allocs, from the path after it.
+func registerPprof(app *fiber.App) {
+	debug := app.Group("/debug/pprof")
+
+	debug.Get("/cmdline", adaptor.HTTPHandlerFunc(pprof.Cmdline))
+	debug.Get("/profile", adaptor.HTTPHandlerFunc(pprof.Profile))
+	debug.Get("/symbol", adaptor.HTTPHandlerFunc(pprof.Symbol))
+	debug.Post("/symbol", adaptor.HTTPHandlerFunc(pprof.Symbol))
+	debug.Get("/trace", adaptor.HTTPHandlerFunc(pprof.Trace))
+	debug.Get("/*", adaptor.HTTPHandlerFunc(pprof.Index))
+}
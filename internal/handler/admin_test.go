@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// withAdminListener moves the operational endpoints to the admin app
+func withAdminListener(cfg *config.Config) {
+	cfg.Server.AdminAddr = "127.0.0.1:9090"
+}
+
+func TestAdminAppServesOperationalEndpoints(t *testing.T) {
+	env := newTestEnv(t, withAdminListener)
+	admin := &testEnv{t: t, app: env.admin}
+	token := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	env.do(http.MethodGet, "/metrics", "", nil).expect(http.StatusNotFound, "NOT_FOUND")
+	env.do(http.MethodGet, "/api/v1/health/details", token, nil).expect(http.StatusNotFound, "NOT_FOUND")
+	env.do(http.MethodGet, "/api/v1/health/ready", "", nil).expect(http.StatusOK)
+
+	resp := admin.do(http.MethodGet, "/metrics", "", nil)
+	resp.expect(http.StatusOK)
+	// The admin app reports the traffic of the public one
+	if want := `route="/api/v1/health/ready",status="2xx"} 1`; !strings.Contains(string(resp.body), want) {
+		t.Errorf("admin metrics have no %s", want)
+	}
+	admin.do(http.MethodGet, "/health/details", "", nil).expect(http.StatusOK)
+	admin.do(http.MethodGet, "/api/v1/users", token, nil).expect(http.StatusNotFound)
+}
+
+func TestPublicAppServesMetricsWithoutAdminListener(t *testing.T) {
+	env := newTestEnv(t)
+	if env.admin != nil {
+		t.Fatal("admin app built without an admin address")
+	}
+
+	env.do(http.MethodGet, "/metrics", "", nil).expect(http.StatusOK)
+}
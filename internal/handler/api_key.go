@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// createAPIKeyRequest is the request body for creating an API key
+type createAPIKeyRequest struct {
+	Name   string               `json:"name" validate:"required,max=100"`
+	Scopes []domain.APIKeyScope `json:"scopes" validate:"required,min=1,dive,oneof=users:read users:write audit:read"`
+}
+
+// createAPIKeyResponse is the created key along with its plaintext, which is only ever returned here
+type createAPIKeyResponse struct {
+	*domain.APIKey
+	Key string `json:"key"`
+}
+
+// apiKeyListResponse is the envelope returned by ListAPIKeys
+type apiKeyListResponse struct {
+	Data []*domain.APIKey `json:"data"`
+}
+
+// APIKeyHandler serves the API key management endpoints
+type APIKeyHandler struct {
+	keys *service.APIKeyService
+}
+
+// NewAPIKeyHandler creates an APIKeyHandler backed by the given service
+func NewAPIKeyHandler(keys *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// CreateAPIKey handler issues a new API key owned by the authenticated admin
+func (h *APIKeyHandler) CreateAPIKey(c fiber.Ctx) error {
+	var input createAPIKeyRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	ownerID, _ := CurrentUserID(c)
+	key, plaintext, err := h.keys.Create(c.Context(), ownerID, service.CreateAPIKeyInput{
+		Name:   input.Name,
+		Scopes: input.Scopes,
+	})
+	if err != nil {
+		return err
+	}
+
+	return respond(c.Status(fiber.StatusCreated), createAPIKeyResponse{APIKey: key, Key: plaintext})
+}
+
+// ListAPIKeys handler returns every API key without the key itself
+func (h *APIKeyHandler) ListAPIKeys(c fiber.Ctx) error {
+	keys, err := h.keys.List(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return respond(c, apiKeyListResponse{Data: keys})
+}
+
+// RevokeAPIKey handler revokes an API key by id
+func (h *APIKeyHandler) RevokeAPIKey(c fiber.Ctx) error {
+	if _, err := h.keys.Revoke(c.Context(), c.Params("id")); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return apperror.NotFound("API_KEY_NOT_FOUND", "api key not found")
+		}
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
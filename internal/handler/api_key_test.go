@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// createAPIKey issues a key with the scopes as the given admin
+func (e *testEnv) createAPIKey(token string, scopes ...domain.APIKeyScope) createAPIKeyResponse {
+	e.t.Helper()
+
+	var created createAPIKeyResponse
+	resp := e.do(http.MethodPost, "/api/v1/api-keys", token, map[string]any{"name": "billing", "scopes": scopes})
+	resp.expect(http.StatusCreated)
+	resp.decode(&created)
+
+	return created
+}
+
+// withAPIKey sends a request authenticated with the API key
+func (e *testEnv) withAPIKey(method, path, key string) *testResponse {
+	e.t.Helper()
+
+	req := newRequest(method, path, nil)
+	req.Header.Set(HeaderAPIKey, key)
+
+	return e.send(req)
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	user := env.createUser("ada@example.com")
+	key := env.createAPIKey(admin, domain.ScopeUsersRead)
+
+	env.withAPIKey(http.MethodGet, "/api/v1/users", key.Key).expect(http.StatusOK)
+	env.withAPIKey(http.MethodGet, "/api/v1/users/"+user.ID, key.Key).expect(http.StatusOK)
+	env.withAPIKey(http.MethodDelete, "/api/v1/users/"+user.ID, key.Key).expect(http.StatusForbidden, "INSUFFICIENT_SCOPE")
+	// Keys never manage keys, whatever their scopes
+	env.withAPIKey(http.MethodGet, "/api/v1/api-keys", key.Key).expect(http.StatusUnauthorized)
+	env.withAPIKey(http.MethodGet, "/api/v1/users", "not-a-key").expect(http.StatusUnauthorized, "INVALID_API_KEY")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		keys, err := env.apiKeyRepo.List(t.Context())
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if keys[0].LastUsedAt != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("last_used_at was never set")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var listed apiKeyListResponse
+	resp := env.do(http.MethodGet, "/api/v1/api-keys", admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&listed)
+	if len(listed.Data) != 1 || strings.Contains(string(resp.body), key.Key) {
+		t.Errorf("list = %s, want the one key without its plaintext", resp.body)
+	}
+
+	env.do(http.MethodDelete, "/api/v1/api-keys/"+key.ID, admin, nil).expect(http.StatusNoContent)
+	env.withAPIKey(http.MethodGet, "/api/v1/users", key.Key).expect(http.StatusUnauthorized, "INVALID_API_KEY")
+}
+
+func TestAPIKeysAreAdminOnly(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	env.do(http.MethodPost, "/api/v1/api-keys", token, map[string]any{"name": "mine", "scopes": []string{"users:read"}}).expect(http.StatusForbidden)
+}
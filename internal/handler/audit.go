@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/pagination"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/query"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// auditListFilters are the query parameters ListAuditLogs accepts besides paging
+var auditListFilters = []string{"actor_id", "action", "from", "to"}
+
+// AuditHandler serves the audit log endpoints
+type AuditHandler struct {
+	logs repository.AuditLogRepository
+}
+
+// NewAuditHandler creates an AuditHandler reading from the given repository
+func NewAuditHandler(logs repository.AuditLogRepository) *AuditHandler {
+	return &AuditHandler{logs: logs}
+}
+
+// ListAuditLogs handler returns a page of audit entries, newest first
+func (h *AuditHandler) ListAuditLogs(c fiber.Ctx) error {
+	list, filter, err := parseAuditQuery(c)
+	if err != nil {
+		return err
+	}
+
+	entries, total, err := h.logs.List(c.Context(), filter, list.Page, list.PageSize)
+	if err != nil {
+		return err
+	}
+
+	return respond(c, pagination.NewPagedResponse(entries, list.Page, list.PageSize, total))
+}
+
+// parseAuditQuery reads the paging and filter query parameters, rejecting
+// any it does not know; audit entries are always sorted newest first
+func parseAuditQuery(c fiber.Ctx) (*query.ListParams, repository.AuditLogFilter, error) {
+	var filter repository.AuditLogFilter
+	list, err := query.Parse(c, nil, auditListFilters)
+	if err != nil {
+		return nil, filter, err
+	}
+
+	if value, ok := list.Filters["actor_id"]; ok {
+		if err := uuid.Validate(value); err != nil {
+			return nil, filter, query.Invalid("actor_id must be a UUID")
+		}
+		filter.ActorID = value
+	}
+
+	if value, ok := list.Filters["action"]; ok {
+		action := domain.AuditAction(value)
+		if !domain.AuditActions[action] {
+			return nil, filter, query.Invalid("unsupported action %q", value)
+		}
+		filter.Action = action
+	}
+
+	if filter.From, err = list.Time("from"); err != nil {
+		return nil, filter, err
+	}
+	if filter.To, err = list.Time("to"); err != nil {
+		return nil, filter, err
+	}
+
+	return list, filter, nil
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/pagination"
+	"github.com/gofiber/fiber/v3"
+)
+
+// auditPage is a page of ListAuditLogs
+type auditPage = pagination.PagedResponse[domain.AuditLog]
+
+func TestCreateUserIsAudited(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	token := env.tokenFor(admin)
+
+	req := newRequest(http.MethodPost, "/api/v1/users", bulkUser("ada@example.com"))
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	req.Header.Set("X-Request-ID", "create-ada")
+	var created domain.User
+	resp := env.send(req)
+	resp.expect(http.StatusCreated)
+	resp.decode(&created)
+
+	var page auditPage
+	resp = env.do(http.MethodGet, "/api/v1/audit-logs?action=user.create", token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&page)
+	if len(page.Data) != 1 {
+		t.Fatalf("found %d user.create entries, want 1", len(page.Data))
+	}
+	entry := page.Data[0]
+	if entry.ActorID != admin.ID || entry.TargetID != created.ID || entry.RequestID != "create-ada" || entry.CreatedAt.IsZero() {
+		t.Errorf("entry = %+v, want the admin creating %s in request create-ada", entry, created.ID)
+	}
+
+	env.do(http.MethodGet, "/api/v1/audit-logs?actor_id="+created.ID, token, nil).decode(&page)
+	if len(page.Data) != 0 {
+		t.Errorf("entries by the created user = %d, want none", len(page.Data))
+	}
+}
+
+func TestListAuditLogsAccess(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	env.do(http.MethodGet, "/api/v1/audit-logs", env.tokenFor(env.createUser("ada@example.com")), nil).expect(http.StatusForbidden, apperror.CodeForbidden)
+	for _, query := range []string{"action=user.dance", "actor_id=42", "from=yesterday"} {
+		env.do(http.MethodGet, "/api/v1/audit-logs?"+query, admin, nil).expect(http.StatusBadRequest, "INVALID_QUERY")
+	}
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// loginRequest is the request body for logging in
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// registerRequest is the request body for public signup; any role in the body is ignored
+type registerRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Name     string `json:"name" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// registerResponse is returned after a successful signup; tokens are
+// omitted when login requires a verified email
+type registerResponse struct {
+	User   *domain.User   `json:"user"`
+	Tokens *tokenResponse `json:"tokens,omitempty"`
+}
+
+// refreshRequest is the request body for refreshing or revoking a session
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokenResponse is returned when tokens are issued
+type tokenResponse struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+}
+
+// newTokenResponse converts a token pair into its response body
+func newTokenResponse(pair *service.TokenPair) *tokenResponse {
+	return &tokenResponse{
+		AccessToken:  pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresAt:    pair.ExpiresAt,
+		RefreshToken: pair.RefreshToken,
+	}
+}
+
+// AuthHandler serves the authentication endpoints
+type AuthHandler struct {
+	users     *service.UserService
+	sessions  *service.SessionService
+	twoFactor *service.TwoFactorService
+}
+
+// NewAuthHandler creates an AuthHandler backed by the given services
+func NewAuthHandler(users *service.UserService, sessions *service.SessionService, twoFactor *service.TwoFactorService) *AuthHandler {
+	return &AuthHandler{users: users, sessions: sessions, twoFactor: twoFactor}
+}
+
+// Register handler creates a regular user account, sends a verification
+// email, and logs it in unless verification is required first
+func (h *AuthHandler) Register(c fiber.Ctx) error {
+	var input registerRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	user, err := h.users.Register(c.Context(), service.RegisterInput{
+		Email:    input.Email,
+		Name:     input.Name,
+		Password: input.Password,
+	})
+	if err != nil {
+		return userError(err)
+	}
+
+	resp := registerResponse{User: user}
+	if !h.users.VerificationRequired(user) {
+		pair, err := h.sessions.Start(c.Context(), user, sessionClient(c))
+		if err != nil {
+			return err
+		}
+		resp.Tokens = newTokenResponse(pair)
+	}
+
+	return respond(c.Status(fiber.StatusCreated), resp)
+}
+
+// Login handler verifies credentials and issues a new token pair. Users with
+// two-factor enabled get a 202 with an mfa_token for VerifyTwoFactor instead.
+func (h *AuthHandler) Login(c fiber.Ctx) error {
+	var input loginRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	user, err := h.users.Authenticate(c.Context(), input.Email, input.Password)
+	if err != nil {
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Set(fiber.HeaderRetryAfter, retryAfter(throttled.RetryAfter))
+		}
+		return authError(err)
+	}
+
+	return signIn(c, h.sessions, h.twoFactor, user)
+}
+
+// signIn answers a successful first login step with a token pair, or with a
+// 202 and an mfa_token when the user has two-factor enabled
+func signIn(c fiber.Ctx, sessions *service.SessionService, twoFactor *service.TwoFactorService, user *domain.User) error {
+	mfaToken, err := twoFactor.Challenge(c.Context(), user)
+	if err != nil {
+		return err
+	}
+	if mfaToken != "" {
+		return respond(c.Status(fiber.StatusAccepted), mfaChallengeResponse{MFARequired: true, MFAToken: mfaToken})
+	}
+
+	pair, err := sessions.Start(c.Context(), user, sessionClient(c))
+	if err != nil {
+		return err
+	}
+
+	return respond(c, newTokenResponse(pair))
+}
+
+// Refresh handler rotates a refresh token and issues a new token pair
+func (h *AuthHandler) Refresh(c fiber.Ctx) error {
+	var input refreshRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	pair, err := h.sessions.Refresh(c.Context(), input.RefreshToken, sessionClient(c))
+	if err != nil {
+		return authError(err)
+	}
+
+	return respond(c, newTokenResponse(pair))
+}
+
+// Logout handler revokes the presented refresh token, and the bearer access
+// token when the request carries one, ending the session at once
+func (h *AuthHandler) Logout(c fiber.Ctx) error {
+	var input refreshRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := h.sessions.End(c.Context(), input.RefreshToken); err != nil {
+		return err
+	}
+	if scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		if err := h.sessions.RevokeAccessToken(c.Context(), token); err != nil {
+			return err
+		}
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// authError translates service authentication errors into API errors;
+// credential failures never reveal which credential was wrong
+func authError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		return apperror.Unauthorized("INVALID_CREDENTIALS", "invalid email or password")
+	case errors.Is(err, service.ErrAccountLocked):
+		return apperror.Locked("ACCOUNT_LOCKED", "account is temporarily locked due to repeated failed logins")
+	case errors.Is(err, service.ErrLoginThrottled):
+		return apperror.TooManyRequests("LOGIN_THROTTLED", "too many failed logins for this email, try again later")
+	case errors.Is(err, service.ErrAccountSuspended):
+		return errAccountSuspended
+	case errors.Is(err, service.ErrEmailNotVerified):
+		return apperror.Forbidden("EMAIL_NOT_VERIFIED", "email address has not been verified")
+	case errors.Is(err, service.ErrInvalidRefreshToken):
+		return apperror.Unauthorized("INVALID_REFRESH_TOKEN", "invalid or expired refresh token")
+	case errors.Is(err, service.ErrInvalidVerificationToken):
+		return apperror.BadRequest("INVALID_VERIFICATION_TOKEN", "invalid or expired verification token")
+	case errors.Is(err, service.ErrInvalidResetToken):
+		return apperror.BadRequest("INVALID_RESET_TOKEN", "invalid or expired reset token")
+	case errors.Is(err, service.ErrWrongPassword):
+		return apperror.Unauthorized("INVALID_PASSWORD", "current password is incorrect")
+	case errors.Is(err, service.ErrPasswordUnchanged):
+		return apperror.BadRequest("PASSWORD_UNCHANGED", "new password must differ from the current password")
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		return apperror.Conflict("TWO_FACTOR_ALREADY_ENABLED", "two-factor authentication is already enabled")
+	case errors.Is(err, service.ErrTwoFactorNotPending):
+		return apperror.Conflict("TWO_FACTOR_NOT_PENDING", "call the enable endpoint before confirming two-factor authentication")
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		return apperror.Unauthorized("INVALID_TWO_FACTOR_CODE", "invalid two-factor code")
+	case errors.Is(err, service.ErrInvalidMFAToken):
+		return apperror.Unauthorized("INVALID_MFA_TOKEN", "invalid or expired mfa token")
+	}
+
+	return userError(err)
+}
+
+// retryAfter formats a wait as Retry-After seconds, rounded up
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// loginBody is the body of a login request with the given credentials
+func loginBody(email, password string) map[string]string {
+	return map[string]string{"email": email, "password": password}
+}
+
+func TestLoginIssuesToken(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+
+	resp := env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword))
+	resp.expect(http.StatusOK)
+
+	var body tokenResponse
+	resp.decode(&body)
+	if body.TokenType != "Bearer" || body.RefreshToken == "" {
+		t.Errorf("token response = %+v, want a bearer token with a refresh token", body)
+	}
+	claims, err := env.tokens.ParseToken(body.AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	if claims.Subject != user.ID {
+		t.Errorf("token subject = %q, want %q", claims.Subject, user.ID)
+	}
+	if !claims.ExpiresAt.Time.Equal(body.ExpiresAt.Truncate(time.Second)) {
+		t.Errorf("token expires at %v, response says %v", claims.ExpiresAt.Time, body.ExpiresAt)
+	}
+}
+
+func TestLoginRejectsBadCredentials(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{"wrong password", "ada@example.com", "not-the-password"},
+		{"unknown email", "nobody@example.com", testPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody(tt.email, tt.password))
+			resp.expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+		})
+	}
+}
+
+func TestRegisterReportsEveryInvalidField(t *testing.T) {
+	env := newTestEnv(t)
+
+	resp := env.do(http.MethodPost, "/api/v1/auth/register", "", map[string]string{"email": "not-an-email"})
+	resp.expect(http.StatusUnprocessableEntity, "VALIDATION_ERROR")
+
+	var body struct {
+		Error struct {
+			Details []struct {
+				Field string `json:"field"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	resp.decode(&body)
+	var fields []string
+	for _, detail := range body.Error.Details {
+		fields = append(fields, detail.Field)
+	}
+	if want := []string{"email", "name", "password"}; !slices.Equal(fields, want) {
+		t.Errorf("invalid fields = %v, want %v", fields, want)
+	}
+}
+
+// registerBody is the body of a signup with a valid password
+func registerBody(email string) map[string]any {
+	return map[string]any{"email": email, "name": "Test User", "password": testPassword}
+}
+
+func TestRegisterCannotEscalateRole(t *testing.T) {
+	env := newTestEnv(t)
+
+	body := registerBody("mallory@example.com")
+	body["roles"] = []string{"admin"}
+	body["role"] = "admin"
+	resp := env.do(http.MethodPost, "/api/v1/auth/register", "", body)
+	resp.expect(http.StatusCreated)
+	if strings.Contains(string(resp.body), "password") {
+		t.Errorf("register response %s exposes the password", resp.body)
+	}
+
+	var created registerResponse
+	resp.decode(&created)
+	stored, err := env.users.GetByID(t.Context(), created.User.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !slices.Equal(stored.Roles, domain.Roles{domain.RoleUser}) {
+		t.Errorf("registered roles = %v, want only %q", stored.Roles, domain.RoleUser)
+	}
+}
+
+func TestRegisterRejectsDuplicateEmail(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+
+	resp := env.do(http.MethodPost, "/api/v1/auth/register", "", registerBody("ada@example.com"))
+	resp.expect(http.StatusConflict, "EMAIL_EXISTS")
+}
+
+func TestRegisterRejectsEmailInAnotherCase(t *testing.T) {
+	env := newTestEnv(t)
+
+	resp := env.do(http.MethodPost, "/api/v1/auth/register", "", registerBody("Ada@Example.com"))
+	resp.expect(http.StatusCreated)
+	var created registerResponse
+	resp.decode(&created)
+	if created.User.Email != "ada@example.com" {
+		t.Errorf("registered email = %q, want %q", created.User.Email, "ada@example.com")
+	}
+
+	for _, email := range []string{"ada@example.com", "ADA@EXAMPLE.COM"} {
+		env.do(http.MethodPost, "/api/v1/auth/register", "", registerBody(email)).expect(http.StatusConflict, "EMAIL_EXISTS")
+	}
+}
+
+func TestLoginIgnoresEmailCase(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+
+	for _, email := range []string{"ada@example.com", "Ada@Example.com", " ADA@EXAMPLE.COM "} {
+		env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody(email, testPassword)).expect(http.StatusOK)
+	}
+}
+
+// lockoutConfig locks accounts after three failures for a short while, with
+// rate limits loose enough not to interfere
+func lockoutConfig(cfg *config.Config) {
+	cfg.Auth.LockoutThreshold = 3
+	cfg.Auth.LockoutDuration = 100 * time.Millisecond
+	cfg.RateLimit.LoginMax = 100
+	cfg.RateLimit.LoginEmailMax = 100
+}
+
+func TestLoginLockout(t *testing.T) {
+	env := newTestEnv(t, lockoutConfig)
+	env.createUser("ada@example.com")
+
+	for range 2 {
+		env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", "wrong")).expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", "wrong")).expect(http.StatusLocked, "ACCOUNT_LOCKED")
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusLocked, "ACCOUNT_LOCKED")
+
+	time.Sleep(150 * time.Millisecond)
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+
+	// The successful login reset the count
+	for range 2 {
+		env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", "wrong")).expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+}
+
+func TestLoginLockoutHidesUnknownEmails(t *testing.T) {
+	env := newTestEnv(t, lockoutConfig)
+
+	for range 2 {
+		env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("nobody@example.com", "wrong")).expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("nobody@example.com", "wrong")).expect(http.StatusLocked, "ACCOUNT_LOCKED")
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("nobody@example.com", "wrong")).expect(http.StatusLocked, "ACCOUNT_LOCKED")
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/gofiber/fiber/v3"
+)
+
+// AvatarHandler serves the current user's avatar endpoints
+type AvatarHandler struct {
+	avatars *service.AvatarService
+}
+
+// NewAvatarHandler creates an AvatarHandler backed by the given service
+func NewAvatarHandler(avatars *service.AvatarService) *AvatarHandler {
+	return &AvatarHandler{avatars: avatars}
+}
+
+// UploadAvatar handler stores the image in the multipart file field as the
+// authenticated user's avatar and returns the user
+func (h *AvatarHandler) UploadAvatar(c fiber.Ctx) error {
+	userID, _ := CurrentUserID(c)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return apperror.BadRequest("MISSING_FILE", "an image is required in the file field")
+	}
+	if header.Size > int64(h.avatars.MaxSize()) {
+		return avatarError(service.ErrAvatarTooLarge, h.avatars.MaxSize())
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(h.avatars.MaxSize())+1))
+	if err != nil {
+		return err
+	}
+
+	user, err := h.avatars.Upload(c.Context(), userID, data)
+	if err != nil {
+		return avatarError(err, h.avatars.MaxSize())
+	}
+
+	return respond(c, user)
+}
+
+// DeleteAvatar handler removes the authenticated user's avatar and returns the user
+func (h *AvatarHandler) DeleteAvatar(c fiber.Ctx) error {
+	userID, _ := CurrentUserID(c)
+
+	user, err := h.avatars.Remove(c.Context(), userID)
+	if err != nil {
+		return avatarError(err, h.avatars.MaxSize())
+	}
+
+	return respond(c, user)
+}
+
+// avatarError maps avatar service errors to API errors
+func avatarError(err error, maxSize int) error {
+	switch {
+	case errors.Is(err, service.ErrAvatarTooLarge):
+		return apperror.New(fiber.StatusRequestEntityTooLarge, "AVATAR_TOO_LARGE", fmt.Sprintf("avatar must be at most %d bytes", maxSize))
+	case errors.Is(err, service.ErrUnsupportedImage):
+		return apperror.UnprocessableEntity("UNSUPPORTED_IMAGE", "avatar must be a PNG, JPEG, or WebP image")
+	case errors.Is(err, service.ErrAvatarDimensions):
+		return apperror.UnprocessableEntity("AVATAR_DIMENSIONS", "avatar exceeds the maximum width or height")
+	}
+
+	return userError(err)
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+)
+
+// pngImage returns an encoded PNG of the given size
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encode PNG: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// uploadAvatar sends data as the avatar of the token's user
+func (e *testEnv) uploadAvatar(token, contentType string, data []byte) *testResponse {
+	e.t.Helper()
+
+	req := newUploadRequest(http.MethodPost, "/api/v1/me/avatar", "file", "avatar.png", contentType, data)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+
+	return e.send(req)
+}
+
+// avatarFile returns the local file backing the avatar URL
+func (e *testEnv) avatarFile(avatarURL string) string {
+	e.t.Helper()
+
+	u, err := url.Parse(avatarURL)
+	if err != nil {
+		e.t.Fatalf("parse avatar URL: %v", err)
+	}
+	key, ok := strings.CutPrefix(u.Path, "/uploads/")
+	if !ok {
+		e.t.Fatalf("avatar URL %q is not below /uploads", avatarURL)
+	}
+
+	return filepath.Join(e.cfg.Storage.LocalDir, filepath.FromSlash(key))
+}
+
+func TestUploadAvatar(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	var first domain.User
+	resp := env.uploadAvatar(token, "image/png", pngImage(t, 64, 64))
+	resp.expect(http.StatusOK)
+	resp.decode(&first)
+	if first.AvatarURL == "" {
+		t.Fatal("upload left the avatar URL empty")
+	}
+	firstFile := env.avatarFile(first.AvatarURL)
+	if _, err := os.Stat(firstFile); err != nil {
+		t.Fatalf("stored avatar: %v", err)
+	}
+
+	u, _ := url.Parse(first.AvatarURL)
+	served := env.do(http.MethodGet, u.Path, "", nil)
+	served.expect(http.StatusOK)
+	if got := served.Header.Get(fiber.HeaderContentType); got != "image/png" {
+		t.Errorf("served Content-Type = %q, want image/png", got)
+	}
+
+	// A new avatar replaces the file of the old one
+	var second domain.User
+	resp = env.uploadAvatar(token, "image/png", pngImage(t, 32, 32))
+	resp.expect(http.StatusOK)
+	resp.decode(&second)
+	if second.AvatarURL == first.AvatarURL {
+		t.Error("replacement kept the old avatar URL")
+	}
+	if _, err := os.Stat(firstFile); !os.IsNotExist(err) {
+		t.Errorf("replaced avatar file still exists: %v", err)
+	}
+}
+
+func TestUploadAvatarRejections(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Avatar.MaxSize = 4 << 10
+		cfg.Avatar.MaxDimension = 100
+		cfg.Avatar.Downscale = false
+	})
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	// The type is sniffed, so claiming image/png does not make text an image
+	env.uploadAvatar(token, "image/png", []byte("definitely not an image")).expect(http.StatusUnprocessableEntity, "UNSUPPORTED_IMAGE")
+	env.uploadAvatar(token, "image/gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")).expect(http.StatusUnprocessableEntity, "UNSUPPORTED_IMAGE")
+	env.uploadAvatar(token, "image/png", pngImage(t, 200, 50)).expect(http.StatusUnprocessableEntity, "AVATAR_DIMENSIONS")
+	env.uploadAvatar(token, "image/png", bytes.Repeat([]byte{0}, 8<<10)).expect(http.StatusRequestEntityTooLarge, "AVATAR_TOO_LARGE")
+
+	req := newRequest(http.MethodPost, "/api/v1/me/avatar", map[string]string{"file": "avatar.png"})
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	env.send(req).expect(http.StatusBadRequest, "MISSING_FILE")
+}
+
+func TestUploadAvatarDownscales(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Avatar.MaxDimension = 100 })
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	var user domain.User
+	resp := env.uploadAvatar(token, "image/png", pngImage(t, 400, 200))
+	resp.expect(http.StatusOK)
+	resp.decode(&user)
+
+	data, err := os.ReadFile(env.avatarFile(user.AvatarURL))
+	if err != nil {
+		t.Fatalf("read stored avatar: %v", err)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode stored avatar: %v", err)
+	}
+	if cfg.Width != 100 || cfg.Height != 50 {
+		t.Errorf("stored avatar is %dx%d, want 100x50", cfg.Width, cfg.Height)
+	}
+}
+
+func TestDeleteAvatar(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	var uploaded domain.User
+	resp := env.uploadAvatar(token, "image/png", pngImage(t, 16, 16))
+	resp.expect(http.StatusOK)
+	resp.decode(&uploaded)
+	file := env.avatarFile(uploaded.AvatarURL)
+
+	var deleted domain.User
+	resp = env.do(http.MethodDelete, "/api/v1/me/avatar", token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&deleted)
+	if deleted.AvatarURL != "" {
+		t.Errorf("avatar URL after delete = %q, want none", deleted.AvatarURL)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("deleted avatar file still exists: %v", err)
+	}
+
+	// Deleting again is a no-op
+	env.do(http.MethodDelete, "/api/v1/me/avatar", token, nil).expect(http.StatusOK)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/gofiber/fiber/v3"
+)
+
+// redacted replaces the values of sensitive fields in logged bodies
+const redacted = "[REDACTED]"
+
+// sensitiveFieldParts mark a JSON field as secret when its name contains one
+var sensitiveFieldParts = []string{"password", "token", "secret"}
+
+// sensitiveFields are secret JSON fields whose names carry none of
+// sensitiveFieldParts
+var sensitiveFields = map[string]bool{
+	"key":            true,
+	"api_key":        true,
+	"apikey":         true,
+	"recovery_codes": true,
+}
+
+// sensitiveRequestFields are also secret in request bodies, where a code is
+// a one-time login code; in responses it is an error code
+var sensitiveRequestFields = map[string]bool{
+	"code": true,
+}
+
+// BodyLog logs request and response bodies at debug level for diagnosing
+// client issues. Only JSON bodies are logged, with the values of sensitive
+// fields redacted and the result cut to cfg.BodyMaxSize bytes; other bodies
+// are logged by content type and size alone. Fiber buffers the request body,
+// so reading it here leaves it intact for the handler.
+func BodyLog(cfg config.LogConfig) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		l := logger.FromContext(c.Context())
+		if !l.Enabled(c.Context(), slog.LevelDebug) {
+			return c.Next()
+		}
+
+		request := loggedBody(c.Get(fiber.HeaderContentType), c.Body(), cfg.BodyMaxSize, sensitiveRequestFields)
+
+		// Render errors here so the logged body is the error response
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		l.LogAttrs(c.Context(), slog.LevelDebug, "http body",
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", c.Response().StatusCode()),
+			slog.String("request_body", request),
+			slog.String("response_body", loggedBody(string(c.Response().Header.ContentType()), c.Response().Body(), cfg.BodyMaxSize, nil)),
+		)
+
+		return nil
+	}
+}
+
+// loggedBody returns body as it may be logged: redacted, along with the
+// extra sensitive fields, and capped at maxSize bytes for JSON, and a summary
+// for anything else
+func loggedBody(contentType string, body []byte, maxSize int, extra map[string]bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != fiber.MIMEApplicationJSON && !strings.HasSuffix(mediaType, "+json") {
+		return bodySummary(mediaType, body)
+	}
+
+	var value any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		// Malformed JSON cannot be redacted, so none of it is logged
+		return bodySummary("invalid JSON", body)
+	}
+
+	out, err := json.Marshal(redact(value, extra))
+	if err != nil {
+		return bodySummary(mediaType, body)
+	}
+	if len(out) > maxSize {
+		return string(out[:maxSize]) + "... (" + strconv.Itoa(len(out)) + " bytes)"
+	}
+
+	return string(out)
+}
+
+// bodySummary describes a body that is not logged
+func bodySummary(kind string, body []byte) string {
+	if kind == "" {
+		kind = "unknown content type"
+	}
+
+	return "[" + kind + ", " + strconv.Itoa(len(body)) + " bytes]"
+}
+
+// redact replaces the values of sensitive fields, at any depth, in a decoded JSON value
+func redact(value any, extra map[string]bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for field, fieldValue := range v {
+			if isSensitiveField(field) || extra[strings.ToLower(field)] {
+				v[field] = redacted
+			} else {
+				v[field] = redact(fieldValue, extra)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redact(item, extra)
+		}
+	}
+
+	return value
+}
+
+// isSensitiveField reports whether a JSON field holds a secret
+func isSensitiveField(field string) bool {
+	field = strings.ToLower(field)
+	if sensitiveFields[field] {
+		return true
+	}
+	for _, part := range sensitiveFieldParts {
+		if strings.Contains(field, part) {
+			return true
+		}
+	}
+
+	return false
+}
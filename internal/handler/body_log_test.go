@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestBodyLogRedactsSecrets(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Log.Bodies = true })
+	env.createUser("ada@example.com")
+	logs := captureLogs(t)
+
+	// The login only succeeds when the handler reads the original password
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+
+	entries := logs.find("http body")
+	if len(entries) != 1 {
+		t.Fatalf("logged %d body entries, want 1", len(entries))
+	}
+	request, _ := entries[0]["request_body"].(string)
+	if strings.Contains(request, testPassword) || !strings.Contains(request, `"password":"[REDACTED]"`) {
+		t.Errorf("request_body = %q, want the password redacted", request)
+	}
+	if !strings.Contains(request, "ada@example.com") {
+		t.Errorf("request_body = %q, want the email kept", request)
+	}
+	response, _ := entries[0]["response_body"].(string)
+	if !strings.Contains(response, `"access_token":"[REDACTED]"`) || !strings.Contains(response, `"refresh_token":"[REDACTED]"`) {
+		t.Errorf("response_body = %q, want the tokens redacted", response)
+	}
+}
+
+func TestBodyLogKeepsRequestBody(t *testing.T) {
+	logs := captureLogs(t)
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(BodyLog(config.LogConfig{BodyMaxSize: 4096}))
+	var received string
+	app.Post("/", func(c fiber.Ctx) error {
+		received = string(c.Body())
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	env := &testEnv{t: t, app: app}
+
+	const body = `{"password":"hunter2","nested":{"api_key":"k-123"},"items":[{"token":"t"}]}`
+	env.do(http.MethodPost, "/", "", body).expect(http.StatusNoContent)
+	if received != body {
+		t.Errorf("handler received %q, want %q", received, body)
+	}
+
+	entries := logs.find("http body")
+	if len(entries) != 1 {
+		t.Fatalf("logged %d body entries, want 1", len(entries))
+	}
+	request, _ := entries[0]["request_body"].(string)
+	for _, secret := range []string{"hunter2", "k-123", `"t"`} {
+		if strings.Contains(request, secret) {
+			t.Errorf("request_body = %q leaks %s", request, secret)
+		}
+	}
+}
+
+func TestLoggedBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		maxSize     int
+		want        string
+	}{
+		{"empty", fiber.MIMEApplicationJSON, "", 64, ""},
+		{"json", fiber.MIMEApplicationJSON, `{"name":"Ada","Password":"x"}`, 64, `{"Password":"[REDACTED]","name":"Ada"}`},
+		{"capped", fiber.MIMEApplicationJSON, `{"name":"Ada Lovelace"}`, 12, `{"name":"Ada... (23 bytes)`},
+		{"not json", fiber.MIMETextPlain, "password=hunter2", 64, "[text/plain, 16 bytes]"},
+		{"malformed json", fiber.MIMEApplicationJSON, `{"password":`, 64, "[invalid JSON, 12 bytes]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loggedBody(tt.contentType, []byte(tt.body), tt.maxSize, nil); got != tt.want {
+				t.Errorf("loggedBody = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodyLogOffByDefault(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+	logs := captureLogs(t)
+
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+	if entries := logs.find("http body"); len(entries) != 0 {
+		t.Errorf("logged %d body entries with body logging off, want none", len(entries))
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// changePasswordRequest is the request body for changing the authenticated user's password
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,max=72"`
+	// RevokeOtherSessions signs out every session except the one RefreshToken belongs to
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+	RefreshToken        string `json:"refresh_token"`
+}
+
+// ChangePassword handler replaces the authenticated user's password after
+// checking the current one
+func (h *AuthHandler) ChangePassword(c fiber.Ctx) error {
+	var input changePasswordRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	userID, _ := CurrentUserID(c)
+	err := h.users.ChangePassword(c.Context(), userID, service.ChangePasswordInput{
+		CurrentPassword:     input.CurrentPassword,
+		NewPassword:         input.NewPassword,
+		RevokeOtherSessions: input.RevokeOtherSessions,
+		RefreshToken:        input.RefreshToken,
+	})
+	if err != nil {
+		return authError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
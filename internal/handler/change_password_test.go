@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+)
+
+// changePasswordBody is the body of a change-password request
+func changePasswordBody(current, next string) map[string]any {
+	return map[string]any{"current_password": current, "new_password": next}
+}
+
+func TestChangePassword(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	current := env.sessionFor(user)
+	other := env.sessionFor(user)
+
+	body := changePasswordBody(testPassword, "a-brand-new-passphrase")
+	body["revoke_other_sessions"] = true
+	body["refresh_token"] = current.RefreshToken
+	env.do(http.MethodPost, "/api/v1/me/change-password", current.AccessToken, body).expect(http.StatusNoContent)
+
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusUnauthorized)
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", "a-brand-new-passphrase")).expect(http.StatusOK)
+
+	env.do(http.MethodPost, "/api/v1/auth/refresh", "", refreshBody(current.RefreshToken)).expect(http.StatusOK)
+	env.do(http.MethodPost, "/api/v1/auth/refresh", "", refreshBody(other.RefreshToken)).expect(http.StatusUnauthorized, "INVALID_REFRESH_TOKEN")
+}
+
+func TestChangePasswordRejections(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	tests := []struct {
+		name    string
+		current string
+		next    string
+		status  int
+		code    string
+	}{
+		{"wrong current password", "not-the-password", "a-brand-new-passphrase", http.StatusUnauthorized, "INVALID_PASSWORD"},
+		{"weak new password", testPassword, "short", http.StatusUnprocessableEntity, "WEAK_PASSWORD"},
+		{"unchanged password", testPassword, testPassword, http.StatusBadRequest, "PASSWORD_UNCHANGED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := env.do(http.MethodPost, "/api/v1/me/change-password", token, changePasswordBody(tt.current, tt.next))
+			resp.expect(tt.status, tt.code)
+		})
+	}
+
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ClientIP returns the address of the client that sent the request. The
+// configured proxy header is only read when the direct peer is a trusted
+// proxy, and then from the right: each proxy appends the address it received
+// the request from, so the first entry that is not itself a trusted proxy is
+// the client. Entries further left were sent by the client and may be forged.
+func ClientIP(c fiber.Ctx) string {
+	remote := c.RequestCtx().RemoteIP().String()
+
+	cfg := c.App().Config()
+	if cfg.ProxyHeader == "" || !c.IsProxyTrusted() {
+		return remote
+	}
+
+	header := c.Get(cfg.ProxyHeader)
+	if header == "" {
+		return remote
+	}
+
+	trusted := parseProxies(cfg.TrustProxyConfig.Proxies)
+	entries := strings.Split(header, ",")
+	for i := len(entries) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(entries[i]))
+		if err != nil {
+			// A malformed hop cannot be attributed, so stop at the last proxy
+			return remote
+		}
+		addr = addr.Unmap()
+		// When every hop is a proxy, the leftmost one is the client
+		if !isTrustedProxy(addr, trusted) || i == 0 {
+			return addr.String()
+		}
+	}
+
+	return remote
+}
+
+// parseProxies parses the trusted proxy IPs and CIDRs, skipping invalid ones,
+// which Fiber has already ignored
+func parseProxies(proxies []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(proxy); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+
+	return prefixes
+}
+
+// isTrustedProxy reports whether addr belongs to a trusted proxy
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/gofiber/fiber/v3"
+)
+
+// testPeer is the remote address app.Test requests come from
+const testPeer = "0.0.0.0"
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		header  string
+		want    string
+	}{
+		{"no trusted proxies", nil, "203.0.113.7", testPeer},
+		{"untrusted peer", []string{"10.0.0.0/8"}, "203.0.113.7", testPeer},
+		{"trusted peer", []string{testPeer}, "203.0.113.7", "203.0.113.7"},
+		{"trusted peer without the header", []string{testPeer}, "", testPeer},
+		{"spoofed entry left of the client", []string{testPeer}, "198.51.100.1, 203.0.113.7", "203.0.113.7"},
+		{"chain of trusted proxies", []string{testPeer, "10.0.0.0/8"}, "198.51.100.1, 203.0.113.7, 10.0.0.5", "203.0.113.7"},
+		{"every hop a proxy", []string{testPeer, "10.0.0.0/8"}, "10.0.0.1, 10.0.0.2", "10.0.0.1"},
+		{"malformed hop", []string{testPeer}, "203.0.113.7, not-an-ip", testPeer},
+		{"IPv4-mapped address", []string{testPeer + "/32"}, "::ffff:203.0.113.7", "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Configured the way newTestEnv and main configure the app
+			fiberConfig := fiber.Config{}
+			if len(tt.proxies) > 0 {
+				fiberConfig.TrustProxy = true
+				fiberConfig.TrustProxyConfig = fiber.TrustProxyConfig{Proxies: tt.proxies}
+				fiberConfig.ProxyHeader = fiber.HeaderXForwardedFor
+			}
+			app := fiber.New(fiberConfig)
+			app.Get("/", func(c fiber.Ctx) error {
+				return c.SendString(ClientIP(c))
+			})
+			env := &testEnv{t: t, app: app}
+
+			req := newRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(fiber.HeaderXForwardedFor, tt.header)
+			}
+			resp := env.send(req)
+			resp.expect(http.StatusOK)
+			if got := string(resp.body); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitUsesClientIP(t *testing.T) {
+	get := func(env *testEnv, forwardedFor string) *testResponse {
+		req := newRequest(http.MethodGet, "/api/v1/health/live", nil)
+		req.Header.Set(fiber.HeaderXForwardedFor, forwardedFor)
+		return env.send(req)
+	}
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		env := newTestEnv(t, func(cfg *config.Config) {
+			cfg.RateLimit.Max = 1
+			cfg.Server.TrustedProxies = []string{testPeer}
+		})
+
+		get(env, "203.0.113.7").expect(http.StatusOK)
+		get(env, "203.0.113.7").expect(http.StatusTooManyRequests)
+		// Another client behind the proxy has its own budget
+		get(env, "203.0.113.8").expect(http.StatusOK)
+	})
+
+	t.Run("untrusted peer", func(t *testing.T) {
+		env := newTestEnv(t, func(cfg *config.Config) { cfg.RateLimit.Max = 1 })
+
+		get(env, "203.0.113.7").expect(http.StatusOK)
+		// A forged header does not buy a fresh budget
+		get(env, "203.0.113.8").expect(http.StatusTooManyRequests)
+	})
+}
+
+func TestAuditRecordsClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		want    string
+	}{
+		{"trusted proxy", []string{testPeer}, "203.0.113.7"},
+		{"untrusted peer", nil, testPeer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) { cfg.Server.TrustedProxies = tt.proxies })
+			env.createUser("ada@example.com")
+
+			req := newRequest(http.MethodPost, "/api/v1/auth/login", loginBody("ada@example.com", testPassword))
+			req.Header.Set(fiber.HeaderXForwardedFor, "198.51.100.1, 203.0.113.7")
+			env.send(req).expect(http.StatusOK)
+
+			entries, _, err := env.auditLogs.List(context.Background(), repository.AuditLogFilter{Action: domain.AuditUserLogin}, 1, 10)
+			if err != nil {
+				t.Fatalf("list audit entries: %v", err)
+			}
+			if len(entries) != 1 || entries[0].IP != tt.want {
+				t.Fatalf("login audit entries = %+v, want one from %s", entries, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"bytes"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/gofiber/fiber/v3"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MIMEMsgPack is the MessagePack media type clients ask for. The vnd and x-
+// variants in msgPackMIMEs are accepted as aliases.
+const MIMEMsgPack = "application/msgpack"
+
+// msgPackMIMEs lists the media types treated as MessagePack
+var msgPackMIMEs = map[string]bool{
+	MIMEMsgPack:                  true,
+	fiber.MIMEApplicationMsgPack: true,
+	"application/x-msgpack":      true,
+}
+
+// responseFormatKey stores the media type Negotiate picked for the response
+const responseFormatKey = "responseFormat"
+
+// errNotAcceptable is returned when no supported format matches Accept
+var errNotAcceptable = apperror.New(fiber.StatusNotAcceptable, "NOT_ACCEPTABLE", "supported response formats are application/json and application/msgpack")
+
+// Negotiate picks the response format from the Accept header, preferring
+// JSON, and rejects requests that accept neither JSON nor MessagePack with
+// 406. Paths with a file extension, such as /users/export.csv, fix their
+// format in the URL and are not negotiated.
+func Negotiate() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if path.Ext(c.Path()) != "" {
+			return c.Next()
+		}
+
+		c.Vary(fiber.HeaderAccept)
+		format := c.Accepts(fiber.MIMEApplicationJSON, MIMEMsgPack, fiber.MIMEApplicationMsgPack, "application/x-msgpack")
+		if format == "" {
+			return errNotAcceptable
+		}
+		c.Locals(responseFormatKey, format)
+
+		return c.Next()
+	}
+}
+
+// respond writes body in the format Negotiate picked, defaulting to JSON.
+// Handlers set the status on c beforehand, as with c.JSON.
+func respond(c fiber.Ctx, body any) error {
+	format, _ := c.Locals(responseFormatKey).(string)
+	if !msgPackMIMEs[format] {
+		return c.JSON(body)
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	// Encode with the json tags so the DTOs define both formats
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(body); err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, format)
+	return c.Send(buf.Bytes())
+}
+
+// decodeBody decodes the request body into v, as MessagePack when the
+// Content-Type says so and as JSON, with the app's JSONDecoder, otherwise
+func decodeBody(c fiber.Ctx, v any) error {
+	mediaType, _, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if !msgPackMIMEs[strings.ToLower(mediaType)] {
+		return c.App().Config().JSONDecoder(c.Body(), v)
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(c.Body()))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// decodeMsgPack decodes a MessagePack body into v by the json tags, the way
+// respond encodes it
+func decodeMsgPack(t *testing.T, body []byte, v any) {
+	t.Helper()
+
+	dec := msgpack.NewDecoder(bytes.NewReader(body))
+	dec.SetCustomStructTag("json")
+	if err := dec.Decode(v); err != nil {
+		t.Fatalf("decode MessagePack body %q: %v", body, err)
+	}
+}
+
+func TestNegotiateResponseFormat(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	token := env.tokenFor(user)
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+	}{
+		{"no Accept", "", fiber.MIMEApplicationJSON},
+		{"any", "*/*", fiber.MIMEApplicationJSON},
+		{"json", fiber.MIMEApplicationJSON, fiber.MIMEApplicationJSON},
+		{"msgpack", MIMEMsgPack, MIMEMsgPack},
+		{"msgpack alias", "application/x-msgpack", "application/x-msgpack"},
+		{"msgpack preferred", "application/json;q=0.5, application/msgpack", MIMEMsgPack},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(http.MethodGet, "/api/v1/me", nil)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			resp := env.send(req)
+			resp.expect(http.StatusOK)
+
+			if got := resp.Header.Get(fiber.HeaderContentType); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if !strings.Contains(resp.Header.Get(fiber.HeaderVary), fiber.HeaderAccept) {
+				t.Errorf("Vary = %q, want Accept in it", resp.Header.Get(fiber.HeaderVary))
+			}
+
+			var got domain.User
+			if tt.contentType == fiber.MIMEApplicationJSON {
+				if err := json.Unmarshal(resp.body, &got); err != nil {
+					t.Fatalf("decode JSON body %s: %v", resp.body, err)
+				}
+			} else {
+				decodeMsgPack(t, resp.body, &got)
+			}
+			if got.ID != user.ID || got.Email != user.Email {
+				t.Errorf("user = %+v, want %s", got, user.Email)
+			}
+		})
+	}
+}
+
+func TestNegotiateRejectsUnsupportedAccept(t *testing.T) {
+	env := newTestEnv(t)
+
+	req := newRequest(http.MethodGet, "/api/v1/health/live", nil)
+	req.Header.Set(fiber.HeaderAccept, "text/html")
+	env.send(req).expect(http.StatusNotAcceptable, "NOT_ACCEPTABLE")
+}
+
+func TestMsgPackErrorResponse(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	req := newRequest(http.MethodGet, "/api/v1/users/00000000-0000-0000-0000-000000000000", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+admin)
+	req.Header.Set(fiber.HeaderAccept, MIMEMsgPack)
+	resp := env.send(req)
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	var body errorBody
+	decodeMsgPack(t, resp.body, &body)
+	if body.Error.Code != "USER_NOT_FOUND" {
+		t.Errorf("error code = %q, want USER_NOT_FOUND", body.Error.Code)
+	}
+}
+
+func TestMsgPackRequestBody(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+
+	enc, err := msgpack.Marshal(map[string]string{"email": "ada@example.com", "password": testPassword})
+	if err != nil {
+		t.Fatalf("encode body: %v", err)
+	}
+	req := newRequest(http.MethodPost, "/api/v1/auth/login", enc)
+	req.Header.Set(fiber.HeaderContentType, MIMEMsgPack)
+	req.Header.Set(fiber.HeaderAccept, MIMEMsgPack)
+	resp := env.send(req)
+	resp.expect(http.StatusOK)
+
+	var tokens tokenResponse
+	decodeMsgPack(t, resp.body, &tokens)
+	if tokens.AccessToken == "" {
+		t.Errorf("login = %+v, want an access token", tokens)
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp"
+)
+
+// Compress encodes response bodies with brotli or gzip, whichever the client
+// accepts first in that order. Bodies below cfg.MinSize, already encoded
+// bodies, and non-text content types such as images are sent as they are.
+//
+// Responses carrying an ETag are never compressed: the tag identifies the
+// uncompressed representation, and changing it per encoding would break the
+// If-Match checks clients make with it.
+func Compress(cfg config.CompressConfig) fiber.Handler {
+	brotliLevel, gzipLevel := fasthttp.CompressBrotliDefaultCompression, fasthttp.CompressDefaultCompression
+	switch cfg.Level {
+	case "speed":
+		brotliLevel, gzipLevel = fasthttp.CompressBrotliBestSpeed, fasthttp.CompressBestSpeed
+	case "best":
+		brotliLevel, gzipLevel = fasthttp.CompressBrotliBestCompression, fasthttp.CompressBestCompression
+	}
+	compress := fasthttp.CompressHandlerBrotliLevel(func(*fasthttp.RequestCtx) {}, brotliLevel, gzipLevel)
+
+	return func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		resp := c.Response()
+		status := resp.StatusCode()
+		if c.Method() == fiber.MethodHead || status < fiber.StatusOK ||
+			status == fiber.StatusNoContent || status == fiber.StatusNotModified ||
+			resp.IsBodyStream() || len(resp.Body()) == 0 ||
+			len(resp.Header.ContentEncoding()) > 0 || c.GetRespHeader(fiber.HeaderETag) != "" {
+			return nil
+		}
+
+		// Whether the body gets compressed depends on Accept-Encoding from here on
+		c.Vary(fiber.HeaderAcceptEncoding)
+		if len(resp.Body()) < cfg.MinSize {
+			return nil
+		}
+
+		compress(c.RequestCtx())
+		return nil
+	}
+}
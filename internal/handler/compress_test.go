@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestCompress(t *testing.T) {
+	large := `{"data":"` + strings.Repeat("compressible ", 200) + `"}`
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(Compress(config.CompressConfig{Enabled: true, Level: "default", MinSize: 1024}))
+	app.Get("/large", func(c fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.SendString(large)
+	})
+	app.Get("/small", func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+	app.Get("/encoded", func(c fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		c.Set(fiber.HeaderContentEncoding, "identity")
+		return c.SendString(large)
+	})
+	app.Get("/image", func(c fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "image/png")
+		return c.SendString(large)
+	})
+	app.Get("/tagged", func(c fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		c.Set(fiber.HeaderETag, `"v1"`)
+		return c.SendString(large)
+	})
+	env := &testEnv{t: t, app: app}
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		encoding       string
+		vary           bool
+	}{
+		{"large with gzip", "/large", "gzip", "gzip", true},
+		{"large preferring brotli", "/large", "gzip, br", "br", true},
+		{"large without Accept-Encoding", "/large", "", "", true},
+		{"small with gzip", "/small", "gzip", "", true},
+		{"already encoded", "/encoded", "gzip", "identity", false},
+		{"image", "/image", "gzip", "", true},
+		{"with an ETag", "/tagged", "gzip", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set(fiber.HeaderAcceptEncoding, tt.acceptEncoding)
+			}
+			resp := env.send(req)
+			resp.expect(http.StatusOK)
+
+			if got := resp.Header.Get(fiber.HeaderContentEncoding); got != tt.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if got := strings.Contains(resp.Header.Get(fiber.HeaderVary), fiber.HeaderAcceptEncoding); got != tt.vary {
+				t.Errorf("Vary = %q, want Accept-Encoding in it: %v", resp.Header.Get(fiber.HeaderVary), tt.vary)
+			}
+			if tt.encoding == "gzip" {
+				if body := gunzip(t, resp.body); body != large {
+					t.Errorf("decompressed body is %d bytes, want the %d sent", len(body), len(large))
+				}
+			}
+		})
+	}
+}
+
+func TestCompressUserList(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		encoding string
+	}{
+		{"enabled", true, "gzip"},
+		{"disabled", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) { cfg.Compress.Enabled = tt.enabled })
+			admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+			for i := range 30 {
+				env.createUser(fmt.Sprintf("user%d@example.com", i))
+			}
+
+			req := newRequest(http.MethodGet, "/api/v1/users?page_size=50", nil)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer "+admin)
+			req.Header.Set(fiber.HeaderAcceptEncoding, "gzip")
+			resp := env.send(req)
+			resp.expect(http.StatusOK)
+
+			if got := resp.Header.Get(fiber.HeaderContentEncoding); got != tt.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			body := string(resp.body)
+			if tt.encoding == "gzip" {
+				body = gunzip(t, resp.body)
+			}
+			if !strings.Contains(body, "user29@example.com") {
+				t.Errorf("body does not list the users: %.200s", body)
+			}
+		})
+	}
+}
+
+// gunzip returns the decompressed body
+func gunzip(t *testing.T, body []byte) string {
+	t.Helper()
+
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("open gzip body: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read gzip body: %v", err)
+	}
+
+	return string(data)
+}
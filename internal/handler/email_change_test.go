@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+)
+
+// confirmEmailPath is the email change confirmation link for token
+func confirmEmailPath(token string) string {
+	return "/api/v1/auth/confirm-email?token=" + url.QueryEscape(token)
+}
+
+// storedEmail returns the email the user currently holds
+func (e *testEnv) storedEmail(user *domain.User) string {
+	e.t.Helper()
+
+	stored, err := e.users.GetByID(e.t.Context(), user.ID)
+	if err != nil {
+		e.t.Fatalf("GetByID: %v", err)
+	}
+
+	return stored.Email
+}
+
+func TestEmailChangeNeedsConfirmation(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	token := env.tokenFor(user)
+
+	var patched domain.User
+	resp := env.do(http.MethodPatch, "/api/v1/me", token, map[string]any{"email": "Ada@Lovelace.dev"})
+	resp.expect(http.StatusOK)
+	resp.decode(&patched)
+	if patched.Email != "ada@example.com" || env.storedEmail(user) != "ada@example.com" {
+		t.Errorf("email after the patch = %q, stored %q, want the old one until confirmed", patched.Email, env.storedEmail(user))
+	}
+
+	// The old address hears about the request, the new one gets the link
+	notices := env.mail.MessagesTo("ada@example.com")
+	if len(notices) != 1 || notices[0].Template != mailer.TemplateEmailChangeRequested {
+		t.Errorf("sent %+v to the old address, want one change notice", notices)
+	}
+	confirm := env.mailedToken("ada@lovelace.dev", mailer.TemplateConfirmEmailChange)
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@lovelace.dev", testPassword)).expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+
+	env.do(http.MethodGet, confirmEmailPath(confirm), "", nil).expect(http.StatusOK)
+	if got := env.storedEmail(user); got != "ada@lovelace.dev" {
+		t.Errorf("email after confirming = %q, want %q", got, "ada@lovelace.dev")
+	}
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@lovelace.dev", testPassword)).expect(http.StatusOK)
+
+	// The link is single-use
+	env.do(http.MethodGet, confirmEmailPath(confirm), "", nil).expect(http.StatusBadRequest, "INVALID_VERIFICATION_TOKEN")
+}
+
+func TestEmailChangePublishesUpdateOnConfirmation(t *testing.T) {
+	updates := make(chan []byte, 10)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		updates <- body
+	}))
+	defer endpoint.Close()
+
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Webhook.OutboxPollInterval = 5 * time.Millisecond })
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	env.do(http.MethodPost, "/api/v1/webhooks", admin, map[string]any{"url": endpoint.URL, "events": []string{"user.updated"}}).
+		expect(http.StatusCreated)
+	user := env.createUser("ada@example.com")
+
+	// Asking for the change leaves the user as it was
+	env.do(http.MethodPatch, "/api/v1/me", env.tokenFor(user), map[string]any{"email": "ada@lovelace.dev"}).expect(http.StatusOK)
+	if got := env.auditEntries(domain.AuditUserUpdate); len(got) != 0 {
+		t.Errorf("update entries = %+v for a pending email change, want none", got)
+	}
+
+	env.do(http.MethodGet, confirmEmailPath(env.mailedToken("ada@lovelace.dev", mailer.TemplateConfirmEmailChange)), "", nil).
+		expect(http.StatusOK)
+	if got := env.auditEntries(domain.AuditEmailChange); len(got) != 1 {
+		t.Errorf("email change entries = %+v, want one for the confirmation", got)
+	}
+
+	// The confirmed change is the only user.updated event
+	select {
+	case body := <-updates:
+		if !strings.Contains(string(body), `"email":"ada@lovelace.dev"`) {
+			t.Errorf("user.updated delivery = %s, want the confirmed address", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no user.updated delivery arrived after the confirmation")
+	}
+	select {
+	case body := <-updates:
+		t.Errorf("another user.updated delivery = %s, want one for the confirmation only", body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEmailChangeRejectsExpiredToken(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Auth.EmailVerificationTTL = time.Millisecond })
+	user := env.createUser("ada@example.com")
+
+	env.do(http.MethodPatch, "/api/v1/me", env.tokenFor(user), map[string]any{"email": "ada@lovelace.dev"}).expect(http.StatusOK)
+	confirm := env.mailedToken("ada@lovelace.dev", mailer.TemplateConfirmEmailChange)
+	time.Sleep(5 * time.Millisecond)
+
+	env.do(http.MethodGet, confirmEmailPath(confirm), "", nil).expect(http.StatusBadRequest, "INVALID_VERIFICATION_TOKEN")
+	if got := env.storedEmail(user); got != "ada@example.com" {
+		t.Errorf("email after an expired confirmation = %q, want it unchanged", got)
+	}
+}
+
+func TestEmailChangeToTakenAddress(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	token := env.tokenFor(user)
+	env.createUser("grace@example.com")
+
+	env.do(http.MethodPatch, "/api/v1/me", token, map[string]any{"email": "grace@example.com"}).expect(http.StatusConflict, "EMAIL_EXISTS")
+	if got := env.mail.MessagesTo("grace@example.com"); len(got) != 0 {
+		t.Errorf("sent %+v to the taken address, want nothing", got)
+	}
+
+	// An address taken after the request is refused when confirming
+	env.do(http.MethodPatch, "/api/v1/me", token, map[string]any{"email": "lin@example.com"}).expect(http.StatusOK)
+	confirm := env.mailedToken("lin@example.com", mailer.TemplateConfirmEmailChange)
+	env.createUser("lin@example.com")
+	env.do(http.MethodGet, confirmEmailPath(confirm), "", nil).expect(http.StatusConflict, "EMAIL_EXISTS")
+	if got := env.storedEmail(user); got != "ada@example.com" {
+		t.Errorf("email = %q, want it unchanged", got)
+	}
+}
+
+func TestVerificationTokenCannotConfirmEmailChange(t *testing.T) {
+	env := newTestEnv(t)
+	env.do(http.MethodPost, "/api/v1/auth/register", "", registerBody("ada@example.com")).expect(http.StatusCreated)
+	verify := env.mailedToken("ada@example.com", mailer.TemplateVerifyEmail)
+
+	env.do(http.MethodGet, confirmEmailPath(verify), "", nil).expect(http.StatusBadRequest, "INVALID_VERIFICATION_TOKEN")
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// deleteAccountRequest is the request body confirming the deletion of the
+// authenticated user's account
+type deleteAccountRequest struct {
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// ErasureHandler serves the account deletion endpoint
+type ErasureHandler struct {
+	erasure *service.ErasureService
+}
+
+// NewErasureHandler creates an ErasureHandler backed by the given service
+func NewErasureHandler(erasure *service.ErasureService) *ErasureHandler {
+	return &ErasureHandler{erasure: erasure}
+}
+
+// DeleteMe handler erases the authenticated user's account after checking
+// their password
+func (h *ErasureHandler) DeleteMe(c fiber.Ctx) error {
+	var input deleteAccountRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	userID, _ := CurrentUserID(c)
+	if err := h.erasure.Erase(c.Context(), userID, input.Password); err != nil {
+		return authError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
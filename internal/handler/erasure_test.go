@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// erasedUser returns the stored row of the erased user, which listings
+// only include along with deleted users
+func (e *testEnv) erasedUser(id string) *domain.User {
+	e.t.Helper()
+
+	users, _, err := e.users.List(context.Background(), repository.ListParams{
+		Filter: repository.UserFilter{IncludeDeleted: true}, Page: 1, PageSize: 100,
+	})
+	if err != nil {
+		e.t.Fatalf("list users: %v", err)
+	}
+	for _, user := range users {
+		if user.ID == id {
+			return user
+		}
+	}
+	e.t.Fatalf("user %s is gone, want it kept anonymized", id)
+
+	return nil
+}
+
+func TestDeleteMeAnonymizesAccount(t *testing.T) {
+	env := newTestEnv(t)
+	ada := env.createUser("ada@example.com")
+	ada.Phone = "+14155550100"
+	if err := env.users.Update(context.Background(), ada); err != nil {
+		t.Fatalf("set phone: %v", err)
+	}
+	token := env.tokenFor(ada)
+
+	env.do(http.MethodDelete, "/api/v1/me", token, map[string]string{"password": testPassword}).expect(http.StatusNoContent)
+
+	erased := env.erasedUser(ada.ID)
+	if erased.Name != "Deleted User" || erased.Phone != "" || erased.PasswordHash != "" || erased.DeletedAt == nil {
+		t.Errorf("erased user = %+v, want the name replaced, phone and password removed, and the row deleted", erased)
+	}
+	if !strings.HasSuffix(erased.Email, "@erased.invalid") || strings.Contains(erased.Email, "example.com") {
+		t.Errorf("erased email = %q, want a placeholder not derived from the address", erased.Email)
+	}
+
+	// Nobody can sign in as the user, and the address is free for a new account
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	env.do(http.MethodPost, "/api/v1/auth/register", "", registerBody("ada@example.com")).expect(http.StatusCreated)
+}
+
+func TestDeleteMeRevokesSessions(t *testing.T) {
+	env := newTestEnv(t)
+	ada := env.createUser("ada@example.com")
+	current := env.sessionFor(ada)
+	other := env.sessionFor(ada)
+
+	env.do(http.MethodDelete, "/api/v1/me", current.AccessToken, map[string]string{"password": testPassword}).expect(http.StatusNoContent)
+
+	for name, accessToken := range map[string]string{"current": current.AccessToken, "other": other.AccessToken} {
+		if resp := env.do(http.MethodGet, "/api/v1/me", accessToken, nil); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("access token of the %s session got %d, want %d", name, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+	for name, refreshToken := range map[string]string{"current": current.RefreshToken, "other": other.RefreshToken} {
+		if resp := env.do(http.MethodPost, "/api/v1/auth/refresh", "", refreshBody(refreshToken)); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("refresh token of the %s session got %d, want %d", name, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestDeleteMeKeepsAuditTrail(t *testing.T) {
+	env := newTestEnv(t)
+	ada := env.createUser("ada@example.com")
+
+	var tokens tokenResponse
+	resp := env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword))
+	resp.expect(http.StatusOK)
+	resp.decode(&tokens)
+	env.do(http.MethodDelete, "/api/v1/me", tokens.AccessToken, map[string]string{"password": testPassword}).expect(http.StatusNoContent)
+
+	logins := env.auditEntries(domain.AuditUserLogin)
+	if len(logins) != 1 || logins[0].ActorID != ada.ID {
+		t.Errorf("login entries = %+v, want the login before the erasure kept", logins)
+	}
+	erasures := env.auditEntries(domain.AuditUserErase)
+	if len(erasures) != 1 || erasures[0].ActorID != ada.ID || erasures[0].TargetID != ada.ID {
+		t.Fatalf("erasure entries = %+v, want one by and about the erased user", erasures)
+	}
+
+	// The entries still resolve to the anonymized user, not the person
+	if erased := env.erasedUser(erasures[0].TargetID); erased.Name != "Deleted User" {
+		t.Errorf("audited target is named %q, want the anonymized user", erased.Name)
+	}
+}
+
+func TestDeleteMeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   any
+		status int
+		code   string
+	}{
+		{"wrong password", map[string]string{"password": "not-the-password"}, http.StatusUnauthorized, "INVALID_PASSWORD"},
+		{"missing password", map[string]string{}, http.StatusUnprocessableEntity, "VALIDATION_ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ada := env.createUser("ada@example.com")
+			token := env.tokenFor(ada)
+
+			env.do(http.MethodDelete, "/api/v1/me", token, tt.body).expect(tt.status, tt.code)
+
+			// Nothing changed, not even the session that asked
+			env.do(http.MethodGet, "/api/v1/me", token, nil).expect(http.StatusOK)
+			if len(env.auditEntries(domain.AuditUserErase)) != 0 {
+				t.Errorf("a refused erasure was audited")
+			}
+		})
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.do(http.MethodDelete, "/api/v1/me", "", map[string]string{"password": testPassword}).expect(http.StatusUnauthorized, "MISSING_TOKEN")
+	})
+}
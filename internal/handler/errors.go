@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/database"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/i18n"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// errorBody is the JSON envelope for every error response
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+// errorDetail describes an error in a client-facing way
+type errorDetail struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// unavailableRetryAfter is the Retry-After of a 503, in seconds
+const unavailableRetryAfter = "1"
+
+// errInvalidBody is returned when a request body cannot be decoded
+var errInvalidBody = apperror.BadRequest("INVALID_BODY", "invalid request body")
+
+// ErrorHandler converts errors returned by handlers into the standard JSON
+// error shape, with the message in the language Accept-Language prefers
+func ErrorHandler(c fiber.Ctx, err error) error {
+	apiErr := localize(c, toAPIError(describeUnmatched(c, err)))
+
+	var constraintErr *repository.ConstraintError
+	switch {
+	case apiErr.Status >= http.StatusInternalServerError:
+		logger.FromContext(c.Context()).Error("request failed", slog.Any("error", err))
+	case errors.As(err, &constraintErr):
+		// Clients only see the kind of violation, so keep which constraint it was
+		logger.FromContext(c.Context()).Warn("write rejected by constraint", slog.Any("error", err))
+	}
+	if apiErr.Status == http.StatusServiceUnavailable {
+		c.Set(fiber.HeaderRetryAfter, unavailableRetryAfter)
+	}
+
+	return respond(c.Status(apiErr.Status), errorBody{
+		Error: errorDetail{
+			Code:      apiErr.Code,
+			Message:   apiErr.Message,
+			Details:   apiErr.Details,
+			RequestID: RequestID(c),
+		},
+	})
+}
+
+// localize translates the message of the error, and of its field errors, into
+// the language Accept-Language prefers. Codes stay the same in every
+// language, so clients can branch on them.
+func localize(c fiber.Ctx, apiErr *apperror.APIError) *apperror.APIError {
+	lang := i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage))
+	c.Vary(fiber.HeaderAcceptLanguage)
+	c.Set(fiber.HeaderContentLanguage, lang.String())
+	if lang == i18n.English {
+		return apiErr
+	}
+
+	localized := *apiErr
+	if message, ok := i18n.Error(lang, apiErr.Code); ok {
+		localized.Message = message
+	}
+	if fieldErrs, ok := apiErr.Details.([]validation.FieldError); ok {
+		localized.Details = validation.Localize(fieldErrs, lang)
+	}
+
+	return &localized
+}
+
+// describeUnmatched describes the errors Fiber's router returns when no route
+// matches the request, naming the method and path. For a path that exists
+// with other methods, Fiber has already listed them in the Allow header.
+func describeUnmatched(c fiber.Ctx, err error) error {
+	switch err {
+	case fiber.ErrNotFound:
+		return apperror.NotFound(apperror.CodeNotFound, fmt.Sprintf("no route matches %s %s", c.Method(), c.Path()))
+	case fiber.ErrMethodNotAllowed:
+		allowed := string(c.Response().Header.Peek(fiber.HeaderAllow))
+		return apperror.New(http.StatusMethodNotAllowed, apperror.CodeMethodNotAllowed,
+			fmt.Sprintf("%s is not allowed on %s; allowed methods: %s", c.Method(), c.Path(), allowed))
+	default:
+		return err
+	}
+}
+
+// constraintError maps a write rejected by a database constraint that the
+// service did not translate itself, without naming the constraint
+func constraintError(err error) *apperror.APIError {
+	switch {
+	case errors.Is(err, repository.ErrUniqueViolation):
+		return apperror.Conflict(apperror.CodeConflict, "a resource with the same unique value already exists")
+	case errors.Is(err, repository.ErrForeignKeyViolation):
+		return apperror.Conflict(apperror.CodeConflict, "the change refers to a resource that does not exist or is still in use")
+	case errors.Is(err, repository.ErrNotNullViolation):
+		return apperror.BadRequest(apperror.CodeBadRequest, "a required value is missing")
+	case errors.Is(err, repository.ErrCheckViolation):
+		return apperror.BadRequest(apperror.CodeBadRequest, "a value is not allowed")
+	default:
+		return nil
+	}
+}
+
+// toAPIError maps known error types to an APIError, hiding anything unexpected
+func toAPIError(err error) *apperror.APIError {
+	var apiErr *apperror.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var policyErr *auth.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return apperror.UnprocessableEntity("WEAK_PASSWORD", "password does not meet the password policy").
+			WithDetails(policyErr.Violations)
+	}
+
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		return apperror.UnprocessableEntity(apperror.CodeValidation, "request validation failed").
+			WithDetails(validationErr.Errors)
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		code := strings.ToUpper(strings.ReplaceAll(http.StatusText(fiberErr.Code), " ", "_"))
+		if code == "" {
+			code = apperror.CodeInternal
+		}
+		return apperror.New(fiberErr.Code, code, fiberErr.Message)
+	}
+
+	// Any write of a user can lose to a concurrent one, not only those of the
+	// user endpoints
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return apperror.Conflict("VERSION_CONFLICT", "user was modified since it was read; reload it and retry")
+	}
+
+	if apiErr := constraintError(err); apiErr != nil {
+		return apiErr
+	}
+
+	// The pool reconnects on its own, so the request may succeed when retried
+	if database.IsUnavailable(err) {
+		return apperror.ServiceUnavailable(err)
+	}
+
+	return apperror.Internal(err)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestUserNotFoundResponse(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	resp := env.do(http.MethodGet, "/api/v1/users/00000000-0000-0000-0000-000000000000", token, nil)
+	resp.expect(http.StatusNotFound, "USER_NOT_FOUND")
+
+	var body errorBody
+	resp.decode(&body)
+	if body.Error.Message != "user not found" {
+		t.Errorf("message = %q, want %q", body.Error.Message, "user not found")
+	}
+	if body.Error.RequestID == "" || body.Error.RequestID != resp.Header.Get("X-Request-ID") {
+		t.Errorf("request_id = %q, want the X-Request-ID %q", body.Error.RequestID, resp.Header.Get("X-Request-ID"))
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"api error", apperror.Conflict("EMAIL_EXISTS", "email already exists"), http.StatusConflict, "EMAIL_EXISTS"},
+		{"wrapped api error", fmt.Errorf("create: %w", apperror.NotFound("USER_NOT_FOUND", "user not found")), http.StatusNotFound, "USER_NOT_FOUND"},
+		{"fiber error", fiber.ErrRequestEntityTooLarge, http.StatusRequestEntityTooLarge, "REQUEST_ENTITY_TOO_LARGE"},
+		{"version conflict", repository.ErrVersionConflict, http.StatusConflict, "VERSION_CONFLICT"},
+		{"database restarting", fmt.Errorf("get user: %w", &pgconn.PgError{Code: "57P01"}), http.StatusServiceUnavailable, apperror.CodeUnavailable},
+		{"unexpected error", errors.New("connection reset by peer"), http.StatusInternalServerError, apperror.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+			app.Get("/", func(fiber.Ctx) error { return tt.err })
+			env := &testEnv{t: t, app: app}
+
+			resp := env.do(http.MethodGet, "/", "", nil)
+			resp.expect(tt.status, tt.code)
+			if strings.Contains(string(resp.body), "connection reset") {
+				t.Errorf("body %s leaks the internal error", resp.body)
+			}
+			if retry := resp.Header.Get(fiber.HeaderRetryAfter); (retry != "") != (tt.status == http.StatusServiceUnavailable) {
+				t.Errorf("Retry-After = %q on a %d", retry, tt.status)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerConstraintViolations(t *testing.T) {
+	tests := []struct {
+		code   string
+		kind   error
+		status int
+		want   string
+	}{
+		{"23505", repository.ErrUniqueViolation, http.StatusConflict, apperror.CodeConflict},
+		{"23503", repository.ErrForeignKeyViolation, http.StatusConflict, apperror.CodeConflict},
+		{"23502", repository.ErrNotNullViolation, http.StatusBadRequest, apperror.CodeBadRequest},
+		{"23514", repository.ErrCheckViolation, http.StatusBadRequest, apperror.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			err := &repository.ConstraintError{
+				Kind:       tt.kind,
+				Table:      "webhooks",
+				Constraint: "webhooks_secret_check",
+				Code:       tt.code,
+			}
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+			app.Get("/", func(fiber.Ctx) error { return fmt.Errorf("create webhook: %w", err) })
+			env := &testEnv{t: t, app: app}
+
+			resp := env.do(http.MethodGet, "/", "", nil)
+			resp.expect(tt.status, tt.want)
+			for _, leak := range []string{tt.code, "webhooks_secret_check", "webhooks"} {
+				if strings.Contains(string(resp.body), leak) {
+					t.Errorf("body %s leaks %q", resp.body, leak)
+				}
+			}
+		})
+	}
+}
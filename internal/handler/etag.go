@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// userETag returns the strong entity tag of the user's current state. Every
+// update changes UpdatedAt, so the tag changes with it.
+func userETag(user *domain.User) string {
+	sum := sha256.Sum256([]byte(user.ID + "|" + user.UpdatedAt.UTC().Format(time.RFC3339Nano)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether a comma-separated If-Match or If-None-Match
+// header lists the tag or "*". Weak tags only match when weak is set, as
+// If-Match requires strong comparison.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
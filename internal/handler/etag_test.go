@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+)
+
+// conditional sends a request with the given conditional header
+func (e *testEnv) conditional(method, path, token string, body any, header, etag string) *testResponse {
+	e.t.Helper()
+
+	req := newRequest(method, path, body)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	req.Header.Set(header, etag)
+
+	return e.send(req)
+}
+
+func TestGetUserNotModified(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	token := env.tokenFor(user)
+	path := "/api/v1/users/" + user.ID
+
+	resp := env.do(http.MethodGet, path, token, nil)
+	resp.expect(http.StatusOK)
+	etag := resp.Header.Get(fiber.HeaderETag)
+	if etag == "" {
+		t.Fatal("GetUser sent no ETag")
+	}
+
+	notModified := env.conditional(http.MethodGet, path, token, nil, fiber.HeaderIfNoneMatch, etag)
+	notModified.expect(http.StatusNotModified)
+	if len(notModified.body) != 0 {
+		t.Errorf("304 body = %q, want none", notModified.body)
+	}
+
+	env.do(http.MethodPatch, path, token, map[string]any{"name": "Ada Lovelace"}).expect(http.StatusOK)
+	changed := env.conditional(http.MethodGet, path, token, nil, fiber.HeaderIfNoneMatch, etag)
+	changed.expect(http.StatusOK)
+	if changed.Header.Get(fiber.HeaderETag) == etag {
+		t.Error("ETag did not change with the user")
+	}
+}
+
+func TestUpdateUserIfMatch(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	token := env.tokenFor(user)
+	path := "/api/v1/users/" + user.ID
+	body := map[string]any{"email": "ada@example.com", "name": "Ada Lovelace"}
+
+	env.do(http.MethodPut, path, token, body).expect(http.StatusPreconditionRequired, "PRECONDITION_REQUIRED")
+
+	stale := env.do(http.MethodGet, path, token, nil).Header.Get(fiber.HeaderETag)
+	updated := env.conditional(http.MethodPut, path, token, body, fiber.HeaderIfMatch, stale)
+	updated.expect(http.StatusOK)
+	current := updated.Header.Get(fiber.HeaderETag)
+	if current == "" || current == stale {
+		t.Errorf("ETag after PUT = %q, want a new one", current)
+	}
+
+	body["name"] = "Lost Update"
+	env.conditional(http.MethodPut, path, token, body, fiber.HeaderIfMatch, stale).expect(http.StatusPreconditionFailed, "PRECONDITION_FAILED")
+	stored, err := env.users.GetByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Name != "Ada Lovelace" {
+		t.Errorf("name = %q, want the stale update rejected", stored.Name)
+	}
+
+	env.conditional(http.MethodPut, path, token, body, fiber.HeaderIfMatch, current).expect(http.StatusOK)
+	env.conditional(http.MethodPut, path, token, body, fiber.HeaderIfMatch, "*").expect(http.StatusOK)
+}
+
+func TestETagMatches(t *testing.T) {
+	user := &domain.User{ID: "1", UpdatedAt: time.Now()}
+	etag := userETag(user)
+
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{etag, false, true},
+		{`"other", ` + etag, false, true},
+		{"*", false, true},
+		{`"other"`, false, false},
+		{"W/" + etag, true, true},
+		{"W/" + etag, false, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, etag, tt.weak); got != tt.want {
+			t.Errorf("etagMatches(%q, %q, %t) = %t, want %t", tt.header, etag, tt.weak, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+)
+
+// userExpansion loads a related resource of every listed user with a single
+// query, keyed by user id, so expanding a page of users costs one query per
+// expansion rather than one per user. Users missing from the result get null.
+type userExpansion func(ctx context.Context, users []*domain.User) (map[string]any, error)
+
+// lastLoginResponse is the last_login expansion of a user
+type lastLoginResponse struct {
+	At time.Time `json:"at"`
+	IP string    `json:"ip,omitempty"`
+}
+
+// parseExpand reads the comma-separated ?expand= list, rejecting names
+// outside allowed
+func parseExpand[T any](c fiber.Ctx, allowed map[string]T) (map[string]bool, error) {
+	if c.Query("expand") == "" {
+		return nil, nil
+	}
+
+	expand := make(map[string]bool)
+	for _, name := range strings.Split(c.Query("expand"), ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := allowed[name]; !ok {
+			return nil, apperror.BadRequest("INVALID_EXPAND", fmt.Sprintf("unknown expansion %q", name))
+		}
+		expand[name] = true
+	}
+
+	return expand, nil
+}
+
+// expandPreferences loads the preferences of the users
+func (h *UserHandler) expandPreferences(ctx context.Context, users []*domain.User) (map[string]any, error) {
+	preferences, err := h.preferences.GetMany(ctx, userIDs(users))
+	if err != nil {
+		return nil, err
+	}
+
+	related := make(map[string]any, len(preferences))
+	for id, p := range preferences {
+		related[id] = p
+	}
+
+	return related, nil
+}
+
+// expandLastLogin loads the time and client IP of the users' latest logins
+// from the audit log
+func (h *UserHandler) expandLastLogin(ctx context.Context, users []*domain.User) (map[string]any, error) {
+	logins, err := h.auditLogs.Latest(ctx, domain.AuditUserLogin, userIDs(users))
+	if err != nil {
+		return nil, err
+	}
+
+	related := make(map[string]any, len(logins))
+	for id, login := range logins {
+		related[id] = lastLoginResponse{At: login.CreatedAt, IP: login.IP}
+	}
+
+	return related, nil
+}
+
+// expandedUserBodies builds the user bodies with their selected fields and
+// embeds the requested expansions in each
+func (h *UserHandler) expandedUserBodies(ctx context.Context, users []*domain.User, fields, expand map[string]bool) ([]any, error) {
+	if len(expand) == 0 {
+		return userBodies(users, fields), nil
+	}
+
+	if fields == nil {
+		fields = userFields
+	}
+	bodies := make([]map[string]any, len(users))
+	for i, user := range users {
+		bodies[i] = selectFields(user, fields)
+	}
+
+	for name := range expand {
+		related, err := h.expansions[name](ctx, users)
+		if err != nil {
+			return nil, err
+		}
+		for i, user := range users {
+			bodies[i][name] = related[user.ID]
+		}
+	}
+
+	result := make([]any, len(bodies))
+	for i, body := range bodies {
+		result[i] = body
+	}
+
+	return result, nil
+}
+
+// userIDs returns the ids of the users
+func userIDs(users []*domain.User) []string {
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+
+	return ids
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// expandedUser is a user body with the expansions the tests request
+type expandedUser struct {
+	ID          string              `json:"id"`
+	Email       string              `json:"email"`
+	Preferences *domain.Preferences `json:"preferences"`
+	LastLogin   *lastLoginResponse  `json:"last_login"`
+}
+
+func TestExpandSingleRelation(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	ada := env.createUser("ada@example.com")
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+
+	var body map[string]json.RawMessage
+	resp := env.do(http.MethodGet, "/api/v1/users/"+ada.ID+"?expand=last_login", admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&body)
+	if _, ok := body["preferences"]; ok {
+		t.Error("user embeds preferences without asking for them")
+	}
+
+	var user expandedUser
+	resp.decode(&user)
+	if user.Email != "ada@example.com" || user.LastLogin == nil || user.LastLogin.At.IsZero() || user.LastLogin.IP != testPeer {
+		t.Errorf("expanded user = %+v, want the user with its last login from %s", user, testPeer)
+	}
+}
+
+func TestExpandMultipleRelationsOnList(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	env.createUser("ada@example.com")
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+
+	var page struct {
+		Data []expandedUser `json:"data"`
+	}
+	resp := env.do(http.MethodGet, "/api/v1/users?expand=preferences,last_login", admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&page)
+	if len(page.Data) != 2 {
+		t.Fatalf("listed %d users, want 2", len(page.Data))
+	}
+	for _, user := range page.Data {
+		if user.Preferences == nil || user.Preferences.Timezone != "UTC" {
+			t.Errorf("user %s preferences = %+v, want the defaults", user.Email, user.Preferences)
+		}
+		// Only ada has logged in; the admin's token was issued directly
+		if loggedIn := user.Email == "ada@example.com"; (user.LastLogin != nil) != loggedIn {
+			t.Errorf("user %s last_login = %+v, want it only after a login", user.Email, user.LastLogin)
+		}
+	}
+}
+
+func TestExpandWithSelectedFields(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	var body map[string]any
+	resp := env.do(http.MethodGet, "/api/v1/me?fields=id&expand=preferences", token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&body)
+	if _, ok := body["preferences"]; !ok || len(body) != 2 {
+		t.Errorf("body keys = %v, want id and preferences", keysOf(body))
+	}
+}
+
+func TestExpandRejectsUnknownKey(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	token := env.tokenFor(admin)
+
+	for _, path := range []string{
+		"/api/v1/me?expand=sessions",
+		"/api/v1/users/" + admin.ID + "?expand=preferences,password_hash",
+		"/api/v1/users?expand=roles",
+		"/api/v1/users?expand=preferences,",
+	} {
+		env.do(http.MethodGet, path, token, nil).expect(http.StatusBadRequest, "INVALID_EXPAND")
+	}
+}
+
+func TestExpandLoadsEachRelationOnce(t *testing.T) {
+	users := make([]*domain.User, 50)
+	for i := range users {
+		users[i] = &domain.User{ID: fmt.Sprintf("user-%d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+	}
+
+	calls := make(map[string]int)
+	counting := func(name string) userExpansion {
+		return func(_ context.Context, users []*domain.User) (map[string]any, error) {
+			calls[name]++
+			return map[string]any{users[0].ID: name}, nil
+		}
+	}
+	h := &UserHandler{expansions: map[string]userExpansion{"a": counting("a"), "b": counting("b")}}
+
+	bodies, err := h.expandedUserBodies(context.Background(), users, nil, map[string]bool{"a": true, "b": true})
+	if err != nil {
+		t.Fatalf("expandedUserBodies: %v", err)
+	}
+	// One load per expansion for the whole page, not one per user
+	if calls["a"] != 1 || calls["b"] != 1 {
+		t.Errorf("expansion calls = %v, want one each", calls)
+	}
+	first, last := bodies[0].(map[string]any), bodies[len(bodies)-1].(map[string]any)
+	if first["a"] != "a" || last["a"] != nil {
+		t.Errorf("first a = %v, last a = %v; want the related value and null for users without one", first["a"], last["a"])
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/featureflag"
+	"github.com/gofiber/fiber/v3"
+)
+
+// HeaderFeatureFlags turns feature flags on or off for one request, as in
+// "webhooks=false,oauth=true", where FeatureFlags allows it
+const HeaderFeatureFlags = "X-Feature-Flags"
+
+// featureFlagsKey is the Fiber locals key holding the request's feature flags
+const featureFlagsKey = "featureFlags"
+
+// FeatureFlags makes the flags available to RequireFeature. With
+// allowOverride, which must stay off in production, the X-Feature-Flags
+// header changes them for the request.
+func FeatureFlags(flags *featureflag.Flags, allowOverride bool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		current := flags
+		if header := c.Get(HeaderFeatureFlags); allowOverride && header != "" {
+			overrides, err := featureflag.Parse(header)
+			if err != nil {
+				return apperror.BadRequest("INVALID_FEATURE_FLAGS", err.Error())
+			}
+			// Parse has already rejected unknown flags
+			current, _ = flags.With(overrides)
+		}
+		c.Locals(featureFlagsKey, current)
+
+		return c.Next()
+	}
+}
+
+// RequireFeature hides the route behind the named flag, answering 404 as for
+// an unmatched route while the flag is off. It must run before
+// authentication so the endpoint does not reveal itself with a 401.
+func RequireFeature(name string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		flags, ok := c.Locals(featureFlagsKey).(*featureflag.Flags)
+		if !ok || !flags.Enabled(name) {
+			return fiber.ErrNotFound
+		}
+
+		return c.Next()
+	}
+}
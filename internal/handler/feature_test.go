@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+)
+
+// listWebhooks lists the webhooks as token, with the X-Feature-Flags header
+// when flags is set
+func (e *testEnv) listWebhooks(token, flags string) *testResponse {
+	e.t.Helper()
+
+	req := newRequest(http.MethodGet, "/api/v1/webhooks", nil)
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	if flags != "" {
+		req.Header.Set(HeaderFeatureFlags, flags)
+	}
+
+	return e.send(req)
+}
+
+func TestRequireFeature(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{"disabled", false},
+		{"enabled", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.FeatureFlags = map[string]bool{"webhooks": tt.enabled}
+			})
+			admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+			if !tt.enabled {
+				env.listWebhooks(admin, "").expect(http.StatusNotFound, "NOT_FOUND")
+				// Hidden routes do not reveal themselves by asking for credentials
+				env.listWebhooks("", "").expect(http.StatusNotFound, "NOT_FOUND")
+				return
+			}
+			env.listWebhooks(admin, "").expect(http.StatusOK)
+			env.listWebhooks("", "").expect(http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestFeatureFlagsHeader(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Env = config.EnvDevelopment })
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	env.listWebhooks(admin, "webhooks=false").expect(http.StatusNotFound, "NOT_FOUND")
+	env.listWebhooks(admin, "webhooks=true").expect(http.StatusOK)
+	env.listWebhooks(admin, "teleport=true").expect(http.StatusBadRequest, "INVALID_FEATURE_FLAGS")
+}
+
+func TestFeatureFlagsHeaderIgnoredInProduction(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Env = config.EnvProduction })
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	env.listWebhooks(admin, "webhooks=false").expect(http.StatusOK)
+	env.listWebhooks(admin, "teleport=true").expect(http.StatusOK)
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+)
+
+// userFields are the names ?fields= may select on users. They come from the
+// json tags of domain.User, so fields it never serializes, such as the
+// password hash, cannot be selected.
+var userFields = jsonFields(reflect.TypeFor[domain.User]())
+
+// jsonFields returns the json names of the fields a struct type serializes
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool)
+	for i := range t.NumField() {
+		if name, _, ok := jsonField(t.Field(i)); ok {
+			fields[name] = true
+		}
+	}
+
+	return fields
+}
+
+// jsonField returns the json name of a struct field and whether it has
+// omitempty, or false when the field is not serialized
+func jsonField(field reflect.StructField) (string, bool, bool) {
+	if !field.IsExported() {
+		return "", false, false
+	}
+
+	name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", false, false
+	}
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, strings.Contains(","+options+",", ",omitempty,"), true
+}
+
+// parseFields reads the comma-separated ?fields= selection, rejecting names
+// outside allowed. It returns nil when the parameter is absent, meaning all
+// fields.
+func parseFields(c fiber.Ctx, allowed map[string]bool) (map[string]bool, error) {
+	if !c.RequestCtx().QueryArgs().Has("fields") {
+		return nil, nil
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range strings.Split(c.Query("fields"), ",") {
+		name = strings.TrimSpace(name)
+		if !allowed[name] {
+			return nil, apperror.BadRequest("INVALID_FIELDS", fmt.Sprintf("unknown field %q", name))
+		}
+		selected[name] = true
+	}
+
+	return selected, nil
+}
+
+// selectFields projects a struct, or a pointer to one, into a map holding
+// only the selected json fields. Values keep their types, so every response
+// format encodes them as it would the full struct.
+func selectFields(v any, fields map[string]bool) map[string]any {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	projected := make(map[string]any, len(fields))
+	for i := range value.NumField() {
+		name, omitEmpty, ok := jsonField(value.Type().Field(i))
+		if !ok || !fields[name] {
+			continue
+		}
+		if omitEmpty && value.Field(i).IsZero() {
+			continue
+		}
+		projected[name] = value.Field(i).Interface()
+	}
+
+	return projected
+}
+
+// userBody returns the user, or only its selected fields when fields is set
+func userBody(user *domain.User, fields map[string]bool) any {
+	if fields == nil {
+		return user
+	}
+
+	return selectFields(user, fields)
+}
+
+// userBodies applies userBody to every user
+func userBodies(users []*domain.User, fields map[string]bool) []any {
+	bodies := make([]any, len(users))
+	for i, user := range users {
+		bodies[i] = userBody(user, fields)
+	}
+
+	return bodies
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// keysOf returns the sorted keys of a decoded JSON object
+func keysOf(object map[string]any) []string {
+	return slices.Sorted(maps.Keys(object))
+}
+
+func TestSparseFieldsets(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	user := env.createUser("ada@example.com")
+
+	var single map[string]any
+	resp := env.do(http.MethodGet, "/api/v1/users/"+user.ID+"?fields=id,name", admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&single)
+	if got := keysOf(single); !slices.Equal(got, []string{"id", "name"}) {
+		t.Errorf("user fields = %v, want [id name]", got)
+	}
+	if single["id"] != user.ID {
+		t.Errorf("id = %v, want %s", single["id"], user.ID)
+	}
+
+	var me map[string]any
+	resp = env.do(http.MethodGet, "/api/v1/me?fields=email", env.tokenFor(user), nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&me)
+	if got := keysOf(me); !slices.Equal(got, []string{"email"}) {
+		t.Errorf("me fields = %v, want [email]", got)
+	}
+
+	var page struct {
+		Data       []map[string]any `json:"data"`
+		TotalCount int              `json:"total_count"`
+	}
+	resp = env.do(http.MethodGet, "/api/v1/users?fields=id,%20email", admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&page)
+	if len(page.Data) != 2 || page.TotalCount != 2 {
+		t.Fatalf("list = %s, want both users in the envelope", resp.body)
+	}
+	for _, item := range page.Data {
+		if got := keysOf(item); !slices.Equal(got, []string{"email", "id"}) {
+			t.Errorf("list item fields = %v, want [email id]", got)
+		}
+	}
+
+	// Without ?fields= the whole user is returned
+	var full map[string]any
+	resp = env.do(http.MethodGet, "/api/v1/users/"+user.ID, admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&full)
+	if _, ok := full["created_at"]; !ok {
+		t.Errorf("user = %s, want every field", resp.body)
+	}
+}
+
+func TestSparseFieldsetsRejectUnknownFields(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	for _, fields := range []string{"id,nickname", "password_hash", "PasswordHash", "failed_login_attempts", ""} {
+		t.Run(fields, func(t *testing.T) {
+			env.do(http.MethodGet, "/api/v1/users?fields="+fields, admin, nil).expect(http.StatusBadRequest, "INVALID_FIELDS")
+		})
+	}
+}
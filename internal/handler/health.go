@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pinger is a dependency whose reachability can be checked
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// poolStater is implemented by databases that report connection pool usage,
+// such as *pgxpool.Pool
+type poolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+// poolStats summarizes connection pool usage for capacity planning
+type poolStats struct {
+	MaxConns   int32 `json:"max_conns"`
+	TotalConns int32 `json:"total_conns"`
+	InUseConns int32 `json:"in_use_conns"`
+	IdleConns  int32 `json:"idle_conns"`
+	// WaitCount counts acquires that had to wait for a free connection, and
+	// WaitDurationMS is the total time they waited; steady growth in either
+	// means the pool is too small for the load
+	WaitCount      int64 `json:"wait_count"`
+	WaitDurationMS int64 `json:"wait_duration_ms"`
+}
+
+// dependencyCheck is the detailed result of checking one dependency
+type dependencyCheck struct {
+	Status string     `json:"status"`
+	Pool   *poolStats `json:"pool,omitempty"`
+}
+
+// healthDetailsResponse is the body of the detailed readiness endpoint
+type healthDetailsResponse struct {
+	Status string                     `json:"status"`
+	Checks map[string]dependencyCheck `json:"checks"`
+}
+
+// Dependency check results
+const (
+	checkUp            = "up"
+	checkDown          = "down"
+	checkNotConfigured = "not_configured"
+)
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	db          Pinger
+	pingTimeout time.Duration
+}
+
+// NewHealthHandler creates a HealthHandler; db may be nil when no database is configured
+func NewHealthHandler(db Pinger, pingTimeout time.Duration) *HealthHandler {
+	return &HealthHandler{
+		db:          db,
+		pingTimeout: pingTimeout,
+	}
+}
+
+// Live handler reports that the process is up
+func (h *HealthHandler) Live(c fiber.Ctx) error {
+	return respond(c, fiber.Map{
+		"status": "healthy",
+	})
+}
+
+// Ready handler reports whether dependencies are reachable, returning 503 when any is down
+func (h *HealthHandler) Ready(c fiber.Ctx) error {
+	database := h.checkDatabase(c.Context())
+
+	if database == checkDown {
+		return respond(c.Status(fiber.StatusServiceUnavailable), fiber.Map{
+			"status": "unhealthy",
+			"checks": fiber.Map{"database": database},
+		})
+	}
+
+	return respond(c, fiber.Map{
+		"status": "healthy",
+		"checks": fiber.Map{"database": database},
+	})
+}
+
+// Details handler reports readiness like Ready, adding connection pool
+// statistics; it exposes operational data, so it is for admins only
+func (h *HealthHandler) Details(c fiber.Ctx) error {
+	database := dependencyCheck{Status: h.checkDatabase(c.Context())}
+	if pool, ok := h.db.(poolStater); ok {
+		stat := pool.Stat()
+		database.Pool = &poolStats{
+			MaxConns:       stat.MaxConns(),
+			TotalConns:     stat.TotalConns(),
+			InUseConns:     stat.AcquiredConns(),
+			IdleConns:      stat.IdleConns(),
+			WaitCount:      stat.EmptyAcquireCount(),
+			WaitDurationMS: stat.EmptyAcquireWaitTime().Milliseconds(),
+		}
+	}
+
+	resp := healthDetailsResponse{
+		Status: "healthy",
+		Checks: map[string]dependencyCheck{"database": database},
+	}
+	if database.Status == checkDown {
+		resp.Status = "unhealthy"
+		return respond(c.Status(fiber.StatusServiceUnavailable), resp)
+	}
+
+	return respond(c, resp)
+}
+
+// checkDatabase pings the database within the configured timeout
+func (h *HealthHandler) checkDatabase(ctx context.Context) string {
+	if h.db == nil {
+		return checkNotConfigured
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.pingTimeout)
+	defer cancel()
+
+	if err := h.db.Ping(ctx); err != nil {
+		logger.FromContext(ctx).Warn("database health check failed", slog.Any("error", err))
+		return checkDown
+	}
+
+	return checkUp
+}
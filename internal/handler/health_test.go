@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// healthBody is the body of the liveness and readiness probes
+type healthBody struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func TestHealthReady(t *testing.T) {
+	env := newTestEnv(t)
+
+	var healthy healthBody
+	resp := env.do(http.MethodGet, "/api/v1/health/ready", "", nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&healthy)
+	if healthy.Status != "healthy" || healthy.Checks["database"] != checkUp {
+		t.Errorf("ready = %+v, want healthy with the database up", healthy)
+	}
+
+	env.db.fail(errors.New("connection refused"))
+
+	for _, path := range []string{"/api/v1/health/ready", "/api/v1/health"} {
+		var unhealthy healthBody
+		resp := env.do(http.MethodGet, path, "", nil)
+		resp.expect(http.StatusServiceUnavailable)
+		resp.decode(&unhealthy)
+		if unhealthy.Status != "unhealthy" || unhealthy.Checks["database"] != checkDown {
+			t.Errorf("%s = %+v, want unhealthy with the database down", path, unhealthy)
+		}
+	}
+}
+
+func TestHealthLiveIgnoresDependencies(t *testing.T) {
+	env := newTestEnv(t)
+	env.db.fail(errors.New("connection refused"))
+
+	var body healthBody
+	resp := env.do(http.MethodGet, "/api/v1/health/live", "", nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&body)
+	if body.Status != "healthy" || body.Checks != nil {
+		t.Errorf("live = %+v, want healthy without dependency checks", body)
+	}
+}
+
+// upPool reports the statistics of a pool that never connects, answering
+// pings as though the database were up
+type upPool struct {
+	*pgxpool.Pool
+}
+
+func (upPool) Ping(context.Context) error {
+	return nil
+}
+
+func TestHealthDetailsPoolStats(t *testing.T) {
+	// pgxpool connects lazily, so the pool reports zero connections
+	pool, err := pgxpool.New(context.Background(), "postgres://postgres@127.0.0.1:1/users?pool_max_conns=7")
+	if err != nil {
+		t.Fatalf("create pool: %v", err)
+	}
+	defer pool.Close()
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/health/details", NewHealthHandler(upPool{pool}, time.Second).Details)
+	env := &testEnv{t: t, app: app}
+
+	var body struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status string         `json:"status"`
+			Pool   map[string]any `json:"pool"`
+		} `json:"checks"`
+	}
+	resp := env.do(http.MethodGet, "/health/details", "", nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&body)
+
+	database := body.Checks["database"]
+	if body.Status != "healthy" || database.Status != checkUp {
+		t.Errorf("details = %s, want healthy with the database up", resp.body)
+	}
+	for _, key := range []string{"max_conns", "total_conns", "in_use_conns", "idle_conns", "wait_count", "wait_duration_ms"} {
+		if _, ok := database.Pool[key]; !ok {
+			t.Errorf("pool stats %v have no %q", database.Pool, key)
+		}
+	}
+	if got := database.Pool["max_conns"]; got != float64(7) {
+		t.Errorf("max_conns = %v, want 7", got)
+	}
+}
+
+func TestHealthDetailsRequiresAdmin(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.do(http.MethodGet, "/api/v1/health/details", "", nil).expect(http.StatusUnauthorized)
+	env.do(http.MethodGet, "/api/v1/health/details", env.tokenFor(env.createUser("ada@example.com")), nil).expect(http.StatusForbidden)
+	env.do(http.MethodGet, "/api/v1/health/details", env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin)), nil).expect(http.StatusOK)
+
+	// The readiness probe stays public and without pool details
+	resp := env.do(http.MethodGet, "/api/v1/health/ready", "", nil)
+	resp.expect(http.StatusOK)
+	if strings.Contains(string(resp.body), "pool") {
+		t.Errorf("ready = %s, want no pool details", resp.body)
+	}
+}
@@ -0,0 +1,473 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/app"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/oauth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+	"github.com/gofiber/fiber/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPassword is the password of the users testEnv.createUser creates
+const testPassword = "correct-horse-battery"
+
+// testEnv is the application built by app.New and NewServer, as cmd/main
+// builds it, on in-memory repositories, with handles on the parts tests set
+// up or inspect
+type testEnv struct {
+	t   *testing.T
+	app *fiber.App
+	cfg *config.Config
+	// admin is the internal admin app, built when cfg.Server.AdminAddr is set
+	admin *fiber.App
+
+	users      *repository.InMemoryUserRepository
+	auditLogs  *repository.InMemoryAuditLogRepository
+	apiKeyRepo *repository.InMemoryAPIKeyRepository
+	webhooks   *repository.InMemoryWebhookRepository
+	outbox     *repository.InMemoryOutboxRepository
+	store      kvstore.Store
+	mail       *mailer.MockMailer
+	db         *fakePinger
+	google     *fakeOAuthProvider
+	roles      *fakeRoleRepository
+
+	tokens     *auth.TokenManager
+	sessions   *service.SessionService
+	apiKeys    *service.APIKeyService
+	dispatcher *webhook.Dispatcher
+}
+
+// newTestEnv builds the application from the development defaults of the
+// configuration, after configure, if given, has adjusted them
+func newTestEnv(t *testing.T, configure ...func(*config.Config)) *testEnv {
+	t.Helper()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	cfg.Storage.LocalDir = t.TempDir()
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	if err := auth.SetBcryptCost(bcrypt.MinCost); err != nil {
+		t.Fatalf("set bcrypt cost: %v", err)
+	}
+	auth.SetPasswordPolicy(auth.PasswordPolicy{
+		MinLength:     cfg.Password.MinLength,
+		RequireUpper:  cfg.Password.RequireUpper,
+		RequireLower:  cfg.Password.RequireLower,
+		RequireDigit:  cfg.Password.RequireDigit,
+		RequireSymbol: cfg.Password.RequireSymbol,
+		RejectCommon:  cfg.Password.RejectCommon,
+	})
+	keys, err := auth.NewHMACKeySet("test", map[string][]byte{"test": []byte("handler-test-secret")})
+	if err != nil {
+		t.Fatalf("create signing keys: %v", err)
+	}
+
+	e := &testEnv{
+		t:          t,
+		cfg:        cfg,
+		users:      repository.NewInMemoryUserRepository(),
+		auditLogs:  repository.NewInMemoryAuditLogRepository(),
+		apiKeyRepo: repository.NewInMemoryAPIKeyRepository(),
+		webhooks:   repository.NewInMemoryWebhookRepository(),
+		outbox:     repository.NewInMemoryOutboxRepository(),
+		store:      kvstore.NewMemory(),
+		mail:       mailer.NewMockMailer(),
+		db:         &fakePinger{},
+		google:     &fakeOAuthProvider{profiles: make(map[string]*oauth.Profile)},
+		roles:      &fakeRoleRepository{grants: make(map[domain.Role][]domain.Permission)},
+	}
+
+	repos := app.NewInMemoryRepositories()
+	repos.Users = e.users
+	repos.AuditLogs = e.auditLogs
+	repos.APIKeys = e.apiKeyRepo
+	repos.Webhooks = e.webhooks
+	repos.Outbox = e.outbox
+	repos.Roles = e.roles
+	a, err := app.New(cfg, repos, app.Options{
+		Keys:           keys,
+		Store:          e.store,
+		DB:             e.db,
+		Mailer:         e.mail,
+		OAuthProviders: map[string]oauth.Provider{"google": e.google},
+	})
+	if err != nil {
+		t.Fatalf("build application: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = a.Close(ctx)
+	})
+	e.tokens, e.sessions, e.apiKeys, e.dispatcher = a.Tokens, a.Sessions, a.APIKeys, a.Dispatcher
+
+	server := NewServer(cfg, a)
+	e.app, e.admin = server.Public, server.Admin
+
+	return e
+}
+
+// createUser stores an active user with testPassword and the given roles,
+// or the user role when none are given
+func (e *testEnv) createUser(email string, roles ...domain.Role) *domain.User {
+	e.t.Helper()
+
+	if len(roles) == 0 {
+		roles = domain.Roles{domain.RoleUser}
+	}
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		e.t.Fatalf("hash password: %v", err)
+	}
+	user := &domain.User{
+		Email:        email,
+		Name:         "Test User",
+		Roles:        roles,
+		Status:       domain.StatusActive,
+		PasswordHash: hash,
+	}
+	if err := e.users.Create(context.Background(), user); err != nil {
+		e.t.Fatalf("create user %s: %v", email, err)
+	}
+
+	return user
+}
+
+// tokenFor starts a session for the user and returns its access token
+func (e *testEnv) tokenFor(user *domain.User) string {
+	return e.sessionFor(user).AccessToken
+}
+
+// sessionFor starts a session for the user and returns its tokens
+func (e *testEnv) sessionFor(user *domain.User) *service.TokenPair {
+	e.t.Helper()
+
+	pair, err := e.sessions.Start(context.Background(), user, service.SessionClient{})
+	if err != nil {
+		e.t.Fatalf("start session for %s: %v", user.Email, err)
+	}
+
+	return pair
+}
+
+// mailedTokenPattern matches an opaque token at the end of an emailed link
+// or on a line of its own
+var mailedTokenPattern = regexp.MustCompile(`(?m)(?:token=|^)([A-Za-z0-9_-]{43})$`)
+
+// mailedToken returns the token in the latest email of the template sent to
+func (e *testEnv) mailedToken(to, template string) string {
+	e.t.Helper()
+
+	messages := e.mail.MessagesTo(to)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Template != template {
+			continue
+		}
+		match := mailedTokenPattern.FindStringSubmatch(messages[i].Body)
+		if match == nil {
+			e.t.Fatalf("%s email to %s has no token: %q", template, to, messages[i].Body)
+		}
+		return match[1]
+	}
+	e.t.Fatalf("no %s email was sent to %s", template, to)
+
+	return ""
+}
+
+// newRequest builds a request whose body is body itself when it is a string
+// or []byte, and body encoded as JSON otherwise
+func newRequest(method, path string, body any) *http.Request {
+	var reader io.Reader
+	contentType := fiber.MIMEApplicationJSON
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			panic(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if reader != nil {
+		req.Header.Set(fiber.HeaderContentType, contentType)
+	}
+
+	return req
+}
+
+// newUploadRequest builds a multipart/form-data request carrying data as the
+// file in field
+func newUploadRequest(method, path, field, filename, contentType string, data []byte) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`form-data; name=%q; filename=%q`, field, filename))
+	header.Set(fiber.HeaderContentType, contentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := part.Write(data); err != nil {
+		panic(err)
+	}
+	if err := form.Close(); err != nil {
+		panic(err)
+	}
+
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set(fiber.HeaderContentType, form.FormDataContentType())
+
+	return req
+}
+
+// do sends a request, authenticated with token unless it is empty
+func (e *testEnv) do(method, path, token string, body any) *testResponse {
+	e.t.Helper()
+
+	req := newRequest(method, path, body)
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+
+	return e.send(req)
+}
+
+// send sends a prepared request to the application
+func (e *testEnv) send(req *http.Request) *testResponse {
+	e.t.Helper()
+
+	resp, err := e.app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		e.t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		e.t.Fatalf("read %s %s response: %v", req.Method, req.URL, err)
+	}
+
+	return &testResponse{t: e.t, Response: resp, body: body}
+}
+
+// testResponse is a response with its body read
+type testResponse struct {
+	*http.Response
+	t    *testing.T
+	body []byte
+}
+
+// decode decodes the JSON body into v
+func (r *testResponse) decode(v any) {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.body, v); err != nil {
+		r.t.Fatalf("decode response %q: %v", r.body, err)
+	}
+}
+
+// errorCode returns the code of an error response
+func (r *testResponse) errorCode() string {
+	r.t.Helper()
+
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	r.decode(&body)
+
+	return body.Error.Code
+}
+
+// expect fails the test unless the response has the given status and,
+// for error responses, the given error code
+func (r *testResponse) expect(status int, code ...string) {
+	r.t.Helper()
+
+	if r.StatusCode != status {
+		r.t.Fatalf("status = %d, want %d; body %s", r.StatusCode, status, r.body)
+	}
+	if len(code) > 0 {
+		if got := r.errorCode(); got != code[0] {
+			r.t.Fatalf("error code = %q, want %q; body %s", got, code[0], r.body)
+		}
+	}
+}
+
+// logEntries collects the JSON records written to the default logger
+type logEntries struct {
+	t   *testing.T
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// captureLogs sends the default logger to a buffer at debug level until
+// the test ends
+func captureLogs(t *testing.T) *logEntries {
+	entries := &logEntries{t: t}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(entries, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return entries
+}
+
+func (l *logEntries) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.buf.Write(p)
+}
+
+// find returns the records with the given message
+func (l *logEntries) find(msg string) []map[string]any {
+	l.t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var found []map[string]any
+	for line := range strings.Lines(l.buf.String()) {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			l.t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		if entry["msg"] == msg {
+			found = append(found, entry)
+		}
+	}
+
+	return found
+}
+
+// fakePinger is a database whose reachability tests set
+type fakePinger struct {
+	mu  sync.Mutex
+	err error
+}
+
+// Ping returns the error set with fail
+func (p *fakePinger) Ping(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err
+}
+
+// fail makes later pings return err; nil makes them succeed again
+func (p *fakePinger) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.err = err
+}
+
+// fakeOAuthProvider is an identity provider that trades the codes tests
+// register for the matching profiles, without any network calls
+type fakeOAuthProvider struct {
+	mu       sync.Mutex
+	profiles map[string]*oauth.Profile
+}
+
+// AuthCodeURL returns a consent screen address carrying state
+func (p *fakeOAuthProvider) AuthCodeURL(state string) string {
+	return "https://accounts.example.com/consent?state=" + state
+}
+
+// Exchange returns the profile registered for code
+func (p *fakeOAuthProvider) Exchange(_ context.Context, code string) (*oauth.Profile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	profile, ok := p.profiles[code]
+	if !ok {
+		return nil, errors.New("unknown authorization code")
+	}
+
+	return profile, nil
+}
+
+// add makes Exchange answer code with profile
+func (p *fakeOAuthProvider) add(code string, profile *oauth.Profile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.profiles[code] = profile
+}
+
+// fakeRoleRepository grants the built-in roles what the migrations grant
+// them, along with the permissions tests grant
+type fakeRoleRepository struct {
+	mu     sync.Mutex
+	grants map[domain.Role][]domain.Permission
+	loads  int
+}
+
+// Permissions returns the default permissions merged with the granted ones
+func (r *fakeRoleRepository) Permissions(ctx context.Context) (map[domain.Role][]domain.Permission, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.loads++
+	permissions, err := repository.NewInMemoryRoleRepository().Permissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for role, granted := range r.grants {
+		permissions[role] = append(permissions[role], granted...)
+	}
+
+	return permissions, nil
+}
+
+// grant makes the role grant the permissions from the next reload on
+func (r *fakeRoleRepository) grant(role domain.Role, permissions ...domain.Permission) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.grants[role] = append(r.grants[role], permissions...)
+}
+
+// loaded returns how many times the mapping was read
+func (r *fakeRoleRepository) loaded() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.loads
+}
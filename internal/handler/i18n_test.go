@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/i18n"
+	"github.com/gofiber/fiber/v3"
+)
+
+// localizedError sends the request with Accept-Language set and returns its
+// error body after checking the status and code
+func (e *testEnv) localizedError(req *http.Request, acceptLanguage string, status int, code string) (errorDetail, *testResponse) {
+	e.t.Helper()
+
+	if acceptLanguage != "" {
+		req.Header.Set(fiber.HeaderAcceptLanguage, acceptLanguage)
+	}
+	resp := e.send(req)
+	resp.expect(status, code)
+	var body errorBody
+	resp.decode(&body)
+
+	return body.Error, resp
+}
+
+func TestErrorMessagesFollowAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		language       string
+		message        string
+	}{
+		{"no header", "", "en", "invalid email or password"},
+		{"English", "en-US", "en", "invalid email or password"},
+		{"Spanish", "es", "es", "correo electrónico o contraseña incorrectos"},
+		{"Spanish preferred", "en;q=0.5, es-MX;q=0.9", "es", "correo electrónico o contraseña incorrectos"},
+		{"unsupported", "de", "en", "invalid email or password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			req := newRequest(http.MethodPost, "/api/v1/auth/login", loginBody("nobody@example.com", "wrong"))
+			body, resp := env.localizedError(req, tt.acceptLanguage, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+
+			if body.Message != tt.message {
+				t.Errorf("message = %q, want %q", body.Message, tt.message)
+			}
+			if got := resp.Header.Get(fiber.HeaderContentLanguage); got != tt.language {
+				t.Errorf("Content-Language = %q, want %q", got, tt.language)
+			}
+			if !strings.Contains(resp.Header.Get(fiber.HeaderVary), fiber.HeaderAcceptLanguage) {
+				t.Errorf("Vary = %q, want Accept-Language in it", resp.Header.Get(fiber.HeaderVary))
+			}
+		})
+	}
+}
+
+func TestValidationMessagesFollowAcceptLanguage(t *testing.T) {
+	env := newTestEnv(t)
+
+	messages := make(map[string]string)
+	for _, lang := range []string{"en", "es"} {
+		req := newRequest(http.MethodPost, "/api/v1/auth/register", map[string]string{"email": "not-an-email", "name": "Ada", "password": testPassword})
+		body, _ := env.localizedError(req, lang, http.StatusUnprocessableEntity, apperror.CodeValidation)
+
+		fields, ok := body.Details.([]any)
+		if !ok || len(fields) != 1 {
+			t.Fatalf("%s details = %v, want the email field error", lang, body.Details)
+		}
+		field := fields[0].(map[string]any)
+		if field["field"] != "email" {
+			t.Errorf("%s field = %v, want email", lang, field["field"])
+		}
+		messages[lang] = body.Message + " / " + field["message"].(string)
+	}
+
+	if want := "request validation failed / must be a valid email"; messages["en"] != want {
+		t.Errorf("English messages = %q, want %q", messages["en"], want)
+	}
+	if want := "la validación de la solicitud ha fallado / debe ser un correo electrónico válido"; messages["es"] != want {
+		t.Errorf("Spanish messages = %q, want %q", messages["es"], want)
+	}
+}
+
+// emittedCodes returns the error codes written as literals in the packages
+// that build API errors, and those of the errors Fiber raises itself
+func emittedCodes(t *testing.T) map[string]bool {
+	t.Helper()
+
+	codes := make(map[string]bool)
+	addLiteral := func(expr ast.Expr) {
+		if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			code, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatalf("unquote %s: %v", lit.Value, err)
+			}
+			codes[code] = true
+		}
+	}
+
+	fset := token.NewFileSet()
+	for _, dir := range []string{".", "../apperror", "../query"} {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatalf("list %s: %v", dir, err)
+		}
+		for _, path := range files {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+			file, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				t.Fatalf("parse %s: %v", path, err)
+			}
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.ValueSpec:
+					// The generic codes of package apperror
+					for i, name := range node.Names {
+						if strings.HasPrefix(name.Name, "Code") && i < len(node.Values) {
+							addLiteral(node.Values[i])
+						}
+					}
+				case *ast.CallExpr:
+					sel, ok := node.Fun.(*ast.SelectorExpr)
+					if !ok {
+						return true
+					}
+					if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "apperror" {
+						return true
+					}
+					arg := 0
+					if sel.Sel.Name == "New" {
+						arg = 1
+					}
+					if len(node.Args) > arg {
+						addLiteral(node.Args[arg])
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	for _, err := range []error{fiber.ErrBadRequest, fiber.ErrNotFound, fiber.ErrMethodNotAllowed, fiber.ErrRequestEntityTooLarge} {
+		codes[toAPIError(err).Code] = true
+	}
+
+	return codes
+}
+
+func TestEveryErrorCodeHasSpanishMessage(t *testing.T) {
+	codes := emittedCodes(t)
+	for _, code := range []string{"INVALID_CREDENTIALS", "MALFORMED_ROW", apperror.CodeValidation} {
+		if !codes[code] {
+			t.Fatalf("found %d codes without %s, the scan misses some", len(codes), code)
+		}
+	}
+
+	for code := range codes {
+		if _, ok := i18n.Error(i18n.Spanish, code); !ok {
+			t.Errorf("%s has no Spanish message", code)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Idempotency headers
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLength bounds the keys clients may send
+const maxIdempotencyKeyLength = 255
+
+// idempotencyLockTTL is how long a reservation blocks retries when the
+// original request never completes, for example because the process died
+const idempotencyLockTTL = time.Minute
+
+// Idempotent replays the stored response when a request repeats an
+// Idempotency-Key the same caller already used on the same endpoint, so
+// retried requests take effect once. It runs after authentication, which
+// identifies the caller. Requests without the header pass through unchanged.
+func Idempotent(store repository.IdempotencyRepository, retention time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		// Copy the header, as Fiber reuses its memory after the request
+		key := strings.Clone(c.Get(HeaderIdempotencyKey))
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return apperror.BadRequest("INVALID_IDEMPOTENCY_KEY", "idempotency key must be at most 255 characters")
+		}
+
+		sum := sha256.Sum256(c.Body())
+		requestHash := hex.EncodeToString(sum[:])
+		record, reserved, err := store.Reserve(c.Context(), &domain.IdempotencyKey{
+			Owner:       idempotencyOwner(c),
+			Key:         key,
+			Endpoint:    c.Method() + " " + c.Route().Path,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().UTC().Add(idempotencyLockTTL),
+		})
+		if err != nil {
+			return err
+		}
+
+		if !reserved {
+			return replay(c, record, requestHash)
+		}
+
+		// Render errors here so the stored response matches what the client sees
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		// Record the outcome even when the request ran out of time
+		ctx := context.WithoutCancel(c.Context())
+
+		// Server errors may not have taken effect, so let the client retry them
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			if err := store.Release(ctx, record); err != nil {
+				logger.FromContext(c.Context()).Error("failed to release idempotency key", slog.Any("error", err))
+			}
+			return nil
+		}
+
+		record.StatusCode = status
+		record.ContentType = string(c.Response().Header.ContentType())
+		record.ResponseBody = append([]byte(nil), c.Response().Body()...)
+		record.ExpiresAt = time.Now().UTC().Add(retention)
+		if err := store.Complete(ctx, record); err != nil {
+			// The request already succeeded; a retry would repeat it, but
+			// failing the response now would hide that it took effect
+			logger.FromContext(c.Context()).Error("failed to store idempotent response", slog.Any("error", err))
+		}
+
+		return nil
+	}
+}
+
+// idempotencyOwner names the caller of the request: the API key it was
+// authenticated with, or else the user
+func idempotencyOwner(c fiber.Ctx) string {
+	if key, ok := CurrentAPIKey(c); ok {
+		return "api_key:" + key.ID
+	}
+	if userID, ok := CurrentUserID(c); ok {
+		return "user:" + userID
+	}
+
+	return ""
+}
+
+// replay answers a repeated request from the record holding its key
+func replay(c fiber.Ctx, record *domain.IdempotencyKey, requestHash string) error {
+	if record.RequestHash != requestHash {
+		return apperror.UnprocessableEntity("IDEMPOTENCY_KEY_REUSED", "idempotency key was already used with a different request body")
+	}
+	if record.CompletedAt == nil {
+		return apperror.Conflict("IDEMPOTENCY_KEY_IN_PROGRESS", "a request with this idempotency key is still being processed")
+	}
+
+	c.Set(HeaderIdempotentReplayed, "true")
+	if record.ContentType != "" {
+		c.Set(fiber.HeaderContentType, record.ContentType)
+	}
+
+	return c.Status(record.StatusCode).Send(record.ResponseBody)
+}
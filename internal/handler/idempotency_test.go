@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/gofiber/fiber/v3"
+)
+
+// createUserWithKey posts a new user to /users with the Idempotency-Key
+func (e *testEnv) createUserWithKey(token, key string, body any) *testResponse {
+	e.t.Helper()
+
+	req := newRequest(http.MethodPost, "/api/v1/users", body)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	req.Header.Set(HeaderIdempotencyKey, key)
+
+	return e.send(req)
+}
+
+func TestIdempotentCreateUser(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	body := bulkUser("ada@example.com")
+
+	first := env.createUserWithKey(admin, "create-ada", body)
+	first.expect(http.StatusCreated)
+	second := env.createUserWithKey(admin, "create-ada", body)
+	second.expect(http.StatusCreated)
+
+	if string(second.body) != string(first.body) {
+		t.Errorf("replayed body = %s, want the original %s", second.body, first.body)
+	}
+	if first.Header.Get(HeaderIdempotentReplayed) != "" || second.Header.Get(HeaderIdempotentReplayed) != "true" {
+		t.Errorf("%s = %q then %q, want only the replay marked", HeaderIdempotentReplayed,
+			first.Header.Get(HeaderIdempotentReplayed), second.Header.Get(HeaderIdempotentReplayed))
+	}
+	_, total, err := env.users.List(t.Context(), repository.ListParams{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("stored %d users, want the admin and one created user", total)
+	}
+
+	env.createUserWithKey(admin, "create-ada", bulkUser("grace@example.com")).expect(http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED")
+	// Without a key, a repeat is a new request
+	env.do(http.MethodPost, "/api/v1/users", admin, body).expect(http.StatusConflict, "EMAIL_EXISTS")
+}
+
+func TestIdempotentRequestInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Post("/", Idempotent(repository.NewInMemoryIdempotencyRepository(), time.Hour), func(c fiber.Ctx) error {
+		close(started)
+		<-release
+		return c.SendStatus(fiber.StatusCreated)
+	})
+	env := &testEnv{t: t, app: app}
+	request := func() *http.Request {
+		req := newRequest(http.MethodPost, "/", `{}`)
+		req.Header.Set(HeaderIdempotencyKey, "slow")
+		return req
+	}
+
+	done := make(chan *testResponse)
+	go func() { done <- env.send(request()) }()
+	<-started
+
+	env.send(request()).expect(http.StatusConflict, "IDEMPOTENCY_KEY_IN_PROGRESS")
+	close(release)
+	(<-done).expect(http.StatusCreated)
+	env.send(request()).expect(http.StatusCreated)
+}
+
+func TestIdempotencyKeysAreScopedToCaller(t *testing.T) {
+	env := newTestEnv(t)
+	ada := env.tokenFor(env.createUser("ada@example.com", domain.RoleAdmin))
+	grace := env.tokenFor(env.createUser("grace@example.com", domain.RoleAdmin))
+	key := env.createAPIKey(ada, domain.ScopeUsersWrite)
+
+	env.createUserWithKey(ada, "create", bulkUser("one@example.com")).expect(http.StatusCreated)
+
+	// Another user picking the same key makes a request of their own,
+	// neither replaying the first response nor refused as a reuse
+	replayed := env.createUserWithKey(grace, "create", bulkUser("two@example.com"))
+	replayed.expect(http.StatusCreated)
+	if replayed.Header.Get(HeaderIdempotentReplayed) != "" {
+		t.Error("another user got the first user's response replayed")
+	}
+	if again := env.createUserWithKey(grace, "create", bulkUser("two@example.com")); again.Header.Get(HeaderIdempotentReplayed) != "true" {
+		t.Errorf("retry of the second user got %d without a replay, want their own response replayed", again.StatusCode)
+	}
+
+	// An API key is a caller apart from its owner
+	req := newRequest(http.MethodPost, "/api/v1/users", bulkUser("one@example.com"))
+	req.Header.Set(HeaderAPIKey, key.Key)
+	req.Header.Set(HeaderIdempotencyKey, "create")
+	env.send(req).expect(http.StatusConflict, "EMAIL_EXISTS")
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/gofiber/fiber/v3"
+)
+
+// impersonationResponse is the access token an admin acts as a user with;
+// it cannot be refreshed
+type impersonationResponse struct {
+	AccessToken string       `json:"access_token"`
+	TokenType   string       `json:"token_type"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	User        *domain.User `json:"user"`
+}
+
+// ImpersonationHandler serves the admin impersonation endpoint
+type ImpersonationHandler struct {
+	impersonation *service.ImpersonationService
+}
+
+// NewImpersonationHandler creates an ImpersonationHandler backed by the given service
+func NewImpersonationHandler(impersonation *service.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{impersonation: impersonation}
+}
+
+// Impersonate handler issues a short-lived token acting as the user
+func (h *ImpersonationHandler) Impersonate(c fiber.Ctx) error {
+	actor, _ := auth.IdentityFromContext(c.Context())
+
+	token, err := h.impersonation.Impersonate(c.Context(), actor, c.Params("userId"))
+	if err != nil {
+		return impersonationError(err)
+	}
+
+	return respond(c, impersonationResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresAt:   token.ExpiresAt,
+		User:        token.User,
+	})
+}
+
+// impersonationError translates impersonation errors into API errors
+func impersonationError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		return apperror.NotFound("USER_NOT_FOUND", "user not found")
+	case errors.Is(err, service.ErrAccountSuspended):
+		return apperror.UnprocessableEntity("ACCOUNT_SUSPENDED", "cannot impersonate a suspended user")
+	case errors.Is(err, service.ErrImpersonateSelf):
+		return apperror.UnprocessableEntity("IMPERSONATE_SELF", "cannot impersonate yourself")
+	case errors.Is(err, service.ErrImpersonateAdmin):
+		return apperror.Forbidden("IMPERSONATE_ADMIN", "only super admins may impersonate admins")
+	case errors.Is(err, service.ErrNestedImpersonation):
+		return apperror.Forbidden("ALREADY_IMPERSONATING", "cannot impersonate while impersonating")
+	}
+
+	return err
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// impersonate returns the token the admin token receives to act as userID
+func (e *testEnv) impersonate(token, userID string) impersonationResponse {
+	e.t.Helper()
+
+	var impersonation impersonationResponse
+	resp := e.do(http.MethodPost, "/api/v1/admin/impersonate/"+userID, token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&impersonation)
+
+	return impersonation
+}
+
+// auditEntries returns the audit entries with the action, newest first
+func (e *testEnv) auditEntries(action domain.AuditAction) []*domain.AuditLog {
+	e.t.Helper()
+
+	entries, _, err := e.auditLogs.List(context.Background(), repository.AuditLogFilter{Action: action}, 1, 10)
+	if err != nil {
+		e.t.Fatalf("list audit logs: %v", err)
+	}
+
+	return entries
+}
+
+func TestImpersonationTokenCarriesBothIdentities(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	ada := env.createUser("ada@example.com")
+
+	impersonation := env.impersonate(env.tokenFor(admin), ada.ID)
+	claims, err := env.tokens.ParseToken(impersonation.AccessToken)
+	if err != nil {
+		t.Fatalf("parse impersonation token: %v", err)
+	}
+	if claims.Subject != ada.ID || claims.ActorID() != admin.ID {
+		t.Errorf("token subject, actor = %q, %q; want %q, %q", claims.Subject, claims.ActorID(), ada.ID, admin.ID)
+	}
+	if lifetime := time.Until(claims.ExpiresAt.Time); lifetime > env.cfg.Auth.ImpersonationTTL {
+		t.Errorf("token lives %v, want at most %v", lifetime, env.cfg.Auth.ImpersonationTTL)
+	}
+
+	// Requests made with it are authorized as the user
+	var me domain.User
+	resp := env.do(http.MethodGet, "/api/v1/me", impersonation.AccessToken, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&me)
+	if me.ID != ada.ID {
+		t.Errorf("GET /me as the impersonated user = %s, want %s", me.ID, ada.ID)
+	}
+	env.do(http.MethodGet, "/api/v1/users", impersonation.AccessToken, nil).expect(http.StatusForbidden)
+}
+
+func TestImpersonationIsAudited(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	ada := env.createUser("ada@example.com")
+
+	impersonation := env.impersonate(env.tokenFor(admin), ada.ID)
+	started := env.auditEntries(domain.AuditImpersonate)
+	if len(started) != 1 || started[0].ActorID != admin.ID || started[0].TargetID != ada.ID {
+		t.Fatalf("impersonation entries = %+v, want one by the admin targeting the user", started)
+	}
+
+	// Actions taken while impersonating name the admin as the actor
+	env.do(http.MethodPatch, "/api/v1/me", impersonation.AccessToken, map[string]string{"name": "Ada Lovelace"}).expect(http.StatusOK)
+	updates := env.auditEntries(domain.AuditUserUpdate)
+	if len(updates) != 1 || updates[0].ActorID != admin.ID || updates[0].ImpersonatedID != ada.ID {
+		t.Errorf("update entries = %+v, want the admin as actor impersonating the user", updates)
+	}
+}
+
+func TestImpersonationRestrictions(t *testing.T) {
+	env := newTestEnv(t)
+	superAdmin := env.createUser("root@example.com", domain.RoleSuperAdmin)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	otherAdmin := env.createUser("other-admin@example.com", domain.RoleAdmin)
+	ada := env.createUser("ada@example.com")
+	suspended := env.createUser("suspended@example.com")
+	suspended.Status = domain.StatusSuspended
+	if err := env.users.Update(context.Background(), suspended); err != nil {
+		t.Fatalf("suspend user: %v", err)
+	}
+	adminToken := env.tokenFor(admin)
+	nested := env.impersonate(env.tokenFor(superAdmin), otherAdmin.ID).AccessToken
+
+	tests := []struct {
+		name   string
+		token  string
+		userID string
+		status int
+		code   string
+	}{
+		{"user", env.tokenFor(ada), admin.ID, http.StatusForbidden, ""},
+		{"another admin", adminToken, otherAdmin.ID, http.StatusForbidden, "IMPERSONATE_ADMIN"},
+		{"self", adminToken, admin.ID, http.StatusUnprocessableEntity, "IMPERSONATE_SELF"},
+		{"suspended user", adminToken, suspended.ID, http.StatusUnprocessableEntity, "ACCOUNT_SUSPENDED"},
+		{"unknown user", adminToken, "00000000-0000-0000-0000-000000000000", http.StatusNotFound, "USER_NOT_FOUND"},
+		{"while impersonating", nested, ada.ID, http.StatusForbidden, "ALREADY_IMPERSONATING"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := env.do(http.MethodPost, "/api/v1/admin/impersonate/"+tt.userID, tt.token, nil)
+			if tt.code == "" {
+				resp.expect(tt.status)
+			} else {
+				resp.expect(tt.status, tt.code)
+			}
+		})
+	}
+
+	// Super admins may impersonate admins
+	if got := env.impersonate(env.tokenFor(superAdmin), admin.ID); got.User == nil || got.User.ID != admin.ID {
+		t.Errorf("super admin impersonation = %+v, want the admin", got)
+	}
+}
+
+func TestImpersonationEndsWithAdminSession(t *testing.T) {
+	env := newTestEnv(t)
+	session := env.sessionFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	ada := env.createUser("ada@example.com")
+
+	impersonation := env.impersonate(session.AccessToken, ada.ID)
+	env.do(http.MethodPost, "/api/v1/auth/logout", session.AccessToken, refreshBody(session.RefreshToken)).expect(http.StatusNoContent)
+
+	env.do(http.MethodGet, "/api/v1/me", impersonation.AccessToken, nil).expect(http.StatusUnauthorized, "TOKEN_REVOKED")
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	gojson "github.com/goccy/go-json"
+)
+
+// jsonLibraries are the encoders JSON_LIBRARY chooses between
+var jsonLibraries = []struct {
+	name    string
+	marshal func(any) ([]byte, error)
+}{
+	{"stdlib", json.Marshal},
+	{"goccy", gojson.Marshal},
+}
+
+// representativeUserPayload is a signup response whose user has every
+// optional field set, hidden fields filled, and text that needs escaping
+func representativeUserPayload() registerResponse {
+	created := time.Date(2024, time.March, 9, 14, 5, 7, 123456789, time.FixedZone("ICT", 7*60*60))
+	verified := created.Add(90 * time.Minute).UTC()
+	locked := created.Add(time.Hour)
+
+	return registerResponse{
+		User: &domain.User{
+			ID:                  "0190f2a4-7c1e-7b3a-9f2d-4e5a6b7c8d9e",
+			Email:               "ada@example.com",
+			Name:                `Ada "<Countess>" & Lovelace — ½`,
+			Phone:               "+66812345678",
+			Roles:               domain.Roles{domain.RoleAdmin, domain.RoleUser},
+			Status:              domain.StatusActive,
+			PasswordHash:        "$2a$10$abcdefghijklmnopqrstuv",
+			EmailVerifiedAt:     &verified,
+			AvatarURL:           "https://cdn.example.com/avatars/ada.png?size=128&v=2",
+			AvatarKey:           "storage/7c1e/ada",
+			FailedLoginAttempts: 2,
+			LockedUntil:         &locked,
+			CreatedAt:           created,
+			UpdatedAt:           created.Add(time.Second),
+			Version:             3,
+		},
+		Tokens: &tokenResponse{
+			AccessToken:  "eyJhbGciOiJIUzI1NiJ9.e30.sig",
+			TokenType:    "Bearer",
+			ExpiresAt:    created.Add(15 * time.Minute),
+			RefreshToken: "refresh-token",
+		},
+	}
+}
+
+func TestJSONLibrariesEncodeIdentically(t *testing.T) {
+	payload := representativeUserPayload()
+	want, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("stdlib encode: %v", err)
+	}
+	for _, hidden := range []string{"password", "$2a$", "storage/7c1e", "failed_login", "locked"} {
+		if bytes.Contains(want, []byte(hidden)) {
+			t.Errorf("encoded user contains %q: %s", hidden, want)
+		}
+	}
+
+	for _, lib := range jsonLibraries {
+		t.Run(lib.name, func(t *testing.T) {
+			got, err := lib.marshal(payload)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("encoded payload =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestJSONLibrariesDecodeIdentically(t *testing.T) {
+	data, err := json.Marshal(representativeUserPayload())
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	var want, got registerResponse
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatalf("stdlib decode: %v", err)
+	}
+	if err := gojson.Unmarshal(data, &got); err != nil {
+		t.Fatalf("goccy decode: %v", err)
+	}
+	wantData, _ := json.Marshal(want)
+	gotData, _ := json.Marshal(got)
+	if !bytes.Equal(gotData, wantData) {
+		t.Errorf("goccy decoded %s, want %s", gotData, wantData)
+	}
+}
+
+func BenchmarkJSONEncodeUser(b *testing.B) {
+	payload := representativeUserPayload()
+	for _, lib := range jsonLibraries {
+		b.Run(lib.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := lib.marshal(payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
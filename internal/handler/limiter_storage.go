@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/gofiber/fiber/v3"
+)
+
+// errStorageReset is returned by limiterStorage.Reset, which the limiter
+// never calls and which would clear state other replicas rely on
+var errStorageReset = errors.New("limiter storage cannot be reset")
+
+// limiterStorage adapts a kvstore.Store to the fiber.Storage the limiter
+// middleware keeps its counters in, namespacing keys by prefix
+type limiterStorage struct {
+	store  kvstore.Store
+	prefix string
+}
+
+var _ fiber.Storage = (*limiterStorage)(nil)
+
+// newLimiterStorage creates a limiterStorage keeping keys under prefix:
+func newLimiterStorage(store kvstore.Store, prefix string) *limiterStorage {
+	return &limiterStorage{store: store, prefix: prefix + ":"}
+}
+
+// GetWithContext returns the value under key, or nil when there is none
+func (s *limiterStorage) GetWithContext(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.store.Get(ctx, s.prefix+key)
+	if errors.Is(err, kvstore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(value), nil
+}
+
+// Get returns the value under key, or nil when there is none
+func (s *limiterStorage) Get(key string) ([]byte, error) {
+	return s.GetWithContext(context.Background(), key)
+}
+
+// SetWithContext stores the value under key, ignoring empty keys and values
+func (s *limiterStorage) SetWithContext(ctx context.Context, key string, val []byte, exp time.Duration) error {
+	if key == "" || len(val) == 0 {
+		return nil
+	}
+
+	return s.store.SetWithTTL(ctx, s.prefix+key, string(val), exp)
+}
+
+// Set stores the value under key, ignoring empty keys and values
+func (s *limiterStorage) Set(key string, val []byte, exp time.Duration) error {
+	return s.SetWithContext(context.Background(), key, val, exp)
+}
+
+// DeleteWithContext removes the value under key
+func (s *limiterStorage) DeleteWithContext(ctx context.Context, key string) error {
+	return s.store.Del(ctx, s.prefix+key)
+}
+
+// Delete removes the value under key
+func (s *limiterStorage) Delete(key string) error {
+	return s.DeleteWithContext(context.Background(), key)
+}
+
+// ResetWithContext is not supported
+func (s *limiterStorage) ResetWithContext(context.Context) error {
+	return errStorageReset
+}
+
+// Reset is not supported
+func (s *limiterStorage) Reset() error {
+	return errStorageReset
+}
+
+// Close leaves the store open, since it is shared and closed by its owner
+func (s *limiterStorage) Close() error {
+	return nil
+}
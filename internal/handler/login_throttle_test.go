@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+// throttleConfig throttles an email after three failed logins, while each
+// client IP could make only two and accounts never lock
+func throttleConfig(cfg *config.Config) {
+	cfg.Server.TrustedProxies = []string{testPeer}
+	cfg.RateLimit.LoginMax = 2
+	cfg.RateLimit.LoginEmailMax = 3
+	cfg.Auth.LockoutThreshold = 100
+}
+
+// loginFromIP sends a login as if forwarded for the client at ip
+func (e *testEnv) loginFromIP(ip, email, password string) *testResponse {
+	e.t.Helper()
+
+	req := newRequest(http.MethodPost, "/api/v1/auth/login", loginBody(email, password))
+	req.Header.Set(fiber.HeaderXForwardedFor, ip)
+
+	return e.send(req)
+}
+
+// clientIP returns a distinct address for each n
+func clientIP(n int) string {
+	return fmt.Sprintf("203.0.113.%d", n+1)
+}
+
+func TestLoginThrottlesEmailAcrossIPs(t *testing.T) {
+	env := newTestEnv(t, throttleConfig)
+	env.createUser("ada@example.com")
+	env.createUser("grace@example.com")
+
+	for i := range env.cfg.RateLimit.LoginEmailMax {
+		env.loginFromIP(clientIP(i), "ada@example.com", "wrong").expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+
+	resp := env.loginFromIP(clientIP(10), "ada@example.com", "wrong")
+	resp.expect(http.StatusTooManyRequests, "LOGIN_THROTTLED")
+	seconds, err := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter))
+	if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > env.cfg.RateLimit.LoginEmailWindow {
+		t.Errorf("Retry-After = %q, want seconds within the window", resp.Header.Get(fiber.HeaderRetryAfter))
+	}
+	// The right password does not get through either
+	env.loginFromIP(clientIP(11), "ada@example.com", testPassword).expect(http.StatusTooManyRequests, "LOGIN_THROTTLED")
+
+	// Other emails are unaffected, from the same addresses too
+	env.loginFromIP(clientIP(0), "grace@example.com", testPassword).expect(http.StatusOK)
+}
+
+func TestLoginThrottleHidesUnknownEmails(t *testing.T) {
+	env := newTestEnv(t, throttleConfig)
+	env.createUser("ada@example.com")
+
+	var bodies []string
+	for _, email := range []string{"ada@example.com", "nobody@example.com"} {
+		for i := range env.cfg.RateLimit.LoginEmailMax {
+			env.loginFromIP(clientIP(i), email, "wrong").expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+		}
+		resp := env.loginFromIP(clientIP(10), email, "wrong")
+		resp.expect(http.StatusTooManyRequests, "LOGIN_THROTTLED")
+
+		var body errorBody
+		resp.decode(&body)
+		bodies = append(bodies, body.Error.Message)
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("throttled messages differ for known and unknown emails: %q, %q", bodies[0], bodies[1])
+	}
+}
+
+func TestLoginThrottleResetsOnSuccess(t *testing.T) {
+	env := newTestEnv(t, throttleConfig)
+	env.createUser("ada@example.com")
+
+	limit := env.cfg.RateLimit.LoginEmailMax
+	for i := range limit - 1 {
+		env.loginFromIP(clientIP(i), "ada@example.com", "wrong").expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+	env.loginFromIP(clientIP(10), "ada@example.com", testPassword).expect(http.StatusOK)
+
+	for i := range limit {
+		env.loginFromIP(clientIP(20+i), "ada@example.com", "wrong").expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+	env.loginFromIP(clientIP(30), "ada@example.com", "wrong").expect(http.StatusTooManyRequests, "LOGIN_THROTTLED")
+}
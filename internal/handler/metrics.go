@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests that never reached a route handler, either
+// because none matched or middleware answered first, so probing random paths
+// cannot create new series
+const unmatchedRoute = "unmatched"
+
+// Metrics records HTTP metrics in its own Prometheus registry
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+// NewMetrics creates the HTTP metrics along with the Go runtime and process collectors
+func NewMetrics() *Metrics {
+	labels := []string{"method", "route", "status"}
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests handled.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to handle HTTP requests.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being handled.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.inFlight,
+	)
+
+	return m
+}
+
+// Middleware records every request under its route template and status class
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		// Render errors here so the recorded status matches the response
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		labels := prometheus.Labels{
+			"method": c.Method(),
+			"route":  routeTemplate(c),
+			"status": statusClass(c.Response().StatusCode()),
+		}
+		m.requests.With(labels).Inc()
+		m.duration.With(labels).Observe(time.Since(start).Seconds())
+
+		return nil
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// routeTemplate returns the pattern of the matched route, such as /api/v1/users/:id
+func routeTemplate(c fiber.Ctx) string {
+	// After the chain returns, the route is the last one that ran, which is
+	// a middleware route when no endpoint handler was reached
+	if !c.Matched() {
+		return unmatchedRoute
+	}
+
+	return c.Route().Path
+}
+
+// statusClass groups status codes as 2xx, 4xx, and so on
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
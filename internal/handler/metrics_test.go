@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+func TestMetrics(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	token := env.tokenFor(admin)
+
+	env.do(http.MethodGet, "/api/v1/users/"+admin.ID, token, nil).expect(http.StatusOK)
+	env.do(http.MethodGet, "/api/v1/users/00000000-0000-0000-0000-000000000000", token, nil).expect(http.StatusNotFound)
+	env.do(http.MethodGet, "/no-such-page", "", nil).expect(http.StatusNotFound)
+
+	resp := env.do(http.MethodGet, "/metrics", "", nil)
+	resp.expect(http.StatusOK)
+	body := string(resp.body)
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/api/v1/users/:id",status="2xx"} 1`,
+		`http_requests_total{method="GET",route="/api/v1/users/:id",status="4xx"} 1`,
+		`http_requests_total{method="GET",route="` + unmatchedRoute + `",status="4xx"} 1`,
+		"http_request_duration_seconds_bucket",
+		"http_requests_in_flight",
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics have no %s", want)
+		}
+	}
+	if strings.Contains(body, admin.ID) {
+		t.Error("metrics are labeled with a concrete path")
+	}
+}
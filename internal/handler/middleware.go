@@ -1,20 +1,445 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/featureflag"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
 	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/middleware/logger"
+	"github.com/gofiber/fiber/v3/middleware/cors"
+	"github.com/gofiber/fiber/v3/middleware/limiter"
 	"github.com/gofiber/fiber/v3/middleware/recover"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// SetupMiddleware configures all application middleware
-func SetupMiddleware(app *fiber.App) {
+// SetupMiddleware configures all application middleware. Rate limits keep
+// their counters in store, shared with other replicas when it is.
+func SetupMiddleware(app *fiber.App, cfg *config.Config, metrics *Metrics, flags *featureflag.Flags, store kvstore.Store) {
 	// Recovery middleware to catch panics
 	app.Use(recover.New(recover.Config{
 		EnableStackTrace: true,
 	}))
 
-	// Logger middleware for HTTP requests
-	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${ip} ${status} - ${latency} ${method} ${path} ${error}\n",
-	}))
+	// Security headers, set first so every response carries them
+	app.Use(SecurityHeaders(cfg.Security, cfg.Server.TLSEnabled))
+
+	// Request ID middleware, reusing an incoming X-Request-ID when present
+	app.Use(requestid.New())
+	app.Use(RequestContext())
+	app.Use(PrimaryReads())
+
+	// Feature flags for RequireFeature, overridable per request outside production
+	app.Use(FeatureFlags(flags, !cfg.IsProduction()))
+
+	// OpenTelemetry server span per request
+	app.Use(Tracing())
+
+	// Prometheus HTTP metrics labeled by route template
+	app.Use(metrics.Middleware())
+
+	// Structured access logging for HTTP requests
+	app.Use(AccessLog(cfg.Log))
+
+	// Reject requests for hosts this server does not serve, after the access
+	// log so they are still logged
+	app.Use(TrustedHosts(cfg.Server.TrustedHosts))
+
+	// Response compression for clients that accept it
+	if cfg.Compress.Enabled {
+		app.Use(Compress(cfg.Compress))
+	}
+
+	// Request and response bodies for debugging, inside compression so the
+	// response is logged readable
+	if cfg.Log.Bodies {
+		app.Use(BodyLog(cfg.Log))
+	}
+
+	// CORS middleware for browser clients on other origins
+	app.Use(newCORS(cfg.CORS))
+
+	// Per-IP rate limiting, with a stricter limit against login brute force
+	app.Use(newRateLimiter(newLimiterStorage(store, "ratelimit:global"), cfg.RateLimit.Max, cfg.RateLimit.Window))
+	app.Use("/api/v1/auth/login", newRateLimiter(newLimiterStorage(store, "ratelimit:login"), cfg.RateLimit.LoginMax, cfg.RateLimit.LoginWindow))
+	app.Use("/api/v1/auth/2fa/verify", newRateLimiter(newLimiterStorage(store, "ratelimit:2fa"), cfg.RateLimit.LoginMax, cfg.RateLimit.LoginWindow))
+
+	// Bound how long a request, and the queries it runs, may take
+	app.Use(Timeout(cfg.Server.RequestTimeout))
+}
+
+// RequestContext copies the request id and client IP into the request
+// context, where the service layer reads them for audit entries; it must run
+// after requestid
+func RequestContext() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ctx := audit.WithRequestID(c.Context(), RequestID(c))
+		c.SetContext(audit.WithClientIP(ctx, ClientIP(c)))
+		return c.Next()
+	}
+}
+
+// PrimaryReads sends the reads of requests that may write to the primary
+// database, so they see their own writes and read-modify-write updates do not
+// start from a lagging replica. Safe methods keep reading from the replica.
+func PrimaryReads() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		default:
+			c.SetContext(repository.ReadPrimary(c.Context()))
+		}
+		return c.Next()
+	}
+}
+
+// Timeout cancels the request context after d, so repository calls stop
+// instead of running on after the client has been answered. Handlers that
+// fail because the deadline passed get a 504; ones that finish anyway keep
+// their response, as their changes may already have taken effect.
+func Timeout(d time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return nextWithTimeout(c, c.Context(), d)
+	}
+}
+
+// RouteTimeout gives a route its own timeout in place of the one Timeout set
+// for every request, so routes such as the CSV export can run longer, and
+// others may give up sooner
+func RouteTimeout(d time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		// Keep the values of the request context but not its deadline
+		return nextWithTimeout(c, context.WithoutCancel(c.Context()), d)
+	}
+}
+
+// nextWithTimeout runs the rest of the chain with a context derived from
+// parent that expires after d, answering failures it caused with a 504
+func nextWithTimeout(c fiber.Ctx, parent context.Context, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(parent, d)
+	defer cancel()
+	c.SetContext(ctx)
+
+	err := c.Next()
+	if ctx.Err() != nil && errors.Is(err, context.DeadlineExceeded) {
+		return apperror.GatewayTimeout(err)
+	}
+	return err
+}
+
+// newRateLimiter allows max requests per client IP within each window
+func newRateLimiter(storage fiber.Storage, maxRequests int, window time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Storage:      storage,
+		Max:          maxRequests,
+		Expiration:   window,
+		KeyGenerator: ClientIP,
+		LimitReached: func(c fiber.Ctx) error {
+			// The limiter has already set the Retry-After header
+			return apperror.TooManyRequests(apperror.CodeTooManyRequests, "too many requests")
+		},
+	})
+}
+
+// AccessLog logs requests through slog and attaches a request-scoped logger
+// carrying the request id to the request context. Every error response and
+// every request slower than cfg.AccessSlowThreshold is logged, while only one
+// in cfg.AccessSampleRate of the others is; sampled entries carry the rate so
+// aggregators can scale counts back up.
+func AccessLog(cfg config.LogConfig) fiber.Handler {
+	var requests atomic.Uint64
+	rate := uint64(max(cfg.AccessSampleRate, 1))
+
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+		l := slog.Default().With(slog.String("request_id", RequestID(c)))
+		if span := trace.SpanContextFromContext(c.Context()); span.HasTraceID() {
+			l = l.With(slog.String("trace_id", span.TraceID().String()))
+		}
+		c.SetContext(logger.WithContext(c.Context(), l))
+
+		// Render errors here so the logged status matches the response
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		latency := time.Since(start)
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Duration("latency", latency),
+			slog.String("ip", ClientIP(c)),
+		}
+		slow := cfg.AccessSlowThreshold > 0 && latency > cfg.AccessSlowThreshold
+		switch {
+		case level > slog.LevelInfo:
+		case slow:
+			attrs = append(attrs, slog.Bool("slow", true))
+		case rate > 1:
+			// Count only the requests subject to sampling, so one in rate of
+			// them is logged however many errors come in between
+			if (requests.Add(1)-1)%rate != 0 {
+				return nil
+			}
+			attrs = append(attrs, slog.Uint64("sample_rate", rate))
+		}
+
+		l.LogAttrs(c.Context(), level, "http request", attrs...)
+
+		return nil
+	}
+}
+
+// newCORS builds the CORS middleware from configuration
+func newCORS(cfg config.CORSConfig) fiber.Handler {
+	corsConfig := cors.Config{
+		AllowOrigins:     cfg.AllowedOrigins,
+		AllowMethods:     cfg.AllowedMethods,
+		AllowHeaders:     cfg.AllowedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		// Let browser clients read the headers used for conditional and retried requests
+		ExposeHeaders: []string{fiber.HeaderETag, HeaderIdempotentReplayed},
+	}
+
+	// The cors middleware treats an empty list as "allow all", so deny explicitly
+	if len(cfg.AllowedOrigins) == 0 {
+		corsConfig.AllowOriginsFunc = func(string) bool { return false }
+	}
+
+	return cors.New(corsConfig)
+}
+
+// Fiber locals keys holding the authenticated identity
+const (
+	userIDKey          = "userID"
+	userRolesKey       = "userRoles"
+	userPermissionsKey = "userPermissions"
+	sessionIDKey       = "sessionID"
+	actorIDKey         = "actorID"
+	apiKeyKey          = "apiKey"
+)
+
+// HeaderAPIKey carries the API key of backend service callers
+const HeaderAPIKey = "X-API-Key"
+
+// errAccountSuspended is returned for requests made by suspended users
+var errAccountSuspended = apperror.Forbidden("ACCOUNT_SUSPENDED", "account is suspended")
+
+// RequireAuth rejects requests without a valid bearer access token, tokens
+// revoked through sessions, and those of users who were suspended or deleted
+// after the token was issued. Impersonation tokens authenticate as the
+// impersonated user, with the admin behind them exposed by CurrentActorID.
+// The permissions the token's roles grant are resolved for RequirePermission.
+func RequireAuth(tokens *auth.TokenManager, users *service.UserService, sessions *service.SessionService, permissions *service.PermissionService) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return apperror.Unauthorized("MISSING_TOKEN", "missing or malformed bearer token")
+		}
+
+		claims, err := tokens.ParseToken(token)
+		if err != nil {
+			return apperror.Unauthorized("INVALID_TOKEN", "invalid or expired token")
+		}
+		if err := sessions.CheckAccessToken(c.Context(), claims); err != nil {
+			if errors.Is(err, service.ErrAccessTokenRevoked) {
+				return apperror.Unauthorized("TOKEN_REVOKED", "token has been revoked")
+			}
+			return err
+		}
+		if err := checkActive(c, users, claims.Subject); err != nil {
+			return err
+		}
+
+		c.Locals(userIDKey, claims.Subject)
+		granted, err := permissions.Permissions(c.Context(), claims.RoleSet())
+		if err != nil {
+			return err
+		}
+
+		c.Locals(userRolesKey, claims.RoleSet())
+		c.Locals(userPermissionsKey, granted)
+		c.Locals(sessionIDKey, claims.SessionID)
+		c.Locals(actorIDKey, claims.ActorID())
+		c.SetContext(auth.WithIdentity(c.Context(), auth.Identity{
+			UserID:    claims.Subject,
+			Roles:     claims.RoleSet(),
+			SessionID: claims.SessionID,
+			ActorID:   claims.ActorID(),
+		}))
+		return c.Next()
+	}
+}
+
+// RequireAPIKey rejects requests without a valid, unrevoked X-API-Key header,
+// and keys whose owner has been suspended or deleted
+func RequireAPIKey(keys *service.APIKeyService, users *service.UserService) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		plaintext := c.Get(HeaderAPIKey)
+		if plaintext == "" {
+			return apperror.Unauthorized("MISSING_API_KEY", "missing api key")
+		}
+
+		key, err := keys.Authenticate(c.Context(), plaintext)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidAPIKey) {
+				return apperror.Unauthorized("INVALID_API_KEY", "invalid or revoked api key")
+			}
+			return err
+		}
+		if err := checkActive(c, users, key.OwnerID); err != nil {
+			return err
+		}
+
+		// Changes made with the key are audited as its owner
+		c.Locals(apiKeyKey, key)
+		c.SetContext(auth.WithIdentity(c.Context(), auth.Identity{UserID: key.OwnerID}))
+		return c.Next()
+	}
+}
+
+// RequireAuthOrAPIKey authenticates requests with an X-API-Key header as an
+// API key and all others as a bearer access token
+func RequireAuthOrAPIKey(tokens *auth.TokenManager, sessions *service.SessionService, permissions *service.PermissionService, keys *service.APIKeyService, users *service.UserService) fiber.Handler {
+	requireAuth := RequireAuth(tokens, users, sessions, permissions)
+	requireAPIKey := RequireAPIKey(keys, users)
+
+	return func(c fiber.Ctx) error {
+		if c.Get(HeaderAPIKey) != "" {
+			return requireAPIKey(c)
+		}
+
+		return requireAuth(c)
+	}
+}
+
+// checkActive rejects credentials of users who are suspended or no longer exist
+func checkActive(c fiber.Ctx, users *service.UserService, userID string) error {
+	err := users.CheckActive(c.Context(), userID)
+	switch {
+	case errors.Is(err, service.ErrAccountSuspended):
+		return errAccountSuspended
+	case errors.Is(err, repository.ErrUserNotFound):
+		return apperror.Unauthorized("INVALID_TOKEN", "invalid or expired token")
+	}
+
+	return err
+}
+
+// RequireRole rejects authenticated users holding no role that grants the
+// given one; it must run after RequireAuth. The roles are those the access
+// token was issued with. API keys carry no role and are always rejected.
+func RequireRole(role domain.Role) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if current, _ := CurrentUserRoles(c); !current.Grants(role) {
+			return apperror.Forbidden(apperror.CodeForbidden, "insufficient permissions")
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireScope rejects API keys without the given scope and lets user tokens
+// through for the route handler to authorize
+func RequireScope(scope domain.APIKeyScope) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if key, ok := CurrentAPIKey(c); ok && !key.HasScope(scope) {
+			return apperror.Forbidden("INSUFFICIENT_SCOPE", fmt.Sprintf("api key lacks the %s scope", scope))
+		}
+
+		return c.Next()
+	}
+}
+
+// RequirePermission rejects users whose roles do not grant the permission,
+// and API keys without the scope domain.PermissionScopes maps it to; keys are
+// refused permissions it does not map. It must run after authentication.
+func RequirePermission(permission domain.Permission) fiber.Handler {
+	scope, keysAllowed := domain.PermissionScopes[permission]
+	requireScope := RequireScope(scope)
+
+	return func(c fiber.Ctx) error {
+		if _, ok := CurrentAPIKey(c); ok {
+			if !keysAllowed {
+				return apperror.Forbidden(apperror.CodeForbidden, "insufficient permissions")
+			}
+			return requireScope(c)
+		}
+
+		if !HasPermission(c, permission) {
+			return apperror.Forbidden(apperror.CodeForbidden, fmt.Sprintf("missing the %s permission", permission))
+		}
+
+		return c.Next()
+	}
+}
+
+// CurrentUserID returns the id of the authenticated user, if any
+func CurrentUserID(c fiber.Ctx) (string, bool) {
+	userID, ok := c.Locals(userIDKey).(string)
+	return userID, ok && userID != ""
+}
+
+// CurrentUserRoles returns the roles of the authenticated user, if any
+func CurrentUserRoles(c fiber.Ctx) (domain.Roles, bool) {
+	roles, ok := c.Locals(userRolesKey).(domain.Roles)
+	return roles, ok && len(roles) > 0
+}
+
+// HasPermission reports whether the authenticated user's roles grant the
+// permission; API keys hold none
+func HasPermission(c fiber.Ctx, permission domain.Permission) bool {
+	permissions, _ := c.Locals(userPermissionsKey).(domain.PermissionSet)
+	return permissions.Has(permission)
+}
+
+// CurrentSessionID returns the login session of the access token the request
+// was authenticated with, if any
+func CurrentSessionID(c fiber.Ctx) (string, bool) {
+	sessionID, ok := c.Locals(sessionIDKey).(string)
+	return sessionID, ok && sessionID != ""
+}
+
+// CurrentActorID returns the id of the admin impersonating the authenticated
+// user, if any
+func CurrentActorID(c fiber.Ctx) (string, bool) {
+	actorID, ok := c.Locals(actorIDKey).(string)
+	return actorID, ok && actorID != ""
+}
+
+// CurrentAPIKey returns the API key the request was authenticated with, if any
+func CurrentAPIKey(c fiber.Ctx) (*domain.APIKey, bool) {
+	key, ok := c.Locals(apiKeyKey).(*domain.APIKey)
+	return key, ok
+}
+
+// RequestID returns the id assigned to the current request
+func RequestID(c fiber.Ctx) string {
+	return requestid.FromContext(c)
 }
@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestRequireAuth(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+
+	expired, err := env.tokens.GenerateToken(user.ID, user.Roles, "", -time.Hour)
+	if err != nil {
+		t.Fatalf("generate expired token: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		code          string
+	}{
+		{"valid token", "Bearer " + env.tokenFor(user), http.StatusOK, ""},
+		{"lowercase scheme", "bearer " + env.tokenFor(user), http.StatusOK, ""},
+		{"expired token", "Bearer " + expired, http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"tampered token", "Bearer " + env.tokenFor(user) + "x", http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"missing header", "", http.StatusUnauthorized, "MISSING_TOKEN"},
+		{"other scheme", "Basic YWRhOnNlY3JldA==", http.StatusUnauthorized, "MISSING_TOKEN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(http.MethodGet, "/api/v1/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			resp := env.send(req)
+			if tt.code == "" {
+				resp.expect(tt.status)
+			} else {
+				resp.expect(tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		allowed bool
+	}{
+		{"allowed origin", []string{"https://app.example.com"}, "https://app.example.com", true},
+		{"other origin", []string{"https://app.example.com"}, "https://evil.example.com", false},
+		{"no origins configured", nil, "https://app.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) { cfg.CORS.AllowedOrigins = tt.origins })
+
+			req := newRequest(http.MethodGet, "/api/v1/health/live", nil)
+			req.Header.Set("Origin", tt.origin)
+			resp := env.send(req)
+			resp.expect(http.StatusOK)
+
+			want := ""
+			if tt.allowed {
+				want = tt.origin
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != want {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.CORS.AllowedOrigins = []string{"https://app.example.com"} })
+
+	req := newRequest(http.MethodOptions, "/api/v1/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	resp := env.send(req)
+	resp.expect(http.StatusNoContent)
+
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the origin", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPatch) {
+		t.Errorf("Access-Control-Allow-Methods = %q, want it to include PATCH", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.RateLimit.Max = 3 })
+
+	for i := range 3 {
+		resp := env.do(http.MethodGet, "/api/v1/health/live", "", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status %d, want %d", i+1, resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	resp := env.do(http.MethodGet, "/api/v1/health/live", "", nil)
+	resp.expect(http.StatusTooManyRequests, "TOO_MANY_REQUESTS")
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("429 response has no Retry-After header")
+	}
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.RateLimit.LoginMax = 2 })
+
+	for range 2 {
+		env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", "wrong")).expect(http.StatusUnauthorized)
+	}
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", "wrong")).expect(http.StatusTooManyRequests)
+
+	// Other routes keep their own, larger budget
+	env.do(http.MethodGet, "/api/v1/health/live", "", nil).expect(http.StatusOK)
+}
+
+func TestRequestID(t *testing.T) {
+	env := newTestEnv(t)
+
+	generated := env.do(http.MethodGet, "/api/v1/health/live", "", nil)
+	generated.expect(http.StatusOK)
+	if generated.Header.Get("X-Request-ID") == "" {
+		t.Error("response has no X-Request-ID header")
+	}
+	other := env.do(http.MethodGet, "/api/v1/health/live", "", nil)
+	if other.Header.Get("X-Request-ID") == generated.Header.Get("X-Request-ID") {
+		t.Errorf("two requests share the X-Request-ID %q", generated.Header.Get("X-Request-ID"))
+	}
+
+	req := newRequest(http.MethodGet, "/api/v1/health/live", nil)
+	req.Header.Set("X-Request-ID", "support-ticket-42")
+	if got := env.send(req).Header.Get("X-Request-ID"); got != "support-ticket-42" {
+		t.Errorf("X-Request-ID = %q, want the supplied %q", got, "support-ticket-42")
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	env := newTestEnv(t)
+	logs := captureLogs(t)
+
+	req := newRequest(http.MethodGet, "/api/v1/health/live", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	env.send(req).expect(http.StatusOK)
+	env.do(http.MethodGet, "/api/v1/me", "", nil).expect(http.StatusUnauthorized)
+
+	entries := logs.find("http request")
+	if len(entries) != 2 {
+		t.Fatalf("logged %d access entries, want 2", len(entries))
+	}
+	for _, key := range []string{"time", "level", "request_id", "method", "path", "status", "latency", "ip"} {
+		if _, ok := entries[0][key]; !ok {
+			t.Errorf("access entry %v has no %q key", entries[0], key)
+		}
+	}
+	if entries[0]["request_id"] != "req-1" || entries[0]["path"] != "/api/v1/health/live" || entries[0]["status"] != float64(http.StatusOK) {
+		t.Errorf("access entry = %v, want request_id req-1, the path and status 200", entries[0])
+	}
+	if entries[1]["level"] != "WARN" || entries[1]["status"] != float64(http.StatusUnauthorized) {
+		t.Errorf("client error entry = %v, want a WARN with status 401", entries[1])
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Server.BodyLimit = 1024 })
+
+	// The limit is enforced while reading the request, before app.Test could
+	// return a response, so the app serves a real connection
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = env.app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+	t.Cleanup(func() { _ = env.app.Shutdown() })
+
+	post := func(body string) *testResponse {
+		t.Helper()
+		resp, err := http.Post("http://"+ln.Addr().String()+"/api/v1/auth/login", fiber.MIMEApplicationJSON, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /api/v1/auth/login: %v", err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		return &testResponse{t: t, Response: resp, body: data}
+	}
+
+	post(`{"email":"ada@example.com","password":"`+strings.Repeat("a", 2048)+`"}`).
+		expect(http.StatusRequestEntityTooLarge, "REQUEST_ENTITY_TOO_LARGE")
+
+	// A body under the limit reaches the handler
+	post(`{"email":"ada@example.com","password":"wrong"}`).expect(http.StatusUnauthorized)
+}
+
+func TestTimeout(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(Timeout(20 * time.Millisecond))
+
+	cancelled := make(chan error, 1)
+	// slow stands in for a query that stops when its context is cancelled
+	app.Get("/slow", func(c fiber.Ctx) error {
+		select {
+		case <-c.Context().Done():
+			cancelled <- c.Context().Err()
+			return c.Context().Err()
+		case <-time.After(5 * time.Second):
+			return c.SendString("finished")
+		}
+	})
+	app.Get("/fast", func(c fiber.Ctx) error {
+		return c.SendString("finished")
+	})
+	// late ignores the deadline and still answers
+	app.Get("/late", func(c fiber.Ctx) error {
+		time.Sleep(40 * time.Millisecond)
+		return c.SendString("finished")
+	})
+	env := &testEnv{t: t, app: app}
+
+	env.do(http.MethodGet, "/slow", "", nil).expect(http.StatusGatewayTimeout, "REQUEST_TIMEOUT")
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("handler context error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	default:
+		t.Error("the handler context was not cancelled")
+	}
+
+	env.do(http.MethodGet, "/fast", "", nil).expect(http.StatusOK)
+	env.do(http.MethodGet, "/late", "", nil).expect(http.StatusOK)
+}
+
+func TestRouteTimeout(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(Timeout(50 * time.Millisecond))
+
+	// wait stands in for a query running d, or until its context is cancelled
+	wait := func(d time.Duration) fiber.Handler {
+		return func(c fiber.Ctx) error {
+			select {
+			case <-c.Context().Done():
+				return c.Context().Err()
+			case <-time.After(d):
+				return c.SendString("finished")
+			}
+		}
+	}
+	app.Get("/short", RouteTimeout(10*time.Millisecond), wait(30*time.Millisecond))
+	app.Get("/long", RouteTimeout(time.Second), wait(100*time.Millisecond))
+	env := &testEnv{t: t, app: app}
+
+	env.do(http.MethodGet, "/short", "", nil).expect(http.StatusGatewayTimeout, "REQUEST_TIMEOUT")
+	// The route outlasts the request timeout it replaces
+	env.do(http.MethodGet, "/long", "", nil).expect(http.StatusOK)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/gofiber/fiber/v3"
+)
+
+// oauthNonceCookie binds an OAuth sign-in to the browser that started it
+const oauthNonceCookie = "oauth_nonce"
+
+// OAuthHandler serves the social login endpoints
+type OAuthHandler struct {
+	oauth     *service.OAuthService
+	sessions  *service.SessionService
+	twoFactor *service.TwoFactorService
+}
+
+// NewOAuthHandler creates an OAuthHandler backed by the given services
+func NewOAuthHandler(oauth *service.OAuthService, sessions *service.SessionService, twoFactor *service.TwoFactorService) *OAuthHandler {
+	return &OAuthHandler{oauth: oauth, sessions: sessions, twoFactor: twoFactor}
+}
+
+// StartOAuth handler redirects to the provider's consent screen, keeping the
+// nonce of the signed state in a cookie scoped to the callback
+func (h *OAuthHandler) StartOAuth(c fiber.Ctx) error {
+	provider := strings.Clone(c.Params("provider"))
+	start, err := h.oauth.Start(c.Context(), provider)
+	if err != nil {
+		return oauthError(c, err)
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthNonceCookie,
+		Value:    start.Nonce,
+		Path:     oauthCookiePath(provider),
+		Expires:  start.ExpiresAt,
+		Secure:   c.Secure(),
+		HTTPOnly: true,
+		// Lax still sends the cookie on the top-level redirect back from the provider
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return c.Redirect().Status(fiber.StatusFound).To(start.URL)
+}
+
+// OAuthCallback handler completes a sign-in when the provider redirects back,
+// answering like Login
+func (h *OAuthHandler) OAuthCallback(c fiber.Ctx) error {
+	provider := strings.Clone(c.Params("provider"))
+	nonce := c.Cookies(oauthNonceCookie)
+
+	// Each nonce serves one callback, whatever its outcome
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthNonceCookie,
+		Path:     oauthCookiePath(provider),
+		Expires:  time.Unix(0, 0),
+		Secure:   c.Secure(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	if c.Query("error") != "" {
+		return apperror.BadRequest("OAUTH_DENIED", "the identity provider did not grant access")
+	}
+
+	user, err := h.oauth.Complete(c.Context(), provider, c.Query("state"), nonce, c.Query("code"))
+	if err != nil {
+		return oauthError(c, err)
+	}
+
+	return signIn(c, h.sessions, h.twoFactor, user)
+}
+
+// oauthCookiePath limits the nonce cookie to the provider's OAuth routes
+func oauthCookiePath(provider string) string {
+	return "/api/v1/auth/oauth/" + provider
+}
+
+// oauthError translates OAuth sign-in errors into API errors
+func oauthError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrUnknownOAuthProvider):
+		return apperror.NotFound("OAUTH_PROVIDER_NOT_FOUND", "unknown or disabled oauth provider")
+	case errors.Is(err, service.ErrInvalidOAuthState):
+		return apperror.BadRequest("INVALID_OAUTH_STATE", "invalid or expired oauth state")
+	case errors.Is(err, service.ErrOAuthEmailNotVerified):
+		return apperror.Forbidden("OAUTH_EMAIL_NOT_VERIFIED", "the identity provider has not verified your email address")
+	case errors.Is(err, service.ErrOAuthExchangeFailed):
+		// Usually an expired or reused code, but the cause helps spot misconfiguration
+		logger.FromContext(c.Context()).Warn("oauth code exchange failed", slog.Any("error", err))
+		return apperror.BadRequest("OAUTH_EXCHANGE_FAILED", "could not complete the sign-in with the identity provider")
+	}
+
+	return authError(err)
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/oauth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/gofiber/fiber/v3"
+)
+
+// listAll lists the first hundred users, enough for any test
+var listAll = repository.ListParams{Page: 1, PageSize: 100}
+
+// oauthStart is what the start route handed the browser
+type oauthStart struct {
+	state string
+	nonce string
+}
+
+// startOAuth begins a Google sign-in and returns its state and nonce cookie
+func (e *testEnv) startOAuth() oauthStart {
+	e.t.Helper()
+
+	resp := e.do(http.MethodGet, "/api/v1/auth/oauth/google", "", nil)
+	resp.expect(http.StatusFound)
+
+	location, err := url.Parse(resp.Header.Get(fiber.HeaderLocation))
+	if err != nil {
+		e.t.Fatalf("parse Location: %v", err)
+	}
+	start := oauthStart{state: location.Query().Get("state")}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oauthNonceCookie {
+			start.nonce = cookie.Value
+			if !cookie.HttpOnly || cookie.Path != "/api/v1/auth/oauth/google" {
+				e.t.Errorf("nonce cookie = %+v, want it HttpOnly and scoped to the provider routes", cookie)
+			}
+		}
+	}
+	if start.state == "" || start.nonce == "" {
+		e.t.Fatalf("start = %+v, want a state and a nonce cookie", start)
+	}
+
+	return start
+}
+
+// oauthCallback returns to the callback with the state, code, and nonce cookie
+func (e *testEnv) oauthCallback(state, nonce, code string) *testResponse {
+	e.t.Helper()
+
+	req := newRequest(http.MethodGet, "/api/v1/auth/oauth/google/callback?"+url.Values{"state": {state}, "code": {code}}.Encode(), nil)
+	if nonce != "" {
+		req.AddCookie(&http.Cookie{Name: oauthNonceCookie, Value: nonce})
+	}
+
+	return e.send(req)
+}
+
+func TestOAuthCallbackCreatesUser(t *testing.T) {
+	env := newTestEnv(t)
+	env.google.add("good-code", &oauth.Profile{Subject: "google-1", Email: "Ada@Example.com", EmailVerified: true, Name: "Ada Lovelace"})
+
+	start := env.startOAuth()
+	var tokens tokenResponse
+	resp := env.oauthCallback(start.state, start.nonce, "good-code")
+	resp.expect(http.StatusOK)
+	resp.decode(&tokens)
+
+	user, err := env.users.GetByEmail(context.Background(), "ada@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	if user.Name != "Ada Lovelace" || user.EmailVerifiedAt == nil {
+		t.Errorf("user = %+v, want the Google profile with a verified email", user)
+	}
+	env.do(http.MethodGet, "/api/v1/me", tokens.AccessToken, nil).expect(http.StatusOK)
+
+	// Signing in again finds the linked user instead of creating another
+	start = env.startOAuth()
+	env.oauthCallback(start.state, start.nonce, "good-code").expect(http.StatusOK)
+	if _, total, _ := env.users.List(context.Background(), listAll); total != 1 {
+		t.Errorf("users after the second sign-in = %d, want 1", total)
+	}
+}
+
+func TestOAuthCallbackLinksRegisteredEmail(t *testing.T) {
+	t.Run("verified", func(t *testing.T) {
+		env := newTestEnv(t)
+		user := env.createUser("ada@example.com")
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
+		if err := env.users.Update(context.Background(), user); err != nil {
+			t.Fatalf("verify email: %v", err)
+		}
+		env.google.add("good-code", &oauth.Profile{Subject: "google-1", Email: "ada@example.com", EmailVerified: true})
+
+		start := env.startOAuth()
+		var tokens tokenResponse
+		resp := env.oauthCallback(start.state, start.nonce, "good-code")
+		resp.expect(http.StatusOK)
+		resp.decode(&tokens)
+
+		var me struct {
+			ID string `json:"id"`
+		}
+		resp = env.do(http.MethodGet, "/api/v1/me", tokens.AccessToken, nil)
+		resp.expect(http.StatusOK)
+		resp.decode(&me)
+		if me.ID != user.ID {
+			t.Errorf("signed in as %s, want the registered user %s", me.ID, user.ID)
+		}
+		// The password keeps working alongside Google
+		env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+	})
+
+	t.Run("unverified", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.createUser("ada@example.com")
+		env.google.add("good-code", &oauth.Profile{Subject: "google-1", Email: "ada@example.com", EmailVerified: true})
+
+		start := env.startOAuth()
+		env.oauthCallback(start.state, start.nonce, "good-code").expect(http.StatusOK)
+
+		// Whoever registered the address without proving it loses the password
+		env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusUnauthorized)
+	})
+}
+
+func TestOAuthCallbackRejectsStateMismatch(t *testing.T) {
+	env := newTestEnv(t)
+	env.google.add("good-code", &oauth.Profile{Subject: "google-1", Email: "ada@example.com", EmailVerified: true})
+	start := env.startOAuth()
+	other := env.startOAuth()
+
+	tests := []struct {
+		name  string
+		state string
+		nonce string
+	}{
+		{"no nonce cookie", start.state, ""},
+		{"nonce of another sign-in", start.state, other.nonce},
+		{"tampered state", start.state + "x", start.nonce},
+		{"no state", "", start.nonce},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.oauthCallback(tt.state, tt.nonce, "good-code").expect(http.StatusBadRequest, "INVALID_OAUTH_STATE")
+		})
+	}
+	if _, total, _ := env.users.List(context.Background(), listAll); total != 0 {
+		t.Errorf("rejected callbacks created %d users", total)
+	}
+}
+
+func TestOAuthCallbackFailures(t *testing.T) {
+	env := newTestEnv(t)
+	env.google.add("unverified-code", &oauth.Profile{Subject: "google-2", Email: "ada@example.com"})
+
+	start := env.startOAuth()
+	env.oauthCallback(start.state, start.nonce, "unknown-code").expect(http.StatusBadRequest, "OAUTH_EXCHANGE_FAILED")
+	start = env.startOAuth()
+	env.oauthCallback(start.state, start.nonce, "unverified-code").expect(http.StatusForbidden, "OAUTH_EMAIL_NOT_VERIFIED")
+
+	env.do(http.MethodGet, "/api/v1/auth/oauth/google/callback?error=access_denied", "", nil).expect(http.StatusBadRequest, "OAUTH_DENIED")
+	env.do(http.MethodGet, "/api/v1/auth/oauth/github", "", nil).expect(http.StatusNotFound, "OAUTH_PROVIDER_NOT_FOUND")
+}
@@ -0,0 +1,641 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/buildinfo"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/openapi"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/pagination"
+	"github.com/gofiber/fiber/v3"
+)
+
+// messageResponse is the body of endpoints that only confirm an action
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// healthResponse is the body of the health endpoints
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// bearerAuth requires an access token in the Authorization header
+var bearerAuth = []map[string][]string{{"bearerAuth": {}}}
+
+// bearerOrAPIKeyAuth accepts either an access token or an X-API-Key header
+var bearerOrAPIKeyAuth = []map[string][]string{{"bearerAuth": {}}, {"apiKeyAuth": {}}}
+
+// noAuth marks an operation as public
+var noAuth = []map[string][]string{}
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+)
+
+// OpenAPISpec handler serves the OpenAPI document describing every route
+func OpenAPISpec(c fiber.Ctx) error {
+	specOnce.Do(func() {
+		// The document only holds plain values, so encoding cannot fail
+		specJSON, _ = json.Marshal(buildSpec())
+	})
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(specJSON)
+}
+
+// SwaggerUI handler serves a Swagger UI page for the OpenAPI document
+func SwaggerUI(c fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(swaggerPage)
+}
+
+const swaggerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>User Management API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>` + swaggerInitScript + `</script>
+</body>
+</html>
+`
+
+// swaggerInitScript starts Swagger UI; docsCSP allows it by its hash
+const swaggerInitScript = `
+    window.ui = SwaggerUIBundle({ url: "/api/v1/openapi.json", dom_id: "#swagger-ui" });
+  `
+
+// specBuilder adds operations to a document with the shared error responses
+type specBuilder struct {
+	doc *openapi.Document
+}
+
+// op describes one route for the spec
+type op struct {
+	summary string
+	tag     string
+	public  bool
+	// permission is the one users need for the route
+	permission domain.Permission
+	// scope also admits API keys granted this scope
+	scope    domain.APIKeyScope
+	params   []openapi.Parameter
+	body     any
+	bodyType string
+	// responses maps a status code to a body, nil for empty responses
+	responses map[int]any
+	errors    []int
+}
+
+func (b *specBuilder) add(method, path string, o op) {
+	operation := &openapi.Operation{
+		Summary:    o.summary,
+		Tags:       []string{o.tag},
+		Security:   bearerAuth,
+		Parameters: o.params,
+		Responses:  make(map[string]openapi.Response),
+	}
+	if o.public {
+		operation.Security = noAuth
+	}
+	var requires []string
+	if o.permission != "" {
+		requires = append(requires, "Users need the "+string(o.permission)+" permission.")
+	}
+	if o.scope != "" {
+		operation.Security = bearerOrAPIKeyAuth
+		requires = append(requires, "API keys need the "+string(o.scope)+" scope.")
+	}
+	operation.Description = strings.Join(requires, " ")
+
+	if o.body != nil {
+		content := dtoContent(b.schema(o.body))
+		if o.bodyType != "" {
+			content = map[string]openapi.MediaType{o.bodyType: {Schema: b.schema(o.body)}}
+		}
+		operation.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content:  content,
+		}
+	}
+
+	for status, body := range o.responses {
+		operation.Responses[strconv.Itoa(status)] = b.response(status, body)
+	}
+
+	errs := o.errors
+	if o.body != nil && o.bodyType == "" {
+		errs = append(errs, http.StatusBadRequest, http.StatusUnprocessableEntity)
+	}
+	if !o.public {
+		errs = append(errs, http.StatusUnauthorized)
+	}
+	if o.permission != "" {
+		errs = append(errs, http.StatusForbidden)
+	}
+	errs = append(errs, http.StatusInternalServerError)
+	for _, status := range errs {
+		operation.Responses[strconv.Itoa(status)] = openapi.Response{
+			Description: http.StatusText(status),
+			Content:     dtoContent(b.doc.SchemaOf(errorBody{})),
+		}
+	}
+
+	b.doc.Add(method, path, operation)
+}
+
+// schema returns the schema of a body, where a string names a raw media type
+func (b *specBuilder) schema(body any) *openapi.Schema {
+	if _, ok := body.(string); ok {
+		return &openapi.Schema{Type: "string", Format: "binary"}
+	}
+
+	return b.doc.SchemaOf(body)
+}
+
+func (b *specBuilder) response(status int, body any) openapi.Response {
+	resp := openapi.Response{Description: http.StatusText(status)}
+	switch body := body.(type) {
+	case nil:
+	case string:
+		resp.Content = map[string]openapi.MediaType{body: {Schema: b.schema(body)}}
+	default:
+		resp.Content = dtoContent(b.schema(body))
+	}
+
+	return resp
+}
+
+// dtoContent lists the JSON and MessagePack encodings of a DTO schema
+func dtoContent(schema *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{
+		fiber.MIMEApplicationJSON: {Schema: schema},
+		MIMEMsgPack:               {Schema: schema},
+	}
+}
+
+func pathParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string", Format: "uuid"},
+	}
+}
+
+func queryParam(name, typ, description string) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      &openapi.Schema{Type: typ},
+	}
+}
+
+func headerParam(name, description string, required bool) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "header",
+		Description: description,
+		Required:    required,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+// idempotencyKeyParam is the header that makes user creation safe to retry
+var idempotencyKeyParam = openapi.Parameter{
+	Name:        HeaderIdempotencyKey,
+	In:          "header",
+	Description: "replays the original response when a request is retried with the same key",
+	Schema:      &openapi.Schema{Type: "string", MaxLength: intPtr(maxIdempotencyKeyLength)},
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// atomicParam is the query parameter of the all-or-nothing batch endpoints
+var atomicParam = queryParam("atomic", "boolean", "store all users or none")
+
+// oauthProvider is the path parameter naming a social login provider
+var oauthProvider = pathParam("provider", "identity provider; only google is supported")
+
+// fieldsParam is the query parameter selecting which user fields to return
+var fieldsParam = queryParam("fields", "string", "comma-separated user fields to return, such as id,name,email")
+
+// expandParam is the query parameter embedding related resources in a user
+var expandParam = queryParam("expand", "string", "comma-separated related resources to embed in each user: preferences, last_login")
+
+// buildSpec describes every route registered by SetupRoutes. Request and
+// response schemas are derived from the DTO structs the handlers decode and
+// encode, so field changes show up without editing this file.
+func buildSpec() *openapi.Document {
+	doc := openapi.New("User Management API", "1.0.0")
+	doc.Components.SecuritySchemes["bearerAuth"] = openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}
+	doc.Components.SecuritySchemes["apiKeyAuth"] = openapi.SecurityScheme{
+		Type: "apiKey",
+		In:   "header",
+		Name: HeaderAPIKey,
+	}
+	doc.Security = bearerAuth
+
+	b := &specBuilder{doc: doc}
+	user := &domain.User{}
+	userID := pathParam("id", "user id")
+
+	// Health checks
+	health := op{tag: "health", public: true, responses: map[int]any{
+		http.StatusOK:                 healthResponse{},
+		http.StatusServiceUnavailable: healthResponse{},
+	}}
+	health.summary = "Readiness check"
+	b.add(http.MethodGet, "/api/v1/version", op{
+		summary: "Version, commit, build time, and Go version of the running build", tag: "health", public: true,
+		responses: map[int]any{http.StatusOK: buildinfo.Info{}},
+	})
+	b.add(http.MethodGet, "/api/v1/health", health)
+	b.add(http.MethodGet, "/api/v1/health/ready", health)
+	b.add(http.MethodGet, "/api/v1/health/live", op{
+		summary: "Liveness check", tag: "health", public: true,
+		responses: map[int]any{http.StatusOK: healthResponse{}},
+	})
+	b.add(http.MethodGet, "/api/v1/health/details", op{
+		summary: "Readiness check with connection pool statistics", tag: "health", permission: domain.PermissionHealthRead,
+		responses: map[int]any{
+			http.StatusOK:                 healthDetailsResponse{},
+			http.StatusServiceUnavailable: healthDetailsResponse{},
+		},
+	})
+
+	// Auth routes
+	b.add(http.MethodPost, "/api/v1/auth/register", op{
+		summary: "Sign up as a regular user", tag: "auth", public: true,
+		body:      registerRequest{},
+		responses: map[int]any{http.StatusCreated: registerResponse{}},
+		errors:    []int{http.StatusConflict},
+	})
+	b.add(http.MethodPost, "/api/v1/auth/login", op{
+		summary: "Log in with email and password", tag: "auth", public: true,
+		body:      loginRequest{},
+		responses: map[int]any{http.StatusOK: tokenResponse{}, http.StatusAccepted: mfaChallengeResponse{}},
+		errors:    []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusLocked, http.StatusTooManyRequests},
+	})
+	b.add(http.MethodPost, "/api/v1/auth/2fa/verify", op{
+		summary: "Complete a two-factor login with a TOTP or recovery code", tag: "auth", public: true,
+		body:      verifyTwoFactorRequest{},
+		responses: map[int]any{http.StatusOK: tokenResponse{}},
+		errors:    []int{http.StatusUnauthorized, http.StatusLocked, http.StatusTooManyRequests},
+	})
+	b.add(http.MethodGet, "/api/v1/auth/oauth/{provider}", op{
+		summary: "Redirect to the provider's consent screen", tag: "auth", public: true,
+		params:    []openapi.Parameter{oauthProvider},
+		responses: map[int]any{http.StatusFound: nil},
+		errors:    []int{http.StatusNotFound},
+	})
+	b.add(http.MethodGet, "/api/v1/auth/oauth/{provider}/callback", op{
+		summary: "Complete a sign-in with an identity provider", tag: "auth", public: true,
+		params: []openapi.Parameter{
+			oauthProvider,
+			queryParam("code", "string", "authorization code from the provider"),
+			queryParam("state", "string", "state issued by the redirect"),
+			queryParam("error", "string", "set by the provider when access was not granted"),
+		},
+		responses: map[int]any{http.StatusOK: tokenResponse{}, http.StatusAccepted: mfaChallengeResponse{}},
+		errors:    []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+	})
+	b.add(http.MethodGet, "/api/v1/auth/verify", op{
+		summary: "Confirm an email address", tag: "auth", public: true,
+		params:    []openapi.Parameter{queryParam("token", "string", "token from the verification link")},
+		responses: map[int]any{http.StatusOK: messageResponse{}},
+		errors:    []int{http.StatusBadRequest},
+	})
+	b.add(http.MethodGet, "/api/v1/auth/confirm-email", op{
+		summary: "Confirm an email change", tag: "auth", public: true,
+		params:    []openapi.Parameter{queryParam("token", "string", "token from the email change link")},
+		responses: map[int]any{http.StatusOK: messageResponse{}},
+		errors:    []int{http.StatusBadRequest, http.StatusConflict},
+	})
+	b.add(http.MethodPost, "/api/v1/auth/resend-verification", op{
+		summary: "Send a new verification link", tag: "auth", public: true,
+		body:      resendVerificationRequest{},
+		responses: map[int]any{http.StatusOK: messageResponse{}},
+	})
+	b.add(http.MethodPost, "/api/v1/auth/forgot-password", op{
+		summary: "Email a password reset token", tag: "auth", public: true,
+		body:      forgotPasswordRequest{},
+		responses: map[int]any{http.StatusOK: messageResponse{}},
+	})
+	b.add(http.MethodPost, "/api/v1/auth/reset-password", op{
+		summary: "Set a new password using a reset token", tag: "auth", public: true,
+		body:      resetPasswordRequest{},
+		responses: map[int]any{http.StatusOK: messageResponse{}},
+	})
+	b.add(http.MethodPost, "/api/v1/auth/refresh", op{
+		summary: "Rotate a refresh token", tag: "auth", public: true,
+		body:      refreshRequest{},
+		responses: map[int]any{http.StatusOK: tokenResponse{}},
+		errors:    []int{http.StatusUnauthorized},
+	})
+	b.add(http.MethodPost, "/api/v1/auth/logout", op{
+		summary: "Revoke a refresh token", tag: "auth", public: true,
+		body:      refreshRequest{},
+		responses: map[int]any{http.StatusNoContent: nil},
+	})
+
+	// Current user routes
+	b.add(http.MethodGet, "/api/v1/me", op{
+		summary: "Get the authenticated user", tag: "me",
+		params:    []openapi.Parameter{fieldsParam, expandParam},
+		responses: map[int]any{http.StatusOK: user},
+		errors:    []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+	b.add(http.MethodPatch, "/api/v1/me", op{
+		summary: "Update the authenticated user's name or email", tag: "me",
+		body:      patchUserRequest{},
+		responses: map[int]any{http.StatusOK: user},
+		errors:    []int{http.StatusConflict},
+	})
+	b.add(http.MethodDelete, "/api/v1/me", op{
+		summary: "Delete the account, anonymizing its personal data", tag: "me",
+		body:      deleteAccountRequest{},
+		responses: map[int]any{http.StatusNoContent: nil},
+	})
+	b.add(http.MethodPost, "/api/v1/me/avatar", op{
+		summary: "Upload a PNG, JPEG, or WebP avatar", tag: "me",
+		body:      avatarUploadForm{},
+		bodyType:  fiber.MIMEMultipartForm,
+		responses: map[int]any{http.StatusOK: user},
+		errors:    []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge},
+	})
+	b.add(http.MethodDelete, "/api/v1/me/avatar", op{
+		summary: "Remove the avatar", tag: "me",
+		responses: map[int]any{http.StatusOK: user},
+	})
+	b.add(http.MethodGet, "/api/v1/me/preferences", op{
+		summary: "Get the preferences, created with defaults on first read", tag: "me",
+		responses: map[int]any{http.StatusOK: domain.Preferences{}},
+	})
+	b.add(http.MethodPut, "/api/v1/me/preferences", op{
+		summary: "Replace the preferences", tag: "me",
+		body:      preferencesRequest{},
+		responses: map[int]any{http.StatusOK: domain.Preferences{}},
+	})
+	b.add(http.MethodPost, "/api/v1/me/change-password", op{
+		summary: "Change the password", tag: "me",
+		body:      changePasswordRequest{},
+		responses: map[int]any{http.StatusNoContent: nil},
+	})
+	b.add(http.MethodGet, "/api/v1/me/sessions", op{
+		summary: "List the active sessions", tag: "me",
+		responses: map[int]any{http.StatusOK: sessionListResponse{}},
+	})
+	b.add(http.MethodDelete, "/api/v1/me/sessions", op{
+		summary: "Sign out of every other session", tag: "me",
+		responses: map[int]any{http.StatusNoContent: nil},
+	})
+	b.add(http.MethodDelete, "/api/v1/me/sessions/{id}", op{
+		summary: "Sign out of a session", tag: "me",
+		params:    []openapi.Parameter{pathParam("id", "session id")},
+		responses: map[int]any{http.StatusNoContent: nil},
+		errors:    []int{http.StatusNotFound},
+	})
+	b.add(http.MethodPost, "/api/v1/me/2fa/enable", op{
+		summary: "Generate a TOTP secret for two-factor authentication", tag: "me",
+		responses: map[int]any{http.StatusOK: twoFactorSetupResponse{}},
+		errors:    []int{http.StatusConflict},
+	})
+	b.add(http.MethodPost, "/api/v1/me/2fa/confirm", op{
+		summary: "Turn on two-factor authentication and get recovery codes", tag: "me",
+		body:      confirmTwoFactorRequest{},
+		responses: map[int]any{http.StatusOK: recoveryCodesResponse{}},
+		errors:    []int{http.StatusConflict},
+	})
+
+	// User routes
+	b.add(http.MethodPost, "/api/v1/users", op{
+		summary: "Create a user", tag: "users", permission: domain.PermissionUsersWrite,
+		scope:     domain.ScopeUsersWrite,
+		params:    []openapi.Parameter{idempotencyKeyParam},
+		body:      createUserRequest{},
+		responses: map[int]any{http.StatusCreated: user},
+		errors:    []int{http.StatusConflict},
+	})
+	b.add(http.MethodPost, "/api/v1/users/bulk", op{
+		summary: "Create users in bulk", tag: "users", permission: domain.PermissionUsersWrite,
+		scope:  domain.ScopeUsersWrite,
+		params: []openapi.Parameter{atomicParam, idempotencyKeyParam},
+		body:   []createUserRequest{},
+		responses: map[int]any{
+			http.StatusCreated:     bulkCreateResponse{},
+			http.StatusMultiStatus: bulkCreateResponse{},
+		},
+		errors: []int{http.StatusConflict},
+	})
+	b.add(http.MethodGet, "/api/v1/users", op{
+		summary: "List users", tag: "users", permission: domain.PermissionUsersRead,
+		scope: domain.ScopeUsersRead,
+		params: []openapi.Parameter{
+			queryParam("page", "integer", "page number, starting at 1"),
+			queryParam("page_size", "integer", "users per page; larger values are capped at 100"),
+			queryParam("sort", "string", "sort field, prefixed with - for descending order; not allowed with cursor"),
+			queryParam("cursor", "string", "opaque cursor for keyset pagination, ordered by created_at; pass an empty value for the first page. Combining it with sort fails with 400 INVALID_QUERY"),
+			queryParam("q", "string", "name or email substring"),
+			queryParam("role", "string", "only users with this role"),
+			queryParam("email_verified", "boolean", "only verified or unverified users"),
+			queryParam("include_deleted", "boolean", "include soft-deleted users"),
+			fieldsParam,
+			expandParam,
+		},
+		responses: map[int]any{http.StatusOK: pagination.PagedResponse[*domain.User]{}},
+		errors:    []int{http.StatusBadRequest},
+	})
+	b.add(http.MethodDelete, "/api/v1/users", op{
+		summary: "Soft-delete users in bulk", tag: "users", permission: domain.PermissionUsersDelete,
+		scope:     domain.ScopeUsersWrite,
+		body:      bulkDeleteRequest{},
+		responses: map[int]any{http.StatusOK: bulkDeleteResponse{}},
+	})
+	b.add(http.MethodGet, "/api/v1/users/export.csv", op{
+		summary: "Download all users as CSV", tag: "users", permission: domain.PermissionUsersRead,
+		scope:     domain.ScopeUsersRead,
+		responses: map[int]any{http.StatusOK: "text/csv"},
+	})
+	b.add(http.MethodGet, "/api/v1/users/export.ndjson", op{
+		summary: "Stream all users as newline-delimited JSON", tag: "users", permission: domain.PermissionUsersRead,
+		scope:     domain.ScopeUsersRead,
+		responses: map[int]any{http.StatusOK: MIMENDJSON},
+	})
+	b.add(http.MethodPost, "/api/v1/users/import", op{
+		summary: "Create users from a CSV upload", tag: "users", permission: domain.PermissionUsersWrite,
+		scope:    domain.ScopeUsersWrite,
+		params:   []openapi.Parameter{atomicParam},
+		body:     csvUploadForm{},
+		bodyType: fiber.MIMEMultipartForm,
+		responses: map[int]any{
+			http.StatusCreated:     csvImportResponse{},
+			http.StatusMultiStatus: csvImportResponse{},
+		},
+		errors: []int{http.StatusBadRequest},
+	})
+	b.add(http.MethodGet, "/api/v1/users/{id}", op{
+		summary: "Get a user", tag: "users",
+		scope: domain.ScopeUsersRead,
+		params: []openapi.Parameter{
+			userID,
+			fieldsParam,
+			expandParam,
+			headerParam(fiber.HeaderIfNoneMatch, "answer 304 when the user still has this ETag and nothing is expanded", false),
+		},
+		responses: map[int]any{http.StatusOK: user, http.StatusNotModified: nil},
+		errors:    []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+	})
+	b.add(http.MethodPut, "/api/v1/users/{id}", op{
+		summary: "Update a user", tag: "users",
+		scope: domain.ScopeUsersWrite,
+		params: []openapi.Parameter{
+			userID,
+			headerParam(fiber.HeaderIfMatch, "ETag of the user as last read; the update fails with 412 if it changed since", true),
+		},
+		body:      updateUserRequest{},
+		responses: map[int]any{http.StatusOK: user},
+		errors: []int{
+			http.StatusForbidden, http.StatusNotFound, http.StatusConflict,
+			http.StatusPreconditionFailed, http.StatusPreconditionRequired,
+		},
+	})
+	b.add(http.MethodPatch, "/api/v1/users/{id}", op{
+		summary: "Partially update a user; a null phone removes it", tag: "users",
+		scope: domain.ScopeUsersWrite,
+		params: []openapi.Parameter{
+			userID,
+			headerParam(fiber.HeaderIfMatch, "ETag of the user as last read; when sent, the update fails with 412 if it changed since", false),
+		},
+		body:      patchUserRequest{},
+		responses: map[int]any{http.StatusOK: user},
+		errors: []int{
+			http.StatusForbidden, http.StatusNotFound, http.StatusConflict,
+			http.StatusPreconditionFailed,
+		},
+	})
+	b.add(http.MethodDelete, "/api/v1/users/{id}", op{
+		summary: "Soft-delete a user", tag: "users", permission: domain.PermissionUsersDelete,
+		scope:     domain.ScopeUsersWrite,
+		params:    []openapi.Parameter{userID},
+		responses: map[int]any{http.StatusNoContent: nil},
+		errors:    []int{http.StatusNotFound},
+	})
+	b.add(http.MethodPost, "/api/v1/users/{id}/restore", op{
+		summary: "Restore a soft-deleted user", tag: "users", permission: domain.PermissionUsersWrite,
+		scope:     domain.ScopeUsersWrite,
+		params:    []openapi.Parameter{userID},
+		responses: map[int]any{http.StatusOK: user},
+		errors:    []int{http.StatusNotFound, http.StatusConflict},
+	})
+	b.add(http.MethodPatch, "/api/v1/users/{id}/status", op{
+		summary: "Suspend or reactivate a user", tag: "users", permission: domain.PermissionUsersWrite,
+		scope:     domain.ScopeUsersWrite,
+		params:    []openapi.Parameter{userID},
+		body:      updateStatusRequest{},
+		responses: map[int]any{http.StatusOK: user},
+		errors:    []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+	b.add(http.MethodGet, "/api/v1/users/{id}/roles", op{
+		summary: "List the roles of a user", tag: "users", permission: domain.PermissionUsersRead,
+		params:    []openapi.Parameter{userID},
+		responses: map[int]any{http.StatusOK: rolesResponse{}},
+		errors:    []int{http.StatusNotFound},
+	})
+	b.add(http.MethodPut, "/api/v1/users/{id}/roles", op{
+		summary: "Replace the roles of a user; tokens carry the new roles once refreshed", tag: "users", permission: domain.PermissionRolesManage,
+		params:    []openapi.Parameter{userID},
+		body:      setRolesRequest{},
+		responses: map[int]any{http.StatusOK: rolesResponse{}},
+		errors:    []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+
+	// Admin routes
+	b.add(http.MethodPost, "/api/v1/admin/impersonate/{userId}", op{
+		summary: "Get a short-lived access token acting as a user", tag: "admin", permission: domain.PermissionUsersImpersonate,
+		params:    []openapi.Parameter{pathParam("userId", "user id")},
+		responses: map[int]any{http.StatusOK: impersonationResponse{}},
+		errors:    []int{http.StatusNotFound, http.StatusUnprocessableEntity},
+	})
+
+	// Audit log routes
+	b.add(http.MethodGet, "/api/v1/audit-logs", op{
+		summary: "List audit log entries, newest first", tag: "audit", permission: domain.PermissionAuditRead,
+		scope: domain.ScopeAuditRead,
+		params: []openapi.Parameter{
+			queryParam("page", "integer", "page number, starting at 1"),
+			queryParam("page_size", "integer", "entries per page; larger values are capped at 100"),
+			queryParam("actor_id", "string", "only entries by this user"),
+			queryParam("action", "string", "only entries with this action, such as user.create"),
+			queryParam("from", "string", "only entries at or after this RFC 3339 time"),
+			queryParam("to", "string", "only entries before this RFC 3339 time"),
+		},
+		responses: map[int]any{http.StatusOK: pagination.PagedResponse[*domain.AuditLog]{}},
+		errors:    []int{http.StatusBadRequest},
+	})
+
+	// API key routes
+	b.add(http.MethodPost, "/api/v1/api-keys", op{
+		summary: "Create an API key; the key is only returned in this response", tag: "api-keys", permission: domain.PermissionAPIKeysManage,
+		body:      createAPIKeyRequest{},
+		responses: map[int]any{http.StatusCreated: createAPIKeyResponse{}},
+	})
+	b.add(http.MethodGet, "/api/v1/api-keys", op{
+		summary: "List API keys", tag: "api-keys", permission: domain.PermissionAPIKeysManage,
+		responses: map[int]any{http.StatusOK: apiKeyListResponse{}},
+	})
+	b.add(http.MethodDelete, "/api/v1/api-keys/{id}", op{
+		summary: "Revoke an API key", tag: "api-keys", permission: domain.PermissionAPIKeysManage,
+		params:    []openapi.Parameter{pathParam("id", "api key id")},
+		responses: map[int]any{http.StatusNoContent: nil},
+		errors:    []int{http.StatusNotFound},
+	})
+
+	// Webhook routes
+	b.add(http.MethodPost, "/api/v1/webhooks", op{
+		summary: "Register a webhook; the signing secret is only returned in this response", tag: "webhooks", permission: domain.PermissionWebhooksManage,
+		body:      createWebhookRequest{},
+		responses: map[int]any{http.StatusCreated: createWebhookResponse{}},
+	})
+	b.add(http.MethodGet, "/api/v1/webhooks", op{
+		summary: "List webhooks", tag: "webhooks", permission: domain.PermissionWebhooksManage,
+		responses: map[int]any{http.StatusOK: webhookListResponse{}},
+	})
+	b.add(http.MethodGet, "/api/v1/webhooks/{id}/deliveries", op{
+		summary: "List the most recent deliveries of a webhook", tag: "webhooks", permission: domain.PermissionWebhooksManage,
+		params:    []openapi.Parameter{pathParam("id", "webhook id")},
+		responses: map[int]any{http.StatusOK: webhookDeliveryListResponse{}},
+		errors:    []int{http.StatusNotFound},
+	})
+
+	return doc
+}
+
+// avatarUploadForm describes the multipart body of an avatar upload
+type avatarUploadForm struct {
+	File []byte `json:"file" validate:"required"`
+}
+
+// csvUploadForm describes the multipart body of the CSV import
+type csvUploadForm struct {
+	File []byte `json:"file" validate:"required"`
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Server.DocsEnabled = true })
+
+	var spec struct {
+		OpenAPI    string                    `json:"openapi"`
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			SecuritySchemes map[string]any `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	resp := env.do(http.MethodGet, "/api/v1/openapi.json", "", nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&spec)
+
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want a 3.x document", spec.OpenAPI)
+	}
+	for path, methods := range map[string][]string{
+		"/api/v1/users":      {"get", "post"},
+		"/api/v1/users/{id}": {"get", "put", "patch", "delete"},
+	} {
+		for _, method := range methods {
+			if _, ok := spec.Paths[path][method]; !ok {
+				t.Errorf("spec has no %s %s operation", method, path)
+			}
+		}
+	}
+	if len(spec.Components.SecuritySchemes) == 0 {
+		t.Error("spec defines no security schemes")
+	}
+
+	docs := env.do(http.MethodGet, "/docs", "", nil)
+	docs.expect(http.StatusOK)
+	if !strings.Contains(string(docs.body), "/api/v1/openapi.json") {
+		t.Error("Swagger UI does not load the spec")
+	}
+}
+
+func TestOpenAPISpecDisabled(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Server.DocsEnabled = false })
+
+	env.do(http.MethodGet, "/api/v1/openapi.json", "", nil).expect(http.StatusNotFound)
+	env.do(http.MethodGet, "/docs", "", nil).expect(http.StatusNotFound)
+}
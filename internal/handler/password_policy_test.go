@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+)
+
+// strictPasswords requires every character class on top of the defaults
+func strictPasswords(cfg *config.Config) {
+	cfg.Password.MinLength = 12
+	cfg.Password.RequireUpper = true
+	cfg.Password.RequireLower = true
+	cfg.Password.RequireDigit = true
+	cfg.Password.RequireSymbol = true
+	cfg.Password.RejectCommon = true
+}
+
+// strongPassword satisfies strictPasswords
+const strongPassword = "Correct-Horse-42"
+
+// rulesOf returns the rules listed in the details of a WEAK_PASSWORD error
+func rulesOf(t *testing.T, details any) []string {
+	t.Helper()
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		t.Fatalf("encode details: %v", err)
+	}
+	var violations []auth.PasswordViolation
+	if err := json.Unmarshal(data, &violations); err != nil {
+		t.Fatalf("decode violations %s: %v", data, err)
+	}
+	rules := make([]string, len(violations))
+	for i, v := range violations {
+		rules[i] = v.Rule
+	}
+
+	return rules
+}
+
+// expectWeak checks the response rejects the password for exactly rules
+func expectWeak(t *testing.T, resp *testResponse, rules ...string) {
+	t.Helper()
+
+	resp.expect(http.StatusUnprocessableEntity, "WEAK_PASSWORD")
+	var body errorBody
+	resp.decode(&body)
+	if got := rulesOf(t, body.Error.Details); fmt.Sprint(got) != fmt.Sprint(rules) {
+		t.Errorf("violations = %v, want %v", got, rules)
+	}
+}
+
+func TestPasswordPolicyOnRegister(t *testing.T) {
+	env := newTestEnv(t, strictPasswords)
+
+	register := func(email, password string) *testResponse {
+		body := registerBody(email)
+		body["password"] = password
+		return env.do(http.MethodPost, "/api/v1/auth/register", "", body)
+	}
+
+	expectWeak(t, register("ada@example.com", "short"), auth.RuleMinLength, auth.RuleUppercase, auth.RuleDigit, auth.RuleSymbol)
+	expectWeak(t, register("ada@example.com", "all-lowercase-42"), auth.RuleUppercase)
+	expectWeak(t, register("ada@example.com", "Password1"), auth.RuleMinLength, auth.RuleSymbol, auth.RuleCommon)
+	register("ada@example.com", strongPassword).expect(http.StatusCreated)
+}
+
+func TestPasswordPolicyOnResetAndChange(t *testing.T) {
+	env := newTestEnv(t, strictPasswords)
+	user := env.createUser("ada@example.com")
+
+	env.do(http.MethodPost, "/api/v1/auth/forgot-password", "", map[string]string{"email": "ada@example.com"}).expect(http.StatusOK)
+	token := env.mailedToken("ada@example.com", mailer.TemplatePasswordReset)
+	expectWeak(t, env.do(http.MethodPost, "/api/v1/auth/reset-password", "", resetBody(token, "NoDigitsHere!")), auth.RuleDigit)
+	// A rejected password leaves the token usable
+	env.do(http.MethodPost, "/api/v1/auth/reset-password", "", resetBody(token, strongPassword)).expect(http.StatusOK)
+
+	expectWeak(t, env.do(http.MethodPost, "/api/v1/me/change-password", env.tokenFor(user), changePasswordBody(strongPassword, "NO-LOWER-CASE-42")), auth.RuleLowercase)
+}
+
+func TestPasswordPolicyInBatches(t *testing.T) {
+	env := newTestEnv(t, strictPasswords)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	strong := bulkUser("ada@example.com")
+	strong["password"] = strongPassword
+	weak := bulkUser("grace@example.com")
+	weak["password"] = "grace"
+
+	var body bulkCreateResponse
+	resp := env.do(http.MethodPost, "/api/v1/users/bulk", admin, []any{strong, weak})
+	resp.expect(http.StatusMultiStatus)
+	resp.decode(&body)
+	if got := statusesOf(body.Results); fmt.Sprint(got) != "[created failed:WEAK_PASSWORD]" {
+		t.Fatalf("results = %v, want the weak password to fail alone", got)
+	}
+	if got := rulesOf(t, body.Results[1].Error.Details); fmt.Sprint(got) != fmt.Sprint([]string{auth.RuleMinLength, auth.RuleUppercase, auth.RuleDigit, auth.RuleSymbol}) {
+		t.Errorf("item violations = %v, want every failed rule", got)
+	}
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// forgotPasswordRequest is the request body for requesting a password reset
+type forgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// resetPasswordRequest is the request body for setting a new password with a reset token
+type resetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,max=72"`
+}
+
+// ForgotPassword handler emails a password reset token; it responds the same
+// way whether or not the email belongs to an account
+func (h *AuthHandler) ForgotPassword(c fiber.Ctx) error {
+	var input forgotPasswordRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	if err := h.users.RequestPasswordReset(c.Context(), input.Email); err != nil {
+		return err
+	}
+
+	return respond(c, fiber.Map{"message": "if the account exists, a password reset email has been sent"})
+}
+
+// ResetPassword handler consumes a reset token, sets the new password, and
+// signs the user out of every existing session
+func (h *AuthHandler) ResetPassword(c fiber.Ctx) error {
+	var input resetPasswordRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	if err := h.users.ResetPassword(c.Context(), input.Token, input.NewPassword); err != nil {
+		return authError(err)
+	}
+
+	return respond(c, fiber.Map{"message": "password has been reset"})
+}
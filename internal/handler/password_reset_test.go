@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+)
+
+// resetBody is the body of a reset-password request
+func resetBody(token, password string) map[string]string {
+	return map[string]string{"token": token, "new_password": password}
+}
+
+// refreshBody is the body of a refresh or logout request
+func refreshBody(refreshToken string) map[string]string {
+	return map[string]string{"refresh_token": refreshToken}
+}
+
+func TestPasswordReset(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	session := env.sessionFor(user)
+
+	env.do(http.MethodPost, "/api/v1/auth/forgot-password", "", map[string]string{"email": "ada@example.com"}).expect(http.StatusOK)
+	token := env.mailedToken("ada@example.com", mailer.TemplatePasswordReset)
+
+	const newPassword = "a-brand-new-passphrase"
+	env.do(http.MethodPost, "/api/v1/auth/reset-password", "", resetBody(token, newPassword)).expect(http.StatusOK)
+
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", newPassword)).expect(http.StatusOK)
+
+	// The reset signed out the existing session, and the token is single-use
+	env.do(http.MethodPost, "/api/v1/auth/refresh", "", refreshBody(session.RefreshToken)).expect(http.StatusUnauthorized, "INVALID_REFRESH_TOKEN")
+	env.do(http.MethodPost, "/api/v1/auth/reset-password", "", resetBody(token, "yet-another-passphrase")).expect(http.StatusBadRequest, "INVALID_RESET_TOKEN")
+}
+
+func TestForgotPasswordHidesUnknownEmails(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+
+	known := env.do(http.MethodPost, "/api/v1/auth/forgot-password", "", map[string]string{"email": "ada@example.com"})
+	unknown := env.do(http.MethodPost, "/api/v1/auth/forgot-password", "", map[string]string{"email": "nobody@example.com"})
+	if known.StatusCode != unknown.StatusCode || string(known.body) != string(unknown.body) {
+		t.Errorf("known email got %d %s, unknown got %d %s, want the same response", known.StatusCode, known.body, unknown.StatusCode, unknown.body)
+	}
+	if got := env.mail.MessagesTo("nobody@example.com"); len(got) != 0 {
+		t.Errorf("sent %d emails to an unknown address, want none", len(got))
+	}
+}
+
+func TestPasswordResetRejectsExpiredToken(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Auth.PasswordResetTTL = time.Millisecond })
+	env.createUser("ada@example.com")
+
+	env.do(http.MethodPost, "/api/v1/auth/forgot-password", "", map[string]string{"email": "ada@example.com"}).expect(http.StatusOK)
+	token := env.mailedToken("ada@example.com", mailer.TemplatePasswordReset)
+	time.Sleep(5 * time.Millisecond)
+
+	env.do(http.MethodPost, "/api/v1/auth/reset-password", "", resetBody(token, "a-brand-new-passphrase")).expect(http.StatusBadRequest, "INVALID_RESET_TOKEN")
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+}
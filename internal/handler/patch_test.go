@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// withPhone stores a phone number for the user
+func (e *testEnv) withPhone(user *domain.User, phone string) {
+	e.t.Helper()
+
+	user.Phone = phone
+	if err := e.users.Update(e.t.Context(), user); err != nil {
+		e.t.Fatalf("set phone of %s: %v", user.Email, err)
+	}
+}
+
+func TestPatchLeavesOmittedFields(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	env.withPhone(user, "+15551234567")
+
+	env.do(http.MethodPatch, "/api/v1/me", env.tokenFor(user), map[string]any{"name": "Ada Lovelace"}).expect(http.StatusOK)
+
+	stored, err := env.users.GetByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Name != "Ada Lovelace" {
+		t.Errorf("name = %q, want %q", stored.Name, "Ada Lovelace")
+	}
+	if stored.Email != "ada@example.com" || stored.Phone != "+15551234567" {
+		t.Errorf("email and phone = %q, %q, want them unchanged", stored.Email, stored.Phone)
+	}
+}
+
+func TestPatchAbsentVersusNull(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	env.withPhone(user, "+15551234567")
+	token := env.tokenFor(user)
+
+	phoneOf := func() string {
+		t.Helper()
+		stored, err := env.users.GetByID(t.Context(), user.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		return stored.Phone
+	}
+
+	env.do(http.MethodPatch, "/api/v1/me", token, `{"name":"Ada"}`).expect(http.StatusOK)
+	if got := phoneOf(); got != "+15551234567" {
+		t.Errorf("phone after a patch without it = %q, want it kept", got)
+	}
+
+	env.do(http.MethodPatch, "/api/v1/me", token, `{"phone":null}`).expect(http.StatusOK)
+	if got := phoneOf(); got != "" {
+		t.Errorf("phone after an explicit null = %q, want it cleared", got)
+	}
+
+	// Fields that cannot be cleared reject a null instead of ignoring it
+	resp := env.do(http.MethodPatch, "/api/v1/me", token, `{"name":null,"email":null}`)
+	resp.expect(http.StatusUnprocessableEntity, apperror.CodeValidation)
+	var body struct {
+		Error struct {
+			Details []struct {
+				Field string `json:"field"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	resp.decode(&body)
+	if len(body.Error.Details) != 2 || body.Error.Details[0].Field != "email" || body.Error.Details[1].Field != "name" {
+		t.Errorf("details = %+v, want email and name", body.Error.Details)
+	}
+}
+
+func TestPatchValidatesPresentFieldsOnly(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"empty body", `{}`, http.StatusBadRequest, "EMPTY_PATCH"},
+		{"only a version", `{"version":1}`, http.StatusBadRequest, "EMPTY_PATCH"},
+		{"invalid email", `{"email":"not-an-email"}`, http.StatusUnprocessableEntity, apperror.CodeValidation},
+		{"empty name", `{"name":""}`, http.StatusUnprocessableEntity, apperror.CodeValidation},
+		{"valid name alone", `{"name":"Ada"}`, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := env.do(http.MethodPatch, "/api/v1/me", token, tt.body)
+			if tt.code == "" {
+				resp.expect(tt.status)
+				return
+			}
+			resp.expect(tt.status, tt.code)
+		})
+	}
+}
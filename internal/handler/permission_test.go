@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+func TestReadPermissionListsButCannotDelete(t *testing.T) {
+	env := newTestEnv(t)
+	env.roles.grant(domain.RoleUser, domain.PermissionUsersRead)
+	reader := env.tokenFor(env.createUser("reader@example.com"))
+	ada := env.createUser("ada@example.com")
+
+	var page userPage
+	resp := env.do(http.MethodGet, "/api/v1/users", reader, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&page)
+	if page.TotalCount != 2 {
+		t.Errorf("listed %d users, want 2", page.TotalCount)
+	}
+	env.do(http.MethodGet, "/api/v1/users/export.csv", reader, nil).expect(http.StatusOK)
+	env.do(http.MethodGet, "/api/v1/users/"+ada.ID+"/roles", reader, nil).expect(http.StatusOK)
+
+	env.do(http.MethodDelete, "/api/v1/users/"+ada.ID, reader, nil).expect(http.StatusForbidden, apperror.CodeForbidden)
+	env.do(http.MethodPost, "/api/v1/users", reader, bulkUser("grace@example.com")).expect(http.StatusForbidden, apperror.CodeForbidden)
+	if _, err := env.users.GetByID(context.Background(), ada.ID); err != nil {
+		t.Errorf("user after a refused delete: %v", err)
+	}
+}
+
+func TestPermissionsFollowRoles(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	user := env.tokenFor(env.createUser("user@example.com"))
+	ada := env.createUser("ada@example.com")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"user cannot list", http.MethodGet, "/api/v1/users", user, http.StatusForbidden},
+		{"user cannot read the audit log", http.MethodGet, "/api/v1/audit-logs", user, http.StatusForbidden},
+		{"user cannot manage api keys", http.MethodGet, "/api/v1/api-keys", user, http.StatusForbidden},
+		{"admin can list", http.MethodGet, "/api/v1/users", admin, http.StatusOK},
+		{"admin can read the audit log", http.MethodGet, "/api/v1/audit-logs", admin, http.StatusOK},
+		{"admin can delete", http.MethodDelete, "/api/v1/users/" + ada.ID, admin, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.do(tt.method, tt.path, tt.token, nil).expect(tt.status)
+		})
+	}
+}
+
+func TestPermissionMappingIsCached(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	for range 5 {
+		env.do(http.MethodGet, "/api/v1/users", admin, nil).expect(http.StatusOK)
+	}
+	if got := env.roles.loaded(); got != 1 {
+		t.Errorf("mapping loaded %d times for 5 requests, want 1", got)
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+)
+
+// pprofPaths are the profiling endpoints the admin app mounts; the CPU
+// profile is left out as it runs for at least a second
+var pprofPaths = []string{
+	"/debug/pprof/",
+	"/debug/pprof/cmdline",
+	"/debug/pprof/heap",
+	"/debug/pprof/goroutine?debug=1",
+	"/debug/pprof/trace?seconds=0.05",
+}
+
+func TestPprofDisabledByDefault(t *testing.T) {
+	env := newTestEnv(t, withAdminListener)
+	admin := &testEnv{t: t, app: env.admin}
+
+	for _, path := range pprofPaths {
+		admin.do(http.MethodGet, path, "", nil).expect(http.StatusNotFound)
+	}
+}
+
+func TestPprofEnabled(t *testing.T) {
+	env := newTestEnv(t, withAdminListener, func(cfg *config.Config) {
+		cfg.Server.PprofEnabled = true
+	})
+	admin := &testEnv{t: t, app: env.admin}
+
+	for _, path := range pprofPaths {
+		admin.do(http.MethodGet, path, "", nil).expect(http.StatusOK)
+		// Profiles stay off the public listener even when enabled
+		env.do(http.MethodGet, path, "", nil).expect(http.StatusNotFound)
+	}
+}
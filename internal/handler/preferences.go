@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// preferencesRequest is the request body replacing the current user's
+// preferences; omitted notification toggles are turned off
+type preferencesRequest struct {
+	Locale        string                         `json:"locale" validate:"required,locale"`
+	Timezone      string                         `json:"timezone" validate:"required,timezone"`
+	Notifications domain.NotificationPreferences `json:"notifications"`
+}
+
+// PreferencesHandler handles the preferences of the current user
+type PreferencesHandler struct {
+	preferences *service.PreferencesService
+}
+
+// NewPreferencesHandler creates a new preferences handler
+func NewPreferencesHandler(preferences *service.PreferencesService) *PreferencesHandler {
+	return &PreferencesHandler{preferences: preferences}
+}
+
+// GetPreferences handler returns the current user's preferences, which start
+// at the defaults
+func (h *PreferencesHandler) GetPreferences(c fiber.Ctx) error {
+	userID, _ := CurrentUserID(c)
+
+	preferences, err := h.preferences.Get(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return respond(c, preferences)
+}
+
+// UpdatePreferences handler replaces the current user's preferences
+func (h *PreferencesHandler) UpdatePreferences(c fiber.Ctx) error {
+	var input preferencesRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	userID, _ := CurrentUserID(c)
+	preferences, err := h.preferences.Update(c.Context(), userID, service.PreferencesInput{
+		Locale:        input.Locale,
+		Timezone:      input.Timezone,
+		Notifications: input.Notifications,
+	})
+	if err != nil {
+		return preferencesError(err)
+	}
+
+	return respond(c, preferences)
+}
+
+// preferencesError translates preferences service errors into API errors
+func preferencesError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidLocale):
+		return apperror.UnprocessableEntity("INVALID_LOCALE", "locale must be a BCP 47 language tag")
+	case errors.Is(err, service.ErrInvalidTimezone):
+		return apperror.UnprocessableEntity("INVALID_TIMEZONE", "timezone must be an IANA time zone")
+	}
+
+	return err
+}
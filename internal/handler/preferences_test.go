@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// preferencesBody is a PUT /api/v1/me/preferences body in the given zone
+func preferencesBody(locale, timezone string) map[string]any {
+	return map[string]any{
+		"locale":        locale,
+		"timezone":      timezone,
+		"notifications": map[string]bool{"email": false, "marketing": true},
+	}
+}
+
+// getPreferences returns the current user's preferences
+func (e *testEnv) getPreferences(token string) domain.Preferences {
+	e.t.Helper()
+
+	var preferences domain.Preferences
+	resp := e.do(http.MethodGet, "/api/v1/me/preferences", token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&preferences)
+
+	return preferences
+}
+
+func TestGetPreferencesCreatesDefaults(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	first := env.getPreferences(token)
+	want := domain.DefaultPreferences("")
+	if first.Locale != want.Locale || first.Timezone != want.Timezone || first.Notifications != want.Notifications {
+		t.Errorf("first preferences = %+v, want the defaults %+v", first, want)
+	}
+	if first.UpdatedAt.IsZero() {
+		t.Error("defaults were returned without being stored")
+	}
+
+	// The second read returns the stored defaults rather than new ones
+	if second := env.getPreferences(token); !second.UpdatedAt.Equal(first.UpdatedAt) {
+		t.Errorf("second read updated_at = %v, want the stored %v", second.UpdatedAt, first.UpdatedAt)
+	}
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("ada@example.com"))
+
+	var updated domain.Preferences
+	resp := env.do(http.MethodPut, "/api/v1/me/preferences", token, preferencesBody("en_us", "Asia/Bangkok"))
+	resp.expect(http.StatusOK)
+	resp.decode(&updated)
+	if updated.Locale != "en-US" || updated.Timezone != "Asia/Bangkok" {
+		t.Errorf("locale, timezone = %q, %q; want en-US, Asia/Bangkok", updated.Locale, updated.Timezone)
+	}
+
+	got := env.getPreferences(token)
+	if got.Timezone != "Asia/Bangkok" || got.Notifications.Email || !got.Notifications.Marketing {
+		t.Errorf("stored preferences = %+v, want the update", got)
+	}
+
+	// Preferences belong to their user
+	other := env.getPreferences(env.tokenFor(env.createUser("grace@example.com")))
+	if other.Timezone != "UTC" {
+		t.Errorf("another user's timezone = %q, want the default", other.Timezone)
+	}
+}
+
+func TestUpdatePreferencesRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   string
+		timezone string
+	}{
+		{"unknown timezone", "en", "Mars/Olympus_Mons"},
+		{"offset instead of a zone", "en", "+07:00"},
+		{"missing timezone", "en", ""},
+		{"server's local zone", "en", "Local"},
+		{"malformed locale", "not a locale", "UTC"},
+		{"undetermined locale", "und", "UTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			token := env.tokenFor(env.createUser("ada@example.com"))
+
+			env.do(http.MethodPut, "/api/v1/me/preferences", token, preferencesBody(tt.locale, tt.timezone)).
+				expect(http.StatusUnprocessableEntity, apperror.CodeValidation)
+			if got := env.getPreferences(token); got.Timezone != "UTC" || got.Locale != "en" {
+				t.Errorf("preferences after a rejected update = %+v, want the defaults", got)
+			}
+		})
+	}
+}
+
+func TestExpandPreferences(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("ada@example.com"))
+	env.do(http.MethodPut, "/api/v1/me/preferences", token, preferencesBody("th", "Asia/Bangkok")).expect(http.StatusOK)
+
+	var body struct {
+		Email       string             `json:"email"`
+		Preferences domain.Preferences `json:"preferences"`
+	}
+	resp := env.do(http.MethodGet, "/api/v1/me?expand=preferences", token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&body)
+	if body.Email != "ada@example.com" || body.Preferences.Timezone != "Asia/Bangkok" {
+		t.Errorf("expanded user = %+v, want the user with its preferences", body)
+	}
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+func TestListQueryErrorsAreUniform(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	for _, query := range []string{"?page=0", "?page_size=abc", "?sort=password_hash", "?bogus=1"} {
+		var messages []string
+		for _, path := range []string{"/api/v1/users", "/api/v1/audit-logs"} {
+			resp := env.do(http.MethodGet, path+query, token, nil)
+			resp.expect(http.StatusBadRequest, "INVALID_QUERY")
+			var body errorBody
+			resp.decode(&body)
+			messages = append(messages, body.Error.Message)
+		}
+		if messages[0] != messages[1] {
+			t.Errorf("%s: users says %q, audit logs say %q, want the same message", query, messages[0], messages[1])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// setRolesRequest is the request body replacing a user's roles
+type setRolesRequest struct {
+	Roles domain.Roles `json:"roles" validate:"required,min=1,max=20,dive,required,max=50"`
+}
+
+// rolesResponse lists the roles of a user
+type rolesResponse struct {
+	Roles domain.Roles `json:"roles"`
+}
+
+// GetUserRoles handler returns the roles of a user
+func (h *UserHandler) GetUserRoles(c fiber.Ctx) error {
+	user, err := h.users.Get(c.Context(), c.Params("id"))
+	if err != nil {
+		return userError(err)
+	}
+
+	return respond(c, rolesResponse{Roles: user.Roles})
+}
+
+// SetUserRoles handler replaces the roles of a user. Its access tokens keep
+// their roles until they are refreshed.
+func (h *UserHandler) SetUserRoles(c fiber.Ctx) error {
+	var input setRolesRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	user, err := h.users.SetRoles(c.Context(), c.Params("id"), input.Roles)
+	if err != nil {
+		return roleError(err)
+	}
+
+	return respond(c, rolesResponse{Roles: user.Roles})
+}
+
+// roleError translates role assignment errors into API errors
+func roleError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrRoleNotAssignable):
+		return apperror.Forbidden("ROLE_NOT_ASSIGNABLE", "the super_admin role is granted in the database only")
+	case errors.Is(err, repository.ErrUnknownRole):
+		return apperror.UnprocessableEntity("UNKNOWN_ROLE", "one of the roles does not exist")
+	}
+
+	return userError(err)
+}
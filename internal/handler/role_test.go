@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// setRoles replaces the user's roles as the admin and returns the result
+func (e *testEnv) setRoles(token, userID string, roles ...domain.Role) domain.Roles {
+	e.t.Helper()
+
+	var body rolesResponse
+	resp := e.do(http.MethodPut, "/api/v1/users/"+userID+"/roles", token, map[string]any{"roles": roles})
+	resp.expect(http.StatusOK)
+	resp.decode(&body)
+
+	return body.Roles
+}
+
+// refresh returns the access token a refresh with the session issues
+func (e *testEnv) refresh(refreshToken string) tokenResponse {
+	e.t.Helper()
+
+	var tokens tokenResponse
+	resp := e.do(http.MethodPost, "/api/v1/auth/refresh", "", refreshBody(refreshToken))
+	resp.expect(http.StatusOK)
+	resp.decode(&tokens)
+
+	return tokens
+}
+
+func TestSetUserRoles(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	ada := env.createUser("ada@example.com")
+
+	// Roles come back sorted and without duplicates
+	assigned := env.setRoles(admin, ada.ID, domain.RoleUser, domain.RoleAdmin, domain.RoleUser)
+	if want := (domain.Roles{domain.RoleAdmin, domain.RoleUser}); !slices.Equal(assigned, want) {
+		t.Errorf("assigned roles = %v, want %v", assigned, want)
+	}
+
+	var listed rolesResponse
+	resp := env.do(http.MethodGet, "/api/v1/users/"+ada.ID+"/roles", admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&listed)
+	if !slices.Equal(listed.Roles, assigned) {
+		t.Errorf("listed roles = %v, want %v", listed.Roles, assigned)
+	}
+
+	// Revoking one role keeps the others
+	if revoked := env.setRoles(admin, ada.ID, domain.RoleUser); !slices.Equal(revoked, domain.Roles{domain.RoleUser}) {
+		t.Errorf("roles after revoking admin = %v, want only user", revoked)
+	}
+}
+
+func TestRoleChangesApplyToNewTokens(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	ada := env.createUser("ada@example.com")
+	session := env.sessionFor(ada)
+
+	env.do(http.MethodGet, "/api/v1/users", session.AccessToken, nil).expect(http.StatusForbidden)
+
+	env.setRoles(admin, ada.ID, domain.RoleUser, domain.RoleAdmin)
+	// The token in hand keeps the roles it was issued with
+	env.do(http.MethodGet, "/api/v1/users", session.AccessToken, nil).expect(http.StatusForbidden)
+	granted := env.refresh(session.RefreshToken)
+	env.do(http.MethodGet, "/api/v1/users", granted.AccessToken, nil).expect(http.StatusOK)
+
+	env.setRoles(admin, ada.ID, domain.RoleUser)
+	revoked := env.refresh(granted.RefreshToken)
+	env.do(http.MethodGet, "/api/v1/users", revoked.AccessToken, nil).expect(http.StatusForbidden)
+}
+
+func TestSetUserRolesErrors(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	ada := env.createUser("ada@example.com")
+	root := env.createUser("root@example.com", domain.RoleSuperAdmin)
+
+	tests := []struct {
+		name   string
+		token  string
+		userID string
+		roles  []string
+		status int
+		code   string
+	}{
+		{"not an admin", env.tokenFor(ada), ada.ID, []string{"admin"}, http.StatusForbidden, apperror.CodeForbidden},
+		{"granting super admin", admin, ada.ID, []string{"user", "super_admin"}, http.StatusForbidden, "ROLE_NOT_ASSIGNABLE"},
+		{"changing a super admin", admin, root.ID, []string{"user"}, http.StatusForbidden, "ROLE_NOT_ASSIGNABLE"},
+		{"undefined role", admin, ada.ID, []string{"wizard"}, http.StatusUnprocessableEntity, "UNKNOWN_ROLE"},
+		{"no roles", admin, ada.ID, []string{}, http.StatusUnprocessableEntity, apperror.CodeValidation},
+		{"unknown user", admin, "00000000-0000-0000-0000-000000000000", []string{"user"}, http.StatusNotFound, "USER_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.do(http.MethodPut, "/api/v1/users/"+tt.userID+"/roles", tt.token, map[string]any{"roles": tt.roles}).expect(tt.status, tt.code)
+		})
+	}
+
+	got, err := env.users.GetByID(t.Context(), ada.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if !slices.Equal(got.Roles, domain.Roles{domain.RoleUser}) {
+		t.Errorf("roles after rejected changes = %v, want only user", got.Roles)
+	}
+}
@@ -1,32 +1,182 @@
 package handler
 
 import (
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/buildinfo"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/featureflag"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/static"
 )
 
+// Dependencies holds the handlers and services the routes are wired to
+type Dependencies struct {
+	Users  *UserHandler
+	Auth   *AuthHandler
+	Health *HealthHandler
+	OAuth  *OAuthHandler
+	Audit  *AuditHandler
+	// Preferences serves the current user's preferences
+	Preferences *PreferencesHandler
+	// Impersonation lets admins act as users
+	Impersonation *ImpersonationHandler
+	// Erasure deletes accounts at their owners' request
+	Erasure *ErasureHandler
+	// Avatars handles avatar uploads; UploadsDir, when set, holds the files
+	// of local storage, which are served at /uploads
+	Avatars    *AvatarHandler
+	UploadsDir string
+	Tokens     *auth.TokenManager
+	// UserService lets the auth middleware reject suspended and deleted users
+	UserService *service.UserService
+	// Sessions lets the auth middleware reject revoked access tokens
+	Sessions *service.SessionService
+	// Permissions resolves what the roles of authenticated users permit
+	Permissions *service.PermissionService
+	// APIKeys manages the keys backend services authenticate with
+	APIKeys       *APIKeyHandler
+	APIKeyService *service.APIKeyService
+	Webhooks      *WebhookHandler
+	// Idempotency stores Idempotency-Key responses for IdempotencyTTL
+	Idempotency    repository.IdempotencyRepository
+	IdempotencyTTL time.Duration
+	// Docs serves the OpenAPI document and Swagger UI when set
+	Docs bool
+	// Metrics is served at /metrics and HealthDetails enables
+	// /api/v1/health/details for admins; leave both off when the admin
+	// listener serves them
+	Metrics       *Metrics
+	HealthDetails bool
+	// CSVTimeout bounds the CSV import in place of the request timeout, as it
+	// handles every user at once; zero keeps the request timeout. Exports
+	// stream after the handler returns, so UserHandler bounds them itself
+	CSVTimeout time.Duration
+}
+
 // SetupRoutes configures all application routes
-func SetupRoutes(app *fiber.App) {
+func SetupRoutes(app *fiber.App, deps Dependencies) {
+	requireAuth := RequireAuth(deps.Tokens, deps.UserService, deps.Sessions, deps.Permissions)
+	// Routes used by backend services also accept API keys with the right scope
+	requireAuthOrAPIKey := RequireAuthOrAPIKey(deps.Tokens, deps.Sessions, deps.Permissions, deps.APIKeyService, deps.UserService)
+	readUsers := RequirePermission(domain.PermissionUsersRead)
+	writeUsers := RequirePermission(domain.PermissionUsersWrite)
+	deleteUsers := RequirePermission(domain.PermissionUsersDelete)
+	idempotent := Idempotent(deps.Idempotency, deps.IdempotencyTTL)
+	csvTimeout := func(c fiber.Ctx) error { return c.Next() }
+	if deps.CSVTimeout > 0 {
+		csvTimeout = RouteTimeout(deps.CSVTimeout)
+	}
+
 	// Root routes
 	app.Get("/", Welcome)
 
-	// API v1 routes
-	api := app.Group("/api/v1")
+	if deps.Metrics != nil {
+		app.Get("/metrics", deps.Metrics.Handler())
+	}
+
+	// Every upload gets a new key, so served files never change and may be cached
+	if deps.UploadsDir != "" {
+		app.Use("/uploads", static.New(deps.UploadsDir, static.Config{MaxAge: 86400}))
+	}
+
+	// API v1 routes answer in JSON or MessagePack, following Accept
+	api := app.Group("/api/v1", Negotiate())
+
+	// API documentation
+	if deps.Docs {
+		app.Get(docsPath, SwaggerUI)
+		api.Get("/openapi.json", OpenAPISpec)
+	}
+
+	api.Get("/version", Version)
+
+	// Health checks
+	api.Get("/health", deps.Health.Ready)
+	api.Get("/health/live", deps.Health.Live)
+	api.Get("/health/ready", deps.Health.Ready)
+	if deps.HealthDetails {
+		api.Get("/health/details", requireAuth, RequirePermission(domain.PermissionHealthRead), deps.Health.Details)
+	}
 
-	// Health check
-	api.Get("/health", HealthCheck)
+	// Auth routes
+	authRoutes := api.Group("/auth")
+	authRoutes.Post("/register", deps.Auth.Register)
+	authRoutes.Post("/login", deps.Auth.Login)
+	authRoutes.Get("/verify", deps.Auth.VerifyEmail)
+	authRoutes.Get("/confirm-email", deps.Auth.ConfirmEmailChange)
+	authRoutes.Post("/resend-verification", deps.Auth.ResendVerification)
+	authRoutes.Post("/forgot-password", deps.Auth.ForgotPassword)
+	authRoutes.Post("/reset-password", deps.Auth.ResetPassword)
+	authRoutes.Post("/refresh", deps.Auth.Refresh)
+	authRoutes.Post("/logout", deps.Auth.Logout)
+	authRoutes.Post("/2fa/verify", RequireFeature(featureflag.TwoFactor), deps.Auth.VerifyTwoFactor)
+	oauthRoutes := authRoutes.Group("/oauth", RequireFeature(featureflag.OAuth))
+	oauthRoutes.Get("/:provider", deps.OAuth.StartOAuth)
+	oauthRoutes.Get("/:provider/callback", deps.OAuth.OAuthCallback)
+
+	// Current user routes; the two-factor ones are gated before authentication
+	api.Use("/me/2fa", RequireFeature(featureflag.TwoFactor))
+	me := api.Group("/me", requireAuth)
+	me.Get("/", deps.Users.GetMe)
+	me.Patch("/", deps.Users.UpdateMe)
+	me.Delete("/", deps.Erasure.DeleteMe)
+	me.Post("/avatar", deps.Avatars.UploadAvatar)
+	me.Delete("/avatar", deps.Avatars.DeleteAvatar)
+	me.Get("/preferences", deps.Preferences.GetPreferences)
+	me.Put("/preferences", deps.Preferences.UpdatePreferences)
+	me.Post("/change-password", deps.Auth.ChangePassword)
+	me.Get("/sessions", deps.Auth.ListSessions)
+	me.Delete("/sessions", deps.Auth.RevokeOtherSessions)
+	me.Delete("/sessions/:id", deps.Auth.RevokeSession)
+	me.Post("/2fa/enable", deps.Auth.EnableTwoFactor)
+	me.Post("/2fa/confirm", deps.Auth.ConfirmTwoFactor)
+
+	// User routes
+	users := api.Group("/users", requireAuthOrAPIKey)
+	users.Post("/", writeUsers, idempotent, deps.Users.CreateUser)
+	users.Post("/bulk", writeUsers, idempotent, deps.Users.BulkCreateUsers)
+	users.Get("/", readUsers, deps.Users.ListUsers)
+	users.Delete("/", deleteUsers, deps.Users.BulkDeleteUsers)
+	users.Get("/export.csv", readUsers, deps.Users.ExportUsers)
+	users.Get("/export.ndjson", readUsers, deps.Users.ExportUsersNDJSON)
+	users.Post("/import", csvTimeout, writeUsers, deps.Users.ImportUsers)
+	users.Get("/:id", RequireScope(domain.ScopeUsersRead), deps.Users.GetUser)
+	users.Put("/:id", RequireScope(domain.ScopeUsersWrite), deps.Users.UpdateUser)
+	users.Patch("/:id", RequireScope(domain.ScopeUsersWrite), deps.Users.PatchUser)
+	users.Delete("/:id", deleteUsers, deps.Users.DeleteUser)
+	users.Post("/:id/restore", writeUsers, deps.Users.RestoreUser)
+	users.Patch("/:id/status", writeUsers, deps.Users.UpdateUserStatus)
+	users.Get("/:id/roles", readUsers, deps.Users.GetUserRoles)
+	users.Put("/:id/roles", RequirePermission(domain.PermissionRolesManage), deps.Users.SetUserRoles)
+
+	// Admin routes
+	admin := api.Group("/admin", requireAuth, RequirePermission(domain.PermissionUsersImpersonate))
+	admin.Post("/impersonate/:userId", deps.Impersonation.Impersonate)
+
+	// Audit log routes
+	api.Get("/audit-logs", requireAuthOrAPIKey, RequirePermission(domain.PermissionAuditRead), deps.Audit.ListAuditLogs)
+
+	// API key routes; keys are managed by admins only, never with another key
+	apiKeys := api.Group("/api-keys", requireAuth, RequirePermission(domain.PermissionAPIKeysManage))
+	apiKeys.Post("/", deps.APIKeys.CreateAPIKey)
+	apiKeys.Get("/", deps.APIKeys.ListAPIKeys)
+	apiKeys.Delete("/:id", deps.APIKeys.RevokeAPIKey)
+
+	// Webhook routes
+	webhooks := api.Group("/webhooks", RequireFeature(featureflag.Webhooks), requireAuth, RequirePermission(domain.PermissionWebhooksManage))
+	webhooks.Post("/", deps.Webhooks.CreateWebhook)
+	webhooks.Get("/", deps.Webhooks.ListWebhooks)
+	webhooks.Get("/:id/deliveries", deps.Webhooks.ListWebhookDeliveries)
 }
 
 // Welcome handler returns a welcome message
 func Welcome(c fiber.Ctx) error {
-	return c.JSON(fiber.Map{
+	return respond(c, fiber.Map{
 		"message": "Welcome to User Management API",
-		"version": "1.0.0",
-	})
-}
-
-// HealthCheck handler returns the health status
-func HealthCheck(c fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"status": "healthy",
+		"version": buildinfo.Version,
 	})
 }
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+// docsPath serves the Swagger UI page, which gets its own CSP
+const docsPath = "/docs"
+
+// docsCSP lets the Swagger UI page load its assets from unpkg and run its
+// inline bootstrap script, allowed by hash, while still refusing framing.
+// Swagger UI sets inline styles, so those are allowed too.
+var docsCSP = "default-src 'none'; " +
+	"script-src https://unpkg.com '" + scriptHash(swaggerInitScript) + "'; " +
+	"style-src https://unpkg.com 'unsafe-inline'; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"frame-ancestors 'none'"
+
+// scriptHash returns the CSP source expression allowing an inline script
+func scriptHash(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// SecurityHeaders sets the configured security headers before the rest of
+// the chain runs, so they are on every response, errors included. An empty
+// setting leaves its header out. Strict-Transport-Security is only sent when
+// tls is set, as browsers ignore it over plain HTTP.
+func SecurityHeaders(cfg config.SecurityConfig, tls bool) fiber.Handler {
+	hsts := ""
+	if tls && cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+	pageCSP := cfg.DocsCSP
+	if pageCSP == "" {
+		pageCSP = docsCSP
+	}
+
+	return func(c fiber.Ctx) error {
+		if cfg.NoSniff {
+			c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		}
+		if cfg.FrameOptions != "" {
+			c.Set(fiber.HeaderXFrameOptions, cfg.FrameOptions)
+		}
+		if cfg.ReferrerPolicy != "" {
+			c.Set(fiber.HeaderReferrerPolicy, cfg.ReferrerPolicy)
+		}
+		if hsts != "" {
+			c.Set(fiber.HeaderStrictTransportSecurity, hsts)
+		}
+
+		csp := cfg.CSP
+		if c.Path() == docsPath {
+			csp = pageCSP
+		}
+		if csp != "" {
+			c.Set(fiber.HeaderContentSecurityPolicy, csp)
+		}
+
+		return c.Next()
+	}
+}
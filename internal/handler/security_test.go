@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+)
+
+// defaultSecurityHeaders are the headers the default configuration sends
+var defaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options":  "nosniff",
+	"X-Frame-Options":         "DENY",
+	"Referrer-Policy":         "no-referrer",
+	"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Server.TLSEnabled = false })
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"normal response", "/api/v1/health/live", http.StatusOK},
+		{"error response", "/no-such-page", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := env.do(http.MethodGet, tt.path, "", nil)
+			resp.expect(tt.status)
+			for header, want := range defaultSecurityHeaders {
+				if got := resp.Header.Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+			if got := resp.Header.Get("Strict-Transport-Security"); got != "" {
+				t.Errorf("Strict-Transport-Security = %q without TLS, want none", got)
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersHSTSWithTLS(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Server.TLSEnabled = true
+		cfg.Security.HSTSIncludeSubdomains = true
+	})
+
+	resp := env.do(http.MethodGet, "/api/v1/health/live", "", nil)
+	resp.expect(http.StatusOK)
+	if got, want := resp.Header.Get("Strict-Transport-Security"), "max-age=31536000; includeSubDomains"; got != want {
+		t.Errorf("Strict-Transport-Security = %q, want %q", got, want)
+	}
+}
+
+func TestSecurityHeadersDisabled(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Server.TLSEnabled = true
+		cfg.Security = config.SecurityConfig{}
+	})
+
+	resp := env.do(http.MethodGet, "/api/v1/health/live", "", nil)
+	resp.expect(http.StatusOK)
+	for header := range defaultSecurityHeaders {
+		if got := resp.Header.Get(header); got != "" {
+			t.Errorf("%s = %q, want it left out", header, got)
+		}
+	}
+	if got := resp.Header.Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Strict-Transport-Security = %q with a zero max age, want none", got)
+	}
+}
+
+func TestSecurityHeadersDocsCSP(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Server.DocsEnabled = true })
+
+	resp := env.do(http.MethodGet, "/docs", "", nil)
+	resp.expect(http.StatusOK)
+	csp := resp.Header.Get("Content-Security-Policy")
+	if !strings.Contains(csp, "https://unpkg.com") || !strings.Contains(csp, "frame-ancestors 'none'") {
+		t.Errorf("docs Content-Security-Policy = %q, want the Swagger UI policy", csp)
+	}
+}
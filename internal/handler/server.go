@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"github.com/boonyarit-iamsaard/user-management-api/internal/app"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	gojson "github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Server holds the public app and, when cfg.Server.AdminAddr is set, the
+// app of the internal admin listener
+type Server struct {
+	Public *fiber.App
+	Admin  *fiber.App
+}
+
+// NewServer wires the middleware and routes of the application to the
+// services of a
+func NewServer(cfg *config.Config, a *app.App) *Server {
+	fiberConfig := fiber.Config{
+		ErrorHandler: ErrorHandler,
+		BodyLimit:    cfg.Server.BodyLimit,
+	}
+
+	// Only honor the proxy header when the request comes from a trusted proxy;
+	// without TrustProxy, Fiber would read the header from any client
+	if len(cfg.Server.TrustedProxies) > 0 {
+		fiberConfig.TrustProxy = true
+		fiberConfig.TrustProxyConfig = fiber.TrustProxyConfig{
+			Proxies: cfg.Server.TrustedProxies,
+		}
+		fiberConfig.ProxyHeader = cfg.Server.ProxyHeader
+	}
+
+	// goccy/go-json is a drop-in replacement producing the same bytes, faster
+	if cfg.Server.JSONLibrary == config.JSONGoccy {
+		fiberConfig.JSONEncoder = gojson.Marshal
+		fiberConfig.JSONDecoder = gojson.Unmarshal
+	}
+
+	s := &Server{Public: fiber.New(fiberConfig)}
+
+	metrics := NewMetrics()
+	SetupMiddleware(s.Public, cfg, metrics, a.Features, a.Store)
+
+	health := NewHealthHandler(a.DB, cfg.Database.PingTimeout)
+	deps := Dependencies{
+		Users:         NewUserHandler(a.Users, a.Preferences, a.Repositories.AuditLogs, cfg.Server.CSVTimeout),
+		Auth:          NewAuthHandler(a.Users, a.Sessions, a.TwoFactor),
+		Health:        health,
+		OAuth:         NewOAuthHandler(a.OAuth, a.Sessions, a.TwoFactor),
+		Audit:         NewAuditHandler(a.Repositories.AuditLogs),
+		Preferences:   NewPreferencesHandler(a.Preferences),
+		Impersonation: NewImpersonationHandler(a.Impersonation),
+		Erasure:       NewErasureHandler(a.Erasure),
+		Tokens:        a.Tokens,
+		UserService:   a.Users,
+		Sessions:      a.Sessions,
+		Permissions:   a.Permissions,
+		Docs:          cfg.Server.DocsEnabled,
+
+		Avatars:    NewAvatarHandler(a.Avatars),
+		UploadsDir: a.UploadsDir,
+
+		APIKeys:       NewAPIKeyHandler(a.APIKeys),
+		APIKeyService: a.APIKeys,
+		Webhooks:      NewWebhookHandler(a.Webhooks),
+
+		Idempotency:    a.Repositories.Idempotency,
+		IdempotencyTTL: cfg.Auth.IdempotencyKeyTTL,
+
+		CSVTimeout: cfg.Server.CSVTimeout,
+	}
+
+	// Operational endpoints move to the internal listener when it is set, so
+	// they stay off the public one
+	if cfg.Server.AdminAddr == "" {
+		deps.Metrics = metrics
+		deps.HealthDetails = true
+	} else {
+		s.Admin = NewAdminApp(AdminDependencies{
+			Metrics: metrics,
+			Health:  health,
+			Pprof:   cfg.Server.PprofEnabled,
+		})
+	}
+	SetupRoutes(s.Public, deps)
+
+	return s
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/gofiber/fiber/v3"
+)
+
+// sessionResponse is an active session, flagged when it is the caller's own
+type sessionResponse struct {
+	*domain.Session
+	Current bool `json:"current"`
+}
+
+// sessionListResponse is returned by ListSessions
+type sessionListResponse struct {
+	Data []sessionResponse `json:"data"`
+}
+
+// sessionClient describes the client of the request for the session it starts or refreshes
+func sessionClient(c fiber.Ctx) service.SessionClient {
+	// The header points into a buffer Fiber reuses once the request ends
+	return service.SessionClient{
+		UserAgent: strings.Clone(c.Get(fiber.HeaderUserAgent)),
+		IP:        ClientIP(c),
+	}
+}
+
+// ListSessions handler returns the authenticated user's active sessions
+func (h *AuthHandler) ListSessions(c fiber.Ctx) error {
+	userID, _ := CurrentUserID(c)
+	currentID, _ := CurrentSessionID(c)
+
+	sessions, err := h.sessions.List(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	resp := sessionListResponse{Data: make([]sessionResponse, len(sessions))}
+	for i, session := range sessions {
+		resp.Data[i] = sessionResponse{Session: session, Current: session.ID == currentID}
+	}
+
+	return respond(c, resp)
+}
+
+// RevokeSession handler signs the authenticated user out of one session
+func (h *AuthHandler) RevokeSession(c fiber.Ctx) error {
+	userID, _ := CurrentUserID(c)
+
+	if err := h.sessions.Revoke(c.Context(), userID, c.Params("id")); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			return apperror.NotFound("SESSION_NOT_FOUND", "session not found")
+		}
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeOtherSessions handler signs the authenticated user out of every
+// session but the one making the request
+func (h *AuthHandler) RevokeOtherSessions(c fiber.Ctx) error {
+	userID, _ := CurrentUserID(c)
+	currentID, _ := CurrentSessionID(c)
+
+	if err := h.sessions.RevokeOthers(c.Context(), userID, currentID); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// loginFrom signs in with the user agent and returns the tokens
+func (e *testEnv) loginFrom(email, userAgent string) tokenResponse {
+	e.t.Helper()
+
+	req := newRequest(http.MethodPost, "/api/v1/auth/login", loginBody(email, testPassword))
+	req.Header.Set(fiber.HeaderUserAgent, userAgent)
+	var tokens tokenResponse
+	resp := e.send(req)
+	resp.expect(http.StatusOK)
+	resp.decode(&tokens)
+
+	return tokens
+}
+
+// listSessions returns the token's user's sessions by user agent
+func (e *testEnv) listSessions(token string) map[string]sessionResponse {
+	e.t.Helper()
+
+	var list sessionListResponse
+	resp := e.do(http.MethodGet, "/api/v1/me/sessions", token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&list)
+
+	sessions := make(map[string]sessionResponse, len(list.Data))
+	for _, session := range list.Data {
+		sessions[session.UserAgent] = session
+	}
+
+	return sessions
+}
+
+func TestListSessions(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+	laptop := env.loginFrom("ada@example.com", "laptop")
+	env.loginFrom("ada@example.com", "phone")
+
+	sessions := env.listSessions(laptop.AccessToken)
+	if len(sessions) != 2 {
+		t.Fatalf("sessions = %v, want the laptop and the phone", sessions)
+	}
+	if !sessions["laptop"].Current || sessions["phone"].Current {
+		t.Errorf("current flags = laptop %v, phone %v, want only the laptop", sessions["laptop"].Current, sessions["phone"].Current)
+	}
+	for agent, session := range sessions {
+		if session.ID == "" || session.IP != testPeer || session.CreatedAt.IsZero() || session.LastUsedAt.IsZero() {
+			t.Errorf("%s session = %+v, want its id, address, and times", agent, session)
+		}
+	}
+
+	// Refreshing keeps the session, and it stays the current one
+	req := newRequest(http.MethodPost, "/api/v1/auth/refresh", refreshBody(laptop.RefreshToken))
+	req.Header.Set(fiber.HeaderUserAgent, "laptop")
+	var refreshed tokenResponse
+	resp := env.send(req)
+	resp.expect(http.StatusOK)
+	resp.decode(&refreshed)
+	after := env.listSessions(refreshed.AccessToken)
+	if session, ok := after["laptop"]; !ok || session.ID != sessions["laptop"].ID || !session.Current {
+		t.Errorf("sessions after refresh = %v, want %s flagged current", after, sessions["laptop"].ID)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+	laptop := env.loginFrom("ada@example.com", "laptop")
+	phone := env.loginFrom("ada@example.com", "phone")
+	phoneID := env.listSessions(laptop.AccessToken)["phone"].ID
+
+	// Another user cannot see or revoke the session
+	env.createUser("grace@example.com")
+	grace := env.loginFrom("grace@example.com", "desktop")
+	env.do(http.MethodDelete, "/api/v1/me/sessions/"+phoneID, grace.AccessToken, nil).expect(http.StatusNotFound, "SESSION_NOT_FOUND")
+
+	env.do(http.MethodDelete, "/api/v1/me/sessions/"+phoneID, laptop.AccessToken, nil).expect(http.StatusNoContent)
+
+	env.do(http.MethodPost, "/api/v1/auth/refresh", "", refreshBody(phone.RefreshToken)).expect(http.StatusUnauthorized, "INVALID_REFRESH_TOKEN")
+	env.do(http.MethodGet, "/api/v1/me", phone.AccessToken, nil).expect(http.StatusUnauthorized)
+	env.do(http.MethodGet, "/api/v1/me", laptop.AccessToken, nil).expect(http.StatusOK)
+	if sessions := env.listSessions(laptop.AccessToken); len(sessions) != 1 {
+		t.Errorf("sessions after revoking = %v, want only the laptop", sessions)
+	}
+	env.do(http.MethodDelete, "/api/v1/me/sessions/"+phoneID, laptop.AccessToken, nil).expect(http.StatusNotFound, "SESSION_NOT_FOUND")
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+	laptop := env.loginFrom("ada@example.com", "laptop")
+	phone := env.loginFrom("ada@example.com", "phone")
+	tablet := env.loginFrom("ada@example.com", "tablet")
+
+	env.do(http.MethodDelete, "/api/v1/me/sessions", laptop.AccessToken, nil).expect(http.StatusNoContent)
+
+	for _, other := range []tokenResponse{phone, tablet} {
+		env.do(http.MethodPost, "/api/v1/auth/refresh", "", refreshBody(other.RefreshToken)).expect(http.StatusUnauthorized, "INVALID_REFRESH_TOKEN")
+	}
+	env.do(http.MethodPost, "/api/v1/auth/refresh", "", refreshBody(laptop.RefreshToken)).expect(http.StatusOK)
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+	laptop := env.loginFrom("ada@example.com", "laptop")
+	phone := env.loginFrom("ada@example.com", "phone")
+
+	env.do(http.MethodPost, "/api/v1/auth/logout", laptop.AccessToken, refreshBody(laptop.RefreshToken)).expect(http.StatusNoContent)
+
+	// The access token is refused at once rather than when it expires
+	env.do(http.MethodGet, "/api/v1/me", laptop.AccessToken, nil).expect(http.StatusUnauthorized, "TOKEN_REVOKED")
+	env.do(http.MethodGet, "/api/v1/me", phone.AccessToken, nil).expect(http.StatusOK)
+}
+
+func TestRevokeOtherSessionsRevokesAccessTokens(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("ada@example.com")
+	laptop := env.loginFrom("ada@example.com", "laptop")
+	phone := env.loginFrom("ada@example.com", "phone")
+	tablet := env.loginFrom("ada@example.com", "tablet")
+
+	env.do(http.MethodDelete, "/api/v1/me/sessions", laptop.AccessToken, nil).expect(http.StatusNoContent)
+
+	for _, other := range []tokenResponse{phone, tablet} {
+		env.do(http.MethodGet, "/api/v1/me", other.AccessToken, nil).expect(http.StatusUnauthorized, "TOKEN_REVOKED")
+	}
+	env.do(http.MethodGet, "/api/v1/me", laptop.AccessToken, nil).expect(http.StatusOK)
+}
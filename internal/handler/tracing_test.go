@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := testutil.RecordSpans(t)
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := newRequest(http.MethodGet, "/api/v1/users/"+admin.ID, nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+env.tokenFor(admin))
+	req.Header.Set("traceparent", traceparent)
+	env.send(req).expect(http.StatusOK)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/v1/users/:id" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span = %s (%s), want the server span named after the route", span.Name(), span.SpanKind())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id = %s, want the one from traceparent", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span id = %s, want the one from traceparent", got)
+	}
+	attrs := attribute.NewSet(span.Attributes()...)
+	if route, _ := attrs.Value("http.route"); route.AsString() != "/api/v1/users/:id" {
+		t.Errorf("http.route = %q, want the route template", route.AsString())
+	}
+	if status, _ := attrs.Value("http.response.status_code"); status.AsInt64() != http.StatusOK {
+		t.Errorf("http.response.status_code = %d, want %d", status.AsInt64(), http.StatusOK)
+	}
+}
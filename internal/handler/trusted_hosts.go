@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/gofiber/fiber/v3"
+)
+
+// probePaths are the health checks exempt from host validation, as
+// orchestrators probe instances by address rather than by hostname
+var probePaths = []string{"/api/v1/health", "/api/v1/health/live", "/api/v1/health/ready"}
+
+// errUntrustedHost is returned for requests addressed to an unlisted host
+var errUntrustedHost = apperror.BadRequest("INVALID_HOST", "host is not served here")
+
+// TrustedHosts rejects requests whose Host, or X-Forwarded-Host from a
+// trusted proxy, is not one of hosts, so a forged header cannot make the
+// response refer to another site. Hostnames compare case-insensitively and
+// without the port; "*" allows any host.
+func TrustedHosts(hosts []string) fiber.Handler {
+	if slices.Contains(hosts, "*") {
+		return func(c fiber.Ctx) error { return c.Next() }
+	}
+
+	allowed := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		allowed[strings.ToLower(host)] = true
+	}
+
+	return func(c fiber.Ctx) error {
+		if slices.Contains(probePaths, c.Path()) {
+			return c.Next()
+		}
+
+		if !allowed[requestHost(c)] {
+			return errUntrustedHost
+		}
+
+		return c.Next()
+	}
+}
+
+// requestHost returns the lowercased hostname the request is addressed to.
+// Fiber reads X-Forwarded-Host from every peer unless proxy checks are on,
+// so without trusted proxies only the Host header itself counts.
+func requestHost(c fiber.Ctx) string {
+	host := c.Hostname()
+	if !c.App().Config().TrustProxy {
+		host = string(c.Request().URI().Host())
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+
+	return strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+}
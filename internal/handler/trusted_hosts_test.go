@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+	"github.com/gofiber/fiber/v3"
+)
+
+// canonicalHost is the host the trusted hosts tests serve
+const canonicalHost = "api.example.com"
+
+// withCanonicalHost serves only canonicalHost and builds links from it
+func withCanonicalHost(cfg *config.Config) {
+	cfg.Server.BaseURL = "https://" + canonicalHost
+	cfg.Server.TrustedHosts = []string{canonicalHost}
+}
+
+// toHost sends the request addressed to host, with X-Forwarded-Host set
+// when forwarded is not empty
+func (e *testEnv) toHost(method, path, host, forwarded string, body any) *testResponse {
+	e.t.Helper()
+
+	req := newRequest(method, path, body)
+	req.Host = host
+	if forwarded != "" {
+		req.Header.Set(fiber.HeaderXForwardedHost, forwarded)
+	}
+
+	return e.send(req)
+}
+
+func TestTrustedHosts(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxies   []string
+		host      string
+		forwarded string
+		status    int
+	}{
+		{"canonical host", nil, canonicalHost, "", http.StatusOK},
+		{"canonical host in another case with a port", nil, "API.example.com:8443", "", http.StatusOK},
+		{"spoofed host", nil, "evil.example.net", "", http.StatusBadRequest},
+		{"host ending like the canonical one", nil, "evil" + canonicalHost, "", http.StatusBadRequest},
+		{"forwarded host from an untrusted peer", nil, canonicalHost, "evil.example.net", http.StatusOK},
+		{"spoofed host forwarded as canonical by an untrusted peer", nil, "evil.example.net", canonicalHost, http.StatusBadRequest},
+		{"spoofed host forwarded as canonical by an unlisted proxy", []string{"10.0.0.0/8"}, "evil.example.net", canonicalHost, http.StatusBadRequest},
+		{"spoofed forwarded host from a trusted proxy", []string{testPeer}, canonicalHost, "evil.example.net", http.StatusBadRequest},
+		{"forwarded host from a trusted proxy", []string{testPeer}, "10.0.0.5", canonicalHost, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, withCanonicalHost, func(cfg *config.Config) { cfg.Server.TrustedProxies = tt.proxies })
+
+			resp := env.toHost(http.MethodGet, "/", tt.host, tt.forwarded, nil)
+			if tt.status == http.StatusBadRequest {
+				resp.expect(http.StatusBadRequest, "INVALID_HOST")
+				return
+			}
+			resp.expect(tt.status)
+		})
+	}
+}
+
+func TestTrustedHostsExemptProbesAndWildcard(t *testing.T) {
+	env := newTestEnv(t, withCanonicalHost)
+	// Orchestrators probe the instance by address
+	env.toHost(http.MethodGet, "/api/v1/health/live", "10.0.0.5:3000", "", nil).expect(http.StatusOK)
+	env.toHost(http.MethodGet, "/api/v1/users", "10.0.0.5:3000", "", nil).expect(http.StatusBadRequest, "INVALID_HOST")
+
+	// Development allows any host by default
+	env = newTestEnv(t)
+	env.toHost(http.MethodGet, "/", "anything.localhost", "", nil).expect(http.StatusOK)
+}
+
+func TestEmailLinksUseCanonicalHost(t *testing.T) {
+	env := newTestEnv(t, withCanonicalHost, func(cfg *config.Config) { cfg.Server.TrustedProxies = []string{testPeer} })
+	env.createUser("ada@example.com")
+
+	// A spoofed host is refused before any email goes out
+	env.toHost(http.MethodPost, "/api/v1/auth/forgot-password", "evil.example.net", "", map[string]string{"email": "ada@example.com"}).
+		expect(http.StatusBadRequest, "INVALID_HOST")
+	env.toHost(http.MethodPost, "/api/v1/auth/register", "evil.example.net", "", registerBody("grace@example.com")).
+		expect(http.StatusBadRequest, "INVALID_HOST")
+	if n := len(env.mail.MessagesTo("ada@example.com")) + len(env.mail.MessagesTo("grace@example.com")); n != 0 {
+		t.Fatalf("sent %d emails for a spoofed host, want none", n)
+	}
+
+	// Reached through a proxy at another address, links still come from the configuration
+	env.toHost(http.MethodPost, "/api/v1/auth/forgot-password", "10.0.0.5", canonicalHost, map[string]string{"email": "ada@example.com"}).
+		expect(http.StatusOK)
+	env.toHost(http.MethodPost, "/api/v1/auth/register", "10.0.0.5", canonicalHost, registerBody("grace@example.com")).
+		expect(http.StatusCreated)
+
+	tests := []struct {
+		to       string
+		template string
+		link     string
+	}{
+		{"ada@example.com", mailer.TemplatePasswordReset, ""},
+		{"grace@example.com", mailer.TemplateVerifyEmail, "https://" + canonicalHost + "/api/v1/auth/verify?token="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			var sent *mailer.Message
+			for _, msg := range env.mail.MessagesTo(tt.to) {
+				if msg.Template == tt.template {
+					sent = &msg
+				}
+			}
+			if sent == nil {
+				t.Fatalf("no %s email sent to %s", tt.template, tt.to)
+			}
+			for _, body := range []string{sent.Body, sent.HTML} {
+				if !strings.Contains(body, tt.link) {
+					t.Errorf("%s email %q does not link to %s", tt.template, body, tt.link)
+				}
+				if strings.Contains(body, "10.0.0.5") {
+					t.Errorf("%s email %q refers to the request's address", tt.template, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// confirmTwoFactorRequest is the request body for confirming a TOTP enrollment
+type confirmTwoFactorRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// verifyTwoFactorRequest is the request body for the second login step; the
+// code is either a TOTP code or a recovery code
+type verifyTwoFactorRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// twoFactorSetupResponse is returned when a TOTP secret is generated
+type twoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	// OTPAuthURL is usually rendered as a QR code for authenticator apps
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// recoveryCodesResponse lists the recovery codes issued on confirmation
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// mfaChallengeResponse is returned by Login when a second factor is required
+type mfaChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// EnableTwoFactor handler generates a TOTP secret for the authenticated user
+func (h *AuthHandler) EnableTwoFactor(c fiber.Ctx) error {
+	userID, _ := CurrentUserID(c)
+	setup, err := h.twoFactor.Enable(c.Context(), userID)
+	if err != nil {
+		return authError(err)
+	}
+
+	return respond(c, twoFactorSetupResponse{Secret: setup.Secret, OTPAuthURL: setup.URL})
+}
+
+// ConfirmTwoFactor handler turns on two-factor after checking a code from the
+// new secret and returns the one-time recovery codes
+func (h *AuthHandler) ConfirmTwoFactor(c fiber.Ctx) error {
+	var input confirmTwoFactorRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	userID, _ := CurrentUserID(c)
+	codes, err := h.twoFactor.Confirm(c.Context(), userID, input.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			// The caller is signed in, so a wrong code is a bad request rather than a failed login
+			return apperror.BadRequest("INVALID_TWO_FACTOR_CODE", "invalid two-factor code")
+		}
+		return authError(err)
+	}
+
+	return respond(c, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// VerifyTwoFactor handler completes a two-factor login and issues a new token pair
+func (h *AuthHandler) VerifyTwoFactor(c fiber.Ctx) error {
+	var input verifyTwoFactorRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	user, err := h.twoFactor.Verify(c.Context(), input.MFAToken, input.Code)
+	if err != nil {
+		return authError(err)
+	}
+
+	pair, err := h.sessions.Start(c.Context(), user, sessionClient(c))
+	if err != nil {
+		return err
+	}
+
+	return respond(c, newTokenResponse(pair))
+}
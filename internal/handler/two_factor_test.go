@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/pquerna/otp/totp"
+)
+
+// totpCode returns the code of the secret for the period offset periods
+// from the current one
+func totpCode(t *testing.T, secret string, offset int) string {
+	t.Helper()
+
+	code, err := totp.GenerateCode(secret, time.Now().Add(time.Duration(offset)*30*time.Second))
+	if err != nil {
+		t.Fatalf("generate TOTP code: %v", err)
+	}
+
+	return code
+}
+
+// enableTwoFactor turns on two-factor for the user and returns its secret
+// and recovery codes. It confirms with the code of the previous period, so
+// the current one is left for a login.
+func (e *testEnv) enableTwoFactor(user *domain.User) (string, []string) {
+	e.t.Helper()
+	token := e.tokenFor(user)
+
+	var setup twoFactorSetupResponse
+	resp := e.do(http.MethodPost, "/api/v1/me/2fa/enable", token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&setup)
+
+	var confirmed recoveryCodesResponse
+	resp = e.do(http.MethodPost, "/api/v1/me/2fa/confirm", token, map[string]string{"code": totpCode(e.t, setup.Secret, -1)})
+	resp.expect(http.StatusOK)
+	resp.decode(&confirmed)
+
+	return setup.Secret, confirmed.RecoveryCodes
+}
+
+func TestEnableTwoFactor(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	token := env.tokenFor(user)
+
+	env.do(http.MethodPost, "/api/v1/me/2fa/confirm", token, map[string]string{"code": "123456"}).expect(http.StatusConflict, "TWO_FACTOR_NOT_PENDING")
+
+	var setup twoFactorSetupResponse
+	resp := env.do(http.MethodPost, "/api/v1/me/2fa/enable", token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&setup)
+	if setup.Secret == "" || !strings.HasPrefix(setup.OTPAuthURL, "otpauth://totp/") || !strings.Contains(setup.OTPAuthURL, "secret="+setup.Secret) {
+		t.Fatalf("setup = %+v, want a secret and its otpauth URL", setup)
+	}
+
+	env.do(http.MethodPost, "/api/v1/me/2fa/confirm", token, map[string]string{"code": "000000"}).expect(http.StatusBadRequest, "INVALID_TWO_FACTOR_CODE")
+
+	var confirmed recoveryCodesResponse
+	resp = env.do(http.MethodPost, "/api/v1/me/2fa/confirm", token, map[string]string{"code": totpCode(t, setup.Secret, 0)})
+	resp.expect(http.StatusOK)
+	resp.decode(&confirmed)
+	if len(confirmed.RecoveryCodes) == 0 {
+		t.Error("confirmation returned no recovery codes")
+	}
+
+	env.do(http.MethodPost, "/api/v1/me/2fa/enable", token, nil).expect(http.StatusConflict, "TWO_FACTOR_ALREADY_ENABLED")
+}
+
+// mfaLogin logs in as ada@example.com and returns the mfa token of the challenge
+func (e *testEnv) mfaLogin() string {
+	e.t.Helper()
+
+	var challenge mfaChallengeResponse
+	resp := e.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword))
+	resp.expect(http.StatusAccepted)
+	resp.decode(&challenge)
+	if !challenge.MFARequired || challenge.MFAToken == "" {
+		e.t.Fatalf("login = %s, want an mfa challenge", resp.body)
+	}
+	if strings.Contains(string(resp.body), "access_token") {
+		e.t.Fatalf("login = %s issued tokens before the second factor", resp.body)
+	}
+
+	return challenge.MFAToken
+}
+
+// verifyTwoFactor completes a two-factor login with the code
+func (e *testEnv) verifyTwoFactor(mfaToken, code string) *testResponse {
+	return e.do(http.MethodPost, "/api/v1/auth/2fa/verify", "", map[string]string{"mfa_token": mfaToken, "code": code})
+}
+
+func TestLoginRequiresSecondFactor(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	secret, recoveryCodes := env.enableTwoFactor(user)
+
+	mfaToken := env.mfaLogin()
+	// The mfa token is not an access token
+	env.do(http.MethodGet, "/api/v1/me", mfaToken, nil).expect(http.StatusUnauthorized)
+	env.verifyTwoFactor(mfaToken, "000000").expect(http.StatusUnauthorized, "INVALID_TWO_FACTOR_CODE")
+	env.verifyTwoFactor("not-a-token", totpCode(t, secret, 0)).expect(http.StatusUnauthorized, "INVALID_MFA_TOKEN")
+
+	var tokens tokenResponse
+	resp := env.verifyTwoFactor(mfaToken, totpCode(t, secret, 0))
+	resp.expect(http.StatusOK)
+	resp.decode(&tokens)
+	env.do(http.MethodGet, "/api/v1/me", tokens.AccessToken, nil).expect(http.StatusOK)
+
+	// Recovery codes work once each
+	env.verifyTwoFactor(env.mfaLogin(), recoveryCodes[0]).expect(http.StatusOK)
+	env.verifyTwoFactor(env.mfaLogin(), recoveryCodes[0]).expect(http.StatusUnauthorized, "INVALID_TWO_FACTOR_CODE")
+}
+
+func TestSecondFactorRefusesReplays(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.RateLimit.LoginMax = 100 })
+	user := env.createUser("ada@example.com")
+	secret, recoveryCodes := env.enableTwoFactor(user)
+
+	// A TOTP code completes one login, even while it is within the skew window
+	code := totpCode(t, secret, 0)
+	env.verifyTwoFactor(env.mfaLogin(), code).expect(http.StatusOK)
+	env.verifyTwoFactor(env.mfaLogin(), code).expect(http.StatusUnauthorized, "INVALID_TWO_FACTOR_CODE")
+	// Nor are codes of earlier periods accepted once a later one was, like the confirmation code
+	env.verifyTwoFactor(env.mfaLogin(), totpCode(t, secret, -1)).expect(http.StatusUnauthorized, "INVALID_TWO_FACTOR_CODE")
+	env.verifyTwoFactor(env.mfaLogin(), totpCode(t, secret, 1)).expect(http.StatusOK)
+
+	// An mfa token completes one login, whatever the code
+	mfaToken := env.mfaLogin()
+	env.verifyTwoFactor(mfaToken, recoveryCodes[0]).expect(http.StatusOK)
+	env.verifyTwoFactor(mfaToken, recoveryCodes[1]).expect(http.StatusUnauthorized, "INVALID_MFA_TOKEN")
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUnmatchedRoute(t *testing.T) {
+	env := newTestEnv(t)
+
+	resp := env.do(http.MethodGet, "/api/v1/no-such-resource", "", nil)
+	resp.expect(http.StatusNotFound, "NOT_FOUND")
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Errorf("Content-Type = %q, want JSON", got)
+	}
+
+	var body errorBody
+	resp.decode(&body)
+	if body.Error.Message != "no route matches GET /api/v1/no-such-resource" {
+		t.Errorf("message = %q, want it to name the method and path", body.Error.Message)
+	}
+	if body.Error.RequestID == "" {
+		t.Error("the 404 has no request_id")
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	env := newTestEnv(t)
+
+	resp := env.do(http.MethodPost, "/api/v1/health", "", nil)
+	resp.expect(http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+	allow := resp.Header.Get("Allow")
+	if !strings.Contains(allow, http.MethodGet) || strings.Contains(allow, http.MethodPost) {
+		t.Errorf("Allow = %q, want GET and not POST", allow)
+	}
+
+	var body errorBody
+	resp.decode(&body)
+	if !strings.Contains(body.Error.Message, "POST is not allowed on /api/v1/health") || !strings.Contains(body.Error.Message, allow) {
+		t.Errorf("message = %q, want it to name the method and list %q", body.Error.Message, allow)
+	}
+}
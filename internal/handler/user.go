@@ -0,0 +1,580 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/i18n"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/pagination"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/query"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// createUserRequest is the request body for creating a user
+type createUserRequest struct {
+	Email    string      `json:"email" validate:"required,email"`
+	Name     string      `json:"name" validate:"required,max=100"`
+	Phone    string      `json:"phone" validate:"omitempty,phone"`
+	Password string      `json:"password" validate:"required,max=72"`
+	Role     domain.Role `json:"role" validate:"omitempty,oneof=user admin"`
+}
+
+// toInput converts the request into service input
+func (r createUserRequest) toInput() service.CreateUserInput {
+	return service.CreateUserInput{
+		Email:    r.Email,
+		Name:     r.Name,
+		Phone:    r.Phone,
+		Password: r.Password,
+		Role:     r.Role,
+	}
+}
+
+// updateUserRequest is the request body for updating a user; it replaces the
+// profile, so an omitted phone number is removed
+type updateUserRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Name  string `json:"name" validate:"required,max=100"`
+	Phone string `json:"phone" validate:"omitempty,phone"`
+	// Version, when set, rejects the update unless the user is still at it
+	Version *int `json:"version" validate:"omitempty,min=1"`
+}
+
+// patchUserRequest is the request body for patching a user or the
+// authenticated user; omitted fields are left unchanged
+type patchUserRequest struct {
+	Email *string `json:"email" validate:"omitempty,email"`
+	Name  *string `json:"name" validate:"omitempty,min=1,max=100"`
+	// Phone set to null or an empty string removes the number
+	Phone *string `json:"phone" validate:"omitempty,phone"`
+	// Version, when set, rejects the patch unless the user is still at it
+	Version *int `json:"version" validate:"omitempty,min=1"`
+}
+
+// profileUpdate converts the request into the service update, given the
+// fields the body named so an explicit null clears its field
+func (r patchUserRequest) profileUpdate(fields map[string]any) service.ProfileUpdate {
+	update := service.ProfileUpdate{Email: r.Email, Name: r.Name, Phone: r.Phone, Version: r.Version}
+	if value, ok := fields["phone"]; ok && value == nil {
+		update.Phone = new(string)
+	}
+
+	return update
+}
+
+// updateStatusRequest is the request body for suspending or reactivating a user
+type updateStatusRequest struct {
+	Status domain.UserStatus `json:"status" validate:"required,oneof=active suspended"`
+}
+
+// patchableFields are the fields a PATCH may change
+var patchableFields = map[string]bool{
+	"email": true,
+	"name":  true,
+	"phone": true,
+}
+
+// versionField names the version a PATCH may send alongside the fields it
+// changes, which does not count as a change itself
+const versionField = "version"
+
+// nullableFields are the patchable fields an explicit null clears
+var nullableFields = map[string]bool{
+	"phone": true,
+}
+
+// errEmptyPatch is returned for a PATCH body that names no field
+var errEmptyPatch = apperror.BadRequest("EMPTY_PATCH", "the request body must contain at least one field to change")
+
+// userCursorResponse is the keyset-paginated envelope returned by ListUsers
+type userCursorResponse struct {
+	// Data holds the users, or only their selected fields
+	Data       []any  `json:"data"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// userListFilters are the query parameters ListUsers accepts besides paging
+// and sorting; cursor, fields, and expand are read by ListUsers itself
+var userListFilters = []string{"q", "role", "email_verified", "include_deleted", "cursor", "fields", "expand"}
+
+// UserHandler serves the user resource endpoints
+type UserHandler struct {
+	users       *service.UserService
+	preferences *service.PreferencesService
+	auditLogs   repository.AuditLogRepository
+	// expansions are the related resources ?expand= may embed in users
+	expansions map[string]userExpansion
+	// exportTimeout bounds a streamed export; zero leaves it unbounded
+	exportTimeout time.Duration
+}
+
+// NewUserHandler creates a UserHandler backed by the given services. The
+// audit log supplies the last_login expansion.
+func NewUserHandler(
+	users *service.UserService, preferences *service.PreferencesService, auditLogs repository.AuditLogRepository,
+	exportTimeout time.Duration,
+) *UserHandler {
+	h := &UserHandler{users: users, preferences: preferences, auditLogs: auditLogs, exportTimeout: exportTimeout}
+	h.expansions = map[string]userExpansion{
+		"preferences": h.expandPreferences,
+		"last_login":  h.expandLastLogin,
+	}
+
+	return h
+}
+
+// CreateUser handler creates a new user
+func (h *UserHandler) CreateUser(c fiber.Ctx) error {
+	var input createUserRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	user, err := h.users.Create(c.Context(), input.toInput())
+	if err != nil {
+		return userError(err)
+	}
+
+	return respond(c.Status(fiber.StatusCreated), user)
+}
+
+// GetUser handler returns a single user by id
+func (h *UserHandler) GetUser(c fiber.Ctx) error {
+	id := c.Params("id")
+	if err := authorizeUserAccess(c, id, domain.PermissionUsersRead); err != nil {
+		return err
+	}
+
+	fields, err := parseFields(c, userFields)
+	if err != nil {
+		return err
+	}
+	expand, err := parseExpand(c, h.expansions)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.users.Get(c.Context(), id)
+	if err != nil {
+		return userError(err)
+	}
+
+	// The ETag versions the user alone, so an expanded response, which may
+	// change without it, is never answered with 304
+	etag := userETag(user)
+	c.Set(fiber.HeaderETag, etag)
+	if header := c.Get(fiber.HeaderIfNoneMatch); header != "" && len(expand) == 0 && etagMatches(header, etag, true) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return h.respondUser(c, user, fields, expand)
+}
+
+// ListUsers handler returns a page of users, using keyset pagination when a cursor is supplied
+func (h *UserHandler) ListUsers(c fiber.Ctx) error {
+	fields, err := parseFields(c, userFields)
+	if err != nil {
+		return err
+	}
+	expand, err := parseExpand(c, h.expansions)
+	if err != nil {
+		return err
+	}
+
+	if c.RequestCtx().QueryArgs().Has("cursor") {
+		return h.listUsersByCursor(c, fields, expand)
+	}
+
+	params, err := parseListParams(c)
+	if err != nil {
+		return err
+	}
+
+	users, total, err := h.users.List(c.Context(), params)
+	if err != nil {
+		return err
+	}
+	bodies, err := h.expandedUserBodies(c.Context(), users, fields, expand)
+	if err != nil {
+		return err
+	}
+
+	return respond(c, pagination.NewPagedResponse(bodies, params.Page, params.PageSize, total))
+}
+
+// listUsersByCursor returns the page of users following the cursor. Cursor
+// pages are always ordered by creation, so a sort is refused rather than
+// silently ignored.
+func (h *UserHandler) listUsersByCursor(c fiber.Ctx, fields, expand map[string]bool) error {
+	if c.RequestCtx().QueryArgs().Has("sort") {
+		return query.Invalid("sort cannot be combined with cursor, whose pages are ordered by created_at")
+	}
+	params, err := parseListParams(c)
+	if err != nil {
+		return err
+	}
+
+	var cursor *repository.Cursor
+	if value := c.Query("cursor"); value != "" {
+		cursor, err = decodeCursor(value)
+		if err != nil {
+			return apperror.BadRequest("INVALID_CURSOR", "invalid cursor")
+		}
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	users, err := h.users.ListAfter(c.Context(), params.Filter, cursor, params.PageSize+1)
+	if err != nil {
+		return err
+	}
+
+	var resp userCursorResponse
+	if len(users) > params.PageSize {
+		users = users[:params.PageSize]
+		resp.NextCursor = encodeCursor(users[params.PageSize-1])
+	}
+	resp.Data, err = h.expandedUserBodies(c.Context(), users, fields, expand)
+	if err != nil {
+		return err
+	}
+
+	return respond(c, resp)
+}
+
+// UpdateUser handler updates an existing user. The If-Match header must carry
+// the ETag from GetUser so concurrent edits cannot overwrite each other.
+func (h *UserHandler) UpdateUser(c fiber.Ctx) error {
+	ifMatch := c.Get(fiber.HeaderIfMatch)
+	if ifMatch == "" {
+		return apperror.PreconditionRequired("PRECONDITION_REQUIRED", "the If-Match header is required")
+	}
+
+	var input updateUserRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	id := c.Params("id")
+	if err := authorizeUserAccess(c, id, domain.PermissionUsersWrite); err != nil {
+		return err
+	}
+
+	user, err := h.users.UpdateProfile(c.Context(), id, service.ProfileUpdate{
+		Email:        &input.Email,
+		Name:         &input.Name,
+		Phone:        &input.Phone,
+		Version:      input.Version,
+		ConfirmEmail: confirmsEmailChange(c),
+		IfMatch: func(current *domain.User) bool {
+			return etagMatches(ifMatch, userETag(current), false)
+		},
+	})
+	if err != nil {
+		return userError(err)
+	}
+
+	c.Set(fiber.HeaderETag, userETag(user))
+	return respond(c, user)
+}
+
+// GetMe handler returns the authenticated user
+func (h *UserHandler) GetMe(c fiber.Ctx) error {
+	userID, _ := CurrentUserID(c)
+
+	fields, err := parseFields(c, userFields)
+	if err != nil {
+		return err
+	}
+	expand, err := parseExpand(c, h.expansions)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.users.Get(c.Context(), userID)
+	if err != nil {
+		return userError(err)
+	}
+
+	return h.respondUser(c, user, fields, expand)
+}
+
+// respondUser sends the user with its selected fields and expansions
+func (h *UserHandler) respondUser(c fiber.Ctx, user *domain.User, fields, expand map[string]bool) error {
+	bodies, err := h.expandedUserBodies(c.Context(), []*domain.User{user}, fields, expand)
+	if err != nil {
+		return err
+	}
+
+	return respond(c, bodies[0])
+}
+
+// PatchUser handler applies a partial update to a user's name, email, and
+// phone number. An If-Match header is optional here; when sent, the update
+// fails unless it carries the current ETag.
+func (h *UserHandler) PatchUser(c fiber.Ctx) error {
+	var input patchUserRequest
+	fields, err := decodePatch(c, &input)
+	if err != nil {
+		return err
+	}
+
+	id := c.Params("id")
+	if err := authorizeUserAccess(c, id, domain.PermissionUsersWrite); err != nil {
+		return err
+	}
+
+	update := input.profileUpdate(fields)
+	update.ConfirmEmail = confirmsEmailChange(c)
+	if ifMatch := c.Get(fiber.HeaderIfMatch); ifMatch != "" {
+		update.IfMatch = func(current *domain.User) bool {
+			return etagMatches(ifMatch, userETag(current), false)
+		}
+	}
+
+	user, err := h.users.UpdateProfile(c.Context(), id, update)
+	if err != nil {
+		return userError(err)
+	}
+
+	c.Set(fiber.HeaderETag, userETag(user))
+	return respond(c, user)
+}
+
+// UpdateMe handler applies a partial update to the authenticated user's name, email, and phone number
+func (h *UserHandler) UpdateMe(c fiber.Ctx) error {
+	var input patchUserRequest
+	fields, err := decodePatch(c, &input)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := CurrentUserID(c)
+	update := input.profileUpdate(fields)
+	update.ConfirmEmail = confirmsEmailChange(c)
+	user, err := h.users.UpdateProfile(c.Context(), userID, update)
+	if err != nil {
+		return userError(err)
+	}
+
+	return respond(c, user)
+}
+
+// decodePatch decodes a PATCH body into v, whose pointer fields stay nil for
+// omitted fields, and returns the fields the body names with nil for an
+// explicit null. It rejects an empty body, fields outside patchableFields,
+// and nulls for fields that cannot be cleared, then validates only the
+// fields present.
+func decodePatch(c fiber.Ctx, v any) (map[string]any, error) {
+	var fields map[string]any
+	if err := decodeBody(c, &fields); err != nil {
+		return nil, errInvalidBody
+	}
+	if _, ok := fields[versionField]; len(fields) == 0 || ok && len(fields) == 1 {
+		return nil, errEmptyPatch
+	}
+
+	var nulls []validation.FieldError
+	for field, value := range fields {
+		if !patchableFields[field] && field != versionField {
+			return nil, apperror.BadRequest("PROTECTED_FIELD", fmt.Sprintf("field %q cannot be changed", field))
+		}
+		if value == nil && !nullableFields[field] {
+			nulls = append(nulls, validation.NewFieldError(field, i18n.RuleNotNull))
+		}
+	}
+	if len(nulls) > 0 {
+		slices.SortFunc(nulls, func(a, b validation.FieldError) int {
+			return strings.Compare(a.Field, b.Field)
+		})
+		return nil, &validation.Error{Errors: nulls}
+	}
+
+	if err := decodeBody(c, v); err != nil {
+		return nil, errInvalidBody
+	}
+	if err := validation.Validate(v); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// DeleteUser handler soft-deletes a user by id
+func (h *UserHandler) DeleteUser(c fiber.Ctx) error {
+	if err := h.users.Delete(c.Context(), c.Params("id")); err != nil {
+		return userError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestoreUser handler brings back a soft-deleted user
+func (h *UserHandler) RestoreUser(c fiber.Ctx) error {
+	user, err := h.users.Restore(c.Context(), c.Params("id"))
+	if err != nil {
+		return userError(err)
+	}
+
+	return respond(c, user)
+}
+
+// UpdateUserStatus handler suspends or reactivates a user
+func (h *UserHandler) UpdateUserStatus(c fiber.Ctx) error {
+	var input updateStatusRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	id := c.Params("id")
+	// An admin suspending themselves would lose the access needed to undo it
+	if currentID, _ := CurrentUserID(c); currentID == id && input.Status == domain.StatusSuspended {
+		return apperror.BadRequest("CANNOT_SUSPEND_SELF", "you cannot suspend your own account")
+	}
+
+	user, err := h.users.SetStatus(c.Context(), id, input.Status)
+	if err != nil {
+		return userError(err)
+	}
+
+	return respond(c, user)
+}
+
+// authorizeUserAccess allows users holding the permission to access any user
+// and everyone else only themselves. API keys reach the handler only after
+// RequireScope admitted them.
+func authorizeUserAccess(c fiber.Ctx, id string, permission domain.Permission) error {
+	if HasPermission(c, permission) {
+		return nil
+	}
+	if _, ok := CurrentAPIKey(c); ok {
+		return nil
+	}
+	if userID, _ := CurrentUserID(c); userID == id {
+		return nil
+	}
+
+	return apperror.Forbidden(apperror.CodeForbidden, "cannot access another user")
+}
+
+// confirmsEmailChange reports whether an email change made by the caller
+// waits for the new address to be confirmed. Users who may write any user
+// and API keys are trusted to set addresses directly, as when they create
+// users.
+func confirmsEmailChange(c fiber.Ctx) bool {
+	if HasPermission(c, domain.PermissionUsersWrite) {
+		return false
+	}
+	_, apiKey := CurrentAPIKey(c)
+
+	return !apiKey
+}
+
+// userError translates repository errors into API errors
+func userError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrUserModified):
+		return apperror.PreconditionFailed("PRECONDITION_FAILED", "user was modified since it was read")
+	case errors.Is(err, repository.ErrUserNotFound):
+		return apperror.NotFound("USER_NOT_FOUND", "user not found")
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		return apperror.Conflict("EMAIL_EXISTS", "email already exists")
+	case errors.Is(err, repository.ErrDuplicatePhone):
+		return apperror.Conflict("PHONE_EXISTS", "phone number already exists")
+	case errors.Is(err, service.ErrInvalidPhone):
+		return apperror.UnprocessableEntity("INVALID_PHONE", "phone number must be in E.164 format")
+	}
+
+	return err
+}
+
+// parseListParams reads the paging, sorting, and filter query parameters,
+// rejecting any it does not know
+func parseListParams(c fiber.Ctx) (repository.ListParams, error) {
+	list, err := query.Parse(c, slices.Sorted(maps.Keys(repository.UserSortFields)), userListFilters)
+	if err != nil {
+		return repository.ListParams{}, err
+	}
+
+	params := repository.ListParams{
+		Page:     list.Page,
+		PageSize: list.PageSize,
+		Sort:     repository.SortByCreatedAt,
+		Desc:     list.Desc,
+	}
+	if list.Sort != "" {
+		params.Sort = list.Sort
+	}
+
+	params.Filter.Query = strings.TrimSpace(list.Filters["q"])
+
+	if value, ok := list.Filters["role"]; ok {
+		role := domain.Role(value)
+		if role != domain.RoleUser && role != domain.RoleAdmin && role != domain.RoleSuperAdmin {
+			return params, query.Invalid("unsupported role %q", value)
+		}
+		params.Filter.Role = role
+	}
+
+	if params.Filter.EmailVerified, err = list.Bool("email_verified"); err != nil {
+		return params, err
+	}
+
+	include, err := list.Bool("include_deleted")
+	if err != nil {
+		return params, err
+	}
+	params.Filter.IncludeDeleted = include != nil && *include
+
+	return params, nil
+}
+
+// encodeCursor returns the opaque cursor pointing at the given user
+func encodeCursor(user *domain.User) string {
+	raw := user.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + user.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses an opaque cursor produced by encodeCursor
+func decodeCursor(value string) (*repository.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := uuid.Validate(id); err != nil {
+		return nil, err
+	}
+
+	return &repository.Cursor{CreatedAt: t, ID: id}, nil
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// maxBulkUsers caps the number of users accepted by a single bulk request
+const maxBulkUsers = 500
+
+// maxBulkDeleteUsers caps the number of ids accepted by a single bulk delete
+const maxBulkDeleteUsers = 200
+
+// Outcomes reported for each item of a bulk request
+const (
+	bulkStatusCreated    = "created"
+	bulkStatusFailed     = "failed"
+	bulkStatusRolledBack = "rolled_back"
+)
+
+// bulkItemResult reports the outcome for one user of a bulk request
+type bulkItemResult struct {
+	Index  int          `json:"index"`
+	Status string       `json:"status"`
+	User   *domain.User `json:"user,omitempty"`
+	Error  *errorDetail `json:"error,omitempty"`
+}
+
+// bulkSummary counts the outcomes of a bulk request
+type bulkSummary struct {
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	Failed  int `json:"failed"`
+}
+
+// bulkCreateResponse is returned by BulkCreateUsers
+type bulkCreateResponse struct {
+	Summary bulkSummary      `json:"summary"`
+	Results []bulkItemResult `json:"results"`
+}
+
+// BulkCreateUsers handler creates many users in one transaction, reporting
+// the outcome of each. With ?atomic=true a single failure stores none of them.
+func (h *UserHandler) BulkCreateUsers(c fiber.Ctx) error {
+	atomic := false
+	if value := c.Query("atomic"); value != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(value); err != nil {
+			return apperror.BadRequest("INVALID_QUERY", "atomic must be a boolean")
+		}
+	}
+
+	var inputs []createUserRequest
+	if err := decodeBody(c, &inputs); err != nil {
+		return errInvalidBody
+	}
+	if len(inputs) == 0 {
+		return apperror.BadRequest("EMPTY_BATCH", "at least one user is required")
+	}
+	if len(inputs) > maxBulkUsers {
+		return apperror.BadRequest("BATCH_TOO_LARGE", fmt.Sprintf("at most %d users can be created at once", maxBulkUsers))
+	}
+
+	results := make([]bulkItemResult, len(inputs))
+	var (
+		valid   []int
+		invalid int
+	)
+	for i, input := range inputs {
+		results[i].Index = i
+		if err := validation.Validate(input); err != nil {
+			results[i].fail(err)
+			invalid++
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	resp := bulkCreateResponse{
+		Summary: bulkSummary{Total: len(inputs), Failed: invalid},
+		Results: results,
+	}
+
+	// An atomic batch with invalid items would be rolled back anyway
+	if len(valid) > 0 && !(atomic && invalid > 0) {
+		batch := make([]service.CreateUserInput, len(valid))
+		for j, i := range valid {
+			batch[j] = inputs[i].toInput()
+		}
+
+		users, errs, err := h.users.CreateBatch(c.Context(), batch, atomic)
+		if err != nil {
+			return err
+		}
+
+		rolledBack := atomic && hasError(errs)
+		for j, i := range valid {
+			switch {
+			case errs[j] != nil:
+				results[i].fail(userError(errs[j]))
+				resp.Summary.Failed++
+			case rolledBack:
+				results[i].Status = bulkStatusRolledBack
+			default:
+				results[i].Status = bulkStatusCreated
+				results[i].User = users[j]
+				resp.Summary.Created++
+			}
+		}
+	} else {
+		for _, i := range valid {
+			results[i].Status = bulkStatusRolledBack
+		}
+	}
+
+	status := fiber.StatusMultiStatus
+	if resp.Summary.Created == resp.Summary.Total {
+		status = fiber.StatusCreated
+	}
+
+	return respond(c.Status(status), resp)
+}
+
+// bulkDeleteRequest is the request body for deleting many users
+type bulkDeleteRequest struct {
+	IDs []string `json:"ids" validate:"dive,uuid"`
+}
+
+// bulkDeleteResponse reports which ids of a bulk delete were deleted, which
+// matched no active user, and which were skipped because they are the caller
+type bulkDeleteResponse struct {
+	Deleted  []string `json:"deleted"`
+	NotFound []string `json:"not_found"`
+	Skipped  []string `json:"skipped"`
+}
+
+// BulkDeleteUsers handler soft-deletes many users in one transaction. The
+// caller's own account is never deleted and is reported as skipped instead.
+func (h *UserHandler) BulkDeleteUsers(c fiber.Ctx) error {
+	var input bulkDeleteRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+	if len(input.IDs) == 0 {
+		return apperror.BadRequest("EMPTY_BATCH", "at least one id is required")
+	}
+	if len(input.IDs) > maxBulkDeleteUsers {
+		return apperror.BadRequest("BATCH_TOO_LARGE", fmt.Sprintf("at most %d users can be deleted at once", maxBulkDeleteUsers))
+	}
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	resp := bulkDeleteResponse{Deleted: []string{}, NotFound: []string{}, Skipped: []string{}}
+	currentID, _ := CurrentUserID(c)
+	var ids []string
+	for _, id := range input.IDs {
+		id = strings.ToLower(id)
+		switch {
+		case slices.Contains(ids, id) || slices.Contains(resp.Skipped, id):
+		case id == currentID:
+			resp.Skipped = append(resp.Skipped, id)
+		default:
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) > 0 {
+		deleted, err := h.users.DeleteBatch(c.Context(), ids)
+		if err != nil {
+			return err
+		}
+		resp.Deleted = deleted
+		for _, id := range ids {
+			if !slices.Contains(deleted, id) {
+				resp.NotFound = append(resp.NotFound, id)
+			}
+		}
+	}
+
+	return respond(c, resp)
+}
+
+// fail records err as the outcome of the item
+func (r *bulkItemResult) fail(err error) {
+	apiErr := toAPIError(err)
+	r.Status = bulkStatusFailed
+	r.Error = &errorDetail{
+		Code:    apiErr.Code,
+		Message: apiErr.Message,
+		Details: apiErr.Details,
+	}
+}
+
+// hasError reports whether any of the errors is non-nil
+func hasError(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return true
+		}
+	}
+
+	return false
+}
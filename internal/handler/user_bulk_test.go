@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// bulkUser is one item of a bulk create request
+func bulkUser(email string) map[string]string {
+	return map[string]string{"email": email, "name": "Test User", "password": testPassword}
+}
+
+// statusesOf returns the outcome of each item, in order
+func statusesOf(results []bulkItemResult) []string {
+	statuses := make([]string, len(results))
+	for i, result := range results {
+		statuses[i] = result.Status
+		if result.Error != nil {
+			statuses[i] += ":" + result.Error.Code
+		}
+	}
+
+	return statuses
+}
+
+func TestBulkCreateUsers(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	var body bulkCreateResponse
+	resp := env.do(http.MethodPost, "/api/v1/users/bulk", admin, []any{bulkUser("ada@example.com"), bulkUser("grace@example.com")})
+	resp.expect(http.StatusCreated)
+	resp.decode(&body)
+	if body.Summary != (bulkSummary{Total: 2, Created: 2}) {
+		t.Errorf("summary = %+v, want both created", body.Summary)
+	}
+	for _, result := range body.Results {
+		if result.User == nil || result.User.ID == "" {
+			t.Errorf("result %d = %+v, want the created user", result.Index, result)
+		}
+	}
+	if _, err := env.users.GetByEmail(t.Context(), "grace@example.com"); err != nil {
+		t.Errorf("GetByEmail: %v", err)
+	}
+}
+
+func TestBulkCreateUsersReportsEachFailure(t *testing.T) {
+	batch := []any{
+		bulkUser("ada@example.com"),
+		bulkUser("taken@example.com"),
+		bulkUser("not-an-email"),
+		bulkUser("ada@example.com"),
+		bulkUser("grace@example.com"),
+	}
+	tests := []struct {
+		query    string
+		created  int
+		statuses []string
+	}{
+		{"", 2, []string{"created", "failed:EMAIL_EXISTS", "failed:VALIDATION_ERROR", "failed:EMAIL_EXISTS", "created"}},
+		{"?atomic=true", 0, []string{"rolled_back", "rolled_back", "failed:VALIDATION_ERROR", "rolled_back", "rolled_back"}},
+	}
+	for _, tt := range tests {
+		t.Run("query="+tt.query, func(t *testing.T) {
+			env := newTestEnv(t)
+			admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+			env.createUser("taken@example.com")
+
+			var body bulkCreateResponse
+			resp := env.do(http.MethodPost, "/api/v1/users/bulk"+tt.query, admin, batch)
+			resp.expect(http.StatusMultiStatus)
+			resp.decode(&body)
+			if got := statusesOf(body.Results); fmt.Sprint(got) != fmt.Sprint(tt.statuses) {
+				t.Errorf("results = %v, want %v", got, tt.statuses)
+			}
+			if body.Summary.Created != tt.created || body.Summary.Failed+body.Summary.Created > body.Summary.Total {
+				t.Errorf("summary = %+v, want %d created", body.Summary, tt.created)
+			}
+			if _, err := env.users.GetByEmail(t.Context(), "grace@example.com"); (err == nil) != (tt.created > 0) {
+				t.Errorf("GetByEmail of a valid item error = %v, want it stored only when the batch was", err)
+			}
+		})
+	}
+}
+
+func TestBulkCreateUsersRejectsOversizedBatch(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	batch := make([]any, maxBulkUsers+1)
+	for i := range batch {
+		batch[i] = bulkUser(fmt.Sprintf("user%d@example.com", i))
+	}
+	env.do(http.MethodPost, "/api/v1/users/bulk", admin, batch).expect(http.StatusBadRequest, "BATCH_TOO_LARGE")
+	env.do(http.MethodPost, "/api/v1/users/bulk", admin, []any{}).expect(http.StatusBadRequest, "EMPTY_BATCH")
+}
+
+func TestBulkDeleteUsers(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	token := env.tokenFor(admin)
+	ada := env.createUser("ada@example.com")
+	grace := env.createUser("grace@example.com")
+	gone := env.createUser("gone@example.com")
+	env.do(http.MethodDelete, "/api/v1/users/"+gone.ID, token, nil).expect(http.StatusNoContent)
+	missing := "00000000-0000-4000-8000-000000000000"
+
+	var result bulkDeleteResponse
+	resp := env.do(http.MethodDelete, "/api/v1/users", token, map[string]any{
+		"ids": []string{ada.ID, missing, admin.ID, grace.ID, gone.ID, ada.ID},
+	})
+	resp.expect(http.StatusOK)
+	resp.decode(&result)
+
+	if got, want := fmt.Sprint(result.Deleted), fmt.Sprint([]string{ada.ID, grace.ID}); got != want {
+		t.Errorf("deleted = %v, want %v", got, want)
+	}
+	if got, want := fmt.Sprint(result.NotFound), fmt.Sprint([]string{missing, gone.ID}); got != want {
+		t.Errorf("not_found = %v, want %v", got, want)
+	}
+	if got, want := fmt.Sprint(result.Skipped), fmt.Sprint([]string{admin.ID}); got != want {
+		t.Errorf("skipped = %v, want the caller %v", got, want)
+	}
+
+	// The deletes are soft, as with the single-user route
+	env.do(http.MethodGet, "/api/v1/users/"+ada.ID, token, nil).expect(http.StatusNotFound, "USER_NOT_FOUND")
+	env.do(http.MethodGet, "/api/v1/me", token, nil).expect(http.StatusOK)
+	env.do(http.MethodPost, "/api/v1/users/"+grace.ID+"/restore", token, nil).expect(http.StatusOK)
+}
+
+func TestBulkDeleteUsersRejectsBadBatches(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	ids := make([]string, maxBulkDeleteUsers+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("00000000-0000-4000-8000-%012d", i)
+	}
+	env.do(http.MethodDelete, "/api/v1/users", admin, map[string]any{"ids": ids}).expect(http.StatusBadRequest, "BATCH_TOO_LARGE")
+	env.do(http.MethodDelete, "/api/v1/users", admin, map[string]any{"ids": []string{}}).expect(http.StatusBadRequest, "EMPTY_BATCH")
+	env.do(http.MethodDelete, "/api/v1/users", admin, map[string]any{"ids": []string{"not-a-uuid"}}).expect(http.StatusUnprocessableEntity)
+	env.do(http.MethodDelete, "/api/v1/users", env.tokenFor(env.createUser("ada@example.com")), map[string]any{"ids": ids[:1]}).
+		expect(http.StatusForbidden)
+}
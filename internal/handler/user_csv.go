@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// csvColumns is the column order of exported files
+var csvColumns = []string{"id", "email", "name", "phone", "roles", "status", "email_verified_at", "created_at", "updated_at"}
+
+// csvImportColumns are the columns an import may contain. Exported read-only
+// columns are accepted and ignored so an export can be imported again; role
+// sets the only role of an imported user.
+var csvImportColumns = map[string]bool{
+	"id":                false,
+	"email":             true,
+	"name":              true,
+	"phone":             false,
+	"role":              false,
+	"roles":             false,
+	"status":            false,
+	"password":          false,
+	"email_verified_at": false,
+	"created_at":        false,
+	"updated_at":        false,
+}
+
+// exportPageSize is the number of users fetched per query while exporting
+const exportPageSize = 500
+
+// csvImportError reports why a row of an import was rejected
+type csvImportError struct {
+	Line  int         `json:"line"`
+	Error errorDetail `json:"error"`
+}
+
+// csvImportResponse is returned by ImportUsers
+type csvImportResponse struct {
+	Summary bulkSummary      `json:"summary"`
+	Errors  []csvImportError `json:"errors"`
+}
+
+// importUserRequest is a user read from an import row; unlike
+// createUserRequest the password is optional
+type importUserRequest struct {
+	Email    string      `json:"email" validate:"required,email"`
+	Name     string      `json:"name" validate:"required,max=100"`
+	Phone    string      `json:"phone" validate:"omitempty,phone"`
+	Password string      `json:"password" validate:"omitempty,max=72"`
+	Role     domain.Role `json:"role" validate:"omitempty,oneof=user admin"`
+}
+
+// csvRow is a parsed import row along with its line in the file
+type csvRow struct {
+	line  int
+	input importUserRequest
+}
+
+// ExportUsers handler streams every active user as CSV
+func (h *UserHandler) ExportUsers(c fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Attachment(fmt.Sprintf("users-%s.csv", time.Now().UTC().Format("20060102")))
+
+	return h.streamExport(c, h.writeUsersCSV)
+}
+
+// streamExport streams the body write produces once the handler has
+// returned. The request context is done by then, so write gets one of its
+// own, which lasts at most exportTimeout and ends as soon as write returns;
+// a failed write or flush means the client went away, so no further page is
+// queried.
+func (h *UserHandler) streamExport(c fiber.Ctx, write func(ctx context.Context, w *bufio.Writer) error) error {
+	// The writer runs after the handler returns, so it must not use c
+	ctx, cancel := context.WithCancel(context.WithoutCancel(c.Context()))
+	if h.exportTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(c.Context()), h.exportTimeout)
+	}
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		if err := write(ctx, w); err != nil {
+			// The status line has already been sent, so the file is just truncated
+			logger.FromContext(ctx).Error("user export failed", slog.Any("error", err))
+		}
+	})
+}
+
+// writeUsersCSV writes the header and every active user, one page at a
+// time, flushing each page to the client
+func (h *UserHandler) writeUsersCSV(ctx context.Context, w *bufio.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return err
+	}
+
+	return h.eachExportPage(ctx, func(users []*domain.User) error {
+		for _, user := range users {
+			if err := cw.Write(userCSVRecord(user)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+
+		return w.Flush()
+	})
+}
+
+// eachExportPage calls fn with every active user, one keyset page at a time,
+// so an export holds a single page in memory however many users there are.
+// It stops at the first error fn returns.
+func (h *UserHandler) eachExportPage(ctx context.Context, fn func(users []*domain.User) error) error {
+	var cursor *repository.Cursor
+	for {
+		users, err := h.users.ListAfter(ctx, repository.UserFilter{}, cursor, exportPageSize)
+		if err != nil {
+			return err
+		}
+		if err := fn(users); err != nil {
+			return err
+		}
+
+		if len(users) < exportPageSize {
+			return nil
+		}
+		last := users[len(users)-1]
+		cursor = &repository.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// userCSVRecord formats a user in csvColumns order
+func userCSVRecord(user *domain.User) []string {
+	var verifiedAt string
+	if user.EmailVerifiedAt != nil {
+		verifiedAt = user.EmailVerifiedAt.UTC().Format(time.RFC3339)
+	}
+
+	return []string{
+		user.ID,
+		user.Email,
+		user.Name,
+		user.Phone,
+		joinRoles(user.Roles),
+		string(user.Status),
+		verifiedAt,
+		user.CreatedAt.UTC().Format(time.RFC3339),
+		user.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// joinRoles lists the roles separated by spaces
+func joinRoles(roles domain.Roles) string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+
+	return strings.Join(names, " ")
+}
+
+// ImportUsers handler creates users from an uploaded CSV file, reporting
+// rejected rows by line number. Rows without a password get a random one, so
+// those users sign in through the password reset flow. With ?atomic=true any
+// rejected row stores none of the users.
+func (h *UserHandler) ImportUsers(c fiber.Ctx) error {
+	atomic := false
+	if value := c.Query("atomic"); value != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(value); err != nil {
+			return apperror.BadRequest("INVALID_QUERY", "atomic must be a boolean")
+		}
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return apperror.BadRequest("MISSING_FILE", "a CSV file is required in the file field")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+	reader.FieldsPerRecord = -1
+	columns, err := readCSVHeader(reader)
+	if err != nil {
+		return err
+	}
+
+	resp := csvImportResponse{Errors: make([]csvImportError, 0)}
+	reject := func(line int, err error) {
+		apiErr := toAPIError(err)
+		resp.Errors = append(resp.Errors, csvImportError{
+			Line:  line,
+			Error: errorDetail{Code: apiErr.Code, Message: apiErr.Message, Details: apiErr.Details},
+		})
+		resp.Summary.Failed++
+	}
+
+	// Rows are read one at a time and created in batches; an atomic import has
+	// to hold every row so the whole file goes through a single transaction
+	var batch []csvRow
+	flush := func() error {
+		created, err := h.createCSVRows(c.Context(), batch, atomic, reject)
+		resp.Summary.Created += created
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		resp.Summary.Total++
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			reject(parseErr.Line, apperror.BadRequest("MALFORMED_ROW", parseErr.Err.Error()))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := csvRow{line: line, input: csvRowInput(columns, record)}
+		if err := validation.Validate(row.input); err != nil {
+			reject(line, err)
+			continue
+		}
+
+		batch = append(batch, row)
+		if !atomic && len(batch) == maxBulkUsers {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if atomic && resp.Summary.Failed > 0 {
+		batch = nil
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	slices.SortFunc(resp.Errors, func(a, b csvImportError) int { return a.Line - b.Line })
+
+	status := fiber.StatusMultiStatus
+	if resp.Summary.Created == resp.Summary.Total {
+		status = fiber.StatusCreated
+	}
+
+	return respond(c.Status(status), resp)
+}
+
+// createCSVRows stores a batch of validated rows and returns how many were created
+func (h *UserHandler) createCSVRows(ctx context.Context, rows []csvRow, atomic bool, reject func(int, error)) (int, error) {
+	inputs := make([]service.CreateUserInput, len(rows))
+	for i, row := range rows {
+		inputs[i] = service.CreateUserInput{
+			Email:    row.input.Email,
+			Name:     row.input.Name,
+			Phone:    row.input.Phone,
+			Password: row.input.Password,
+			Role:     row.input.Role,
+		}
+	}
+
+	_, errs, err := h.users.CreateBatch(ctx, inputs, atomic)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for i, err := range errs {
+		if err != nil {
+			reject(rows[i].line, userError(err))
+			continue
+		}
+		created++
+	}
+	if atomic && created < len(rows) {
+		created = 0
+	}
+
+	return created, nil
+}
+
+// readCSVHeader reads the header row and returns the position of each column
+func readCSVHeader(reader *csv.Reader) (map[string]int, error) {
+	record, err := reader.Read()
+	if err != nil {
+		return nil, apperror.BadRequest("INVALID_CSV", "file must start with a header row")
+	}
+
+	columns := make(map[string]int, len(record))
+	for i, name := range record {
+		if _, known := csvImportColumns[name]; !known {
+			return nil, apperror.BadRequest("INVALID_CSV", fmt.Sprintf("unexpected column %q", name))
+		}
+		if _, seen := columns[name]; seen {
+			return nil, apperror.BadRequest("INVALID_CSV", fmt.Sprintf("duplicate column %q", name))
+		}
+		columns[name] = i
+	}
+
+	for name, required := range csvImportColumns {
+		if _, ok := columns[name]; required && !ok {
+			return nil, apperror.BadRequest("INVALID_CSV", fmt.Sprintf("missing column %q", name))
+		}
+	}
+
+	return columns, nil
+}
+
+// csvRowInput maps a record onto an import request using the header positions
+func csvRowInput(columns map[string]int, record []string) importUserRequest {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	return importUserRequest{
+		Email:    field("email"),
+		Name:     field("name"),
+		Phone:    field("phone"),
+		Role:     domain.Role(field("role")),
+		Password: field("password"),
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+)
+
+// importCSV uploads data to the import endpoint as the given admin
+func (e *testEnv) importCSV(token, query, data string) *testResponse {
+	e.t.Helper()
+
+	req := newUploadRequest(http.MethodPost, "/api/v1/users/import"+query, "file", "users.csv", "text/csv", []byte(data))
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+
+	return e.send(req)
+}
+
+func TestUsersCSVRoundTrip(t *testing.T) {
+	source := newTestEnv(t)
+	admin := source.tokenFor(source.createUser("admin@example.com", domain.RoleAdmin))
+	source.createUser("ada@example.com")
+	source.createUser("grace@example.com")
+
+	resp := source.do(http.MethodGet, "/api/v1/users/export.csv", admin, nil)
+	resp.expect(http.StatusOK)
+	if got := resp.Header.Get(fiber.HeaderContentDisposition); !strings.HasPrefix(got, "attachment") || !strings.Contains(got, ".csv") {
+		t.Errorf("Content-Disposition = %q, want a CSV attachment", got)
+	}
+	records, err := csv.NewReader(strings.NewReader(string(resp.body))).ReadAll()
+	if err != nil {
+		t.Fatalf("parse export: %v", err)
+	}
+	if !slices.Equal(records[0], csvColumns) {
+		t.Errorf("header = %v, want %v", records[0], csvColumns)
+	}
+	if len(records) != 4 {
+		t.Fatalf("export has %d rows, want a header and 3 users", len(records))
+	}
+
+	target := newTestEnv(t)
+	targetAdmin := target.tokenFor(target.createUser("root@example.com", domain.RoleAdmin))
+	var body csvImportResponse
+	resp = target.importCSV(targetAdmin, "", string(resp.body))
+	resp.expect(http.StatusCreated)
+	resp.decode(&body)
+	if body.Summary != (bulkSummary{Total: 3, Created: 3}) {
+		t.Errorf("import summary = %+v, want all 3 created; errors %+v", body.Summary, body.Errors)
+	}
+	for _, record := range records[1:] {
+		user, err := target.users.GetByEmail(t.Context(), record[1])
+		if err != nil {
+			t.Errorf("GetByEmail(%s): %v", record[1], err)
+			continue
+		}
+		if user.Name != record[2] {
+			t.Errorf("imported name = %q, want %q", user.Name, record[2])
+		}
+	}
+}
+
+func TestImportUsersReportsBadRows(t *testing.T) {
+	const file = "email,name\n" +
+		"ada@example.com,Ada\n" +
+		"not-an-email,Nobody\n" +
+		"bad@example.com,\"Unterminated \"quote\"\n" +
+		"grace@example.com,Grace\n"
+
+	tests := []struct {
+		query   string
+		status  int
+		created int
+	}{
+		{"", http.StatusMultiStatus, 2},
+		{"?atomic=true", http.StatusMultiStatus, 0},
+	}
+	for _, tt := range tests {
+		t.Run("query="+tt.query, func(t *testing.T) {
+			env := newTestEnv(t)
+			admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+			var body csvImportResponse
+			resp := env.importCSV(admin, tt.query, file)
+			resp.expect(tt.status)
+			resp.decode(&body)
+			if body.Summary.Total != 4 || body.Summary.Created != tt.created {
+				t.Errorf("summary = %+v, want 4 rows with %d created", body.Summary, tt.created)
+			}
+
+			var lines []string
+			for _, rowErr := range body.Errors {
+				lines = append(lines, fmt.Sprintf("%d:%s", rowErr.Line, rowErr.Error.Code))
+			}
+			if want := []string{"3:VALIDATION_ERROR", "4:MALFORMED_ROW"}; !slices.Equal(lines, want) {
+				t.Errorf("row errors = %v, want %v", lines, want)
+			}
+			if _, err := env.users.GetByEmail(t.Context(), "grace@example.com"); (err == nil) != (tt.created > 0) {
+				t.Errorf("GetByEmail of a valid row error = %v, want it stored only when the import was", err)
+			}
+		})
+	}
+}
+
+func TestImportUsersRejectsUnexpectedHeader(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	env.importCSV(admin, "", "email,name,is_admin\nada@example.com,Ada,true\n").expect(http.StatusBadRequest, "INVALID_CSV")
+	env.importCSV(admin, "", "name\nAda\n").expect(http.StatusBadRequest, "INVALID_CSV")
+}
+
+func TestStreamExportTimeout(t *testing.T) {
+	h := &UserHandler{exportTimeout: 50 * time.Millisecond}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	// The request timeout is over before the streamed body is written
+	app.Use(Timeout(time.Millisecond))
+	started := make(chan error, 1)
+	ended := make(chan error, 1)
+	app.Get("/export", func(c fiber.Ctx) error {
+		return h.streamExport(c, func(ctx context.Context, w *bufio.Writer) error {
+			time.Sleep(5 * time.Millisecond)
+			started <- ctx.Err()
+			w.WriteString("header\n")
+			w.Flush()
+			<-ctx.Done()
+			ended <- ctx.Err()
+			return ctx.Err()
+		})
+	})
+	env := &testEnv{t: t, app: app}
+
+	resp := env.do(http.MethodGet, "/export", "", nil)
+	resp.expect(http.StatusOK)
+	if string(resp.body) != "header\n" {
+		t.Errorf("body = %q, want the part written before the deadline", resp.body)
+	}
+	if err := <-started; err != nil {
+		t.Errorf("export context error at start = %v, want it to outlive the request timeout", err)
+	}
+	if err := <-ended; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("export context error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
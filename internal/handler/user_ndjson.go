@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/utils/v2"
+)
+
+// MIMENDJSON is the media type of newline-delimited JSON
+const MIMENDJSON = "application/x-ndjson"
+
+// ExportUsersNDJSON handler streams every active user as one JSON object
+// per line, flushing after each page so clients can process users as they
+// arrive
+func (h *UserHandler) ExportUsersNDJSON(c fiber.Ctx) error {
+	// Attachment guesses the type from the extension, which it does not know
+	c.Attachment(fmt.Sprintf("users-%s.ndjson", time.Now().UTC().Format("20060102")))
+	c.Set(fiber.HeaderContentType, MIMENDJSON)
+
+	// Read before streaming: the writer must not touch c
+	encode := c.App().Config().JSONEncoder
+
+	return h.streamExport(c, func(ctx context.Context, w *bufio.Writer) error {
+		return h.writeUsersNDJSON(ctx, w, encode)
+	})
+}
+
+// writeUsersNDJSON writes every active user as a line of JSON, one page at
+// a time, encoding with the app's configured JSON encoder
+func (h *UserHandler) writeUsersNDJSON(ctx context.Context, w *bufio.Writer, encode utils.JSONMarshal) error {
+	return h.eachExportPage(ctx, func(users []*domain.User) error {
+		for _, user := range users {
+			line, err := encode(user)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+			if err := w.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+
+		return w.Flush()
+	})
+}
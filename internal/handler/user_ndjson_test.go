@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+)
+
+// createUsers stores n users without passwords, which is quicker than
+// createUser for filling several export pages
+func (e *testEnv) createUsers(n int) map[string]bool {
+	e.t.Helper()
+
+	ids := make(map[string]bool, n)
+	for i := range n {
+		user := &domain.User{
+			Email:  fmt.Sprintf("user%d@example.com", i),
+			Name:   fmt.Sprintf("User %d", i),
+			Roles:  domain.Roles{domain.RoleUser},
+			Status: domain.StatusActive,
+		}
+		if err := e.users.Create(e.t.Context(), user); err != nil {
+			e.t.Fatalf("create user %s: %v", user.Email, err)
+		}
+		ids[user.ID] = true
+	}
+
+	return ids
+}
+
+func TestExportUsersNDJSON(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	ids := env.createUsers(2*exportPageSize + 1)
+	ids[admin.ID] = true
+
+	resp := env.do(http.MethodGet, "/api/v1/users/export.ndjson", env.tokenFor(admin), nil)
+	resp.expect(http.StatusOK)
+	if got := resp.Header.Get(fiber.HeaderContentType); got != MIMENDJSON {
+		t.Errorf("Content-Type = %q, want %q", got, MIMENDJSON)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentDisposition); !strings.Contains(got, ".ndjson") {
+		t.Errorf("Content-Disposition = %q, want an NDJSON attachment", got)
+	}
+
+	seen := make(map[string]bool, len(ids))
+	scanner := bufio.NewScanner(bytes.NewReader(resp.body))
+	for line := 1; scanner.Scan(); line++ {
+		var user map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &user); err != nil {
+			t.Fatalf("line %d is not JSON: %v: %q", line, err, scanner.Text())
+		}
+		if _, ok := user["password_hash"]; ok {
+			t.Errorf("line %d exposes the password hash", line)
+		}
+		id, _ := user["id"].(string)
+		if !ids[id] || seen[id] {
+			t.Errorf("line %d holds unexpected or repeated user %q", line, id)
+		}
+		seen[id] = true
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	if len(seen) != len(ids) {
+		t.Errorf("export has %d users, want %d", len(seen), len(ids))
+	}
+}
+
+func TestExportUsersNDJSONStreams(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	env.createUsers(exportPageSize + 1)
+
+	// app.Test buffers the whole response, so the app serves a real connection
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = env.app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+	t.Cleanup(func() { _ = env.app.Shutdown() })
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/api/v1/users/export.ndjson", nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+admin)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET export: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// A buffered body would have been sent with its length
+	if resp.ContentLength != -1 || len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("Content-Length %d, Transfer-Encoding %v, want a chunked stream", resp.ContentLength, resp.TransferEncoding)
+	}
+	lines := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if !json.Valid(scanner.Bytes()) {
+			t.Fatalf("line %d is not JSON: %q", lines+1, scanner.Text())
+		}
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	if lines != exportPageSize+2 {
+		t.Errorf("export has %d lines, want %d", lines, exportPageSize+2)
+	}
+}
@@ -0,0 +1,391 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/pagination"
+)
+
+// userPage is a page of ListUsers
+type userPage = pagination.PagedResponse[domain.User]
+
+// emailsOf returns the emails of the users, in order
+func emailsOf(users []domain.User) []string {
+	emails := make([]string, len(users))
+	for i, user := range users {
+		emails[i] = user.Email
+	}
+
+	return emails
+}
+
+func TestListUsersPagination(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	for i := range 24 {
+		env.createUser(fmt.Sprintf("user%02d@example.com", i))
+	}
+	token := env.tokenFor(admin)
+
+	t.Run("default page", func(t *testing.T) {
+		resp := env.do(http.MethodGet, "/api/v1/users", token, nil)
+		resp.expect(http.StatusOK)
+
+		var page userPage
+		resp.decode(&page)
+		if len(page.Data) != 20 || page.Page != 1 || page.PageSize != 20 || page.TotalCount != 25 || page.TotalPages != 2 {
+			t.Errorf("page = %d users, page %d of %d, size %d, total %d; want 20 users, page 1 of 2, size 20, total 25",
+				len(page.Data), page.Page, page.TotalPages, page.PageSize, page.TotalCount)
+		}
+		if !page.HasNext || page.HasPrev {
+			t.Errorf("has_next = %t, has_prev = %t; want true, false", page.HasNext, page.HasPrev)
+		}
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		resp := env.do(http.MethodGet, "/api/v1/users?page=2", token, nil)
+		resp.expect(http.StatusOK)
+
+		var page userPage
+		resp.decode(&page)
+		if len(page.Data) != 5 || page.HasNext || !page.HasPrev {
+			t.Errorf("page 2 = %d users, has_next %t, has_prev %t; want 5 users, false, true", len(page.Data), page.HasNext, page.HasPrev)
+		}
+	})
+
+	t.Run("out of range page", func(t *testing.T) {
+		resp := env.do(http.MethodGet, "/api/v1/users?page=9", token, nil)
+		resp.expect(http.StatusOK)
+		if !strings.Contains(string(resp.body), `"data":[]`) {
+			t.Errorf("body = %s, want an empty data array", resp.body)
+		}
+
+		var page userPage
+		resp.decode(&page)
+		if page.TotalCount != 25 || page.HasNext {
+			t.Errorf("total = %d, has_next = %t; want 25, false", page.TotalCount, page.HasNext)
+		}
+	})
+
+	t.Run("sorted descending", func(t *testing.T) {
+		resp := env.do(http.MethodGet, "/api/v1/users?sort=-email&page_size=3", token, nil)
+		resp.expect(http.StatusOK)
+
+		var page userPage
+		resp.decode(&page)
+		want := []string{"user23@example.com", "user22@example.com", "user21@example.com"}
+		if got := emailsOf(page.Data); !slices.Equal(got, want) {
+			t.Errorf("emails = %v, want %v", got, want)
+		}
+	})
+
+	for _, query := range []string{"sort=password", "sort=-", "page=0", "page_size=many"} {
+		t.Run("invalid "+query, func(t *testing.T) {
+			env.do(http.MethodGet, "/api/v1/users?"+query, token, nil).expect(http.StatusBadRequest, "INVALID_QUERY")
+		})
+	}
+}
+
+func TestListUsersCursorIsStableAcrossInserts(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	seen := map[string]int{admin.Email: 0}
+	for i := range 9 {
+		env.createUser(fmt.Sprintf("user%d@example.com", i))
+	}
+	token := env.tokenFor(admin)
+
+	var emails []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("cursor pagination did not end")
+		}
+		resp := env.do(http.MethodGet, "/api/v1/users?page_size=4&cursor="+cursor, token, nil)
+		resp.expect(http.StatusOK)
+
+		var page struct {
+			Data       []domain.User `json:"data"`
+			NextCursor string        `json:"next_cursor"`
+		}
+		resp.decode(&page)
+		emails = append(emails, emailsOf(page.Data)...)
+
+		// Users created mid-iteration sort after every existing one
+		if pages == 0 {
+			for i := range 3 {
+				env.createUser(fmt.Sprintf("late%d@example.com", i))
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	for _, email := range emails {
+		seen[email]++
+	}
+	for email, count := range seen {
+		if count != 1 {
+			t.Errorf("%s was listed %d times, want once", email, count)
+		}
+	}
+	if len(emails) != 13 {
+		t.Fatalf("listed %d users, want all 13: %v", len(emails), emails)
+	}
+	if got := emails[10:]; !slices.Equal(got, []string{"late0@example.com", "late1@example.com", "late2@example.com"}) {
+		t.Errorf("last users = %v, want the ones created during iteration", got)
+	}
+}
+
+func TestListUsersRejectsBadCursor(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	env.do(http.MethodGet, "/api/v1/users?cursor=not-a-cursor", token, nil).expect(http.StatusBadRequest, "INVALID_CURSOR")
+}
+
+func TestListUsersRejectsSortWithCursor(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	for _, path := range []string{"/api/v1/users?cursor=&sort=email", "/api/v1/users?sort=-created_at&cursor="} {
+		env.do(http.MethodGet, path, token, nil).expect(http.StatusBadRequest, "INVALID_QUERY")
+	}
+	// Either one alone is fine
+	env.do(http.MethodGet, "/api/v1/users?cursor=", token, nil).expect(http.StatusOK)
+	env.do(http.MethodGet, "/api/v1/users?sort=email", token, nil).expect(http.StatusOK)
+}
+
+func TestAdminRoutesRequireAdmin(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+
+	userToken := env.tokenFor(user)
+	env.do(http.MethodGet, "/api/v1/users", userToken, nil).expect(http.StatusForbidden, apperror.CodeForbidden)
+	env.do(http.MethodDelete, "/api/v1/users/"+admin.ID, userToken, nil).expect(http.StatusForbidden, apperror.CodeForbidden)
+
+	adminToken := env.tokenFor(admin)
+	env.do(http.MethodGet, "/api/v1/users", adminToken, nil).expect(http.StatusOK)
+	env.do(http.MethodDelete, "/api/v1/users/"+user.ID, adminToken, nil).expect(http.StatusNoContent)
+}
+
+func TestMe(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	token := env.tokenFor(user)
+
+	var me domain.User
+	resp := env.do(http.MethodGet, "/api/v1/me", token, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&me)
+	if me.ID != user.ID || me.Email != user.Email {
+		t.Errorf("GET /me = %+v, want the caller", me)
+	}
+
+	resp = env.do(http.MethodPatch, "/api/v1/me", token, map[string]any{"name": "Ada Lovelace"})
+	resp.expect(http.StatusOK)
+	resp.decode(&me)
+	if me.Name != "Ada Lovelace" {
+		t.Errorf("name after PATCH /me = %q, want %q", me.Name, "Ada Lovelace")
+	}
+
+	for _, field := range []string{"roles", "id"} {
+		resp := env.do(http.MethodPatch, "/api/v1/me", token, map[string]any{"name": "Ada", field: "admin"})
+		resp.expect(http.StatusBadRequest, "PROTECTED_FIELD")
+	}
+	stored, err := env.users.GetByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Name != "Ada Lovelace" || !slices.Equal(stored.Roles, domain.Roles{domain.RoleUser}) {
+		t.Errorf("stored user = %+v, want the rejected patches not applied", stored)
+	}
+}
+
+func TestUserOwnership(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("ada@example.com")
+	other := env.createUser("grace@example.com")
+	token := env.tokenFor(user)
+
+	env.do(http.MethodGet, "/api/v1/users/"+user.ID, token, nil).expect(http.StatusOK)
+	env.do(http.MethodPatch, "/api/v1/users/"+user.ID, token, map[string]any{"name": "Ada Lovelace"}).expect(http.StatusOK)
+
+	env.do(http.MethodGet, "/api/v1/users/"+other.ID, token, nil).expect(http.StatusForbidden, apperror.CodeForbidden)
+	env.do(http.MethodPatch, "/api/v1/users/"+other.ID, token, map[string]any{"name": "Taken Over"}).expect(http.StatusForbidden, apperror.CodeForbidden)
+
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	env.do(http.MethodGet, "/api/v1/users/"+other.ID, admin, nil).expect(http.StatusOK)
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	user := env.createUser("ada@example.com")
+
+	env.do(http.MethodDelete, "/api/v1/users/"+user.ID, admin, nil).expect(http.StatusNoContent)
+	env.do(http.MethodGet, "/api/v1/users/"+user.ID, admin, nil).expect(http.StatusNotFound, "USER_NOT_FOUND")
+
+	var page userPage
+	env.do(http.MethodGet, "/api/v1/users", admin, nil).decode(&page)
+	if got := emailsOf(page.Data); slices.Contains(got, "ada@example.com") {
+		t.Errorf("list = %v, want the deleted user hidden", got)
+	}
+	env.do(http.MethodGet, "/api/v1/users?include_deleted=true", admin, nil).decode(&page)
+	if got := emailsOf(page.Data); !slices.Contains(got, "ada@example.com") {
+		t.Errorf("list with include_deleted = %v, want the deleted user", got)
+	}
+
+	var restored domain.User
+	resp := env.do(http.MethodPost, "/api/v1/users/"+user.ID+"/restore", admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&restored)
+	if restored.DeletedAt != nil {
+		t.Errorf("restored user has deleted_at %v", restored.DeletedAt)
+	}
+	env.do(http.MethodGet, "/api/v1/users/"+user.ID, admin, nil).expect(http.StatusOK)
+	env.do(http.MethodPost, "/api/v1/users/"+user.ID+"/restore", env.tokenFor(user), nil).expect(http.StatusForbidden)
+}
+
+func TestListUsersSearchAndFilters(t *testing.T) {
+	env := newTestEnv(t)
+	token := env.tokenFor(env.createUser("root@example.com", domain.RoleAdmin))
+	env.createUser("ada.lovelace@acme.io", domain.RoleAdmin)
+	env.createUser("grace.hopper@acme.io")
+	env.createUser("alan@other.org", domain.RoleAdmin)
+	linus := env.createUser("linus@acme.io", domain.RoleAdmin)
+	now := time.Now().UTC()
+	linus.Name = "Linus"
+	linus.EmailVerifiedAt = &now
+	if err := env.users.Update(t.Context(), linus); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"q=ACME", []string{"ada.lovelace@acme.io", "grace.hopper@acme.io", "linus@acme.io"}},
+		{"q=hopper", []string{"grace.hopper@acme.io"}},
+		{"q=linus", []string{"linus@acme.io"}},
+		{"role=admin", []string{"ada.lovelace@acme.io", "alan@other.org", "linus@acme.io", "root@example.com"}},
+		{"q=acme&role=admin", []string{"ada.lovelace@acme.io", "linus@acme.io"}},
+		{"q=acme&role=admin&email_verified=true", []string{"linus@acme.io"}},
+		{"q=acme&role=admin&page_size=1&page=2", []string{"linus@acme.io"}},
+		{"q=nobody", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var page userPage
+			resp := env.do(http.MethodGet, "/api/v1/users?sort=email&"+tt.query, token, nil)
+			resp.expect(http.StatusOK)
+			resp.decode(&page)
+			if got := emailsOf(page.Data); !slices.Equal(got, tt.want) {
+				t.Errorf("emails = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var page userPage
+	env.do(http.MethodGet, "/api/v1/users?q=acme&role=admin&page_size=1", token, nil).decode(&page)
+	if page.TotalCount != 2 || !page.HasNext {
+		t.Errorf("filtered page total = %d, has_next = %t; want 2, true", page.TotalCount, page.HasNext)
+	}
+
+	for _, query := range []string{"status=active", "role=owner", "email_verified=maybe"} {
+		env.do(http.MethodGet, "/api/v1/users?"+query, token, nil).expect(http.StatusBadRequest, "INVALID_QUERY")
+	}
+}
+
+func TestUserPhone(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	body := bulkUser("ada@example.com")
+	body["phone"] = "+1 (555) 123-4567"
+	var created domain.User
+	resp := env.do(http.MethodPost, "/api/v1/users", admin, body)
+	resp.expect(http.StatusCreated)
+	resp.decode(&created)
+	if created.Phone != "+15551234567" {
+		t.Errorf("phone = %q, want it stored as E.164", created.Phone)
+	}
+
+	// The same number in another formatting is taken
+	other := bulkUser("grace@example.com")
+	other["phone"] = "+15551234567"
+	env.do(http.MethodPost, "/api/v1/users", admin, other).expect(http.StatusConflict, "PHONE_EXISTS")
+
+	for _, phone := range []string{"555-123-4567", "+1 555 CALL NOW", "+0123456789"} {
+		other["phone"] = phone
+		env.do(http.MethodPost, "/api/v1/users", admin, other).expect(http.StatusUnprocessableEntity, apperror.CodeValidation)
+	}
+
+	token := env.tokenFor(env.createUser("grace@example.com"))
+	var updated domain.User
+	resp = env.do(http.MethodPatch, "/api/v1/me", token, map[string]any{"phone": "+44 20 7946 0958"})
+	resp.expect(http.StatusOK)
+	resp.decode(&updated)
+	if updated.Phone != "+442079460958" {
+		t.Errorf("phone after update = %q, want %q", updated.Phone, "+442079460958")
+	}
+	env.do(http.MethodPatch, "/api/v1/me", token, map[string]any{"phone": "12345"}).expect(http.StatusUnprocessableEntity, apperror.CodeValidation)
+
+	var cleared domain.User
+	resp = env.do(http.MethodPatch, "/api/v1/me", token, map[string]any{"phone": nil})
+	resp.expect(http.StatusOK)
+	resp.decode(&cleared)
+	if cleared.Phone != "" {
+		t.Errorf("phone after removal = %q, want none", cleared.Phone)
+	}
+}
+
+func TestSuspendUser(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser("admin@example.com", domain.RoleAdmin)
+	adminToken := env.tokenFor(admin)
+	user := env.createUser("ada@example.com")
+	session := env.sessionFor(user)
+	sessionless, err := env.tokens.GenerateToken(user.ID, user.Roles, "", time.Hour)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	statusPath := "/api/v1/users/" + user.ID + "/status"
+
+	env.do(http.MethodPatch, statusPath, session.AccessToken, map[string]string{"status": "suspended"}).expect(http.StatusForbidden)
+	env.do(http.MethodPatch, statusPath, adminToken, map[string]string{"status": "banned"}).expect(http.StatusUnprocessableEntity, apperror.CodeValidation)
+	env.do(http.MethodPatch, "/api/v1/users/"+admin.ID+"/status", adminToken, map[string]string{"status": "suspended"}).
+		expect(http.StatusBadRequest, "CANNOT_SUSPEND_SELF")
+
+	var suspended domain.User
+	resp := env.do(http.MethodPatch, statusPath, adminToken, map[string]string{"status": "suspended"})
+	resp.expect(http.StatusOK)
+	resp.decode(&suspended)
+	if suspended.Status != domain.StatusSuspended {
+		t.Errorf("status = %q, want %q", suspended.Status, domain.StatusSuspended)
+	}
+
+	// Suspending ends the sessions, and tokens outside one are checked
+	// against the status on every request
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusForbidden, "ACCOUNT_SUSPENDED")
+	env.do(http.MethodGet, "/api/v1/me", session.AccessToken, nil).expect(http.StatusUnauthorized, "TOKEN_REVOKED")
+	env.do(http.MethodPost, "/api/v1/auth/refresh", "", refreshBody(session.RefreshToken)).expect(http.StatusUnauthorized)
+	env.do(http.MethodGet, "/api/v1/me", sessionless, nil).expect(http.StatusForbidden, "ACCOUNT_SUSPENDED")
+
+	// Suspension is not deletion: the user is still listed
+	env.do(http.MethodGet, "/api/v1/users/"+user.ID, adminToken, nil).expect(http.StatusOK)
+
+	env.do(http.MethodPatch, statusPath, adminToken, map[string]string{"status": "active"}).expect(http.StatusOK)
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+	env.do(http.MethodGet, "/api/v1/me", sessionless, nil).expect(http.StatusOK)
+}
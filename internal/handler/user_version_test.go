@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/gofiber/fiber/v3"
+)
+
+// userUpdate is a PUT /api/v1/users/:id body at the given version
+func userUpdate(name string, version int) map[string]any {
+	return map[string]any{"email": "ada@example.com", "name": name, "version": version}
+}
+
+// putAnyMatch sends a PUT with If-Match: *, leaving the version to decide
+// whether the update applies
+func (e *testEnv) putAnyMatch(path, token string, body any) *testResponse {
+	e.t.Helper()
+
+	return e.conditional(http.MethodPut, path, token, body, fiber.HeaderIfMatch, "*")
+}
+
+func TestVersionedUpdate(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	ada := env.createUser("ada@example.com")
+	path := "/api/v1/users/" + ada.ID
+
+	var updated domain.User
+	resp := env.putAnyMatch(path, admin, userUpdate("Ada Lovelace", ada.Version))
+	resp.expect(http.StatusOK)
+	resp.decode(&updated)
+	if updated.Version != ada.Version+1 || updated.Name != "Ada Lovelace" {
+		t.Fatalf("updated user at version %d named %q, want version %d", updated.Version, updated.Name, ada.Version+1)
+	}
+
+	// A patch at the new version goes through and increments it again
+	var patched domain.User
+	resp = env.do(http.MethodPatch, path, admin, map[string]any{"name": "Countess", "version": updated.Version})
+	resp.expect(http.StatusOK)
+	resp.decode(&patched)
+	if patched.Version != updated.Version+1 {
+		t.Errorf("patched version = %d, want %d", patched.Version, updated.Version+1)
+	}
+}
+
+func TestStaleVersionConflicts(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+	ada := env.createUser("ada@example.com")
+	path := "/api/v1/users/" + ada.ID
+
+	// Two clients read the same version, and the second write loses
+	env.putAnyMatch(path, admin, userUpdate("First", ada.Version)).expect(http.StatusOK)
+	env.putAnyMatch(path, admin, userUpdate("Second", ada.Version)).expect(http.StatusConflict, "VERSION_CONFLICT")
+	env.do(http.MethodPatch, path, admin, map[string]any{"name": "Third", "version": ada.Version}).expect(http.StatusConflict, "VERSION_CONFLICT")
+	token := env.tokenFor(ada)
+	env.do(http.MethodPatch, "/api/v1/me", token, map[string]any{"name": "Fourth", "version": ada.Version}).expect(http.StatusConflict, "VERSION_CONFLICT")
+
+	var stored domain.User
+	resp := env.do(http.MethodGet, path, admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&stored)
+	if stored.Name != "First" || stored.Version != ada.Version+1 {
+		t.Errorf("stored user named %q at version %d, want the first write only", stored.Name, stored.Version)
+	}
+
+	// Without a version the write applies to whatever is stored
+	env.do(http.MethodPatch, path, admin, map[string]any{"name": "Unversioned"}).expect(http.StatusOK)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// resendVerificationRequest is the request body for requesting a new verification link
+type resendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// VerifyEmail handler consumes a verification token and marks the user's email as verified
+func (h *AuthHandler) VerifyEmail(c fiber.Ctx) error {
+	if err := h.users.VerifyEmail(c.Context(), c.Query("token")); err != nil {
+		return authError(err)
+	}
+
+	return respond(c, fiber.Map{"message": "email verified"})
+}
+
+// ConfirmEmailChange handler consumes an email change token and moves the
+// user to the new address
+func (h *AuthHandler) ConfirmEmailChange(c fiber.Ctx) error {
+	if _, err := h.users.ConfirmEmailChange(c.Context(), c.Query("token")); err != nil {
+		return authError(err)
+	}
+
+	return respond(c, fiber.Map{"message": "email changed"})
+}
+
+// ResendVerification handler sends a new verification link; it responds the
+// same way whether or not the email belongs to an unverified account
+func (h *AuthHandler) ResendVerification(c fiber.Ctx) error {
+	var input resendVerificationRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	if err := h.users.ResendVerification(c.Context(), input.Email); err != nil {
+		return err
+	}
+
+	return respond(c, fiber.Map{"message": "if the account exists and is unverified, a verification email has been sent"})
+}
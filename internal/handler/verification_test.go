@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+)
+
+// verifyPath is the verification link for token
+func verifyPath(token string) string {
+	return "/api/v1/auth/verify?token=" + url.QueryEscape(token)
+}
+
+func TestRegisterSendsOneVerificationEmail(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.do(http.MethodPost, "/api/v1/auth/register", "", registerBody("ada@example.com")).expect(http.StatusCreated)
+
+	messages := env.mail.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("sent %d emails, want 1: %+v", len(messages), messages)
+	}
+	if messages[0].To != "ada@example.com" || messages[0].Template != mailer.TemplateVerifyEmail {
+		t.Errorf("sent %s email to %s, want %s to ada@example.com", messages[0].Template, messages[0].To, mailer.TemplateVerifyEmail)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Auth.RequireEmailVerification = true })
+
+	var created registerResponse
+	resp := env.do(http.MethodPost, "/api/v1/auth/register", "", registerBody("ada@example.com"))
+	resp.expect(http.StatusCreated)
+	resp.decode(&created)
+	if created.Tokens != nil {
+		t.Error("register issued tokens before the email was verified")
+	}
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusForbidden, "EMAIL_NOT_VERIFIED")
+
+	token := env.mailedToken("ada@example.com", mailer.TemplateVerifyEmail)
+	env.do(http.MethodGet, verifyPath(token), "", nil).expect(http.StatusOK)
+
+	stored, err := env.users.GetByID(t.Context(), created.User.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.EmailVerifiedAt == nil {
+		t.Error("email_verified_at is not set after verification")
+	}
+	env.do(http.MethodPost, "/api/v1/auth/login", "", loginBody("ada@example.com", testPassword)).expect(http.StatusOK)
+
+	// The token is single-use
+	env.do(http.MethodGet, verifyPath(token), "", nil).expect(http.StatusBadRequest, "INVALID_VERIFICATION_TOKEN")
+}
+
+func TestVerifyEmailRejectsExpiredToken(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.Auth.EmailVerificationTTL = time.Millisecond })
+
+	env.do(http.MethodPost, "/api/v1/auth/register", "", registerBody("ada@example.com")).expect(http.StatusCreated)
+	token := env.mailedToken("ada@example.com", mailer.TemplateVerifyEmail)
+	time.Sleep(5 * time.Millisecond)
+
+	env.do(http.MethodGet, verifyPath(token), "", nil).expect(http.StatusBadRequest, "INVALID_VERIFICATION_TOKEN")
+}
+
+func TestResendVerification(t *testing.T) {
+	env := newTestEnv(t)
+	env.do(http.MethodPost, "/api/v1/auth/register", "", registerBody("ada@example.com")).expect(http.StatusCreated)
+	first := env.mailedToken("ada@example.com", mailer.TemplateVerifyEmail)
+
+	env.do(http.MethodPost, "/api/v1/auth/resend-verification", "", map[string]string{"email": "ada@example.com"}).expect(http.StatusOK)
+	// Unknown emails get the same answer
+	env.do(http.MethodPost, "/api/v1/auth/resend-verification", "", map[string]string{"email": "nobody@example.com"}).expect(http.StatusOK)
+
+	second := env.mailedToken("ada@example.com", mailer.TemplateVerifyEmail)
+	if second == first {
+		t.Fatal("resend-verification did not send a new token")
+	}
+	env.do(http.MethodGet, verifyPath(second), "", nil).expect(http.StatusOK)
+	if got := env.mail.MessagesTo("nobody@example.com"); len(got) != 0 {
+		t.Errorf("sent %d emails to an unknown address, want none", len(got))
+	}
+}
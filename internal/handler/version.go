@@ -0,0 +1,11 @@
+package handler
+
+import (
+	"github.com/boonyarit-iamsaard/user-management-api/internal/buildinfo"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Version handler returns the metadata of the running build
+func Version(c fiber.Ctx) error {
+	return respond(c, buildinfo.Get())
+}
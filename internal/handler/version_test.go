@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	env := newTestEnv(t)
+
+	var body map[string]string
+	resp := env.do(http.MethodGet, "/api/v1/version", "", nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&body)
+
+	// Test binaries are built without ldflags or version control details
+	want := map[string]string{
+		"version":    "dev",
+		"commit":     "unknown",
+		"build_time": "unknown",
+		"go_version": runtime.Version(),
+	}
+	if len(body) != len(want) {
+		t.Errorf("version = %v, want the keys of %v", body, want)
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("%s = %q, want %q", key, body[key], value)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/service"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// webhookDeliveryLimit is how many recent deliveries ListWebhookDeliveries returns
+const webhookDeliveryLimit = 100
+
+// createWebhookRequest is the request body for registering a webhook
+type createWebhookRequest struct {
+	URL    string                `json:"url" validate:"required,http_url,max=2048"`
+	Events []domain.WebhookEvent `json:"events" validate:"required,min=1,dive,oneof=user.created user.updated user.deleted"`
+}
+
+// createWebhookResponse is the registered webhook along with its signing secret, which is only ever returned here
+type createWebhookResponse struct {
+	*domain.Webhook
+	Secret string `json:"secret"`
+}
+
+// webhookListResponse is the envelope returned by ListWebhooks
+type webhookListResponse struct {
+	Data []*domain.Webhook `json:"data"`
+}
+
+// webhookDeliveryListResponse is the envelope returned by ListWebhookDeliveries
+type webhookDeliveryListResponse struct {
+	Data []*domain.WebhookDelivery `json:"data"`
+}
+
+// WebhookHandler serves the webhook management endpoints
+type WebhookHandler struct {
+	webhooks *service.WebhookService
+}
+
+// NewWebhookHandler creates a WebhookHandler backed by the given service
+func NewWebhookHandler(webhooks *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+// CreateWebhook handler registers an endpoint for user lifecycle events
+func (h *WebhookHandler) CreateWebhook(c fiber.Ctx) error {
+	var input createWebhookRequest
+	if err := decodeBody(c, &input); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Validate(input); err != nil {
+		return err
+	}
+
+	webhook, err := h.webhooks.Create(c.Context(), input.URL, input.Events)
+	if err != nil {
+		return err
+	}
+
+	return respond(c.Status(fiber.StatusCreated), createWebhookResponse{Webhook: webhook, Secret: webhook.Secret})
+}
+
+// ListWebhooks handler returns every webhook without its secret
+func (h *WebhookHandler) ListWebhooks(c fiber.Ctx) error {
+	webhooks, err := h.webhooks.List(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return respond(c, webhookListResponse{Data: webhooks})
+}
+
+// ListWebhookDeliveries handler returns the most recent deliveries of a webhook
+func (h *WebhookHandler) ListWebhookDeliveries(c fiber.Ctx) error {
+	deliveries, err := h.webhooks.ListDeliveries(c.Context(), c.Params("id"), webhookDeliveryLimit)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return apperror.NotFound("WEBHOOK_NOT_FOUND", "webhook not found")
+		}
+		return err
+	}
+
+	return respond(c, webhookDeliveryListResponse{Data: deliveries})
+}
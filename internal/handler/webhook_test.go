@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+)
+
+func TestWebhookDeliveredOnUserCreation(t *testing.T) {
+	type delivery struct {
+		signature string
+		body      []byte
+	}
+	received := make(chan delivery, 1)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{signature: r.Header.Get(webhook.HeaderSignature), body: body}
+	}))
+	defer endpoint.Close()
+
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Webhook.OutboxPollInterval = 5 * time.Millisecond
+	})
+	admin := env.tokenFor(env.createUser("admin@example.com", domain.RoleAdmin))
+
+	var registered createWebhookResponse
+	resp := env.do(http.MethodPost, "/api/v1/webhooks", admin, map[string]any{
+		"url":    endpoint.URL,
+		"events": []string{"user.created"},
+	})
+	resp.expect(http.StatusCreated)
+	resp.decode(&registered)
+	if registered.Secret == "" {
+		t.Fatal("registration did not return the signing secret")
+	}
+
+	var listed struct {
+		Data []map[string]any `json:"data"`
+	}
+	resp = env.do(http.MethodGet, "/api/v1/webhooks", admin, nil)
+	resp.expect(http.StatusOK)
+	resp.decode(&listed)
+	if len(listed.Data) != 1 || listed.Data[0]["secret"] != nil {
+		t.Errorf("webhooks = %s, want the webhook without its secret", resp.body)
+	}
+
+	env.do(http.MethodPost, "/api/v1/users", admin, bulkUser("ada@example.com")).expect(http.StatusCreated)
+
+	var got delivery
+	select {
+	case got = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery arrived")
+	}
+	if got.signature != webhook.Sign(registered.Secret, got.body) {
+		t.Errorf("%s = %q, want the HMAC of the body with the webhook secret", webhook.HeaderSignature, got.signature)
+	}
+	var event struct {
+		Type domain.WebhookEvent `json:"type"`
+		Data struct {
+			Email string `json:"email"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(got.body, &event); err != nil {
+		t.Fatalf("decode delivery %s: %v", got.body, err)
+	}
+	if event.Type != domain.EventUserCreated || event.Data.Email != "ada@example.com" {
+		t.Errorf("delivery = %s, want user.created for ada@example.com", got.body)
+	}
+
+	env.do(http.MethodGet, "/api/v1/webhooks", env.tokenFor(env.createUser("user@example.com")), nil).expect(http.StatusForbidden)
+}
@@ -0,0 +1,93 @@
+package i18n
+
+// spanishErrors are the Spanish messages of the API error codes. Codes
+// raised with details in their English message, such as the name of an
+// unknown field, get a message that stands without them.
+var spanishErrors = map[string]string{
+	// Generic codes
+	"BAD_REQUEST":              "la solicitud no es válida",
+	"UNAUTHORIZED":             "se requiere autenticación",
+	"FORBIDDEN":                "no tienes permiso para realizar esta acción",
+	"NOT_FOUND":                "el recurso no existe",
+	"METHOD_NOT_ALLOWED":       "el método no está permitido en esta ruta",
+	"NOT_ACCEPTABLE":           "los formatos de respuesta admitidos son application/json y application/msgpack",
+	"CONFLICT":                 "el cambio entra en conflicto con otro recurso",
+	"VALIDATION_ERROR":         "la validación de la solicitud ha fallado",
+	"TOO_MANY_REQUESTS":        "demasiadas solicitudes",
+	"REQUEST_ENTITY_TOO_LARGE": "el cuerpo de la solicitud es demasiado grande",
+	"INTERNAL_ERROR":           "error interno del servidor",
+	"REQUEST_TIMEOUT":          "la solicitud ha excedido el tiempo de espera",
+	"SERVICE_UNAVAILABLE":      "el servicio no está disponible temporalmente, inténtalo de nuevo",
+
+	// Authentication
+	"MISSING_TOKEN":              "falta el token de portador o está mal formado",
+	"INVALID_TOKEN":              "el token no es válido o ha caducado",
+	"TOKEN_REVOKED":              "el token ha sido revocado",
+	"MISSING_API_KEY":            "falta la clave de API",
+	"INVALID_API_KEY":            "la clave de API no es válida o ha sido revocada",
+	"INSUFFICIENT_SCOPE":         "la clave de API no tiene el alcance necesario",
+	"INVALID_CREDENTIALS":        "correo electrónico o contraseña incorrectos",
+	"ACCOUNT_LOCKED":             "la cuenta está bloqueada temporalmente por repetidos inicios de sesión fallidos",
+	"ACCOUNT_SUSPENDED":          "la cuenta está suspendida",
+	"LOGIN_THROTTLED":            "demasiados inicios de sesión fallidos para este correo electrónico, inténtalo más tarde",
+	"EMAIL_NOT_VERIFIED":         "la dirección de correo electrónico no ha sido verificada",
+	"INVALID_REFRESH_TOKEN":      "el token de actualización no es válido o ha caducado",
+	"INVALID_MFA_TOKEN":          "el token de MFA no es válido o ha caducado",
+	"INVALID_TWO_FACTOR_CODE":    "el código de dos factores no es válido",
+	"TWO_FACTOR_ALREADY_ENABLED": "la autenticación de dos factores ya está activada",
+	"TWO_FACTOR_NOT_PENDING":     "llama al endpoint de activación antes de confirmar la autenticación de dos factores",
+	"INVALID_PASSWORD":           "la contraseña actual es incorrecta",
+	"PASSWORD_UNCHANGED":         "la nueva contraseña debe ser distinta de la actual",
+	"WEAK_PASSWORD":              "la contraseña no cumple la política de contraseñas",
+	"INVALID_VERIFICATION_TOKEN": "el token de verificación no es válido o ha caducado",
+	"INVALID_RESET_TOKEN":        "el token de restablecimiento no es válido o ha caducado",
+	"OAUTH_PROVIDER_NOT_FOUND":   "el proveedor de OAuth no existe o está desactivado",
+	"INVALID_OAUTH_STATE":        "el estado de OAuth no es válido o ha caducado",
+	"OAUTH_DENIED":               "el proveedor de identidad no concedió el acceso",
+	"OAUTH_EXCHANGE_FAILED":      "no se pudo completar el inicio de sesión con el proveedor de identidad",
+	"OAUTH_EMAIL_NOT_VERIFIED":   "el proveedor de identidad no ha verificado tu dirección de correo electrónico",
+	"SESSION_NOT_FOUND":          "la sesión no existe",
+	"IMPERSONATE_SELF":           "no puedes suplantarte a ti mismo",
+	"IMPERSONATE_ADMIN":          "solo los superadministradores pueden suplantar a administradores",
+	"ALREADY_IMPERSONATING":      "no puedes iniciar una suplantación mientras suplantas a otro usuario",
+
+	// Users
+	"USER_NOT_FOUND":        "el usuario no existe",
+	"EMAIL_EXISTS":          "el correo electrónico ya existe",
+	"PHONE_EXISTS":          "el número de teléfono ya existe",
+	"INVALID_PHONE":         "el número de teléfono debe estar en formato E.164",
+	"INVALID_LOCALE":        "la configuración regional debe ser una etiqueta de idioma BCP 47",
+	"INVALID_TIMEZONE":      "la zona horaria debe ser una zona horaria IANA",
+	"ROLE_NOT_ASSIGNABLE":   "el rol super_admin solo se concede en la base de datos",
+	"UNKNOWN_ROLE":          "uno de los roles no existe",
+	"CANNOT_SUSPEND_SELF":   "no puedes suspender tu propia cuenta",
+	"PRECONDITION_REQUIRED": "la cabecera If-Match es obligatoria",
+	"PRECONDITION_FAILED":   "el usuario se ha modificado desde que se leyó",
+	"VERSION_CONFLICT":      "el usuario se ha modificado desde que se leyó; vuelva a cargarlo e inténtelo de nuevo",
+	"EMPTY_PATCH":           "el cuerpo de la solicitud debe contener al menos un campo que cambiar",
+	"PROTECTED_FIELD":       "la solicitud intenta cambiar un campo que no se puede cambiar",
+	"EMPTY_BATCH":           "el lote debe contener al menos un elemento",
+	"BATCH_TOO_LARGE":       "el lote contiene demasiados elementos",
+	"INVALID_CSV":           "el archivo CSV no tiene las columnas esperadas",
+	"MALFORMED_ROW":         "la fila del archivo CSV está mal formada",
+	"MISSING_FILE":          "falta el archivo en el campo file",
+	"AVATAR_TOO_LARGE":      "el archivo del avatar es demasiado grande",
+	"AVATAR_DIMENSIONS":     "el avatar supera el ancho o el alto máximos",
+	"UNSUPPORTED_IMAGE":     "el avatar debe ser una imagen PNG, JPEG o WebP",
+
+	// Requests
+	"INVALID_BODY":                "el cuerpo de la solicitud no es válido",
+	"INVALID_HOST":                "este servidor no atiende el host solicitado",
+	"INVALID_QUERY":               "un parámetro de consulta no es válido",
+	"INVALID_CURSOR":              "el cursor no es válido",
+	"INVALID_FIELDS":              "se ha solicitado un campo desconocido",
+	"INVALID_EXPAND":              "se ha solicitado una expansión desconocida",
+	"INVALID_FEATURE_FLAGS":       "la cabecera X-Feature-Flags no es válida",
+	"INVALID_IDEMPOTENCY_KEY":     "la clave de idempotencia debe tener como máximo 255 caracteres",
+	"IDEMPOTENCY_KEY_IN_PROGRESS": "todavía se está procesando una solicitud con esta clave de idempotencia",
+	"IDEMPOTENCY_KEY_REUSED":      "la clave de idempotencia ya se usó con un cuerpo de solicitud distinto",
+
+	// Resources
+	"API_KEY_NOT_FOUND": "la clave de API no existe",
+	"WEBHOOK_NOT_FOUND": "el webhook no existe",
+}
@@ -0,0 +1,90 @@
+package i18n
+
+import (
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+// Supported languages; English comes first, making it the fallback
+var (
+	English = language.English
+	Spanish = language.Spanish
+)
+
+// supported lists the languages with catalogs, in the order the matcher
+// prefers them on ties
+var supported = []language.Tag{English, Spanish}
+
+// matcher picks the supported language closest to the requested ones
+var matcher = language.NewMatcher(supported)
+
+// catalog holds the messages of one language
+type catalog struct {
+	// errors are keyed by error code
+	errors map[string]string
+	// rules are keyed by validation rule and may take fmt arguments
+	rules map[string]string
+}
+
+// catalogs holds the catalog of every supported language
+var catalogs = map[language.Tag]catalog{
+	English: {rules: englishRules},
+	Spanish: {errors: spanishErrors, rules: spanishRules},
+}
+
+// Negotiate returns the supported language that best matches an
+// Accept-Language header, weighing its quality values, or English when the
+// header is missing, malformed, or names no supported language
+func Negotiate(acceptLanguage string) language.Tag {
+	if acceptLanguage == "" {
+		return English
+	}
+
+	tags, weights, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return English
+	}
+
+	// q=0 marks a language as not acceptable
+	acceptable := make([]language.Tag, 0, len(tags))
+	for i, tag := range tags {
+		if weights[i] > 0 {
+			acceptable = append(acceptable, tag)
+		}
+	}
+	if len(acceptable) == 0 {
+		return English
+	}
+
+	_, index, confidence := matcher.Match(acceptable...)
+	if confidence == language.No {
+		return English
+	}
+
+	return supported[index]
+}
+
+// Error returns the message of an error code in lang. English has no error
+// catalog, as errors are raised with their English message, so it reports
+// false there and for codes lang does not translate; callers then keep the
+// English message.
+func Error(lang language.Tag, code string) (string, bool) {
+	message, ok := catalogs[lang].errors[code]
+	return message, ok
+}
+
+// Rule returns the message of a failed validation rule in lang, falling back
+// to English, with args filling its placeholders. Unknown rules are described
+// by name.
+func Rule(lang language.Tag, rule string, args ...any) string {
+	format, ok := catalogs[lang].rules[rule]
+	if !ok {
+		format, ok = englishRules[rule]
+	}
+	if !ok {
+		return Rule(lang, RuleUnknown, rule)
+	}
+
+	return fmt.Sprintf(format, args...)
+}
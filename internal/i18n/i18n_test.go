@@ -0,0 +1,84 @@
+package i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   language.Tag
+	}{
+		{"missing", "", English},
+		{"malformed", "es;q=high", English},
+		{"unsupported", "de-DE, fr;q=0.8", English},
+		{"exact", "es", Spanish},
+		{"regional variant", "es-MX", Spanish},
+		{"wildcard", "*", English},
+		{"quality order", "en;q=0.3, es;q=0.9", Spanish},
+		{"first supported", "de, es;q=0.5, en;q=0.4", Spanish},
+		{"not acceptable", "es;q=0", English},
+		{"unsupported preferred", "fr, en;q=0.2", English},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.header); got != tt.want {
+				t.Errorf("Negotiate(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	if message, ok := Error(Spanish, "USER_NOT_FOUND"); !ok || message != "el usuario no existe" {
+		t.Errorf("Spanish USER_NOT_FOUND = %q, %v; want its translation", message, ok)
+	}
+	// English messages come with the errors themselves
+	if _, ok := Error(English, "USER_NOT_FOUND"); ok {
+		t.Error("English has an error catalog")
+	}
+	if _, ok := Error(Spanish, "NO_SUCH_CODE"); ok {
+		t.Error("unknown code has a Spanish message")
+	}
+}
+
+func TestRule(t *testing.T) {
+	tests := []struct {
+		name string
+		lang language.Tag
+		rule string
+		args []any
+		want string
+	}{
+		{"English", English, "required", nil, "is required"},
+		{"Spanish", Spanish, "required", nil, "es obligatorio"},
+		{"with arguments", Spanish, RuleMinLength, []any{"8"}, "debe tener al menos 8 caracteres"},
+		{"unknown rule", English, "hexcolor", nil, `failed the "hexcolor" rule`},
+		{"unknown rule in Spanish", Spanish, "hexcolor", nil, `no cumple la regla "hexcolor"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Rule(tt.lang, tt.rule, tt.args...); got != tt.want {
+				t.Errorf("Rule(%v, %q) = %q, want %q", tt.lang, tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalogsCoverEveryRule(t *testing.T) {
+	for _, lang := range supported {
+		for rule := range englishRules {
+			if _, ok := catalogs[lang].rules[rule]; !ok {
+				t.Errorf("%v has no message for rule %q", lang, rule)
+			}
+		}
+		for rule := range catalogs[lang].rules {
+			if _, ok := englishRules[rule]; !ok {
+				t.Errorf("%v has a message for rule %q, which English lacks", lang, rule)
+			}
+		}
+	}
+}
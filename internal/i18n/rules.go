@@ -0,0 +1,54 @@
+package i18n
+
+// Validation rule keys beyond the validator tags themselves; length rules are
+// keyed by what they count
+const (
+	RuleMinLength = "min_length"
+	RuleMinItems  = "min_items"
+	RuleMaxLength = "max_length"
+	RuleMaxItems  = "max_items"
+	RuleNotNull   = "not_null"
+	// RuleUnknown describes a rule without a message, taking its name
+	RuleUnknown = "unknown"
+)
+
+// englishRules are the English validation messages, which every other
+// language falls back to
+var englishRules = map[string]string{
+	"required":    "is required",
+	"email":       "must be a valid email",
+	"phone":       "must be an E.164 phone number such as +15551234567",
+	"locale":      "must be a BCP 47 language tag such as en-US",
+	"timezone":    "must be an IANA time zone such as Europe/Berlin",
+	"http_url":    "must be an http or https URL",
+	"uuid":        "must be a UUID",
+	"min":         "must be at least %s",
+	"max":         "must be at most %s",
+	RuleMinLength: "must be at least %s characters",
+	RuleMinItems:  "must be at least %s items",
+	RuleMaxLength: "must be at most %s characters",
+	RuleMaxItems:  "must be at most %s items",
+	"oneof":       "must be one of: %s",
+	RuleNotNull:   "cannot be null",
+	RuleUnknown:   "failed the %q rule",
+}
+
+// spanishRules are the Spanish validation messages
+var spanishRules = map[string]string{
+	"required":    "es obligatorio",
+	"email":       "debe ser un correo electrónico válido",
+	"phone":       "debe ser un número de teléfono E.164 como +15551234567",
+	"locale":      "debe ser una etiqueta de idioma BCP 47 como es-ES",
+	"timezone":    "debe ser una zona horaria IANA como Europe/Madrid",
+	"http_url":    "debe ser una URL http o https",
+	"uuid":        "debe ser un UUID",
+	"min":         "debe ser al menos %s",
+	"max":         "debe ser como máximo %s",
+	RuleMinLength: "debe tener al menos %s caracteres",
+	RuleMinItems:  "debe tener al menos %s elementos",
+	RuleMaxLength: "debe tener como máximo %s caracteres",
+	RuleMaxItems:  "debe tener como máximo %s elementos",
+	"oneof":       "debe ser uno de: %s",
+	RuleNotNull:   "no puede ser nulo",
+	RuleUnknown:   "no cumple la regla %q",
+}
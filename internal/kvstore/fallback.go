@@ -0,0 +1,109 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// fallbackRetryInterval is how long Fallback stays on its fallback store
+// after the primary fails before trying the primary again
+const fallbackRetryInterval = 5 * time.Second
+
+// Fallback is a Store that uses a primary, such as Redis, and switches to a
+// fallback, such as Memory, while the primary fails, so an outage degrades
+// state sharing between replicas rather than failing requests
+type Fallback struct {
+	primary  Store
+	fallback Store
+
+	mu sync.Mutex
+	// retryAt is when to try the primary again, zero while it is healthy
+	retryAt time.Time
+}
+
+// NewFallback creates a Fallback store over primary and fallback
+func NewFallback(primary, fallback Store) *Fallback {
+	return &Fallback{primary: primary, fallback: fallback}
+}
+
+// Incr adds one to the integer under key
+func (f *Fallback) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return call(ctx, f, func(s Store) (int64, error) {
+		return s.Incr(ctx, key, ttl)
+	})
+}
+
+// Get returns the value under key
+func (f *Fallback) Get(ctx context.Context, key string) (string, error) {
+	return call(ctx, f, func(s Store) (string, error) {
+		return s.Get(ctx, key)
+	})
+}
+
+// SetWithTTL stores value under key
+func (f *Fallback) SetWithTTL(ctx context.Context, key, value string, ttl time.Duration) error {
+	_, err := call(ctx, f, func(s Store) (struct{}, error) {
+		return struct{}{}, s.SetWithTTL(ctx, key, value, ttl)
+	})
+	return err
+}
+
+// Del removes the keys
+func (f *Fallback) Del(ctx context.Context, keys ...string) error {
+	_, err := call(ctx, f, func(s Store) (struct{}, error) {
+		return struct{}{}, s.Del(ctx, keys...)
+	})
+	return err
+}
+
+// call runs op on the primary unless it recently failed, and on the
+// fallback when it fails now
+func call[T any](ctx context.Context, f *Fallback, op func(Store) (T, error)) (T, error) {
+	if f.primaryDue(time.Now()) {
+		value, err := op(f.primary)
+		if err == nil || errors.Is(err, ErrNotFound) {
+			f.recovered()
+			return value, err
+		}
+		// A canceled request says nothing about the primary
+		if ctx.Err() != nil {
+			return value, err
+		}
+		f.failed(err)
+	}
+
+	return op(f.fallback)
+}
+
+// primaryDue reports whether calls should go to the primary
+func (f *Fallback) primaryDue(now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return !now.Before(f.retryAt)
+}
+
+// failed switches to the fallback, warning when the primary was healthy
+func (f *Fallback) failed(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.retryAt.IsZero() {
+		slog.Warn("key-value store unavailable, using in-memory state until it recovers", slog.Any("error", err))
+	}
+	f.retryAt = time.Now().Add(fallbackRetryInterval)
+}
+
+// recovered switches back to the primary after a successful call
+func (f *Fallback) recovered() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.retryAt.IsZero() {
+		slog.Info("key-value store available again")
+		f.retryAt = time.Time{}
+	}
+}
@@ -0,0 +1,108 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyStore is a Store failing every call while down and counting them
+type flakyStore struct {
+	*Memory
+	down  bool
+	calls int
+}
+
+func (s *flakyStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if err := s.call(); err != nil {
+		return 0, err
+	}
+	return s.Memory.Incr(ctx, key, ttl)
+}
+
+func (s *flakyStore) Get(ctx context.Context, key string) (string, error) {
+	if err := s.call(); err != nil {
+		return "", err
+	}
+	return s.Memory.Get(ctx, key)
+}
+
+func (s *flakyStore) call() error {
+	s.calls++
+	if s.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestFallbackSwitchesWhilePrimaryFails(t *testing.T) {
+	ctx := context.Background()
+	primary := &flakyStore{Memory: NewMemory()}
+	fallback := NewMemory()
+	store := NewFallback(primary, fallback)
+
+	// A missing key is an answer, not a failure
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get error = %v, want %v", err, ErrNotFound)
+	}
+	if primary.calls != 1 || !store.primaryDue(time.Now()) {
+		t.Fatal("ErrNotFound from the primary switched to the fallback")
+	}
+
+	primary.down = true
+	for want := int64(1); want <= 2; want++ {
+		if n, err := store.Incr(ctx, "counter", time.Minute); err != nil || n != want {
+			t.Fatalf("Incr while the primary is down = %d, %v; want %d", n, err, want)
+		}
+	}
+	// Only the first call tried the primary before switching
+	if primary.calls != 2 {
+		t.Errorf("primary called %d times, want 2", primary.calls)
+	}
+	if got, err := fallback.Get(ctx, "counter"); err != nil || got != "2" {
+		t.Errorf("fallback count = %q, %v; want 2", got, err)
+	}
+
+	// Once the retry is due, a healthy primary takes over again
+	primary.down = false
+	store.retryAt = time.Now()
+	if n, err := store.Incr(ctx, "counter", time.Minute); err != nil || n != 1 {
+		t.Errorf("Incr after recovery = %d, %v; want the primary's count of 1", n, err)
+	}
+	if !store.retryAt.IsZero() {
+		t.Error("Fallback did not switch back to the primary")
+	}
+}
+
+func TestFallbackIgnoresCanceledCalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	primary := &flakyStore{Memory: NewMemory(), down: true}
+	store := NewFallback(primary, NewMemory())
+
+	if _, err := store.Get(ctx, "key"); err == nil {
+		t.Fatal("Get with a canceled context succeeded")
+	}
+	if !store.primaryDue(time.Now()) {
+		t.Error("a canceled call switched to the fallback")
+	}
+}
+
+func TestFallbackOverUnreachableRedis(t *testing.T) {
+	ctx := context.Background()
+	// Nothing listens on port 1, so every Redis call fails fast
+	primary, err := NewRedis("redis://127.0.0.1:1")
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+	t.Cleanup(func() { _ = primary.Close() })
+	store := NewFallback(primary, NewMemory())
+
+	if n, err := store.Incr(ctx, "counter", time.Minute); err != nil || n != 1 {
+		t.Fatalf("Incr = %d, %v; want 1 from the fallback", n, err)
+	}
+	if got, err := store.Get(ctx, "counter"); err != nil || got != "1" {
+		t.Errorf("Get = %q, %v; want 1", got, err)
+	}
+}
@@ -0,0 +1,25 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when no live value is stored under a key
+var ErrNotFound = errors.New("key not found")
+
+// Store keeps short-lived counters and flags, such as rate limits and
+// lockouts, that replicas must share. Keys are namespaced by the caller, such
+// as ratelimit:<name>:<ip>, and a zero ttl keeps a value until it is deleted.
+type Store interface {
+	// Incr adds one to the integer under key and returns the result. A
+	// missing key starts from zero and expires after ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Get returns the value under key, or ErrNotFound
+	Get(ctx context.Context, key string) (string, error)
+	// SetWithTTL stores value under key, replacing any value and expiry
+	SetWithTTL(ctx context.Context, key, value string, ttl time.Duration) error
+	// Del removes the keys, ignoring missing ones
+	Del(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,98 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testStore checks the Store contract on store, under keys starting with
+// prefix so a shared server can run it
+func testStore(t *testing.T, store Store, prefix string) {
+	ctx := context.Background()
+	key := func(name string) string { return prefix + name }
+
+	t.Run("missing key", func(t *testing.T) {
+		if _, err := store.Get(ctx, key("missing")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get error = %v, want %v", err, ErrNotFound)
+		}
+		if err := store.Del(ctx, key("missing")); err != nil {
+			t.Errorf("Del of a missing key: %v", err)
+		}
+		if err := store.Del(ctx); err != nil {
+			t.Errorf("Del of no keys: %v", err)
+		}
+	})
+
+	t.Run("incr", func(t *testing.T) {
+		for want := int64(1); want <= 3; want++ {
+			n, err := store.Incr(ctx, key("counter"), time.Minute)
+			if err != nil {
+				t.Fatalf("Incr: %v", err)
+			}
+			if n != want {
+				t.Errorf("Incr = %d, want %d", n, want)
+			}
+		}
+		if got, err := store.Get(ctx, key("counter")); err != nil || got != "3" {
+			t.Errorf("Get = %q, %v; want 3", got, err)
+		}
+	})
+
+	t.Run("incr of a non-integer", func(t *testing.T) {
+		if err := store.SetWithTTL(ctx, key("text"), "abc", time.Minute); err != nil {
+			t.Fatalf("SetWithTTL: %v", err)
+		}
+		if _, err := store.Incr(ctx, key("text"), time.Minute); err == nil {
+			t.Error("Incr of a non-integer succeeded")
+		}
+	})
+
+	t.Run("set and delete", func(t *testing.T) {
+		if err := store.SetWithTTL(ctx, key("a"), "1", time.Minute); err != nil {
+			t.Fatalf("SetWithTTL: %v", err)
+		}
+		if err := store.SetWithTTL(ctx, key("a"), "2", 0); err != nil {
+			t.Fatalf("SetWithTTL replacing: %v", err)
+		}
+		if err := store.SetWithTTL(ctx, key("b"), "3", 0); err != nil {
+			t.Fatalf("SetWithTTL: %v", err)
+		}
+		if got, err := store.Get(ctx, key("a")); err != nil || got != "2" {
+			t.Errorf("Get = %q, %v; want the replaced value 2", got, err)
+		}
+
+		if err := store.Del(ctx, key("a"), key("b")); err != nil {
+			t.Fatalf("Del: %v", err)
+		}
+		for _, name := range []string{"a", "b"} {
+			if _, err := store.Get(ctx, key(name)); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get %s after Del error = %v, want %v", name, err, ErrNotFound)
+			}
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		if err := store.SetWithTTL(ctx, key("flag"), "on", 50*time.Millisecond); err != nil {
+			t.Fatalf("SetWithTTL: %v", err)
+		}
+		if _, err := store.Incr(ctx, key("window"), 50*time.Millisecond); err != nil {
+			t.Fatalf("Incr: %v", err)
+		}
+		// Later hits do not extend the window
+		if _, err := store.Incr(ctx, key("window"), time.Hour); err != nil {
+			t.Fatalf("Incr: %v", err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		for _, name := range []string{"flag", "window"} {
+			if _, err := store.Get(ctx, key(name)); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get %s after its ttl error = %v, want %v", name, err, ErrNotFound)
+			}
+		}
+		if n, err := store.Incr(ctx, key("window"), time.Minute); err != nil || n != 1 {
+			t.Errorf("Incr after expiry = %d, %v; want a new count of 1", n, err)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package kvstore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is how often writes drop expired values
+const memorySweepInterval = time.Minute
+
+// Memory is a Store kept in process memory, for single instances and as the
+// fallback when Redis is unreachable
+type Memory struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+	swept time.Time
+}
+
+// memoryItem is a value and the time it expires, zero for never
+type memoryItem struct {
+	value   string
+	expires time.Time
+}
+
+// NewMemory creates an empty Memory store
+func NewMemory() *Memory {
+	return &Memory{items: make(map[string]memoryItem)}
+}
+
+// Incr adds one to the integer under key, starting a missing key at zero
+func (m *Memory) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	item, ok := m.live(key, now)
+	if !ok {
+		item = memoryItem{value: "0", expires: expiry(now, ttl)}
+	}
+
+	n, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("incr %s: value is not an integer", key)
+	}
+	n++
+	item.value = strconv.FormatInt(n, 10)
+	m.items[key] = item
+
+	return n, nil
+}
+
+// Get returns the value under key
+func (m *Memory) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.live(key, time.Now())
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return item.value, nil
+}
+
+// SetWithTTL stores value under key
+func (m *Memory) SetWithTTL(_ context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+	m.items[key] = memoryItem{value: value, expires: expiry(now, ttl)}
+
+	return nil
+}
+
+// Del removes the keys
+func (m *Memory) Del(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.items, key)
+	}
+
+	return nil
+}
+
+// live returns the item under key unless it has expired
+func (m *Memory) live(key string, now time.Time) (memoryItem, bool) {
+	item, ok := m.items[key]
+	if !ok || (!item.expires.IsZero() && !now.Before(item.expires)) {
+		return memoryItem{}, false
+	}
+
+	return item, true
+}
+
+// sweep drops expired items, at most once per memorySweepInterval, keeping
+// the map from growing without bound
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.swept) < memorySweepInterval {
+		return
+	}
+	m.swept = now
+
+	for key, item := range m.items {
+		if !item.expires.IsZero() && !now.Before(item.expires) {
+			delete(m.items, key)
+		}
+	}
+}
+
+// expiry returns when a value written now with ttl expires
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return now.Add(ttl)
+}
@@ -0,0 +1,57 @@
+package kvstore
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory(), "")
+}
+
+func TestMemoryConcurrentIncr(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+
+	const hits = 100
+	var wg sync.WaitGroup
+	for range hits {
+		wg.Go(func() {
+			if _, err := store.Incr(ctx, "counter", time.Minute); err != nil {
+				t.Errorf("Incr: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got, err := store.Get(ctx, "counter"); err != nil || got != "100" {
+		t.Errorf("count = %q, %v; want %d", got, err, hits)
+	}
+}
+
+func TestMemorySweepsExpiredValues(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+
+	if err := store.SetWithTTL(ctx, "old", "1", time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL: %v", err)
+	}
+	if err := store.SetWithTTL(ctx, "kept", "1", 0); err != nil {
+		t.Fatalf("SetWithTTL: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// The next write after the sweep interval drops what has expired
+	store.swept = time.Now().Add(-memorySweepInterval)
+	if _, err := store.Incr(ctx, "new", time.Minute); err != nil {
+		t.Fatalf("Incr: %v", err)
+	}
+	if _, ok := store.items["old"]; ok {
+		t.Error("expired value was not swept")
+	}
+	if _, ok := store.items["kept"]; !ok {
+		t.Error("value without a ttl was swept")
+	}
+}
@@ -0,0 +1,106 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Timeouts of Redis calls, kept short and without retries so an unreachable
+// Redis delays requests only briefly before Fallback takes over
+const (
+	redisDialTimeout = time.Second
+	redisCallTimeout = 500 * time.Millisecond
+)
+
+// incrScript increments a key and sets its expiry only when it creates it, so
+// later hits don't extend the window
+var incrScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// Redis is a Store shared by every replica using the same Redis
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis creates a Redis store from a redis:// or rediss:// URL. It does
+// not connect until the first call, see Ping.
+func NewRedis(url string) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	opts.DialTimeout = redisDialTimeout
+	opts.ReadTimeout = redisCallTimeout
+	opts.WriteTimeout = redisCallTimeout
+	opts.DialerRetries = 1
+	opts.MaxRetries = -1
+
+	return &Redis{client: redis.NewClient(opts)}, nil
+}
+
+// Ping checks that Redis is reachable
+func (r *Redis) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("ping redis: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the connections to Redis
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+// Incr adds one to the integer under key, starting a missing key at zero
+func (r *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	n, err := incrScript.Run(ctx, r.client, []string{key}, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("incr %s: %w", key, err)
+	}
+
+	return n, nil
+}
+
+// Get returns the value under key
+func (r *Redis) Get(ctx context.Context, key string) (string, error) {
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("get %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// SetWithTTL stores value under key
+func (r *Redis) SetWithTTL(ctx context.Context, key, value string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, key, value, max(ttl, 0)).Err(); err != nil {
+		return fmt.Errorf("set %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// Del removes the keys
+func (r *Redis) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("del: %w", err)
+	}
+
+	return nil
+}
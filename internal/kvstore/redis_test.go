@@ -0,0 +1,43 @@
+package kvstore
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// redisStore connects to the Redis at TEST_REDIS_URL, skipping t when it is
+// unset
+func redisStore(t *testing.T) *Redis {
+	t.Helper()
+
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL is unset")
+	}
+	store, err := NewRedis(url)
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if err := store.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	return store
+}
+
+func TestRedis(t *testing.T) {
+	store := redisStore(t)
+
+	// The server may be shared, so the keys are unique to this run
+	testStore(t, store, "kvstore-test:"+strconv.FormatInt(time.Now().UnixNano(), 36)+":")
+}
+
+func TestNewRedisRejectsInvalidURL(t *testing.T) {
+	if _, err := NewRedis("http://localhost:6379"); err == nil {
+		t.Error("NewRedis accepted a URL that is not redis://")
+	}
+}
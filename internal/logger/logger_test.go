@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "info", FormatJSON)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	l.With(slog.String("request_id", "abc")).Info("http request", slog.String("method", "GET"), slog.Int("status", 200))
+	l.Debug("below the level")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1: %s", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("log line %q is not JSON: %v", lines[0], err)
+	}
+	for _, key := range []string{"time", "level", "msg", "request_id", "method", "status"} {
+		if _, ok := entry[key]; !ok {
+			t.Errorf("log entry %v has no %q key", entry, key)
+		}
+	}
+	if entry["msg"] != "http request" || entry["level"] != "INFO" {
+		t.Errorf("msg, level = %v, %v, want %q, %q", entry["msg"], entry["level"], "http request", "INFO")
+	}
+}
+
+func TestNewText(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "debug", FormatText)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	l.Debug("starting", slog.String("port", "3000"))
+	if got := buf.String(); !strings.Contains(got, "msg=starting") || !strings.Contains(got, "port=3000") {
+		t.Errorf("text output = %q, want key=value pairs", got)
+	}
+}
+
+func TestNewRejectsInvalidSettings(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "loud", FormatJSON); err == nil {
+		t.Error("New with level \"loud\" succeeded, want an error")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("New with format \"xml\" succeeded, want an error")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != slog.Default() {
+		t.Error("FromContext without a logger did not return the default logger")
+	}
+
+	l := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	if got := FromContext(WithContext(context.Background(), l)); got != l {
+		t.Error("FromContext did not return the stored logger")
+	}
+}
@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+)
+
+// Names of the emails the service sends
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplatePasswordReset = "password_reset"
+	TemplateWelcome       = "welcome"
+	// TemplateConfirmEmailChange goes to the new address of an email change
+	TemplateConfirmEmailChange = "confirm_email_change"
+	// TemplateEmailChangeRequested tells the current address about an email change
+	TemplateEmailChangeRequested = "email_change_requested"
+)
+
+// Message is an email to be delivered
+type Message struct {
+	To      string
+	Subject string
+	// Body is the plaintext body
+	Body string
+	// HTML is the optional HTML body, sent alongside Body as an alternative
+	HTML string
+	// Template names which kind of email this is, such as TemplateVerifyEmail
+	Template string
+	// Retry asks a RetryingMailer to try again later when sending fails. It
+	// suits mails the user cannot ask for again, unlike a verification link.
+	Retry bool
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer is a Mailer that only logs messages, for development and until
+// a real transport is configured
+type LogMailer struct{}
+
+// NewLogMailer creates a LogMailer
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the message instead of delivering it
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	logger.FromContext(ctx).Info("email sent",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("template", msg.Template),
+		slog.String("body", msg.Body),
+	)
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MockMailer is a Mailer that keeps every message instead of delivering it,
+// so tests can check what was sent. It is safe for concurrent use.
+type MockMailer struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+// NewMockMailer creates a MockMailer with no messages
+func NewMockMailer() *MockMailer {
+	return &MockMailer{}
+}
+
+// Send records the message, or returns the error set with FailWith
+func (m *MockMailer) Send(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, msg)
+
+	return nil
+}
+
+// FailWith makes later sends return err instead of recording the message;
+// nil makes them succeed again
+func (m *MockMailer) FailWith(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
+
+// Messages returns the recorded messages, oldest first
+func (m *MockMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.messages)
+}
+
+// LastMessage returns the most recent message, or false when none was sent
+func (m *MockMailer) LastMessage() (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.messages) == 0 {
+		return Message{}, false
+	}
+
+	return m.messages[len(m.messages)-1], true
+}
+
+// MessagesTo returns the messages sent to the address, oldest first
+func (m *MockMailer) MessagesTo(to string) []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var messages []Message
+	for _, msg := range m.messages {
+		if msg.To == to {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+// Reset forgets the recorded messages
+func (m *MockMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
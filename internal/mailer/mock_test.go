@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestMockMailer(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockMailer()
+
+	if _, ok := m.LastMessage(); ok {
+		t.Error("LastMessage of a new mailer reported a message")
+	}
+
+	sent := []Message{
+		{To: "ada@example.com", Subject: "Verify", Template: TemplateVerifyEmail},
+		{To: "grace@example.com", Subject: "Reset", Template: TemplatePasswordReset},
+		{To: "ada@example.com", Subject: "Welcome", Template: TemplateWelcome},
+	}
+	for _, msg := range sent {
+		if err := m.Send(ctx, msg); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	if got := m.Messages(); len(got) != len(sent) {
+		t.Errorf("Messages returned %d messages, want %d", len(got), len(sent))
+	}
+	if last, ok := m.LastMessage(); !ok || last != sent[2] {
+		t.Errorf("LastMessage = %+v, %v, want %+v", last, ok, sent[2])
+	}
+	toAda := m.MessagesTo("ada@example.com")
+	if len(toAda) != 2 || toAda[0] != sent[0] || toAda[1] != sent[2] {
+		t.Errorf("MessagesTo(ada) = %+v, want the first and last messages", toAda)
+	}
+
+	errSMTP := errors.New("smtp unavailable")
+	m.FailWith(errSMTP)
+	if err := m.Send(ctx, Message{To: "ada@example.com"}); !errors.Is(err, errSMTP) {
+		t.Errorf("Send after FailWith error = %v, want %v", err, errSMTP)
+	}
+	m.FailWith(nil)
+	if got := len(m.Messages()); got != len(sent) {
+		t.Errorf("a failed send was recorded: %d messages, want %d", got, len(sent))
+	}
+
+	m.Reset()
+	if got := m.Messages(); len(got) != 0 {
+		t.Errorf("Messages after Reset = %+v, want none", got)
+	}
+}
+
+func TestMockMailerConcurrentSends(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockMailer()
+
+	const senders = 50
+	var wg sync.WaitGroup
+	for i := range senders {
+		wg.Go(func() {
+			if err := m.Send(ctx, Message{To: fmt.Sprintf("user%d@example.com", i)}); err != nil {
+				t.Errorf("Send: %v", err)
+			}
+			m.MessagesTo("user0@example.com")
+			m.LastMessage()
+		})
+	}
+	wg.Wait()
+
+	if got := len(m.Messages()); got != senders {
+		t.Errorf("recorded %d messages, want %d", got, senders)
+	}
+}
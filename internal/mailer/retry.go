@@ -0,0 +1,146 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+)
+
+// sendTimeout bounds each background retry
+const sendTimeout = 30 * time.Second
+
+// RetrySettings configures a RetryingMailer
+type RetrySettings struct {
+	// QueueSize bounds the messages waiting for a retry; more are dropped
+	QueueSize int
+	// Attempts is the number of tries, including the first, before a message is dropped
+	Attempts int
+	// Backoff is the wait before the first retry, doubling after each failure
+	Backoff time.Duration
+}
+
+// retry is a message waiting for another attempt
+type retry struct {
+	msg      Message
+	attempts int
+}
+
+// RetryingMailer wraps a Mailer so an outage does not lose the messages
+// marked Retry. Sending is attempted at once; when it fails, a Retry message
+// is queued for a background worker and Send reports success, while any
+// other message returns the error to the caller.
+type RetryingMailer struct {
+	next     Mailer
+	settings RetrySettings
+
+	// mu guards closed, so nothing is sent on the queue once it is closed
+	mu     sync.Mutex
+	closed bool
+	queue  chan retry
+	done   chan struct{}
+}
+
+// NewRetryingMailer creates a RetryingMailer and starts its worker
+func NewRetryingMailer(next Mailer, settings RetrySettings) *RetryingMailer {
+	m := &RetryingMailer{
+		next:     next,
+		settings: settings,
+		queue:    make(chan retry, settings.QueueSize),
+		done:     make(chan struct{}),
+	}
+	go m.work()
+
+	return m
+}
+
+// Send tries the message once, queueing a Retry message for later when that fails
+func (m *RetryingMailer) Send(ctx context.Context, msg Message) error {
+	err := m.next.Send(ctx, msg)
+	if err == nil || !msg.Retry || m.settings.Attempts <= 1 {
+		return err
+	}
+
+	logger.FromContext(ctx).Warn("email failed, retrying later",
+		slog.String("template", msg.Template),
+		slog.Any("error", err),
+	)
+	m.schedule(retry{msg: msg, attempts: 1})
+
+	return nil
+}
+
+// Close stops accepting retries and waits for the worker to finish the
+// queued ones or ctx to end. Retries still waiting for their backoff are
+// dropped.
+func (m *RetryingMailer) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue adds the retry unless the queue is full or closed
+func (m *RetryingMailer) enqueue(r retry) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return false
+	}
+
+	select {
+	case m.queue <- r:
+		return true
+	default:
+		return false
+	}
+}
+
+// work retries queued messages until the queue is closed and drained
+func (m *RetryingMailer) work() {
+	defer close(m.done)
+
+	for r := range m.queue {
+		m.attempt(r)
+	}
+}
+
+// attempt sends the message once more, scheduling the next try until the
+// attempts run out
+func (m *RetryingMailer) attempt(r retry) {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	err := m.next.Send(ctx, r.msg)
+	cancel()
+	if err == nil {
+		return
+	}
+
+	r.attempts++
+	if r.attempts >= m.settings.Attempts {
+		slog.Error("giving up on email", slog.String("template", r.msg.Template), slog.Any("error", err))
+		return
+	}
+	m.schedule(r)
+}
+
+// schedule queues the retry once its backoff has passed
+func (m *RetryingMailer) schedule(r retry) {
+	backoff := m.settings.Backoff << (r.attempts - 1)
+	time.AfterFunc(backoff, func() {
+		if !m.enqueue(r) {
+			slog.Warn("email retry queue full or closed, dropping email", slog.String("template", r.msg.Template))
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyMailer fails its first sends, then records like a MockMailer
+type flakyMailer struct {
+	*MockMailer
+	failures chan struct{}
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg Message) error {
+	select {
+	case <-m.failures:
+		return errors.New("smtp unavailable")
+	default:
+		return m.MockMailer.Send(ctx, msg)
+	}
+}
+
+// newFlakyMailer returns a mailer failing its first n sends
+func newFlakyMailer(n int) *flakyMailer {
+	m := &flakyMailer{MockMailer: NewMockMailer(), failures: make(chan struct{}, n)}
+	for range n {
+		m.failures <- struct{}{}
+	}
+
+	return m
+}
+
+func TestRetryingMailerRetries(t *testing.T) {
+	next := newFlakyMailer(2)
+	m := NewRetryingMailer(next, RetrySettings{QueueSize: 10, Attempts: 3, Backoff: time.Millisecond})
+
+	if err := m.Send(context.Background(), Message{To: "ada@example.com", Template: TemplateWelcome, Retry: true}); err != nil {
+		t.Fatalf("Send of a retried message error = %v, want nil", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(next.Messages()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the message was never delivered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestRetryingMailerReturnsErrorsOfOtherMessages(t *testing.T) {
+	next := newFlakyMailer(1)
+	m := NewRetryingMailer(next, RetrySettings{QueueSize: 10, Attempts: 3, Backoff: time.Millisecond})
+	defer m.Close(context.Background())
+
+	if err := m.Send(context.Background(), Message{To: "ada@example.com", Template: TemplateVerifyEmail}); err == nil {
+		t.Error("Send of a message without Retry hid the failure")
+	}
+}
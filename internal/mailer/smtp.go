@@ -0,0 +1,214 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// implicitTLSPort is the submission port that speaks TLS from the start
+// rather than upgrading with STARTTLS
+const implicitTLSPort = 465
+
+// SMTPSettings configures the SMTP server and sender
+type SMTPSettings struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN auth when Username is set
+	Username string
+	Password string
+	// From is the sender address, optionally with a display name
+	From string
+	// Timeout bounds each delivery, from dialing to QUIT
+	Timeout time.Duration
+}
+
+// SMTPMailer is a Mailer that delivers through an SMTP server. It upgrades
+// the connection with STARTTLS whenever the server offers it, and uses TLS
+// from the start on port 465.
+type SMTPMailer struct {
+	settings SMTPSettings
+	from     *mail.Address
+}
+
+// NewSMTPMailer creates an SMTPMailer, failing when From is not a valid address
+func NewSMTPMailer(settings SMTPSettings) (*SMTPMailer, error) {
+	from, err := mail.ParseAddress(settings.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	return &SMTPMailer{settings: settings, from: from}, nil
+}
+
+// Send delivers the message, as multipart/alternative when it has an HTML body
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	// Parsing also rejects line breaks that would inject headers
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	data, err := m.compose(to, msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.settings.Timeout)
+	defer cancel()
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to smtp server: %w", err)
+	}
+	defer client.Close()
+
+	if err := m.deliver(client, to.Address, data); err != nil {
+		return fmt.Errorf("send email: %w", err)
+	}
+
+	return nil
+}
+
+// dial connects and greets the server, with the connection deadline taken from ctx
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.settings.Host, strconv.Itoa(m.settings.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if m.settings.Port == implicitTLSPort {
+		conn = tls.Client(conn, &tls.Config{ServerName: m.settings.Host})
+	}
+
+	client, err := smtp.NewClient(conn, m.settings.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// deliver runs the SMTP transaction on a connected client
+func (m *SMTPMailer) deliver(client *smtp.Client, to string, data []byte) error {
+	if m.settings.Port != implicitTLSPort {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: m.settings.Host}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.settings.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("server does not support authentication")
+		}
+		// PlainAuth refuses to send the password unencrypted, except to localhost
+		auth := smtp.PlainAuth("", m.settings.Username, m.settings.Password, m.settings.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// compose builds the RFC 5322 message with quoted-printable bodies
+func (m *SMTPMailer) compose(to *mail.Address, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	header("From", m.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+rand.Text()+"@"+m.settings.Host+">")
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+
+	// Clients show the last alternative they support, so HTML goes last
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Body},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes body to w in quoted-printable encoding
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
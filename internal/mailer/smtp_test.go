@@ -0,0 +1,109 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSMTPMailer returns an SMTPMailer sending from the example domain
+// through addr
+func newTestSMTPMailer(t *testing.T, addr string) *SMTPMailer {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("split %s: %v", addr, err)
+	}
+	portNum, err := net.LookupPort("tcp", port)
+	if err != nil {
+		t.Fatalf("parse port %s: %v", port, err)
+	}
+	m, err := NewSMTPMailer(SMTPSettings{
+		Host: host, Port: portNum, From: "Users <noreply@example.com>", Timeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPMailer: %v", err)
+	}
+
+	return m
+}
+
+func TestSMTPMailerComposeMultipart(t *testing.T) {
+	m := newTestSMTPMailer(t, "localhost:25")
+	msg, err := Render(TemplateVerifyEmail, "ada@example.com", VerifyEmailData{Name: "Ada", Link: "https://example.com/verify"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	data, err := m.compose(&mail.Address{Address: msg.To}, msg)
+	if err != nil {
+		t.Fatalf("compose: %v", err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("parse composed message: %v", err)
+	}
+	if got := parsed.Header.Get("To"); got != "<ada@example.com>" {
+		t.Errorf("To = %q, want <ada@example.com>", got)
+	}
+	if got := parsed.Header.Get("Subject"); got != msg.Subject {
+		t.Errorf("Subject = %q, want %q", got, msg.Subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v, want multipart/alternative", mediaType, err)
+	}
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	var types []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("read part body: %v", err)
+		}
+		if !strings.Contains(string(body), "https://example.com/verify") {
+			t.Errorf("%s part %q does not contain the link", part.Header.Get("Content-Type"), body)
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+	}
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Errorf("parts = %v, want plaintext then HTML", types)
+	}
+}
+
+func TestSMTPMailerConnectionFailure(t *testing.T) {
+	// A listener closed straight away leaves a port nothing answers on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	m := newTestSMTPMailer(t, addr)
+	if err := m.Send(context.Background(), Message{To: "ada@example.com", Subject: "Hi", Body: "Hi"}); err == nil {
+		t.Error("Send without a server succeeded")
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	m := newTestSMTPMailer(t, "localhost:25")
+	err := m.Send(context.Background(), Message{To: "ada@example.com\r\nBcc: mallory@example.com", Subject: "Hi", Body: "Hi"})
+	if err == nil {
+		t.Error("Send to an address with a line break succeeded")
+	}
+}
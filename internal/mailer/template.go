@@ -0,0 +1,94 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// templateFS holds a <name>.txt and a <name>.html for every template. The
+// text file defines the "subject" and the plaintext "text" body; the HTML
+// file is the HTML body.
+//
+//go:embed templates
+var templateFS embed.FS
+
+// VerifyEmailData fills in TemplateVerifyEmail
+type VerifyEmailData struct {
+	Name string
+	Link string
+}
+
+// PasswordResetData fills in TemplatePasswordReset
+type PasswordResetData struct {
+	Name  string
+	Token string
+}
+
+// EmailChangeRequestedData fills in TemplateEmailChangeRequested;
+// TemplateConfirmEmailChange takes VerifyEmailData
+type EmailChangeRequestedData struct {
+	Name     string
+	NewEmail string
+}
+
+// WelcomeData fills in TemplateWelcome
+type WelcomeData struct {
+	Name string
+}
+
+// emailTemplate is the parsed text and HTML of one template
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templates are parsed once; the files are embedded, so a parse error is a bug
+var templates = parseTemplates(
+	TemplateVerifyEmail, TemplatePasswordReset, TemplateWelcome,
+	TemplateConfirmEmailChange, TemplateEmailChangeRequested,
+)
+
+// parseTemplates parses the named templates from templateFS
+func parseTemplates(names ...string) map[string]emailTemplate {
+	parsed := make(map[string]emailTemplate, len(names))
+	for _, name := range names {
+		parsed[name] = emailTemplate{
+			text: texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/"+name+".txt")),
+			html: htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/"+name+".html")),
+		}
+	}
+
+	return parsed
+}
+
+// Render builds the message of the named template addressed to to, filling
+// it in with data
+func Render(name, to string, data any) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return Message{}, fmt.Errorf("render %s html: %w", name, err)
+	}
+
+	return Message{
+		To:       to,
+		Subject:  strings.TrimSpace(subject.String()),
+		Body:     text.String(),
+		HTML:     html.String(),
+		Template: name,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	const link = "https://example.com/api/v1/auth/verify?token=abc"
+	tests := []struct {
+		name    string
+		data    any
+		subject string
+		want    string
+	}{
+		{TemplateVerifyEmail, VerifyEmailData{Name: "Ada", Link: link}, "Verify your email address", link},
+		{TemplatePasswordReset, PasswordResetData{Name: "Ada", Token: "reset-token"}, "Reset your password", "reset-token"},
+		{TemplateWelcome, WelcomeData{Name: "Ada"}, "Welcome aboard", "Ada"},
+		{TemplateConfirmEmailChange, VerifyEmailData{Name: "Ada", Link: link}, "Confirm your new email address", link},
+		{TemplateEmailChangeRequested, EmailChangeRequestedData{Name: "Ada", NewEmail: "new@example.com"}, "Your email address is being changed", "new@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Render(tt.name, "ada@example.com", tt.data)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if msg.To != "ada@example.com" || msg.Template != tt.name {
+				t.Errorf("message to %q with template %q, want ada@example.com and %q", msg.To, msg.Template, tt.name)
+			}
+			if msg.Subject != tt.subject {
+				t.Errorf("subject = %q, want %q", msg.Subject, tt.subject)
+			}
+			if !strings.Contains(msg.Body, "Hi Ada,") || !strings.Contains(msg.Body, tt.want) {
+				t.Errorf("text body %q does not greet Ada or contain %q", msg.Body, tt.want)
+			}
+			if !strings.Contains(msg.HTML, "<html>") || !strings.Contains(msg.HTML, "Ada") {
+				t.Errorf("HTML body %q does not greet Ada", msg.HTML)
+			}
+		})
+	}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	msg, err := Render(TemplateWelcome, "ada@example.com", WelcomeData{Name: "<script>alert(1)</script>"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(msg.HTML, "<script>") {
+		t.Errorf("HTML body %q does not escape the name", msg.HTML)
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	if _, err := Render("no_such_template", "ada@example.com", nil); err == nil {
+		t.Error("Render of an unknown template succeeded")
+	}
+}
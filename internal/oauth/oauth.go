@@ -0,0 +1,96 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+// Profile is the account an identity provider vouches for
+type Profile struct {
+	// Subject is the provider's stable id for the account
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider runs the authorization code flow of an identity provider
+type Provider interface {
+	// AuthCodeURL returns the consent screen address carrying state
+	AuthCodeURL(state string) string
+	// Exchange trades an authorization code for the profile of the account
+	Exchange(ctx context.Context, code string) (*Profile, error)
+}
+
+// googleUserInfoURL is Google's OpenID Connect userinfo endpoint
+const googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+
+// Google signs users in with their Google account
+type Google struct {
+	config *oauth2.Config
+}
+
+// NewGoogle creates a Google provider for the given OAuth client, which must
+// list redirectURL as an authorized redirect URI
+func NewGoogle(clientID, clientSecret, redirectURL string) *Google {
+	return &Google{config: &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Endpoint:     endpoints.Google,
+		Scopes:       []string{"openid", "email", "profile"},
+	}}
+}
+
+// AuthCodeURL returns the address of Google's consent screen
+func (g *Google) AuthCodeURL(state string) string {
+	return g.config.AuthCodeURL(state)
+}
+
+// Exchange trades the code for a token and reads the account's profile with it
+func (g *Google) Exchange(ctx context.Context, code string) (*Profile, error) {
+	token, err := g.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("exchange code: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, googleUserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.config.Client(ctx, token).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch profile: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch profile: unexpected status %d", resp.StatusCode)
+	}
+
+	var info struct {
+		Subject       string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("decode profile: %w", err)
+	}
+	if info.Subject == "" {
+		return nil, errors.New("decode profile: missing subject")
+	}
+
+	return &Profile{
+		Subject:       info.Subject,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		Name:          info.Name,
+	}, nil
+}
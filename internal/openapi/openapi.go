@@ -0,0 +1,290 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem holds the operations of one path, keyed by lowercase HTTP method
+type PathItem map[string]*Operation
+
+// Operation describes a single route
+type Operation struct {
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Security    []map[string][]string `json:"security"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+}
+
+// Parameter describes a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the body an operation accepts
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes one response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType wraps the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes how requests authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	// In and Name locate the credential of apiKey schemes
+	In   string `json:"in,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Schema is a JSON schema as used by OpenAPI 3.0
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// New creates an empty document
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]SecurityScheme),
+		},
+	}
+}
+
+// Add registers an operation under the method and path
+func (d *Document) Add(method, path string, op *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		d.Paths[path] = item
+	}
+	if op.Responses == nil {
+		op.Responses = make(map[string]Response)
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// SchemaOf returns the schema of v's type. Named structs are added to the
+// components and referenced, so each DTO appears once in the document.
+func (d *Document) SchemaOf(v any) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		s := d.schemaOf(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return &Schema{Type: "string", Format: "binary"}
+	case t.Kind() == reflect.Struct:
+		return d.structSchema(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	}
+
+	// Interfaces and anything else accept any value
+	return &Schema{}
+}
+
+// structSchema describes a struct from its json and validate tags
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	name := schemaName(t)
+	if name != "" {
+		if _, ok := d.Components.Schemas[name]; ok {
+			return &Schema{Ref: "#/components/schemas/" + name}
+		}
+		// Reserve the name first so self-referencing types terminate
+		d.Components.Schemas[name] = &Schema{}
+	}
+
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if field.Anonymous && jsonName == "" {
+			// encoding/json promotes the fields of embedded struct pointers too
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Pointer {
+				embeddedType = embeddedType.Elem()
+			}
+			embedded := d.structSchema(embeddedType)
+			if embedded.Ref != "" {
+				embedded = d.Components.Schemas[strings.TrimPrefix(embedded.Ref, "#/components/schemas/")]
+			}
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+
+		prop := d.schemaOf(field.Type)
+		if prop.Ref == "" {
+			applyValidation(prop, field.Tag.Get("validate"))
+		}
+		s.Properties[jsonName] = prop
+
+		if isRequired(field) {
+			s.Required = append(s.Required, jsonName)
+		}
+	}
+
+	if name == "" {
+		return s
+	}
+	d.Components.Schemas[name] = s
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// schemaName returns the component name of a named struct, or "" for anonymous ones
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" {
+		return ""
+	}
+
+	// Instances of generic types are named after their type arguments, so
+	// PagedResponse[*domain.User] becomes PagedResponseUser
+	if base, args, ok := strings.Cut(name, "["); ok {
+		name = base
+		for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+			arg = strings.TrimLeft(arg[strings.LastIndex(arg, ".")+1:], "*[]")
+			name += strings.ToUpper(arg[:1]) + arg[1:]
+		}
+	}
+
+	// Unexported DTO names read better capitalized in the docs
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// isRequired reports whether the validate tag makes the field mandatory
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyValidation copies the constraints of common validate rules onto the schema
+func applyValidation(s *Schema, tag string) {
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			// The remaining rules apply to the elements of a slice
+			if s.Items != nil && s.Items.Ref == "" {
+				applyValidation(s.Items, strings.Join(rules[i+1:], ","))
+			}
+			return
+		case "email":
+			s.Format = "email"
+		case "oneof":
+			s.Enum = strings.Fields(param)
+		case "min", "max":
+			n, err := strconv.Atoi(param)
+			if err != nil || s.Type != "string" {
+				continue
+			}
+			if name == "min" {
+				s.MinLength = &n
+			} else {
+				s.MaxLength = &n
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package pagination
+
+// PagedResponse is one page of T with the position of the page in the full result
+type PagedResponse[T any] struct {
+	Data       []T  `json:"data"`
+	Page       int  `json:"page"`
+	PageSize   int  `json:"page_size"`
+	TotalCount int  `json:"total_count"`
+	TotalPages int  `json:"total_pages"`
+	HasNext    bool `json:"has_next"`
+	HasPrev    bool `json:"has_prev"`
+}
+
+// NewPagedResponse wraps data, the items of the 1-based page of the given
+// size, out of total items overall
+func NewPagedResponse[T any](data []T, page, size, total int) PagedResponse[T] {
+	// Encode an empty page as [] rather than null
+	if data == nil {
+		data = []T{}
+	}
+
+	var totalPages int
+	if size > 0 {
+		totalPages = (total + size - 1) / size
+	}
+
+	return PagedResponse[T]{
+		Data:       data,
+		Page:       page,
+		PageSize:   size,
+		TotalCount: total,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
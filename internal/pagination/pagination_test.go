@@ -0,0 +1,52 @@
+package pagination
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewPagedResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		items      int
+		page, size int
+		total      int
+		totalPages int
+		hasNext    bool
+		hasPrev    bool
+	}{
+		{"zero results", 0, 1, 20, 0, 0, false, false},
+		{"single partial page", 3, 1, 20, 3, 1, false, false},
+		{"first of exact pages", 10, 1, 10, 30, 3, true, false},
+		{"middle of exact pages", 10, 2, 10, 30, 3, true, true},
+		{"last exact page", 10, 3, 10, 30, 3, false, true},
+		{"last partial page", 1, 4, 10, 31, 4, false, true},
+		{"beyond the last page", 0, 5, 10, 31, 4, false, true},
+		{"zero page size", 0, 1, 0, 5, 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPagedResponse(make([]int, tt.items), tt.page, tt.size, tt.total)
+
+			if got.TotalPages != tt.totalPages || got.HasNext != tt.hasNext || got.HasPrev != tt.hasPrev {
+				t.Errorf("total_pages, has_next, has_prev = %d, %v, %v, want %d, %v, %v",
+					got.TotalPages, got.HasNext, got.HasPrev, tt.totalPages, tt.hasNext, tt.hasPrev)
+			}
+			if got.Page != tt.page || got.PageSize != tt.size || got.TotalCount != tt.total || len(got.Data) != tt.items {
+				t.Errorf("response = %+v, want the page, size, total, and items passed in", got)
+			}
+		})
+	}
+}
+
+func TestNewPagedResponseEncodesEmptyPageAsArray(t *testing.T) {
+	data, err := json.Marshal(NewPagedResponse[string](nil, 1, 20, 0))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	want := `{"data":[],"page":1,"page_size":20,"total_count":0,"total_pages":0,"has_next":false,"has_prev":false}`
+	if string(data) != want {
+		t.Errorf("encoded = %s, want %s", data, want)
+	}
+}
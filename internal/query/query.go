@@ -0,0 +1,118 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Paging defaults of list endpoints
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ListParams are the paging, sorting, and filter parameters of a list request
+type ListParams struct {
+	Page int
+	// PageSize is at most MaxPageSize; larger requests are clamped to it
+	PageSize int
+	// Sort is the field to sort by, or empty when the request names none
+	Sort string
+	Desc bool
+	// Filters holds the allowed filters the request set, by name
+	Filters map[string]string
+}
+
+// Parse reads page, page_size, and sort, which is a field of allowedSorts
+// with an optional - prefix for descending order, along with the filters
+// named in allowedFilters. Any other query parameter is rejected. Errors are
+// 400 INVALID_QUERY responses.
+func Parse(c fiber.Ctx, allowedSorts, allowedFilters []string) (*ListParams, error) {
+	params := &ListParams{
+		Page:     1,
+		PageSize: DefaultPageSize,
+		Filters:  make(map[string]string),
+	}
+
+	for key, value := range c.Queries() {
+		switch key {
+		case "page", "page_size", "sort":
+		default:
+			if !slices.Contains(allowedFilters, key) {
+				return nil, Invalid("unknown query parameter %q", key)
+			}
+			if value != "" {
+				params.Filters[key] = value
+			}
+		}
+	}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return nil, Invalid("page must be a positive integer")
+		}
+		params.Page = page
+	}
+
+	if value := c.Query("page_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			return nil, Invalid("page_size must be a positive integer")
+		}
+		params.PageSize = min(size, MaxPageSize)
+	}
+
+	if value := c.Query("sort"); value != "" {
+		field, desc := strings.CutPrefix(value, "-")
+		if !slices.Contains(allowedSorts, field) {
+			return nil, Invalid("unsupported sort field %q", field)
+		}
+		params.Sort = field
+		params.Desc = desc
+	}
+
+	return params, nil
+}
+
+// Bool returns the boolean filter of the given name, or nil when it is not set
+func (p *ListParams) Bool(name string) (*bool, error) {
+	value, ok := p.Filters[name]
+	if !ok {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, Invalid("%s must be a boolean", name)
+	}
+
+	return &b, nil
+}
+
+// Time returns the RFC 3339 timestamp filter of the given name, or nil when
+// it is not set
+func (p *ListParams) Time(name string) (*time.Time, error) {
+	value, ok := p.Filters[name]
+	if !ok {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, Invalid("%s must be an RFC 3339 timestamp", name)
+	}
+
+	return &t, nil
+}
+
+// Invalid returns the 400 INVALID_QUERY error for a bad query parameter
+func Invalid(format string, args ...any) error {
+	return apperror.BadRequest("INVALID_QUERY", fmt.Sprintf(format, args...))
+}
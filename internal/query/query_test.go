@@ -0,0 +1,119 @@
+package query
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/apperror"
+	"github.com/gofiber/fiber/v3"
+)
+
+// parse runs Parse on a request for target with the user list's sorts and filters
+func parse(t *testing.T, target string) (*ListParams, error) {
+	t.Helper()
+
+	var (
+		params   *ListParams
+		parseErr error
+	)
+	app := fiber.New()
+	app.Get("/", func(c fiber.Ctx) error {
+		params, parseErr = Parse(c, []string{"created_at", "email"}, []string{"role", "email_verified", "since"})
+		return nil
+	})
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, target, nil))
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	resp.Body.Close()
+
+	return params, parseErr
+}
+
+func TestParseDefaults(t *testing.T) {
+	params, err := parse(t, "/")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if params.Page != 1 || params.PageSize != DefaultPageSize || params.Sort != "" || params.Desc || len(params.Filters) != 0 {
+		t.Errorf("params = %+v, want page 1 of %d, unsorted and unfiltered", params, DefaultPageSize)
+	}
+}
+
+func TestParse(t *testing.T) {
+	params, err := parse(t, "/?page=3&page_size=50&sort=-email&role=admin&email_verified=")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if params.Page != 3 || params.PageSize != 50 || params.Sort != "email" || !params.Desc {
+		t.Errorf("params = %+v, want page 3 of 50 sorted by email descending", params)
+	}
+	if len(params.Filters) != 1 || params.Filters["role"] != "admin" {
+		t.Errorf("filters = %v, want only role=admin, as empty filters are unset", params.Filters)
+	}
+}
+
+func TestParseClampsPageSize(t *testing.T) {
+	params, err := parse(t, "/?page_size=1000")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if params.PageSize != MaxPageSize {
+		t.Errorf("page size = %d, want it clamped to %d", params.PageSize, MaxPageSize)
+	}
+}
+
+func TestParseRejectsBadInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"zero page", "/?page=0"},
+		{"page not a number", "/?page=two"},
+		{"negative page size", "/?page_size=-1"},
+		{"unknown sort field", "/?sort=password_hash"},
+		{"unknown descending sort field", "/?sort=-password_hash"},
+		{"unknown filter", "/?status=active"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, tt.target)
+			var apiErr *apperror.APIError
+			if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest || apiErr.Code != "INVALID_QUERY" {
+				t.Errorf("Parse(%s) error = %v, want a 400 INVALID_QUERY", tt.target, err)
+			}
+		})
+	}
+}
+
+func TestListParamsTypedFilters(t *testing.T) {
+	params, err := parse(t, "/?email_verified=true&since=2026-01-02T03:04:05Z")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	verified, err := params.Bool("email_verified")
+	if err != nil || verified == nil || !*verified {
+		t.Errorf("Bool(email_verified) = %v, %v, want true", verified, err)
+	}
+	since, err := params.Time("since")
+	if want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC); err != nil || since == nil || !since.Equal(want) {
+		t.Errorf("Time(since) = %v, %v, want %v", since, err, want)
+	}
+	if role, err := params.Bool("role"); role != nil || err != nil {
+		t.Errorf("Bool of an unset filter = %v, %v, want nil", role, err)
+	}
+
+	params, err = parse(t, "/?email_verified=maybe&since=yesterday")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := params.Bool("email_verified"); err == nil {
+		t.Error("Bool of a non-boolean succeeded")
+	}
+	if _, err := params.Time("since"); err == nil {
+		t.Error("Time of a non-timestamp succeeded")
+	}
+}
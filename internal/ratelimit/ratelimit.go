@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+)
+
+// Limiter counts hits per key and refuses keys that reach their limit
+type Limiter interface {
+	// Wait reports how long the key must wait before it may proceed, zero
+	// when it is under its limit
+	Wait(ctx context.Context, key string) (time.Duration, error)
+	// Hit counts an event against the key
+	Hit(ctx context.Context, key string) error
+	// Reset forgets the hits of the key
+	Reset(ctx context.Context, key string) error
+}
+
+// SlidingWindow is a Limiter allowing limit hits per key within any
+// window-long period. It counts hits per fixed window in a kvstore.Store and
+// weights the previous window by how much of it still overlaps the sliding
+// one, so replicas sharing the store share the limit.
+type SlidingWindow struct {
+	store  kvstore.Store
+	prefix string
+	limit  int
+	window time.Duration
+}
+
+// windowCount holds the hits of a key in two consecutive fixed windows
+type windowCount struct {
+	start    time.Time
+	current  int
+	previous int
+}
+
+// NewSlidingWindow creates a SlidingWindow keeping its counts in store under
+// prefix:<key>:<window start>
+func NewSlidingWindow(store kvstore.Store, prefix string, limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{store: store, prefix: prefix, limit: limit, window: window}
+}
+
+// Wait reports how long until the weighted count of the key drops below the
+// limit
+func (w *SlidingWindow) Wait(ctx context.Context, key string) (time.Duration, error) {
+	now := time.Now()
+	count := windowCount{start: now.Truncate(w.window)}
+
+	var err error
+	if count.current, err = w.count(ctx, key, count.start); err != nil {
+		return 0, err
+	}
+	if count.previous, err = w.count(ctx, key, count.start.Add(-w.window)); err != nil {
+		return 0, err
+	}
+
+	return count.wait(now, w.limit, w.window), nil
+}
+
+// Hit counts an event against the key. The count outlives its window by
+// one more, while it is still weighted in.
+func (w *SlidingWindow) Hit(ctx context.Context, key string) error {
+	_, err := w.store.Incr(ctx, w.key(key, time.Now().Truncate(w.window)), 2*w.window)
+	return err
+}
+
+// Reset forgets the hits of the key
+func (w *SlidingWindow) Reset(ctx context.Context, key string) error {
+	start := time.Now().Truncate(w.window)
+	return w.store.Del(ctx, w.key(key, start), w.key(key, start.Add(-w.window)))
+}
+
+// count reads the hits of the key in the fixed window starting at start
+func (w *SlidingWindow) count(ctx context.Context, key string, start time.Time) (int, error) {
+	value, err := w.store.Get(ctx, w.key(key, start))
+	if errors.Is(err, kvstore.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("rate limit count %s: %w", key, err)
+	}
+
+	return n, nil
+}
+
+// key names the count of the key in the fixed window starting at start
+func (w *SlidingWindow) key(key string, start time.Time) string {
+	return w.prefix + ":" + key + ":" + strconv.FormatInt(start.UnixMilli(), 10)
+}
+
+// wait reports how long until the weighted count drops below limit
+func (c *windowCount) wait(now time.Time, limit int, window time.Duration) time.Duration {
+	elapsed := now.Sub(c.start)
+	remaining := float64(window - elapsed)
+	weighted := float64(c.previous)*remaining/float64(window) + float64(c.current)
+	if weighted < float64(limit) {
+		return 0
+	}
+
+	// The previous window alone is over the limit: wait for it to slide out
+	// far enough within the current one
+	if c.previous > 0 && c.current < limit {
+		overlap := float64(limit-c.current) * float64(window) / float64(c.previous)
+		return ceilDuration(remaining - overlap)
+	}
+
+	// The current window is over the limit: wait for the next window, where
+	// the current count becomes the weighted previous one
+	overlap := float64(limit) * float64(window) / float64(c.current)
+	return ceilDuration(remaining + float64(window) - overlap)
+}
+
+// ceilDuration rounds a positive float of nanoseconds up, so the caller never
+// retries just before it is allowed
+func ceilDuration(ns float64) time.Duration {
+	return time.Duration(math.Ceil(ns)) + time.Nanosecond
+}
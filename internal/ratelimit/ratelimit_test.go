@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+)
+
+func TestWindowCountWait(t *testing.T) {
+	const (
+		limit  = 10
+		window = time.Minute
+	)
+	start := time.Date(2024, time.March, 9, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		elapsed  time.Duration
+		previous int
+		current  int
+		want     time.Duration
+	}{
+		{"no hits", 0, 0, 0, 0},
+		{"under the limit", 0, 0, limit - 1, 0},
+		{"previous window sliding out", 30 * time.Second, limit, 0, 0},
+		{"weighted under the limit", 30 * time.Second, limit, limit/2 - 1, 0},
+		{"current window full", 0, 0, limit, time.Minute},
+		{"current window full late", 45 * time.Second, 0, limit, 15 * time.Second},
+		{"previous window over the limit", 0, 2 * limit, 0, 30 * time.Second},
+		{"previous window at the limit with hits", 0, limit, limit / 2, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count := windowCount{start: start, previous: tt.previous, current: tt.current}
+
+			got := count.wait(start.Add(tt.elapsed), limit, window)
+			// Waits are rounded up past the exact moment
+			if got < tt.want || got > tt.want+time.Microsecond {
+				t.Errorf("wait = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	// An hour-long window keeps the hits of the test in one fixed window
+	limiter := NewSlidingWindow(kvstore.NewMemory(), "test", 3, time.Hour)
+
+	for range 3 {
+		if wait, err := limiter.Wait(ctx, "ada@example.com"); err != nil || wait != 0 {
+			t.Fatalf("Wait under the limit = %v, %v; want 0", wait, err)
+		}
+		if err := limiter.Hit(ctx, "ada@example.com"); err != nil {
+			t.Fatalf("Hit: %v", err)
+		}
+	}
+
+	wait, err := limiter.Wait(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if wait <= 0 || wait > 2*time.Hour {
+		t.Errorf("Wait at the limit = %v, want a wait of at most two windows", wait)
+	}
+	if wait, err := limiter.Wait(ctx, "grace@example.com"); err != nil || wait != 0 {
+		t.Errorf("Wait for another key = %v, %v; want 0", wait, err)
+	}
+
+	if err := limiter.Reset(ctx, "ada@example.com"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if wait, err := limiter.Wait(ctx, "ada@example.com"); err != nil || wait != 0 {
+		t.Errorf("Wait after Reset = %v, %v; want 0", wait, err)
+	}
+}
+
+func TestSlidingWindowSharesStore(t *testing.T) {
+	ctx := context.Background()
+	store := kvstore.NewMemory()
+	// Two replicas share the store, while another limiter uses its own prefix
+	replicas := []*SlidingWindow{
+		NewSlidingWindow(store, "login", 2, time.Hour),
+		NewSlidingWindow(store, "login", 2, time.Hour),
+	}
+	other := NewSlidingWindow(store, "2fa", 2, time.Hour)
+
+	for _, replica := range replicas {
+		if err := replica.Hit(ctx, "ada@example.com"); err != nil {
+			t.Fatalf("Hit: %v", err)
+		}
+	}
+	for i, replica := range replicas {
+		if wait, err := replica.Wait(ctx, "ada@example.com"); err != nil || wait == 0 {
+			t.Errorf("replica %d Wait = %v, %v; want the shared limit reached", i, wait, err)
+		}
+	}
+	if wait, err := other.Wait(ctx, "ada@example.com"); err != nil || wait != 0 {
+		t.Errorf("Wait under another prefix = %v, %v; want 0", wait, err)
+	}
+}
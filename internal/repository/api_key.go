@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// ErrAPIKeyNotFound is returned when no API key matches the lookup
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyRepository defines the persistence operations for API keys
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	GetByHash(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	// List returns every key, revoked ones included, newest first
+	List(ctx context.Context) ([]*domain.APIKey, error)
+	// Revoke marks the key revoked; revoking a revoked key keeps the first revocation time
+	Revoke(ctx context.Context, id string) (*domain.APIKey, error)
+	TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
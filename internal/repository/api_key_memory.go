@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// InMemoryAPIKeyRepository is an APIKeyRepository backed by a map
+type InMemoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]domain.APIKey
+}
+
+// NewInMemoryAPIKeyRepository creates an empty in-memory API key repository
+func NewInMemoryAPIKeyRepository() *InMemoryAPIKeyRepository {
+	return &InMemoryAPIKeyRepository{
+		keys: make(map[string]domain.APIKey),
+	}
+}
+
+// Create stores a new API key and assigns its id
+func (r *InMemoryAPIKeyRepository) Create(_ context.Context, key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.ID = uuid.NewString()
+	key.CreatedAt = time.Now().UTC()
+	r.keys[key.ID] = cloneAPIKey(*key)
+
+	return nil
+}
+
+// GetByHash returns the API key with the given hash
+func (r *InMemoryAPIKeyRepository) GetByHash(_ context.Context, keyHash string) (*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			key = cloneAPIKey(key)
+			return &key, nil
+		}
+	}
+
+	return nil, ErrAPIKeyNotFound
+}
+
+// List returns every key, newest first
+func (r *InMemoryAPIKeyRepository) List(_ context.Context) ([]*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*domain.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		key = cloneAPIKey(key)
+		keys = append(keys, &key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// Revoke marks the key revoked
+func (r *InMemoryAPIKeyRepository) Revoke(_ context.Context, id string) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+		r.keys[id] = key
+	}
+
+	key = cloneAPIKey(key)
+	return &key, nil
+}
+
+// TouchLastUsed records when the key was last used
+func (r *InMemoryAPIKeyRepository) TouchLastUsed(_ context.Context, id string, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	key.LastUsedAt = &usedAt
+	r.keys[id] = key
+
+	return nil
+}
+
+// cloneAPIKey copies the scopes so callers cannot modify the stored key
+func cloneAPIKey(key domain.APIKey) domain.APIKey {
+	key.Scopes = slices.Clone(key.Scopes)
+	return key
+}
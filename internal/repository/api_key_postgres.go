@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// apiKeyColumns lists the api_keys columns in the order scanAPIKey reads them
+const apiKeyColumns = `id, name, owner_id, prefix, key_hash, scopes, last_used_at, revoked_at, created_at`
+
+// PostgresAPIKeyRepository is an APIKeyRepository backed by PostgreSQL
+type PostgresAPIKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAPIKeyRepository creates an API key repository using the given connection pool
+func NewPostgresAPIKeyRepository(pool *pgxpool.Pool) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{pool: pool}
+}
+
+// Create inserts a new API key and populates its generated fields
+func (r *PostgresAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	const query = `
+		INSERT INTO api_keys (name, owner_id, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	err := r.pool.QueryRow(ctx, query, key.Name, key.OwnerID, key.Prefix, key.KeyHash, textArray(key.Scopes)).
+		Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create api key: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// GetByHash returns the API key with the given hash
+func (r *PostgresAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, query, keyHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// List returns every key, newest first
+func (r *PostgresAPIKeyRepository) List(ctx context.Context) ([]*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke marks the key revoked
+func (r *PostgresAPIKeyRepository) Revoke(ctx context.Context, id string) (*domain.APIKey, error) {
+	query := `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		RETURNING ` + apiKeyColumns
+
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || isInvalidID(err) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("revoke api key: %w", mapPgError(err))
+	}
+
+	return key, nil
+}
+
+// TouchLastUsed records when the key was last used
+func (r *PostgresAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, usedAt)
+	if err != nil {
+		return fmt.Errorf("touch api key: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// scanAPIKey reads a row selected with apiKeyColumns
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	var (
+		key    domain.APIKey
+		scopes []string
+	)
+	err := row.Scan(
+		&key.ID, &key.Name, &key.OwnerID, &key.Prefix, &key.KeyHash, &scopes,
+		&key.LastUsedAt, &key.RevokedAt, &key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = fromTextArray[domain.APIKeyScope](scopes)
+	return &key, nil
+}
+
+// textArray converts string-like values to the text array stored in the database
+func textArray[T ~string](values []T) []string {
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = string(value)
+	}
+
+	return texts
+}
+
+// fromTextArray converts a text array read from the database to string-like values
+func fromTextArray[T ~string](texts []string) []T {
+	values := make([]T, len(texts))
+	for i, text := range texts {
+		values[i] = T(text)
+	}
+
+	return values
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// AuditLogFilter narrows which entries a listing returns; all set fields must match
+type AuditLogFilter struct {
+	ActorID string
+	Action  domain.AuditAction
+	// From and To bound the entry time, inclusive of From and exclusive of To
+	From *time.Time
+	To   *time.Time
+}
+
+// AuditLogRepository defines the persistence operations for audit logs
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *domain.AuditLog) error
+	// List returns one page of entries, newest first, along with the total
+	// number of matching entries
+	List(ctx context.Context, filter AuditLogFilter, page, pageSize int) ([]*domain.AuditLog, int, error)
+	// Latest returns the newest entry with the action for each of the
+	// targets in one query, keyed by target id; targets without any are left out
+	Latest(ctx context.Context, action domain.AuditAction, targetIDs []string) (map[string]*domain.AuditLog, error)
+}
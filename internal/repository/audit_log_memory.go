@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// InMemoryAuditLogRepository is an AuditLogRepository backed by a slice
+type InMemoryAuditLogRepository struct {
+	mu      sync.RWMutex
+	entries []domain.AuditLog
+}
+
+// NewInMemoryAuditLogRepository creates an empty in-memory audit log repository
+func NewInMemoryAuditLogRepository() *InMemoryAuditLogRepository {
+	return &InMemoryAuditLogRepository{}
+}
+
+// Create appends an entry and assigns its id
+func (r *InMemoryAuditLogRepository) Create(_ context.Context, entry *domain.AuditLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = uuid.NewString()
+	entry.CreatedAt = time.Now().UTC()
+	r.entries = append(r.entries, *entry)
+
+	return nil
+}
+
+// List returns one page of matching entries, newest first
+func (r *InMemoryAuditLogRepository) List(_ context.Context, filter AuditLogFilter, page, pageSize int) ([]*domain.AuditLog, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Entries are appended in time order, so walk backwards for newest first
+	matched := make([]*domain.AuditLog, 0)
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if entry := r.entries[i]; matchesAuditFilter(entry, filter) {
+			matched = append(matched, &entry)
+		}
+	}
+
+	total := len(matched)
+	start := min((page-1)*pageSize, total)
+	end := min(start+pageSize, total)
+
+	return matched[start:end], total, nil
+}
+
+// Latest returns the newest entry with the action for each target
+func (r *InMemoryAuditLogRepository) Latest(_ context.Context, action domain.AuditAction, targetIDs []string) (map[string]*domain.AuditLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(targetIDs))
+	for _, id := range targetIDs {
+		wanted[id] = true
+	}
+
+	latest := make(map[string]*domain.AuditLog, len(targetIDs))
+	for i := len(r.entries) - 1; i >= 0 && len(latest) < len(wanted); i-- {
+		entry := r.entries[i]
+		if entry.Action == action && wanted[entry.TargetID] && latest[entry.TargetID] == nil {
+			latest[entry.TargetID] = &entry
+		}
+	}
+
+	return latest, nil
+}
+
+// matchesAuditFilter reports whether the entry satisfies every set filter field
+func matchesAuditFilter(entry domain.AuditLog, filter AuditLogFilter) bool {
+	if filter.ActorID != "" && entry.ActorID != filter.ActorID {
+		return false
+	}
+	if filter.Action != "" && entry.Action != filter.Action {
+		return false
+	}
+	if filter.From != nil && entry.CreatedAt.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && !entry.CreatedAt.Before(*filter.To) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresAuditLogRepository is an AuditLogRepository backed by PostgreSQL
+type PostgresAuditLogRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAuditLogRepository creates an audit log repository using the given connection pool
+func NewPostgresAuditLogRepository(pool *pgxpool.Pool) *PostgresAuditLogRepository {
+	return &PostgresAuditLogRepository{pool: pool}
+}
+
+// Create inserts an entry and populates its generated fields
+func (r *PostgresAuditLogRepository) Create(ctx context.Context, entry *domain.AuditLog) error {
+	const query = `
+		INSERT INTO audit_logs (actor_id, impersonated_id, action, target_id, request_id, ip)
+		VALUES (NULLIF($1, '')::uuid, NULLIF($2, '')::uuid, $3, NULLIF($4, '')::uuid, $5, $6)
+		RETURNING id, created_at`
+
+	err := r.pool.QueryRow(ctx, query, entry.ActorID, entry.ImpersonatedID, entry.Action, entry.TargetID, entry.RequestID, entry.IP).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create audit log: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// List returns one page of matching entries, newest first
+func (r *PostgresAuditLogRepository) List(ctx context.Context, filter AuditLogFilter, page, pageSize int) ([]*domain.AuditLog, int, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID != "" {
+		add("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+	where := whereClause(conditions)
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM audit_logs `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count audit logs: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, COALESCE(actor_id::text, ''), COALESCE(impersonated_id::text, ''), action,
+			COALESCE(target_id::text, ''), request_id, ip, created_at
+		FROM audit_logs %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+
+	rows, err := r.pool.Query(ctx, query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*domain.AuditLog, 0)
+	for rows.Next() {
+		var entry domain.AuditLog
+		if err := scanAuditLog(rows, &entry); err != nil {
+			return nil, 0, fmt.Errorf("scan audit log: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("list audit logs: %w", err)
+	}
+
+	return entries, total, nil
+}
+
+// Latest returns the newest entry with the action for each target
+func (r *PostgresAuditLogRepository) Latest(ctx context.Context, action domain.AuditAction, targetIDs []string) (map[string]*domain.AuditLog, error) {
+	const query = `
+		SELECT DISTINCT ON (target_id)
+			id, COALESCE(actor_id::text, ''), COALESCE(impersonated_id::text, ''), action,
+			target_id::text, request_id, ip, created_at
+		FROM audit_logs
+		WHERE action = $1 AND target_id = ANY($2::uuid[])
+		ORDER BY target_id, created_at DESC, id DESC`
+
+	rows, err := r.pool.Query(ctx, query, action, targetIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get latest audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	latest := make(map[string]*domain.AuditLog, len(targetIDs))
+	for rows.Next() {
+		var entry domain.AuditLog
+		if err := scanAuditLog(rows, &entry); err != nil {
+			return nil, fmt.Errorf("scan audit log: %w", err)
+		}
+		latest[entry.TargetID] = &entry
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get latest audit logs: %w", err)
+	}
+
+	return latest, nil
+}
+
+// scanAuditLog reads a row of audit log columns into entry
+func scanAuditLog(row pgx.Row, entry *domain.AuditLog) error {
+	return row.Scan(
+		&entry.ID, &entry.ActorID, &entry.ImpersonatedID, &entry.Action, &entry.TargetID,
+		&entry.RequestID, &entry.IP, &entry.CreatedAt,
+	)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// Repository errors returned by email verification repositories
+var (
+	ErrVerificationNotFound = errors.New("email verification not found")
+	ErrVerificationUsed     = errors.New("email verification already used")
+)
+
+// EmailVerificationRepository defines the persistence operations for email verification tokens
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, verification *domain.EmailVerification) error
+	GetByHash(ctx context.Context, tokenHash string) (*domain.EmailVerification, error)
+	// MarkUsed consumes the token, returning ErrVerificationUsed if it already was
+	MarkUsed(ctx context.Context, id string) error
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// InMemoryEmailVerificationRepository is an EmailVerificationRepository backed by a map
+type InMemoryEmailVerificationRepository struct {
+	mu            sync.RWMutex
+	verifications map[string]domain.EmailVerification
+}
+
+// NewInMemoryEmailVerificationRepository creates an empty in-memory email verification repository
+func NewInMemoryEmailVerificationRepository() *InMemoryEmailVerificationRepository {
+	return &InMemoryEmailVerificationRepository{
+		verifications: make(map[string]domain.EmailVerification),
+	}
+}
+
+// Create stores a new verification token and assigns its id
+func (r *InMemoryEmailVerificationRepository) Create(_ context.Context, verification *domain.EmailVerification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	verification.ID = uuid.NewString()
+	verification.CreatedAt = time.Now().UTC()
+	r.verifications[verification.ID] = *verification
+
+	return nil
+}
+
+// GetByHash returns the verification token with the given hash
+func (r *InMemoryEmailVerificationRepository) GetByHash(_ context.Context, tokenHash string) (*domain.EmailVerification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, verification := range r.verifications {
+		if verification.TokenHash == tokenHash {
+			return &verification, nil
+		}
+	}
+
+	return nil, ErrVerificationNotFound
+}
+
+// MarkUsed records that the verification token has been consumed
+func (r *InMemoryEmailVerificationRepository) MarkUsed(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	verification, ok := r.verifications[id]
+	if !ok {
+		return ErrVerificationNotFound
+	}
+	if verification.UsedAt != nil {
+		return ErrVerificationUsed
+	}
+
+	now := time.Now().UTC()
+	verification.UsedAt = &now
+	r.verifications[id] = verification
+
+	return nil
+}
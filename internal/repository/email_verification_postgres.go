@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresEmailVerificationRepository is an EmailVerificationRepository backed by PostgreSQL
+type PostgresEmailVerificationRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresEmailVerificationRepository creates an email verification repository using the given connection pool
+func NewPostgresEmailVerificationRepository(pool *pgxpool.Pool) *PostgresEmailVerificationRepository {
+	return &PostgresEmailVerificationRepository{pool: pool}
+}
+
+// Create inserts a new verification token and populates its generated fields
+func (r *PostgresEmailVerificationRepository) Create(ctx context.Context, verification *domain.EmailVerification) error {
+	const query = `
+		INSERT INTO email_verifications (user_id, token_hash, new_email, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err := r.pool.QueryRow(ctx, query, verification.UserID, verification.TokenHash, verification.NewEmail, verification.ExpiresAt).
+		Scan(&verification.ID, &verification.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create email verification: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// GetByHash returns the verification token with the given hash
+func (r *PostgresEmailVerificationRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.EmailVerification, error) {
+	const query = `
+		SELECT id, user_id, token_hash, new_email, expires_at, used_at, created_at
+		FROM email_verifications
+		WHERE token_hash = $1`
+
+	var verification domain.EmailVerification
+	err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&verification.ID, &verification.UserID, &verification.TokenHash, &verification.NewEmail,
+		&verification.ExpiresAt, &verification.UsedAt, &verification.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrVerificationNotFound
+		}
+		return nil, fmt.Errorf("get email verification: %w", err)
+	}
+
+	return &verification, nil
+}
+
+// MarkUsed records that the verification token has been consumed
+func (r *PostgresEmailVerificationRepository) MarkUsed(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `UPDATE email_verifications SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("mark email verification used: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		// The token was loaded by the caller, so no update means it was already used
+		return ErrVerificationUsed
+	}
+
+	return nil
+}
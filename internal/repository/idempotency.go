@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// IdempotencyRepository defines the persistence operations for idempotency
+// keys, which are unique per owner, key, and endpoint. Expired keys are
+// treated as absent until DeleteExpired removes them.
+type IdempotencyRepository interface {
+	// Reserve claims the key for a new request and reports true. When the
+	// key is already held, it returns the existing record and false.
+	Reserve(ctx context.Context, record *domain.IdempotencyKey) (*domain.IdempotencyKey, bool, error)
+	// Complete stores the response of a reserved key until record.ExpiresAt
+	Complete(ctx context.Context, record *domain.IdempotencyKey) error
+	// Release drops a reservation so the request can be retried
+	Release(ctx context.Context, record *domain.IdempotencyKey) error
+	// DeleteExpired removes up to limit expired keys and reports how many
+	DeleteExpired(ctx context.Context, limit int) (int, error)
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// idempotencyID identifies a key of an owner within an endpoint
+type idempotencyID struct {
+	owner    string
+	key      string
+	endpoint string
+}
+
+// InMemoryIdempotencyRepository is an IdempotencyRepository backed by a map
+type InMemoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[idempotencyID]domain.IdempotencyKey
+}
+
+// NewInMemoryIdempotencyRepository creates an empty in-memory idempotency repository
+func NewInMemoryIdempotencyRepository() *InMemoryIdempotencyRepository {
+	return &InMemoryIdempotencyRepository{
+		records: make(map[idempotencyID]domain.IdempotencyKey),
+	}
+}
+
+// Reserve claims the key unless an unexpired record already holds it
+func (r *InMemoryIdempotencyRepository) Reserve(_ context.Context, record *domain.IdempotencyKey) (*domain.IdempotencyKey, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	id := recordID(record)
+	if existing, ok := r.records[id]; ok && existing.ExpiresAt.After(now) {
+		return &existing, false, nil
+	}
+
+	record.CreatedAt = now
+	r.records[id] = *record
+
+	return record, true, nil
+}
+
+// Complete stores the response of a reserved key
+func (r *InMemoryIdempotencyRepository) Complete(_ context.Context, record *domain.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	record.CompletedAt = &now
+	r.records[recordID(record)] = *record
+
+	return nil
+}
+
+// Release drops a reservation
+func (r *InMemoryIdempotencyRepository) Release(_ context.Context, record *domain.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, recordID(record))
+
+	return nil
+}
+
+// DeleteExpired removes up to limit expired keys
+func (r *InMemoryIdempotencyRepository) DeleteExpired(_ context.Context, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	deleted := 0
+	for id, existing := range r.records {
+		if deleted == limit {
+			break
+		}
+		if !existing.ExpiresAt.After(now) {
+			delete(r.records, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// recordID returns the map key of the record
+func recordID(record *domain.IdempotencyKey) idempotencyID {
+	return idempotencyID{owner: record.Owner, key: record.Key, endpoint: record.Endpoint}
+}
@@ -0,0 +1,7 @@
+package repository
+
+import "testing"
+
+func TestInMemoryIdempotencyRepository(t *testing.T) {
+	testIdempotencyRepository(t, NewInMemoryIdempotencyRepository())
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresIdempotencyRepository is an IdempotencyRepository backed by PostgreSQL
+type PostgresIdempotencyRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresIdempotencyRepository creates an idempotency repository using the given connection pool
+func NewPostgresIdempotencyRepository(pool *pgxpool.Pool) *PostgresIdempotencyRepository {
+	return &PostgresIdempotencyRepository{pool: pool}
+}
+
+// Reserve claims the key unless an unexpired record already holds it. An
+// expired record is replaced in place, so reserving never waits for
+// DeleteExpired.
+func (r *PostgresIdempotencyRepository) Reserve(ctx context.Context, record *domain.IdempotencyKey) (*domain.IdempotencyKey, bool, error) {
+	const insert = `
+		INSERT INTO idempotency_keys (owner, key, endpoint, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (owner, key, endpoint) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL, response_body = NULL,
+			created_at = NOW(), completed_at = NULL, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+		RETURNING created_at`
+
+	const selectExisting = `
+		SELECT request_hash, status_code, content_type, response_body, created_at, completed_at, expires_at
+		FROM idempotency_keys
+		WHERE owner = $1 AND key = $2 AND endpoint = $3`
+
+	// The holder may be released between the two statements, in which case
+	// the insert is tried again
+	for range 3 {
+		err := r.pool.QueryRow(ctx, insert, record.Owner, record.Key, record.Endpoint, record.RequestHash, record.ExpiresAt).
+			Scan(&record.CreatedAt)
+		if err == nil {
+			return record, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, fmt.Errorf("reserve idempotency key: %w", mapPgError(err))
+		}
+
+		existing := domain.IdempotencyKey{Owner: record.Owner, Key: record.Key, Endpoint: record.Endpoint}
+		var (
+			statusCode  *int
+			contentType *string
+		)
+		err = r.pool.QueryRow(ctx, selectExisting, record.Owner, record.Key, record.Endpoint).Scan(
+			&existing.RequestHash, &statusCode, &contentType, &existing.ResponseBody,
+			&existing.CreatedAt, &existing.CompletedAt, &existing.ExpiresAt,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("get idempotency key: %w", err)
+		}
+		if statusCode != nil {
+			existing.StatusCode = *statusCode
+		}
+		if contentType != nil {
+			existing.ContentType = *contentType
+		}
+
+		return &existing, false, nil
+	}
+
+	return nil, false, errors.New("reserve idempotency key: key kept changing")
+}
+
+// Complete stores the response of a reserved key
+func (r *PostgresIdempotencyRepository) Complete(ctx context.Context, record *domain.IdempotencyKey) error {
+	const query = `
+		UPDATE idempotency_keys
+		SET status_code = $4, content_type = $5, response_body = $6, completed_at = NOW(), expires_at = $7
+		WHERE owner = $1 AND key = $2 AND endpoint = $3
+		RETURNING completed_at`
+
+	err := r.pool.QueryRow(ctx, query,
+		record.Owner, record.Key, record.Endpoint, record.StatusCode, record.ContentType, record.ResponseBody, record.ExpiresAt,
+	).Scan(&record.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// Release drops a reservation
+func (r *PostgresIdempotencyRepository) Release(ctx context.Context, record *domain.IdempotencyKey) error {
+	const query = `
+		DELETE FROM idempotency_keys
+		WHERE owner = $1 AND key = $2 AND endpoint = $3 AND completed_at IS NULL`
+
+	if _, err := r.pool.Exec(ctx, query, record.Owner, record.Key, record.Endpoint); err != nil {
+		return fmt.Errorf("release idempotency key: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// DeleteExpired removes up to limit expired keys, oldest first
+func (r *PostgresIdempotencyRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	const query = `
+		DELETE FROM idempotency_keys
+		WHERE ctid IN (
+			SELECT ctid FROM idempotency_keys
+			WHERE expires_at <= NOW()
+			ORDER BY expires_at
+			LIMIT $1
+		)`
+
+	tag, err := r.pool.Exec(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", mapPgError(err))
+	}
+
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+)
+
+func TestPostgresIdempotencyRepository(t *testing.T) {
+	testIdempotencyRepository(t, NewPostgresIdempotencyRepository(testutil.Postgres(t)))
+}
+
+// testIdempotencyRepository checks that keys are held per owner, that an
+// expired key can be reserved again, and that DeleteExpired is bounded
+func testIdempotencyRepository(t *testing.T, repo IdempotencyRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	reserve := func(owner, key string, expiresAt time.Time) (*domain.IdempotencyKey, bool) {
+		t.Helper()
+
+		record, reserved, err := repo.Reserve(ctx, &domain.IdempotencyKey{
+			Owner: owner, Key: key, Endpoint: "POST /api/v1/users", RequestHash: owner, ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			t.Fatalf("Reserve(%s, %s): %v", owner, key, err)
+		}
+		return record, reserved
+	}
+	later := time.Now().UTC().Add(time.Hour)
+
+	if _, reserved := reserve("user:ada", "create", later); !reserved {
+		t.Fatal("first reservation was refused")
+	}
+	if held, reserved := reserve("user:ada", "create", later); reserved || held.RequestHash != "user:ada" {
+		t.Errorf("repeated reservation = %+v, %v; want the held record", held, reserved)
+	}
+	if _, reserved := reserve("user:grace", "create", later); !reserved {
+		t.Error("the same key of another owner was refused")
+	}
+
+	// An expired key is free again without waiting to be deleted
+	past := time.Now().UTC().Add(-time.Second)
+	for _, key := range []string{"expired-1", "expired-2", "expired-3"} {
+		reserve("user:ada", key, past)
+	}
+	if _, reserved := reserve("user:ada", "expired-1", past); !reserved {
+		t.Error("an expired key was refused")
+	}
+
+	deleted, err := repo.DeleteExpired(ctx, 2)
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteExpired(2) = %d, %v; want 2", deleted, err)
+	}
+	if deleted, err := repo.DeleteExpired(ctx, 10); err != nil || deleted != 1 {
+		t.Errorf("DeleteExpired(10) = %d, %v; want the last expired key", deleted, err)
+	}
+	if _, reserved := reserve("user:ada", "create", later); reserved {
+		t.Error("DeleteExpired removed a live key")
+	}
+}
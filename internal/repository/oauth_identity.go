@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// ErrOAuthIdentityNotFound is returned when no user is linked to a provider account
+var ErrOAuthIdentityNotFound = errors.New("oauth identity not found")
+
+// OAuthIdentityRepository defines the persistence operations for links
+// between users and identity provider accounts
+type OAuthIdentityRepository interface {
+	Get(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error)
+	// Link stores the identity, moving an existing link of the same provider
+	// account to identity.UserID
+	Link(ctx context.Context, identity *domain.OAuthIdentity) error
+}
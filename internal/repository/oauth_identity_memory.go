@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// InMemoryOAuthIdentityRepository is an OAuthIdentityRepository backed by a map
+type InMemoryOAuthIdentityRepository struct {
+	mu         sync.RWMutex
+	identities map[[2]string]domain.OAuthIdentity
+}
+
+// NewInMemoryOAuthIdentityRepository creates an empty in-memory identity repository
+func NewInMemoryOAuthIdentityRepository() *InMemoryOAuthIdentityRepository {
+	return &InMemoryOAuthIdentityRepository{identities: make(map[[2]string]domain.OAuthIdentity)}
+}
+
+// Get returns the link of the provider account
+func (r *InMemoryOAuthIdentityRepository) Get(_ context.Context, provider, subject string) (*domain.OAuthIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	identity, ok := r.identities[[2]string{provider, subject}]
+	if !ok {
+		return nil, ErrOAuthIdentityNotFound
+	}
+
+	return &identity, nil
+}
+
+// Link stores the identity, replacing any earlier link of the provider account
+func (r *InMemoryOAuthIdentityRepository) Link(_ context.Context, identity *domain.OAuthIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	identity.CreatedAt = time.Now().UTC()
+	r.identities[[2]string{identity.Provider, identity.Subject}] = *identity
+
+	return nil
+}
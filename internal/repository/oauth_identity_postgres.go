@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresOAuthIdentityRepository is an OAuthIdentityRepository backed by PostgreSQL
+type PostgresOAuthIdentityRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresOAuthIdentityRepository creates an identity repository using the given connection pool
+func NewPostgresOAuthIdentityRepository(pool *pgxpool.Pool) *PostgresOAuthIdentityRepository {
+	return &PostgresOAuthIdentityRepository{pool: pool}
+}
+
+// Get returns the link of the provider account
+func (r *PostgresOAuthIdentityRepository) Get(ctx context.Context, provider, subject string) (*domain.OAuthIdentity, error) {
+	const query = `
+		SELECT provider, subject, user_id, created_at
+		FROM oauth_identities
+		WHERE provider = $1 AND subject = $2`
+
+	var identity domain.OAuthIdentity
+	err := r.pool.QueryRow(ctx, query, provider, subject).Scan(
+		&identity.Provider, &identity.Subject, &identity.UserID, &identity.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOAuthIdentityNotFound
+		}
+		return nil, fmt.Errorf("get oauth identity: %w", err)
+	}
+
+	return &identity, nil
+}
+
+// Link stores the identity, replacing any earlier link of the provider account
+func (r *PostgresOAuthIdentityRepository) Link(ctx context.Context, identity *domain.OAuthIdentity) error {
+	const query = `
+		INSERT INTO oauth_identities (provider, subject, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (provider, subject) DO UPDATE
+		SET user_id = EXCLUDED.user_id, created_at = NOW()
+		RETURNING created_at`
+
+	err := r.pool.QueryRow(ctx, query, identity.Provider, identity.Subject, identity.UserID).Scan(&identity.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("link oauth identity: %w", mapPgError(err))
+	}
+
+	return nil
+}
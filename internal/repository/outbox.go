@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// ErrOutboxEventNotFound is returned when no outbox event matches the id
+var ErrOutboxEventNotFound = errors.New("outbox event not found")
+
+// OutboxRepository defines the persistence operations for outbox events
+type OutboxRepository interface {
+	// Add records the event and populates its generated fields. The Postgres
+	// implementation writes it in the transaction open on ctx, so it commits
+	// or rolls back with the change it announces.
+	Add(ctx context.Context, event *domain.OutboxEvent) error
+	// ClaimPending returns up to limit events that are neither sent nor
+	// dead, oldest first. The Postgres implementation locks them until the
+	// transaction open on ctx ends and skips those another transaction
+	// holds, so concurrent relays never claim the same event.
+	ClaimPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error)
+	// MarkSent records that the event was handed to its webhooks, in the
+	// transaction open on ctx
+	MarkSent(ctx context.Context, id string) error
+	// RecordFailure counts a failed attempt to hand the event over, marking
+	// it dead when dead is true
+	RecordFailure(ctx context.Context, id string, lastError string, dead bool) error
+}
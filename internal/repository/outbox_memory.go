@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// InMemoryOutboxRepository is an OutboxRepository backed by a map. Its
+// events do not survive a restart, so it only orders delivery for the
+// in-memory repositories.
+type InMemoryOutboxRepository struct {
+	mu     sync.Mutex
+	events map[string]domain.OutboxEvent
+}
+
+// NewInMemoryOutboxRepository creates an empty in-memory outbox repository
+func NewInMemoryOutboxRepository() *InMemoryOutboxRepository {
+	return &InMemoryOutboxRepository{events: make(map[string]domain.OutboxEvent)}
+}
+
+// Add stores the event and assigns its id
+func (r *InMemoryOutboxRepository) Add(_ context.Context, event *domain.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = uuid.NewString()
+	event.CreatedAt = time.Now().UTC()
+	stored := *event
+	stored.Payload = slices.Clone(event.Payload)
+	r.events[event.ID] = stored
+
+	return nil
+}
+
+// ClaimPending returns up to limit events that are neither sent nor dead,
+// oldest first. A process has a single relay, so nothing else competes for them.
+func (r *InMemoryOutboxRepository) ClaimPending(_ context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []*domain.OutboxEvent{}
+	for _, event := range r.events {
+		if event.SentAt == nil && !event.Dead {
+			events = append(events, &event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+
+	return events[:min(limit, len(events))], nil
+}
+
+// MarkSent records that the event was handed to its webhooks
+func (r *InMemoryOutboxRepository) MarkSent(_ context.Context, id string) error {
+	return r.update(id, func(event *domain.OutboxEvent) {
+		now := time.Now().UTC()
+		event.SentAt = &now
+	})
+}
+
+// RecordFailure counts a failed attempt, marking the event dead when dead is true
+func (r *InMemoryOutboxRepository) RecordFailure(_ context.Context, id string, lastError string, dead bool) error {
+	return r.update(id, func(event *domain.OutboxEvent) {
+		event.Attempts++
+		event.LastError = lastError
+		event.Dead = dead
+	})
+}
+
+// update applies change to the stored event
+func (r *InMemoryOutboxRepository) update(id string, change func(*domain.OutboxEvent)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[id]
+	if !ok {
+		return ErrOutboxEventNotFound
+	}
+	change(&event)
+	r.events[id] = event
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresOutboxRepository is an OutboxRepository backed by PostgreSQL
+type PostgresOutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresOutboxRepository creates an outbox repository using the given connection pool
+func NewPostgresOutboxRepository(pool *pgxpool.Pool) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{pool: pool}
+}
+
+// Add inserts the event in the transaction open on ctx, or on its own
+// outside of one, and populates its generated fields
+func (r *PostgresOutboxRepository) Add(ctx context.Context, event *domain.OutboxEvent) error {
+	const query = `
+		INSERT INTO outbox_events (type, payload)
+		VALUES ($1, $2)
+		RETURNING id, created_at`
+
+	err := conn(ctx, r.pool).QueryRow(ctx, query, event.Type, event.Payload).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("add outbox event: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// ClaimPending locks up to limit events that are neither sent nor dead,
+// oldest first, for the rest of the transaction open on ctx. Events locked
+// by another relay are skipped rather than waited for.
+func (r *PostgresOutboxRepository) ClaimPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	const query = `
+		SELECT id, type, payload, attempts, last_error, dead, created_at, sent_at
+		FROM outbox_events
+		WHERE sent_at IS NULL AND NOT dead
+		ORDER BY created_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := conn(ctx, r.pool).Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*domain.OutboxEvent{}
+	for rows.Next() {
+		var event domain.OutboxEvent
+		err := rows.Scan(
+			&event.ID, &event.Type, &event.Payload, &event.Attempts, &event.LastError, &event.Dead, &event.CreatedAt, &event.SentAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan outbox event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list outbox events: %w", err)
+	}
+
+	return events, nil
+}
+
+// MarkSent records that the event was handed to its webhooks, in the
+// transaction open on ctx
+func (r *PostgresOutboxRepository) MarkSent(ctx context.Context, id string) error {
+	tag, err := conn(ctx, r.pool).Exec(ctx, `UPDATE outbox_events SET sent_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("mark outbox event sent: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOutboxEventNotFound
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed attempt, marking the event dead when dead is true
+func (r *PostgresOutboxRepository) RecordFailure(ctx context.Context, id string, lastError string, dead bool) error {
+	const query = `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, last_error = $2, dead = $3
+		WHERE id = $1`
+
+	tag, err := conn(ctx, r.pool).Exec(ctx, query, id, lastError, dead)
+	if err != nil {
+		return fmt.Errorf("record outbox event failure: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOutboxEventNotFound
+	}
+
+	return nil
+}
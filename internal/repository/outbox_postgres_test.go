@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+)
+
+func TestPostgresOutboxRepositoryJoinsTransaction(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	users := NewPostgresUserRepository(pool, nil)
+	outbox := NewPostgresOutboxRepository(pool)
+
+	errAbort := errors.New("abort")
+	err := NewPostgresTransactor(pool).InTx(ctx, func(ctx context.Context) error {
+		if err := users.Create(ctx, newTestUser("ada@example.com")); err != nil {
+			return err
+		}
+		if err := outbox.Add(ctx, &domain.OutboxEvent{Type: domain.EventUserCreated, Payload: []byte(`{}`)}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("InTx error = %v, want %v", err, errAbort)
+	}
+
+	pending, err := outbox.ClaimPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("ClaimPending: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("pending events after rollback = %d, want 0", len(pending))
+	}
+}
+
+func TestPostgresOutboxRepositoryLifecycle(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewPostgresOutboxRepository(testutil.Postgres(t))
+
+	sent := &domain.OutboxEvent{Type: domain.EventUserCreated, Payload: []byte(`{}`)}
+	poison := &domain.OutboxEvent{Type: domain.EventUserCreated, Payload: []byte(`{}`)}
+	for _, event := range []*domain.OutboxEvent{sent, poison} {
+		if err := outbox.Add(ctx, event); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	if err := outbox.MarkSent(ctx, sent.ID); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+	if err := outbox.RecordFailure(ctx, poison.ID, "timeout", false); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+	pending, err := outbox.ClaimPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("ClaimPending: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != poison.ID || pending[0].Attempts != 1 || pending[0].LastError != "timeout" {
+		t.Fatalf("pending events = %+v, want the failed event with one attempt", pending)
+	}
+
+	if err := outbox.RecordFailure(ctx, poison.ID, "timeout", true); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+	pending, err = outbox.ClaimPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("ClaimPending: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("pending events after dead-lettering = %d, want 0", len(pending))
+	}
+
+	if err := outbox.MarkSent(ctx, "00000000-0000-0000-0000-000000000000"); !errors.Is(err, ErrOutboxEventNotFound) {
+		t.Errorf("MarkSent of an unknown event error = %v, want %v", err, ErrOutboxEventNotFound)
+	}
+}
+
+func TestPostgresOutboxRepositoryClaimSkipsLockedEvents(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	outbox := NewPostgresOutboxRepository(pool)
+	transactor := NewPostgresTransactor(pool)
+
+	for range 2 {
+		if err := outbox.Add(ctx, &domain.OutboxEvent{Type: domain.EventUserCreated, Payload: []byte(`{}`)}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	err := transactor.InTx(ctx, func(txCtx context.Context) error {
+		first, err := outbox.ClaimPending(txCtx, 1)
+		if err != nil || len(first) != 1 {
+			t.Fatalf("first claim = %+v, %v; want one event", first, err)
+		}
+
+		// Another relay's claim passes over the locked event instead of waiting
+		return transactor.InTx(ctx, func(otherCtx context.Context) error {
+			other, err := outbox.ClaimPending(otherCtx, 10)
+			if err != nil {
+				return err
+			}
+			if len(other) != 1 || other[0].ID == first[0].ID {
+				t.Errorf("second claim = %+v, want only the event not locked by the first", other)
+			}
+			return outbox.MarkSent(otherCtx, other[0].ID)
+		})
+	})
+	if err != nil {
+		t.Fatalf("InTx: %v", err)
+	}
+
+	// The first claim ended without marking its event sent, so it is pending again
+	if pending, err := outbox.ClaimPending(ctx, 10); err != nil || len(pending) != 1 {
+		t.Errorf("pending events = %+v, %v; want the one left unsent", pending, err)
+	}
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// Repository errors returned by password reset repositories
+var (
+	ErrPasswordResetNotFound = errors.New("password reset not found")
+	ErrPasswordResetUsed     = errors.New("password reset already used")
+)
+
+// PasswordResetRepository defines the persistence operations for password password reset tokens
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *domain.PasswordReset) error
+	GetByHash(ctx context.Context, tokenHash string) (*domain.PasswordReset, error)
+	// MarkUsed consumes the token, returning ErrPasswordResetUsed if it already was
+	MarkUsed(ctx context.Context, id string) error
+}
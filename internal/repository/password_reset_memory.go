@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// InMemoryPasswordResetRepository is a PasswordResetRepository backed by a map
+type InMemoryPasswordResetRepository struct {
+	mu     sync.RWMutex
+	resets map[string]domain.PasswordReset
+}
+
+// NewInMemoryPasswordResetRepository creates an empty in-memory password reset repository
+func NewInMemoryPasswordResetRepository() *InMemoryPasswordResetRepository {
+	return &InMemoryPasswordResetRepository{
+		resets: make(map[string]domain.PasswordReset),
+	}
+}
+
+// Create stores a new password reset token and assigns its id
+func (r *InMemoryPasswordResetRepository) Create(_ context.Context, reset *domain.PasswordReset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reset.ID = uuid.NewString()
+	reset.CreatedAt = time.Now().UTC()
+	r.resets[reset.ID] = *reset
+
+	return nil
+}
+
+// GetByHash returns the password reset token with the given hash
+func (r *InMemoryPasswordResetRepository) GetByHash(_ context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, reset := range r.resets {
+		if reset.TokenHash == tokenHash {
+			return &reset, nil
+		}
+	}
+
+	return nil, ErrPasswordResetNotFound
+}
+
+// MarkUsed records that the password reset token has been consumed
+func (r *InMemoryPasswordResetRepository) MarkUsed(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reset, ok := r.resets[id]
+	if !ok {
+		return ErrPasswordResetNotFound
+	}
+	if reset.UsedAt != nil {
+		return ErrPasswordResetUsed
+	}
+
+	now := time.Now().UTC()
+	reset.UsedAt = &now
+	r.resets[id] = reset
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresPasswordResetRepository is a PasswordResetRepository backed by PostgreSQL
+type PostgresPasswordResetRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresPasswordResetRepository creates a password reset repository using the given connection pool
+func NewPostgresPasswordResetRepository(pool *pgxpool.Pool) *PostgresPasswordResetRepository {
+	return &PostgresPasswordResetRepository{pool: pool}
+}
+
+// Create inserts a new password reset token and populates its generated fields
+func (r *PostgresPasswordResetRepository) Create(ctx context.Context, reset *domain.PasswordReset) error {
+	const query = `
+		INSERT INTO password_resets (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err := r.pool.QueryRow(ctx, query, reset.UserID, reset.TokenHash, reset.ExpiresAt).
+		Scan(&reset.ID, &reset.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create password reset: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// GetByHash returns the password reset token with the given hash
+func (r *PostgresPasswordResetRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	const query = `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_resets
+		WHERE token_hash = $1`
+
+	var reset domain.PasswordReset
+	err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&reset.ID, &reset.UserID, &reset.TokenHash,
+		&reset.ExpiresAt, &reset.UsedAt, &reset.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPasswordResetNotFound
+		}
+		return nil, fmt.Errorf("get password reset: %w", err)
+	}
+
+	return &reset, nil
+}
+
+// MarkUsed records that the password reset token has been consumed
+func (r *PostgresPasswordResetRepository) MarkUsed(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `UPDATE password_resets SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("mark password reset used: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		// The token was loaded by the caller, so no update means it was already used
+		return ErrPasswordResetUsed
+	}
+
+	return nil
+}
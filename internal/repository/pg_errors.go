@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL error codes handled by the repository
+const (
+	uniqueViolation           = "23505"
+	foreignKeyViolation       = "23503"
+	notNullViolation          = "23502"
+	checkViolation            = "23514"
+	invalidTextRepresentation = "22P02"
+)
+
+// Kinds of constraint violation a ConstraintError matches with errors.Is
+var (
+	ErrUniqueViolation     = errors.New("unique constraint violated")
+	ErrForeignKeyViolation = errors.New("foreign key constraint violated")
+	ErrNotNullViolation    = errors.New("not-null constraint violated")
+	ErrCheckViolation      = errors.New("check constraint violated")
+)
+
+// constraintKinds maps the SQLSTATEs of constraint violations to their kind
+var constraintKinds = map[string]error{
+	uniqueViolation:     ErrUniqueViolation,
+	foreignKeyViolation: ErrForeignKeyViolation,
+	notNullViolation:    ErrNotNullViolation,
+	checkViolation:      ErrCheckViolation,
+}
+
+// ConstraintError is a write rejected by a database constraint. It matches
+// its Kind with errors.Is, and its message names the table, constraint, and
+// column for the logs, which clients are not shown.
+type ConstraintError struct {
+	// Kind is one of ErrUniqueViolation, ErrForeignKeyViolation,
+	// ErrNotNullViolation, and ErrCheckViolation
+	Kind       error
+	Table      string
+	Constraint string
+	// Column is only reported for not-null violations
+	Column string
+	// Code is the SQLSTATE of the violation
+	Code  string
+	pgErr *pgconn.PgError
+}
+
+// Error describes the violation and where it happened
+func (e *ConstraintError) Error() string {
+	name := e.Constraint
+	if name == "" {
+		name = e.Column
+	}
+
+	return fmt.Sprintf("%s: %s on %s (SQLSTATE %s)", e.Kind, name, e.Table, e.Code)
+}
+
+// Unwrap returns the kind and the original PostgreSQL error
+func (e *ConstraintError) Unwrap() []error {
+	if e.pgErr == nil {
+		return []error{e.Kind}
+	}
+
+	return []error{e.Kind, e.pgErr}
+}
+
+// mapPgError converts a constraint violation into a ConstraintError and
+// returns any other error unchanged. Write methods run their errors through
+// it so callers can tell a conflicting or invalid write from a failure.
+func mapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	kind, ok := constraintKinds[pgErr.Code]
+	if !ok {
+		return err
+	}
+
+	return &ConstraintError{
+		Kind:       kind,
+		Table:      pgErr.TableName,
+		Constraint: pgErr.ConstraintName,
+		Column:     pgErr.ColumnName,
+		Code:       pgErr.Code,
+		pgErr:      pgErr,
+	}
+}
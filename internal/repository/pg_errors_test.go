@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestMapPgError(t *testing.T) {
+	tests := []struct {
+		code string
+		want error
+	}{
+		{"23505", ErrUniqueViolation},
+		{"23503", ErrForeignKeyViolation},
+		{"23502", ErrNotNullViolation},
+		{"23514", ErrCheckViolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			pgErr := &pgconn.PgError{Code: tt.code, TableName: "users", ConstraintName: "users_check", ColumnName: "name"}
+			err := mapPgError(fmt.Errorf("exec: %w", pgErr))
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("mapPgError(%s) = %v, want it to match %v", tt.code, err, tt.want)
+			}
+			var constraintErr *ConstraintError
+			if !errors.As(err, &constraintErr) {
+				t.Fatalf("mapPgError(%s) = %T, want a *ConstraintError", tt.code, err)
+			}
+			if constraintErr.Table != "users" || constraintErr.Constraint != "users_check" || constraintErr.Code != tt.code {
+				t.Errorf("ConstraintError = %+v, want the table, constraint, and code kept", constraintErr)
+			}
+			// The logs keep the details, and the original error stays reachable
+			if msg := err.Error(); !strings.Contains(msg, "users_check") || !strings.Contains(msg, tt.code) {
+				t.Errorf("message %q does not name the constraint and SQLSTATE", msg)
+			}
+			var original *pgconn.PgError
+			if !errors.As(err, &original) || original != pgErr {
+				t.Error("the PgError is not reachable with errors.As")
+			}
+		})
+	}
+}
+
+func TestMapPgErrorLeavesOtherErrors(t *testing.T) {
+	for _, err := range []error{
+		&pgconn.PgError{Code: "42P01"},
+		errors.New("connection reset"),
+	} {
+		if got := mapPgError(err); got != err {
+			t.Errorf("mapPgError(%v) = %v, want it unchanged", err, got)
+		}
+	}
+	if got := mapPgError(nil); got != nil {
+		t.Errorf("mapPgError(nil) = %v, want nil", got)
+	}
+}
+
+func TestMapWriteError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *pgconn.PgError
+		want error
+	}{
+		{"email", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_active_key"}, ErrDuplicateEmail},
+		{"phone", &pgconn.PgError{Code: "23505", ConstraintName: "users_phone_active_key"}, ErrDuplicatePhone},
+		{"role", &pgconn.PgError{Code: "23503", ConstraintName: "user_roles_role_fkey"}, ErrUnknownRole},
+		{"other constraint", &pgconn.PgError{Code: "23514", ConstraintName: "users_status_check"}, ErrCheckViolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := mapWriteError(tt.err); !errors.Is(err, tt.want) {
+				t.Errorf("mapWriteError(%s) = %v, want %v", tt.err.ConstraintName, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// ErrPreferencesNotFound is returned when a user has no stored preferences
+var ErrPreferencesNotFound = errors.New("preferences not found")
+
+// PreferencesRepository defines the persistence operations for user preferences
+type PreferencesRepository interface {
+	Get(ctx context.Context, userID string) (*domain.Preferences, error)
+	// GetMany returns the stored preferences of the users in one query, keyed
+	// by user id; users without any are left out
+	GetMany(ctx context.Context, userIDs []string) (map[string]*domain.Preferences, error)
+	// CreateIfMissing stores the preferences unless the user already has
+	// some, and returns the stored ones either way
+	CreateIfMissing(ctx context.Context, preferences *domain.Preferences) (*domain.Preferences, error)
+	// CreateDefaults stores the defaults for each of the users who have no
+	// preferences yet in one query and returns the ones it stored
+	CreateDefaults(ctx context.Context, userIDs []string) (map[string]*domain.Preferences, error)
+	// Save stores the preferences, replacing any the user had
+	Save(ctx context.Context, preferences *domain.Preferences) error
+}
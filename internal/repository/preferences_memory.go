@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// InMemoryPreferencesRepository is a PreferencesRepository backed by a map
+type InMemoryPreferencesRepository struct {
+	mu          sync.RWMutex
+	preferences map[string]domain.Preferences
+}
+
+// NewInMemoryPreferencesRepository creates an empty in-memory preferences repository
+func NewInMemoryPreferencesRepository() *InMemoryPreferencesRepository {
+	return &InMemoryPreferencesRepository{preferences: make(map[string]domain.Preferences)}
+}
+
+// Get returns the preferences of the user
+func (r *InMemoryPreferencesRepository) Get(_ context.Context, userID string) (*domain.Preferences, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	preferences, ok := r.preferences[userID]
+	if !ok {
+		return nil, ErrPreferencesNotFound
+	}
+
+	return &preferences, nil
+}
+
+// GetMany returns the stored preferences of the users, keyed by user id
+func (r *InMemoryPreferencesRepository) GetMany(_ context.Context, userIDs []string) (map[string]*domain.Preferences, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := make(map[string]*domain.Preferences, len(userIDs))
+	for _, userID := range userIDs {
+		if preferences, ok := r.preferences[userID]; ok {
+			found[userID] = &preferences
+		}
+	}
+
+	return found, nil
+}
+
+// CreateIfMissing stores the preferences unless the user already has some
+func (r *InMemoryPreferencesRepository) CreateIfMissing(_ context.Context, preferences *domain.Preferences) (*domain.Preferences, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.preferences[preferences.UserID]
+	if !ok {
+		stored = *preferences
+		stored.UpdatedAt = time.Now().UTC()
+		r.preferences[preferences.UserID] = stored
+	}
+
+	return &stored, nil
+}
+
+// CreateDefaults stores the defaults for the users without preferences
+func (r *InMemoryPreferencesRepository) CreateDefaults(_ context.Context, userIDs []string) (map[string]*domain.Preferences, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := make(map[string]*domain.Preferences, len(userIDs))
+	for _, userID := range userIDs {
+		if _, ok := r.preferences[userID]; ok {
+			continue
+		}
+		preferences := *domain.DefaultPreferences(userID)
+		preferences.UpdatedAt = time.Now().UTC()
+		r.preferences[userID] = preferences
+		created[userID] = &preferences
+	}
+
+	return created, nil
+}
+
+// Save stores the preferences, replacing any the user had
+func (r *InMemoryPreferencesRepository) Save(_ context.Context, preferences *domain.Preferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	preferences.UpdatedAt = time.Now().UTC()
+	r.preferences[preferences.UserID] = *preferences
+
+	return nil
+}
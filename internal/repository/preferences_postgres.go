@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// preferencesColumns lists the user_preferences columns in the order scanPreferences reads them
+const preferencesColumns = `user_id, locale, timezone, email_notifications, marketing_emails, updated_at`
+
+// PostgresPreferencesRepository is a PreferencesRepository backed by PostgreSQL
+type PostgresPreferencesRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresPreferencesRepository creates a preferences repository using the given connection pool
+func NewPostgresPreferencesRepository(pool *pgxpool.Pool) *PostgresPreferencesRepository {
+	return &PostgresPreferencesRepository{pool: pool}
+}
+
+// Get returns the preferences of the user
+func (r *PostgresPreferencesRepository) Get(ctx context.Context, userID string) (*domain.Preferences, error) {
+	query := `SELECT ` + preferencesColumns + ` FROM user_preferences WHERE user_id = $1`
+
+	preferences, err := scanPreferences(r.pool.QueryRow(ctx, query, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPreferencesNotFound
+		}
+		return nil, fmt.Errorf("get preferences: %w", err)
+	}
+
+	return preferences, nil
+}
+
+// GetMany returns the stored preferences of the users, keyed by user id
+func (r *PostgresPreferencesRepository) GetMany(ctx context.Context, userIDs []string) (map[string]*domain.Preferences, error) {
+	query := `SELECT ` + preferencesColumns + ` FROM user_preferences WHERE user_id = ANY($1::uuid[])`
+
+	rows, err := r.pool.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get preferences: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]*domain.Preferences, len(userIDs))
+	for rows.Next() {
+		preferences, err := scanPreferences(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan preferences: %w", err)
+		}
+		found[preferences.UserID] = preferences
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get preferences: %w", err)
+	}
+
+	return found, nil
+}
+
+// CreateIfMissing stores the preferences unless the user already has some.
+// Concurrent first reads may both try; the loser reads the winner's row.
+func (r *PostgresPreferencesRepository) CreateIfMissing(ctx context.Context, preferences *domain.Preferences) (*domain.Preferences, error) {
+	query := `
+		INSERT INTO user_preferences (user_id, locale, timezone, email_notifications, marketing_emails)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO NOTHING
+		RETURNING ` + preferencesColumns
+
+	created, err := scanPreferences(r.pool.QueryRow(ctx, query,
+		preferences.UserID, preferences.Locale, preferences.Timezone,
+		preferences.Notifications.Email, preferences.Notifications.Marketing,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.Get(ctx, preferences.UserID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create preferences: %w", mapPgError(err))
+	}
+
+	return created, nil
+}
+
+// CreateDefaults stores the defaults for the users without preferences,
+// leaving the column defaults to supply the values
+func (r *PostgresPreferencesRepository) CreateDefaults(ctx context.Context, userIDs []string) (map[string]*domain.Preferences, error) {
+	query := `
+		INSERT INTO user_preferences (user_id)
+		SELECT unnest($1::uuid[])
+		ON CONFLICT (user_id) DO NOTHING
+		RETURNING ` + preferencesColumns
+
+	rows, err := r.pool.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("create preferences: %w", mapPgError(err))
+	}
+	defer rows.Close()
+
+	created := make(map[string]*domain.Preferences, len(userIDs))
+	for rows.Next() {
+		preferences, err := scanPreferences(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan preferences: %w", err)
+		}
+		created[preferences.UserID] = preferences
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("create preferences: %w", mapPgError(err))
+	}
+
+	return created, nil
+}
+
+// Save stores the preferences, replacing any the user had
+func (r *PostgresPreferencesRepository) Save(ctx context.Context, preferences *domain.Preferences) error {
+	const query = `
+		INSERT INTO user_preferences (user_id, locale, timezone, email_notifications, marketing_emails)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET locale = EXCLUDED.locale,
+		    timezone = EXCLUDED.timezone,
+		    email_notifications = EXCLUDED.email_notifications,
+		    marketing_emails = EXCLUDED.marketing_emails,
+		    updated_at = NOW()
+		RETURNING updated_at`
+
+	err := r.pool.QueryRow(ctx, query,
+		preferences.UserID, preferences.Locale, preferences.Timezone,
+		preferences.Notifications.Email, preferences.Notifications.Marketing,
+	).Scan(&preferences.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("save preferences: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// scanPreferences reads a row of preferencesColumns
+func scanPreferences(row pgx.Row) (*domain.Preferences, error) {
+	var preferences domain.Preferences
+	err := row.Scan(
+		&preferences.UserID, &preferences.Locale, &preferences.Timezone,
+		&preferences.Notifications.Email, &preferences.Notifications.Marketing, &preferences.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preferences, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+)
+
+func TestPostgresPreferencesRepository(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	users := NewPostgresUserRepository(pool, nil)
+	repo := NewPostgresPreferencesRepository(pool)
+
+	ada, grace := newTestUser("ada@example.com"), newTestUser("grace@example.com")
+	for _, user := range []*domain.User{ada, grace} {
+		if err := users.Create(ctx, user); err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+	}
+
+	if _, err := repo.Get(ctx, ada.ID); !errors.Is(err, ErrPreferencesNotFound) {
+		t.Fatalf("Get before any write error = %v, want %v", err, ErrPreferencesNotFound)
+	}
+
+	created, err := repo.CreateIfMissing(ctx, domain.DefaultPreferences(ada.ID))
+	if err != nil {
+		t.Fatalf("CreateIfMissing: %v", err)
+	}
+	if created.Timezone != "UTC" || created.UpdatedAt.IsZero() {
+		t.Errorf("created preferences = %+v, want the stored defaults", created)
+	}
+
+	saved := &domain.Preferences{UserID: ada.ID, Locale: "th", Timezone: "Asia/Bangkok"}
+	if err := repo.Save(ctx, saved); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	// Saved preferences are kept over later defaults
+	again, err := repo.CreateIfMissing(ctx, domain.DefaultPreferences(ada.ID))
+	if err != nil {
+		t.Fatalf("CreateIfMissing again: %v", err)
+	}
+	if again.Timezone != "Asia/Bangkok" {
+		t.Errorf("CreateIfMissing replaced the saved timezone with %q", again.Timezone)
+	}
+
+	defaults, err := repo.CreateDefaults(ctx, []string{ada.ID, grace.ID})
+	if err != nil {
+		t.Fatalf("CreateDefaults: %v", err)
+	}
+	if _, ok := defaults[ada.ID]; ok || defaults[grace.ID] == nil {
+		t.Errorf("CreateDefaults stored %v, want grace's defaults only", defaults)
+	}
+
+	all, err := repo.GetMany(ctx, []string{ada.ID, grace.ID, "00000000-0000-0000-0000-000000000000"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(all) != 2 || all[ada.ID].Timezone != "Asia/Bangkok" || all[grace.ID].Timezone != "UTC" {
+		t.Errorf("GetMany = %v, want both users' stored preferences", all)
+	}
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// Repository errors returned by refresh token repositories
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenRevoked  = errors.New("refresh token revoked")
+)
+
+// RefreshTokenRepository defines the persistence operations for refresh tokens
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *domain.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
+	// Revoke marks the token revoked, returning ErrRefreshTokenRevoked if it already was
+	Revoke(ctx context.Context, id string) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeAllForUser ends every session belonging to the user except the
+	// keepFamilyID session; an empty keepFamilyID ends them all
+	RevokeAllForUser(ctx context.Context, userID, keepFamilyID string) error
+	// ListSessions returns the user's sessions that hold a live token, most
+	// recently used first
+	ListSessions(ctx context.Context, userID string) ([]*domain.Session, error)
+}
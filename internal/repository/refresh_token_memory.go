@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// InMemoryRefreshTokenRepository is a RefreshTokenRepository backed by a map
+type InMemoryRefreshTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]domain.RefreshToken
+}
+
+// NewInMemoryRefreshTokenRepository creates an empty in-memory refresh token repository
+func NewInMemoryRefreshTokenRepository() *InMemoryRefreshTokenRepository {
+	return &InMemoryRefreshTokenRepository{
+		tokens: make(map[string]domain.RefreshToken),
+	}
+}
+
+// Create stores a new refresh token and assigns its id
+func (r *InMemoryRefreshTokenRepository) Create(_ context.Context, token *domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = uuid.NewString()
+	token.CreatedAt = time.Now().UTC()
+	r.tokens[token.ID] = *token
+
+	return nil
+}
+
+// GetByHash returns the refresh token with the given hash
+func (r *InMemoryRefreshTokenRepository) GetByHash(_ context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+
+	return nil, ErrRefreshTokenNotFound
+}
+
+// Revoke marks a single refresh token as revoked
+func (r *InMemoryRefreshTokenRepository) Revoke(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok {
+		return ErrRefreshTokenNotFound
+	}
+	if token.Revoked {
+		return ErrRefreshTokenRevoked
+	}
+
+	token.Revoked = true
+	r.tokens[id] = token
+
+	return nil
+}
+
+// RevokeFamily marks every refresh token in the family as revoked
+func (r *InMemoryRefreshTokenRepository) RevokeFamily(_ context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			r.tokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+// RevokeAllForUser marks the refresh tokens of the user outside the kept family as revoked
+func (r *InMemoryRefreshTokenRepository) RevokeAllForUser(_ context.Context, userID, keepFamilyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserID == userID && token.FamilyID != keepFamilyID {
+			token.Revoked = true
+			r.tokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+// ListSessions returns the user's sessions that hold a live token
+func (r *InMemoryRefreshTokenRepository) ListSessions(_ context.Context, userID string) ([]*domain.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	started := make(map[string]time.Time)
+	for _, token := range r.tokens {
+		if token.UserID != userID {
+			continue
+		}
+		if first, ok := started[token.FamilyID]; !ok || token.CreatedAt.Before(first) {
+			started[token.FamilyID] = token.CreatedAt
+		}
+	}
+
+	now := time.Now()
+	sessions := make([]*domain.Session, 0)
+	for _, token := range r.tokens {
+		if token.UserID != userID || token.Revoked || !now.Before(token.ExpiresAt) {
+			continue
+		}
+		sessions = append(sessions, &domain.Session{
+			ID:         token.FamilyID,
+			CreatedAt:  started[token.FamilyID],
+			LastUsedAt: token.CreatedAt,
+			UserAgent:  token.UserAgent,
+			IP:         token.IP,
+		})
+	}
+	slices.SortFunc(sessions, func(a, b *domain.Session) int {
+		return b.LastUsedAt.Compare(a.LastUsedAt)
+	})
+
+	return sessions, nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresRefreshTokenRepository is a RefreshTokenRepository backed by PostgreSQL
+type PostgresRefreshTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRefreshTokenRepository creates a refresh token repository using the given connection pool
+func NewPostgresRefreshTokenRepository(pool *pgxpool.Pool) *PostgresRefreshTokenRepository {
+	return &PostgresRefreshTokenRepository{pool: pool}
+}
+
+// Create inserts a new refresh token and populates its generated fields
+func (r *PostgresRefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	const query = `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err := r.pool.QueryRow(ctx, query,
+		token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt, token.UserAgent, token.IP,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create refresh token: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// GetByHash returns the refresh token with the given hash
+func (r *PostgresRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	const query = `
+		SELECT id, user_id, family_id, token_hash, expires_at, revoked, user_agent, ip, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	var token domain.RefreshToken
+	err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash,
+		&token.ExpiresAt, &token.Revoked, &token.UserAgent, &token.IP, &token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("get refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// Revoke marks a single refresh token as revoked
+func (r *PostgresRefreshTokenRepository) Revoke(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `UPDATE refresh_tokens SET revoked = TRUE WHERE id = $1 AND NOT revoked`, id)
+	if err != nil {
+		return fmt.Errorf("revoke refresh token: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		// The token was loaded by the caller, so no update means it was already revoked
+		return ErrRefreshTokenRevoked
+	}
+
+	return nil
+}
+
+// RevokeFamily marks every refresh token in the family as revoked
+func (r *PostgresRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.pool.Exec(ctx, `UPDATE refresh_tokens SET revoked = TRUE WHERE family_id = $1`, familyID)
+	if err != nil {
+		return fmt.Errorf("revoke refresh token family: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// RevokeAllForUser marks the refresh tokens of the user outside the kept family as revoked
+func (r *PostgresRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID, keepFamilyID string) error {
+	const query = `
+		UPDATE refresh_tokens SET revoked = TRUE
+		WHERE user_id = $1 AND NOT revoked AND family_id::text <> $2`
+
+	_, err := r.pool.Exec(ctx, query, userID, keepFamilyID)
+	if err != nil {
+		return fmt.Errorf("revoke user refresh tokens: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// ListSessions returns the user's sessions that hold a live token
+func (r *PostgresRefreshTokenRepository) ListSessions(ctx context.Context, userID string) ([]*domain.Session, error) {
+	const query = `
+		SELECT t.family_id, f.started_at, t.created_at, t.user_agent, t.ip
+		FROM refresh_tokens t
+		JOIN (
+			SELECT family_id, MIN(created_at) AS started_at
+			FROM refresh_tokens
+			WHERE user_id = $1
+			GROUP BY family_id
+		) f ON f.family_id = t.family_id
+		WHERE t.user_id = $1 AND NOT t.revoked AND t.expires_at > NOW()
+		ORDER BY t.created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.Session, error) {
+		var session domain.Session
+		err := row.Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt, &session.UserAgent, &session.IP)
+		return &session, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// readPrimaryKey is the context key marking reads that must see the latest writes
+type readPrimaryKey struct{}
+
+// ReadPrimary returns a context whose reads go to the primary rather than
+// a replica, so they see writes made just before despite replication lag
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPrimaryKey{}, true)
+}
+
+// readConn returns the connection for a query that only reads: the replica,
+// unless none is configured, ctx asks for the primary with ReadPrimary, or a
+// transaction is open on ctx, in which case it is the same as conn
+func readConn(ctx context.Context, primary, replica *pgxpool.Pool) dbtx {
+	if replica == nil || ctx.Value(readPrimaryKey{}) != nil {
+		return conn(ctx, primary)
+	}
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return conn(ctx, primary)
+	}
+
+	return replica
+}
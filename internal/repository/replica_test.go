@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lazyPool returns a pool that never connects unless a query is run on it,
+// which tells pools apart without a database
+func lazyPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	pool, err := pgxpool.New(context.Background(), "postgres://postgres@127.0.0.1:1/users")
+	if err != nil {
+		t.Fatalf("create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+func TestReadConn(t *testing.T) {
+	primary, replica := lazyPool(t), lazyPool(t)
+	tx := &txState{}
+	inTx := context.WithValue(context.Background(), txKey{}, tx)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		replica *pgxpool.Pool
+		want    dbtx
+	}{
+		{"read", context.Background(), replica, replica},
+		{"no replica", context.Background(), nil, primary},
+		{"read primary", ReadPrimary(context.Background()), replica, primary},
+		{"transaction", inTx, replica, tx.tx},
+		{"read primary in a transaction", ReadPrimary(inTx), replica, tx.tx},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readConn(tt.ctx, primary, tt.replica); got != tt.want {
+				t.Errorf("readConn = %p, want %p", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresUserRepositoryReplicaReads(t *testing.T) {
+	ctx := context.Background()
+	// Two unrelated servers stand in for a replica that has not caught up
+	primary, replica := testutil.Postgres(t), testutil.Postgres(t)
+	repo := NewPostgresUserRepository(primary, replica)
+
+	user := newTestUser("ada@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetByID error = %v, want the replica's %v", err, ErrUserNotFound)
+	}
+	if _, total, err := repo.List(ctx, ListParams{Page: 1, PageSize: 10}); err != nil || total != 0 {
+		t.Errorf("List total = %d, %v, want the replica's 0", total, err)
+	}
+
+	got, err := repo.GetByID(ReadPrimary(ctx), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID on the primary: %v", err)
+	}
+	if got.Email != user.Email {
+		t.Errorf("GetByID on the primary = %+v, want the created user", got)
+	}
+	// Login lookups always read the primary
+	if _, err := repo.GetByEmail(ctx, user.Email); err != nil {
+		t.Errorf("GetByEmail: %v", err)
+	}
+}
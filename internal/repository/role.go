@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// RoleRepository reads what the roles users hold permit
+type RoleRepository interface {
+	// Permissions returns the permissions of every role in one query, keyed
+	// by role; roles granting none are left out
+	Permissions(ctx context.Context) (map[domain.Role][]domain.Permission, error)
+}
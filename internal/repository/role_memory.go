@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"slices"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// InMemoryRoleRepository is a RoleRepository granting the built-in roles
+// what the migrations grant them
+type InMemoryRoleRepository struct{}
+
+// NewInMemoryRoleRepository creates an in-memory role repository
+func NewInMemoryRoleRepository() *InMemoryRoleRepository {
+	return &InMemoryRoleRepository{}
+}
+
+// Permissions returns the permissions granted to each role
+func (r *InMemoryRoleRepository) Permissions(_ context.Context) (map[domain.Role][]domain.Permission, error) {
+	permissions := make(map[domain.Role][]domain.Permission, len(domain.DefaultRolePermissions))
+	for role, granted := range domain.DefaultRolePermissions {
+		permissions[role] = slices.Clone(granted)
+	}
+
+	return permissions, nil
+}
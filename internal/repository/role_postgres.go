@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresRoleRepository is a RoleRepository backed by PostgreSQL
+type PostgresRoleRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRoleRepository creates a role repository using the given connection pool
+func NewPostgresRoleRepository(pool *pgxpool.Pool) *PostgresRoleRepository {
+	return &PostgresRoleRepository{pool: pool}
+}
+
+// Permissions returns the permissions granted to each role
+func (r *PostgresRoleRepository) Permissions(ctx context.Context) (map[domain.Role][]domain.Permission, error) {
+	rows, err := r.pool.Query(ctx, `SELECT role, permission FROM role_permissions ORDER BY role, permission`)
+	if err != nil {
+		return nil, fmt.Errorf("list role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := make(map[domain.Role][]domain.Permission)
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, fmt.Errorf("scan role permission: %w", err)
+		}
+		permissions[domain.Role(role)] = append(permissions[domain.Role(role)], domain.Permission(permission))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list role permissions: %w", err)
+	}
+
+	return permissions, nil
+}
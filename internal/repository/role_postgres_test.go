@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+)
+
+func TestPostgresRoleRepositoryPermissions(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	repo := NewPostgresRoleRepository(pool)
+
+	// A role composed in the database needs no code change
+	if _, err := pool.Exec(ctx, `INSERT INTO roles (name) VALUES ('viewer')`); err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `INSERT INTO role_permissions (role, permission) VALUES ('viewer', 'users:read')`); err != nil {
+		t.Fatalf("grant permission: %v", err)
+	}
+
+	got, err := repo.Permissions(ctx)
+	if err != nil {
+		t.Fatalf("Permissions: %v", err)
+	}
+	if want := []domain.Permission{domain.PermissionUsersRead}; !slices.Equal(got["viewer"], want) {
+		t.Errorf("viewer permissions = %v, want %v", got["viewer"], want)
+	}
+	if _, ok := got[domain.RoleUser]; ok {
+		t.Errorf("user role has permissions %v, want none", got[domain.RoleUser])
+	}
+
+	// The seeded permissions match what the in-memory repository grants
+	for role, want := range domain.DefaultRolePermissions {
+		if !slices.Equal(slices.Sorted(slices.Values(got[role])), slices.Sorted(slices.Values(want))) {
+			t.Errorf("%s permissions = %v, want %v", role, got[role], want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// Repository errors returned by two-factor repositories
+var (
+	ErrTwoFactorNotFound    = errors.New("two-factor enrollment not found")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+	ErrTOTPStepUsed         = errors.New("totp code already used")
+)
+
+// TwoFactorRepository defines the persistence operations for TOTP enrollments
+// and their one-time recovery codes
+type TwoFactorRepository interface {
+	Get(ctx context.Context, userID string) (*domain.TwoFactor, error)
+	// SavePending stores a new unconfirmed enrollment, replacing any earlier
+	// pending one; a confirmed enrollment is left untouched
+	SavePending(ctx context.Context, enrollment *domain.TwoFactor) error
+	// Confirm enables the pending enrollment and replaces the user's recovery codes
+	Confirm(ctx context.Context, userID string, recoveryCodeHashes []string) error
+	// UseRecoveryCode consumes an unused recovery code, returning
+	// ErrRecoveryCodeNotFound if there is none with the hash
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	// UseTOTPStep records that a code of the time step was accepted,
+	// returning ErrTOTPStepUsed if a code of it or a later step already was
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// InMemoryTwoFactorRepository is a TwoFactorRepository backed by maps
+type InMemoryTwoFactorRepository struct {
+	mu          sync.RWMutex
+	enrollments map[string]domain.TwoFactor
+	// recoveryCodes holds the unused code hashes of each user
+	recoveryCodes map[string]map[string]bool
+}
+
+// NewInMemoryTwoFactorRepository creates an empty in-memory two-factor repository
+func NewInMemoryTwoFactorRepository() *InMemoryTwoFactorRepository {
+	return &InMemoryTwoFactorRepository{
+		enrollments:   make(map[string]domain.TwoFactor),
+		recoveryCodes: make(map[string]map[string]bool),
+	}
+}
+
+// Get returns the enrollment of the user
+func (r *InMemoryTwoFactorRepository) Get(_ context.Context, userID string) (*domain.TwoFactor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	enrollment, ok := r.enrollments[userID]
+	if !ok {
+		return nil, ErrTwoFactorNotFound
+	}
+
+	return &enrollment, nil
+}
+
+// SavePending stores an unconfirmed enrollment unless a confirmed one exists
+func (r *InMemoryTwoFactorRepository) SavePending(_ context.Context, enrollment *domain.TwoFactor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.enrollments[enrollment.UserID]; ok && existing.Enabled() {
+		return nil
+	}
+
+	enrollment.ConfirmedAt = nil
+	enrollment.LastTOTPStep = 0
+	enrollment.CreatedAt = time.Now().UTC()
+	r.enrollments[enrollment.UserID] = *enrollment
+
+	return nil
+}
+
+// Confirm enables the enrollment and replaces the user's recovery codes
+func (r *InMemoryTwoFactorRepository) Confirm(_ context.Context, userID string, recoveryCodeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enrollment, ok := r.enrollments[userID]
+	if !ok {
+		return ErrTwoFactorNotFound
+	}
+
+	now := time.Now().UTC()
+	enrollment.ConfirmedAt = &now
+	r.enrollments[userID] = enrollment
+
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = true
+	}
+	r.recoveryCodes[userID] = codes
+
+	return nil
+}
+
+// UseRecoveryCode consumes an unused recovery code of the user
+func (r *InMemoryTwoFactorRepository) UseRecoveryCode(_ context.Context, userID, codeHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.recoveryCodes[userID][codeHash] {
+		return ErrRecoveryCodeNotFound
+	}
+	delete(r.recoveryCodes[userID], codeHash)
+
+	return nil
+}
+
+// UseTOTPStep records the step unless a code of it or a later step was accepted
+func (r *InMemoryTwoFactorRepository) UseTOTPStep(_ context.Context, userID string, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enrollment, ok := r.enrollments[userID]
+	if !ok || enrollment.LastTOTPStep >= step {
+		return ErrTOTPStepUsed
+	}
+	enrollment.LastTOTPStep = step
+	r.enrollments[userID] = enrollment
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresTwoFactorRepository is a TwoFactorRepository backed by PostgreSQL
+type PostgresTwoFactorRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresTwoFactorRepository creates a two-factor repository using the given connection pool
+func NewPostgresTwoFactorRepository(pool *pgxpool.Pool) *PostgresTwoFactorRepository {
+	return &PostgresTwoFactorRepository{pool: pool}
+}
+
+// Get returns the enrollment of the user
+func (r *PostgresTwoFactorRepository) Get(ctx context.Context, userID string) (*domain.TwoFactor, error) {
+	const query = `
+		SELECT user_id, secret, confirmed_at, created_at, COALESCE(last_totp_step, 0)
+		FROM user_two_factor
+		WHERE user_id = $1`
+
+	var enrollment domain.TwoFactor
+	err := r.pool.QueryRow(ctx, query, userID).Scan(
+		&enrollment.UserID, &enrollment.Secret, &enrollment.ConfirmedAt, &enrollment.CreatedAt, &enrollment.LastTOTPStep,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTwoFactorNotFound
+		}
+		return nil, fmt.Errorf("get two-factor enrollment: %w", err)
+	}
+
+	return &enrollment, nil
+}
+
+// SavePending stores an unconfirmed enrollment unless a confirmed one exists
+func (r *PostgresTwoFactorRepository) SavePending(ctx context.Context, enrollment *domain.TwoFactor) error {
+	const query = `
+		INSERT INTO user_two_factor (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, created_at = NOW(), last_totp_step = NULL
+		WHERE user_two_factor.confirmed_at IS NULL
+		RETURNING created_at`
+
+	err := r.pool.QueryRow(ctx, query, enrollment.UserID, enrollment.Secret).Scan(&enrollment.CreatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		// No row means a confirmed enrollment already exists and was kept
+		return fmt.Errorf("save two-factor enrollment: %w", mapPgError(err))
+	}
+	enrollment.ConfirmedAt = nil
+	enrollment.LastTOTPStep = 0
+
+	return nil
+}
+
+// Confirm enables the enrollment and replaces the user's recovery codes in one transaction
+func (r *PostgresTwoFactorRepository) Confirm(ctx context.Context, userID string, recoveryCodeHashes []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin two-factor confirmation: %w", err)
+	}
+	// Rolling back is a no-op once the transaction has been committed
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE user_two_factor SET confirmed_at = NOW() WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("confirm two-factor enrollment: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTwoFactorNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", mapPgError(err))
+	}
+
+	const insert = `
+		INSERT INTO recovery_codes (user_id, code_hash)
+		SELECT $1, UNNEST($2::text[])`
+	if _, err := tx.Exec(ctx, insert, userID, recoveryCodeHashes); err != nil {
+		return fmt.Errorf("create recovery codes: %w", mapPgError(err))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit two-factor confirmation: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode consumes an unused recovery code of the user
+func (r *PostgresTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	const query = `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	tag, err := r.pool.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("use recovery code: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+// UseTOTPStep records the step unless a code of it or a later step was accepted
+func (r *PostgresTwoFactorRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	const query = `
+		UPDATE user_two_factor SET last_totp_step = $2
+		WHERE user_id = $1 AND (last_totp_step IS NULL OR last_totp_step < $2)`
+
+	tag, err := r.pool.Exec(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("use totp step: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPStepUsed
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Transactor runs a unit of work so the writes made through the context it
+// passes on commit or roll back together
+type Transactor interface {
+	// InTx runs fn in a transaction, committing when it returns nil and
+	// rolling back otherwise. Calls nested in fn join the outer transaction.
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// InMemoryTransactor is the Transactor of the in-memory repositories. Their
+// writes apply immediately, so it only runs the work and cannot roll back.
+type InMemoryTransactor struct{}
+
+// NewInMemoryTransactor creates a Transactor for the in-memory repositories
+func NewInMemoryTransactor() InMemoryTransactor {
+	return InMemoryTransactor{}
+}
+
+// InTx runs fn with ctx unchanged
+func (InMemoryTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// PostgresTransactor runs work in a PostgreSQL transaction that the Postgres
+// repositories supporting it pick up from the context
+type PostgresTransactor struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresTransactor creates a Transactor using the given connection pool
+func NewPostgresTransactor(pool *pgxpool.Pool) *PostgresTransactor {
+	return &PostgresTransactor{pool: pool}
+}
+
+// txKey is the context key of the open transaction
+type txKey struct{}
+
+// txState is the transaction open on a context and the work waiting for it
+// to commit
+type txState struct {
+	tx          pgx.Tx
+	afterCommit []func()
+}
+
+// InTx runs fn in a new transaction, or in the one already open on ctx
+func (t *PostgresTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// Rolling back is a no-op once the transaction has been committed
+	defer tx.Rollback(ctx)
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	for _, run := range state.afterCommit {
+		run()
+	}
+
+	return nil
+}
+
+// afterCommit runs fn once the transaction open on ctx commits, or at once
+// outside of one
+func afterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+
+	fn()
+}
+
+// dbtx is the query interface shared by the pool and transactions
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// conn returns the transaction open on ctx, or the pool outside of one
+func conn(ctx context.Context, pool *pgxpool.Pool) dbtx {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+
+	return pool
+}
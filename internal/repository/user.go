@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// Repository errors returned by user repositories
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrDuplicateEmail = errors.New("email already exists")
+	ErrDuplicatePhone = errors.New("phone number already exists")
+	ErrUserModified   = errors.New("user was modified concurrently")
+	// ErrVersionConflict is returned when a user is saved with a Version other than the stored one
+	ErrVersionConflict = errors.New("user version conflict")
+	// ErrUnknownRole is returned when a user is given a role the roles table does not define
+	ErrUnknownRole = errors.New("unknown role")
+)
+
+// User fields that listings can be sorted by
+const (
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+	SortByName      = "name"
+	SortByEmail     = "email"
+)
+
+// UserSortFields is the set of fields accepted in ListParams.Sort
+var UserSortFields = map[string]bool{
+	SortByCreatedAt: true,
+	SortByUpdatedAt: true,
+	SortByName:      true,
+	SortByEmail:     true,
+}
+
+// UserFilter narrows which users a listing returns; all set fields must match
+type UserFilter struct {
+	// Query matches a case-insensitive substring of the name or email
+	Query string
+	// Role matches users holding the role itself, not one that grants it
+	Role domain.Role
+	// EmailVerified, when set, matches users by whether their email is verified
+	EmailVerified *bool
+	// IncludeDeleted also returns soft-deleted users
+	IncludeDeleted bool
+}
+
+// ListParams controls filtering, paging, and ordering of a user listing
+type ListParams struct {
+	Filter   UserFilter
+	Page     int
+	PageSize int
+	Sort     string
+	Desc     bool
+}
+
+// Offset returns the number of rows to skip for the requested page
+func (p ListParams) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// Cursor identifies the last user of a keyset page; users are ordered by
+// creation time with the id as a tie-breaker
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// UserRepository defines the persistence operations for users. Soft-deleted
+// users are invisible to every method except Restore and filtered listings.
+type UserRepository interface {
+	Create(ctx context.Context, user *domain.User) error
+	// CreateBatch inserts the users in one transaction and returns a per-user
+	// error slice, with nil marking success. When atomic is true and any user
+	// fails, none are stored. The returned error reports a failure of the whole batch.
+	CreateBatch(ctx context.Context, users []*domain.User, atomic bool) ([]error, error)
+	GetByID(ctx context.Context, id string) (*domain.User, error)
+	// GetByEmail compares emails case-insensitively, as uniqueness does
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+	// List returns one page of users along with the total number of users
+	List(ctx context.Context, params ListParams) ([]*domain.User, int, error)
+	// ListAfter returns up to limit users ordered after the cursor, or from the start when it is nil
+	ListAfter(ctx context.Context, filter UserFilter, cursor *Cursor, limit int) ([]*domain.User, error)
+	// Update saves the user's profile and increments its Version, returning
+	// ErrVersionConflict unless user.Version is the stored version. Roles,
+	// which SetRoles saves, and login failure tracking are left untouched.
+	Update(ctx context.Context, user *domain.User) error
+	// UpdateIfUnmodified saves like Update, but only while the stored user's
+	// UpdatedAt still equals since, returning ErrUserModified otherwise
+	UpdateIfUnmodified(ctx context.Context, user *domain.User, since time.Time) error
+	// SetRoles replaces the user's roles with user.Roles and refreshes its
+	// UpdatedAt and Version, returning ErrUnknownRole for an undefined role
+	SetRoles(ctx context.Context, user *domain.User) error
+	// RecordLoginFailure counts a failed login and locks the account until
+	// lockedUntil once threshold consecutive failures are reached. It returns
+	// the resulting lock expiry, which is nil while the account is unlocked.
+	RecordLoginFailure(ctx context.Context, id string, threshold int, lockedUntil time.Time) (*time.Time, error)
+	// ResetLoginFailures clears the failed login counter and any lock
+	ResetLoginFailures(ctx context.Context, id string) error
+	// Delete soft-deletes the user, keeping the row so it can be restored
+	Delete(ctx context.Context, id string) error
+	// DeleteBatch soft-deletes the users with the given ids in one
+	// transaction and returns the ids it deleted. Ids of missing or already
+	// deleted users are left out.
+	DeleteBatch(ctx context.Context, ids []string) ([]string, error)
+	// Restore undoes a soft delete, returning ErrDuplicateEmail if the email
+	// has since been taken by another user
+	Restore(ctx context.Context, id string) error
+	// Erase soft-deletes the user, saving the anonymized email, name, and
+	// password hash of user and clearing its other personal fields, and
+	// removes what other tables keep about it, such as sessions, linked
+	// identities, two-factor secrets, preferences, and API keys. The row
+	// stays, so audit entries still refer to it.
+	Erase(ctx context.Context, user *domain.User) error
+}
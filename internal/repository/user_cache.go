@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"golang.org/x/sync/singleflight"
+)
+
+// CachingUserRepository wraps a UserRepository and caches GetByID results in
+// a size-bounded LRU with a TTL. Writes through the wrapper evict the user,
+// but writes made by other processes are only seen once the entry expires.
+type CachingUserRepository struct {
+	UserRepository
+
+	cache *expirable.LRU[string, domain.User]
+	loads singleflight.Group
+
+	// mu guards generation, which counts invalidations so a load racing a
+	// write does not store the stale user it read
+	mu         sync.Mutex
+	generation uint64
+}
+
+// NewCachingUserRepository caches up to maxEntries users from next for ttl each
+func NewCachingUserRepository(next UserRepository, maxEntries int, ttl time.Duration) *CachingUserRepository {
+	return &CachingUserRepository{
+		UserRepository: next,
+		cache:          expirable.NewLRU[string, domain.User](maxEntries, nil, ttl),
+	}
+}
+
+// GetByID returns the cached user, loading it once for concurrent misses
+func (r *CachingUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	if user, ok := r.cache.Get(id); ok {
+		return &user, nil
+	}
+
+	value, err, _ := r.loads.Do(id, func() (any, error) {
+		r.mu.Lock()
+		generation := r.generation
+		r.mu.Unlock()
+
+		// The load is shared, so one caller going away must not fail the others
+		user, err := r.UserRepository.GetByID(context.WithoutCancel(ctx), id)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		if r.generation == generation {
+			// Key by the stored id, as id may alias a reused request buffer
+			r.cache.Add(user.ID, *user)
+		}
+		r.mu.Unlock()
+
+		return *user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every caller gets its own copy, since services modify the users they read
+	user := value.(domain.User)
+	return &user, nil
+}
+
+// Update saves the user and evicts the cached copy
+func (r *CachingUserRepository) Update(ctx context.Context, user *domain.User) error {
+	defer r.invalidate(ctx, user.ID)
+	return r.UserRepository.Update(ctx, user)
+}
+
+// UpdateIfUnmodified saves the user unless it changed and evicts the cached copy
+func (r *CachingUserRepository) UpdateIfUnmodified(ctx context.Context, user *domain.User, since time.Time) error {
+	defer r.invalidate(ctx, user.ID)
+	return r.UserRepository.UpdateIfUnmodified(ctx, user, since)
+}
+
+// SetRoles replaces the user's roles and evicts the cached copy
+func (r *CachingUserRepository) SetRoles(ctx context.Context, user *domain.User) error {
+	defer r.invalidate(ctx, user.ID)
+	return r.UserRepository.SetRoles(ctx, user)
+}
+
+// RecordLoginFailure counts a failed login and evicts the cached copy
+func (r *CachingUserRepository) RecordLoginFailure(ctx context.Context, id string, threshold int, lockedUntil time.Time) (*time.Time, error) {
+	defer r.invalidate(ctx, id)
+	return r.UserRepository.RecordLoginFailure(ctx, id, threshold, lockedUntil)
+}
+
+// ResetLoginFailures clears the failed login counter and evicts the cached copy
+func (r *CachingUserRepository) ResetLoginFailures(ctx context.Context, id string) error {
+	defer r.invalidate(ctx, id)
+	return r.UserRepository.ResetLoginFailures(ctx, id)
+}
+
+// Delete soft-deletes the user and evicts the cached copy
+func (r *CachingUserRepository) Delete(ctx context.Context, id string) error {
+	defer r.invalidate(ctx, id)
+	return r.UserRepository.Delete(ctx, id)
+}
+
+// DeleteBatch soft-deletes the users and evicts their cached copies
+func (r *CachingUserRepository) DeleteBatch(ctx context.Context, ids []string) ([]string, error) {
+	defer func() {
+		for _, id := range ids {
+			r.invalidate(ctx, id)
+		}
+	}()
+	return r.UserRepository.DeleteBatch(ctx, ids)
+}
+
+// Erase anonymizes the user and evicts the cached copy
+func (r *CachingUserRepository) Erase(ctx context.Context, user *domain.User) error {
+	defer r.invalidate(ctx, user.ID)
+	return r.UserRepository.Erase(ctx, user)
+}
+
+// Restore undoes a soft delete and evicts any cached state for the user
+func (r *CachingUserRepository) Restore(ctx context.Context, id string) error {
+	defer r.invalidate(ctx, id)
+	return r.UserRepository.Restore(ctx, id)
+}
+
+// invalidate evicts the user and stops in-flight loads from caching it.
+// It runs after the write, so a concurrent read cannot refill the old state,
+// and again once a transaction open on ctx commits, since reads until then
+// still see the old state.
+func (r *CachingUserRepository) invalidate(ctx context.Context, id string) {
+	r.evict(id)
+	afterCommit(ctx, func() { r.evict(id) })
+}
+
+// evict removes the cached user and bumps the generation
+func (r *CachingUserRepository) evict(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.cache.Remove(id)
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// countingUserRepository counts the GetByID calls that reach it, holding
+// each until release is closed when release is set
+type countingUserRepository struct {
+	UserRepository
+	gets    atomic.Int32
+	release chan struct{}
+}
+
+func (r *countingUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	r.gets.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+
+	return r.UserRepository.GetByID(ctx, id)
+}
+
+// newCachedTestUser stores a user behind a cache with the given TTL
+func newCachedTestUser(t *testing.T, ttl time.Duration) (*CachingUserRepository, *countingUserRepository, *domain.User) {
+	t.Helper()
+
+	next := &countingUserRepository{UserRepository: NewInMemoryUserRepository()}
+	user := newTestUser("ada@example.com")
+	if err := next.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	return NewCachingUserRepository(next, 10, ttl), next, user
+}
+
+func TestCachingUserRepositoryHit(t *testing.T) {
+	ctx := context.Background()
+	cache, next, user := newCachedTestUser(t, time.Minute)
+
+	first, err := cache.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	first.Name = "changed by the caller"
+	second, err := cache.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("second GetByID: %v", err)
+	}
+
+	if got := next.gets.Load(); got != 1 {
+		t.Errorf("underlying GetByID ran %d times, want 1", got)
+	}
+	if second.Name != "Test User" {
+		t.Errorf("cached name = %q, want it unaffected by changes to an earlier copy", second.Name)
+	}
+}
+
+func TestCachingUserRepositoryExpires(t *testing.T) {
+	ctx := context.Background()
+	cache, next, user := newCachedTestUser(t, 20*time.Millisecond)
+
+	if _, err := cache.GetByID(ctx, user.ID); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := cache.GetByID(ctx, user.ID); err != nil {
+		t.Fatalf("GetByID after the TTL: %v", err)
+	}
+
+	if got := next.gets.Load(); got != 2 {
+		t.Errorf("underlying GetByID ran %d times, want 2", got)
+	}
+}
+
+func TestCachingUserRepositoryWritesEvict(t *testing.T) {
+	ctx := context.Background()
+	cache, next, user := newCachedTestUser(t, time.Minute)
+
+	cached, err := cache.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	cached.Name = "Ada Lovelace"
+	if err := cache.Update(ctx, cached); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	updated, err := cache.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID after Update: %v", err)
+	}
+	if updated.Name != "Ada Lovelace" || next.gets.Load() != 2 {
+		t.Errorf("GetByID after Update = %q after %d loads, want the new name from a second load", updated.Name, next.gets.Load())
+	}
+
+	if err := cache.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := cache.GetByID(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetByID after Delete error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestCachingUserRepositorySharesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	cache, next, user := newCachedTestUser(t, time.Minute)
+	next.release = make(chan struct{})
+
+	const readers = 20
+	var wg sync.WaitGroup
+	for range readers {
+		wg.Go(func() {
+			if _, err := cache.GetByID(ctx, user.ID); err != nil {
+				t.Errorf("GetByID: %v", err)
+			}
+		})
+	}
+	// Let the readers pile up on the first load before it completes
+	time.Sleep(20 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	if got := next.gets.Load(); got != 1 {
+		t.Errorf("underlying GetByID ran %d times for %d concurrent misses, want 1", got, readers)
+	}
+}
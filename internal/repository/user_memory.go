@@ -0,0 +1,445 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// InMemoryUserRepository is a UserRepository backed by a map
+type InMemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]domain.User
+	// roles are the roles users may be given, as seeded by the migrations
+	roles map[domain.Role]bool
+}
+
+// NewInMemoryUserRepository creates an empty in-memory user repository
+func NewInMemoryUserRepository() *InMemoryUserRepository {
+	return &InMemoryUserRepository{
+		users: make(map[string]domain.User),
+		roles: map[domain.Role]bool{domain.RoleUser: true, domain.RoleAdmin: true, domain.RoleSuperAdmin: true},
+	}
+}
+
+// Create stores a new user and assigns its id and timestamps
+func (r *InMemoryUserRepository) Create(_ context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.conflict(user, ""); err != nil {
+		return err
+	}
+	if err := r.checkRoles(user.Roles); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	user.ID = uuid.NewString()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.Version = 1
+	r.users[user.ID] = withRoles(*user, user.Roles)
+
+	return nil
+}
+
+// CreateBatch stores the users, skipping those with a taken email, or none of them in atomic mode
+func (r *InMemoryUserRepository) CreateBatch(_ context.Context, users []*domain.User, atomic bool) ([]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// An undefined role fails the whole batch, as it does in PostgreSQL
+	for _, user := range users {
+		if err := r.checkRoles(user.Roles); err != nil {
+			return nil, err
+		}
+	}
+
+	errs := make([]error, len(users))
+	var created []string
+	failed := false
+	now := time.Now().UTC()
+	for i, user := range users {
+		if err := r.conflict(user, ""); err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
+
+		user.ID = uuid.NewString()
+		user.CreatedAt = now
+		user.UpdatedAt = now
+		user.Version = 1
+		r.users[user.ID] = withRoles(*user, user.Roles)
+		created = append(created, user.ID)
+	}
+
+	if atomic && failed {
+		for _, id := range created {
+			delete(r.users, id)
+		}
+	}
+
+	return errs, nil
+}
+
+// GetByID returns the user with the given id
+func (r *InMemoryUserRepository) GetByID(_ context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+// GetByEmail returns the user with the given email, ignoring case
+func (r *InMemoryUserRepository) GetByEmail(_ context.Context, email string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) && user.DeletedAt == nil {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
+
+// List returns one page of users in the requested order
+func (r *InMemoryUserRepository) List(_ context.Context, params ListParams) ([]*domain.User, int, error) {
+	r.mu.RLock()
+	users := make([]*domain.User, 0, len(r.users))
+	for _, user := range r.users {
+		if matchesFilter(&user, params.Filter) {
+			users = append(users, &user)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		cmp := compareUsers(users[i], users[j], params.Sort)
+		if cmp == 0 {
+			cmp = strings.Compare(users[i].ID, users[j].ID)
+		}
+		if params.Desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	total := len(users)
+	start := min(params.Offset(), total)
+	end := min(start+params.PageSize, total)
+
+	return users[start:end], total, nil
+}
+
+// ListAfter returns up to limit users created after the cursor position
+func (r *InMemoryUserRepository) ListAfter(_ context.Context, filter UserFilter, cursor *Cursor, limit int) ([]*domain.User, error) {
+	r.mu.RLock()
+	users := make([]*domain.User, 0, len(r.users))
+	for _, user := range r.users {
+		if matchesFilter(&user, filter) && (cursor == nil || isAfterCursor(&user, cursor)) {
+			users = append(users, &user)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		return isAfterCursor(users[j], &Cursor{CreatedAt: users[i].CreatedAt, ID: users[i].ID})
+	})
+
+	return users[:min(limit, len(users))], nil
+}
+
+// matchesFilter reports whether the user should be included in a filtered listing
+func matchesFilter(user *domain.User, filter UserFilter) bool {
+	if !filter.IncludeDeleted && user.DeletedAt != nil {
+		return false
+	}
+	if filter.Role != "" && !slices.Contains(user.Roles, filter.Role) {
+		return false
+	}
+	if filter.EmailVerified != nil && (user.EmailVerifiedAt != nil) != *filter.EmailVerified {
+		return false
+	}
+	if filter.Query != "" {
+		query := strings.ToLower(filter.Query)
+		if !strings.Contains(strings.ToLower(user.Name), query) && !strings.Contains(strings.ToLower(user.Email), query) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isAfterCursor reports whether the user sorts after the cursor position
+func isAfterCursor(user *domain.User, cursor *Cursor) bool {
+	if cmp := user.CreatedAt.Compare(cursor.CreatedAt); cmp != 0 {
+		return cmp > 0
+	}
+
+	return user.ID > cursor.ID
+}
+
+// compareUsers orders two users by the given sort field
+func compareUsers(a, b *domain.User, field string) int {
+	switch field {
+	case SortByUpdatedAt:
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	case SortByName:
+		return strings.Compare(a.Name, b.Name)
+	case SortByEmail:
+		return strings.Compare(a.Email, b.Email)
+	default:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+}
+
+// Update replaces the stored user unless it was saved since user.Version
+// was read, refreshing its updated timestamp
+func (r *InMemoryUserRepository) Update(_ context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.update(user, nil)
+}
+
+// UpdateIfUnmodified saves the user unless it changed after since
+func (r *InMemoryUserRepository) UpdateIfUnmodified(_ context.Context, user *domain.User, since time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.update(user, &since)
+}
+
+// update saves the user at the next version, requiring an unchanged
+// UpdatedAt when since is set; the caller must hold the write lock
+func (r *InMemoryUserRepository) update(user *domain.User, since *time.Time) error {
+	existing, ok := r.users[user.ID]
+	if !ok || existing.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	if since != nil && (!existing.UpdatedAt.Equal(*since) || existing.Version != user.Version) {
+		return ErrUserModified
+	}
+	if existing.Version != user.Version {
+		return ErrVersionConflict
+	}
+	if err := r.conflict(user, user.ID); err != nil {
+		return err
+	}
+
+	user.CreatedAt = existing.CreatedAt
+	user.DeletedAt = nil
+	user.UpdatedAt = time.Now().UTC()
+	user.Version = existing.Version + 1
+	user.Roles = existing.Roles
+	user.FailedLoginAttempts = existing.FailedLoginAttempts
+	user.LockedUntil = existing.LockedUntil
+	r.users[user.ID] = *user
+
+	return nil
+}
+
+// SetRoles replaces the user's roles and moves it to its next version
+func (r *InMemoryUserRepository) SetRoles(_ context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[user.ID]
+	if !ok || existing.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	if err := r.checkRoles(user.Roles); err != nil {
+		return err
+	}
+
+	existing = withRoles(existing, user.Roles.Normalize())
+	existing.UpdatedAt = time.Now().UTC()
+	existing.Version++
+	r.users[user.ID] = existing
+	user.UpdatedAt = existing.UpdatedAt
+	user.Version = existing.Version
+
+	return nil
+}
+
+// checkRoles returns ErrUnknownRole unless every role is defined
+func (r *InMemoryUserRepository) checkRoles(roles domain.Roles) error {
+	for _, role := range roles {
+		if !r.roles[role] {
+			return ErrUnknownRole
+		}
+	}
+
+	return nil
+}
+
+// withRoles returns the user holding its own copy of roles, so callers
+// changing theirs cannot change the stored user
+func withRoles(user domain.User, roles domain.Roles) domain.User {
+	user.Roles = slices.Clip(slices.Clone(roles))
+	return user
+}
+
+// RecordLoginFailure increments the failed login counter, locking the account at the threshold
+func (r *InMemoryUserRepository) RecordLoginFailure(_ context.Context, id string, threshold int, lockedUntil time.Time) (*time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	user.FailedLoginAttempts++
+	if user.FailedLoginAttempts >= threshold {
+		// Start counting afresh once the lock expires
+		user.FailedLoginAttempts = 0
+		user.LockedUntil = &lockedUntil
+	}
+	r.users[id] = user
+
+	return user.LockedUntil, nil
+}
+
+// ResetLoginFailures clears the failed login counter and any lock
+func (r *InMemoryUserRepository) ResetLoginFailures(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	r.users[id] = user
+
+	return nil
+}
+
+// Delete marks the user with the given id as deleted
+func (r *InMemoryUserRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+
+	now := time.Now().UTC()
+	user.DeletedAt = &now
+	user.UpdatedAt = now
+	user.Version++
+	r.users[id] = user
+
+	return nil
+}
+
+// DeleteBatch marks the users with the given ids as deleted
+func (r *InMemoryUserRepository) DeleteBatch(_ context.Context, ids []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	deleted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		user, ok := r.users[id]
+		if !ok || user.DeletedAt != nil {
+			continue
+		}
+		user.DeletedAt = &now
+		user.UpdatedAt = now
+		user.Version++
+		r.users[id] = user
+		deleted = append(deleted, id)
+	}
+
+	return deleted, nil
+}
+
+// Restore clears the deletion mark of the user with the given id
+func (r *InMemoryUserRepository) Restore(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt == nil {
+		return ErrUserNotFound
+	}
+	if err := r.conflict(&user, id); err != nil {
+		return err
+	}
+
+	user.DeletedAt = nil
+	user.UpdatedAt = time.Now().UTC()
+	user.Version++
+	r.users[id] = user
+
+	return nil
+}
+
+// Erase anonymizes and soft-deletes the user. The other in-memory
+// repositories are separate stores, so what they keep about the user stays.
+func (r *InMemoryUserRepository) Erase(_ context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok || stored.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+
+	now := time.Now().UTC()
+	stored.Email = user.Email
+	stored.Name = user.Name
+	stored.PasswordHash = user.PasswordHash
+	stored.Phone = ""
+	stored.EmailVerifiedAt = nil
+	stored.AvatarKey = ""
+	stored.AvatarURL = ""
+	stored.FailedLoginAttempts = 0
+	stored.LockedUntil = nil
+	stored.DeletedAt = &now
+	stored.UpdatedAt = now
+	stored.Version++
+	r.users[user.ID] = stored
+	*user = withRoles(stored, stored.Roles)
+
+	return nil
+}
+
+// conflict returns ErrDuplicateEmail or ErrDuplicatePhone when another active
+// user already uses the user's email or phone number; soft-deleted users
+// release both
+func (r *InMemoryUserRepository) conflict(user *domain.User, exceptID string) error {
+	for id, other := range r.users {
+		if id == exceptID || other.DeletedAt != nil {
+			continue
+		}
+		if strings.EqualFold(other.Email, user.Email) {
+			return ErrDuplicateEmail
+		}
+		if user.Phone != "" && other.Phone == user.Phone {
+			return ErrDuplicatePhone
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// newTestUser returns an unsaved user with the given email
+func newTestUser(email string) *domain.User {
+	return &domain.User{Email: email, Name: "Test User", Roles: domain.Roles{domain.RoleUser}}
+}
+
+func TestInMemoryUserRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryUserRepository()
+
+	user := newTestUser("ada@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if user.ID == "" || user.CreatedAt.IsZero() || user.Version != 1 {
+		t.Fatalf("Create did not assign id, timestamps, and version: %+v", user)
+	}
+
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Email != user.Email {
+		t.Errorf("GetByID email = %q, want %q", got.Email, user.Email)
+	}
+
+	got.Name = "Ada Lovelace"
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	updated, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID after Update: %v", err)
+	}
+	if updated.Name != "Ada Lovelace" {
+		t.Errorf("name after Update = %q, want %q", updated.Name, "Ada Lovelace")
+	}
+
+	if err := repo.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetByID after Delete error = %v, want %v", err, ErrUserNotFound)
+	}
+	if err := repo.Delete(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("second Delete error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestInMemoryUserRepositoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryUserRepository()
+
+	user := newTestUser("ada@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	user.Name = "changed without Update"
+
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Name != "Test User" {
+		t.Errorf("stored name = %q, want the name at Create", got.Name)
+	}
+}
+
+func TestInMemoryUserRepositoryConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryUserRepository()
+
+	const writers = 50
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Go(func() {
+			user := newTestUser(fmt.Sprintf("user%d@example.com", i))
+			if err := repo.Create(ctx, user); err != nil {
+				t.Errorf("Create: %v", err)
+				return
+			}
+			user.Name = "Renamed"
+			if err := repo.Update(ctx, user); err != nil {
+				t.Errorf("Update: %v", err)
+			}
+			if _, _, err := repo.List(ctx, ListParams{Page: 1, PageSize: writers}); err != nil {
+				t.Errorf("List: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	users, total, err := repo.List(ctx, ListParams{Page: 1, PageSize: writers})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != writers || len(users) != writers {
+		t.Fatalf("List returned %d of %d users, want %d", len(users), total, writers)
+	}
+	for _, user := range users {
+		if user.Name != "Renamed" || user.Version != 2 {
+			t.Errorf("user %s has name %q at version %d, want the update applied once", user.Email, user.Name, user.Version)
+		}
+	}
+}
+
+func TestInMemoryUserRepositoryConcurrentDuplicateEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryUserRepository()
+
+	const attempts = 20
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		created    int
+		duplicates int
+	)
+	for range attempts {
+		wg.Go(func() {
+			err := repo.Create(ctx, newTestUser("same@example.com"))
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, ErrDuplicateEmail):
+				duplicates++
+			default:
+				t.Errorf("Create: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if created != 1 || duplicates != attempts-1 {
+		t.Errorf("created %d and rejected %d duplicates, want 1 and %d", created, duplicates, attempts-1)
+	}
+}
+
+func TestInMemoryUserRepositoryVersionedUpdate(t *testing.T) {
+	testVersionedUpdate(t, NewInMemoryUserRepository())
+}
+
+func TestInMemoryUserRepositorySetRoles(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryUserRepository()
+
+	user := newTestUser("ada@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	user.Roles = domain.Roles{domain.RoleUser, domain.RoleAdmin}
+	if err := repo.SetRoles(ctx, user); err != nil {
+		t.Fatalf("SetRoles: %v", err)
+	}
+	user.Roles[0] = "changed by the caller"
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if len(got.Roles) != 2 || !got.Roles.Grants(domain.RoleAdmin) || !slices.Contains(got.Roles, domain.RoleUser) {
+		t.Errorf("roles = %v, want admin and user", got.Roles)
+	}
+
+	user.Roles = domain.Roles{"wizard"}
+	if err := repo.SetRoles(ctx, user); !errors.Is(err, ErrUnknownRole) {
+		t.Errorf("SetRoles with an undefined role error = %v, want %v", err, ErrUnknownRole)
+	}
+}
+
+func TestInMemoryUserRepositoryErase(t *testing.T) {
+	testErase(t, NewInMemoryUserRepository())
+}
@@ -0,0 +1,575 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// userColumns lists the users table columns in the order scanned by scanUser
+const userColumns = "id, email, name, COALESCE(phone, ''), " +
+	"ARRAY(SELECT role FROM user_roles WHERE user_id = users.id ORDER BY role), status, password_hash, email_verified_at, " +
+	"COALESCE(avatar_key, ''), COALESCE(avatar_url, ''), failed_login_attempts, locked_until, " +
+	"created_at, updated_at, version, deleted_at"
+
+// sortColumns maps the accepted sort fields to users table columns
+var sortColumns = map[string]string{
+	SortByCreatedAt: "created_at",
+	SortByUpdatedAt: "updated_at",
+	SortByName:      "name",
+	SortByEmail:     "email",
+}
+
+// PostgresUserRepository is a UserRepository backed by PostgreSQL. Its
+// methods run in the transaction a PostgresTransactor opened on the context.
+// Lookups by id and listings read from the replica when one is configured;
+// see ReadPrimary.
+type PostgresUserRepository struct {
+	pool    *pgxpool.Pool
+	replica *pgxpool.Pool
+}
+
+// NewPostgresUserRepository creates a user repository writing to the given
+// primary pool and reading from replica, or from the primary when replica is nil
+func NewPostgresUserRepository(pool, replica *pgxpool.Pool) *PostgresUserRepository {
+	return &PostgresUserRepository{pool: pool, replica: replica}
+}
+
+// db returns the transaction open on ctx, or the primary pool outside of one
+func (r *PostgresUserRepository) db(ctx context.Context) dbtx {
+	return conn(ctx, r.pool)
+}
+
+// read returns the connection for lookups that may lag behind the primary
+func (r *PostgresUserRepository) read(ctx context.Context) dbtx {
+	return readConn(ctx, r.pool, r.replica)
+}
+
+// querier is the query interface shared by the pool and transactions
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Create inserts a new user and populates its generated fields
+func (r *PostgresUserRepository) Create(ctx context.Context, user *domain.User) error {
+	return insertUser(ctx, r.db(ctx), user)
+}
+
+// CreateBatch inserts the users in a single transaction. Each insert runs in
+// its own savepoint so a duplicate email only discards that row, unless the
+// batch is atomic, in which case any failure rolls back the transaction.
+// Inside an open transaction the batch runs in a savepoint of it.
+func (r *PostgresUserRepository) CreateBatch(ctx context.Context, users []*domain.User, atomic bool) ([]error, error) {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin user batch: %w", err)
+	}
+	// Rolling back is a no-op once the transaction has been committed
+	defer tx.Rollback(ctx)
+
+	errs := make([]error, len(users))
+	failed := false
+	for i, user := range users {
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("begin user batch savepoint: %w", err)
+		}
+
+		if err := insertUser(ctx, savepoint, user); err != nil {
+			if rbErr := savepoint.Rollback(ctx); rbErr != nil {
+				return nil, fmt.Errorf("roll back user batch savepoint: %w", rbErr)
+			}
+			if !errors.Is(err, ErrDuplicateEmail) && !errors.Is(err, ErrDuplicatePhone) {
+				return nil, err
+			}
+			errs[i] = err
+			failed = true
+			continue
+		}
+
+		if err := savepoint.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("release user batch savepoint: %w", err)
+		}
+	}
+
+	if atomic && failed {
+		return errs, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit user batch: %w", err)
+	}
+
+	return errs, nil
+}
+
+// insertUser inserts a user and its roles through q and populates its
+// generated fields
+func insertUser(ctx context.Context, q querier, user *domain.User) error {
+	const query = `
+		WITH inserted AS (
+			INSERT INTO users (email, name, phone, status, password_hash, email_verified_at)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+			RETURNING id, created_at, updated_at, version
+		), granted AS (
+			INSERT INTO user_roles (user_id, role)
+			SELECT inserted.id, unnest($7::text[]) FROM inserted
+		)
+		SELECT id, created_at, updated_at, version FROM inserted`
+
+	err := q.QueryRow(ctx, query,
+		user.Email, user.Name, user.Phone, user.Status, user.PasswordHash, user.EmailVerifiedAt, roleNames(user.Roles),
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.Version)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+// GetByID returns the user with the given id
+func (r *PostgresUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND deleted_at IS NULL`
+
+	user, err := scanUser(r.read(ctx).QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || isInvalidID(err) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetByEmail returns the user with the given email, ignoring case. It reads
+// from the primary, as logins must see the latest password and lockout.
+func (r *PostgresUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`
+
+	user, err := scanUser(r.db(ctx).QueryRow(ctx, query, email))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("get user by email: %w", err)
+	}
+
+	return user, nil
+}
+
+// List returns one page of users in the requested order
+func (r *PostgresUserRepository) List(ctx context.Context, params ListParams) ([]*domain.User, int, error) {
+	conditions, args := filterConditions(params.Filter, nil)
+	where := whereClause(conditions)
+
+	var total int
+	if err := r.read(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM users `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count users: %w", err)
+	}
+
+	column, ok := sortColumns[params.Sort]
+	if !ok {
+		column = sortColumns[SortByCreatedAt]
+	}
+	direction := "ASC"
+	if params.Desc {
+		direction = "DESC"
+	}
+
+	// The column and direction come from fixed allowlists, never from user input
+	query := fmt.Sprintf(
+		`SELECT %s FROM users %s ORDER BY %s %s, id %s LIMIT $%d OFFSET $%d`,
+		userColumns, where, column, direction, direction, len(args)+1, len(args)+2,
+	)
+
+	rows, err := r.read(ctx).Query(ctx, query, append(args, params.PageSize, params.Offset())...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*domain.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("list users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// ListAfter returns up to limit users created after the cursor position
+func (r *PostgresUserRepository) ListAfter(ctx context.Context, filter UserFilter, cursor *Cursor, limit int) ([]*domain.User, error) {
+	conditions, args := filterConditions(filter, []any{limit})
+	if cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) > ($%d, $%d)", len(args)+1, len(args)+2))
+		args = append(args, cursor.CreatedAt, cursor.ID)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users ` + whereClause(conditions) + `
+		ORDER BY created_at, id
+		LIMIT $1`
+
+	rows, err := r.read(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list users after cursor: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*domain.User, 0, limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list users after cursor: %w", err)
+	}
+
+	return users, nil
+}
+
+// Update persists changes to an existing user unless it was saved since
+// user.Version was read
+func (r *PostgresUserRepository) Update(ctx context.Context, user *domain.User) error {
+	const query = `
+		UPDATE users
+		SET email = $2, name = $3, phone = NULLIF($4, ''), status = $5, password_hash = $6,
+			email_verified_at = $7, avatar_key = NULLIF($8, ''), avatar_url = NULLIF($9, ''), updated_at = NOW(),
+			version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND version = $10
+		RETURNING created_at, updated_at, version`
+
+	err := r.db(ctx).QueryRow(ctx, query,
+		user.ID, user.Email, user.Name, user.Phone, user.Status, user.PasswordHash, user.EmailVerifiedAt,
+		user.AvatarKey, user.AvatarURL, user.Version,
+	).Scan(&user.CreatedAt, &user.UpdatedAt, &user.Version)
+	if err == nil {
+		return nil
+	}
+	if isInvalidID(err) {
+		return ErrUserNotFound
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return mapWriteError(err)
+	}
+
+	// No row matched, either because the user is gone or because it was saved since
+	if _, err := r.GetByID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return ErrVersionConflict
+}
+
+// UpdateIfUnmodified persists changes unless the user changed after since
+func (r *PostgresUserRepository) UpdateIfUnmodified(ctx context.Context, user *domain.User, since time.Time) error {
+	const query = `
+		UPDATE users
+		SET email = $2, name = $3, phone = NULLIF($4, ''), status = $5, password_hash = $6,
+			email_verified_at = $7, avatar_key = NULLIF($8, ''), avatar_url = NULLIF($9, ''), updated_at = NOW(),
+			version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND updated_at = $10 AND version = $11
+		RETURNING created_at, updated_at, version`
+
+	err := r.db(ctx).QueryRow(ctx, query,
+		user.ID, user.Email, user.Name, user.Phone, user.Status, user.PasswordHash, user.EmailVerifiedAt,
+		user.AvatarKey, user.AvatarURL, since, user.Version,
+	).Scan(&user.CreatedAt, &user.UpdatedAt, &user.Version)
+	if err == nil {
+		return nil
+	}
+	if isInvalidID(err) {
+		return ErrUserNotFound
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return mapWriteError(err)
+	}
+
+	// No row matched, either because the user is gone or because it changed
+	if _, err := r.GetByID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return ErrUserModified
+}
+
+// SetRoles replaces the user's roles in one statement, which also moves the
+// user to its next version
+func (r *PostgresUserRepository) SetRoles(ctx context.Context, user *domain.User) error {
+	const query = `
+		WITH target AS (
+			UPDATE users SET updated_at = NOW(), version = version + 1
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING id, updated_at, version
+		), revoked AS (
+			DELETE FROM user_roles
+			WHERE user_id IN (SELECT id FROM target) AND role <> ALL($2::text[])
+		), granted AS (
+			INSERT INTO user_roles (user_id, role)
+			SELECT target.id, unnest($2::text[]) FROM target
+			ON CONFLICT DO NOTHING
+		)
+		SELECT updated_at, version FROM target`
+
+	err := r.db(ctx).QueryRow(ctx, query, user.ID, roleNames(user.Roles)).Scan(&user.UpdatedAt, &user.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || isInvalidID(err) {
+			return ErrUserNotFound
+		}
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+// RecordLoginFailure increments the failed login counter, locking the account at the threshold
+func (r *PostgresUserRepository) RecordLoginFailure(ctx context.Context, id string, threshold int, lockedUntil time.Time) (*time.Time, error) {
+	// Start counting afresh once a lock is applied so the user gets a full
+	// set of attempts after it expires
+	const query = `
+		UPDATE users SET
+			locked_until = CASE WHEN failed_login_attempts + 1 >= $2 THEN $3 ELSE locked_until END,
+			failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $2 THEN 0 ELSE failed_login_attempts + 1 END
+		WHERE id = $1
+		RETURNING locked_until`
+
+	var locked *time.Time
+	err := r.db(ctx).QueryRow(ctx, query, id, threshold, lockedUntil).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || isInvalidID(err) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("record login failure: %w", mapPgError(err))
+	}
+
+	return locked, nil
+}
+
+// ResetLoginFailures clears the failed login counter and any lock
+func (r *PostgresUserRepository) ResetLoginFailures(ctx context.Context, id string) error {
+	tag, err := r.db(ctx).Exec(ctx, `UPDATE users SET failed_login_attempts = 0, locked_until = NULL WHERE id = $1`, id)
+	if err != nil {
+		if isInvalidID(err) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("reset login failures: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Delete marks the user with the given id as deleted
+func (r *PostgresUserRepository) Delete(ctx context.Context, id string) error {
+	const query = `UPDATE users SET deleted_at = NOW(), updated_at = NOW(), version = version + 1 WHERE id = $1 AND deleted_at IS NULL`
+
+	tag, err := r.db(ctx).Exec(ctx, query, id)
+	if err != nil {
+		if isInvalidID(err) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("delete user: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// DeleteBatch marks the users with the given ids as deleted in a single
+// statement, so either all of them are deleted or none are
+func (r *PostgresUserRepository) DeleteBatch(ctx context.Context, ids []string) ([]string, error) {
+	const query = `UPDATE users SET deleted_at = NOW(), updated_at = NOW(), version = version + 1
+		WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL
+		RETURNING id`
+
+	rows, err := r.db(ctx).Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("delete users: %w", mapPgError(err))
+	}
+
+	deleted, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("delete users: %w", mapPgError(err))
+	}
+
+	return deleted, nil
+}
+
+// Restore clears the deletion mark of the user with the given id
+func (r *PostgresUserRepository) Restore(ctx context.Context, id string) error {
+	const query = `UPDATE users SET deleted_at = NULL, updated_at = NOW(), version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	tag, err := r.db(ctx).Exec(ctx, query, id)
+	if err != nil {
+		if isInvalidID(err) {
+			return ErrUserNotFound
+		}
+		return mapWriteError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Erase anonymizes and soft-deletes the user and deletes its dependent rows
+// in one statement
+func (r *PostgresUserRepository) Erase(ctx context.Context, user *domain.User) error {
+	const query = `
+		WITH target AS (
+			UPDATE users
+			SET email = $2, name = $3, phone = NULL, password_hash = $4, email_verified_at = NULL,
+				avatar_key = NULL, avatar_url = NULL, failed_login_attempts = 0, locked_until = NULL,
+				deleted_at = NOW(), updated_at = NOW(), version = version + 1
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING id, updated_at, version, deleted_at
+		), sessions AS (
+			DELETE FROM refresh_tokens WHERE user_id IN (SELECT id FROM target)
+		), verifications AS (
+			DELETE FROM email_verifications WHERE user_id IN (SELECT id FROM target)
+		), resets AS (
+			DELETE FROM password_resets WHERE user_id IN (SELECT id FROM target)
+		), identities AS (
+			DELETE FROM oauth_identities WHERE user_id IN (SELECT id FROM target)
+		), two_factor AS (
+			DELETE FROM user_two_factor WHERE user_id IN (SELECT id FROM target)
+		), recovery AS (
+			DELETE FROM recovery_codes WHERE user_id IN (SELECT id FROM target)
+		), preferences AS (
+			DELETE FROM user_preferences WHERE user_id IN (SELECT id FROM target)
+		), keys AS (
+			DELETE FROM api_keys WHERE owner_id IN (SELECT id FROM target)
+		)
+		SELECT updated_at, version, deleted_at FROM target`
+
+	err := r.db(ctx).QueryRow(ctx, query, user.ID, user.Email, user.Name, user.PasswordHash).
+		Scan(&user.UpdatedAt, &user.Version, &user.DeletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || isInvalidID(err) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("erase user: %w", mapPgError(err))
+	}
+	user.Phone = ""
+	user.EmailVerifiedAt = nil
+	user.AvatarKey = ""
+	user.AvatarURL = ""
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+
+	return nil
+}
+
+// likeEscaper escapes the ILIKE wildcards so search terms match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filterConditions returns the SQL conditions selecting the users matched by
+// the filter, appending their values to args and numbering placeholders after them
+func filterConditions(filter UserFilter, args []any) ([]string, []any) {
+	var conditions []string
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM user_roles WHERE user_id = users.id AND role = $%d)", len(args)))
+	}
+	if filter.EmailVerified != nil {
+		if *filter.EmailVerified {
+			conditions = append(conditions, "email_verified_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "email_verified_at IS NULL")
+		}
+	}
+	if filter.Query != "" {
+		args = append(args, "%"+likeEscaper.Replace(filter.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%d OR email ILIKE $%d)", len(args), len(args)))
+	}
+
+	return conditions, args
+}
+
+// whereClause joins conditions into a WHERE clause, or returns an empty string when there are none
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+// scanUser reads a row selected with userColumns into a user
+func scanUser(row pgx.Row) (*domain.User, error) {
+	var user domain.User
+	var roles []string
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Name, &user.Phone, &roles, &user.Status, &user.PasswordHash,
+		&user.EmailVerifiedAt, &user.AvatarKey, &user.AvatarURL, &user.FailedLoginAttempts, &user.LockedUntil,
+		&user.CreatedAt, &user.UpdatedAt, &user.Version, &user.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		user.Roles = append(user.Roles, domain.Role(role))
+	}
+
+	return &user, nil
+}
+
+// mapWriteError converts constraint violations into repository errors,
+// naming the duplicate field for the unique indexes users see
+func mapWriteError(err error) error {
+	err = mapPgError(err)
+
+	var constraintErr *ConstraintError
+	if errors.As(err, &constraintErr) {
+		switch constraintErr.Constraint {
+		case "users_email_active_key":
+			return ErrDuplicateEmail
+		case "users_phone_active_key":
+			return ErrDuplicatePhone
+		case "user_roles_role_fkey":
+			return ErrUnknownRole
+		}
+	}
+
+	return fmt.Errorf("write user: %w", err)
+}
+
+// roleNames converts roles to the text array they are stored as
+func roleNames(roles domain.Roles) []string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+
+	return names
+}
+
+// isInvalidID reports whether the error was caused by an id that is not a valid UUID
+func isInvalidID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
+}
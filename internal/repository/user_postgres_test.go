@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+)
+
+func TestPostgresUserRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := NewPostgresUserRepository(testutil.Postgres(t), nil)
+
+	user := newTestUser("ada@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if user.ID == "" || user.CreatedAt.IsZero() {
+		t.Fatalf("Create did not assign the id and timestamps: %+v", user)
+	}
+
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Email != user.Email || got.Name != user.Name {
+		t.Errorf("GetByID = %+v, want the created user", got)
+	}
+
+	got.Name = "Ada Lovelace"
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	updated, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID after Update: %v", err)
+	}
+	if updated.Name != "Ada Lovelace" {
+		t.Errorf("name after Update = %q, want %q", updated.Name, "Ada Lovelace")
+	}
+
+	if err := repo.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetByID after Delete error = %v, want %v", err, ErrUserNotFound)
+	}
+	if err := repo.Delete(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("second Delete error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestPostgresUserRepositoryDuplicateEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := NewPostgresUserRepository(testutil.Postgres(t), nil)
+
+	if err := repo.Create(ctx, newTestUser("ada@example.com")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.Create(ctx, newTestUser("ada@example.com")); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Create with a taken email error = %v, want %v", err, ErrDuplicateEmail)
+	}
+
+	// The unique index ignores case even when the caller did not normalize
+	if err := repo.Create(ctx, newTestUser("Ada@Example.com")); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Create with the email in another case error = %v, want %v", err, ErrDuplicateEmail)
+	}
+	if _, err := repo.GetByEmail(ctx, "ADA@example.com"); err != nil {
+		t.Errorf("GetByEmail in another case: %v", err)
+	}
+
+	other := newTestUser("grace@example.com")
+	if err := repo.Create(ctx, other); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	other.Email = "ada@example.com"
+	if err := repo.Update(ctx, other); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Update to a taken email error = %v, want %v", err, ErrDuplicateEmail)
+	}
+}
+
+func TestPostgresUserRepositoryCancelledQuery(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	repo := NewPostgresUserRepository(pool, nil)
+
+	user := newTestUser("ada@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Another transaction holds the row, so the update waits until cancelled
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, user.ID); err != nil {
+		t.Fatalf("lock user: %v", err)
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(100*time.Millisecond, cancel)
+	user.Name = "Ada Lovelace"
+	start := time.Now()
+	if err := repo.Update(cancelCtx, user); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Update error = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Update returned after %v, want it to stop on cancellation", elapsed)
+	}
+
+	if _, err := repo.GetByID(cancelCtx, user.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetByID with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestPostgresUserRepositoryVersionedUpdate(t *testing.T) {
+	testVersionedUpdate(t, NewPostgresUserRepository(testutil.Postgres(t), nil))
+}
+
+// testVersionedUpdate checks that repo increments the version on every
+// update and refuses updates from a stale one
+func testVersionedUpdate(t *testing.T, repo UserRepository) {
+	ctx := context.Background()
+
+	user := newTestUser("ada@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	stale := *user
+
+	user.Name = "Ada Lovelace"
+	if err := repo.Update(ctx, user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if user.Version != stale.Version+1 {
+		t.Errorf("version after Update = %d, want %d", user.Version, stale.Version+1)
+	}
+
+	stale.Name = "Lost update"
+	if err := repo.Update(ctx, &stale); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Update at a stale version error = %v, want %v", err, ErrVersionConflict)
+	}
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Name != "Ada Lovelace" || got.Version != user.Version {
+		t.Errorf("stored user named %q at version %d, want the first update", got.Name, got.Version)
+	}
+}
+
+func TestPostgresUserRepositorySetRoles(t *testing.T) {
+	ctx := context.Background()
+	repo := NewPostgresUserRepository(testutil.Postgres(t), nil)
+
+	user := newTestUser("ada@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	user.Roles = domain.Roles{domain.RoleUser, domain.RoleAdmin}
+	if err := repo.SetRoles(ctx, user); err != nil {
+		t.Fatalf("SetRoles: %v", err)
+	}
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if want := (domain.Roles{domain.RoleAdmin, domain.RoleUser}); !slices.Equal(got.Roles, want) {
+		t.Errorf("roles = %v, want %v", got.Roles, want)
+	}
+
+	user.Roles = domain.Roles{domain.RoleUser, "wizard"}
+	if err := repo.SetRoles(ctx, user); !errors.Is(err, ErrUnknownRole) {
+		t.Errorf("SetRoles with an undefined role error = %v, want %v", err, ErrUnknownRole)
+	}
+	// The rejected change left the roles alone
+	if got, err := repo.GetByID(ctx, user.ID); err != nil || len(got.Roles) != 2 {
+		t.Errorf("roles after a rejected change = %v, %v; want the two assigned", got, err)
+	}
+}
+
+func TestPostgresUserRepositoryErase(t *testing.T) {
+	testErase(t, NewPostgresUserRepository(testutil.Postgres(t), nil))
+}
+
+// testErase checks that repo replaces the personal fields of an erased user
+// while keeping its row, and frees its email for another account
+func testErase(t *testing.T, repo UserRepository) {
+	ctx := context.Background()
+
+	user := newTestUser("ada@example.com")
+	user.Phone = "+14155550100"
+	user.PasswordHash = "hash"
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	erased := &domain.User{ID: user.ID, Email: "erased-1@erased.invalid", Name: "Deleted User"}
+	if err := repo.Erase(ctx, erased); err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+	if erased.DeletedAt == nil || erased.Version != user.Version+1 {
+		t.Errorf("erased user deleted at %v, version %d; want it deleted at version %d", erased.DeletedAt, erased.Version, user.Version+1)
+	}
+
+	users, _, err := repo.List(ctx, ListParams{Filter: UserFilter{IncludeDeleted: true}, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("List returned %d users, want the erased row kept", len(users))
+	}
+	got := users[0]
+	if got.Email != erased.Email || got.Name != erased.Name || got.Phone != "" || got.PasswordHash != "" {
+		t.Errorf("stored user = %+v, want only the placeholders left", got)
+	}
+
+	if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetByID after Erase error = %v, want %v", err, ErrUserNotFound)
+	}
+	if err := repo.Erase(ctx, erased); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("second Erase error = %v, want %v", err, ErrUserNotFound)
+	}
+	if err := repo.Create(ctx, newTestUser("ada@example.com")); err != nil {
+		t.Errorf("Create with the erased user's email: %v", err)
+	}
+}
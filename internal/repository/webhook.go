@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// ErrWebhookNotFound is returned when no webhook matches the lookup
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// WebhookRepository defines the persistence operations for webhooks and their deliveries
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *domain.Webhook) error
+	// List returns every webhook, newest first
+	List(ctx context.Context) ([]*domain.Webhook, error)
+	// ListForEvent returns the webhooks subscribed to the event
+	ListForEvent(ctx context.Context, event domain.WebhookEvent) ([]*domain.Webhook, error)
+	// CreateDelivery records a delivery, in the transaction open on ctx
+	CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	// UpdateDelivery saves the status, attempt count, last result, and next
+	// attempt of a delivery
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	// ClaimDueDeliveries returns up to limit pending deliveries whose next
+	// attempt is due, soonest first, and leases them by moving that attempt
+	// lease into the future. Concurrent callers never claim the same
+	// delivery, and one whose attempt never reports back is due again once
+	// the lease ends.
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error)
+	// ListDeliveries returns the most recent deliveries of the webhook, newest first
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*domain.WebhookDelivery, error)
+}
+
+// ClaimedDelivery is a delivery claimed for an attempt and the webhook it goes to
+type ClaimedDelivery struct {
+	Delivery *domain.WebhookDelivery
+	Webhook  *domain.Webhook
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// InMemoryWebhookRepository is a WebhookRepository backed by maps
+type InMemoryWebhookRepository struct {
+	mu         sync.RWMutex
+	webhooks   map[string]domain.Webhook
+	deliveries map[string]domain.WebhookDelivery
+}
+
+// NewInMemoryWebhookRepository creates an empty in-memory webhook repository
+func NewInMemoryWebhookRepository() *InMemoryWebhookRepository {
+	return &InMemoryWebhookRepository{
+		webhooks:   make(map[string]domain.Webhook),
+		deliveries: make(map[string]domain.WebhookDelivery),
+	}
+}
+
+// Create stores a new webhook and assigns its id
+func (r *InMemoryWebhookRepository) Create(_ context.Context, webhook *domain.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook.ID = uuid.NewString()
+	webhook.CreatedAt = time.Now().UTC()
+	stored := *webhook
+	stored.Events = slices.Clone(webhook.Events)
+	r.webhooks[webhook.ID] = stored
+
+	return nil
+}
+
+// List returns every webhook, newest first
+func (r *InMemoryWebhookRepository) List(_ context.Context) ([]*domain.Webhook, error) {
+	return r.list(func(domain.Webhook) bool { return true }), nil
+}
+
+// ListForEvent returns the webhooks subscribed to the event
+func (r *InMemoryWebhookRepository) ListForEvent(_ context.Context, event domain.WebhookEvent) ([]*domain.Webhook, error) {
+	return r.list(func(webhook domain.Webhook) bool {
+		return slices.Contains(webhook.Events, event)
+	}), nil
+}
+
+// list returns copies of the matching webhooks, newest first
+func (r *InMemoryWebhookRepository) list(match func(domain.Webhook) bool) []*domain.Webhook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := []*domain.Webhook{}
+	for _, webhook := range r.webhooks {
+		if match(webhook) {
+			webhook.Events = slices.Clone(webhook.Events)
+			webhooks = append(webhooks, &webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.After(webhooks[j].CreatedAt)
+	})
+
+	return webhooks
+}
+
+// CreateDelivery stores a new delivery and assigns its id
+func (r *InMemoryWebhookRepository) CreateDelivery(_ context.Context, delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[delivery.WebhookID]; !ok {
+		return ErrWebhookNotFound
+	}
+
+	now := time.Now().UTC()
+	delivery.ID = uuid.NewString()
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	r.deliveries[delivery.ID] = *delivery
+
+	return nil
+}
+
+// ClaimDueDeliveries leases up to limit due pending deliveries, soonest
+// first, and returns them with their webhooks
+func (r *InMemoryWebhookRepository) ClaimDueDeliveries(_ context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	due := []domain.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.Status == domain.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+	})
+
+	claimed := []ClaimedDelivery{}
+	leasedUntil := now.Add(lease)
+	for _, delivery := range due[:min(limit, len(due))] {
+		delivery.NextAttemptAt = &leasedUntil
+		r.deliveries[delivery.ID] = delivery
+		webhook := r.webhooks[delivery.WebhookID]
+		webhook.Events = slices.Clone(webhook.Events)
+		claimed = append(claimed, ClaimedDelivery{Delivery: &delivery, Webhook: &webhook})
+	}
+
+	return claimed, nil
+}
+
+// UpdateDelivery saves the result of the latest attempt
+func (r *InMemoryWebhookRepository) UpdateDelivery(_ context.Context, delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.deliveries[delivery.ID]
+	if !ok {
+		return ErrWebhookNotFound
+	}
+
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.LastStatusCode = delivery.LastStatusCode
+	stored.LastError = delivery.LastError
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.UpdatedAt = time.Now().UTC()
+	r.deliveries[delivery.ID] = stored
+	delivery.UpdatedAt = stored.UpdatedAt
+
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries of the webhook, newest first
+func (r *InMemoryWebhookRepository) ListDeliveries(_ context.Context, webhookID string, limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.webhooks[webhookID]; !ok {
+		return nil, ErrWebhookNotFound
+	}
+
+	deliveries := []*domain.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+
+	return deliveries[:min(limit, len(deliveries))], nil
+}
@@ -0,0 +1,7 @@
+package repository
+
+import "testing"
+
+func TestInMemoryWebhookRepositoryClaimDueDeliveries(t *testing.T) {
+	testClaimDueDeliveries(t, NewInMemoryWebhookRepository())
+}
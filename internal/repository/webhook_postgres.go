@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// webhookColumns lists the webhooks columns in the order scanWebhook reads them
+const webhookColumns = `id, url, events, secret, created_at`
+
+// deliveryColumns lists the webhook_deliveries columns in the order scanDelivery reads them
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, last_status_code, last_error, next_attempt_at, created_at, updated_at`
+
+// PostgresWebhookRepository is a WebhookRepository backed by PostgreSQL
+type PostgresWebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresWebhookRepository creates a webhook repository using the given connection pool
+func NewPostgresWebhookRepository(pool *pgxpool.Pool) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{pool: pool}
+}
+
+// Create inserts a new webhook and populates its generated fields
+func (r *PostgresWebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	const query = `
+		INSERT INTO webhooks (url, events, secret)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err := r.pool.QueryRow(ctx, query, webhook.URL, textArray(webhook.Events), webhook.Secret).
+		Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create webhook: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// List returns every webhook, newest first
+func (r *PostgresWebhookRepository) List(ctx context.Context) ([]*domain.Webhook, error) {
+	return r.query(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY created_at DESC, id DESC`)
+}
+
+// ListForEvent returns the webhooks subscribed to the event
+func (r *PostgresWebhookRepository) ListForEvent(ctx context.Context, event domain.WebhookEvent) ([]*domain.Webhook, error) {
+	return r.query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE $1 = ANY (events) ORDER BY created_at`, event)
+}
+
+// query runs a webhook select and scans every row
+func (r *PostgresWebhookRepository) query(ctx context.Context, query string, args ...any) ([]*domain.Webhook, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*domain.Webhook{}
+	for rows.Next() {
+		var (
+			webhook domain.Webhook
+			events  []string
+		)
+		if err := rows.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		webhook.Events = fromTextArray[domain.WebhookEvent](events)
+		webhooks = append(webhooks, &webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// CreateDelivery inserts a new delivery in the transaction open on ctx, or
+// on its own outside of one, and populates its generated fields
+func (r *PostgresWebhookRepository) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	const query = `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+
+	err := conn(ctx, r.pool).QueryRow(ctx, query, delivery.WebhookID, delivery.Event, delivery.Payload, delivery.Status, delivery.NextAttemptAt).
+		Scan(&delivery.ID, &delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		err = mapPgError(err)
+		if errors.Is(err, ErrForeignKeyViolation) {
+			return ErrWebhookNotFound
+		}
+		return fmt.Errorf("create webhook delivery: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// UpdateDelivery saves the result of the latest attempt
+func (r *PostgresWebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	const query = `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_status_code = $4, last_error = $5, next_attempt_at = $6, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.pool.QueryRow(ctx, query,
+		delivery.ID, delivery.Status, delivery.Attempts, delivery.LastStatusCode, delivery.LastError, delivery.NextAttemptAt,
+	).Scan(&delivery.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWebhookNotFound
+		}
+		return fmt.Errorf("update webhook delivery: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries of the webhook, newest first
+func (r *PostgresWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*domain.WebhookDelivery, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1)`, webhookID).Scan(&exists)
+	if err != nil {
+		if isInvalidID(err) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("get webhook: %w", err)
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.pool.Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries leases up to limit due pending deliveries, soonest
+// first, and returns them with their webhooks. Rows another caller is
+// claiming are skipped rather than waited for.
+func (r *PostgresWebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error) {
+	const query = `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $3 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries AS d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		FROM due, webhooks AS w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.last_status_code, d.last_error,
+			d.next_attempt_at, d.created_at, d.updated_at, w.id, w.url, w.events, w.secret, w.created_at`
+
+	rows, err := r.pool.Query(ctx, query, limit, lease.Milliseconds(), domain.DeliveryPending)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	claimed := []ClaimedDelivery{}
+	for rows.Next() {
+		var (
+			delivery domain.WebhookDelivery
+			webhook  domain.Webhook
+			events   []string
+		)
+		err := rows.Scan(
+			&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status,
+			&delivery.Attempts, &delivery.LastStatusCode, &delivery.LastError, &delivery.NextAttemptAt,
+			&delivery.CreatedAt, &delivery.UpdatedAt,
+			&webhook.ID, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		webhook.Events = fromTextArray[domain.WebhookEvent](events)
+		claimed = append(claimed, ClaimedDelivery{Delivery: &delivery, Webhook: &webhook})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+
+	return claimed, nil
+}
+
+// scanDelivery reads a row of deliveryColumns
+func scanDelivery(row pgx.Row) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &delivery.LastStatusCode, &delivery.LastError, &delivery.NextAttemptAt,
+		&delivery.CreatedAt, &delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("scan webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
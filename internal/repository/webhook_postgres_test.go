@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+)
+
+func TestPostgresWebhookRepositoryClaimDueDeliveries(t *testing.T) {
+	testClaimDueDeliveries(t, NewPostgresWebhookRepository(testutil.Postgres(t)))
+}
+
+// testClaimDueDeliveries checks that only due pending deliveries are
+// claimed, and that a claimed one is not claimed again before its lease ends
+func testClaimDueDeliveries(t *testing.T, repo WebhookRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	webhook := &domain.Webhook{URL: "https://example.com/hook", Events: []domain.WebhookEvent{domain.EventUserCreated}, Secret: "secret"}
+	if err := repo.Create(ctx, webhook); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	now, later := time.Now().UTC().Add(-time.Second), time.Now().UTC().Add(time.Hour)
+	due := &domain.WebhookDelivery{Status: domain.DeliveryPending, NextAttemptAt: &now}
+	scheduled := &domain.WebhookDelivery{Status: domain.DeliveryPending, NextAttemptAt: &later}
+	done := &domain.WebhookDelivery{Status: domain.DeliverySucceeded}
+	for _, delivery := range []*domain.WebhookDelivery{due, scheduled, done} {
+		delivery.WebhookID, delivery.Event, delivery.Payload = webhook.ID, domain.EventUserCreated, []byte(`{}`)
+		if err := repo.CreateDelivery(ctx, delivery); err != nil {
+			t.Fatalf("CreateDelivery: %v", err)
+		}
+	}
+
+	claimed, err := repo.ClaimDueDeliveries(ctx, 10, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ClaimDueDeliveries: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Delivery.ID != due.ID || claimed[0].Webhook.ID != webhook.ID {
+		t.Fatalf("claimed %+v, want only the due delivery with its webhook", claimed)
+	}
+	if claimed[0].Webhook.Secret != webhook.Secret || string(claimed[0].Delivery.Payload) != `{}` {
+		t.Errorf("claimed %+v, want the delivery and webhook in full", claimed[0])
+	}
+
+	// The lease hides the delivery from other relays until it ends
+	if claimed, err := repo.ClaimDueDeliveries(ctx, 10, time.Minute); err != nil || len(claimed) != 0 {
+		t.Fatalf("claim during the lease = %+v, %v; want nothing", claimed, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if claimed, err := repo.ClaimDueDeliveries(ctx, 10, time.Minute); err != nil || len(claimed) != 1 {
+		t.Errorf("claim after the lease = %+v, %v; want the delivery again", claimed, err)
+	}
+}
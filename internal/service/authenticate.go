@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// LoginThrottledError is returned, matching ErrLoginThrottled, while an email
+// is refused further logins
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrLoginThrottled.Error()
+}
+
+// Is makes errors.Is match ErrLoginThrottled
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrLoginThrottled
+}
+
+// Authenticate checks the credentials and returns the user they belong to,
+// locking the account after repeated failures and throttling the email after
+// repeated failures from any source
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (*domain.User, error) {
+	email = domain.NormalizeEmail(email)
+
+	// Throttle before looking the email up so known and unknown emails are
+	// refused alike, and without spending a password check
+	if err := s.checkLoginThrottle(ctx, email); err != nil {
+		return nil, err
+	}
+
+	user, err := s.authenticate(ctx, email, password)
+	s.recordLoginAttempt(ctx, email, err)
+
+	return user, err
+}
+
+// checkLoginThrottle returns a LoginThrottledError while the email is over
+// its limit. A failing throttle lets the login through rather than locking
+// everyone out.
+func (s *UserService) checkLoginThrottle(ctx context.Context, email string) error {
+	if s.settings.LoginThrottle == nil {
+		return nil
+	}
+
+	wait, err := s.settings.LoginThrottle.Wait(ctx, email)
+	if err != nil {
+		logger.FromContext(ctx).Warn("login throttle unavailable", slog.Any("error", err))
+		return nil
+	}
+	if wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// recordLoginAttempt counts a failed login against the email and forgets its
+// failures once a login succeeds
+func (s *UserService) recordLoginAttempt(ctx context.Context, email string, loginErr error) {
+	if s.settings.LoginThrottle == nil {
+		return
+	}
+
+	var err error
+	switch {
+	case loginErr == nil:
+		err = s.settings.LoginThrottle.Reset(ctx, email)
+	case errors.Is(loginErr, ErrInvalidCredentials), errors.Is(loginErr, ErrAccountLocked):
+		err = s.settings.LoginThrottle.Hit(ctx, email)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warn("login throttle unavailable", slog.Any("error", err))
+	}
+}
+
+// authenticate checks the credentials against the account or, for unknown
+// emails, its stand-in
+func (s *UserService) authenticate(ctx context.Context, email, password string) (*domain.User, error) {
+	now := time.Now()
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			return nil, err
+		}
+		// Spend the same time as a real check and lock out like a real account
+		// so unknown emails are not detectable
+		auth.CompareDummyPassword(password)
+		if s.unknownLogins.locked(ctx, email) {
+			return nil, ErrAccountLocked
+		}
+		if s.unknownLogins.recordFailure(ctx, email, s.settings.LockoutThreshold, s.settings.LockoutDuration) {
+			return nil, ErrAccountLocked
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// Check the password even when locked so a locked account answers in the same time
+	passwordOK := auth.CheckPassword(user.PasswordHash, password)
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return nil, ErrAccountLocked
+	}
+
+	if !passwordOK {
+		lockedUntil, err := s.users.RecordLoginFailure(ctx, user.ID, s.settings.LockoutThreshold, now.Add(s.settings.LockoutDuration))
+		if err != nil {
+			return nil, err
+		}
+		if lockedUntil != nil && now.Before(*lockedUntil) {
+			return nil, ErrAccountLocked
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := s.users.ResetLoginFailures(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only reveal the suspension to someone who knows the password
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+	if s.VerificationRequired(user) {
+		return nil, ErrEmailNotVerified
+	}
+	s.audit.RecordAs(ctx, user.ID, domain.AuditUserLogin, user.ID)
+
+	return user, nil
+}
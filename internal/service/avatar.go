@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log/slog"
+	"net/http"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/storage"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder with image
+)
+
+// maxAvatarPixels bounds the images decoded for downscaling, so a small
+// file declaring huge dimensions cannot exhaust memory
+const maxAvatarPixels = 50_000_000
+
+// avatarFormats maps the accepted sniffed content types to file extensions
+var avatarFormats = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/webp": "webp",
+}
+
+// AvatarSettings controls which uploads AvatarService accepts
+type AvatarSettings struct {
+	// MaxSize is the largest file accepted, in bytes
+	MaxSize int
+	// MaxDimension bounds the width and height of a stored avatar
+	MaxDimension int
+	// Downscale shrinks larger images to fit MaxDimension; without it they are
+	// rejected with ErrAvatarDimensions
+	Downscale bool
+}
+
+// AvatarService stores profile pictures and records them on users
+type AvatarService struct {
+	users    repository.UserRepository
+	tx       repository.Transactor
+	storage  storage.Storage
+	audit    *audit.Logger
+	events   *webhook.Dispatcher
+	settings AvatarSettings
+}
+
+// NewAvatarService creates an AvatarService keeping files in store
+func NewAvatarService(
+	users repository.UserRepository,
+	tx repository.Transactor,
+	store storage.Storage,
+	auditLog *audit.Logger,
+	events *webhook.Dispatcher,
+	settings AvatarSettings,
+) *AvatarService {
+	return &AvatarService{
+		users:    users,
+		tx:       tx,
+		storage:  store,
+		audit:    auditLog,
+		events:   events,
+		settings: settings,
+	}
+}
+
+// MaxSize returns the largest upload accepted, in bytes
+func (s *AvatarService) MaxSize() int {
+	return s.settings.MaxSize
+}
+
+// Upload validates the image, stores it, and makes it the user's avatar,
+// removing the one it replaces
+func (s *AvatarService) Upload(ctx context.Context, userID string, data []byte) (*domain.User, error) {
+	if len(data) > s.settings.MaxSize {
+		return nil, ErrAvatarTooLarge
+	}
+
+	// The type is sniffed from the content; the client's claim is not trusted
+	contentType := http.DetectContentType(data)
+	ext, ok := avatarFormats[contentType]
+	if !ok {
+		return nil, ErrUnsupportedImage
+	}
+
+	data, contentType, ext, err := s.fit(data, contentType, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("avatars/%s/%s.%s", user.ID, rand.Text(), ext)
+	if err := s.storage.Put(ctx, key, data, contentType); err != nil {
+		return nil, fmt.Errorf("store avatar: %w", err)
+	}
+
+	previous := user.AvatarKey
+	user.AvatarKey = key
+	user.AvatarURL = s.storage.URL(key)
+	if err := s.update(ctx, user); err != nil {
+		s.deleteObject(ctx, key)
+		return nil, err
+	}
+	if previous != "" {
+		s.deleteObject(ctx, previous)
+	}
+	s.audit.Record(ctx, domain.AuditUserUpdate, user.ID)
+
+	return user, nil
+}
+
+// Remove clears the user's avatar and deletes its file; users without an
+// avatar are returned unchanged
+func (s *AvatarService) Remove(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AvatarKey == "" {
+		return user, nil
+	}
+
+	previous := user.AvatarKey
+	user.AvatarKey = ""
+	user.AvatarURL = ""
+	if err := s.update(ctx, user); err != nil {
+		return nil, err
+	}
+	s.deleteObject(ctx, previous)
+	s.audit.Record(ctx, domain.AuditUserUpdate, user.ID)
+
+	return user, nil
+}
+
+// update saves the user along with its user.updated event
+func (s *AvatarService) update(ctx context.Context, user *domain.User) error {
+	return writeWithEvent(ctx, s.tx, s.events, domain.EventUserUpdated, user, func(ctx context.Context) error {
+		return s.users.Update(ctx, user)
+	})
+}
+
+// fit checks the image dimensions, downscaling an image larger than
+// MaxDimension when allowed. Downscaled WebP images are stored as PNG, as
+// there is no WebP encoder.
+func (s *AvatarService) fit(data []byte, contentType, ext string) ([]byte, string, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", ErrUnsupportedImage
+	}
+
+	limit := s.settings.MaxDimension
+	if cfg.Width <= limit && cfg.Height <= limit {
+		return data, contentType, ext, nil
+	}
+	if !s.settings.Downscale || cfg.Width*cfg.Height > maxAvatarPixels {
+		return nil, "", "", ErrAvatarDimensions
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", ErrUnsupportedImage
+	}
+
+	width, height := limit, cfg.Height*limit/cfg.Width
+	if cfg.Height > cfg.Width {
+		width, height = cfg.Width*limit/cfg.Height, limit
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(width, 1), max(height, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 90})
+	} else {
+		contentType, ext = "image/png", "png"
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, "", "", fmt.Errorf("encode avatar: %w", err)
+	}
+
+	return buf.Bytes(), contentType, ext, nil
+}
+
+// deleteObject removes a stored file that is no longer referenced. A failure
+// only leaves an orphaned file behind, so it is logged rather than returned.
+func (s *AvatarService) deleteObject(ctx context.Context, key string) {
+	if err := s.storage.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logger.FromContext(ctx).Warn("failed to delete avatar file",
+			slog.String("key", key),
+			slog.Any("error", err),
+		)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+)
+
+// Replacements of the personal fields of erased users
+const (
+	erasedName        = "Deleted User"
+	erasedEmailDomain = "erased.invalid"
+)
+
+// ErasureService deletes accounts at their owners' request. It anonymizes
+// the user rather than removing the row, so the audit log keeps pointing at
+// a user that no longer identifies anyone.
+type ErasureService struct {
+	users    repository.UserRepository
+	tx       repository.Transactor
+	sessions *SessionService
+	avatars  *AvatarService
+	audit    *audit.Logger
+	events   *webhook.Dispatcher
+}
+
+// NewErasureService creates an ErasureService ending sessions through
+// sessions and removing avatar files through avatars
+func NewErasureService(
+	users repository.UserRepository,
+	tx repository.Transactor,
+	sessions *SessionService,
+	avatars *AvatarService,
+	auditLog *audit.Logger,
+	events *webhook.Dispatcher,
+) *ErasureService {
+	return &ErasureService{
+		users:    users,
+		tx:       tx,
+		sessions: sessions,
+		avatars:  avatars,
+		audit:    auditLog,
+		events:   events,
+	}
+}
+
+// Erase signs the user out everywhere and anonymizes their account once
+// password confirms it is theirs. The user cannot sign in again, and
+// nothing left on the account tells who they were.
+func (s *ErasureService) Erase(ctx context.Context, userID, password string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !auth.CheckPassword(user.PasswordHash, password) {
+		return ErrWrongPassword
+	}
+
+	// End the sessions first, so a failure leaves the account intact
+	if err := s.sessions.EndAll(ctx, user.ID, ""); err != nil {
+		return err
+	}
+
+	avatarKey := user.AvatarKey
+	user.Email = erasedEmail(user.ID)
+	user.Name = erasedName
+	// No password matches an empty hash
+	user.PasswordHash = ""
+	if err := writeWithEvent(ctx, s.tx, s.events, domain.EventUserDeleted, deletedUser{ID: user.ID}, func(ctx context.Context) error {
+		return s.users.Erase(ctx, user)
+	}); err != nil {
+		return err
+	}
+	if avatarKey != "" {
+		s.avatars.deleteObject(ctx, avatarKey)
+	}
+	s.audit.Record(ctx, domain.AuditUserErase, user.ID)
+
+	return nil
+}
+
+// erasedEmail returns the placeholder replacing the email of the erased
+// user. It is derived from the id, which the audit log refers to, rather
+// than from the email, so it cannot be matched against a known address.
+func erasedEmail(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return "erased-" + hex.EncodeToString(sum[:16]) + "@" + erasedEmailDomain
+}
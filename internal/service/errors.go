@@ -0,0 +1,39 @@
+package service
+
+import "errors"
+
+// Errors returned by the services; repository errors such as
+// repository.ErrUserNotFound are passed through unchanged
+var (
+	ErrInvalidCredentials       = errors.New("invalid email or password")
+	ErrAccountLocked            = errors.New("account is temporarily locked")
+	ErrLoginThrottled           = errors.New("too many failed logins for this email")
+	ErrAccountSuspended         = errors.New("account is suspended")
+	ErrEmailNotVerified         = errors.New("email address has not been verified")
+	ErrWrongPassword            = errors.New("current password is incorrect")
+	ErrPasswordUnchanged        = errors.New("new password equals the current password")
+	ErrInvalidRefreshToken      = errors.New("invalid or expired refresh token")
+	ErrAccessTokenRevoked       = errors.New("access token has been revoked")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrInvalidResetToken        = errors.New("invalid or expired reset token")
+	ErrInvalidAPIKey            = errors.New("invalid or revoked api key")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotPending      = errors.New("two-factor authentication has not been set up")
+	ErrInvalidTwoFactorCode     = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken          = errors.New("invalid or expired mfa token")
+	ErrInvalidPhone             = errors.New("phone number is not in E.164 format")
+	ErrInvalidLocale            = errors.New("locale is not a BCP 47 language tag")
+	ErrInvalidTimezone          = errors.New("timezone is not an IANA time zone")
+	ErrUnknownOAuthProvider     = errors.New("unknown oauth provider")
+	ErrInvalidOAuthState        = errors.New("invalid or expired oauth state")
+	ErrOAuthEmailNotVerified    = errors.New("identity provider has not verified the email address")
+	ErrOAuthExchangeFailed      = errors.New("identity provider did not complete the sign-in")
+	ErrSessionNotFound          = errors.New("session not found")
+	ErrImpersonateSelf          = errors.New("cannot impersonate yourself")
+	ErrImpersonateAdmin         = errors.New("only super admins may impersonate admins")
+	ErrNestedImpersonation      = errors.New("cannot impersonate while impersonating")
+	ErrRoleNotAssignable        = errors.New("the super_admin role is granted in the database only")
+	ErrAvatarTooLarge           = errors.New("avatar file is too large")
+	ErrUnsupportedImage         = errors.New("avatar must be a PNG, JPEG, or WebP image")
+	ErrAvatarDimensions         = errors.New("avatar exceeds the maximum dimensions")
+)
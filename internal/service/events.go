@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+)
+
+// writeWithEvent runs write and records the event announcing it in one
+// transaction, so the change is never kept without its event. data is
+// encoded after write, so it may be filled in by it.
+func writeWithEvent(
+	ctx context.Context,
+	tx repository.Transactor,
+	events *webhook.Dispatcher,
+	eventType domain.WebhookEvent,
+	data any,
+	write func(ctx context.Context) error,
+) error {
+	return tx.InTx(ctx, func(ctx context.Context) error {
+		if err := write(ctx); err != nil {
+			return err
+		}
+
+		return events.Publish(ctx, eventType, data)
+	})
+}
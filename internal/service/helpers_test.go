@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPassword is the password of the users createTestUser creates
+const testPassword = "correct-horse-battery"
+
+// newTestTokens returns a token manager signing with a fixed test key
+func newTestTokens(t *testing.T) *auth.TokenManager {
+	t.Helper()
+
+	keys, err := auth.NewHMACKeySet("test", map[string][]byte{"test": []byte("service-test-secret")})
+	if err != nil {
+		t.Fatalf("create signing keys: %v", err)
+	}
+
+	return auth.NewTokenManager(keys)
+}
+
+// newTestDenylist returns a denylist kept in memory
+func newTestDenylist() *auth.Denylist {
+	return auth.NewDenylist(kvstore.NewMemory())
+}
+
+// createTestUser stores an active user with testPassword
+func createTestUser(t *testing.T, users repository.UserRepository, email string, roles ...domain.Role) *domain.User {
+	t.Helper()
+
+	if err := auth.SetBcryptCost(bcrypt.MinCost); err != nil {
+		t.Fatalf("set bcrypt cost: %v", err)
+	}
+	if len(roles) == 0 {
+		roles = domain.Roles{domain.RoleUser}
+	}
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user := &domain.User{Email: email, Name: "Test User", Roles: roles, Status: domain.StatusActive, PasswordHash: hash}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user %s: %v", email, err)
+	}
+
+	return user
+}
+
+// mockUserRepository is an in-memory user repository that records the
+// users written to it and fails writes with the errors tests set
+type mockUserRepository struct {
+	*repository.InMemoryUserRepository
+
+	mu        sync.Mutex
+	createErr error
+	updateErr error
+	created   []*domain.User
+	updated   []*domain.User
+}
+
+// newMockUserRepository returns an empty mockUserRepository
+func newMockUserRepository() *mockUserRepository {
+	return &mockUserRepository{InMemoryUserRepository: repository.NewInMemoryUserRepository()}
+}
+
+func (r *mockUserRepository) Create(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	err := r.createErr
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := r.InMemoryUserRepository.Create(ctx, user); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.created = append(r.created, user)
+
+	return nil
+}
+
+func (r *mockUserRepository) Update(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	err := r.updateErr
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := r.InMemoryUserRepository.Update(ctx, user); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updated = append(r.updated, user)
+
+	return nil
+}
+
+// testUserService is a UserService on users with handles on what it sends
+type testUserService struct {
+	*UserService
+	sessions  *SessionService
+	mail      *mailer.MockMailer
+	auditLogs *repository.InMemoryAuditLogRepository
+}
+
+// newTestUserService returns a UserService on users with the given
+// settings, where zero lockout settings get workable defaults
+func newTestUserService(t *testing.T, users repository.UserRepository, settings UserSettings) *testUserService {
+	t.Helper()
+
+	if settings.LockoutThreshold == 0 {
+		settings.LockoutThreshold = 5
+		settings.LockoutDuration = time.Minute
+	}
+	if settings.VerificationTTL == 0 {
+		settings.VerificationTTL = time.Hour
+		settings.ResetTTL = time.Hour
+	}
+
+	dispatcher := webhook.NewDispatcher(repository.NewInMemoryWebhookRepository(), repository.NewInMemoryOutboxRepository(), repository.NewInMemoryTransactor(), webhook.Settings{
+		QueueSize:         10,
+		Workers:           1,
+		MaxAttempts:       1,
+		Timeout:           time.Second,
+		Backoff:           time.Second,
+		PollInterval:      time.Hour,
+		PollBatch:         10,
+		OutboxMaxAttempts: 1,
+	})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = dispatcher.Close(ctx)
+	})
+
+	s := &testUserService{
+		sessions: NewSessionService(
+			users, repository.NewInMemoryRefreshTokenRepository(), newTestTokens(t), newTestDenylist(), time.Minute, time.Hour,
+		),
+		mail:      mailer.NewMockMailer(),
+		auditLogs: repository.NewInMemoryAuditLogRepository(),
+	}
+	s.UserService = NewUserService(
+		users, repository.NewInMemoryTransactor(), repository.NewInMemoryEmailVerificationRepository(),
+		repository.NewInMemoryPasswordResetRepository(), s.sessions, s.mail, audit.NewLogger(s.auditLogs), dispatcher, settings,
+	)
+
+	return s
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// ImpersonationToken is an access token letting an admin act as a user
+type ImpersonationToken struct {
+	AccessToken string
+	ExpiresAt   time.Time
+	User        *domain.User
+}
+
+// ImpersonationService lets admins act as users to reproduce their issues
+type ImpersonationService struct {
+	users  repository.UserRepository
+	tokens *auth.TokenManager
+	audit  *audit.Logger
+	ttl    time.Duration
+}
+
+// NewImpersonationService creates an ImpersonationService issuing tokens
+// valid for ttl, which should not outlast access tokens, so the session
+// denylist can still refuse them
+func NewImpersonationService(users repository.UserRepository, tokens *auth.TokenManager, auditLog *audit.Logger, ttl time.Duration) *ImpersonationService {
+	return &ImpersonationService{users: users, tokens: tokens, audit: auditLog, ttl: ttl}
+}
+
+// Impersonate issues a token acting as the user on behalf of the admin
+// actor. Only super admins may impersonate admins, and impersonation tokens
+// cannot start another impersonation.
+func (s *ImpersonationService) Impersonate(ctx context.Context, actor auth.Identity, userID string) (*ImpersonationToken, error) {
+	if actor.ActorID != "" {
+		return nil, ErrNestedImpersonation
+	}
+	if actor.UserID == userID {
+		return nil, ErrImpersonateSelf
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+	if user.Roles.Grants(domain.RoleAdmin) && !actor.Roles.Grants(domain.RoleSuperAdmin) {
+		return nil, ErrImpersonateAdmin
+	}
+
+	expiresAt := time.Now().Add(s.ttl)
+	token, err := s.tokens.GenerateImpersonationToken(user.ID, user.Roles, actor.UserID, actor.SessionID, s.ttl)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, domain.AuditImpersonate, user.ID)
+
+	return &ImpersonationToken{AccessToken: token, ExpiresAt: expiresAt, User: user}, nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+)
+
+// unknownLogins mirrors account lockout for emails without an account, so
+// repeated failures lock them too and responses don't reveal which emails
+// exist. Its state lives in a kvstore.Store so replicas share it.
+type unknownLogins struct {
+	store kvstore.Store
+}
+
+// newUnknownLogins creates a tracker keeping its state in store
+func newUnknownLogins(store kvstore.Store) *unknownLogins {
+	return &unknownLogins{store: store}
+}
+
+// locked reports whether the email is currently locked. A failing store
+// reports it unlocked, as a real account would answer without the store.
+func (u *unknownLogins) locked(ctx context.Context, email string) bool {
+	_, err := u.store.Get(ctx, unknownLockedKey(email))
+	if err != nil && !errors.Is(err, kvstore.ErrNotFound) {
+		logger.FromContext(ctx).Warn("failed to read unknown email lockout", slog.Any("error", err))
+	}
+
+	return err == nil
+}
+
+// recordFailure counts a failed login and reports whether it locked the
+// email. Failures are forgotten a lockout period after the first one.
+func (u *unknownLogins) recordFailure(ctx context.Context, email string, threshold int, lockout time.Duration) bool {
+	failures, err := u.store.Incr(ctx, unknownFailuresKey(email), lockout)
+	if err != nil {
+		logger.FromContext(ctx).Warn("failed to count unknown email login failure", slog.Any("error", err))
+		return false
+	}
+	if failures < int64(threshold) {
+		return false
+	}
+
+	if err := u.store.Del(ctx, unknownFailuresKey(email)); err != nil {
+		logger.FromContext(ctx).Warn("failed to reset unknown email login failures", slog.Any("error", err))
+	}
+	if err := u.store.SetWithTTL(ctx, unknownLockedKey(email), "1", lockout); err != nil {
+		logger.FromContext(ctx).Warn("failed to lock unknown email", slog.Any("error", err))
+	}
+
+	return true
+}
+
+// unknownFailuresKey names the failure count of an unknown email
+func unknownFailuresKey(email string) string {
+	return "lockout:unknown:" + email + ":failures"
+}
+
+// unknownLockedKey names the lock of an unknown email
+func unknownLockedKey(email string) string {
+	return "lockout:unknown:" + email + ":locked"
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/oauth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+)
+
+// maxNameLength mirrors the name limit enforced on signups
+const maxNameLength = 100
+
+// OAuthStart is what a client needs to send the user to a provider
+type OAuthStart struct {
+	// URL is the provider's consent screen
+	URL string
+	// Nonce must be kept by the browser, such as in a cookie, and passed to
+	// Complete so a callback started elsewhere is rejected
+	Nonce string
+	// ExpiresAt is when the sign-in can no longer be completed
+	ExpiresAt time.Time
+}
+
+// OAuthService signs users in through external identity providers, linking
+// provider accounts to users by verified email or creating new users
+type OAuthService struct {
+	providers  map[string]oauth.Provider
+	users      repository.UserRepository
+	tx         repository.Transactor
+	identities repository.OAuthIdentityRepository
+	sessions   *SessionService
+	tokens     *auth.TokenManager
+	audit      *audit.Logger
+	events     *webhook.Dispatcher
+	stateTTL   time.Duration
+}
+
+// NewOAuthService creates an OAuthService for the named providers. A sign-in
+// must return from the provider within stateTTL.
+func NewOAuthService(
+	providers map[string]oauth.Provider,
+	users repository.UserRepository,
+	tx repository.Transactor,
+	identities repository.OAuthIdentityRepository,
+	sessions *SessionService,
+	tokens *auth.TokenManager,
+	auditLog *audit.Logger,
+	events *webhook.Dispatcher,
+	stateTTL time.Duration,
+) *OAuthService {
+	return &OAuthService{
+		providers:  providers,
+		users:      users,
+		tx:         tx,
+		identities: identities,
+		sessions:   sessions,
+		tokens:     tokens,
+		audit:      auditLog,
+		events:     events,
+		stateTTL:   stateTTL,
+	}
+}
+
+// Start begins a sign-in with the provider, returning its consent screen
+// address with a signed state and the nonce bound to that state
+func (s *OAuthService) Start(_ context.Context, providerName string) (*OAuthStart, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownOAuthProvider
+	}
+
+	nonce, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.tokens.GenerateOAuthState(nonce, s.stateTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthStart{
+		URL:       provider.AuthCodeURL(state),
+		Nonce:     nonce,
+		ExpiresAt: time.Now().Add(s.stateTTL),
+	}, nil
+}
+
+// Complete finishes a sign-in once the provider redirects back with code and
+// state. The state must be one issued by Start for the given nonce.
+func (s *OAuthService) Complete(ctx context.Context, providerName, state, nonce, code string) (*domain.User, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownOAuthProvider
+	}
+
+	stateNonce, err := s.tokens.ParseOAuthState(state)
+	if err != nil || nonce == "" || subtle.ConstantTimeCompare([]byte(stateNonce), []byte(nonce)) != 1 {
+		return nil, ErrInvalidOAuthState
+	}
+
+	profile, err := provider.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOAuthExchangeFailed, err)
+	}
+
+	user, err := s.findOrCreateUser(ctx, providerName, profile)
+	if err != nil {
+		return nil, err
+	}
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+	s.audit.RecordAs(ctx, user.ID, domain.AuditUserLogin, user.ID)
+
+	return user, nil
+}
+
+// findOrCreateUser returns the user linked to the provider account, linking
+// it first to the user with the same email or to a new user
+func (s *OAuthService) findOrCreateUser(ctx context.Context, providerName string, profile *oauth.Profile) (*domain.User, error) {
+	identity, err := s.identities.Get(ctx, providerName, profile.Subject)
+	switch {
+	case err == nil:
+		user, err := s.users.GetByID(ctx, identity.UserID)
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			return user, err
+		}
+		// The linked user was deleted, so link again as for a new account
+	case !errors.Is(err, repository.ErrOAuthIdentityNotFound):
+		return nil, err
+	}
+
+	// Only an address the provider verified proves the account owns it
+	if profile.Email == "" || !profile.EmailVerified {
+		return nil, ErrOAuthEmailNotVerified
+	}
+
+	profile.Email = domain.NormalizeEmail(profile.Email)
+	user, err := s.users.GetByEmail(ctx, profile.Email)
+	switch {
+	case err == nil:
+		if user.EmailVerifiedAt == nil {
+			if err := s.claimUnverified(ctx, user); err != nil {
+				return nil, err
+			}
+		}
+	case errors.Is(err, repository.ErrUserNotFound):
+		if user, err = s.createUser(ctx, profile); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.identities.Link(ctx, &domain.OAuthIdentity{
+		Provider: providerName,
+		Subject:  profile.Subject,
+		UserID:   user.ID,
+	}); err != nil {
+		return nil, err
+	}
+	s.audit.RecordAs(ctx, user.ID, domain.AuditOAuthLink, user.ID)
+
+	return user, nil
+}
+
+// claimUnverified hands an account whose email was never verified to the
+// provider account that proved it owns the address. Whoever registered it may
+// know its password, so the password is replaced and its sessions end; the
+// owner can set a new one through the password reset flow.
+func (s *OAuthService) claimUnverified(ctx context.Context, user *domain.User) error {
+	password, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	user.PasswordHash = hash
+	user.EmailVerifiedAt = &now
+	if err := writeWithEvent(ctx, s.tx, s.events, domain.EventUserUpdated, user, func(ctx context.Context) error {
+		return s.users.Update(ctx, user)
+	}); err != nil {
+		return err
+	}
+
+	return s.sessions.EndAll(ctx, user.ID, "")
+}
+
+// createUser adds a verified user for the provider account. It gets a random
+// password, which the user may replace through the password reset flow.
+func (s *OAuthService) createUser(ctx context.Context, profile *oauth.Profile) (*domain.User, error) {
+	name := strings.TrimSpace(profile.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(profile.Email, "@")
+	}
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength])
+	}
+
+	user, err := newAdminCreatedUser(CreateUserInput{Email: profile.Email, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeWithEvent(ctx, s.tx, s.events, domain.EventUserCreated, user, func(ctx context.Context) error {
+		return s.users.Create(ctx, user)
+	}); err != nil {
+		return nil, err
+	}
+	s.audit.RecordAs(ctx, user.ID, domain.AuditUserRegister, user.ID)
+
+	return user, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// ChangePasswordInput describes a password change by a signed-in user
+type ChangePasswordInput struct {
+	CurrentPassword string
+	NewPassword     string
+	// RevokeOtherSessions signs out every session except the one RefreshToken belongs to
+	RevokeOtherSessions bool
+	RefreshToken        string
+}
+
+// ChangePassword replaces the user's password after checking the current one
+func (s *UserService) ChangePassword(ctx context.Context, userID string, input ChangePasswordInput) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !auth.CheckPassword(user.PasswordHash, input.CurrentPassword) {
+		return ErrWrongPassword
+	}
+	if input.NewPassword == input.CurrentPassword {
+		return ErrPasswordUnchanged
+	}
+	if err := auth.ValidatePassword(input.NewPassword); err != nil {
+		return err
+	}
+
+	hash, err := auth.HashPassword(input.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+	if err := s.users.Update(ctx, user); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditPasswordChange, user.ID)
+
+	if input.RevokeOtherSessions {
+		return s.sessions.EndAll(ctx, user.ID, input.RefreshToken)
+	}
+
+	return nil
+}
+
+// RequestPasswordReset emails a reset token when the email belongs to an
+// account; unknown emails are silently ignored so callers cannot probe for them
+func (s *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, domain.NormalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.sendPasswordReset(ctx, user); err != nil {
+		logger.FromContext(ctx).Error("failed to send password reset email", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+// ResetPassword consumes a reset token, sets the new password, and signs the
+// user out of every existing session
+func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	reset, err := s.resets.GetByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrPasswordResetNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	// Check the password before consuming the token, so the user can retry
+	if err := auth.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	hash, err := auth.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.resets.MarkUsed(ctx, reset.ID); err != nil {
+		if errors.Is(err, repository.ErrPasswordResetUsed) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := s.users.GetByID(ctx, reset.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user.PasswordHash = hash
+	// Receiving the reset email proves ownership of the address
+	if user.EmailVerifiedAt == nil {
+		now := time.Now().UTC()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.users.Update(ctx, user); err != nil {
+		return err
+	}
+	s.audit.RecordAs(ctx, user.ID, domain.AuditPasswordReset, user.ID)
+
+	return s.sessions.EndAll(ctx, user.ID, "")
+}
+
+// sendPasswordReset stores a new reset token for the user and emails it to them
+func (s *UserService) sendPasswordReset(ctx context.Context, user *domain.User) error {
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.resets.Create(ctx, &domain.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(s.settings.ResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := mailer.Render(mailer.TemplatePasswordReset, user.Email, mailer.PasswordResetData{Name: user.Name, Token: token})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
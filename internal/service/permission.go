@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// PermissionService resolves the permissions granted by a set of roles. It
+// keeps the whole role to permission mapping in memory and reloads it once
+// it is older than ttl, so authorizing a request costs no query and changes
+// to role_permissions apply within ttl.
+type PermissionService struct {
+	roles repository.RoleRepository
+	ttl   time.Duration
+
+	// mu guards the cached mapping and serializes its reloads
+	mu       sync.Mutex
+	grants   map[domain.Role][]domain.Permission
+	loadedAt time.Time
+}
+
+// NewPermissionService creates a PermissionService caching the mapping from
+// roles for ttl
+func NewPermissionService(roles repository.RoleRepository, ttl time.Duration) *PermissionService {
+	return &PermissionService{roles: roles, ttl: ttl}
+}
+
+// Permissions returns the union of the permissions the roles grant
+func (s *PermissionService) Permissions(ctx context.Context, roles domain.Roles) (domain.PermissionSet, error) {
+	grants, err := s.mapping(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(domain.PermissionSet)
+	for _, role := range roles {
+		for _, permission := range grants[role] {
+			permissions[permission] = true
+		}
+	}
+
+	return permissions, nil
+}
+
+// mapping returns the cached mapping, reloading it once it has expired. A
+// failed reload keeps serving the previous mapping for another ttl, so a
+// database outage neither locks everyone out nor costs a query per request.
+func (s *PermissionService) mapping(ctx context.Context) (map[domain.Role][]domain.Permission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.grants != nil && time.Since(s.loadedAt) < s.ttl {
+		return s.grants, nil
+	}
+
+	grants, err := s.roles.Permissions(ctx)
+	if err != nil {
+		if s.grants == nil {
+			return nil, err
+		}
+		logger.FromContext(ctx).Warn("failed to reload role permissions", slog.Any("error", err))
+		s.loadedAt = time.Now()
+		return s.grants, nil
+	}
+	s.grants = grants
+	s.loadedAt = time.Now()
+
+	return grants, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+)
+
+// stubRoleRepository returns the mapping or error tests set, counting loads
+type stubRoleRepository struct {
+	mu     sync.Mutex
+	grants map[domain.Role][]domain.Permission
+	err    error
+	loads  int
+}
+
+// Permissions returns the mapping set, or the error when one is set
+func (r *stubRoleRepository) Permissions(context.Context) (map[domain.Role][]domain.Permission, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.loads++
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return r.grants, nil
+}
+
+// set replaces what later loads return
+func (r *stubRoleRepository) set(grants map[domain.Role][]domain.Permission, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.grants, r.err = grants, err
+}
+
+func TestPermissionServiceUnion(t *testing.T) {
+	roles := &stubRoleRepository{grants: map[domain.Role][]domain.Permission{
+		"viewer":  {domain.PermissionUsersRead},
+		"auditor": {domain.PermissionAuditRead, domain.PermissionUsersRead},
+	}}
+	permissions := NewPermissionService(roles, time.Minute)
+
+	tests := []struct {
+		name  string
+		roles domain.Roles
+		want  []domain.Permission
+	}{
+		{"no roles", nil, nil},
+		{"role granting none", domain.Roles{domain.RoleUser}, nil},
+		{"one role", domain.Roles{"viewer"}, []domain.Permission{domain.PermissionUsersRead}},
+		{"composed roles", domain.Roles{"viewer", "auditor"}, []domain.Permission{domain.PermissionUsersRead, domain.PermissionAuditRead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := permissions.Permissions(context.Background(), tt.roles)
+			if err != nil {
+				t.Fatalf("Permissions: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("Permissions(%v) = %v, want %v", tt.roles, got, tt.want)
+			}
+			for _, permission := range tt.want {
+				if !got.Has(permission) {
+					t.Errorf("Permissions(%v) lacks %s", tt.roles, permission)
+				}
+			}
+			if got.Has(domain.PermissionUsersDelete) {
+				t.Errorf("Permissions(%v) has %s, which no role grants", tt.roles, domain.PermissionUsersDelete)
+			}
+		})
+	}
+}
+
+func TestPermissionServiceCachesMapping(t *testing.T) {
+	ctx := context.Background()
+	roles := &stubRoleRepository{grants: map[domain.Role][]domain.Permission{"viewer": {domain.PermissionUsersRead}}}
+	permissions := NewPermissionService(roles, 50*time.Millisecond)
+
+	for range 3 {
+		if _, err := permissions.Permissions(ctx, domain.Roles{"viewer"}); err != nil {
+			t.Fatalf("Permissions: %v", err)
+		}
+	}
+	if roles.loads != 1 {
+		t.Errorf("loaded the mapping %d times within the ttl, want 1", roles.loads)
+	}
+
+	// A change to the mapping applies once the ttl has passed
+	roles.set(map[domain.Role][]domain.Permission{"viewer": {domain.PermissionUsersRead, domain.PermissionUsersWrite}}, nil)
+	time.Sleep(60 * time.Millisecond)
+	got, err := permissions.Permissions(ctx, domain.Roles{"viewer"})
+	if err != nil {
+		t.Fatalf("Permissions after the ttl: %v", err)
+	}
+	if !got.Has(domain.PermissionUsersWrite) || roles.loads != 2 {
+		t.Errorf("after the ttl got %v in %d loads, want the new mapping reloaded", got, roles.loads)
+	}
+}
+
+func TestPermissionServiceFailedReload(t *testing.T) {
+	ctx := context.Background()
+	errDown := errors.New("database is down")
+
+	t.Run("first load", func(t *testing.T) {
+		permissions := NewPermissionService(&stubRoleRepository{err: errDown}, time.Minute)
+		if _, err := permissions.Permissions(ctx, domain.Roles{"viewer"}); !errors.Is(err, errDown) {
+			t.Errorf("Permissions error = %v, want %v", err, errDown)
+		}
+	})
+
+	t.Run("keeps the previous mapping", func(t *testing.T) {
+		roles := &stubRoleRepository{grants: map[domain.Role][]domain.Permission{"viewer": {domain.PermissionUsersRead}}}
+		permissions := NewPermissionService(roles, 20*time.Millisecond)
+		if _, err := permissions.Permissions(ctx, domain.Roles{"viewer"}); err != nil {
+			t.Fatalf("Permissions: %v", err)
+		}
+
+		roles.set(nil, errDown)
+		time.Sleep(30 * time.Millisecond)
+		got, err := permissions.Permissions(ctx, domain.Roles{"viewer"})
+		if err != nil || !got.Has(domain.PermissionUsersRead) {
+			t.Fatalf("Permissions during the outage = %v, %v; want the previous mapping", got, err)
+		}
+		// The failed reload counts as one, so the outage does not cost a query per call
+		if _, err := permissions.Permissions(ctx, domain.Roles{"viewer"}); err != nil || roles.loads != 2 {
+			t.Errorf("Permissions after a failed reload = %v with %d loads, want no further load", err, roles.loads)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// PreferencesInput is the full set of preferences a user saves
+type PreferencesInput struct {
+	// Locale may use any casing or an underscore; it is stored canonical
+	Locale        string
+	Timezone      string
+	Notifications domain.NotificationPreferences
+}
+
+// PreferencesService reads and saves user preferences
+type PreferencesService struct {
+	preferences repository.PreferencesRepository
+	audit       *audit.Logger
+}
+
+// NewPreferencesService creates a PreferencesService with the given repository
+func NewPreferencesService(preferences repository.PreferencesRepository, auditLog *audit.Logger) *PreferencesService {
+	return &PreferencesService{preferences: preferences, audit: auditLog}
+}
+
+// Get returns the preferences of the user, storing the defaults on the first read
+func (s *PreferencesService) Get(ctx context.Context, userID string) (*domain.Preferences, error) {
+	preferences, err := s.preferences.Get(ctx, userID)
+	if errors.Is(err, repository.ErrPreferencesNotFound) {
+		return s.preferences.CreateIfMissing(ctx, domain.DefaultPreferences(userID))
+	}
+
+	return preferences, err
+}
+
+// GetMany returns the preferences of the users keyed by user id, storing the
+// defaults of those without any, in two queries at most
+func (s *PreferencesService) GetMany(ctx context.Context, userIDs []string) (map[string]*domain.Preferences, error) {
+	preferences, err := s.preferences.GetMany(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, userID := range userIDs {
+		if preferences[userID] == nil {
+			missing = append(missing, userID)
+		}
+	}
+	if len(missing) == 0 {
+		return preferences, nil
+	}
+
+	created, err := s.preferences.CreateDefaults(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range missing {
+		preferences[userID] = created[userID]
+		// A concurrent first read stored this user's defaults first
+		if preferences[userID] == nil {
+			preferences[userID] = domain.DefaultPreferences(userID)
+		}
+	}
+
+	return preferences, nil
+}
+
+// Update replaces the preferences of the user
+func (s *PreferencesService) Update(ctx context.Context, userID string, input PreferencesInput) (*domain.Preferences, error) {
+	locale, ok := domain.NormalizeLocale(input.Locale)
+	if !ok {
+		return nil, ErrInvalidLocale
+	}
+	if !validTimezone(input.Timezone) {
+		return nil, ErrInvalidTimezone
+	}
+
+	preferences := &domain.Preferences{
+		UserID:        userID,
+		Locale:        locale,
+		Timezone:      input.Timezone,
+		Notifications: input.Notifications,
+	}
+	if err := s.preferences.Save(ctx, preferences); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, domain.AuditPreferencesUpdate, userID)
+
+	return preferences, nil
+}
+
+// validTimezone reports whether name is an IANA time zone. LoadLocation also
+// accepts an empty name and Local, which mean UTC and the server's own zone.
+func validTimezone(name string) bool {
+	if name == "" || strings.EqualFold(name, "Local") {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+
+	return err == nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// newTestPreferences returns a PreferencesService on an in-memory repository
+func newTestPreferences() *PreferencesService {
+	return NewPreferencesService(repository.NewInMemoryPreferencesRepository(), audit.NewLogger(repository.NewInMemoryAuditLogRepository()))
+}
+
+func TestPreferencesUpdate(t *testing.T) {
+	ctx := context.Background()
+	preferences := newTestPreferences()
+
+	got, err := preferences.Update(ctx, "user-1", PreferencesInput{Locale: "pt_br", Timezone: "America/Sao_Paulo"})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got.Locale != "pt-BR" || got.Timezone != "America/Sao_Paulo" {
+		t.Errorf("locale, timezone = %q, %q; want pt-BR, America/Sao_Paulo", got.Locale, got.Timezone)
+	}
+
+	stored, err := preferences.Get(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Locale != "pt-BR" || stored.Timezone != "America/Sao_Paulo" {
+		t.Errorf("stored preferences = %+v, want the update", stored)
+	}
+}
+
+func TestPreferencesUpdateRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		input PreferencesInput
+		want  error
+	}{
+		{"unknown timezone", PreferencesInput{Locale: "en", Timezone: "Mars/Olympus_Mons"}, ErrInvalidTimezone},
+		{"empty timezone", PreferencesInput{Locale: "en", Timezone: ""}, ErrInvalidTimezone},
+		{"server's local zone", PreferencesInput{Locale: "en", Timezone: "local"}, ErrInvalidTimezone},
+		{"malformed locale", PreferencesInput{Locale: "en--US", Timezone: "UTC"}, ErrInvalidLocale},
+		{"undetermined locale", PreferencesInput{Locale: "und", Timezone: "UTC"}, ErrInvalidLocale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newTestPreferences().Update(context.Background(), "user-1", tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("Update error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreferencesGetManyCreatesDefaults(t *testing.T) {
+	ctx := context.Background()
+	preferences := newTestPreferences()
+	if _, err := preferences.Update(ctx, "user-1", PreferencesInput{Locale: "th", Timezone: "Asia/Bangkok"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, err := preferences.GetMany(ctx, []string{"user-1", "user-2"})
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if got["user-1"] == nil || got["user-1"].Timezone != "Asia/Bangkok" {
+		t.Errorf("user-1 preferences = %+v, want the stored ones", got["user-1"])
+	}
+	defaults := domain.DefaultPreferences("user-2")
+	if got["user-2"] == nil || got["user-2"].Timezone != defaults.Timezone || got["user-2"].Locale != defaults.Locale {
+		t.Errorf("user-2 preferences = %+v, want the defaults", got["user-2"])
+	}
+
+	// The defaults were stored, so later reads return them too
+	stored, err := preferences.Get(ctx, "user-2")
+	if err != nil || !stored.UpdatedAt.Equal(got["user-2"].UpdatedAt) {
+		t.Errorf("Get after GetMany = %+v, %v; want the stored defaults", stored, err)
+	}
+}
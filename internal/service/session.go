@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/google/uuid"
+)
+
+// TokenPair is an access token with the refresh token that renews it
+type TokenPair struct {
+	AccessToken  string
+	ExpiresAt    time.Time
+	RefreshToken string
+}
+
+// SessionClient describes the device a session is used from
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+// maxUserAgentLength truncates the stored user agent of a session
+const maxUserAgentLength = 512
+
+// SessionService issues, rotates, and revokes login sessions. A session is a
+// family of refresh tokens, each rotation replacing the previous token.
+type SessionService struct {
+	users         repository.UserRepository
+	refreshTokens repository.RefreshTokenRepository
+	tokens        *auth.TokenManager
+	denylist      *auth.Denylist
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+}
+
+// NewSessionService creates a SessionService issuing access tokens valid for
+// accessTTL and refresh tokens valid for refreshTTL. Sessions it ends are
+// added to denylist, when set, so their access tokens stop working at once.
+func NewSessionService(
+	users repository.UserRepository,
+	refreshTokens repository.RefreshTokenRepository,
+	tokens *auth.TokenManager,
+	denylist *auth.Denylist,
+	accessTTL, refreshTTL time.Duration,
+) *SessionService {
+	return &SessionService{
+		users:         users,
+		refreshTokens: refreshTokens,
+		tokens:        tokens,
+		denylist:      denylist,
+		accessTTL:     accessTTL,
+		refreshTTL:    refreshTTL,
+	}
+}
+
+// Start opens a new session for the user on the client
+func (s *SessionService) Start(ctx context.Context, user *domain.User, client SessionClient) (*TokenPair, error) {
+	return s.issue(ctx, user, uuid.NewString(), client)
+}
+
+// Refresh rotates a refresh token and issues a new token pair in the same
+// session, recording the client as its latest
+func (s *SessionService) Refresh(ctx context.Context, refreshToken string, client SessionClient) (*TokenPair, error) {
+	stored, err := s.refreshTokens.GetByHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if stored.Revoked {
+		// A rotated token being presented again means it leaked, so end the whole session
+		if err := s.revokeFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := s.refreshTokens.Revoke(ctx, stored.ID); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenRevoked) {
+			// Lost a race with another rotation of the same token
+			if err := s.revokeFamily(ctx, stored.FamilyID); err != nil {
+				return nil, err
+			}
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	// Load the user so the new access token reflects their current role
+	user, err := s.users.GetByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+
+	return s.issue(ctx, user, stored.FamilyID, client)
+}
+
+// End revokes the presented refresh token; unknown or already revoked tokens are ignored
+func (s *SessionService) End(ctx context.Context, refreshToken string) error {
+	stored, err := s.refreshTokens.GetByHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// A token that was already revoked has been rotated, so its session
+	// either continues with a newer token or has ended before
+	if err := s.refreshTokens.Revoke(ctx, stored.ID); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenRevoked) {
+			return nil
+		}
+		return err
+	}
+
+	return s.denySessions(ctx, stored.FamilyID)
+}
+
+// RevokeAccessToken refuses the access token for the rest of its lifetime,
+// as on logout; invalid or expired tokens are ignored
+func (s *SessionService) RevokeAccessToken(ctx context.Context, accessToken string) error {
+	if s.denylist == nil {
+		return nil
+	}
+
+	claims, err := s.tokens.ParseToken(accessToken)
+	if err != nil {
+		return nil
+	}
+
+	return s.denylist.Revoke(ctx, claims)
+}
+
+// CheckAccessToken returns ErrAccessTokenRevoked when the token or its
+// session has been revoked
+func (s *SessionService) CheckAccessToken(ctx context.Context, claims *auth.Claims) error {
+	if s.denylist == nil {
+		return nil
+	}
+
+	revoked, err := s.denylist.Revoked(ctx, claims)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrAccessTokenRevoked
+	}
+
+	return nil
+}
+
+// EndAll revokes every session of the user, except the one the keepRefreshToken
+// belongs to when it is a live token of that user
+func (s *SessionService) EndAll(ctx context.Context, userID, keepRefreshToken string) error {
+	var keepFamilyID string
+	if keepRefreshToken != "" {
+		stored, err := s.refreshTokens.GetByHash(ctx, auth.HashToken(keepRefreshToken))
+		if err != nil && !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return err
+		}
+		if stored != nil && stored.UserID == userID && !stored.Revoked {
+			keepFamilyID = stored.FamilyID
+		}
+	}
+
+	return s.RevokeOthers(ctx, userID, keepFamilyID)
+}
+
+// List returns the user's active sessions, most recently used first
+func (s *SessionService) List(ctx context.Context, userID string) ([]*domain.Session, error) {
+	return s.refreshTokens.ListSessions(ctx, userID)
+}
+
+// Revoke ends one of the user's active sessions, returning
+// ErrSessionNotFound when they have no such session
+func (s *SessionService) Revoke(ctx context.Context, userID, sessionID string) error {
+	sessions, err := s.refreshTokens.ListSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(sessions, func(session *domain.Session) bool { return session.ID == sessionID }) {
+		return ErrSessionNotFound
+	}
+
+	return s.revokeFamily(ctx, sessionID)
+}
+
+// RevokeOthers ends every session of the user except keepSessionID; an empty
+// keepSessionID ends them all
+func (s *SessionService) RevokeOthers(ctx context.Context, userID, keepSessionID string) error {
+	var ended []string
+	if s.denylist != nil {
+		sessions, err := s.refreshTokens.ListSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, session := range sessions {
+			if session.ID != keepSessionID {
+				ended = append(ended, session.ID)
+			}
+		}
+	}
+
+	if err := s.refreshTokens.RevokeAllForUser(ctx, userID, keepSessionID); err != nil {
+		return err
+	}
+
+	return s.denySessions(ctx, ended...)
+}
+
+// revokeFamily ends the session of a refresh token family
+func (s *SessionService) revokeFamily(ctx context.Context, familyID string) error {
+	if err := s.refreshTokens.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+
+	return s.denySessions(ctx, familyID)
+}
+
+// denySessions adds ended sessions to the denylist. Access tokens are not
+// recorded, so their jtis are unknown; denying the session refuses every
+// token issued to it until the last of them would have expired.
+func (s *SessionService) denySessions(ctx context.Context, sessionIDs ...string) error {
+	if s.denylist == nil {
+		return nil
+	}
+	for _, id := range sessionIDs {
+		if err := s.denylist.RevokeSession(ctx, id, s.accessTTL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// issue creates an access token and a refresh token in the given family
+func (s *SessionService) issue(ctx context.Context, user *domain.User, familyID string, client SessionClient) (*TokenPair, error) {
+	now := time.Now()
+	accessToken, err := s.tokens.GenerateToken(user.ID, user.Roles, familyID, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := client.UserAgent
+	if runes := []rune(userAgent); len(runes) > maxUserAgentLength {
+		userAgent = string(runes[:maxUserAgentLength])
+	}
+
+	err = s.refreshTokens.Create(ctx, &domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: auth.HashToken(refreshToken),
+		ExpiresAt: now.Add(s.refreshTTL),
+		UserAgent: userAgent,
+		IP:        client.IP,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		ExpiresAt:    now.Add(s.accessTTL).UTC(),
+		RefreshToken: refreshToken,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// newTestSessions returns a session service issuing refresh tokens valid
+// for refreshTTL, with the user repository it reads
+func newTestSessions(t *testing.T, refreshTTL time.Duration) (*SessionService, *repository.InMemoryUserRepository) {
+	t.Helper()
+
+	users := repository.NewInMemoryUserRepository()
+	sessions := NewSessionService(
+		users, repository.NewInMemoryRefreshTokenRepository(), newTestTokens(t), newTestDenylist(), time.Minute, refreshTTL,
+	)
+
+	return sessions, users
+}
+
+func TestSessionRefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	sessions, users := newTestSessions(t, time.Hour)
+	user := createTestUser(t, users, "ada@example.com")
+
+	first, err := sessions.Start(ctx, user, SessionClient{})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	second, err := sessions.Refresh(ctx, first.RefreshToken, SessionClient{})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Refresh returned the same refresh token")
+	}
+	if second.AccessToken == "" {
+		t.Error("Refresh returned no access token")
+	}
+
+	// The rotated token keeps the session going
+	if _, err := sessions.Refresh(ctx, second.RefreshToken, SessionClient{}); err != nil {
+		t.Errorf("Refresh with the rotated token: %v", err)
+	}
+}
+
+func TestSessionRefreshRejectsExpiredToken(t *testing.T) {
+	ctx := context.Background()
+	sessions, users := newTestSessions(t, -time.Minute)
+	user := createTestUser(t, users, "ada@example.com")
+
+	pair, err := sessions.Start(ctx, user, SessionClient{})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := sessions.Refresh(ctx, pair.RefreshToken, SessionClient{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh with an expired token error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestSessionRefreshRejectsUnknownToken(t *testing.T) {
+	sessions, _ := newTestSessions(t, time.Hour)
+
+	if _, err := sessions.Refresh(context.Background(), "not-a-refresh-token", SessionClient{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh with an unknown token error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestSessionRefreshReuseEndsSession(t *testing.T) {
+	ctx := context.Background()
+	sessions, users := newTestSessions(t, time.Hour)
+	user := createTestUser(t, users, "ada@example.com")
+
+	first, err := sessions.Start(ctx, user, SessionClient{})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	second, err := sessions.Refresh(ctx, first.RefreshToken, SessionClient{})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// Presenting the rotated-out token again means it leaked
+	if _, err := sessions.Refresh(ctx, first.RefreshToken, SessionClient{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reused refresh token error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err := sessions.Refresh(ctx, second.RefreshToken, SessionClient{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh after reuse error = %v, want the whole session ended", err)
+	}
+
+	claims, err := sessions.tokens.ParseToken(second.AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	if err := sessions.CheckAccessToken(ctx, claims); !errors.Is(err, ErrAccessTokenRevoked) {
+		t.Errorf("access token of the reused session error = %v, want %v", err, ErrAccessTokenRevoked)
+	}
+
+	// Other sessions of the user are unaffected
+	other, err := sessions.Start(ctx, user, SessionClient{})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := sessions.Refresh(ctx, other.RefreshToken, SessionClient{}); err != nil {
+		t.Errorf("Refresh of another session: %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// recoveryCodeCount is the number of recovery codes issued on confirmation
+const recoveryCodeCount = 10
+
+// recoveryCodeBytes is the randomness in each recovery code, 10 base32 characters
+const recoveryCodeBytes = 6
+
+// totpValidateOpts accept the current code and those of the neighboring
+// periods, allowing for clock skew of up to 30 seconds either way
+var totpValidateOpts = totp.ValidateOpts{
+	Period:    30,
+	Skew:      1,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// TwoFactorSettings configures TOTP enrollment and the second login step
+type TwoFactorSettings struct {
+	// Issuer names the service in authenticator apps
+	Issuer string
+	// MFATokenTTL is how long the second step may follow the password step
+	MFATokenTTL time.Duration
+	// Failed codes count towards the same lockout as failed passwords
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// UsedTokenStore keeps the ids of the mfa tokens already exchanged,
+	// so each completes one login; nil keeps them in process memory
+	UsedTokenStore kvstore.Store
+}
+
+// TwoFactorSetup is what an authenticator app needs to enroll
+type TwoFactorSetup struct {
+	Secret string
+	// URL is an otpauth:// URL, usually shown as a QR code
+	URL string
+}
+
+// TwoFactorService enrolls users in TOTP two-factor authentication and runs
+// the second step of their logins
+type TwoFactorService struct {
+	users     repository.UserRepository
+	twoFactor repository.TwoFactorRepository
+	tokens    *auth.TokenManager
+	audit     *audit.Logger
+	used      kvstore.Store
+	settings  TwoFactorSettings
+}
+
+// NewTwoFactorService creates a TwoFactorService with the given repositories and settings
+func NewTwoFactorService(
+	users repository.UserRepository,
+	twoFactor repository.TwoFactorRepository,
+	tokens *auth.TokenManager,
+	auditLog *audit.Logger,
+	settings TwoFactorSettings,
+) *TwoFactorService {
+	used := settings.UsedTokenStore
+	if used == nil {
+		used = kvstore.NewMemory()
+	}
+
+	return &TwoFactorService{
+		users:     users,
+		twoFactor: twoFactor,
+		tokens:    tokens,
+		audit:     auditLog,
+		used:      used,
+		settings:  settings,
+	}
+}
+
+// Enable generates a new TOTP secret for the user. Two-factor stays off until
+// Confirm is called with a code from the secret.
+func (s *TwoFactorService) Enable(ctx context.Context, userID string) (*TwoFactorSetup, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.twoFactor.Get(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrTwoFactorNotFound) {
+		return nil, err
+	}
+	if existing != nil && existing.Enabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.settings.Issuer,
+		AccountName: user.Email,
+		Period:      totpValidateOpts.Period,
+		Digits:      totpValidateOpts.Digits,
+		Algorithm:   totpValidateOpts.Algorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generate totp secret: %w", err)
+	}
+
+	if err := s.twoFactor.SavePending(ctx, &domain.TwoFactor{UserID: userID, Secret: key.Secret()}); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{Secret: key.Secret(), URL: key.URL()}, nil
+}
+
+// Confirm turns on two-factor once the user proves their authenticator works,
+// returning the one-time recovery codes, which cannot be retrieved again
+func (s *TwoFactorService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	enrollment, err := s.twoFactor.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return nil, ErrTwoFactorNotPending
+		}
+		return nil, err
+	}
+	if enrollment.Enabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	// The code cannot complete a login afterwards
+	step, ok := matchTOTP(code, enrollment.Secret, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if err := s.twoFactor.UseTOTPStep(ctx, userID, step); err != nil {
+		if errors.Is(err, repository.ErrTOTPStepUsed) {
+			return nil, ErrInvalidTwoFactorCode
+		}
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = auth.HashToken(normalizeRecoveryCode(codes[i]))
+	}
+
+	if err := s.twoFactor.Confirm(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, domain.AuditTwoFactorEnable, userID)
+	return codes, nil
+}
+
+// Challenge returns the token for the second login step when the user has
+// two-factor enabled, or "" when the password alone suffices
+func (s *TwoFactorService) Challenge(ctx context.Context, user *domain.User) (string, error) {
+	enrollment, err := s.twoFactor.Get(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !enrollment.Enabled() {
+		return "", nil
+	}
+
+	return s.tokens.GenerateMFAToken(user.ID, s.settings.MFATokenTTL)
+}
+
+// Verify completes a two-factor login with a TOTP code or an unused recovery
+// code, returning the user to start a session for. Each mfa token completes
+// one login at most.
+func (s *TwoFactorService) Verify(ctx context.Context, mfaToken, code string) (*domain.User, error) {
+	claims, err := s.tokens.ParseMFAToken(mfaToken)
+	if err != nil || claims.ID == "" {
+		return nil, ErrInvalidMFAToken
+	}
+	if _, err := s.used.Get(ctx, usedMFATokenKey(claims.ID)); err == nil {
+		return nil, ErrInvalidMFAToken
+	} else if !errors.Is(err, kvstore.ErrNotFound) {
+		return nil, fmt.Errorf("check mfa token: %w", err)
+	}
+	userID := claims.Subject
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+	now := time.Now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return nil, ErrAccountLocked
+	}
+
+	enrollment, err := s.twoFactor.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	ok, err := s.checkCode(ctx, enrollment, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		lockedUntil, err := s.users.RecordLoginFailure(ctx, user.ID, s.settings.LockoutThreshold, now.Add(s.settings.LockoutDuration))
+		if err != nil {
+			return nil, err
+		}
+		if lockedUntil != nil && now.Before(*lockedUntil) {
+			return nil, ErrAccountLocked
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	// Counting the uses settles concurrent logins with the same token
+	uses, err := s.used.Incr(ctx, usedMFATokenKey(claims.ID), time.Until(claims.ExpiresAt.Time))
+	if err != nil {
+		return nil, fmt.Errorf("use mfa token: %w", err)
+	}
+	if uses > 1 {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if user.FailedLoginAttempts > 0 {
+		if err := s.users.ResetLoginFailures(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// checkCode accepts a current TOTP code not used before or consumes a recovery code
+func (s *TwoFactorService) checkCode(ctx context.Context, enrollment *domain.TwoFactor, code string) (bool, error) {
+	if step, ok := matchTOTP(code, enrollment.Secret, time.Now()); ok {
+		err := s.twoFactor.UseTOTPStep(ctx, enrollment.UserID, step)
+		if errors.Is(err, repository.ErrTOTPStepUsed) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	err := s.twoFactor.UseRecoveryCode(ctx, enrollment.UserID, auth.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// matchTOTP returns the time step whose code for the secret is code, trying
+// the step of now and those within the skew either side of it
+func matchTOTP(code, secret string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	period := int64(totpValidateOpts.Period)
+	skew := int64(totpValidateOpts.Skew)
+
+	current := now.Unix() / period
+	for step := current - skew; step <= current+skew; step++ {
+		want, err := totp.GenerateCodeCustom(secret, time.Unix(step*period, 0).UTC(), totpValidateOpts)
+		if err == nil && subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// usedMFATokenKey names the entry counting the uses of an mfa token
+func usedMFATokenKey(jti string) string {
+	return "mfa:used:" + jti
+}
+
+// generateRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, recoveryCodeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate recovery code: %w", err)
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode ignores case, spaces, and dashes in a recovery code
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
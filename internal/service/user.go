@@ -0,0 +1,522 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/kvstore"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/ratelimit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/webhook"
+)
+
+// UserSettings controls the account policies enforced by UserService
+type UserSettings struct {
+	VerificationTTL time.Duration
+	ResetTTL        time.Duration
+	// RequireVerifiedEmail rejects logins from users who have not verified their email
+	RequireVerifiedEmail bool
+	// LockoutThreshold consecutive failed logins lock an account for LockoutDuration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// LoginThrottle counts failed logins per email whatever the client IP and
+	// refuses further attempts once it is over its limit; nil disables it
+	LoginThrottle ratelimit.Limiter
+	// LockoutStore keeps the lockout state of emails without an account;
+	// nil keeps it in process memory
+	LockoutStore kvstore.Store
+	// BaseURL is the public address used to build links sent by email
+	BaseURL string
+}
+
+// CreateUserInput describes a user created by an administrator
+type CreateUserInput struct {
+	Email string
+	Name  string
+	// Phone may use any common formatting; it is stored as E.164
+	Phone string
+	// Password may be empty, in which case a random one is set and the user
+	// chooses their own through the password reset flow
+	Password string
+	// Role is the user's first role, user unless set; SetRoles changes them
+	Role domain.Role
+}
+
+// RegisterInput describes a public signup
+type RegisterInput struct {
+	Email    string
+	Name     string
+	Password string
+}
+
+// ProfileUpdate lists the profile fields to change; nil fields are left unchanged
+type ProfileUpdate struct {
+	Email *string
+	Name  *string
+	// Phone is stored as E.164; an empty number removes it
+	Phone *string
+	// ConfirmEmail keeps the current email until the new one is confirmed
+	// from a link sent to it, as when users change their own address
+	ConfirmEmail bool
+	// IfMatch, when set, rejects the update with repository.ErrUserModified
+	// unless it accepts the stored user, such as by comparing its ETag
+	IfMatch func(current *domain.User) bool
+	// Version, when set, is the version of the user the client read; the
+	// update fails with repository.ErrVersionConflict unless it is current
+	Version *int
+}
+
+// deletedUser is the webhook payload of a deleted user
+type deletedUser struct {
+	ID string `json:"id"`
+}
+
+// UserService implements the account rules shared by the user and auth endpoints
+type UserService struct {
+	users         repository.UserRepository
+	tx            repository.Transactor
+	verifications repository.EmailVerificationRepository
+	resets        repository.PasswordResetRepository
+	sessions      *SessionService
+	mailer        mailer.Mailer
+	audit         *audit.Logger
+	events        *webhook.Dispatcher
+	settings      UserSettings
+	unknownLogins *unknownLogins
+}
+
+// NewUserService creates a UserService with the given repositories and settings
+func NewUserService(
+	users repository.UserRepository,
+	tx repository.Transactor,
+	verifications repository.EmailVerificationRepository,
+	resets repository.PasswordResetRepository,
+	sessions *SessionService,
+	mail mailer.Mailer,
+	auditLog *audit.Logger,
+	events *webhook.Dispatcher,
+	settings UserSettings,
+) *UserService {
+	lockoutStore := settings.LockoutStore
+	if lockoutStore == nil {
+		lockoutStore = kvstore.NewMemory()
+	}
+
+	return &UserService{
+		users:         users,
+		tx:            tx,
+		verifications: verifications,
+		resets:        resets,
+		sessions:      sessions,
+		mailer:        mail,
+		audit:         auditLog,
+		events:        events,
+		settings:      settings,
+		unknownLogins: newUnknownLogins(lockoutStore),
+	}
+}
+
+// Create adds a user on behalf of an administrator
+func (s *UserService) Create(ctx context.Context, input CreateUserInput) (*domain.User, error) {
+	user, err := newAdminCreatedUser(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeWithEvent(ctx, s.tx, s.events, domain.EventUserCreated, user, func(ctx context.Context) error {
+		return s.users.Create(ctx, user)
+	}); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, domain.AuditUserCreate, user.ID)
+
+	return user, nil
+}
+
+// CreateBatch adds many users in one transaction, returning the users and a
+// per-user error slice in input order. A weak password or malformed phone
+// number only fails its own user. When atomic is true and any user fails,
+// none are stored.
+func (s *UserService) CreateBatch(ctx context.Context, inputs []CreateUserInput, atomic bool) ([]*domain.User, []error, error) {
+	users := make([]*domain.User, len(inputs))
+	errs := make([]error, len(inputs))
+
+	// bcrypt dominates the cost of a large batch, so hash in parallel
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, input := range inputs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			users[i], errs[i] = newAdminCreatedUser(input)
+		}()
+	}
+	wg.Wait()
+
+	var (
+		valid     []*domain.User
+		positions []int
+	)
+	for i, err := range errs {
+		if err == nil {
+			valid = append(valid, users[i])
+			positions = append(positions, i)
+			continue
+		}
+		if !isInvalidInput(err) {
+			return nil, nil, err
+		}
+	}
+	if len(valid) == 0 || (atomic && len(valid) < len(users)) {
+		return users, errs, nil
+	}
+
+	var stored bool
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		storeErrs, err := s.users.CreateBatch(ctx, valid, atomic)
+		if err != nil {
+			return err
+		}
+		for j, i := range positions {
+			errs[i] = storeErrs[j]
+		}
+
+		failed := slices.ContainsFunc(storeErrs, func(err error) bool { return err != nil })
+		if atomic && failed {
+			return nil
+		}
+		stored = true
+		for i, user := range users {
+			if errs[i] == nil {
+				if err := s.events.Publish(ctx, domain.EventUserCreated, user); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if stored {
+		for i, user := range users {
+			if errs[i] == nil {
+				s.audit.Record(ctx, domain.AuditUserCreate, user.ID)
+			}
+		}
+	}
+
+	return users, errs, nil
+}
+
+// isInvalidInput reports whether err rejects the input of one user, rather
+// than stopping the work as a whole
+func isInvalidInput(err error) bool {
+	var policyErr *auth.PasswordPolicyError
+	return errors.Is(err, ErrInvalidPhone) || errors.As(err, &policyErr)
+}
+
+// Register creates a regular user account and emails a verification link
+func (s *UserService) Register(ctx context.Context, input RegisterInput) (*domain.User, error) {
+	if err := auth.ValidatePassword(input.Password); err != nil {
+		return nil, err
+	}
+
+	hash, err := auth.HashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{
+		Email:        domain.NormalizeEmail(input.Email),
+		Name:         input.Name,
+		Roles:        domain.Roles{domain.RoleUser},
+		Status:       domain.StatusActive,
+		PasswordHash: hash,
+	}
+	if err := writeWithEvent(ctx, s.tx, s.events, domain.EventUserCreated, user, func(ctx context.Context) error {
+		return s.users.Create(ctx, user)
+	}); err != nil {
+		return nil, err
+	}
+	s.audit.RecordAs(ctx, user.ID, domain.AuditUserRegister, user.ID)
+
+	// The account exists at this point, so a delivery failure only means the
+	// user has to ask for the link again
+	if err := s.sendVerification(ctx, user); err != nil {
+		logger.FromContext(ctx).Error("failed to send verification email", slog.Any("error", err))
+	}
+
+	return user, nil
+}
+
+// Get returns the user with the given id
+func (s *UserService) Get(ctx context.Context, id string) (*domain.User, error) {
+	return s.users.GetByID(ctx, id)
+}
+
+// List returns one page of users and the total number matching the filter
+func (s *UserService) List(ctx context.Context, params repository.ListParams) ([]*domain.User, int, error) {
+	return s.users.List(ctx, params)
+}
+
+// ListAfter returns up to limit users following the cursor
+func (s *UserService) ListAfter(ctx context.Context, filter repository.UserFilter, cursor *repository.Cursor, limit int) ([]*domain.User, error) {
+	return s.users.ListAfter(ctx, filter, cursor, limit)
+}
+
+// UpdateProfile changes the user's name, email, and phone number
+func (s *UserService) UpdateProfile(ctx context.Context, id string, update ProfileUpdate) (*domain.User, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.IfMatch != nil && !update.IfMatch(user) {
+		return nil, repository.ErrUserModified
+	}
+	// A stale version conflicts even when If-Match accepts the user; the
+	// repository still refuses a write that races this read
+	if update.Version != nil && *update.Version != user.Version {
+		return nil, repository.ErrVersionConflict
+	}
+
+	var pendingEmail string
+	changed := false
+	if update.Email != nil {
+		email := domain.NormalizeEmail(*update.Email)
+		switch {
+		case email == user.Email:
+		case update.ConfirmEmail:
+			if err := s.checkEmailFree(ctx, email); err != nil {
+				return nil, err
+			}
+			pendingEmail = email
+		default:
+			user.Email = email
+			changed = true
+		}
+	}
+	if update.Name != nil && *update.Name != user.Name {
+		user.Name = *update.Name
+		changed = true
+	}
+	if update.Phone != nil {
+		phone, ok := domain.NormalizePhone(*update.Phone)
+		if !ok {
+			return nil, ErrInvalidPhone
+		}
+		if phone != user.Phone {
+			user.Phone = phone
+			changed = true
+		}
+	}
+
+	// An update changing nothing, or only asking for an email change, leaves
+	// the row alone; ConfirmEmailChange publishes the change once confirmed
+	if changed {
+		// Without a precondition the last write wins; with one, a write that
+		// landed after the check above must not be overwritten
+		err = writeWithEvent(ctx, s.tx, s.events, domain.EventUserUpdated, user, func(ctx context.Context) error {
+			if update.IfMatch == nil {
+				return s.users.Update(ctx, user)
+			}
+			return s.users.UpdateIfUnmodified(ctx, user, user.UpdatedAt)
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.audit.Record(ctx, domain.AuditUserUpdate, user.ID)
+	}
+
+	if pendingEmail != "" {
+		if err := s.requestEmailChange(ctx, user, pendingEmail); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// SetStatus suspends or reactivates the user. Suspending also ends all of
+// their sessions, and RequireAuth rejects their remaining access tokens.
+func (s *UserService) SetStatus(ctx context.Context, id string, status domain.UserStatus) (*domain.User, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == status {
+		return user, nil
+	}
+
+	user.Status = status
+	if err := writeWithEvent(ctx, s.tx, s.events, domain.EventUserUpdated, user, func(ctx context.Context) error {
+		return s.users.Update(ctx, user)
+	}); err != nil {
+		return nil, err
+	}
+
+	action := domain.AuditUserReactivate
+	if user.Suspended() {
+		action = domain.AuditUserSuspend
+		if err := s.sessions.EndAll(ctx, user.ID, ""); err != nil {
+			return nil, err
+		}
+	}
+	s.audit.Record(ctx, action, user.ID)
+
+	return user, nil
+}
+
+// SetRoles replaces the user's roles. Super admin is granted in the database
+// only, so it can neither be given nor taken away here. Access tokens keep
+// the roles they were issued with until they expire.
+func (s *UserService) SetRoles(ctx context.Context, id string, roles domain.Roles) (*domain.User, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	roles = roles.Normalize()
+	if slices.Contains(roles, domain.RoleSuperAdmin) || slices.Contains(user.Roles, domain.RoleSuperAdmin) {
+		return nil, ErrRoleNotAssignable
+	}
+	if slices.Equal(roles, user.Roles) {
+		return user, nil
+	}
+
+	user.Roles = roles
+	if err := writeWithEvent(ctx, s.tx, s.events, domain.EventUserUpdated, user, func(ctx context.Context) error {
+		return s.users.SetRoles(ctx, user)
+	}); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, domain.AuditRolesUpdate, user.ID)
+
+	return user, nil
+}
+
+// CheckActive returns ErrAccountSuspended for suspended users and
+// repository.ErrUserNotFound for deleted ones, so their access tokens stop
+// working before they expire
+func (s *UserService) CheckActive(ctx context.Context, id string) error {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Suspended() {
+		return ErrAccountSuspended
+	}
+
+	return nil
+}
+
+// Delete soft-deletes the user
+func (s *UserService) Delete(ctx context.Context, id string) error {
+	if err := writeWithEvent(ctx, s.tx, s.events, domain.EventUserDeleted, deletedUser{ID: id}, func(ctx context.Context) error {
+		return s.users.Delete(ctx, id)
+	}); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditUserDelete, id)
+
+	return nil
+}
+
+// DeleteBatch soft-deletes the users with the given ids in one transaction
+// and returns the ids it deleted; the rest were not found
+func (s *UserService) DeleteBatch(ctx context.Context, ids []string) ([]string, error) {
+	var deleted []string
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		deleted, err = s.users.DeleteBatch(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range deleted {
+			if err := s.events.Publish(ctx, domain.EventUserDeleted, deletedUser{ID: id}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range deleted {
+		s.audit.Record(ctx, domain.AuditUserDelete, id)
+	}
+
+	return deleted, nil
+}
+
+// Restore brings back a soft-deleted user
+func (s *UserService) Restore(ctx context.Context, id string) (*domain.User, error) {
+	if err := s.users.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, domain.AuditUserRestore, id)
+
+	return s.users.GetByID(ctx, id)
+}
+
+// VerificationRequired reports whether the user must verify their email before signing in
+func (s *UserService) VerificationRequired(user *domain.User) bool {
+	return s.settings.RequireVerifiedEmail && user.EmailVerifiedAt == nil
+}
+
+// newAdminCreatedUser builds the user described by the input, defaulting to the user role
+func newAdminCreatedUser(input CreateUserInput) (*domain.User, error) {
+	phone, ok := domain.NormalizePhone(input.Phone)
+	if !ok {
+		return nil, ErrInvalidPhone
+	}
+
+	password := input.Password
+	if password == "" {
+		var err error
+		if password, err = auth.GenerateOpaqueToken(); err != nil {
+			return nil, err
+		}
+	} else if err := auth.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	role := input.Role
+	if role == "" {
+		role = domain.RoleUser
+	}
+
+	// Accounts created by an admin are trusted, so they skip email verification
+	now := time.Now().UTC()
+
+	return &domain.User{
+		Email:           domain.NormalizeEmail(input.Email),
+		Name:            input.Name,
+		Phone:           phone,
+		Roles:           domain.Roles{role},
+		Status:          domain.StatusActive,
+		PasswordHash:    hash,
+		EmailVerifiedAt: &now,
+	}, nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	users := newMockUserRepository()
+	s := newTestUserService(t, users, UserSettings{})
+	createTestUser(t, users, "taken@example.com")
+
+	user, err := s.Register(ctx, RegisterInput{Email: "  Ada@Example.com ", Name: "Ada", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.Email != "ada@example.com" || len(user.Roles) != 1 || user.Roles[0] != domain.RoleUser {
+		t.Errorf("registered user = %+v, want a normalized email and only the user role", user)
+	}
+	if user.PasswordHash == testPassword || !auth.CheckPassword(user.PasswordHash, testPassword) {
+		t.Error("password is not stored as a hash of the given one")
+	}
+	if got := s.mail.MessagesTo("ada@example.com"); len(got) != 1 || got[0].Template != mailer.TemplateVerifyEmail {
+		t.Errorf("sent %+v, want one verification email", got)
+	}
+
+	if _, err := s.Register(ctx, RegisterInput{Email: "taken@example.com", Name: "Ada", Password: testPassword}); !errors.Is(err, repository.ErrDuplicateEmail) {
+		t.Errorf("Register with a taken email error = %v, want %v", err, repository.ErrDuplicateEmail)
+	}
+
+	var policyErr *auth.PasswordPolicyError
+	before := len(users.created)
+	if _, err := s.Register(ctx, RegisterInput{Email: "weak@example.com", Name: "Weak", Password: "short"}); !errors.As(err, &policyErr) {
+		t.Errorf("Register with a weak password error = %v, want a PasswordPolicyError", err)
+	}
+	if len(users.created) != before {
+		t.Error("Register with a weak password reached the repository")
+	}
+}
+
+func TestRegisterSurvivesMailerFailure(t *testing.T) {
+	s := newTestUserService(t, newMockUserRepository(), UserSettings{})
+	s.mail.FailWith(errors.New("smtp unavailable"))
+
+	if _, err := s.Register(context.Background(), RegisterInput{Email: "ada@example.com", Name: "Ada", Password: testPassword}); err != nil {
+		t.Errorf("Register with a failing mailer error = %v, want nil", err)
+	}
+}
+
+func TestRegisterReturnsRepositoryErrors(t *testing.T) {
+	users := newMockUserRepository()
+	users.createErr = errors.New("database is down")
+	s := newTestUserService(t, users, UserSettings{})
+
+	_, err := s.Register(context.Background(), RegisterInput{Email: "ada@example.com", Name: "Ada", Password: testPassword})
+	if !errors.Is(err, users.createErr) {
+		t.Errorf("Register error = %v, want %v", err, users.createErr)
+	}
+	if got := s.mail.Messages(); len(got) != 0 {
+		t.Errorf("sent %d emails for a user that was not stored, want none", len(got))
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	users := newMockUserRepository()
+	s := newTestUserService(t, users, UserSettings{})
+	user := createTestUser(t, users, "ada@example.com")
+
+	got, err := s.Authenticate(ctx, "ADA@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Errorf("Authenticate returned user %s, want %s", got.ID, user.ID)
+	}
+
+	for _, tt := range []struct{ email, password string }{
+		{"ada@example.com", "not-the-password"},
+		{"nobody@example.com", testPassword},
+	} {
+		if _, err := s.Authenticate(ctx, tt.email, tt.password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Authenticate(%s, %s) error = %v, want %v", tt.email, tt.password, err, ErrInvalidCredentials)
+		}
+	}
+}
+
+func TestAuthenticateRequiresVerifiedEmail(t *testing.T) {
+	users := newMockUserRepository()
+	s := newTestUserService(t, users, UserSettings{RequireVerifiedEmail: true})
+	createTestUser(t, users, "ada@example.com")
+
+	if _, err := s.Authenticate(context.Background(), "ada@example.com", testPassword); !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("Authenticate of an unverified user error = %v, want %v", err, ErrEmailNotVerified)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	users := newMockUserRepository()
+	s := newTestUserService(t, users, UserSettings{})
+	user := createTestUser(t, users, "ada@example.com")
+	createTestUser(t, users, "grace@example.com")
+
+	name, email := "Ada Lovelace", "Lovelace@Example.com"
+	updated, err := s.UpdateProfile(ctx, user.ID, ProfileUpdate{Name: &name, Email: &email})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if updated.Name != name || updated.Email != "lovelace@example.com" {
+		t.Errorf("updated user = %+v, want the new name and normalized email", updated)
+	}
+
+	taken := "grace@example.com"
+	if _, err := s.UpdateProfile(ctx, user.ID, ProfileUpdate{Email: &taken}); !errors.Is(err, repository.ErrDuplicateEmail) {
+		t.Errorf("UpdateProfile to a taken email error = %v, want %v", err, repository.ErrDuplicateEmail)
+	}
+	phone := "not a phone"
+	if _, err := s.UpdateProfile(ctx, user.ID, ProfileUpdate{Phone: &phone}); !errors.Is(err, ErrInvalidPhone) {
+		t.Errorf("UpdateProfile with a bad phone error = %v, want %v", err, ErrInvalidPhone)
+	}
+
+	users.updateErr = errors.New("database is down")
+	// Setting the name it already has writes nothing, so cannot fail
+	if _, err := s.UpdateProfile(ctx, user.ID, ProfileUpdate{Name: &name}); err != nil {
+		t.Errorf("UpdateProfile without a change: %v", err)
+	}
+	fullName := "Augusta Ada King"
+	if _, err := s.UpdateProfile(ctx, user.ID, ProfileUpdate{Name: &fullName}); !errors.Is(err, users.updateErr) {
+		t.Errorf("UpdateProfile error = %v, want %v", err, users.updateErr)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	users := newMockUserRepository()
+	s := newTestUserService(t, users, UserSettings{})
+	user := createTestUser(t, users, "ada@example.com")
+
+	tests := []struct {
+		name    string
+		current string
+		next    string
+		want    error
+	}{
+		{"wrong current password", "not-the-password", "a-brand-new-passphrase", ErrWrongPassword},
+		{"unchanged password", testPassword, testPassword, ErrPasswordUnchanged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.ChangePassword(ctx, user.ID, ChangePasswordInput{CurrentPassword: tt.current, NewPassword: tt.next})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ChangePassword error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if len(users.updated) != 0 {
+		t.Fatalf("rejected changes wrote %d updates, want none", len(users.updated))
+	}
+
+	if err := s.ChangePassword(ctx, user.ID, ChangePasswordInput{CurrentPassword: testPassword, NewPassword: "a-brand-new-passphrase"}); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if len(users.updated) != 1 || !auth.CheckPassword(users.updated[0].PasswordHash, "a-brand-new-passphrase") {
+		t.Error("ChangePassword did not store a hash of the new password")
+	}
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/logger"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/mailer"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// VerifyEmail consumes a verification token and marks the user's email as verified
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	verification, err := s.verifications.GetByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrVerificationNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	// Email change tokens are only accepted by ConfirmEmailChange
+	if verification.NewEmail != "" || verification.UsedAt != nil || time.Now().After(verification.ExpiresAt) {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.consumeVerification(ctx, verification)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	user.EmailVerifiedAt = &now
+	if err := s.users.Update(ctx, user); err != nil {
+		return err
+	}
+	s.audit.RecordAs(ctx, user.ID, domain.AuditEmailVerify, user.ID)
+
+	if err := s.sendWelcome(ctx, user); err != nil {
+		logger.FromContext(ctx).Error("failed to send welcome email", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange consumes an email change token and moves the user to
+// the new address, which the token proves they own. It fails with
+// repository.ErrDuplicateEmail when the address was taken in the meantime.
+func (s *UserService) ConfirmEmailChange(ctx context.Context, token string) (*domain.User, error) {
+	verification, err := s.verifications.GetByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrVerificationNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	if verification.NewEmail == "" || verification.UsedAt != nil || time.Now().After(verification.ExpiresAt) {
+		return nil, ErrInvalidVerificationToken
+	}
+	// Checked before consuming the token so the user can still confirm once
+	// the address is freed
+	if err := s.checkEmailFree(ctx, verification.NewEmail); err != nil {
+		return nil, err
+	}
+
+	user, err := s.consumeVerification(ctx, verification)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	user.Email = verification.NewEmail
+	user.EmailVerifiedAt = &now
+	if err := writeWithEvent(ctx, s.tx, s.events, domain.EventUserUpdated, user, func(ctx context.Context) error {
+		return s.users.Update(ctx, user)
+	}); err != nil {
+		return nil, err
+	}
+	s.audit.RecordAs(ctx, user.ID, domain.AuditEmailChange, user.ID)
+
+	return user, nil
+}
+
+// consumeVerification marks the token used and returns the user it belongs to
+func (s *UserService) consumeVerification(ctx context.Context, verification *domain.EmailVerification) (*domain.User, error) {
+	if err := s.verifications.MarkUsed(ctx, verification.ID); err != nil {
+		if errors.Is(err, repository.ErrVerificationUsed) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(ctx, verification.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResendVerification emails a new verification link when the email belongs to
+// an unverified account; other emails are silently ignored
+func (s *UserService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, domain.NormalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	if err := s.sendVerification(ctx, user); err != nil {
+		logger.FromContext(ctx).Error("failed to send verification email", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+// sendVerification stores a new verification token for the user and emails them the link
+func (s *UserService) sendVerification(ctx context.Context, user *domain.User) error {
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.verifications.Create(ctx, &domain.EmailVerification{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(s.settings.VerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.settings.BaseURL + "/api/v1/auth/verify?token=" + url.QueryEscape(token)
+
+	msg, err := mailer.Render(mailer.TemplateVerifyEmail, user.Email, mailer.VerifyEmailData{Name: user.Name, Link: link})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// requestEmailChange stores a token for moving the user to newEmail, emails
+// the confirmation link to that address, and tells the current address about
+// the request. As with verification links, a delivery failure is only logged;
+// the user can ask for the change again.
+func (s *UserService) requestEmailChange(ctx context.Context, user *domain.User, newEmail string) error {
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.verifications.Create(ctx, &domain.EmailVerification{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		NewEmail:  newEmail,
+		ExpiresAt: time.Now().Add(s.settings.VerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.settings.BaseURL + "/api/v1/auth/confirm-email?token=" + url.QueryEscape(token)
+	confirm, err := mailer.Render(mailer.TemplateConfirmEmailChange, newEmail, mailer.VerifyEmailData{Name: user.Name, Link: link})
+	if err != nil {
+		return err
+	}
+	if err := s.mailer.Send(ctx, confirm); err != nil {
+		logger.FromContext(ctx).Error("failed to send email change confirmation", slog.Any("error", err))
+	}
+
+	notice, err := mailer.Render(mailer.TemplateEmailChangeRequested, user.Email, mailer.EmailChangeRequestedData{Name: user.Name, NewEmail: newEmail})
+	if err != nil {
+		return err
+	}
+	if err := s.mailer.Send(ctx, notice); err != nil {
+		logger.FromContext(ctx).Error("failed to send email change notice", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+// checkEmailFree returns repository.ErrDuplicateEmail when a user already holds email
+func (s *UserService) checkEmailFree(ctx context.Context, email string) error {
+	_, err := s.users.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		return repository.ErrDuplicateEmail
+	case errors.Is(err, repository.ErrUserNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+// sendWelcome greets a user whose email was just verified. The user cannot
+// ask for it again, so it is retried when the mail server is down.
+func (s *UserService) sendWelcome(ctx context.Context, user *domain.User) error {
+	msg, err := mailer.Render(mailer.TemplateWelcome, user.Email, mailer.WelcomeData{Name: user.Name})
+	if err != nil {
+		return err
+	}
+	msg.Retry = true
+
+	return s.mailer.Send(ctx, msg)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/audit"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// WebhookService registers webhooks and reports on their deliveries
+type WebhookService struct {
+	webhooks repository.WebhookRepository
+	audit    *audit.Logger
+}
+
+// NewWebhookService creates a WebhookService backed by the given repository
+func NewWebhookService(webhooks repository.WebhookRepository, auditLog *audit.Logger) *WebhookService {
+	return &WebhookService{webhooks: webhooks, audit: auditLog}
+}
+
+// Create registers a webhook for the events with a new signing secret, which
+// is only returned here
+func (s *WebhookService) Create(ctx context.Context, url string, events []domain.WebhookEvent) (*domain.Webhook, error) {
+	secret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &domain.Webhook{URL: url, Events: events, Secret: secret}
+	if err := s.webhooks.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, domain.AuditWebhookCreate, webhook.ID)
+	return webhook, nil
+}
+
+// List returns every webhook, newest first
+func (s *WebhookService) List(ctx context.Context) ([]*domain.Webhook, error) {
+	return s.webhooks.List(ctx)
+}
+
+// ListDeliveries returns the most recent deliveries of the webhook
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*domain.WebhookDelivery, error) {
+	return s.webhooks.ListDeliveries(ctx, webhookID, limit)
+}
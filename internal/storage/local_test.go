@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewLocal(dir, "http://localhost:8080/uploads/")
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+
+	key := "avatars/user-1/a.png"
+	if err := s.Put(ctx, key, []byte("first"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put(ctx, key, []byte("second"), "image/png"); err != nil {
+		t.Fatalf("Put over an existing object: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "avatars", "user-1", "a.png"))
+	if err != nil {
+		t.Fatalf("read stored file: %v", err)
+	}
+	if string(data) != "second" {
+		t.Errorf("stored %q, want the latest Put", data)
+	}
+
+	if got, want := s.URL(key), "http://localhost:8080/uploads/avatars/user-1/a.png"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	s, err := NewLocal(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+
+	for _, key := range []string{"../outside.png", "avatars/../../outside.png", "/etc/passwd", ""} {
+		if err := s.Put(context.Background(), key, []byte("x"), "image/png"); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", key)
+		}
+	}
+}
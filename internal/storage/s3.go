@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrS3NotImplemented is returned by every write to S3 storage
+var ErrS3NotImplemented = errors.New("s3 storage is not implemented yet")
+
+// S3 is the placeholder for storage in an S3-compatible bucket. It computes
+// object URLs but cannot store or delete objects yet.
+type S3 struct {
+	endpoint string
+	bucket   string
+	region   string
+}
+
+// NewS3 creates S3 storage for the bucket at the endpoint, such as
+// https://s3.us-east-1.amazonaws.com; region is what requests will be signed for
+func NewS3(endpoint, bucket, region string) *S3 {
+	return &S3{endpoint: strings.TrimSuffix(endpoint, "/"), bucket: bucket, region: region}
+}
+
+// Put always fails until S3 uploads are implemented
+func (s *S3) Put(_ context.Context, key string, _ []byte, _ string) error {
+	return fmt.Errorf("put %s: %w", key, ErrS3NotImplemented)
+}
+
+// Delete always fails until S3 deletes are implemented
+func (s *S3) Delete(_ context.Context, key string) error {
+	return fmt.Errorf("delete %s: %w", key, ErrS3NotImplemented)
+}
+
+// URL returns the path-style address of the object
+func (s *S3) URL(key string) string {
+	return s.endpoint + "/" + s.bucket + "/" + key
+}
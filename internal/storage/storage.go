@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps uploaded files. Keys are slash-separated paths chosen by the
+// caller, such as avatars/<user id>/<name>.png.
+type Storage interface {
+	// Put stores data under key, replacing any object already there
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the object, returning ErrNotFound if there is none
+	Delete(ctx context.Context, key string) error
+	// URL returns the public address the object is served from
+	URL(key string) string
+}
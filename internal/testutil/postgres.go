@@ -0,0 +1,76 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/database"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// postgresImage is the server the Postgres-backed tests run against
+const postgresImage = "postgres:17-alpine"
+
+// DockerAvailable reports whether a Docker daemon is reachable to run
+// containers in; the check runs once per test binary
+func DockerAvailable() bool {
+	return dockerAvailable()
+}
+
+var dockerAvailable = sync.OnceValue(func() (ok bool) {
+	// testcontainers panics instead of failing on some broken setups
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return false
+	}
+	defer provider.Close()
+
+	return provider.Health(context.Background()) == nil
+})
+
+// Postgres starts a throwaway PostgreSQL server, applies the migrations and
+// returns a pool on it. The server is removed when t ends, and t is skipped
+// when Docker is unavailable.
+func Postgres(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	if !DockerAvailable() {
+		t.Skip("Docker is unavailable")
+	}
+
+	ctx := context.Background()
+	container, err := postgres.Run(ctx, postgresImage,
+		postgres.WithDatabase("users"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("start postgres: %v", err)
+	}
+
+	dbURL, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("postgres connection string: %v", err)
+	}
+	if err := database.RunMigrations(dbURL); err != nil {
+		t.Fatalf("migrate postgres: %v", err)
+	}
+
+	pool, err := database.Connect(ctx, dbURL, 0)
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return pool
+}
@@ -0,0 +1,134 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/i18n"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+// FieldError describes a single failed validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	// rule and args look the message up again in other languages
+	rule string
+	args []any
+}
+
+// NewFieldError reports that field failed rule, an i18n rule key, with the
+// English message
+func NewFieldError(field, rule string, args ...any) FieldError {
+	return FieldError{Field: field, Message: i18n.Rule(i18n.English, rule, args...), rule: rule, args: args}
+}
+
+// Error is returned when one or more fields fail validation
+type Error struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fe.Field + " " + fe.Message
+	}
+
+	return "validation failed: " + strings.Join(parts, ", ")
+}
+
+// Localize returns the field errors with their messages in lang
+func Localize(errs []FieldError, lang language.Tag) []FieldError {
+	localized := make([]FieldError, len(errs))
+	for i, fe := range errs {
+		localized[i] = fe
+		if fe.rule != "" {
+			localized[i].Message = i18n.Rule(lang, fe.rule, fe.args...)
+		}
+	}
+
+	return localized
+}
+
+var validate = newValidator()
+
+// newValidator creates a validator that reports fields by their JSON names
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	// Phone numbers are checked in any common formatting; the service
+	// stores them normalized
+	_ = v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		_, ok := domain.NormalizePhone(fl.Field().String())
+		return ok
+	})
+	// Locales are BCP 47 language tags in any casing, likewise normalized
+	_ = v.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+		_, ok := domain.NormalizeLocale(fl.Field().String())
+		return ok
+	})
+
+	return v
+}
+
+// Validate checks the struct against its validate tags, collecting every failure
+func Validate(s any) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	result := &Error{Errors: make([]FieldError, 0, len(validationErrs))}
+	for _, fe := range validationErrs {
+		rule, args := ruleOf(fe)
+		result.Errors = append(result.Errors, NewFieldError(fe.Field(), rule, args...))
+	}
+
+	return result
+}
+
+// ruleOf returns the i18n rule key describing the failed rule, with the
+// arguments its message takes
+func ruleOf(fe validator.FieldError) (string, []any) {
+	switch fe.Tag() {
+	case "min":
+		return lengthRule(fe, "min", i18n.RuleMinLength, i18n.RuleMinItems), []any{fe.Param()}
+	case "max":
+		return lengthRule(fe, "max", i18n.RuleMaxLength, i18n.RuleMaxItems), []any{fe.Param()}
+	case "oneof":
+		return "oneof", []any{strings.ReplaceAll(fe.Param(), " ", ", ")}
+	default:
+		return fe.Tag(), nil
+	}
+}
+
+// lengthRule picks the key of a min or max rule by what it counts for the
+// field kind: characters of strings, items of collections, or the value itself
+func lengthRule(fe validator.FieldError, value, characters, items string) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return characters
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return items
+	default:
+		return value
+	}
+}
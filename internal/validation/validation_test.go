@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+type signup struct {
+	Email    string   `json:"email" validate:"required,email"`
+	Name     string   `json:"name" validate:"required,max=5"`
+	Password string   `json:"password" validate:"min=8"`
+	Tags     []string `json:"tags" validate:"max=1"`
+	Plan     string   `json:"plan" validate:"omitempty,oneof=free pro"`
+	Internal string   `json:"-" validate:"omitempty,email"`
+}
+
+func TestValidateCollectsEveryFailure(t *testing.T) {
+	err := Validate(signup{
+		Email:    "not-an-email",
+		Name:     "Ada Lovelace",
+		Password: "short",
+		Tags:     []string{"a", "b"},
+		Plan:     "enterprise",
+	})
+
+	var validationErr *Error
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Validate error = %v, want a *Error", err)
+	}
+	want := []string{
+		"email must be a valid email",
+		"name must be at most 5 characters",
+		"password must be at least 8 characters",
+		"tags must be at most 1 items",
+		"plan must be one of: free, pro",
+	}
+	got := make([]string, len(validationErr.Errors))
+	for i, fe := range validationErr.Errors {
+		got[i] = fe.Field + " " + fe.Message
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("field errors = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateAcceptsValidStruct(t *testing.T) {
+	err := Validate(signup{Email: "ada@example.com", Name: "Ada", Password: "long enough", Plan: "pro"})
+	if err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
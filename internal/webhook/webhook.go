@@ -0,0 +1,439 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderSignature = "X-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// storeTimeout bounds each repository call made by the workers
+const storeTimeout = 5 * time.Second
+
+// maxResponseBytes is how much of a response body is read before closing it
+const maxResponseBytes = 64 << 10
+
+// Event is the JSON body of every delivery
+type Event struct {
+	ID        string              `json:"id"`
+	Type      domain.WebhookEvent `json:"type"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      json.RawMessage     `json:"data"`
+}
+
+// Settings configures the dispatcher
+type Settings struct {
+	// QueueSize bounds the delivery attempts waiting for a worker
+	QueueSize int
+	Workers   int
+	// MaxAttempts is the number of tries before a delivery is marked failed
+	MaxAttempts int
+	// Timeout bounds each HTTP attempt
+	Timeout time.Duration
+	// Backoff is the wait before the first retry, doubling after each failure
+	Backoff time.Duration
+	// PollInterval is how often the outbox is checked for unsent events and
+	// the deliveries for due attempts
+	PollInterval time.Duration
+	// PollBatch is the most events relayed, or due deliveries claimed, in
+	// one round of a poll
+	PollBatch int
+	// OutboxMaxAttempts is the number of failed fan-outs before an event is
+	// marked dead
+	OutboxMaxAttempts int
+}
+
+// job is one delivery attempt
+type job struct {
+	delivery *domain.WebhookDelivery
+	webhook  *domain.Webhook
+}
+
+// Dispatcher records events in the outbox and delivers them to the
+// subscribed webhooks. A relay polls the outbox for unsent events and the
+// deliveries for due attempts, so an event committed with its change is
+// delivered at least once even when the process stops before sending it,
+// and several replicas share the work without sending anything twice.
+type Dispatcher struct {
+	webhooks repository.WebhookRepository
+	outbox   repository.OutboxRepository
+	tx       repository.Transactor
+	client   *http.Client
+	settings Settings
+	// lease is how long a claimed delivery is held for its attempt
+	lease time.Duration
+
+	// mu guards closed, so nothing is sent on the queue once it is closed
+	mu      sync.Mutex
+	closed  bool
+	queue   chan job
+	workers sync.WaitGroup
+
+	stopRelay  sync.Once
+	stop       chan struct{}
+	relayEnded chan struct{}
+}
+
+// NewDispatcher creates a Dispatcher and starts its workers and outbox relay.
+// Each event is fanned out in its own transaction from tx, so its
+// deliveries are recorded together with marking it sent.
+func NewDispatcher(
+	webhooks repository.WebhookRepository,
+	outbox repository.OutboxRepository,
+	tx repository.Transactor,
+	settings Settings,
+) *Dispatcher {
+	d := &Dispatcher{
+		webhooks: webhooks,
+		outbox:   outbox,
+		tx:       tx,
+		client:   &http.Client{Timeout: settings.Timeout},
+		settings: settings,
+		// A claimed delivery waits behind at most a full queue shared by the
+		// workers, each attempt taking up to Timeout
+		lease:      time.Duration(settings.QueueSize/max(settings.Workers, 1)+1)*settings.Timeout + storeTimeout,
+		queue:      make(chan job, settings.QueueSize),
+		stop:       make(chan struct{}),
+		relayEnded: make(chan struct{}),
+	}
+
+	for range settings.Workers {
+		d.workers.Add(1)
+		go d.work()
+	}
+	go d.relay()
+
+	return d
+}
+
+// Publish records the event in the outbox for every webhook subscribed to
+// it. Called inside a repository.Transactor transaction, the event commits
+// or rolls back with the change it announces, so callers return the error
+// to undo the change when the event cannot be recorded.
+func (d *Dispatcher) Publish(ctx context.Context, eventType domain.WebhookEvent, data any) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode webhook event: %w", err)
+	}
+
+	if err := d.outbox.Add(ctx, &domain.OutboxEvent{Type: eventType, Payload: body}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Close stops the relay and accepting attempts, then waits for queued
+// attempts to finish or ctx to end. Deliveries waiting for a retry, or
+// claimed and not yet attempted, stay pending in the repository and are
+// sent by the next relay once their backoff or lease ends, as are unsent
+// outbox events.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.stopRelay.Do(func() { close(d.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		// The relay may still be queueing its last event's attempts
+		<-d.relayEnded
+		d.mu.Lock()
+		if !d.closed {
+			d.closed = true
+			close(d.queue)
+		}
+		d.mu.Unlock()
+		d.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// relay hands unsent outbox events to fanOut, and due deliveries to the
+// workers, every PollInterval until Close
+func (d *Dispatcher) relay() {
+	defer close(d.relayEnded)
+
+	ticker := time.NewTicker(d.settings.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.poll()
+		}
+	}
+}
+
+// poll relays pending events a batch at a time, queueing the attempts that
+// are due after each batch, until no events are left or Close is called
+func (d *Dispatcher) poll() {
+	for {
+		relayed := 0
+		for relayed < d.settings.PollBatch && !d.stopping() {
+			ok, err := d.relayNext()
+			if err != nil {
+				slog.Error("failed to claim outbox event", slog.Any("error", err))
+				break
+			}
+			if !ok {
+				break
+			}
+			relayed++
+		}
+
+		d.queueDue()
+		if relayed < d.settings.PollBatch || d.stopping() {
+			return
+		}
+	}
+}
+
+// stopping reports whether Close has been called
+func (d *Dispatcher) stopping() bool {
+	select {
+	case <-d.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// relayNext claims the oldest pending event and, in the same transaction,
+// records its deliveries and marks it sent, so replicas never relay one
+// event twice and a crash before the commit leaves it pending. It reports
+// whether an event was relayed, so a failed one waits for the next poll.
+func (d *Dispatcher) relayNext() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	var (
+		claimed   *domain.OutboxEvent
+		fanOutErr error
+	)
+	err := d.tx.InTx(ctx, func(ctx context.Context) error {
+		events, err := d.outbox.ClaimPending(ctx, 1)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		claimed = events[0]
+
+		if err := d.fanOut(ctx, claimed); err != nil {
+			// Roll the recorded deliveries back along with the claim
+			fanOutErr = err
+			return err
+		}
+		return d.outbox.MarkSent(ctx, claimed.ID)
+	})
+	if claimed == nil {
+		return false, err
+	}
+	if fanOutErr != nil {
+		d.recordFailure(claimed, fanOutErr)
+		return false, nil
+	}
+	if err != nil {
+		slog.Error("failed to relay outbox event", slog.String("event_id", claimed.ID), slog.Any("error", err))
+	}
+
+	return true, nil
+}
+
+// recordFailure counts a failed fan-out of the event, marking it dead once
+// it has failed OutboxMaxAttempts times
+func (d *Dispatcher) recordFailure(event *domain.OutboxEvent, err error) {
+	dead := event.Attempts+1 >= d.settings.OutboxMaxAttempts
+	if dead {
+		slog.Error("giving up on outbox event", slog.String("event_id", event.ID), slog.String("event", string(event.Type)), slog.Any("error", err))
+	} else {
+		slog.Warn("failed to relay outbox event", slog.String("event_id", event.ID), slog.String("event", string(event.Type)), slog.Any("error", err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := d.outbox.RecordFailure(ctx, event.ID, err.Error(), dead); err != nil {
+		slog.Error("failed to record outbox event failure", slog.String("event_id", event.ID), slog.Any("error", err))
+	}
+}
+
+// queueDue claims as many due deliveries as the queue has room for and
+// hands them to the workers. A delivery that cannot be queued keeps its
+// lease and is claimed again once the lease ends.
+func (d *Dispatcher) queueDue() {
+	room := min(cap(d.queue)-len(d.queue), d.settings.PollBatch)
+	if room <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	claimed, err := d.webhooks.ClaimDueDeliveries(ctx, room, d.lease)
+	cancel()
+	if err != nil {
+		slog.Error("failed to claim webhook deliveries", slog.Any("error", err))
+		return
+	}
+
+	for _, c := range claimed {
+		if !d.enqueue(job{delivery: c.Delivery, webhook: c.Webhook}) {
+			slog.Warn("leaving webhook delivery for a later poll", slog.String("delivery_id", c.Delivery.ID))
+		}
+	}
+}
+
+// Sign returns the X-Signature value of a payload: the hex HMAC-SHA256 of the
+// body keyed with the webhook secret, prefixed with sha256=
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// enqueue adds the job unless the queue is full or closed
+func (d *Dispatcher) enqueue(j job) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return false
+	}
+
+	select {
+	case d.queue <- j:
+		return true
+	default:
+		return false
+	}
+}
+
+// work runs jobs until the queue is closed and drained
+func (d *Dispatcher) work() {
+	defer d.workers.Done()
+
+	for j := range d.queue {
+		d.attempt(j.webhook, j.delivery)
+	}
+}
+
+// fanOut records a pending delivery, due at once, for each webhook
+// subscribed to the event, in the transaction open on ctx. Any failure is
+// returned so the whole fan-out rolls back and the event is relayed again.
+func (d *Dispatcher) fanOut(ctx context.Context, event *domain.OutboxEvent) error {
+	payload, err := json.Marshal(&Event{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("encode webhook event: %w", err)
+	}
+
+	webhooks, err := d.webhooks.ListForEvent(ctx, event.Type)
+	if err != nil {
+		return fmt.Errorf("list webhooks: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, webhook := range webhooks {
+		delivery := &domain.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event.Type,
+			Payload:       payload,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := d.webhooks.CreateDelivery(ctx, delivery); err != nil {
+			return fmt.Errorf("record delivery to webhook %s: %w", webhook.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// attempt sends the delivery once. A failure schedules the next attempt
+// after the backoff until the attempts run out; the relay picks it up from
+// the repository when it is due.
+func (d *Dispatcher) attempt(webhook *domain.Webhook, delivery *domain.WebhookDelivery) {
+	delivery.Attempts++
+	status, err := d.send(webhook, delivery)
+	delivery.LastStatusCode = status
+	delivery.NextAttemptAt = nil
+
+	if err == nil {
+		d.record(delivery, domain.DeliverySucceeded, "")
+		return
+	}
+	if delivery.Attempts >= d.settings.MaxAttempts {
+		d.record(delivery, domain.DeliveryFailed, err.Error())
+		return
+	}
+
+	next := time.Now().UTC().Add(d.settings.Backoff << (delivery.Attempts - 1))
+	delivery.NextAttemptAt = &next
+	d.record(delivery, domain.DeliveryPending, err.Error())
+}
+
+// send posts the signed payload, treating any non-2xx response as a failure
+func (d *Dispatcher) send(webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "user-management-api-webhooks")
+	req.Header.Set(HeaderEvent, string(delivery.Event))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain some of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// record saves the outcome of an attempt
+func (d *Dispatcher) record(delivery *domain.WebhookDelivery, status domain.DeliveryStatus, lastError string) {
+	delivery.Status = status
+	delivery.LastError = lastError
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := d.webhooks.UpdateDelivery(ctx, delivery); err != nil {
+		slog.Error("failed to update webhook delivery", slog.String("delivery_id", delivery.ID), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,409 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/domain"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/repository"
+)
+
+// delivered is one request received by the test endpoint
+type delivered struct {
+	header http.Header
+	body   []byte
+}
+
+// newEndpoint serves a webhook endpoint answering with the statuses in turn,
+// then 200, and reports every request it receives on the returned channel
+func newEndpoint(t *testing.T, statuses ...int) (*httptest.Server, <-chan delivered) {
+	t.Helper()
+
+	received := make(chan delivered, 10)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read delivery: %v", err)
+		}
+		received <- delivered{header: r.Header.Clone(), body: body}
+
+		if n := int(calls.Add(1)); n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, received
+}
+
+// newTestDispatcher returns a dispatcher polling quickly and retrying after a
+// millisecond, closed when the test ends
+func newTestDispatcher(t *testing.T, webhooks repository.WebhookRepository, maxAttempts int) *Dispatcher {
+	t.Helper()
+
+	return newOutboxDispatcher(t, webhooks, repository.NewInMemoryOutboxRepository(), maxAttempts)
+}
+
+// newOutboxDispatcher is newTestDispatcher relaying the given outbox
+func newOutboxDispatcher(t *testing.T, webhooks repository.WebhookRepository, outbox repository.OutboxRepository, maxAttempts int) *Dispatcher {
+	t.Helper()
+
+	d := NewDispatcher(webhooks, outbox, repository.NewInMemoryTransactor(), Settings{
+		QueueSize:         10,
+		Workers:           1,
+		MaxAttempts:       maxAttempts,
+		Timeout:           time.Second,
+		Backoff:           time.Millisecond,
+		PollInterval:      5 * time.Millisecond,
+		PollBatch:         10,
+		OutboxMaxAttempts: 3,
+	})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = d.Close(ctx)
+	})
+
+	return d
+}
+
+// register subscribes the URL to user.created
+func register(t *testing.T, webhooks repository.WebhookRepository, url string) *domain.Webhook {
+	t.Helper()
+
+	webhook := &domain.Webhook{URL: url, Events: []domain.WebhookEvent{domain.EventUserCreated}, Secret: "test-secret"}
+	if err := webhooks.Create(context.Background(), webhook); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+
+	return webhook
+}
+
+// receive waits for the next delivery
+func receive(t *testing.T, received <-chan delivered) delivered {
+	t.Helper()
+
+	select {
+	case d := <-received:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery arrived")
+		return delivered{}
+	}
+}
+
+// waitForStatus polls the webhook's only delivery until it reaches status
+func waitForStatus(t *testing.T, webhooks repository.WebhookRepository, webhookID string, status domain.DeliveryStatus) *domain.WebhookDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := webhooks.ListDeliveries(context.Background(), webhookID, 10)
+		if err != nil {
+			t.Fatalf("list deliveries: %v", err)
+		}
+		if len(deliveries) == 1 && deliveries[0].Status == status {
+			return deliveries[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries = %+v, want one %s", deliveries, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '{"a":1}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=aa9e2e3575f5d7098b6caccd790888c36d5fdb63342a73bada2d6a51747a8494"
+	if got := Sign("secret", []byte(`{"a":1}`)); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestDispatcherDeliversSignedEvent(t *testing.T) {
+	webhooks := repository.NewInMemoryWebhookRepository()
+	server, received := newEndpoint(t)
+	webhook := register(t, webhooks, server.URL)
+	d := newTestDispatcher(t, webhooks, 3)
+
+	if err := d.Publish(context.Background(), domain.EventUserCreated, map[string]string{"email": "ada@example.com"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	got := receive(t, received)
+	if sig := got.header.Get(HeaderSignature); sig != Sign(webhook.Secret, got.body) {
+		t.Errorf("%s = %q, want the HMAC of the body", HeaderSignature, sig)
+	}
+	if event := got.header.Get(HeaderEvent); event != string(domain.EventUserCreated) {
+		t.Errorf("%s = %q, want %q", HeaderEvent, event, domain.EventUserCreated)
+	}
+
+	var event Event
+	if err := json.Unmarshal(got.body, &event); err != nil {
+		t.Fatalf("decode delivery %s: %v", got.body, err)
+	}
+	if event.ID == "" || event.Type != domain.EventUserCreated || string(event.Data) != `{"email":"ada@example.com"}` {
+		t.Errorf("event = %+v, want the published user.created", event)
+	}
+
+	delivery := waitForStatus(t, webhooks, webhook.ID, domain.DeliverySucceeded)
+	if delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusOK {
+		t.Errorf("delivery = %+v, want one attempt answered with 200", delivery)
+	}
+}
+
+func TestDispatcherIgnoresOtherEvents(t *testing.T) {
+	webhooks := repository.NewInMemoryWebhookRepository()
+	server, received := newEndpoint(t)
+	register(t, webhooks, server.URL)
+	d := newTestDispatcher(t, webhooks, 3)
+
+	if err := d.Publish(context.Background(), domain.EventUserDeleted, map[string]string{"id": "1"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		t.Errorf("received %s for an event the webhook is not subscribed to", got.body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	t.Run("succeeds after a failure", func(t *testing.T) {
+		webhooks := repository.NewInMemoryWebhookRepository()
+		server, received := newEndpoint(t, http.StatusInternalServerError)
+		webhook := register(t, webhooks, server.URL)
+		d := newTestDispatcher(t, webhooks, 3)
+
+		if err := d.Publish(context.Background(), domain.EventUserCreated, map[string]string{}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+
+		first, second := receive(t, received), receive(t, received)
+		if first.header.Get(HeaderDelivery) != second.header.Get(HeaderDelivery) {
+			t.Error("the retry was sent as a different delivery")
+		}
+		delivery := waitForStatus(t, webhooks, webhook.ID, domain.DeliverySucceeded)
+		if delivery.Attempts != 2 {
+			t.Errorf("attempts = %d, want 2", delivery.Attempts)
+		}
+	})
+
+	t.Run("fails after the last attempt", func(t *testing.T) {
+		webhooks := repository.NewInMemoryWebhookRepository()
+		server, received := newEndpoint(t, http.StatusBadGateway, http.StatusBadGateway)
+		webhook := register(t, webhooks, server.URL)
+		d := newTestDispatcher(t, webhooks, 2)
+
+		if err := d.Publish(context.Background(), domain.EventUserCreated, map[string]string{}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+
+		receive(t, received)
+		receive(t, received)
+		delivery := waitForStatus(t, webhooks, webhook.ID, domain.DeliveryFailed)
+		if delivery.Attempts != 2 || delivery.LastStatusCode != http.StatusBadGateway || delivery.LastError == "" {
+			t.Errorf("delivery = %+v, want two attempts ending with the 502", delivery)
+		}
+	})
+}
+
+func TestOutboxDeliversAfterCrash(t *testing.T) {
+	webhooks := repository.NewInMemoryWebhookRepository()
+	outbox := repository.NewInMemoryOutboxRepository()
+	server, received := newEndpoint(t)
+	register(t, webhooks, server.URL)
+
+	// The first process commits the event and stops before its relay runs
+	crashed := NewDispatcher(webhooks, outbox, repository.NewInMemoryTransactor(), Settings{
+		QueueSize: 10, Workers: 1, MaxAttempts: 1, Timeout: time.Second, Backoff: time.Second,
+		PollInterval: time.Hour, PollBatch: 10, OutboxMaxAttempts: 3,
+	})
+	if err := crashed.Publish(context.Background(), domain.EventUserCreated, map[string]string{"email": "ada@example.com"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := crashed.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	select {
+	case got := <-received:
+		t.Fatalf("received %s before the restart", got.body)
+	default:
+	}
+	pending, err := outbox.ClaimPending(context.Background(), 10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending events = %v, %v, want the committed event", pending, err)
+	}
+
+	// The next process picks the event up from the outbox
+	newOutboxDispatcher(t, webhooks, outbox, 1)
+	var event Event
+	if err := json.Unmarshal(receive(t, received).body, &event); err != nil {
+		t.Fatalf("decode delivery: %v", err)
+	}
+	if event.ID != pending[0].ID || event.Type != domain.EventUserCreated {
+		t.Errorf("delivered %+v, want the outbox event %s", event, pending[0].ID)
+	}
+
+	waitForPending(t, outbox, 0)
+}
+
+// failingWebhooks fails to list the webhooks of any event
+type failingWebhooks struct {
+	*repository.InMemoryWebhookRepository
+}
+
+func (failingWebhooks) ListForEvent(context.Context, domain.WebhookEvent) ([]*domain.Webhook, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func TestOutboxMarksPoisonEventsDead(t *testing.T) {
+	outbox := repository.NewInMemoryOutboxRepository()
+	event := &domain.OutboxEvent{Type: domain.EventUserCreated, Payload: []byte(`{}`)}
+	if err := outbox.Add(context.Background(), event); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// newOutboxDispatcher gives up after three failed fan-outs
+	newOutboxDispatcher(t, failingWebhooks{repository.NewInMemoryWebhookRepository()}, outbox, 1)
+	waitForPending(t, outbox, 0)
+}
+
+// waitForPending polls the outbox until it holds n pending events
+func waitForPending(t *testing.T, outbox repository.OutboxRepository, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pending, err := outbox.ClaimPending(context.Background(), 10)
+		if err != nil {
+			t.Fatalf("list pending events: %v", err)
+		}
+		if len(pending) == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pending events = %d, want %d", len(pending), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcherResumesRetryAfterRestart(t *testing.T) {
+	webhooks := repository.NewInMemoryWebhookRepository()
+	outbox := repository.NewInMemoryOutboxRepository()
+	server, received := newEndpoint(t, http.StatusInternalServerError)
+	webhook := register(t, webhooks, server.URL)
+
+	// The first process fails the first attempt and stops before the retry
+	stopped := NewDispatcher(webhooks, outbox, repository.NewInMemoryTransactor(), Settings{
+		QueueSize: 10, Workers: 1, MaxAttempts: 3, Timeout: time.Second, Backoff: 100 * time.Millisecond,
+		PollInterval: 5 * time.Millisecond, PollBatch: 10, OutboxMaxAttempts: 3,
+	})
+	if err := stopped.Publish(context.Background(), domain.EventUserCreated, map[string]string{}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	first := receive(t, received)
+	waiting := waitForAttempts(t, webhooks, webhook.ID, 1)
+	if err := stopped.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if waiting.Status != domain.DeliveryPending || waiting.NextAttemptAt == nil {
+		t.Fatalf("delivery after the failure = %+v, want it pending with its next attempt scheduled", waiting)
+	}
+
+	// The next process sends the retry once it is due
+	newOutboxDispatcher(t, webhooks, outbox, 3)
+	second := receive(t, received)
+	if second.header.Get(HeaderDelivery) != first.header.Get(HeaderDelivery) {
+		t.Error("the retry was sent as a different delivery")
+	}
+	delivery := waitForStatus(t, webhooks, webhook.ID, domain.DeliverySucceeded)
+	if delivery.Attempts != 2 || delivery.NextAttemptAt != nil {
+		t.Errorf("delivery = %+v, want two attempts and nothing scheduled", delivery)
+	}
+}
+
+func TestDispatcherWaitsForRoomInQueue(t *testing.T) {
+	webhooks := repository.NewInMemoryWebhookRepository()
+	var ids []string
+	for range 5 {
+		server, _ := newEndpoint(t)
+		ids = append(ids, register(t, webhooks, server.URL).ID)
+	}
+
+	// A queue of one cannot take the five deliveries at once
+	d := NewDispatcher(webhooks, repository.NewInMemoryOutboxRepository(), repository.NewInMemoryTransactor(), Settings{
+		QueueSize: 1, Workers: 1, MaxAttempts: 1, Timeout: time.Second, Backoff: time.Millisecond,
+		PollInterval: 5 * time.Millisecond, PollBatch: 10, OutboxMaxAttempts: 3,
+	})
+	t.Cleanup(func() { _ = d.Close(context.Background()) })
+	if err := d.Publish(context.Background(), domain.EventUserCreated, map[string]string{}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	for _, id := range ids {
+		waitForStatus(t, webhooks, id, domain.DeliverySucceeded)
+	}
+}
+
+// flakyWebhooks fails to record the first fails deliveries
+type flakyWebhooks struct {
+	*repository.InMemoryWebhookRepository
+	fails atomic.Int32
+}
+
+func (r *flakyWebhooks) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	if r.fails.Add(-1) >= 0 {
+		return errors.New("database unavailable")
+	}
+
+	return r.InMemoryWebhookRepository.CreateDelivery(ctx, delivery)
+}
+
+func TestOutboxKeepsEventWhenDeliveryNotRecorded(t *testing.T) {
+	webhooks := &flakyWebhooks{InMemoryWebhookRepository: repository.NewInMemoryWebhookRepository()}
+	webhooks.fails.Store(1)
+	outbox := repository.NewInMemoryOutboxRepository()
+	server, received := newEndpoint(t)
+	webhook := register(t, webhooks, server.URL)
+	if err := outbox.Add(context.Background(), &domain.OutboxEvent{Type: domain.EventUserCreated, Payload: []byte(`{}`)}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// The failed fan-out leaves the event pending for the next poll
+	newOutboxDispatcher(t, webhooks, outbox, 1)
+	receive(t, received)
+	waitForStatus(t, webhooks, webhook.ID, domain.DeliverySucceeded)
+	waitForPending(t, outbox, 0)
+}
+
+// waitForAttempts polls the webhook's only delivery until it has been
+// attempted n times
+func waitForAttempts(t *testing.T, webhooks repository.WebhookRepository, webhookID string, n int) *domain.WebhookDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := webhooks.ListDeliveries(context.Background(), webhookID, 10)
+		if err != nil {
+			t.Fatalf("list deliveries: %v", err)
+		}
+		if len(deliveries) == 1 && deliveries[0].Attempts == n {
+			return deliveries[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries = %+v, want one attempted %d times", deliveries, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
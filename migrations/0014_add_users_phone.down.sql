@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_phone_active_key;
+
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
@@ -0,0 +1,5 @@
+-- Numbers are stored in E.164 form, so formatting differences cannot create duplicates
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone TEXT;
+
+-- Like emails, only active users need unique phone numbers
+CREATE UNIQUE INDEX IF NOT EXISTS users_phone_active_key ON users (phone) WHERE deleted_at IS NULL;
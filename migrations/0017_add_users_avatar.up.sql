@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS avatar_key TEXT,
+    ADD COLUMN IF NOT EXISTS avatar_url TEXT;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS refresh_tokens_user_id_idx;
+
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS ip,
+    DROP COLUMN IF EXISTS user_agent;
@@ -0,0 +1,6 @@
+-- Fails with a unique violation if two active users hold the same address in
+-- different cases; resolve those accounts by hand before migrating
+UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email));
+
+DROP INDEX IF EXISTS users_email_active_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_active_key ON users (LOWER(email)) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- An email change is confirmed with a verification token naming the new
+-- address; plain verifications leave it empty
+ALTER TABLE email_verifications ADD COLUMN IF NOT EXISTS new_email TEXT NOT NULL DEFAULT '';
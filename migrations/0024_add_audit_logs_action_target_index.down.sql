@@ -0,0 +1 @@
+DROP INDEX IF EXISTS audit_logs_action_target_idx;
@@ -0,0 +1,2 @@
+-- Serves the newest entry of an action per target, such as a user's last login
+CREATE INDEX IF NOT EXISTS audit_logs_action_target_idx ON audit_logs (action, target_id, created_at);
@@ -0,0 +1,4 @@
+UPDATE users SET role = 'admin' WHERE role = 'super_admin';
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_role_check,
+    ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_role_check,
+    ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin', 'super_admin'));
@@ -0,0 +1 @@
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS impersonated_id UUID;
@@ -0,0 +1,13 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'
+    CONSTRAINT users_role_check CHECK (role IN ('user', 'admin', 'super_admin'));
+
+-- Keep the highest built-in role of each user; other roles are lost
+UPDATE users SET role = CASE
+    WHEN EXISTS (SELECT 1 FROM user_roles WHERE user_id = users.id AND role = 'super_admin') THEN 'super_admin'
+    WHEN EXISTS (SELECT 1 FROM user_roles WHERE user_id = users.id AND role = 'admin') THEN 'admin'
+    ELSE 'user'
+END;
+
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS roles;
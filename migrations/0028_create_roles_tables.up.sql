@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO roles (name) VALUES ('user'), ('admin'), ('super_admin') ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role TEXT NOT NULL REFERENCES roles (name),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS user_roles_role_idx ON user_roles (role);
+
+INSERT INTO user_roles (user_id, role) SELECT id, role FROM users ON CONFLICT DO NOTHING;
+
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    name TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO permissions (name) VALUES
+    ('users:read'), ('users:write'), ('users:delete'), ('users:impersonate'), ('roles:manage'),
+    ('audit:read'), ('api_keys:manage'), ('webhooks:manage'), ('health:read')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (role, permission)
+);
+
+-- Admins and super admins keep everything they could do before permissions
+INSERT INTO role_permissions (role, permission)
+SELECT roles.name, permissions.name FROM roles CROSS JOIN permissions
+WHERE roles.name IN ('admin', 'super_admin')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,9 @@
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ;
+
+-- Retries used to wait in memory, and a full queue failed deliveries before
+-- their first attempt; both are picked up again from the table now
+UPDATE webhook_deliveries
+SET status = 'pending', next_attempt_at = NOW()
+WHERE status = 'pending' OR (status = 'failed' AND attempts = 0);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,6 @@
+-- Callers may have used the same key, which can no longer be told apart
+DELETE FROM idempotency_keys;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS owner;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key, endpoint);
@@ -0,0 +1,7 @@
+-- Keys stored before callers were recorded are left unowned, so no caller
+-- can replay them, and expire with the rest
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys ALTER COLUMN owner DROP DEFAULT;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (owner, key, endpoint);
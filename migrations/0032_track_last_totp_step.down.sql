@@ -0,0 +1 @@
+ALTER TABLE user_two_factor DROP COLUMN IF EXISTS last_totp_step;
@@ -0,0 +1,3 @@
+-- The time step of the last accepted TOTP code, so a code cannot be used
+-- again while it is still within the skew window
+ALTER TABLE user_two_factor ADD COLUMN IF NOT EXISTS last_totp_step BIGINT;
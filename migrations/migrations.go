@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL schema migrations
+package migrations
+
+import "embed"
+
+// FS contains all migration files
+//
+//go:embed *.sql
+var FS embed.FS
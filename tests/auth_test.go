@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+)
+
+// tokens is the token pair returned by registration and login
+type tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// profile is the part of a user these tests look at
+type profile struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+func TestRegisterLoginProfile(t *testing.T) {
+	s := newServer(t)
+
+	var registered struct {
+		User profile `json:"user"`
+	}
+	status := s.do(http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"email":    "ada@example.com",
+		"name":     "Ada Lovelace",
+		"password": "Analytical-Engine-1843",
+	}, &registered)
+	if status != http.StatusCreated {
+		t.Fatalf("register: status %d, want %d", status, http.StatusCreated)
+	}
+	if registered.User.ID == "" {
+		t.Fatal("register: response has no user id")
+	}
+
+	var login tokens
+	status = s.do(http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email":    "ada@example.com",
+		"password": "Analytical-Engine-1843",
+	}, &login)
+	if status != http.StatusOK {
+		t.Fatalf("login: status %d, want %d", status, http.StatusOK)
+	}
+	if login.AccessToken == "" || login.RefreshToken == "" {
+		t.Fatalf("login: got tokens %+v, want an access and a refresh token", login)
+	}
+
+	var me profile
+	if status := s.do(http.MethodGet, "/api/v1/me", login.AccessToken, nil, &me); status != http.StatusOK {
+		t.Fatalf("get profile: status %d, want %d", status, http.StatusOK)
+	}
+	want := profile{ID: registered.User.ID, Email: "ada@example.com", Name: "Ada Lovelace"}
+	if me != want {
+		t.Errorf("get profile: got %+v, want %+v", me, want)
+	}
+
+	if status := s.do(http.MethodGet, "/api/v1/me", "", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("get profile without a token: status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
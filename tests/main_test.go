@@ -0,0 +1,20 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+)
+
+// TestMain skips the whole suite when Docker is unavailable, as every test
+// here runs against a real PostgreSQL server
+func TestMain(m *testing.M) {
+	if !testutil.DockerAvailable() {
+		fmt.Println("skipping end-to-end tests: Docker is unavailable")
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
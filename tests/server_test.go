@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/app"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/auth"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/config"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/handler"
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+	"github.com/gofiber/fiber/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// server is the application built on a throwaway PostgreSQL database the
+// way cmd/main builds it, with the integrations the configuration leaves off
+type server struct {
+	t   *testing.T
+	app *fiber.App
+}
+
+// newServer starts PostgreSQL and serves the application on it with the
+// development defaults of the configuration
+func newServer(t *testing.T) *server {
+	t.Helper()
+	pool := testutil.Postgres(t)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	cfg.Storage.LocalDir = t.TempDir()
+	if err := auth.SetBcryptCost(bcrypt.MinCost); err != nil {
+		t.Fatalf("set bcrypt cost: %v", err)
+	}
+	keys, err := auth.NewHMACKeySet("test", map[string][]byte{"test": []byte("end-to-end-test-secret")})
+	if err != nil {
+		t.Fatalf("create signing keys: %v", err)
+	}
+
+	a, err := app.New(cfg, app.NewPostgresRepositories(pool, nil), app.Options{Keys: keys, DB: pool})
+	if err != nil {
+		t.Fatalf("build application: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = a.Close(ctx)
+	})
+
+	return &server{t: t, app: handler.NewServer(cfg, a).Public}
+}
+
+// do sends a JSON request, with a bearer token when token is set, and
+// decodes the JSON response into out when out is not nil
+func (s *server) do(method, path, token string, body, out any) int {
+	s.t.Helper()
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("encode %s %s body: %v", method, path, err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reqBody)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+
+	resp, err := s.app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatalf("read %s %s response: %v", method, path, err)
+	}
+	if out != nil && resp.StatusCode < http.StatusBadRequest {
+		if err := json.Unmarshal(data, out); err != nil {
+			s.t.Fatalf("decode %s %s response %q: %v", method, path, data, err)
+		}
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		s.t.Logf("%s %s -> %d %s", method, path, resp.StatusCode, data)
+	}
+
+	return resp.StatusCode
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boonyarit-iamsaard/user-management-api/internal/testutil"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestSpanHasQuerySpans(t *testing.T) {
+	s := newServer(t)
+	recorder := testutil.RecordSpans(t)
+
+	if status := s.do(http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email":    "nobody@example.com",
+		"password": "Analytical-Engine-1843",
+	}, nil); status != http.StatusUnauthorized {
+		t.Fatalf("login: status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	var server trace.SpanContext
+	for _, span := range recorder.Ended() {
+		if span.SpanKind() == trace.SpanKindServer {
+			server = span.SpanContext()
+		}
+	}
+	if !server.IsValid() {
+		t.Fatal("the request recorded no server span")
+	}
+
+	var queries int
+	for _, span := range recorder.Ended() {
+		if !strings.HasPrefix(span.Name(), "db ") {
+			continue
+		}
+		queries++
+		if span.Parent().SpanID() != server.SpanID() || span.SpanContext().TraceID() != server.TraceID() {
+			t.Errorf("query span %s is not a child of the request span", span.Name())
+		}
+	}
+	if queries == 0 {
+		t.Error("the request recorded no query spans")
+	}
+}